@@ -0,0 +1,768 @@
+// cmd/redditctl/main.go
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"reddit-ingestion/internal/anonymize"
+	"reddit-ingestion/internal/app"
+	"reddit-ingestion/internal/archive"
+	"reddit-ingestion/internal/audit"
+	"reddit-ingestion/internal/compression"
+	"reddit-ingestion/internal/config"
+	"reddit-ingestion/internal/export"
+	"reddit-ingestion/internal/policy"
+	"reddit-ingestion/internal/quality"
+	"reddit-ingestion/internal/scrub"
+	"reddit-ingestion/internal/sink"
+	"reddit-ingestion/pkg/client"
+	"reddit-ingestion/pkg/parser"
+	"reddit-ingestion/pkg/scraper"
+	"reddit-ingestion/pkg/utils"
+)
+
+const usage = `redditctl scrapes Reddit without running the HTTP server.
+
+Usage:
+  redditctl <command> [flags]
+
+Commands:
+  subreddit   Fetch posts from a subreddit        (-subreddit, -limit, -since_timestamp, filters)
+  user        Fetch a user's profile and activity (-username, -post_limit, -comment_limit, -since_timestamp, filters)
+  post        Fetch a post with all its comments  (-post_id, -related)
+  search      Search Reddit posts                 (-search_string, -subreddit, -author, -sort, -time, -limit, -since_timestamp, -nsfw)
+  frontpage   Fetch the front page, r/all or r/popular (-feed, -sort, -time, -geo, -limit)
+  reprocess   Re-parse archived raw pages         (-prefix, -to kafka|output)
+  keygen      Create a key pair for sealed pseudonym mappings
+  unseal      Print a sealed pseudonym mapping    (-in, -key-file)
+
+Filters (subreddit; user takes -title_contains, -body_contains and -regex):
+  -flair, -exclude_flair  Keep or leave out posts with this flair
+  -author     Only posts by this author
+  -title_contains, -body_contains  Only posts whose title or body contains this
+  -regex      Match filters as regular expressions
+  -nsfw       include, exclude or only NSFW posts (subreddit and search)
+
+Common flags:
+  -format     json, ndjson or csv (default json)
+  -o          Write output to this file instead of stdout
+  -timeout    Overall timeout for the command (default 10m)
+  -purpose    Purpose of the scrape, recorded in the audit log
+  -pool       Only use proxies with this label
+  -awards     Include the awards of posts and comments (json or ndjson only)
+  -anonymize  Pseudonymize usernames with ANONYMIZE_KEY (ndjson or csv only)
+  -anonymize-key  Use this ANONYMIZE_KEY ID instead of the newest key
+  -mapping    Write the pseudonym mapping, sealed to ANONYMIZE_MAPPING_PUBLIC_KEY, to this file
+  -compress   Compress the output: none, gzip or zstd (default none)
+  -compress-level  Compression level, 0 for the codec's default
+  -rotate-mb  Start a new numbered -o file every this many MB (ndjson or csv only)
+
+Configuration is read from the environment and .env, exactly like the server.
+`
+
+// commonFlags are accepted by every subcommand
+type commonFlags struct {
+	format    string
+	output    string
+	timeout   time.Duration
+	purpose   string
+	pool      string
+	anonymize bool
+	awards    bool
+	// ANONYMIZE_KEY ID to use and where to write the sealed mapping
+	anonymizeKey string
+	mapping      string
+	// Output compression and size-based rotation of the -o file
+	compress      string
+	compressLevel int
+	rotateMB      int
+}
+
+func (c *commonFlags) register(fs *flag.FlagSet) {
+	fs.StringVar(&c.format, "format", "json", "output format: json, ndjson or csv")
+	fs.StringVar(&c.output, "o", "", "output file (default stdout)")
+	fs.DurationVar(&c.timeout, "timeout", 10*time.Minute, "overall timeout")
+	fs.StringVar(&c.purpose, "purpose", "", "purpose of the scrape, recorded in the audit log (required with REQUIRE_PURPOSE)")
+	fs.StringVar(&c.pool, "pool", "", "only use proxies with this label")
+	fs.BoolVar(&c.anonymize, "anonymize", false, "replace usernames with stable pseudonyms keyed by ANONYMIZE_KEY")
+	fs.BoolVar(&c.awards, "awards", false, "include the awards of posts and comments (json and ndjson only)")
+	fs.StringVar(&c.anonymizeKey, "anonymize-key", "", "ID of the ANONYMIZE_KEY entry to use (default the newest)")
+	fs.StringVar(&c.mapping, "mapping", "", "write the pseudonym mapping, sealed to ANONYMIZE_MAPPING_PUBLIC_KEY, to this file")
+	fs.StringVar(&c.compress, "compress", "none", "compress the output: none, gzip or zstd")
+	fs.IntVar(&c.compressLevel, "compress-level", 0, "compression level, 0 for the codec's default (gzip 1-9, zstd 1-22)")
+	fs.IntVar(&c.rotateMB, "rotate-mb", 0, "start a new numbered -o file every this many MB, 0 to write one file")
+}
+
+// openOutput returns where results go: the -o file, or stdout when there is
+// none, compressed as requested. close flushes and closes it and must be
+// called before the output is complete; calling it again is a no-op.
+func (c *commonFlags) openOutput(stdout io.Writer) (io.Writer, func() error, error) {
+	codec, err := compression.Parse(c.compress)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := codec.CheckLevel(c.compressLevel); err != nil {
+		return nil, nil, err
+	}
+	if c.rotateMB < 0 {
+		return nil, nil, fmt.Errorf("-rotate-mb must not be negative")
+	}
+
+	if c.output == "" {
+		if c.rotateMB > 0 {
+			return nil, nil, fmt.Errorf("-rotate-mb needs an output file, set -o")
+		}
+		w, err := compression.NewWriter(stdout, codec, c.compressLevel)
+		if err != nil {
+			return nil, nil, err
+		}
+		return w, closeOnce(w.Close), nil
+	}
+
+	f, err := export.CreateFile(c.output, export.FileOptions{
+		Compression: codec,
+		Level:       c.compressLevel,
+		MaxBytes:    int64(c.rotateMB) << 20,
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return f, closeOnce(func() error {
+		err := f.Close()
+		if names := f.Names(); len(names) > 1 {
+			fmt.Fprintf(os.Stderr, "Wrote %d files, %s to %s\n", len(names), names[0], names[len(names)-1])
+		}
+		return err
+	}), nil
+}
+
+func closeOnce(fn func() error) func() error {
+	closed := false
+	return func() error {
+		if closed {
+			return nil
+		}
+		closed = true
+		return fn()
+	}
+}
+
+// repeatedFlag collects every value of a flag that may be given several times
+type repeatedFlag []string
+
+func (r *repeatedFlag) String() string { return strings.Join(*r, ",") }
+
+func (r *repeatedFlag) Set(value string) error {
+	*r = append(*r, value)
+	return nil
+}
+
+// filterFlags are the post filter flags of the subreddit and user commands,
+// matching the filter parameters of /subreddit and /user
+type filterFlags struct {
+	flair         repeatedFlag
+	excludeFlair  repeatedFlag
+	author        repeatedFlag
+	titleContains repeatedFlag
+	bodyContains  repeatedFlag
+	regex         bool
+	nsfw          string
+}
+
+// registerText registers the title and body filters, which user takes
+func (f *filterFlags) registerText(fs *flag.FlagSet) {
+	fs.Var(&f.titleContains, "title_contains", "only posts whose title contains this; repeatable")
+	fs.Var(&f.bodyContains, "body_contains", "only posts whose body contains this; repeatable")
+	fs.BoolVar(&f.regex, "regex", false, "match filters as regular expressions instead of exactly or as substrings")
+}
+
+// register registers every filter, which subreddit takes
+func (f *filterFlags) register(fs *flag.FlagSet) {
+	f.registerText(fs)
+	fs.Var(&f.flair, "flair", "only posts with this flair; repeat for any of several")
+	fs.Var(&f.excludeFlair, "exclude_flair", "leave out posts with this flair; repeatable")
+	fs.Var(&f.author, "author", "only posts by this author; repeatable")
+	registerNSFW(fs, &f.nsfw)
+}
+
+// registerNSFW registers the -nsfw flag of the subreddit and search commands
+func registerNSFW(fs *flag.FlagSet, nsfw *string) {
+	fs.StringVar(nsfw, "nsfw", "", "NSFW posts: include, exclude or only (default include)")
+}
+
+func (f *filterFlags) filter() (scraper.PostFilter, error) {
+	var filter scraper.PostFilter
+	for _, flags := range []struct {
+		name       string
+		values     []string
+		matchers   *[]scraper.TextMatcher
+		newMatcher func(string, bool) (scraper.TextMatcher, error)
+	}{
+		{"flair", f.flair, &filter.Flair, scraper.NewTextMatcher},
+		{"exclude_flair", f.excludeFlair, &filter.ExcludeFlair, scraper.NewTextMatcher},
+		{"author", f.author, &filter.Author, scraper.NewTextMatcher},
+		{"title_contains", f.titleContains, &filter.TitleContains, scraper.NewContainsMatcher},
+		{"body_contains", f.bodyContains, &filter.BodyContains, scraper.NewContainsMatcher},
+	} {
+		for _, value := range flags.values {
+			m, err := flags.newMatcher(value, f.regex)
+			if err != nil {
+				return scraper.PostFilter{}, fmt.Errorf("-%s: %w", flags.name, err)
+			}
+			*flags.matchers = append(*flags.matchers, m)
+		}
+	}
+	nsfw, err := scraper.ParseNSFWMode(f.nsfw)
+	if err != nil {
+		return scraper.PostFilter{}, fmt.Errorf("-nsfw: %w", err)
+	}
+	filter.NSFW = nsfw
+	return filter, nil
+}
+
+// newAnonymizer returns the anonymizer for -anonymize, or nil when it is off
+func (c *commonFlags) newAnonymizer(cfg *config.Config) (*export.Anonymizer, error) {
+	if !c.anonymize {
+		if c.anonymizeKey != "" || c.mapping != "" {
+			return nil, fmt.Errorf("-anonymize-key and -mapping require -anonymize")
+		}
+		return nil, nil
+	}
+	anonymizer, err := export.NewAnonymizer(cfg.AnonymizeKey, c.anonymizeKey)
+	if err != nil {
+		return nil, fmt.Errorf("-anonymize needs ANONYMIZE_KEY: %w", err)
+	}
+	if c.mapping != "" {
+		// The mapping de-pseudonymizes the export, so it is never written in the clear
+		if cfg.AnonymizeMappingPublicKey == "" {
+			return nil, fmt.Errorf("-mapping requires ANONYMIZE_MAPPING_PUBLIC_KEY, see redditctl keygen")
+		}
+		anonymizer.TrackMapping()
+	}
+	return anonymizer, nil
+}
+
+// writeMapping seals the pseudonyms anonymizer handed out to the -mapping file
+func (c *commonFlags) writeMapping(cfg *config.Config, anonymizer *export.Anonymizer) error {
+	if anonymizer == nil || c.mapping == "" {
+		return nil
+	}
+	sealed, err := export.SealMapping(export.Mapping{
+		KeyID:      anonymizer.KeyID(),
+		CreatedAt:  time.Now().UTC(),
+		Pseudonyms: anonymizer.Mapping(),
+	}, cfg.AnonymizeMappingPublicKey)
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(c.mapping, sealed, 0o600); err != nil {
+		return fmt.Errorf("write pseudonym mapping: %w", err)
+	}
+	return nil
+}
+
+func main() {
+	if len(os.Args) < 2 || os.Args[1] == "-h" || os.Args[1] == "--help" || os.Args[1] == "help" {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	// The scraper reports progress with fmt.Printf; keep that off the real stdout
+	// so it cannot corrupt the JSON/CSV written there.
+	stdout := os.Stdout
+	os.Stdout = os.Stderr
+
+	run := runScrape
+	switch os.Args[1] {
+	case "reprocess":
+		run = runReprocess
+	case "keygen":
+		run = runKeygen
+	case "unseal":
+		run = runUnseal
+	}
+
+	if err := run(os.Args[1], os.Args[2:], stdout); err != nil {
+		fmt.Fprintf(os.Stderr, "redditctl: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func runScrape(command string, args []string, stdout io.Writer) error {
+	var common commonFlags
+	fs := flag.NewFlagSet(command, flag.ExitOnError)
+	common.register(fs)
+
+	var execute func(ctx context.Context, svc scraper.ScraperService) (interface{}, []export.Record, error)
+
+	switch command {
+	case "subreddit":
+		subreddit := fs.String("subreddit", "", "subreddit name without the r/ prefix")
+		limit := fs.Int("limit", 25, "maximum number of posts, -1 for all")
+		since := fs.Int64("since_timestamp", 0, "only return posts newer than this Unix timestamp")
+		after := fs.String("after", "", "continue after this post fullname, e.g. the cursor of an earlier run")
+		var filters filterFlags
+		filters.register(fs)
+		execute = func(ctx context.Context, svc scraper.ScraperService) (interface{}, []export.Record, error) {
+			if *subreddit == "" {
+				return nil, nil, fmt.Errorf("missing -subreddit")
+			}
+			filter, err := filters.filter()
+			if err != nil {
+				return nil, nil, err
+			}
+			posts, meta, err := svc.ScrapeSubreddit(ctx, *subreddit, *since, *limit, scraper.ListingOptions{After: *after, Filter: filter})
+			if err != nil {
+				return nil, nil, err
+			}
+			return map[string]interface{}{"posts": posts, "meta": meta}, export.PostRecords(posts), nil
+		}
+
+	case "user":
+		username := fs.String("username", "", "Reddit username")
+		postLimit := fs.Int("post_limit", 25, "maximum number of posts, -1 for all")
+		commentLimit := fs.Int("comment_limit", 25, "maximum number of comments, -1 for all")
+		since := fs.Int64("since_timestamp", 0, "only return content newer than this Unix timestamp")
+		strict := fs.Bool("strict", false, "fail when posts or comments cannot be fetched instead of returning the rest")
+		var filters filterFlags
+		filters.registerText(fs)
+		execute = func(ctx context.Context, svc scraper.ScraperService) (interface{}, []export.Record, error) {
+			if *username == "" {
+				return nil, nil, fmt.Errorf("missing -username")
+			}
+			if *strict {
+				ctx = scraper.WithStrict(ctx)
+			}
+			filter, err := filters.filter()
+			if err != nil {
+				return nil, nil, err
+			}
+			if !filter.IsZero() {
+				ctx = scraper.WithActivityFilter(ctx, filter)
+			}
+			activity, err := svc.ScrapeUserActivity(ctx, *username, *since, *postLimit, *commentLimit)
+			if err != nil {
+				return nil, nil, err
+			}
+			return activity, export.UserActivityRecords(activity), nil
+		}
+
+	case "post":
+		postID := fs.String("post_id", "", "Reddit post ID")
+		related := fs.Bool("related", false, "also fetch the other submissions of the post's link, crossposts included")
+		commentSort := fs.String("sort", client.DefaultCommentSort, "order of the comments (best, top, new, controversial, old, qa)")
+		var tree client.CommentTree
+		fs.IntVar(&tree.Depth, "depth", 0, "levels of replies to fetch, 1 for top-level comments only; skips \"load more\" expansion")
+		fs.IntVar(&tree.Limit, "limit", 0, "most comments to fetch; skips \"load more\" expansion")
+		var expansion scraper.ExpansionOptions
+		fs.IntVar(&expansion.Workers, "expand_workers", 0, "\"load more\" comment sets fetched at once (default SCRAPER_EXPANSION_WORKERS)")
+		fs.IntVar(&expansion.BatchSize, "expand_batch_size", 0, "\"load more\" comment sets taken per round (default SCRAPER_EXPANSION_BATCH_SIZE)")
+		fs.IntVar(&expansion.Concurrency, "expand_concurrency", 0, "requests per \"load more\" set run at once (default SCRAPER_EXPANSION_CONCURRENCY)")
+		execute = func(ctx context.Context, svc scraper.ScraperService) (interface{}, []export.Record, error) {
+			if *postID == "" {
+				return nil, nil, fmt.Errorf("missing -post_id")
+			}
+			if !slices.Contains(client.CommentSorts, *commentSort) {
+				return nil, nil, fmt.Errorf("invalid -sort %q", *commentSort)
+			}
+			if tree.Depth < 0 || tree.Limit < 0 {
+				return nil, nil, fmt.Errorf("-depth and -limit must not be negative")
+			}
+			if *related {
+				ctx = scraper.WithRelated(ctx)
+			}
+			ctx = scraper.WithExpansion(ctx, expansion)
+			ctx = client.WithCommentSort(ctx, *commentSort)
+			ctx = client.WithCommentTree(ctx, tree)
+			detail, err := svc.ScrapePost(ctx, *postID)
+			if err != nil {
+				return nil, nil, err
+			}
+			return detail, export.CommentRecords(detail), nil
+		}
+
+	case "search":
+		params := map[string]*string{}
+		for _, name := range []string{"search_string", "subreddit", "author", "site", "url", "selftext", "self", "restrict_sr"} {
+			params[name] = fs.String(name, "", name+" search parameter")
+		}
+		var nsfw string
+		registerNSFW(fs, &nsfw)
+		sort := fs.String("sort", "relevance", "sort order (relevance, hot, top, new, comments)")
+		timeRange := fs.String("time", "all", "time range (hour, day, week, month, year, all)")
+		limit := fs.Int("limit", 25, "maximum number of results, -1 for all")
+		since := fs.Int64("since_timestamp", 0, "only return posts newer than this Unix timestamp")
+		after := fs.String("after", "", "continue after this post fullname, e.g. the cursor of an earlier run")
+		execute = func(ctx context.Context, svc scraper.ScraperService) (interface{}, []export.Record, error) {
+			searchParams := map[string]string{
+				"sort":  *sort,
+				"time":  *timeRange,
+				"limit": fmt.Sprintf("%d", *limit),
+			}
+			for name, value := range params {
+				if *value != "" {
+					searchParams[name] = *value
+				}
+			}
+			if searchParams["search_string"] == "" {
+				return nil, nil, fmt.Errorf("missing -search_string")
+			}
+			mode, err := scraper.ParseNSFWMode(nsfw)
+			if err != nil {
+				return nil, nil, fmt.Errorf("-nsfw: %w", err)
+			}
+			opts := scraper.ListingOptions{After: *after, Filter: scraper.PostFilter{NSFW: mode}}
+			posts, meta, err := svc.Search(ctx, searchParams, *since, *limit, opts)
+			if err != nil {
+				return nil, nil, err
+			}
+			return map[string]interface{}{"posts": posts, "meta": meta}, export.PostRecords(posts), nil
+		}
+
+	case "frontpage":
+		feed := fs.String("feed", scraper.FeedFrontpage, "frontpage, all or popular")
+		sort := fs.String("sort", "hot", "sort order (hot, new, top, rising, controversial)")
+		timeRange := fs.String("time", "", "time range of top and controversial (hour, day, week, month, year, all)")
+		geo := fs.String("geo", "", "region r/popular is tailored to, e.g. GLOBAL or GB")
+		limit := fs.Int("limit", 25, "maximum number of posts, -1 for all")
+		after := fs.String("after", "", "continue after this post fullname, e.g. the cursor of an earlier run")
+		execute = func(ctx context.Context, svc scraper.ScraperService) (interface{}, []export.Record, error) {
+			switch *feed {
+			case scraper.FeedFrontpage, scraper.FeedAll, scraper.FeedPopular:
+			default:
+				return nil, nil, fmt.Errorf("unsupported -feed %q, must be frontpage, all or popular", *feed)
+			}
+			params := map[string]string{"sort": *sort}
+			if *timeRange != "" {
+				params["t"] = *timeRange
+			}
+			if *geo != "" {
+				params["geo_filter"] = strings.ToUpper(*geo)
+			}
+			posts, meta, err := svc.ScrapeFrontpage(ctx, *feed, params, *limit, scraper.ListingOptions{After: *after})
+			if err != nil {
+				return nil, nil, err
+			}
+			return map[string]interface{}{"posts": posts, "meta": meta}, export.PostRecords(posts), nil
+		}
+
+	default:
+		fmt.Fprint(os.Stderr, usage)
+		return fmt.Errorf("unknown command %q", command)
+	}
+
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	format, err := export.ParseFormat(common.format)
+	if err != nil {
+		return err
+	}
+	if common.anonymize && format == export.FormatJSON {
+		return fmt.Errorf("-anonymize writes records, use -format ndjson or csv")
+	}
+	if common.rotateMB > 0 && format == export.FormatJSON {
+		return fmt.Errorf("-rotate-mb splits output between records, use -format ndjson or csv")
+	}
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return fmt.Errorf("failed to load configuration: %w", err)
+	}
+	anonymizer, err := common.newAnonymizer(cfg)
+	if err != nil {
+		return err
+	}
+
+	svc, err := newScraperService(cfg, common.pool)
+	if err != nil {
+		return err
+	}
+
+	out, closeOutput, err := common.openOutput(stdout)
+	if err != nil {
+		return err
+	}
+	defer closeOutput()
+
+	ctx, finish, err := startOperation(cfg, fs, common)
+	if err != nil {
+		return err
+	}
+
+	doc, records, err := execute(ctx, svc)
+	finish(err)
+	if err != nil {
+		return fmt.Errorf("%s failed: %w", command, err)
+	}
+	if anonymizer != nil {
+		records = anonymizer.Records(records)
+	}
+
+	w := export.NewWriter(out, format)
+	if err := w.Write(doc, records); err != nil {
+		return err
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	if err := closeOutput(); err != nil {
+		return err
+	}
+	return common.writeMapping(cfg, anonymizer)
+}
+
+// runReprocess re-parses archived raw pages, writing the results either to the
+// configured Kafka sink or as NDJSON records to the output
+func runReprocess(command string, args []string, stdout io.Writer) error {
+	var common commonFlags
+	fs := flag.NewFlagSet(command, flag.ExitOnError)
+	common.register(fs)
+	prefix := fs.String("prefix", "", "archive key prefix, e.g. raw/2025/04/15/r/golang")
+	to := fs.String("to", "output", "where parsed results go: output (NDJSON) or kafka")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if *prefix == "" {
+		return fmt.Errorf("missing -prefix")
+	}
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return fmt.Errorf("failed to load configuration: %w", err)
+	}
+	if cfg.ArchiveBackend == "" {
+		return fmt.Errorf("ARCHIVE_BACKEND is not set, there is no archive to reprocess")
+	}
+
+	store, err := app.NewArchiveStore(cfg)
+	if err != nil {
+		return fmt.Errorf("failed to open raw archive: %w", err)
+	}
+	parserOptions, err := app.ParserOptions(cfg)
+	if err != nil {
+		return err
+	}
+
+	var dataSink sink.Sink
+	var anonymizer *export.Anonymizer
+	closeOutput := func() error { return nil }
+	switch *to {
+	case "kafka":
+		if len(cfg.KafkaBrokers) == 0 {
+			return fmt.Errorf("-to kafka requires KAFKA_BROKERS")
+		}
+		if common.anonymize || common.mapping != "" {
+			return fmt.Errorf("-anonymize only applies to -to output")
+		}
+		if codec, _ := compression.Parse(common.compress); codec != compression.None || common.rotateMB > 0 {
+			return fmt.Errorf("-compress and -rotate-mb only apply to -to output")
+		}
+		if dataSink, err = app.NewSink(cfg); err != nil {
+			return err
+		}
+	case "output":
+		format, err := export.ParseFormat(common.format)
+		if err != nil {
+			return err
+		}
+		if format == export.FormatCSV {
+			return fmt.Errorf("reprocess mixes posts, comments and users, use -format ndjson")
+		}
+		if anonymizer, err = common.newAnonymizer(cfg); err != nil {
+			return err
+		}
+		out, closeFile, err := common.openOutput(stdout)
+		if err != nil {
+			return err
+		}
+		defer closeFile()
+		closeOutput = closeFile
+		w := export.NewWriter(out, export.FormatNDJSON)
+		if anonymizer != nil {
+			dataSink = export.NewAnonymizingRecordSink(w, anonymizer)
+		} else {
+			dataSink = export.NewRecordSink(w)
+		}
+	default:
+		return fmt.Errorf("unsupported -to %q, must be output or kafka", *to)
+	}
+	scrubber, err := app.NewScrubber(cfg)
+	if err != nil {
+		return err
+	}
+	if scrubber != nil {
+		dataSink = scrub.WrapSink(dataSink, scrubber)
+	}
+
+	ctx, finish, err := startOperation(cfg, fs, common)
+	if err != nil {
+		return err
+	}
+
+	result, err := archive.NewReplayer(store, parser.NewRedditParserWithOptions(parserOptions), dataSink).Replay(ctx, *prefix)
+	if closeErr := dataSink.Close(); err == nil {
+		err = closeErr
+	}
+	if closeErr := closeOutput(); err == nil {
+		err = closeErr
+	}
+	finish(err)
+	if err != nil {
+		return fmt.Errorf("reprocess failed: %w", err)
+	}
+	if err := common.writeMapping(cfg, anonymizer); err != nil {
+		return err
+	}
+
+	for _, e := range result.Errors {
+		fmt.Fprintf(os.Stderr, "skipped %s\n", e)
+	}
+	fmt.Fprintf(os.Stderr, "Reprocessed %d objects: %d posts, %d comments, %d users, %d skipped\n",
+		result.Objects, result.Posts, result.Comments, result.Users, result.Skipped)
+	return nil
+}
+
+// runKeygen creates the key pair pseudonym mappings are sealed with
+func runKeygen(command string, args []string, stdout io.Writer) error {
+	fs := flag.NewFlagSet(command, flag.ExitOnError)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	publicKey, privateKey, err := export.GenerateMappingKeys()
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(stdout, "ANONYMIZE_MAPPING_PUBLIC_KEY=%s\n", publicKey)
+	fmt.Fprintf(stdout, "# Private key: give it only to whoever may de-pseudonymize, keep it off this host\n")
+	fmt.Fprintf(stdout, "%s\n", privateKey)
+	return nil
+}
+
+// runUnseal prints a sealed pseudonym mapping as JSON
+func runUnseal(command string, args []string, stdout io.Writer) error {
+	fs := flag.NewFlagSet(command, flag.ExitOnError)
+	in := fs.String("in", "", "sealed mapping file written by -mapping")
+	keyFile := fs.String("key-file", "", "file holding the mapping private key from keygen")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *in == "" || *keyFile == "" {
+		return fmt.Errorf("missing -in or -key-file")
+	}
+
+	sealed, err := os.ReadFile(*in)
+	if err != nil {
+		return fmt.Errorf("read sealed mapping: %w", err)
+	}
+	privateKey, err := os.ReadFile(*keyFile)
+	if err != nil {
+		return fmt.Errorf("read private key: %w", err)
+	}
+	mapping, err := export.OpenMapping(sealed, strings.TrimSpace(string(privateKey)))
+	if err != nil {
+		return err
+	}
+
+	encoder := json.NewEncoder(stdout)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(mapping)
+}
+
+// startOperation enforces REQUIRE_PURPOSE and returns the command's context,
+// carrying the purpose and timeout. finish records the run in the audit log.
+func startOperation(cfg *config.Config, fs *flag.FlagSet, common commonFlags) (context.Context, func(error), error) {
+	if common.purpose == "" && cfg.RequirePurpose {
+		return nil, nil, fmt.Errorf("REQUIRE_PURPOSE is set, pass -purpose")
+	}
+
+	logger, err := app.NewAuditLogger(cfg)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	params := make(map[string]string)
+	fs.Visit(func(f *flag.Flag) {
+		if f.Name != "purpose" {
+			params[f.Name] = f.Value.String()
+		}
+	})
+
+	start := time.Now()
+	ctx := utils.WithProxyPool(audit.WithPurpose(context.Background(), common.purpose), common.pool)
+	if common.awards {
+		ctx = parser.WithAwards(ctx)
+	}
+	ctx, cancel := context.WithTimeout(ctx, common.timeout)
+
+	finish := func(runErr error) {
+		cancel()
+		status := 0
+		if runErr != nil {
+			status = 1
+		}
+		if err := logger.Log(audit.Entry{
+			Time:       start.UTC(),
+			Method:     "CLI",
+			Path:       fs.Name(),
+			Params:     params,
+			Purpose:    common.purpose,
+			RemoteIP:   "local",
+			Status:     status,
+			DurationMS: time.Since(start).Milliseconds(),
+		}); err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to write audit entry: %v\n", err)
+		}
+		logger.Close()
+	}
+	return ctx, finish, nil
+}
+
+func newScraperService(cfg *config.Config, pool string) (scraper.ScraperService, error) {
+	// Left running until the command exits
+	app.StartFakeReddit(cfg)
+	redditClient, err := client.NewRedditClient(cfg.ClientOptions())
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Reddit client: %w", err)
+	}
+	if pool != "" && !redditClient.HasProxyPool(pool) {
+		return nil, fmt.Errorf("no proxy in REDDIT_PROXY_URLS is labelled %q", pool)
+	}
+
+	parserOptions, err := app.ParserOptions(cfg)
+	if err != nil {
+		return nil, err
+	}
+	scraperOptions, err := app.ScraperOptions(cfg)
+	if err != nil {
+		return nil, err
+	}
+	scraperOptions.Throttle = redditClient.Throttle()
+	svc := scraper.NewScraperServiceWithOptions(redditClient, parser.NewRedditParserWithOptions(parserOptions), scraperOptions)
+	blocklist := policy.NewBlocklist(cfg.BlockedSubreddits, cfg.BlockedUsers)
+	if err := blocklist.UseFile(cfg.BlocklistFile); err != nil {
+		return nil, err
+	}
+	svc = policy.WrapService(svc, blocklist)
+	scrubber, err := app.NewScrubber(cfg)
+	if err != nil {
+		return nil, err
+	}
+	if scrubber != nil {
+		svc = scrub.WrapService(svc, scrubber)
+	}
+	if cfg.AnonymizeAuthors {
+		svc = anonymize.WrapService(svc, true)
+	}
+	return quality.WrapService(svc, nil), nil
+}
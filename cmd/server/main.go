@@ -42,6 +42,17 @@ func main() {
 	log.Println("Server started successfully")
 	log.Println("Swagger documentation available at http://localhost:8080/swagger/index.html")
 
+	reload := make(chan os.Signal, 1)
+	signal.Notify(reload, syscall.SIGHUP)
+	go func() {
+		for range reload {
+			log.Println("Received SIGHUP, reloading configuration...")
+			if err := application.Reload(); err != nil {
+				log.Printf("Configuration reload failed, keeping previous settings: %v", err)
+			}
+		}
+	}()
+
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
 	<-quit
@@ -55,5 +66,9 @@ func main() {
 		log.Printf("Server shutdown error: %v", err)
 	}
 
+	if err := application.Close(); err != nil {
+		log.Printf("Error closing application resources: %v", err)
+	}
+
 	log.Println("Server stopped")
 }
\ No newline at end of file
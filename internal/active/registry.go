@@ -0,0 +1,117 @@
+// internal/active/registry.go
+package active
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"reddit-ingestion/internal/audit"
+	"reddit-ingestion/pkg/utils"
+)
+
+// ErrCancelled is returned by scrapes cancelled through Registry.Cancel
+var ErrCancelled = errors.New("scrape cancelled")
+
+// Operation is a scrape in progress
+type Operation struct {
+	ID string `json:"id"`
+	// Operation is subreddit, user, post, search or frontpage
+	Operation string `json:"operation"`
+	// Target is the subreddit, user, post ID, query or feed scraped
+	Target       string    `json:"target"`
+	Purpose      string    `json:"purpose,omitempty"`
+	StartedAt    time.Time `json:"started_at"`
+	ElapsedMS    int64     `json:"elapsed_ms"`
+	PagesFetched int64     `json:"pages_fetched"`
+	// Requests sent again after a failed attempt
+	Retries int64 `json:"retries"`
+}
+
+// Registry tracks the scrapes in progress so they can be listed and
+// cancelled. It is safe for concurrent use.
+type Registry struct {
+	mutex  sync.Mutex
+	nextID int64
+	byID   map[string]*tracked
+}
+
+type tracked struct {
+	op     Operation
+	pages  *utils.PageCounter
+	cancel context.CancelCauseFunc
+}
+
+// NewRegistry creates a registry with no operations
+func NewRegistry() *Registry {
+	return &Registry{byID: make(map[string]*tracked)}
+}
+
+// Start registers a scrape of target and returns the context to run it with,
+// which counts the pages fetched and is cancelled by Cancel, and a function
+// to call once the scrape is over
+func (r *Registry) Start(ctx context.Context, operation, target string) (context.Context, func()) {
+	ctx, cancel := context.WithCancelCause(ctx)
+	pages := &utils.PageCounter{}
+	ctx = utils.WithPageCounter(ctx, pages)
+
+	r.mutex.Lock()
+	r.nextID++
+	id := strconv.FormatInt(r.nextID, 10)
+	r.byID[id] = &tracked{
+		op: Operation{
+			ID:        id,
+			Operation: operation,
+			Target:    target,
+			Purpose:   audit.PurposeFromContext(ctx),
+			StartedAt: time.Now().UTC(),
+		},
+		pages:  pages,
+		cancel: cancel,
+	}
+	r.mutex.Unlock()
+
+	return ctx, func() {
+		r.mutex.Lock()
+		delete(r.byID, id)
+		r.mutex.Unlock()
+		cancel(nil)
+	}
+}
+
+// List returns the operations in progress, oldest first
+func (r *Registry) List() []Operation {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	ops := make([]Operation, 0, len(r.byID))
+	for _, t := range r.byID {
+		op := t.op
+		op.ElapsedMS = time.Since(op.StartedAt).Milliseconds()
+		op.PagesFetched = t.pages.Pages()
+		op.Retries = t.pages.Retries()
+		ops = append(ops, op)
+	}
+	sort.Slice(ops, func(i, j int) bool {
+		if !ops[i].StartedAt.Equal(ops[j].StartedAt) {
+			return ops[i].StartedAt.Before(ops[j].StartedAt)
+		}
+		return ops[i].ID < ops[j].ID
+	})
+	return ops
+}
+
+// Cancel cancels the operation with id; it reports false when no such
+// operation is in progress
+func (r *Registry) Cancel(id string) bool {
+	r.mutex.Lock()
+	t, ok := r.byID[id]
+	r.mutex.Unlock()
+	if ok {
+		t.cancel(ErrCancelled)
+	}
+	return ok
+}
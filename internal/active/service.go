@@ -0,0 +1,77 @@
+// internal/active/service.go
+package active
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"reddit-ingestion/pkg/models"
+	"reddit-ingestion/pkg/scraper"
+)
+
+// trackingService registers every scrape in a Registry while it runs
+type trackingService struct {
+	scraper.ScraperService
+	registry *Registry
+}
+
+// WrapService returns a ScraperService that lists its scrapes in r while they
+// run and returns ErrCancelled from those cancelled through r
+func WrapService(svc scraper.ScraperService, r *Registry) scraper.ScraperService {
+	return &trackingService{
+		ScraperService: svc,
+		registry:       r,
+	}
+}
+
+func (w *trackingService) ScrapeSubreddit(ctx context.Context, subreddit string, sinceTimestamp int64, limit int, opts scraper.ListingOptions) ([]models.Post, models.ListingMeta, error) {
+	ctx, done := w.registry.Start(ctx, "subreddit", subreddit)
+	defer done()
+	posts, meta, err := w.ScraperService.ScrapeSubreddit(ctx, subreddit, sinceTimestamp, limit, opts)
+	return posts, meta, cancelled(ctx, err)
+}
+
+func (w *trackingService) ScrapeUserActivity(ctx context.Context, username string, sinceTimestamp int64, postLimit, commentLimit int) (models.UserActivity, error) {
+	ctx, done := w.registry.Start(ctx, "user", username)
+	defer done()
+	activity, err := w.ScraperService.ScrapeUserActivity(ctx, username, sinceTimestamp, postLimit, commentLimit)
+	return activity, cancelled(ctx, err)
+}
+
+func (w *trackingService) ScrapeUserOverview(ctx context.Context, username string, sinceTimestamp int64, limit int) (models.UserOverview, error) {
+	ctx, done := w.registry.Start(ctx, "user_overview", username)
+	defer done()
+	overview, err := w.ScraperService.ScrapeUserOverview(ctx, username, sinceTimestamp, limit)
+	return overview, cancelled(ctx, err)
+}
+
+func (w *trackingService) ScrapePost(ctx context.Context, postID string) (models.PostDetail, error) {
+	ctx, done := w.registry.Start(ctx, "post", postID)
+	defer done()
+	detail, err := w.ScraperService.ScrapePost(ctx, postID)
+	return detail, cancelled(ctx, err)
+}
+
+func (w *trackingService) Search(ctx context.Context, searchParams map[string]string, sinceTimestamp int64, limit int, opts scraper.ListingOptions) ([]models.Post, models.ListingMeta, error) {
+	ctx, done := w.registry.Start(ctx, "search", searchParams["search_string"])
+	defer done()
+	posts, meta, err := w.ScraperService.Search(ctx, searchParams, sinceTimestamp, limit, opts)
+	return posts, meta, cancelled(ctx, err)
+}
+
+func (w *trackingService) ScrapeFrontpage(ctx context.Context, feed string, params map[string]string, limit int, opts scraper.ListingOptions) ([]models.Post, models.ListingMeta, error) {
+	ctx, done := w.registry.Start(ctx, "frontpage", feed)
+	defer done()
+	posts, meta, err := w.ScraperService.ScrapeFrontpage(ctx, feed, params, limit, opts)
+	return posts, meta, cancelled(ctx, err)
+}
+
+// cancelled marks err as ErrCancelled when the scrape was cancelled through
+// the registry rather than by a timeout or a closed connection
+func cancelled(ctx context.Context, err error) error {
+	if err != nil && !errors.Is(err, ErrCancelled) && errors.Is(context.Cause(ctx), ErrCancelled) {
+		return fmt.Errorf("%w: %w", ErrCancelled, err)
+	}
+	return err
+}
@@ -0,0 +1,149 @@
+// internal/anonymize/anonymize.go
+package anonymize
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+
+	"reddit-ingestion/internal/export"
+	"reddit-ingestion/pkg/models"
+)
+
+type anonymizeKey struct{}
+
+// WithAnonymizedAuthors marks ctx so the scrape's authors are replaced by
+// pseudonyms
+func WithAnonymizedAuthors(ctx context.Context) context.Context {
+	return context.WithValue(ctx, anonymizeKey{}, true)
+}
+
+// IsAnonymized reports whether ctx was marked by WithAnonymizedAuthors
+func IsAnonymized(ctx context.Context) bool {
+	anonymized, _ := ctx.Value(anonymizeKey{}).(bool)
+	return anonymized
+}
+
+// Response pseudonymizes the usernames of a single scrape result. Every
+// response gets its own random salt: a username maps to the same pseudonym
+// throughout the response, so who replied to whom can still be studied, but
+// pseudonyms of different responses cannot be joined. Exports that have to
+// join use export.Anonymizer with ANONYMIZE_KEY instead.
+type Response struct {
+	anonymizer *export.Anonymizer
+}
+
+// NewResponse creates the pseudonymizer for one response
+func NewResponse() (*Response, error) {
+	salt := make([]byte, 16)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, fmt.Errorf("generate anonymization salt: %w", err)
+	}
+	anonymizer, err := export.NewAnonymizer(hex.EncodeToString(salt), "")
+	if err != nil {
+		return nil, err
+	}
+	return &Response{anonymizer: anonymizer}, nil
+}
+
+// Posts replaces the authors of posts and the u/name mentions in their titles
+// and bodies
+func (r *Response) Posts(posts []models.Post) []models.Post {
+	if posts == nil {
+		return nil
+	}
+	anonymized := make([]models.Post, len(posts))
+	for i, post := range posts {
+		anonymized[i] = r.post(post)
+	}
+	return anonymized
+}
+
+func (r *Response) post(post models.Post) models.Post {
+	post.Author = r.anonymizer.Pseudonym(post.Author)
+	post.Title = r.anonymizer.Text(post.Title)
+	post.Body = r.anonymizer.Text(post.Body)
+	return post
+}
+
+// Comments replaces the authors of comments and all their replies and the
+// u/name mentions in their bodies
+func (r *Response) Comments(comments []models.Comment) []models.Comment {
+	if comments == nil {
+		return nil
+	}
+	anonymized := make([]models.Comment, len(comments))
+	for i, comment := range comments {
+		comment.Author = r.anonymizer.Pseudonym(comment.Author)
+		comment.Body = r.anonymizer.Text(comment.Body)
+		comment.Replies = r.Comments(comment.Replies)
+		anonymized[i] = comment
+	}
+	return anonymized
+}
+
+// PostDetail pseudonymizes a post, its comment tree and its related posts
+func (r *Response) PostDetail(detail models.PostDetail) models.PostDetail {
+	detail.Post = r.post(detail.Post)
+	detail.Comments = r.Comments(detail.Comments)
+	detail.Related = r.Posts(detail.Related)
+	return detail
+}
+
+// UserActivity replaces the username of the profile, the authors of the
+// comments replied to and the u/name mentions in posts and comments
+func (r *Response) UserActivity(activity models.UserActivity) models.UserActivity {
+	activity.UserInfo.Username = r.anonymizer.Pseudonym(activity.UserInfo.Username)
+	if activity.Posts != nil {
+		posts := make([]models.UserPost, len(activity.Posts))
+		for i, post := range activity.Posts {
+			posts[i] = r.userPost(post)
+		}
+		activity.Posts = posts
+	}
+	if activity.Comments != nil {
+		comments := make([]models.UserComment, len(activity.Comments))
+		for i, comment := range activity.Comments {
+			comments[i] = r.userComment(comment)
+		}
+		activity.Comments = comments
+	}
+	return activity
+}
+
+// UserOverview replaces the username of the overview, the authors of the
+// comments replied to and the u/name mentions in its posts and comments
+func (r *Response) UserOverview(overview models.UserOverview) models.UserOverview {
+	overview.Username = r.anonymizer.Pseudonym(overview.Username)
+	if overview.Items == nil {
+		return overview
+	}
+	items := make([]models.UserOverviewItem, len(overview.Items))
+	for i, item := range overview.Items {
+		if item.Post != nil {
+			post := r.userPost(*item.Post)
+			item.Post = &post
+		}
+		if item.Comment != nil {
+			comment := r.userComment(*item.Comment)
+			item.Comment = &comment
+		}
+		items[i] = item
+	}
+	overview.Items = items
+	return overview
+}
+
+func (r *Response) userPost(post models.UserPost) models.UserPost {
+	post.Title = r.anonymizer.Text(post.Title)
+	post.Body = r.anonymizer.Text(post.Body)
+	return post
+}
+
+func (r *Response) userComment(comment models.UserComment) models.UserComment {
+	comment.Body = r.anonymizer.Text(comment.Body)
+	comment.PostTitle = r.anonymizer.Text(comment.PostTitle)
+	comment.ParentAuthor = r.anonymizer.Pseudonym(comment.ParentAuthor)
+	return comment
+}
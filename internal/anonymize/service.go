@@ -0,0 +1,107 @@
+// internal/anonymize/service.go
+package anonymize
+
+import (
+	"context"
+
+	"reddit-ingestion/pkg/models"
+	"reddit-ingestion/pkg/scraper"
+)
+
+// anonymizingService pseudonymizes the authors of scrape results, of every
+// scrape or only of those marked by WithAnonymizedAuthors
+type anonymizingService struct {
+	scraper.ScraperService
+	always bool
+}
+
+// WrapService returns a ScraperService that pseudonymizes authors in results
+// of scrapes marked by WithAnonymizedAuthors. With always set every result is
+// pseudonymized; wrap it inside the sink so no usernames are stored.
+func WrapService(svc scraper.ScraperService, always bool) scraper.ScraperService {
+	return &anonymizingService{
+		ScraperService: svc,
+		always:         always,
+	}
+}
+
+// response returns the pseudonymizer of one scrape result, or nil when the
+// scrape is not anonymized
+func (w *anonymizingService) response(ctx context.Context) (*Response, error) {
+	if !w.always && !IsAnonymized(ctx) {
+		return nil, nil
+	}
+	return NewResponse()
+}
+
+func (w *anonymizingService) ScrapeSubreddit(ctx context.Context, subreddit string, sinceTimestamp int64, limit int, opts scraper.ListingOptions) ([]models.Post, models.ListingMeta, error) {
+	r, err := w.response(ctx)
+	if err != nil {
+		return nil, models.ListingMeta{}, err
+	}
+	posts, meta, err := w.ScraperService.ScrapeSubreddit(ctx, subreddit, sinceTimestamp, limit, opts)
+	if r != nil {
+		posts = r.Posts(posts)
+	}
+	return posts, meta, err
+}
+
+func (w *anonymizingService) ScrapeUserActivity(ctx context.Context, username string, sinceTimestamp int64, postLimit, commentLimit int) (models.UserActivity, error) {
+	r, err := w.response(ctx)
+	if err != nil {
+		return models.UserActivity{}, err
+	}
+	activity, err := w.ScraperService.ScrapeUserActivity(ctx, username, sinceTimestamp, postLimit, commentLimit)
+	if r != nil {
+		activity = r.UserActivity(activity)
+	}
+	return activity, err
+}
+
+func (w *anonymizingService) ScrapeUserOverview(ctx context.Context, username string, sinceTimestamp int64, limit int) (models.UserOverview, error) {
+	r, err := w.response(ctx)
+	if err != nil {
+		return models.UserOverview{}, err
+	}
+	overview, err := w.ScraperService.ScrapeUserOverview(ctx, username, sinceTimestamp, limit)
+	if r != nil {
+		overview = r.UserOverview(overview)
+	}
+	return overview, err
+}
+
+func (w *anonymizingService) ScrapePost(ctx context.Context, postID string) (models.PostDetail, error) {
+	r, err := w.response(ctx)
+	if err != nil {
+		return models.PostDetail{}, err
+	}
+	detail, err := w.ScraperService.ScrapePost(ctx, postID)
+	if r != nil {
+		detail = r.PostDetail(detail)
+	}
+	return detail, err
+}
+
+func (w *anonymizingService) Search(ctx context.Context, searchParams map[string]string, sinceTimestamp int64, limit int, opts scraper.ListingOptions) ([]models.Post, models.ListingMeta, error) {
+	r, err := w.response(ctx)
+	if err != nil {
+		return nil, models.ListingMeta{}, err
+	}
+	posts, meta, err := w.ScraperService.Search(ctx, searchParams, sinceTimestamp, limit, opts)
+	if r != nil {
+		posts = r.Posts(posts)
+	}
+	return posts, meta, err
+}
+
+func (w *anonymizingService) ScrapeFrontpage(ctx context.Context, feed string, params map[string]string, limit int, opts scraper.ListingOptions) ([]models.Post, models.ListingMeta, error) {
+	r, err := w.response(ctx)
+	if err != nil {
+		return nil, models.ListingMeta{}, err
+	}
+	posts, meta, err := w.ScraperService.ScrapeFrontpage(ctx, feed, params, limit, opts)
+	if r != nil {
+		posts = r.Posts(posts)
+	}
+	return posts, meta, err
+}
@@ -3,54 +3,236 @@ package app
 
 import (
 	"fmt"
+	"net/url"
+	"os"
 
 	"github.com/labstack/echo/v4"
 	"github.com/labstack/echo/v4/middleware"
 	echoSwagger "github.com/swaggo/echo-swagger"
 
-	"reddit-ingestion/internal/client"
+	"reddit-ingestion/internal/active"
+	"reddit-ingestion/internal/anonymize"
+	"reddit-ingestion/internal/archive"
+	"reddit-ingestion/internal/audit"
+	"reddit-ingestion/internal/compression"
 	"reddit-ingestion/internal/config"
-	"reddit-ingestion/internal/parser"
+	"reddit-ingestion/internal/dedup"
+	"reddit-ingestion/internal/fakereddit"
+	handler "reddit-ingestion/internal/handler/http"
+	"reddit-ingestion/internal/pagecache"
+	"reddit-ingestion/internal/policy"
+	"reddit-ingestion/internal/quality"
+	"reddit-ingestion/internal/resultcache"
 	"reddit-ingestion/internal/router"
-	"reddit-ingestion/internal/scraper"
+	"reddit-ingestion/internal/scrub"
+	"reddit-ingestion/internal/sink"
+	"reddit-ingestion/internal/sink/kafka"
+	"reddit-ingestion/internal/stats"
+	"reddit-ingestion/pkg/client"
+	"reddit-ingestion/pkg/parser"
+	"reddit-ingestion/pkg/scraper"
 )
 
 type App struct {
 	Config  *config.Config
+	Live    *config.Live
 	Echo    *echo.Echo
 	Service scraper.ScraperService
 	Client  *client.RedditClient
 	Parser  parser.Parser
+	Sink    sink.Sink
+
+	Blocklist  *policy.Blocklist
+	Categories *parser.FlairCategories
+	Policies   *scraper.CrawlPolicies
+	Scrubber   *scrub.Scrubber
+	Audit      *audit.Logger
+	// In-process fake Reddit served instead of Reddit when REDDIT_FAKE is set
+	Fake *fakereddit.Server
 }
 
-func Initialize() (*App, error) {
+func Initialize() (_ *App, err error) {
 	cfg, err := config.LoadConfig()
 	if err != nil {
 		return nil, fmt.Errorf("failed to load configuration: %w", err)
 	}
+	fake := StartFakeReddit(cfg)
+	// Everything started is stopped again when a later step fails
+	defer func() {
+		if err != nil && fake != nil {
+			fake.Close()
+		}
+	}()
 
-	redditClient, err := client.NewRedditClient(cfg)
+	redditClient, err := client.NewRedditClient(cfg.ClientOptions())
 	if err != nil {
 		return nil, fmt.Errorf("failed to create Reddit client: %w", err)
 	}
+	defer func() {
+		if err != nil {
+			redditClient.Close()
+		}
+	}()
 	
-	redditParser := parser.NewRedditParser()
-	scraperService := scraper.NewScraperService(redditClient, redditParser)
+	var fetcher client.RedditClientInterface = redditClient
+	var archiveStore archive.ObjectStore
+	if cfg.ArchiveBackend != "" {
+		archiveStore, err = NewArchiveStore(cfg)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create raw archive: %w", err)
+		}
+		archiveOptions, err := ArchiveOptions(cfg)
+		if err != nil {
+			return nil, err
+		}
+		fetcher = archive.WrapClientWithOptions(fetcher, archiveStore, cfg.ArchivePrefix, archiveOptions)
+		fmt.Printf("Archiving raw Reddit responses to %s backend\n", cfg.ArchiveBackend)
+	}
+
+	if cfg.PageCacheDir != "" {
+		fetcher, err = pagecache.WrapClient(fetcher, cfg.PageCacheDir)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create page cache: %w", err)
+		}
+		fmt.Printf("Caching raw pages in %s\n", cfg.PageCacheDir)
+	}
+
+	parserOptions, err := ParserOptions(cfg)
+	if err != nil {
+		return nil, err
+	}
+	redditParser := parser.NewRedditParserWithOptions(parserOptions)
+	scraperOptions, err := ScraperOptions(cfg)
+	if err != nil {
+		return nil, err
+	}
+	scraperOptions.Throttle = redditClient.Throttle()
+	scraperService := scraper.NewScraperServiceWithOptions(fetcher, redditParser, scraperOptions)
+	// Inside the blocklist, audit log and sink, so a cached or shared result is
+	// checked, logged and forwarded like a fresh one
+	if cfg.ResultCacheTTL > 0 {
+		scraperService = resultcache.WrapService(scraperService, resultcache.New(cfg.ResultCacheTTL, cfg.ResultCacheMaxEntries))
+		fmt.Printf("Reusing scrape results for %v\n", cfg.ResultCacheTTL)
+	}
+	// Outside the cache, so requests arriving together fill it with one scrape
+	scraperService = resultcache.ShareInFlight(scraperService)
+
+	auditLogger, err := NewAuditLogger(cfg)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err != nil {
+			auditLogger.Close()
+		}
+	}()
+
+	// The blocklist sits inside the sink so blocked content is never forwarded
+	blocklist := policy.NewBlocklist(cfg.BlockedSubreddits, cfg.BlockedUsers)
+	if err := blocklist.UseFile(cfg.BlocklistFile); err != nil {
+		return nil, fmt.Errorf("invalid BLOCKLIST_FILE: %w", err)
+	}
+	scraperService = policy.WrapServiceWithAudit(scraperService, blocklist, auditLogger)
+
+	// Hashed before scrubbing, so redacting a repost does not hide it
+	scraperService = dedup.WrapService(scraperService)
+
+	// Scrubbed inside the sink as well, so personal data is never stored
+	scrubber, err := NewScrubber(cfg)
+	if err != nil {
+		return nil, err
+	}
+	if scrubber != nil {
+		scraperService = scrub.WrapService(scraperService, scrubber)
+		fmt.Println("Scrubbing personal data from scraped text")
+	}
+	if cfg.AnonymizeAuthors {
+		scraperService = anonymize.WrapService(scraperService, true)
+		fmt.Println("Replacing usernames with pseudonyms in every scrape")
+	}
+
+	dataSink, err := NewSink(cfg)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err != nil {
+			dataSink.Close()
+		}
+	}()
+	if len(cfg.KafkaBrokers) > 0 {
+		scraperService = sink.WrapService(scraperService, dataSink)
+	}
+	qualityTracker := quality.NewTracker()
+	scraperService = quality.WrapService(scraperService, qualityTracker)
+	statsRegistry := stats.NewRegistry()
+	scraperService = stats.WrapService(scraperService, statsRegistry)
+	activeRegistry := active.NewRegistry()
+	scraperService = active.WrapService(scraperService, activeRegistry)
+	// Outside the sink, so anonymize=true only changes the response
+	if !cfg.AnonymizeAuthors {
+		scraperService = anonymize.WrapService(scraperService, false)
+	}
 	
+	compressOpts, err := CompressOptions(cfg)
+	if err != nil {
+		return nil, err
+	}
+
 	e := echo.New()
 	e.Use(middleware.Logger())
 	e.Use(middleware.Recover())
 	e.Use(middleware.CORS())
+	e.Use(handler.CompressMiddleware(compressOpts))
 	e.GET("/swagger/*", echoSwagger.WrapHandler)
 	
-	router.NewRouter(e, scraperService)
+	limits := handler.Limits{
+		DefaultPostLimit:    cfg.DefaultPostLimit,
+		DefaultCommentLimit: cfg.DefaultCommentLimit,
+		MaxLimit:            cfg.MaxLimit,
+	}
+	router.NewRouterWithLimits(e, scraperService, limits,
+		audit.Middleware(auditLogger, cfg.RequirePurpose),
+		handler.ProxyPoolMiddleware(redditClient.HasProxyPool))
+
+	// Only the Kafka sink counts its deliveries
+	sinkStats, _ := dataSink.(handler.SinkReporter)
+	live := config.NewLive(cfg)
+	adminOpts := router.AdminOptions{Key: cfg.AdminAPIKey, Config: live, Bandwidth: redditClient, Usage: redditClient, Quality: qualityTracker, Active: activeRegistry, Blocklist: blocklist, Sink: sinkStats}
+	if archiveStore != nil {
+		replaySink := dataSink
+		if scrubber != nil {
+			replaySink = scrub.WrapSink(dataSink, scrubber)
+		}
+		adminOpts.Replayer = archive.NewReplayer(archiveStore, redditParser, replaySink)
+	}
+	router.NewAdminRouter(e, adminOpts)
+	if cfg.AdminAPIKey != "" {
+		router.NewRawRouter(e, redditClient, cfg.RawPathAllowlist,
+			handler.AdminKeyMiddleware(cfg.AdminAPIKey),
+			audit.Middleware(auditLogger, cfg.RequirePurpose),
+			handler.ProxyPoolMiddleware(redditClient.HasProxyPool))
+	} else {
+		fmt.Println("GET /raw, GET /admin/config, archive replay, the blocklist changes and scrape cancellation are disabled, set ADMIN_API_KEY to enable them")
+	}
+	router.NewStatsRouter(e, statsRegistry, redditClient, sinkStats)
+	router.NewHealthRouter(e, redditClient)
 	
 	return &App{
 		Config:  cfg,
+		Live:    live,
 		Echo:    e,
 		Service: scraperService,
 		Client:  redditClient,
 		Parser:  redditParser,
+		Sink:    dataSink,
+
+		Blocklist:  blocklist,
+		Categories: parserOptions.Categories,
+		Policies:   scraperOptions.Policies,
+		Scrubber:   scrubber,
+		Audit:      auditLogger,
+		Fake:       fake,
 	}, nil
 }
 
@@ -60,4 +242,234 @@ func (a *App) Start() error {
 		port = "8080"
 	}
 	return a.Echo.Start(":" + port)
+}
+
+// StartFakeReddit starts the fake Reddit of REDDIT_FAKE and points cfg at it,
+// or returns nil when REDDIT_FAKE is off. The caller closes it.
+func StartFakeReddit(cfg *config.Config) *fakereddit.Server {
+	if !cfg.FakeReddit {
+		return nil
+	}
+	fake := fakereddit.NewServer(fakereddit.Options{
+		Latency:   cfg.FakeRedditLatency,
+		ErrorRate: cfg.FakeRedditErrorRate,
+	})
+	useFakeReddit(cfg, fake)
+	fmt.Printf("Serving Reddit from a fake at %s, proxies are not used\n", fake.URL)
+	return fake
+}
+
+// useFakeReddit points cfg at fake, or at Reddit when fake is nil
+func useFakeReddit(cfg *config.Config, fake *fakereddit.Server) {
+	cfg.FakeReddit = fake != nil
+	if fake != nil {
+		cfg.RedditBaseURL = fake.URL
+		cfg.RedditAPIURL = fake.URL
+		// Falling back to Reddit would reach it without proxies
+		cfg.RedditEndpointURLs = nil
+		cfg.RedditFallbackURLs = nil
+	}
+}
+
+// ScraperOptions maps configuration onto scraper tuning options and reads the
+// crawl policies of CRAWL_POLICIES_FILE
+func ScraperOptions(cfg *config.Config) (scraper.ScraperOptions, error) {
+	opts := scraper.DefaultScraperOptions()
+	opts.UserWindowWorkers = cfg.UserWindowWorkers
+	opts.EmptyPageRetries = cfg.EmptyPageRetries
+	opts.Backfill = cfg.Backfill
+	opts.Expansion = scraper.ExpansionOptions{
+		Workers:     cfg.ExpansionWorkers,
+		BatchSize:   cfg.ExpansionBatchSize,
+		Concurrency: cfg.ExpansionConcurrency,
+	}
+	opts.Proxies = len(cfg.ProxyURLs) + len(cfg.ProxySessionGateways)*cfg.ProxySessionsPerGateway
+	opts.StickyProxySessions = cfg.ProxyAffinity != "request"
+	policies, err := scraper.ReadCrawlPolicies(cfg.CrawlPoliciesFile)
+	if err != nil {
+		return scraper.ScraperOptions{}, fmt.Errorf("invalid CRAWL_POLICIES_FILE: %w", err)
+	}
+	opts.Policies = scraper.NewCrawlPolicies(policies)
+	return opts, nil
+}
+
+// ParserOptions links posts to REDDIT_CANONICAL_HOST, records the host of
+// REDDIT_BASE_URL as their source and categorizes flairs with
+// FLAIR_CATEGORIES_FILE
+func ParserOptions(cfg *config.Config) (parser.ParserOptions, error) {
+	opts := parser.DefaultParserOptions()
+	if cfg.CanonicalHost != "" {
+		opts.CanonicalHost = cfg.CanonicalHost
+	}
+	if baseURL, err := url.Parse(cfg.RedditBaseURL); err == nil {
+		opts.SourceHost = baseURL.Hostname()
+	}
+	categories, err := parser.ReadFlairCategories(cfg.FlairCategoriesFile)
+	if err != nil {
+		return parser.ParserOptions{}, fmt.Errorf("invalid FLAIR_CATEGORIES_FILE: %w", err)
+	}
+	opts.Categories = parser.NewFlairCategories(categories)
+	return opts, nil
+}
+
+// NewScrubber builds the scrubber enabled by SCRUB_PII with the patterns of
+// SCRUB_PATTERNS_FILE, or returns nil when scrubbing is off
+func NewScrubber(cfg *config.Config) (*scrub.Scrubber, error) {
+	if !cfg.ScrubPII {
+		return nil, nil
+	}
+	patterns, err := scrub.ReadPatterns(cfg.ScrubPatternsFile)
+	if err != nil {
+		return nil, fmt.Errorf("invalid SCRUB_PATTERNS_FILE: %w", err)
+	}
+	scrubber, err := scrub.NewScrubber(patterns)
+	if err != nil {
+		return nil, fmt.Errorf("invalid SCRUB_PATTERNS_FILE: %w", err)
+	}
+	return scrubber, nil
+}
+
+// NewAuditLogger opens AUDIT_LOG_PATH, or logs audit entries to stdout when it is unset
+func NewAuditLogger(cfg *config.Config) (*audit.Logger, error) {
+	if cfg.AuditLogPath == "" {
+		return audit.NewLogger(os.Stdout), nil
+	}
+	return audit.NewFileLogger(cfg.AuditLogPath)
+}
+
+// NewSink builds the configured data sink: Kafka when KAFKA_BROKERS is set, otherwise a NopSink
+func NewSink(cfg *config.Config) (sink.Sink, error) {
+	if len(cfg.KafkaBrokers) == 0 {
+		return sink.NopSink{}, nil
+	}
+	producer, err := kafka.NewProducer(kafka.Config{
+		Brokers:           cfg.KafkaBrokers,
+		PostsTopic:        cfg.KafkaPostsTopic,
+		CommentsTopic:     cfg.KafkaCommentsTopic,
+		UserActivityTopic: cfg.KafkaUserActivityTopic,
+		BatchSize:         cfg.KafkaBatchSize,
+		BatchTimeout:      cfg.KafkaBatchTimeout,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Kafka sink: %w", err)
+	}
+	return producer, nil
+}
+
+// CompressOptions maps the RESPONSE_COMPRESSION settings onto the options of
+// the compression middleware
+func CompressOptions(cfg *config.Config) (handler.CompressOptions, error) {
+	opts := handler.CompressOptions{
+		Compression: cfg.ResponseCompression,
+		GzipLevel:   cfg.ResponseGzipLevel,
+		BrotliLevel: cfg.ResponseBrotliLevel,
+		MinSize:     cfg.ResponseCompressionMinSize,
+	}
+	if err := opts.Validate(); err != nil {
+		return handler.CompressOptions{}, fmt.Errorf("invalid RESPONSE_COMPRESSION settings: %w", err)
+	}
+	return opts, nil
+}
+
+// ArchiveOptions maps the ARCHIVE_COMPRESSION settings onto archive options
+func ArchiveOptions(cfg *config.Config) (archive.Options, error) {
+	codec, err := compression.Parse(cfg.ArchiveCompression)
+	if err != nil {
+		return archive.Options{}, fmt.Errorf("invalid ARCHIVE_COMPRESSION: %w", err)
+	}
+	if err := codec.CheckLevel(cfg.ArchiveCompressionLevel); err != nil {
+		return archive.Options{}, fmt.Errorf("invalid ARCHIVE_COMPRESSION_LEVEL: %w", err)
+	}
+	return archive.Options{Compression: codec, Level: cfg.ArchiveCompressionLevel}, nil
+}
+
+// NewArchiveStore builds the object store selected by ARCHIVE_BACKEND
+func NewArchiveStore(cfg *config.Config) (archive.ObjectStore, error) {
+	switch cfg.ArchiveBackend {
+	case "file":
+		return archive.NewFileStore(cfg.ArchiveDir)
+	case "s3":
+		codec, err := compression.Parse(cfg.ArchiveCompression)
+		if err != nil {
+			return nil, fmt.Errorf("invalid ARCHIVE_COMPRESSION: %w", err)
+		}
+		return archive.NewS3Store(archive.S3Config{
+			Endpoint:    cfg.ArchiveS3Endpoint,
+			Region:      cfg.ArchiveS3Region,
+			Bucket:      cfg.ArchiveS3Bucket,
+			AccessKey:   cfg.ArchiveS3AccessKey,
+			SecretKey:   cfg.ArchiveS3SecretKey,
+			Compression: codec,
+		})
+	default:
+		return nil, fmt.Errorf("unsupported ARCHIVE_BACKEND %q, must be file or s3", cfg.ArchiveBackend)
+	}
+}
+
+// Reload re-reads the configuration and applies the settings that can change
+// without a restart: proxy list, retry count, user agent, blocklist, flair
+// categories, crawl policies and scrub patterns. On error the running
+// configuration is left untouched.
+func (a *App) Reload() error {
+	cfg, err := config.ReloadConfig()
+	if err != nil {
+		return fmt.Errorf("failed to reload configuration: %w", err)
+	}
+	categories, err := parser.ReadFlairCategories(cfg.FlairCategoriesFile)
+	if err != nil {
+		return fmt.Errorf("invalid FLAIR_CATEGORIES_FILE: %w", err)
+	}
+	policies, err := scraper.ReadCrawlPolicies(cfg.CrawlPoliciesFile)
+	if err != nil {
+		return fmt.Errorf("invalid CRAWL_POLICIES_FILE: %w", err)
+	}
+	var scrubPatterns map[string]string
+	if a.Scrubber != nil {
+		scrubPatterns, err = scrub.ReadPatterns(cfg.ScrubPatternsFile)
+		if err == nil {
+			_, err = scrub.NewScrubber(scrubPatterns)
+		}
+		if err != nil {
+			return fmt.Errorf("invalid SCRUB_PATTERNS_FILE: %w", err)
+		}
+	}
+	// Validated on a scratch list so a broken file leaves the entries in force
+	if err := policy.NewBlocklist(nil, nil).UseFile(cfg.BlocklistFile); err != nil {
+		return fmt.Errorf("invalid BLOCKLIST_FILE: %w", err)
+	}
+
+	// The fake Reddit is started or stopped only by a restart
+	useFakeReddit(cfg, a.Fake)
+
+	if err := a.Client.Reload(cfg.ClientOptions()); err != nil {
+		return err
+	}
+
+	a.Blocklist.Set(cfg.BlockedSubreddits, cfg.BlockedUsers)
+	a.Blocklist.UseFile(cfg.BlocklistFile)
+	a.Categories.Set(categories)
+	a.Policies.Set(policies)
+	if a.Scrubber != nil {
+		a.Scrubber.Set(scrubPatterns)
+	}
+	a.Live.Set(cfg)
+	fmt.Printf("Configuration reloaded: %d proxies\n", len(cfg.ProxyURLs))
+	return nil
+}
+
+// Close releases resources held outside the HTTP server, flushing sinks and
+// the proxy usage file
+func (a *App) Close() error {
+	err := a.Sink.Close()
+	if auditErr := a.Audit.Close(); err == nil {
+		err = auditErr
+	}
+	if usageErr := a.Client.SaveUsage(); err == nil {
+		err = usageErr
+	}
+	a.Client.Close()
+	if a.Fake != nil {
+		a.Fake.Close()
+	}
+	return err
 }
\ No newline at end of file
@@ -0,0 +1,158 @@
+// internal/archive/client.go
+package archive
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"reddit-ingestion/internal/compression"
+	"reddit-ingestion/pkg/client"
+)
+
+// Page kinds encoded in archive keys, used to pick a parser on replay
+const (
+	KindListing      = "listing"
+	KindPost         = "post"
+	KindUserAbout    = "user_about"
+	KindUserPosts    = "user_posts"
+	KindUserComments = "user_comments"
+	KindUserOverview = "user_overview"
+	KindSearch       = "search"
+	KindMoreChildren = "morechildren"
+	KindOther        = "other"
+)
+
+// Options controls how raw pages are stored
+type Options struct {
+	// Compression of each object; the key's extension names it
+	Compression compression.Codec
+	// Compression level, 0 for the codec's default
+	Level int
+}
+
+// DefaultOptions returns the options used by WrapClient: gzip at its default level
+func DefaultOptions() Options {
+	return Options{Compression: compression.Gzip}
+}
+
+// archivingClient writes every raw response to an ObjectStore before it is
+// handed to the parser
+type archivingClient struct {
+	client.RedditClientInterface
+	store  ObjectStore
+	prefix string
+	opts   Options
+}
+
+// WrapClient returns a client that archives raw JSON to store under prefix.
+// Archive failures are logged and never fail the fetch.
+func WrapClient(c client.RedditClientInterface, store ObjectStore, prefix string) client.RedditClientInterface {
+	return WrapClientWithOptions(c, store, prefix, DefaultOptions())
+}
+
+// WrapClientWithOptions is WrapClient with explicit storage options
+func WrapClientWithOptions(c client.RedditClientInterface, store ObjectStore, prefix string, opts Options) client.RedditClientInterface {
+	return &archivingClient{
+		RedditClientInterface: c,
+		store:                 store,
+		prefix:                strings.Trim(prefix, "/"),
+		opts:                  opts,
+	}
+}
+
+func (a *archivingClient) FetchJSON(ctx context.Context, url string) (json.RawMessage, error) {
+	data, err := a.RedditClientInterface.FetchJSON(ctx, url)
+	if err == nil {
+		partition, kind := classifyURL(url)
+		a.archive(ctx, a.objectKey(partition, kind), data)
+	}
+	return data, err
+}
+
+func (a *archivingClient) FetchMoreComments(ctx context.Context, postID string, commentIDs []string) (json.RawMessage, error) {
+	data, err := a.RedditClientInterface.FetchMoreComments(ctx, postID, commentIDs)
+	if err == nil && len(data) > 0 {
+		partition := "morechildren/" + strings.TrimPrefix(postID, "t3_")
+		a.archive(ctx, a.objectKey(partition, KindMoreChildren), data)
+	}
+	return data, err
+}
+
+func (a *archivingClient) objectKey(partition, kind string) string {
+	return objectKey(a.prefix, time.Now(), partition, kind, a.opts.Compression)
+}
+
+func (a *archivingClient) archive(ctx context.Context, key string, data json.RawMessage) {
+	var buf bytes.Buffer
+	w, err := compression.NewWriter(&buf, a.opts.Compression, a.opts.Level)
+	if err != nil {
+		fmt.Printf("Archive compression failed for %s: %v\n", key, err)
+		return
+	}
+	if _, err := w.Write(data); err != nil {
+		fmt.Printf("Archive compression failed for %s: %v\n", key, err)
+		return
+	}
+	if err := w.Close(); err != nil {
+		fmt.Printf("Archive compression failed for %s: %v\n", key, err)
+		return
+	}
+
+	if err := a.store.Put(ctx, key, buf.Bytes()); err != nil {
+		fmt.Printf("Archive write failed for %s: %v\n", key, err)
+	}
+}
+
+// Key derives the archive key for a fetched Reddit URL. Keys are laid out
+// as <prefix>/<yyyy>/<mm>/<dd>/<partition>/<kind>/<unix-nanos>.json.gz where
+// the partition is the subreddit, user or post the page belongs to. Objects
+// stored with another compression end in its extension instead of .gz.
+func Key(prefix, rawURL string, fetchedAt time.Time) string {
+	partition, kind := classifyURL(rawURL)
+	return objectKey(prefix, fetchedAt, partition, kind, compression.Gzip)
+}
+
+func objectKey(prefix string, fetchedAt time.Time, partition, kind string, codec compression.Codec) string {
+	fetchedAt = fetchedAt.UTC()
+	key := fmt.Sprintf("%s/%s/%s/%d.json%s", fetchedAt.Format("2006/01/02"), partition, kind, fetchedAt.UnixNano(), codec.Extension())
+	if prefix != "" {
+		key = prefix + "/" + key
+	}
+	return key
+}
+
+func classifyURL(rawURL string) (string, string) {
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		return "other", KindOther
+	}
+
+	segments := strings.Split(strings.Trim(parsed.Path, "/"), "/")
+	switch {
+	case len(segments) >= 2 && segments[0] == "r":
+		return "r/" + strings.ToLower(segments[1]), KindListing
+	case len(segments) >= 3 && segments[0] == "user":
+		partition := "user/" + strings.ToLower(segments[1])
+		switch {
+		case strings.HasPrefix(segments[2], "about"):
+			return partition, KindUserAbout
+		case segments[2] == "submitted":
+			return partition, KindUserPosts
+		case segments[2] == "comments":
+			return partition, KindUserComments
+		case strings.HasPrefix(segments[2], "overview"):
+			return partition, KindUserOverview
+		}
+		return partition, KindOther
+	case len(segments) >= 2 && segments[0] == "comments":
+		return "post/" + strings.TrimSuffix(segments[1], ".json"), KindPost
+	case len(segments) >= 1 && segments[0] == "search.json":
+		return "search", KindSearch
+	}
+	return "other", KindOther
+}
@@ -0,0 +1,205 @@
+// internal/archive/replay.go
+package archive
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"reddit-ingestion/internal/compression"
+	"reddit-ingestion/internal/sink"
+	"reddit-ingestion/pkg/models"
+	"reddit-ingestion/pkg/parser"
+)
+
+// ReplayResult summarizes a replay run
+type ReplayResult struct {
+	Prefix   string   `json:"prefix"`
+	Objects  int      `json:"objects"`
+	Posts    int      `json:"posts"`
+	Comments int      `json:"comments"`
+	Users    int      `json:"users"`
+	Skipped  int      `json:"skipped"`
+	Errors   []string `json:"errors,omitempty"`
+}
+
+// maxReplayErrors caps how many per-object errors a ReplayResult carries
+const maxReplayErrors = 20
+
+// Replayer re-parses archived raw pages with the current parser and writes
+// the resulting models to a sink, so parser improvements can be backfilled
+// without fetching from Reddit again
+type Replayer struct {
+	store  ObjectStore
+	parser parser.ParserInterface
+	sink   sink.Sink
+}
+
+func NewReplayer(store ObjectStore, p parser.ParserInterface, s sink.Sink) *Replayer {
+	return &Replayer{
+		store:  store,
+		parser: p,
+		sink:   s,
+	}
+}
+
+// Replay processes every archived object whose key starts with prefix.
+// Objects that fail to decode or parse are counted and reported, not fatal.
+func (r *Replayer) Replay(ctx context.Context, prefix string) (ReplayResult, error) {
+	result := ReplayResult{Prefix: prefix}
+
+	keys, err := r.store.List(ctx, prefix)
+	if err != nil {
+		return result, fmt.Errorf("list archive: %w", err)
+	}
+
+	fmt.Printf("Replaying %d archived objects under %q\n", len(keys), prefix)
+
+	for _, key := range keys {
+		if ctx.Err() != nil {
+			return result, ctx.Err()
+		}
+
+		result.Objects++
+		if err := r.replayObject(ctx, key, &result); err != nil {
+			result.Skipped++
+			if len(result.Errors) < maxReplayErrors {
+				result.Errors = append(result.Errors, fmt.Sprintf("%s: %v", key, err))
+			}
+		}
+	}
+
+	fmt.Printf("Replay finished: %d objects, %d posts, %d comments, %d users, %d skipped\n",
+		result.Objects, result.Posts, result.Comments, result.Users, result.Skipped)
+	return result, nil
+}
+
+func (r *Replayer) replayObject(ctx context.Context, key string, result *ReplayResult) error {
+	compressed, err := r.store.Get(ctx, key)
+	if err != nil {
+		return err
+	}
+
+	// The key's extension tells how the object was compressed
+	dec, err := compression.NewReader(bytes.NewReader(compressed), compression.FromName(key))
+	if err != nil {
+		return fmt.Errorf("decompress: %w", err)
+	}
+	data, err := io.ReadAll(dec)
+	dec.Close()
+	if err != nil {
+		return fmt.Errorf("decompress: %w", err)
+	}
+
+	partition, kind := partitionFromKey(key)
+
+	switch kind {
+	case KindListing, KindSearch:
+		posts, _, err := r.parser.ParseSubreddit(ctx, data)
+		if err != nil {
+			return err
+		}
+		result.Posts += len(posts)
+		return r.sink.WritePosts(ctx, posts)
+
+	case KindPost:
+		var raw []json.RawMessage
+		if err := json.Unmarshal(data, &raw); err != nil || len(raw) < 2 {
+			return fmt.Errorf("invalid post JSON format: %w", err)
+		}
+		detail, err := r.parser.ParsePost(ctx, raw[0], raw[1])
+		if err != nil {
+			return err
+		}
+		result.Posts++
+		result.Comments += countComments(detail.Comments)
+		if err := r.sink.WritePosts(ctx, []models.Post{detail.Post}); err != nil {
+			return err
+		}
+		return r.sink.WriteComments(ctx, detail.Post.ID, detail.Comments)
+
+	case KindMoreChildren:
+		comments, err := r.parser.ParseMoreComments(ctx, data)
+		if err != nil {
+			return err
+		}
+		result.Comments += len(comments)
+		return r.sink.WriteComments(ctx, strings.TrimPrefix(partition, "morechildren/"), comments)
+
+	case KindUserAbout:
+		info, err := r.parser.ParseUserInfo(ctx, data)
+		if err != nil {
+			return err
+		}
+		result.Users++
+		return r.sink.WriteUserActivity(ctx, models.UserActivity{UserInfo: info})
+
+	case KindUserPosts:
+		posts, _, err := r.parser.ParseUserPosts(ctx, data)
+		if err != nil {
+			return err
+		}
+		result.Posts += len(posts)
+		return r.sink.WriteUserActivity(ctx, models.UserActivity{
+			UserInfo: models.UserInfo{Username: strings.TrimPrefix(partition, "user/")},
+			Posts:    posts,
+		})
+
+	case KindUserComments:
+		comments, _, err := r.parser.ParseUserComments(ctx, data)
+		if err != nil {
+			return err
+		}
+		result.Comments += len(comments)
+		return r.sink.WriteUserActivity(ctx, models.UserActivity{
+			UserInfo: models.UserInfo{Username: strings.TrimPrefix(partition, "user/")},
+			Comments: comments,
+		})
+
+	case KindUserOverview:
+		items, _, err := r.parser.ParseUserOverview(ctx, data)
+		if err != nil {
+			return err
+		}
+		overview := models.UserOverview{Username: strings.TrimPrefix(partition, "user/"), Items: items}
+		activity := overview.Activity()
+		result.Posts += len(activity.Posts)
+		result.Comments += len(activity.Comments)
+		return r.sink.WriteUserActivity(ctx, activity)
+	}
+
+	return fmt.Errorf("unknown page kind %q", kind)
+}
+
+// partitionFromKey recovers the partition and page kind from a key built by
+// objectKey: .../<yyyy>/<mm>/<dd>/<partition...>/<kind>/<file>
+func partitionFromKey(key string) (string, string) {
+	segments := strings.Split(key, "/")
+	if len(segments) < 3 {
+		return "", KindOther
+	}
+	kind := segments[len(segments)-2]
+
+	// The partition sits between the date and the kind; the date is the
+	// first run of yyyy/mm/dd segments
+	for i := 0; i+3 < len(segments)-2; i++ {
+		if len(segments[i]) == 4 && len(segments[i+1]) == 2 && len(segments[i+2]) == 2 {
+			return strings.Join(segments[i+3:len(segments)-2], "/"), kind
+		}
+	}
+	return "", kind
+}
+
+func countComments(comments []models.Comment) int {
+	count := 0
+	for _, comment := range comments {
+		if !comment.IsMore {
+			count++
+		}
+		count += countComments(comment.Replies)
+	}
+	return count
+}
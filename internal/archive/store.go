@@ -0,0 +1,86 @@
+// internal/archive/store.go
+package archive
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// ObjectStore is the minimal blob storage the archive writes raw pages to
+// and replays them from
+type ObjectStore interface {
+	Put(ctx context.Context, key string, body []byte) error
+	Get(ctx context.Context, key string) ([]byte, error)
+	// List returns all keys starting with prefix in lexical order
+	List(ctx context.Context, prefix string) ([]string, error)
+}
+
+// FileStore writes objects below a local directory, mirroring the key layout
+type FileStore struct {
+	root string
+}
+
+func NewFileStore(root string) (*FileStore, error) {
+	if root == "" {
+		return nil, fmt.Errorf("archive directory must be provided")
+	}
+	if err := os.MkdirAll(root, 0755); err != nil {
+		return nil, fmt.Errorf("create archive directory: %w", err)
+	}
+	return &FileStore{root: root}, nil
+}
+
+func (f *FileStore) Put(ctx context.Context, key string, body []byte) error {
+	path := filepath.Join(f.root, filepath.FromSlash(key))
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("create archive partition: %w", err)
+	}
+	if err := os.WriteFile(path, body, 0644); err != nil {
+		return fmt.Errorf("write archive object: %w", err)
+	}
+	return nil
+}
+
+func (f *FileStore) Get(ctx context.Context, key string) ([]byte, error) {
+	data, err := os.ReadFile(filepath.Join(f.root, filepath.FromSlash(key)))
+	if err != nil {
+		return nil, fmt.Errorf("read archive object: %w", err)
+	}
+	return data, nil
+}
+
+func (f *FileStore) List(ctx context.Context, prefix string) ([]string, error) {
+	var keys []string
+	err := filepath.WalkDir(f.root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if d.IsDir() {
+			return nil
+		}
+
+		rel, err := filepath.Rel(f.root, path)
+		if err != nil {
+			return err
+		}
+		key := filepath.ToSlash(rel)
+		if strings.HasPrefix(key, prefix) {
+			keys = append(keys, key)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("list archive objects: %w", err)
+	}
+
+	sort.Strings(keys)
+	return keys, nil
+}
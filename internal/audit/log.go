@@ -0,0 +1,87 @@
+// internal/audit/log.go
+package audit
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// Entry is one audited API request
+type Entry struct {
+	Time       time.Time         `json:"time"`
+	Method     string            `json:"method"`
+	Path       string            `json:"path"`
+	Params     map[string]string `json:"params,omitempty"`
+	Purpose    string            `json:"purpose,omitempty"`
+	RemoteIP   string            `json:"remote_ip"`
+	Status     int               `json:"status"`
+	DurationMS int64             `json:"duration_ms"`
+}
+
+// Refusal is a scrape refused by the blocklist. It is logged next to the
+// entry of the request, with event "refused" telling the two apart.
+type Refusal struct {
+	Time      time.Time `json:"time"`
+	Event     string    `json:"event"`
+	Operation string    `json:"operation"`
+	Target    string    `json:"target"`
+	Reason    string    `json:"reason"`
+	Purpose   string    `json:"purpose,omitempty"`
+}
+
+// Logger appends audit entries as JSON lines
+type Logger struct {
+	mutex sync.Mutex
+	w     io.Writer
+	close func() error
+}
+
+func NewLogger(w io.Writer) *Logger {
+	return &Logger{w: w, close: func() error { return nil }}
+}
+
+// NewFileLogger appends to the file at path, creating it if needed
+func NewFileLogger(path string) (*Logger, error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o640)
+	if err != nil {
+		return nil, fmt.Errorf("open audit log: %w", err)
+	}
+	return &Logger{w: f, close: f.Close}, nil
+}
+
+func (l *Logger) Log(entry Entry) error {
+	return l.write(entry)
+}
+
+// LogRefusal records a refused scrape; a nil Logger records nothing
+func (l *Logger) LogRefusal(refusal Refusal) error {
+	if l == nil {
+		return nil
+	}
+	refusal.Event = "refused"
+	if refusal.Time.IsZero() {
+		refusal.Time = time.Now().UTC()
+	}
+	return l.write(refusal)
+}
+
+func (l *Logger) write(v interface{}) error {
+	line, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("marshal audit entry: %w", err)
+	}
+	line = append(line, '\n')
+
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	_, err = l.w.Write(line)
+	return err
+}
+
+func (l *Logger) Close() error {
+	return l.close()
+}
@@ -0,0 +1,73 @@
+// internal/audit/middleware.go
+package audit
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"reddit-ingestion/pkg/models"
+)
+
+// PurposeHeader can carry the purpose instead of the purpose query parameter
+const PurposeHeader = "X-Scrape-Purpose"
+
+// Middleware records every request in the audit log together with its declared
+// purpose, taken from the purpose query parameter or the X-Scrape-Purpose
+// header. With requirePurpose set, requests without one are rejected with 400.
+func Middleware(logger *Logger, requirePurpose bool) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			start := time.Now()
+
+			purpose := strings.TrimSpace(c.QueryParam("purpose"))
+			if purpose == "" {
+				purpose = strings.TrimSpace(c.Request().Header.Get(PurposeHeader))
+			}
+
+			var err error
+			if purpose == "" && requirePurpose {
+				err = echo.NewHTTPError(http.StatusBadRequest, models.ValidationError{
+					Message: "`purpose` is required",
+					Errors:  []models.FieldError{{Field: "purpose", Message: "is required"}},
+				})
+			} else {
+				req := c.Request()
+				c.SetRequest(req.WithContext(WithPurpose(req.Context(), purpose)))
+				err = next(c)
+			}
+
+			status := c.Response().Status
+			if httpErr, ok := err.(*echo.HTTPError); ok {
+				status = httpErr.Code
+			} else if err != nil {
+				status = http.StatusInternalServerError
+			}
+
+			params := make(map[string]string)
+			for key, values := range c.QueryParams() {
+				if key != "purpose" && len(values) > 0 {
+					params[key] = values[0]
+				}
+			}
+
+			logErr := logger.Log(Entry{
+				Time:       start.UTC(),
+				Method:     c.Request().Method,
+				Path:       c.Path(),
+				Params:     params,
+				Purpose:    purpose,
+				RemoteIP:   c.RealIP(),
+				Status:     status,
+				DurationMS: time.Since(start).Milliseconds(),
+			})
+			if logErr != nil {
+				fmt.Printf("Failed to write audit entry: %v\n", logErr)
+			}
+
+			return err
+		}
+	}
+}
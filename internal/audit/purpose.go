@@ -0,0 +1,21 @@
+// internal/audit/purpose.go
+package audit
+
+import "context"
+
+type purposeKey struct{}
+
+// WithPurpose attaches the declared purpose of a scrape to ctx so it can be
+// recorded downstream, e.g. as a header on sink messages
+func WithPurpose(ctx context.Context, purpose string) context.Context {
+	if purpose == "" {
+		return ctx
+	}
+	return context.WithValue(ctx, purposeKey{}, purpose)
+}
+
+// PurposeFromContext returns the purpose attached by WithPurpose, or ""
+func PurposeFromContext(ctx context.Context) string {
+	purpose, _ := ctx.Value(purposeKey{}).(string)
+	return purpose
+}
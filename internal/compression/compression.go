@@ -0,0 +1,119 @@
+// internal/compression/compression.go
+package compression
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// Codec is a compression format for archives and export files
+type Codec string
+
+const (
+	None Codec = "none"
+	Gzip Codec = "gzip"
+	Zstd Codec = "zstd"
+)
+
+// Parse validates a user supplied codec name; empty means none
+func Parse(s string) (Codec, error) {
+	switch c := Codec(strings.ToLower(strings.TrimSpace(s))); c {
+	case None, Gzip, Zstd:
+		return c, nil
+	case "":
+		return None, nil
+	default:
+		return "", fmt.Errorf("unsupported compression %q, must be none, gzip or zstd", s)
+	}
+}
+
+// FromName returns the codec a file or object name's extension indicates
+func FromName(name string) Codec {
+	switch {
+	case strings.HasSuffix(name, ".gz"):
+		return Gzip
+	case strings.HasSuffix(name, ".zst"):
+		return Zstd
+	default:
+		return None
+	}
+}
+
+// Extension is the suffix files compressed with c carry, e.g. ".gz"
+func (c Codec) Extension() string {
+	switch c {
+	case Gzip:
+		return ".gz"
+	case Zstd:
+		return ".zst"
+	default:
+		return ""
+	}
+}
+
+// CheckLevel validates a level for c. 0 picks the codec's default; gzip takes
+// 1 (fastest) to 9 (smallest), zstd 1 to 22 like the zstd command.
+func (c Codec) CheckLevel(level int) error {
+	max := 0
+	switch c {
+	case Gzip:
+		max = gzip.BestCompression
+	case Zstd:
+		max = 22
+	}
+	if level < 0 || level > max {
+		if max == 0 {
+			return fmt.Errorf("compression level %d needs gzip or zstd", level)
+		}
+		return fmt.Errorf("%s compression level must be between 1 and %d, got %d", c, max, level)
+	}
+	return nil
+}
+
+// NewWriter compresses everything written to the returned writer into w.
+// Close flushes the compressed stream but leaves w open.
+func NewWriter(w io.Writer, c Codec, level int) (io.WriteCloser, error) {
+	if err := c.CheckLevel(level); err != nil {
+		return nil, err
+	}
+	switch c {
+	case Gzip:
+		if level == 0 {
+			level = gzip.DefaultCompression
+		}
+		return gzip.NewWriterLevel(w, level)
+	case Zstd:
+		if level == 0 {
+			return zstd.NewWriter(w)
+		}
+		return zstd.NewWriter(w, zstd.WithEncoderLevel(zstd.EncoderLevelFromZstd(level)))
+	default:
+		return nopCloser{w}, nil
+	}
+}
+
+// NewReader decompresses r as c
+func NewReader(r io.Reader, c Codec) (io.ReadCloser, error) {
+	switch c {
+	case Gzip:
+		return gzip.NewReader(r)
+	case Zstd:
+		dec, err := zstd.NewReader(r)
+		if err != nil {
+			return nil, err
+		}
+		return dec.IOReadCloser(), nil
+	default:
+		return io.NopCloser(r), nil
+	}
+}
+
+type nopCloser struct {
+	io.Writer
+}
+
+func (nopCloser) Close() error { return nil }
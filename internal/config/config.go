@@ -5,25 +5,207 @@ import (
 	"fmt"
 	"net/url"
 	"os"
+	"path"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
 
 	"github.com/joho/godotenv"
+	"reddit-ingestion/pkg/client"
+	"reddit-ingestion/pkg/utils"
 )
 
 type Config struct {
 	ProxyURLs           []string
 	UserAgent           string
+	RandomUserAgents    bool
 	MaxRetries          int
 	DefaultPostLimit    int
 	DefaultCommentLimit int
+	MaxLimit            int
 	ServerPort          string
 	ReadTimeout         time.Duration
 	WriteTimeout        time.Duration
 	RedditBaseURL       string
 	RequestTimeout      time.Duration
 	RateLimitDelay      time.Duration
+
+	// Host of the post URLs the service returns, e.g. reddit.com
+	CanonicalHost string
+
+	// Base URL of Reddit's API host, which serves morechildren
+	RedditAPIURL string
+
+	// Base URLs of the endpoints not read from RedditBaseURL (or
+	// RedditAPIURL), by endpoint name, and the base URLs a failing request
+	// falls back to in turn
+	RedditEndpointURLs map[string]string
+	RedditFallbackURLs []string
+	// Failures in a row after which requests for RedditBaseURL go to the
+	// first fallback, and how often it is probed to send them back
+	RedditFailoverThreshold     int
+	RedditFailoverProbeInterval time.Duration
+
+	// Hosts besides Reddit's and those of the base URLs the client may
+	// fetch, subdomains included
+	ExtraAllowedHosts []string
+
+	// Serve Reddit from an in-process fake instead, with the given latency
+	// and share of failed requests, for tests and demos
+	FakeReddit          bool
+	FakeRedditLatency   time.Duration
+	FakeRedditErrorRate float64
+
+	// Parallel listing windows for full-history user scrapes (1 disables)
+	UserWindowWorkers int
+
+	// Refetches of listing pages that parse to nothing from a sizeable body
+	EmptyPageRetries int
+
+	// Search past Reddit's listing cap when since_timestamp reaches beyond it
+	Backfill bool
+
+	// Worker pools expanding "load more" comments, clamped to the proxy count
+	ExpansionWorkers     int
+	ExpansionBatchSize   int
+	ExpansionConcurrency int
+
+	// Proxy selection: "session" pins each scrape to one proxy, "request" rotates per request
+	ProxyAffinity string
+
+	// Daily traffic cap per proxy in megabytes, 0 for unlimited
+	ProxyDailyBandwidthMB int
+
+	// Residential gateways minting sticky sessions, with {session} where the
+	// session id goes, how many sessions each keeps in rotation and how often
+	// they are replaced by fresh ones
+	ProxySessionGateways    []string
+	ProxySessionsPerGateway int
+	ProxySessionTTL         time.Duration
+
+	// Monthly traffic cap per proxy in megabytes, 0 for unlimited, with
+	// overrides for the proxies carrying a label
+	ProxyMonthlyBandwidthMB   int
+	ProxyMonthlyBandwidthCaps map[string]int
+	// JSON file keeping each proxy's monthly usage across restarts, in
+	// memory only when empty
+	ProxyUsageFile string
+
+	// Adaptive throttling: the window 429/403 rates are measured over (0
+	// disables) and the rate that slows scrapes down
+	ThrottleWindow    time.Duration
+	ThrottleBlockRate float64
+
+	// Largest decoded Reddit response accepted, in megabytes; 0 for unlimited
+	MaxResponseSizeMB int
+
+	// Waits between the attempts of a request: drawn at random up to
+	// RetryBaseDelay doubled per retry, at most RetryMaxDelay, as long as the
+	// request stays within RetryBudget (0 for no time limit)
+	RetryBaseDelay time.Duration
+	RetryMaxDelay  time.Duration
+	RetryBudget    time.Duration
+
+	// How long the cookie jar of each proxy and browser profile is kept;
+	// 0 sends no cookies
+	ProxyCookieTTL time.Duration
+
+	// Kafka sink, enabled when KafkaBrokers is non-empty
+	KafkaBrokers           []string
+	KafkaPostsTopic        string
+	KafkaCommentsTopic     string
+	KafkaUserActivityTopic string
+	KafkaBatchSize         int
+	KafkaBatchTimeout      time.Duration
+
+	// Raw response archive: "" (disabled), "file" or "s3"
+	ArchiveBackend     string
+	ArchiveDir         string
+	ArchivePrefix      string
+	ArchiveS3Endpoint  string
+	ArchiveS3Region    string
+	ArchiveS3Bucket    string
+	ArchiveS3AccessKey string
+	ArchiveS3SecretKey string
+	// Compression of archived pages (gzip, zstd or none) and its level, 0
+	// for the codec's default
+	ArchiveCompression      string
+	ArchiveCompressionLevel int
+
+	// Raw page cache directory, disabled when empty
+	PageCacheDir string
+
+	// How long scrape results are reused for identical requests, disabled
+	// when 0, and how many results are kept
+	ResultCacheTTL        time.Duration
+	ResultCacheMaxEntries int
+
+	// Compression of the API's responses ("gzip", "brotli" or "none"), the
+	// level of each codec, 0 for its default, and the smallest body compressed
+	ResponseCompression        string
+	ResponseGzipLevel          int
+	ResponseBrotliLevel        int
+	ResponseCompressionMinSize int
+
+	// Audit log of API requests (stdout when empty) and whether each scrape
+	// must declare a purpose
+	AuditLogPath   string
+	RequirePurpose bool
+
+	// Key the admin-only GET /raw passthrough requires, which is off when
+	// empty, and the Reddit paths it may fetch, as path.Match patterns
+	AdminAPIKey      string
+	RawPathAllowlist []string
+
+	// HMAC keys for pseudonymizing usernames in anonymized exports, newest
+	// first, and the public key pseudonym mappings are sealed to
+	AnonymizeKey              string
+	AnonymizeMappingPublicKey string
+
+	// Replace usernames with per-response pseudonyms in every scrape, stored
+	// results included
+	AnonymizeAuthors bool
+
+	// Subreddits and usernames the service refuses to scrape
+	BlockedSubreddits []string
+	BlockedUsers      []string
+
+	// JSON file keeping the blocklist entries managed through the admin API,
+	// in memory only when empty
+	BlocklistFile string
+
+	// Redact emails, phone numbers and u/name mentions from scraped text,
+	// with extra or overriding patterns from a JSON file
+	ScrubPII          bool
+	ScrubPatternsFile string
+
+	// JSON file mapping each subreddit's flairs onto normalized categories,
+	// none when empty
+	FlairCategoriesFile string
+
+	// JSON file with the crawl policy of each subreddit, none when empty
+	CrawlPoliciesFile string
+}
+
+// DefaultFallbackURLs are where requests go when their Reddit host fails and
+// REDDIT_FALLBACK_URLS is unset: www.reddit.com serves the same JSON as
+// old.reddit.com
+var DefaultFallbackURLs = []string{"https://www.reddit.com"}
+
+// DefaultRawPathAllowlist are the paths GET /raw may fetch when
+// RAW_PATH_ALLOWLIST is unset: the JSON endpoints the scraper reads
+var DefaultRawPathAllowlist = []string{
+	"/*.json",
+	"/r/*/*.json",
+	"/r/*/comments/*.json",
+	"/r/*/comments/*/*.json",
+	"/comments/*.json",
+	"/duplicates/*.json",
+	"/user/*/*.json",
+	"/user/*/*/*.json",
+	"/api/morechildren",
+	"/api/info.json",
 }
 
 func LoadConfig() (*Config, error) {
@@ -32,6 +214,21 @@ func LoadConfig() (*Config, error) {
 		return nil, fmt.Errorf("error loading .env file: %w", err)
 	}
 
+	return fromEnv()
+}
+
+// ReloadConfig re-reads .env, letting its values override the current
+// environment, and builds a fresh Config. Used for SIGHUP hot reloads.
+func ReloadConfig() (*Config, error) {
+	err := godotenv.Overload()
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("error loading .env file: %w", err)
+	}
+
+	return fromEnv()
+}
+
+func fromEnv() (*Config, error) {
 	proxyURLsStr := os.Getenv("REDDIT_PROXY_URLS")
 	var proxyURLs []string
 
@@ -45,13 +242,12 @@ func LoadConfig() (*Config, error) {
 				continue
 			}
 
-			if !strings.HasPrefix(proxy, "http://") && !strings.HasPrefix(proxy, "https://") {
-				return nil, fmt.Errorf("invalid proxy URL format, must start with http:// or https://: %s", proxy)
+			if !hasProxyScheme(proxy) {
+				return nil, fmt.Errorf("invalid proxy URL format, must start with http://, https://, socks5:// or socks5h://: %s", maskURL(proxy))
 			}
 
-			_, err := url.Parse(proxy)
-			if err != nil {
-				return nil, fmt.Errorf("invalid proxy URL %s: %w", proxy, err)
+			if _, err := utils.ParseProxyURL(proxy); err != nil {
+				return nil, fmt.Errorf("invalid proxy URL %s: %w", maskURL(proxy), err)
 			}
 
 			proxyURLs = append(proxyURLs, proxy)
@@ -60,31 +256,222 @@ func LoadConfig() (*Config, error) {
 		fmt.Printf("Loaded %d proxy URLs from configuration\n", len(proxyURLs))
 	}
 
-	if len(proxyURLs) == 0 {
-		return nil, fmt.Errorf("REDDIT_PROXY_URLS environment variable is required and must contain at least one valid proxy URL")
+	sessionGateways := getEnvList("PROXY_SESSION_GATEWAYS")
+	for _, gateway := range sessionGateways {
+		if err := utils.ValidateSessionGateway(gateway); err != nil {
+			return nil, fmt.Errorf("invalid PROXY_SESSION_GATEWAYS: %w", err)
+		}
+	}
+
+	fakeReddit := getEnvBool("REDDIT_FAKE", false)
+	if len(proxyURLs) == 0 && len(sessionGateways) == 0 && !fakeReddit {
+		return nil, fmt.Errorf("REDDIT_PROXY_URLS environment variable is required and must contain at least one valid proxy URL, unless PROXY_SESSION_GATEWAYS or REDDIT_FAKE is set")
+	}
+	fakeErrorRate := getEnvFloat("REDDIT_FAKE_ERROR_RATE", 0)
+	if fakeErrorRate < 0 || fakeErrorRate > 1 {
+		return nil, fmt.Errorf("invalid REDDIT_FAKE_ERROR_RATE %v, must be between 0 and 1", fakeErrorRate)
+	}
+
+	canonicalHost := strings.ToLower(getEnv("REDDIT_CANONICAL_HOST", "reddit.com"))
+	if strings.ContainsAny(canonicalHost, "/:@?#") {
+		return nil, fmt.Errorf("invalid REDDIT_CANONICAL_HOST %q, expected a host name such as www.reddit.com", canonicalHost)
+	}
+
+	monthlyCaps, err := parseLabelCaps(os.Getenv("PROXY_MONTHLY_BANDWIDTH_CAPS"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid PROXY_MONTHLY_BANDWIDTH_CAPS: %w", err)
+	}
+
+	defaultPostLimit := getEnvInt("SCRAPER_DEFAULT_POST_LIMIT", 25)
+	defaultCommentLimit := getEnvInt("SCRAPER_DEFAULT_COMMENT_LIMIT", 50)
+	maxLimit := getEnvInt("SCRAPER_MAX_LIMIT", 1000)
+	for _, l := range []struct {
+		name  string
+		limit int
+	}{{"SCRAPER_DEFAULT_POST_LIMIT", defaultPostLimit}, {"SCRAPER_DEFAULT_COMMENT_LIMIT", defaultCommentLimit}} {
+		if l.limit < -1 || maxLimit > 0 && l.limit > maxLimit {
+			return nil, fmt.Errorf("invalid %s %d, must be -1, 0 or a positive integer up to SCRAPER_MAX_LIMIT", l.name, l.limit)
+		}
+	}
+
+	endpointURLs, err := parseEndpointURLs(os.Getenv("REDDIT_ENDPOINT_URLS"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid REDDIT_ENDPOINT_URLS: %w", err)
+	}
+	fallbackURLs := getEnvList("REDDIT_FALLBACK_URLS")
+	switch {
+	case len(fallbackURLs) == 0:
+		fallbackURLs = DefaultFallbackURLs
+	case len(fallbackURLs) == 1 && fallbackURLs[0] == "none":
+		fallbackURLs = nil
+	}
+	for _, fallback := range fallbackURLs {
+		if !isBaseURL(fallback) {
+			return nil, fmt.Errorf("invalid REDDIT_FALLBACK_URLS entry %q, expected a URL such as https://www.reddit.com", fallback)
+		}
+	}
+
+	rawAllowlist := getEnvList("RAW_PATH_ALLOWLIST")
+	if len(rawAllowlist) == 0 {
+		rawAllowlist = DefaultRawPathAllowlist
+	}
+	for _, pattern := range rawAllowlist {
+		if _, err := path.Match(pattern, "/"); err != nil || !strings.HasPrefix(pattern, "/") {
+			return nil, fmt.Errorf("invalid RAW_PATH_ALLOWLIST pattern %q, expected a path such as /r/*/new.json", pattern)
+		}
 	}
 
 	userAgent := os.Getenv("REDDIT_USER_AGENT")
 	if userAgent == "" {
-		userAgent = "Mozilla/5.0"
+		userAgent = client.DefaultUserAgent
 		fmt.Println("No user agent specified, using default:", userAgent)
 	}
 
 	return &Config{
 		ProxyURLs:           proxyURLs,
 		UserAgent:           userAgent,
-		MaxRetries:          getEnvInt("PROXY_MAX_RETRIES", 3),
-		DefaultPostLimit:    getEnvInt("SCRAPER_DEFAULT_POST_LIMIT", 25),
-		DefaultCommentLimit: getEnvInt("SCRAPER_DEFAULT_COMMENT_LIMIT", 50),
+		RandomUserAgents:    getEnvBool("USE_RANDOM_USER_AGENTS", true),
+		MaxRetries:          getEnvInt("PROXY_MAX_RETRIES", client.DefaultMaxRetries),
+		DefaultPostLimit:    defaultPostLimit,
+		DefaultCommentLimit: defaultCommentLimit,
+		MaxLimit:            maxLimit,
 		ServerPort:          getEnv("SERVER_PORT", "8080"),
 		RequestTimeout:      getEnvDuration("REQUEST_TIMEOUT", 30*time.Second),
 		ReadTimeout:         getEnvDuration("SERVER_READ_TIMEOUT", 30*time.Second),
 		WriteTimeout:        getEnvDuration("SERVER_WRITE_TIMEOUT", 30*time.Second),
 		RateLimitDelay:      getEnvDuration("RATE_LIMIT_DELAY", 100*time.Millisecond),
 		RedditBaseURL:       getEnv("REDDIT_BASE_URL", "https://old.reddit.com"),
+		CanonicalHost:       canonicalHost,
+		RedditAPIURL:        getEnv("REDDIT_API_URL", "https://api.reddit.com"),
+		RedditEndpointURLs:  endpointURLs,
+		RedditFallbackURLs:  fallbackURLs,
+		ExtraAllowedHosts:   getEnvList("REDDIT_EXTRA_HOSTS"),
+		UserWindowWorkers:   getEnvInt("SCRAPER_USER_WINDOW_WORKERS", 1),
+		EmptyPageRetries:    getEnvInt("SCRAPER_EMPTY_PAGE_RETRIES", 1),
+		Backfill:            getEnvBool("SCRAPER_BACKFILL", true),
+
+		RedditFailoverThreshold:     getEnvInt("REDDIT_FAILOVER_THRESHOLD", client.DefaultFailoverThreshold),
+		RedditFailoverProbeInterval: getEnvDuration("REDDIT_FAILOVER_PROBE_INTERVAL", client.DefaultFailoverProbeInterval),
+
+		ExpansionWorkers:     getEnvInt("SCRAPER_EXPANSION_WORKERS", 3),
+		ExpansionBatchSize:   getEnvInt("SCRAPER_EXPANSION_BATCH_SIZE", 15),
+		ExpansionConcurrency: getEnvInt("SCRAPER_EXPANSION_CONCURRENCY", 2),
+
+		ProxyAffinity: strings.ToLower(getEnv("PROXY_AFFINITY", "session")),
+
+		ProxyDailyBandwidthMB: getEnvInt("PROXY_DAILY_BANDWIDTH_MB", 0),
+		ThrottleWindow:        getEnvDuration("THROTTLE_WINDOW", time.Minute),
+		ThrottleBlockRate:     getEnvFloat("THROTTLE_BLOCK_RATE", 0.05),
+		MaxResponseSizeMB:     getEnvInt("MAX_RESPONSE_SIZE_MB", 64),
+
+		RetryBaseDelay: getEnvDuration("PROXY_RETRY_BASE_DELAY", time.Second),
+		RetryMaxDelay:  getEnvDuration("PROXY_RETRY_MAX_DELAY", 30*time.Second),
+		RetryBudget:    getEnvDuration("PROXY_RETRY_BUDGET", 2*time.Minute),
+
+		ProxyCookieTTL: getEnvDuration("PROXY_COOKIE_TTL", 0),
+
+		FakeReddit:          fakeReddit,
+		FakeRedditLatency:   getEnvDuration("REDDIT_FAKE_LATENCY", 0),
+		FakeRedditErrorRate: fakeErrorRate,
+
+		ProxySessionGateways:    sessionGateways,
+		ProxySessionsPerGateway: getEnvInt("PROXY_SESSIONS_PER_GATEWAY", 5),
+		ProxySessionTTL:         getEnvDuration("PROXY_SESSION_TTL", 10*time.Minute),
+
+		ProxyMonthlyBandwidthMB:   getEnvInt("PROXY_MONTHLY_BANDWIDTH_MB", 0),
+		ProxyMonthlyBandwidthCaps: monthlyCaps,
+		ProxyUsageFile:            getEnv("PROXY_USAGE_FILE", ""),
+
+		KafkaBrokers:           getEnvList("KAFKA_BROKERS"),
+		KafkaPostsTopic:        getEnv("KAFKA_TOPIC_POSTS", "reddit.posts"),
+		KafkaCommentsTopic:     getEnv("KAFKA_TOPIC_COMMENTS", "reddit.comments"),
+		KafkaUserActivityTopic: getEnv("KAFKA_TOPIC_USER_ACTIVITY", "reddit.user_activity"),
+		KafkaBatchSize:         getEnvInt("KAFKA_BATCH_SIZE", 100),
+		KafkaBatchTimeout:      getEnvDuration("KAFKA_BATCH_TIMEOUT", time.Second),
+
+		ArchiveBackend:     strings.ToLower(getEnv("ARCHIVE_BACKEND", "")),
+		ArchiveDir:         getEnv("ARCHIVE_DIR", "./archive"),
+		ArchivePrefix:      getEnv("ARCHIVE_PREFIX", "raw"),
+		ArchiveS3Endpoint:  getEnv("ARCHIVE_S3_ENDPOINT", ""),
+		ArchiveS3Region:    getEnv("ARCHIVE_S3_REGION", "us-east-1"),
+		ArchiveS3Bucket:    getEnv("ARCHIVE_S3_BUCKET", ""),
+		ArchiveS3AccessKey: getEnv("ARCHIVE_S3_ACCESS_KEY", ""),
+		ArchiveS3SecretKey: getEnv("ARCHIVE_S3_SECRET_KEY", ""),
+
+		ArchiveCompression:      strings.ToLower(getEnv("ARCHIVE_COMPRESSION", "gzip")),
+		ArchiveCompressionLevel: getEnvInt("ARCHIVE_COMPRESSION_LEVEL", 0),
+
+		PageCacheDir: getEnv("PAGE_CACHE_DIR", ""),
+
+		ResultCacheTTL:        getEnvDuration("RESULT_CACHE_TTL", 0),
+		ResultCacheMaxEntries: getEnvInt("RESULT_CACHE_MAX_ENTRIES", 256),
+
+		ResponseCompression:        strings.ToLower(getEnv("RESPONSE_COMPRESSION", "gzip")),
+		ResponseGzipLevel:          getEnvInt("RESPONSE_GZIP_LEVEL", 0),
+		ResponseBrotliLevel:        getEnvInt("RESPONSE_BROTLI_LEVEL", 0),
+		ResponseCompressionMinSize: getEnvInt("RESPONSE_COMPRESSION_MIN_SIZE", 1024),
+
+		AuditLogPath:   getEnv("AUDIT_LOG_PATH", ""),
+		RequirePurpose: getEnvBool("REQUIRE_PURPOSE", false),
+
+		AdminAPIKey:      getEnv("ADMIN_API_KEY", ""),
+		RawPathAllowlist: rawAllowlist,
+
+		AnonymizeKey:              getEnv("ANONYMIZE_KEY", ""),
+		AnonymizeMappingPublicKey: getEnv("ANONYMIZE_MAPPING_PUBLIC_KEY", ""),
+		AnonymizeAuthors:          getEnvBool("ANONYMIZE_AUTHORS", false),
+
+		BlockedSubreddits: getEnvList("BLOCKED_SUBREDDITS"),
+		BlockedUsers:      getEnvList("BLOCKED_USERS"),
+		BlocklistFile:     getEnv("BLOCKLIST_FILE", ""),
+
+		ScrubPII:          getEnvBool("SCRUB_PII", false),
+		ScrubPatternsFile: getEnv("SCRUB_PATTERNS_FILE", ""),
+
+		FlairCategoriesFile: getEnv("FLAIR_CATEGORIES_FILE", ""),
+		CrawlPoliciesFile:   getEnv("CRAWL_POLICIES_FILE", ""),
 	}, nil
 }
 
+// ClientOptions are the settings of the Reddit client in c; a fake Reddit is
+// reached without proxies
+func (c *Config) ClientOptions() client.Options {
+	monthlyCaps := make(map[string]int64, len(c.ProxyMonthlyBandwidthCaps))
+	for label, capMB := range c.ProxyMonthlyBandwidthCaps {
+		monthlyCaps[label] = int64(capMB) << 20
+	}
+	return client.Options{
+		UserAgent:            c.UserAgent,
+		RandomUserAgents:     c.RandomUserAgents,
+		ProxyURLs:            c.ProxyURLs,
+		SessionGateways:      c.ProxySessionGateways,
+		SessionsPerGateway:   c.ProxySessionsPerGateway,
+		SessionTTL:           c.ProxySessionTTL,
+		Direct:               c.FakeReddit,
+		MaxRetries:           c.MaxRetries,
+		BaseURL:              c.RedditBaseURL,
+		APIURL:               c.RedditAPIURL,
+		EndpointURLs:         c.RedditEndpointURLs,
+		FallbackURLs:         c.RedditFallbackURLs,
+		ExtraHosts:           c.ExtraAllowedHosts,
+		DailyBandwidthCap:    int64(c.ProxyDailyBandwidthMB) << 20,
+		MonthlyBandwidthCap:  int64(c.ProxyMonthlyBandwidthMB) << 20,
+		MonthlyBandwidthCaps: monthlyCaps,
+		UsageFile:            c.ProxyUsageFile,
+		MaxResponseBytes:     int64(c.MaxResponseSizeMB) << 20,
+		ThrottleWindow:       c.ThrottleWindow,
+		ThrottleBlockRate:    c.ThrottleBlockRate,
+		RetryPolicy: utils.RetryPolicy{
+			BaseDelay: c.RetryBaseDelay,
+			MaxDelay:  c.RetryMaxDelay,
+			Budget:    c.RetryBudget,
+		},
+		CookieTTL:             c.ProxyCookieTTL,
+		FailoverThreshold:     c.RedditFailoverThreshold,
+		FailoverProbeInterval: c.RedditFailoverProbeInterval,
+	}
+}
+
 func getEnv(key, defaultValue string) string {
 	value := os.Getenv(key)
 	if value == "" {
@@ -93,6 +480,88 @@ func getEnv(key, defaultValue string) string {
 	return value
 }
 
+func getEnvList(key string) []string {
+	var values []string
+	for _, value := range strings.Split(os.Getenv(key), ",") {
+		value = strings.TrimSpace(value)
+		if value != "" {
+			values = append(values, value)
+		}
+	}
+	return values
+}
+
+// hasProxyScheme reports whether proxy starts with a scheme the client can dial
+func hasProxyScheme(proxy string) bool {
+	for _, scheme := range []string{"http://", "https://", "socks5://", "socks5h://"} {
+		if strings.HasPrefix(strings.ToLower(proxy), scheme) {
+			return true
+		}
+	}
+	return false
+}
+
+// parseLabelCaps parses comma separated label=megabytes pairs, e.g.
+// "residential=50000,datacenter=0"
+func parseLabelCaps(value string) (map[string]int, error) {
+	caps := make(map[string]int)
+	for _, pair := range strings.Split(value, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		label, mb, ok := strings.Cut(pair, "=")
+		label = strings.ToLower(strings.TrimSpace(label))
+		if !ok || label == "" {
+			return nil, fmt.Errorf("%q is not label=megabytes", pair)
+		}
+		capMB, err := strconv.Atoi(strings.TrimSpace(mb))
+		if err != nil || capMB < 0 {
+			return nil, fmt.Errorf("%q is not label=megabytes", pair)
+		}
+		caps[label] = capMB
+	}
+	return caps, nil
+}
+
+// parseEndpointURLs reads endpoint=base URL pairs, the endpoints being those
+// of client.Endpoints
+func parseEndpointURLs(value string) (map[string]string, error) {
+	urls := make(map[string]string)
+	for _, pair := range strings.Split(value, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		endpoint, baseURL, ok := strings.Cut(pair, "=")
+		endpoint = strings.ToLower(strings.TrimSpace(endpoint))
+		baseURL = strings.TrimSpace(baseURL)
+		if !ok || !slices.Contains(client.Endpoints, endpoint) || !isBaseURL(baseURL) {
+			return nil, fmt.Errorf("%q is not endpoint=URL with an endpoint of %s", pair, strings.Join(client.Endpoints, ", "))
+		}
+		urls[endpoint] = baseURL
+	}
+	return urls, nil
+}
+
+// isBaseURL reports whether value is an http or https URL with a host
+func isBaseURL(value string) bool {
+	u, err := url.Parse(value)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
+
+func getEnvBool(key string, defaultValue bool) bool {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	boolValue, err := strconv.ParseBool(value)
+	if err != nil {
+		return defaultValue
+	}
+	return boolValue
+}
+
 func getEnvInt(key string, defaultValue int) int {
 	value := os.Getenv(key)
 	if value == "" {
@@ -105,6 +574,18 @@ func getEnvInt(key string, defaultValue int) int {
 	return intValue
 }
 
+func getEnvFloat(key string, defaultValue float64) float64 {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	floatValue, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return defaultValue
+	}
+	return floatValue
+}
+
 func getEnvDuration(key string, defaultValue time.Duration) time.Duration {
 	value := os.Getenv(key)
 	if value == "" {
@@ -0,0 +1,170 @@
+// internal/config/live.go
+package config
+
+import (
+	"net/url"
+	"strings"
+	"sync"
+)
+
+// Live holds the configuration currently in effect so it can be swapped by a
+// hot reload while handlers read it
+type Live struct {
+	mutex sync.RWMutex
+	cfg   *Config
+}
+
+func NewLive(cfg *Config) *Live {
+	return &Live{cfg: cfg}
+}
+
+// Get returns the configuration currently in effect
+func (l *Live) Get() *Config {
+	l.mutex.RLock()
+	defer l.mutex.RUnlock()
+	return l.cfg
+}
+
+// Set replaces the configuration currently in effect
+func (l *Live) Set(cfg *Config) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	l.cfg = cfg
+}
+
+// Redacted returns the configuration keyed by environment variable name with
+// proxy passwords and storage credentials masked, for display
+func (c *Config) Redacted() map[string]interface{} {
+	proxies := make([]string, 0, len(c.ProxyURLs))
+	for _, proxy := range c.ProxyURLs {
+		proxies = append(proxies, maskURL(proxy))
+	}
+	gateways := make([]string, 0, len(c.ProxySessionGateways))
+	for _, gateway := range c.ProxySessionGateways {
+		// The placeholder is not valid in a URL, so it is swapped out to mask the password
+		masked := maskURL(strings.ReplaceAll(gateway, "{session}", "SESSION-ID"))
+		gateways = append(gateways, strings.ReplaceAll(masked, "SESSION-ID", "{session}"))
+	}
+
+	return map[string]interface{}{
+		"REDDIT_PROXY_URLS":             proxies,
+		"REDDIT_USER_AGENT":             c.UserAgent,
+		"USE_RANDOM_USER_AGENTS":        c.RandomUserAgents,
+		"REDDIT_BASE_URL":               c.RedditBaseURL,
+		"REDDIT_CANONICAL_HOST":         c.CanonicalHost,
+		"REDDIT_API_URL":                c.RedditAPIURL,
+		"REDDIT_ENDPOINT_URLS":          c.RedditEndpointURLs,
+		"REDDIT_FALLBACK_URLS":          c.RedditFallbackURLs,
+		"REDDIT_EXTRA_HOSTS":            c.ExtraAllowedHosts,
+		"REDDIT_FAKE":                   c.FakeReddit,
+		"REDDIT_FAKE_LATENCY":           c.FakeRedditLatency.String(),
+		"REDDIT_FAKE_ERROR_RATE":        c.FakeRedditErrorRate,
+		"PROXY_MAX_RETRIES":             c.MaxRetries,
+		"PROXY_RETRY_BASE_DELAY":        c.RetryBaseDelay.String(),
+		"PROXY_RETRY_MAX_DELAY":         c.RetryMaxDelay.String(),
+		"PROXY_RETRY_BUDGET":            c.RetryBudget.String(),
+		"PROXY_COOKIE_TTL":              c.ProxyCookieTTL.String(),
+		"PROXY_AFFINITY":                c.ProxyAffinity,
+		"PROXY_SESSION_GATEWAYS":        gateways,
+		"PROXY_SESSIONS_PER_GATEWAY":    c.ProxySessionsPerGateway,
+		"PROXY_SESSION_TTL":             c.ProxySessionTTL.String(),
+		"PROXY_DAILY_BANDWIDTH_MB":      c.ProxyDailyBandwidthMB,
+		"PROXY_MONTHLY_BANDWIDTH_MB":    c.ProxyMonthlyBandwidthMB,
+		"PROXY_MONTHLY_BANDWIDTH_CAPS":  c.ProxyMonthlyBandwidthCaps,
+		"PROXY_USAGE_FILE":              c.ProxyUsageFile,
+		"THROTTLE_WINDOW":               c.ThrottleWindow.String(),
+		"THROTTLE_BLOCK_RATE":           c.ThrottleBlockRate,
+		"MAX_RESPONSE_SIZE_MB":          c.MaxResponseSizeMB,
+		"SCRAPER_DEFAULT_POST_LIMIT":    c.DefaultPostLimit,
+		"SCRAPER_DEFAULT_COMMENT_LIMIT": c.DefaultCommentLimit,
+		"SCRAPER_MAX_LIMIT":             c.MaxLimit,
+		"SCRAPER_USER_WINDOW_WORKERS":   c.UserWindowWorkers,
+		"SCRAPER_EMPTY_PAGE_RETRIES":    c.EmptyPageRetries,
+		"SCRAPER_BACKFILL":              c.Backfill,
+		"SCRAPER_EXPANSION_WORKERS":     c.ExpansionWorkers,
+		"SCRAPER_EXPANSION_BATCH_SIZE":  c.ExpansionBatchSize,
+		"SCRAPER_EXPANSION_CONCURRENCY": c.ExpansionConcurrency,
+		"SERVER_PORT":                   c.ServerPort,
+		"SERVER_READ_TIMEOUT":           c.ReadTimeout.String(),
+		"SERVER_WRITE_TIMEOUT":          c.WriteTimeout.String(),
+		"REQUEST_TIMEOUT":               c.RequestTimeout.String(),
+		"RATE_LIMIT_DELAY":              c.RateLimitDelay.String(),
+
+		"REDDIT_FAILOVER_THRESHOLD":      c.RedditFailoverThreshold,
+		"REDDIT_FAILOVER_PROBE_INTERVAL": c.RedditFailoverProbeInterval.String(),
+
+		"KAFKA_BROKERS":             c.KafkaBrokers,
+		"KAFKA_TOPIC_POSTS":         c.KafkaPostsTopic,
+		"KAFKA_TOPIC_COMMENTS":      c.KafkaCommentsTopic,
+		"KAFKA_TOPIC_USER_ACTIVITY": c.KafkaUserActivityTopic,
+		"KAFKA_BATCH_SIZE":          c.KafkaBatchSize,
+		"KAFKA_BATCH_TIMEOUT":       c.KafkaBatchTimeout.String(),
+
+		"ARCHIVE_BACKEND":       c.ArchiveBackend,
+		"ARCHIVE_DIR":           c.ArchiveDir,
+		"ARCHIVE_PREFIX":        c.ArchivePrefix,
+		"ARCHIVE_S3_ENDPOINT":   c.ArchiveS3Endpoint,
+		"ARCHIVE_S3_REGION":     c.ArchiveS3Region,
+		"ARCHIVE_S3_BUCKET":     c.ArchiveS3Bucket,
+		"ARCHIVE_S3_ACCESS_KEY": maskSecret(c.ArchiveS3AccessKey),
+		"ARCHIVE_S3_SECRET_KEY": maskSecret(c.ArchiveS3SecretKey),
+
+		"RESPONSE_COMPRESSION":          c.ResponseCompression,
+		"RESPONSE_GZIP_LEVEL":           c.ResponseGzipLevel,
+		"RESPONSE_BROTLI_LEVEL":         c.ResponseBrotliLevel,
+		"RESPONSE_COMPRESSION_MIN_SIZE": c.ResponseCompressionMinSize,
+
+		"ARCHIVE_COMPRESSION":       c.ArchiveCompression,
+		"ARCHIVE_COMPRESSION_LEVEL": c.ArchiveCompressionLevel,
+
+		"PAGE_CACHE_DIR": c.PageCacheDir,
+
+		"RESULT_CACHE_TTL":         c.ResultCacheTTL.String(),
+		"RESULT_CACHE_MAX_ENTRIES": c.ResultCacheMaxEntries,
+
+		"AUDIT_LOG_PATH":  c.AuditLogPath,
+		"REQUIRE_PURPOSE": c.RequirePurpose,
+
+		"ADMIN_API_KEY":      maskSecret(c.AdminAPIKey),
+		"RAW_PATH_ALLOWLIST": c.RawPathAllowlist,
+
+		"ANONYMIZE_KEY":                maskSecret(c.AnonymizeKey),
+		"ANONYMIZE_MAPPING_PUBLIC_KEY": c.AnonymizeMappingPublicKey,
+		"ANONYMIZE_AUTHORS":            c.AnonymizeAuthors,
+
+		"BLOCKED_SUBREDDITS": c.BlockedSubreddits,
+		"BLOCKED_USERS":      c.BlockedUsers,
+		"BLOCKLIST_FILE":     c.BlocklistFile,
+
+		"SCRUB_PII":           c.ScrubPII,
+		"SCRUB_PATTERNS_FILE": c.ScrubPatternsFile,
+
+		"FLAIR_CATEGORIES_FILE": c.FlairCategoriesFile,
+		"CRAWL_POLICIES_FILE":   c.CrawlPoliciesFile,
+	}
+}
+
+func maskSecret(secret string) string {
+	if secret == "" {
+		return ""
+	}
+	return "****"
+}
+
+// maskURL hides the password of a URL with userinfo, leaving the username
+func maskURL(rawURL string) string {
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		if strings.Contains(rawURL, "@") {
+			return "[masked]"
+		}
+		return rawURL
+	}
+	if parsed.User == nil {
+		return rawURL
+	}
+	if _, hasPassword := parsed.User.Password(); !hasPassword {
+		return rawURL
+	}
+	return strings.Replace(rawURL, parsed.User.String(), parsed.User.Username()+":****", 1)
+}
@@ -0,0 +1,122 @@
+// internal/dedup/duplicates.go
+package dedup
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"reddit-ingestion/pkg/models"
+	"reddit-ingestion/pkg/scraper"
+)
+
+// Kinds of DuplicateGroup
+const (
+	KindContent = "content"
+	KindLink    = "link"
+)
+
+// Finder looks for posts repeated across subreddits
+type Finder interface {
+	// Duplicates reads the posts made in subreddits, joined by +, within
+	// window before now, at most limit of them (-1 for all), and groups
+	// those sharing a hash
+	Duplicates(ctx context.Context, subreddits string, window time.Duration, limit int) (models.Duplicates, error)
+}
+
+type finder struct {
+	svc scraper.ScraperService
+	now func() time.Time
+}
+
+func NewFinder(svc scraper.ScraperService) Finder {
+	return &finder{
+		svc: svc,
+		now: time.Now,
+	}
+}
+
+func (f *finder) Duplicates(ctx context.Context, subreddits string, window time.Duration, limit int) (models.Duplicates, error) {
+	to := f.now()
+	from := to.Add(-window)
+	posts, _, err := f.svc.ScrapeSubreddit(ctx, subreddits, from.Unix(), limit, scraper.ListingOptions{})
+	if err != nil {
+		return models.Duplicates{}, fmt.Errorf("read subreddits: %w", err)
+	}
+
+	groups := Group(Enrich(posts))
+	fmt.Printf("Found %d duplicate groups among %d posts of %s\n", len(groups), len(posts), subreddits)
+	return models.Duplicates{
+		Subreddits:   subreddits,
+		From:         from,
+		To:           to,
+		PostsScanned: len(posts),
+		Groups:       groups,
+	}, nil
+}
+
+// Group collects the posts sharing a content or link hash, keeping the groups
+// that span at least two subreddits. Crossposts are left out: they repeat a
+// post openly. A post can be in both a content and a link group. Groups come
+// largest first, then by their oldest post.
+func Group(posts []models.Post) []models.DuplicateGroup {
+	type groupKey struct{ kind, hash string }
+	byKey := make(map[groupKey][]models.Post)
+	var keys []groupKey
+	add := func(key groupKey, post models.Post) {
+		if key.hash == "" {
+			return
+		}
+		if _, ok := byKey[key]; !ok {
+			keys = append(keys, key)
+		}
+		byKey[key] = append(byKey[key], post)
+	}
+	for _, post := range posts {
+		if post.CrosspostParent != "" {
+			continue
+		}
+		add(groupKey{KindContent, post.ContentHash}, post)
+		add(groupKey{KindLink, post.LinkHash}, post)
+	}
+
+	groups := []models.DuplicateGroup{}
+	for _, key := range keys {
+		group := newGroup(key.kind, key.hash, byKey[key])
+		if len(group.Subreddits) >= 2 {
+			groups = append(groups, group)
+		}
+	}
+	sort.SliceStable(groups, func(i, j int) bool {
+		if len(groups[i].Posts) != len(groups[j].Posts) {
+			return len(groups[i].Posts) > len(groups[j].Posts)
+		}
+		return groups[i].Posts[0].CreatedUTC < groups[j].Posts[0].CreatedUTC
+	})
+	return groups
+}
+
+func newGroup(kind, hash string, posts []models.Post) models.DuplicateGroup {
+	sort.SliceStable(posts, func(i, j int) bool {
+		return posts[i].CreatedUTC < posts[j].CreatedUTC
+	})
+	subreddits := make(map[string]bool)
+	authors := make(map[string]bool)
+	for _, post := range posts {
+		subreddits[post.Subreddit] = true
+		authors[post.Author] = true
+	}
+
+	group := models.DuplicateGroup{
+		Kind:    kind,
+		Hash:    hash,
+		Authors: len(authors),
+		Posts:   posts,
+	}
+	for subreddit := range subreddits {
+		group.Subreddits = append(group.Subreddits, subreddit)
+	}
+	sort.Strings(group.Subreddits)
+	return group
+}
@@ -0,0 +1,130 @@
+// internal/dedup/hash.go
+package dedup
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/url"
+	"strings"
+	"unicode"
+
+	"reddit-ingestion/pkg/models"
+)
+
+// trackingParams are query parameters that only record where a link was
+// shared from, dropped so the same page shared twice has one canonical URL
+var trackingParams = map[string]bool{
+	"fbclid":   true,
+	"gclid":    true,
+	"igshid":   true,
+	"mc_cid":   true,
+	"mc_eid":   true,
+	"ref":      true,
+	"ref_src":  true,
+	"share_id": true,
+	"si":       true,
+}
+
+// removedBodies are the bodies Reddit shows in place of removed text, which
+// say nothing about what the post said
+var removedBodies = map[string]bool{
+	"[removed]": true,
+	"[deleted]": true,
+}
+
+// Normalize reduces text to its lowercase words, dropping punctuation, symbols
+// and extra whitespace, so texts differing only in those compare equal
+func Normalize(text string) string {
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	return strings.Join(words, " ")
+}
+
+// ContentHash is the hash of the normalized title and body; empty when both
+// normalize to nothing
+func ContentHash(title, body string) string {
+	if removedBodies[strings.TrimSpace(body)] {
+		body = ""
+	}
+	title, body = Normalize(title), Normalize(body)
+	if title == "" && body == "" {
+		return ""
+	}
+	return hash(title + "\n" + body)
+}
+
+// CanonicalURL is link with the differences that do not change the page it
+// points to removed: the scheme, a www. or m. host prefix, the fragment,
+// tracking parameters, the order of the query and a trailing slash. youtu.be
+// and YouTube Shorts links become watch links. It is empty when link is not
+// an absolute URL.
+func CanonicalURL(link string) string {
+	u, err := url.Parse(strings.TrimSpace(link))
+	if err != nil || u.Host == "" {
+		return ""
+	}
+
+	host := strings.ToLower(u.Hostname())
+	host = strings.TrimPrefix(host, "www.")
+	host = strings.TrimPrefix(host, "m.")
+	path := strings.TrimSuffix(u.EscapedPath(), "/")
+	query := u.Query()
+
+	switch {
+	case host == "youtu.be" && path != "":
+		query.Set("v", strings.TrimPrefix(path, "/"))
+		host, path = "youtube.com", "/watch"
+	case host == "youtube.com" && strings.HasPrefix(path, "/shorts/"):
+		query.Set("v", strings.TrimPrefix(path, "/shorts/"))
+		path = "/watch"
+	}
+
+	for name := range query {
+		if trackingParams[name] || strings.HasPrefix(name, "utm_") {
+			query.Del(name)
+		}
+	}
+
+	canonical := "https://" + host + path
+	if len(query) > 0 {
+		// Encode sorts by name
+		canonical += "?" + query.Encode()
+	}
+	return canonical
+}
+
+// LinkHash is the hash of the canonical form of link; empty when link is
+// not an absolute URL
+func LinkHash(link string) string {
+	canonical := CanonicalURL(link)
+	if canonical == "" {
+		return ""
+	}
+	return hash(canonical)
+}
+
+// hash is a short hex digest of s; 64 bits keep collisions out of reach for
+// the posts compared at once
+func hash(s string) string {
+	sum := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(sum[:8])
+}
+
+// Enrich sets the content and link hashes of posts in place and returns them.
+// Posts already hashed keep their hashes, which may have been taken before
+// their text was scrubbed.
+func Enrich(posts []models.Post) []models.Post {
+	for i := range posts {
+		if posts[i].ContentHash == "" && posts[i].LinkHash == "" {
+			EnrichPost(&posts[i])
+		}
+	}
+	return posts
+}
+
+// EnrichPost sets the content and link hashes of post
+func EnrichPost(post *models.Post) {
+	post.ContentHash = ContentHash(post.Title, post.Body)
+	post.LinkHash = LinkHash(post.LinkURL)
+}
@@ -0,0 +1,41 @@
+// internal/dedup/service.go
+package dedup
+
+import (
+	"context"
+
+	"reddit-ingestion/pkg/models"
+	"reddit-ingestion/pkg/scraper"
+)
+
+// hashingService sets the content and link hashes of every post scraped
+type hashingService struct {
+	scraper.ScraperService
+}
+
+// WrapService returns a ScraperService that sets ContentHash and LinkHash on
+// the posts of subreddit, search and frontpage listings and of post details
+func WrapService(svc scraper.ScraperService) scraper.ScraperService {
+	return &hashingService{ScraperService: svc}
+}
+
+func (w *hashingService) ScrapeSubreddit(ctx context.Context, subreddit string, sinceTimestamp int64, limit int, opts scraper.ListingOptions) ([]models.Post, models.ListingMeta, error) {
+	posts, meta, err := w.ScraperService.ScrapeSubreddit(ctx, subreddit, sinceTimestamp, limit, opts)
+	return Enrich(posts), meta, err
+}
+
+func (w *hashingService) ScrapePost(ctx context.Context, postID string) (models.PostDetail, error) {
+	detail, err := w.ScraperService.ScrapePost(ctx, postID)
+	EnrichPost(&detail.Post)
+	return detail, err
+}
+
+func (w *hashingService) Search(ctx context.Context, searchParams map[string]string, sinceTimestamp int64, limit int, opts scraper.ListingOptions) ([]models.Post, models.ListingMeta, error) {
+	posts, meta, err := w.ScraperService.Search(ctx, searchParams, sinceTimestamp, limit, opts)
+	return Enrich(posts), meta, err
+}
+
+func (w *hashingService) ScrapeFrontpage(ctx context.Context, feed string, params map[string]string, limit int, opts scraper.ListingOptions) ([]models.Post, models.ListingMeta, error) {
+	posts, meta, err := w.ScraperService.ScrapeFrontpage(ctx, feed, params, limit, opts)
+	return Enrich(posts), meta, err
+}
@@ -0,0 +1,184 @@
+// internal/export/anonymize.go
+package export
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// MinAnonymizeKeyLength keeps keys long enough that pseudonyms cannot be
+// reversed by guessing the key and hashing known usernames
+const MinAnonymizeKeyLength = 16
+
+// userMention matches u/name and /user/name references in post and comment text
+var userMention = regexp.MustCompile(`(?i)\b(u|user)/([a-z0-9_-]{3,20})`)
+
+// anonymizeKeyID is the optional "id:" prefix of an ANONYMIZE_KEY entry
+var anonymizeKeyID = regexp.MustCompile(`^([a-z0-9]{1,16}):(.+)$`)
+
+// AnonymizeKey is one pseudonymization key. Keys are rotated by adding a new
+// one with a new ID in front; the old ones stay available to extend datasets
+// made with them.
+type AnonymizeKey struct {
+	ID     string
+	Secret string
+}
+
+// ParseAnonymizeKeys parses ANONYMIZE_KEY: a comma-separated list of id:secret
+// entries, newest first, or a single secret without an ID. IDs are lowercase
+// letters and digits.
+func ParseAnonymizeKeys(value string) ([]AnonymizeKey, error) {
+	var keys []AnonymizeKey
+	seen := make(map[string]bool)
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		key := AnonymizeKey{Secret: entry}
+		if parts := anonymizeKeyID.FindStringSubmatch(entry); parts != nil {
+			key = AnonymizeKey{ID: parts[1], Secret: parts[2]}
+		}
+		if len(key.Secret) < MinAnonymizeKeyLength {
+			return nil, fmt.Errorf("anonymization key %q must be at least %d characters", key.ID, MinAnonymizeKeyLength)
+		}
+		if seen[key.ID] {
+			return nil, fmt.Errorf("anonymization key ID %q is used twice", key.ID)
+		}
+		seen[key.ID] = true
+		keys = append(keys, key)
+	}
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("no anonymization key configured")
+	}
+	if len(keys) > 1 && seen[""] {
+		return nil, fmt.Errorf("every anonymization key needs an ID when several are configured")
+	}
+	return keys, nil
+}
+
+// Anonymizer pseudonymizes usernames in records so datasets can be shared for
+// research. A username always maps to the same pseudonym under the same key,
+// so records from different exports still join on author; without the key
+// the pseudonyms cannot be traced back to accounts. Pseudonyms name their key,
+// so a dataset shows which key it was made with.
+type Anonymizer struct {
+	key AnonymizeKey
+
+	mutex   sync.Mutex
+	mapping map[string]string // pseudonym -> username, when tracked
+}
+
+// NewAnonymizer creates an anonymizer from ANONYMIZE_KEY, using the key with
+// keyID or the newest key when keyID is empty
+func NewAnonymizer(keys, keyID string) (*Anonymizer, error) {
+	parsed, err := ParseAnonymizeKeys(keys)
+	if err != nil {
+		return nil, err
+	}
+	if keyID == "" {
+		return &Anonymizer{key: parsed[0]}, nil
+	}
+	for _, key := range parsed {
+		if key.ID == keyID {
+			return &Anonymizer{key: key}, nil
+		}
+	}
+	return nil, fmt.Errorf("no anonymization key with ID %q", keyID)
+}
+
+// KeyID is the ID of the key in use, empty for a key without one
+func (a *Anonymizer) KeyID() string {
+	return a.key.ID
+}
+
+// TrackMapping makes the anonymizer remember which username each pseudonym it
+// hands out stands for, for a sealed mapping export
+func (a *Anonymizer) TrackMapping() {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	if a.mapping == nil {
+		a.mapping = make(map[string]string)
+	}
+}
+
+// Mapping returns the pseudonyms handed out since TrackMapping, each with its
+// username
+func (a *Anonymizer) Mapping() map[string]string {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	mapping := make(map[string]string, len(a.mapping))
+	for pseudonym, username := range a.mapping {
+		mapping[pseudonym] = username
+	}
+	return mapping
+}
+
+// Pseudonym returns the stable pseudonym of a username. Reddit usernames are
+// case-insensitive, so case is ignored. Empty and deleted authors are kept, as
+// they identify no one.
+func (a *Anonymizer) Pseudonym(username string) string {
+	if username == "" || username == "[deleted]" {
+		return username
+	}
+	name := strings.ToLower(username)
+	mac := hmac.New(sha256.New, []byte(a.key.Secret))
+	mac.Write([]byte(name))
+
+	pseudonym := "anon_"
+	if a.key.ID != "" {
+		pseudonym += a.key.ID + "_"
+	}
+	pseudonym += hex.EncodeToString(mac.Sum(nil))[:16]
+
+	a.mutex.Lock()
+	if a.mapping != nil {
+		a.mapping[pseudonym] = name
+	}
+	a.mutex.Unlock()
+	return pseudonym
+}
+
+// Text replaces u/name mentions in free text with the named user's pseudonym
+func (a *Anonymizer) Text(text string) string {
+	return userMention.ReplaceAllStringFunc(text, func(mention string) string {
+		parts := userMention.FindStringSubmatch(mention)
+		return parts[1] + "/" + a.Pseudonym(parts[2])
+	})
+}
+
+// Records returns anonymized copies of records: authors and usernames are
+// replaced by pseudonyms and mentions in titles and bodies are rewritten.
+// Post and comment IDs are kept so records still link to each other.
+func (a *Anonymizer) Records(records []Record) []Record {
+	anonymized := make([]Record, 0, len(records))
+	for _, record := range records {
+		anonymized = append(anonymized, a.record(record))
+	}
+	return anonymized
+}
+
+func (a *Anonymizer) record(record Record) Record {
+	switch r := record.(type) {
+	case PostRecord:
+		r.Author = a.Pseudonym(r.Author)
+		r.Title = a.Text(r.Title)
+		r.Body = a.Text(r.Body)
+		return r
+	case CommentRecord:
+		r.Author = a.Pseudonym(r.Author)
+		r.Body = a.Text(r.Body)
+		return r
+	case UserItemRecord:
+		r.Username = a.Pseudonym(r.Username)
+		r.Title = a.Text(r.Title)
+		r.Body = a.Text(r.Body)
+		return r
+	}
+	return record
+}
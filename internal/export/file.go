@@ -0,0 +1,134 @@
+// internal/export/file.go
+package export
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"reddit-ingestion/internal/compression"
+)
+
+// FileOptions controls how an export file is written
+type FileOptions struct {
+	Compression compression.Codec
+	// Compression level, 0 for the codec's default
+	Level int
+	// Start a new file once the current one holds this many bytes on disk;
+	// 0 writes a single file
+	MaxBytes int64
+}
+
+// File is an export output file that compresses what is written to it. With
+// MaxBytes set it rotates through numbered files, out-00001.ndjson.gz,
+// out-00002.ndjson.gz and so on. A Writer only rotates between records, so
+// every file holds whole records and CSV files each get a header.
+type File struct {
+	path string
+	opts FileOptions
+
+	index   int
+	file    *os.File
+	written *countingWriter
+	w       io.WriteCloser
+	names   []string
+}
+
+// CreateFile creates the first file of an export at path. The codec's
+// extension is appended unless path already ends in it.
+func CreateFile(path string, opts FileOptions) (*File, error) {
+	if err := opts.Compression.CheckLevel(opts.Level); err != nil {
+		return nil, err
+	}
+	f := &File{path: path, opts: opts}
+	if err := f.open(); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+func (f *File) Write(p []byte) (int, error) {
+	if f.w == nil {
+		return 0, fmt.Errorf("export file %s is closed", f.name())
+	}
+	return f.w.Write(p)
+}
+
+// Names returns the files written so far, in order
+func (f *File) Names() []string {
+	return append([]string(nil), f.names...)
+}
+
+// Close flushes the compressed stream and closes the current file. Closing
+// twice is a no-op.
+func (f *File) Close() error {
+	if f.file == nil {
+		return nil
+	}
+	err := f.w.Close()
+	if closeErr := f.file.Close(); err == nil {
+		err = closeErr
+	}
+	f.file, f.w = nil, nil
+	if err != nil {
+		return fmt.Errorf("close export file: %w", err)
+	}
+	return nil
+}
+
+// full reports whether the current file has reached MaxBytes
+func (f *File) full() bool {
+	return f.opts.MaxBytes > 0 && f.written != nil && f.written.n >= f.opts.MaxBytes
+}
+
+// rotate closes the current file and starts the next one
+func (f *File) rotate() error {
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return f.open()
+}
+
+func (f *File) open() error {
+	f.index++
+	name := f.name()
+	file, err := os.Create(name)
+	if err != nil {
+		return fmt.Errorf("failed to create output file: %w", err)
+	}
+	f.written = &countingWriter{w: file}
+	w, err := compression.NewWriter(f.written, f.opts.Compression, f.opts.Level)
+	if err != nil {
+		file.Close()
+		return err
+	}
+	f.file, f.w = file, w
+	f.names = append(f.names, name)
+	return nil
+}
+
+// name is the path of the current file
+func (f *File) name() string {
+	ext := f.opts.Compression.Extension()
+	path := strings.TrimSuffix(f.path, ext)
+	if f.opts.MaxBytes > 0 {
+		// Number the files ahead of their type, e.g. out-00001.ndjson
+		typeExt := filepath.Ext(path)
+		path = fmt.Sprintf("%s-%05d%s", strings.TrimSuffix(path, typeExt), f.index, typeExt)
+	}
+	return path + ext
+}
+
+// countingWriter counts the bytes that reach the file, i.e. after compression
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
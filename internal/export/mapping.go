@@ -0,0 +1,87 @@
+// internal/export/mapping.go
+package export
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"golang.org/x/crypto/curve25519"
+	"golang.org/x/crypto/nacl/box"
+)
+
+// Mapping ties the pseudonyms of one anonymized export back to usernames. It
+// is only ever written sealed, so the export can be de-pseudonymized by
+// whoever holds the mapping private key and no one else.
+type Mapping struct {
+	KeyID     string    `json:"key_id,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	// Pseudonym to lowercased username
+	Pseudonyms map[string]string `json:"pseudonyms"`
+}
+
+// GenerateMappingKeys creates a key pair for sealed mappings, base64 encoded.
+// The public key goes into ANONYMIZE_MAPPING_PUBLIC_KEY; the private key stays
+// with the party allowed to de-pseudonymize, e.g. the IRB's data steward.
+func GenerateMappingKeys() (publicKey, privateKey string, err error) {
+	public, private, err := box.GenerateKey(rand.Reader)
+	if err != nil {
+		return "", "", fmt.Errorf("generate mapping keys: %w", err)
+	}
+	return base64.StdEncoding.EncodeToString(public[:]), base64.StdEncoding.EncodeToString(private[:]), nil
+}
+
+// SealMapping encrypts mapping to publicKey as a NaCl anonymous sealed box:
+// nothing in the output, including who sealed it, can be read without the
+// matching private key
+func SealMapping(mapping Mapping, publicKey string) ([]byte, error) {
+	recipient, err := decodeMappingKey(publicKey)
+	if err != nil {
+		return nil, fmt.Errorf("mapping public key: %w", err)
+	}
+	data, err := json.Marshal(mapping)
+	if err != nil {
+		return nil, fmt.Errorf("encode mapping: %w", err)
+	}
+	sealed, err := box.SealAnonymous(nil, data, recipient, rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("seal mapping: %w", err)
+	}
+	return sealed, nil
+}
+
+// OpenMapping decrypts a mapping sealed by SealMapping
+func OpenMapping(sealed []byte, privateKey string) (Mapping, error) {
+	private, err := decodeMappingKey(privateKey)
+	if err != nil {
+		return Mapping{}, fmt.Errorf("mapping private key: %w", err)
+	}
+	public, err := curve25519.X25519(private[:], curve25519.Basepoint)
+	if err != nil {
+		return Mapping{}, fmt.Errorf("mapping private key: %w", err)
+	}
+
+	data, ok := box.OpenAnonymous(nil, sealed, (*[32]byte)(public), private)
+	if !ok {
+		return Mapping{}, errors.New("mapping cannot be opened with this key or is corrupt")
+	}
+	var mapping Mapping
+	if err := json.Unmarshal(data, &mapping); err != nil {
+		return Mapping{}, fmt.Errorf("decode mapping: %w", err)
+	}
+	return mapping, nil
+}
+
+func decodeMappingKey(encoded string) (*[32]byte, error) {
+	raw, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("not base64: %w", err)
+	}
+	if len(raw) != 32 {
+		return nil, fmt.Errorf("expected 32 bytes, got %d", len(raw))
+	}
+	return (*[32]byte)(raw), nil
+}
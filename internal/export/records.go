@@ -0,0 +1,163 @@
+// internal/export/records.go
+package export
+
+import (
+	"strconv"
+	"time"
+
+	"reddit-ingestion/pkg/models"
+)
+
+// PostRecord is a listing post flattened for NDJSON/CSV output
+type PostRecord struct {
+	models.Post
+}
+
+func (r PostRecord) Columns() []string {
+	return []string{"id", "subreddit", "title", "body", "author", "score", "num_comments", "created_at", "created_utc", "flair", "category", "nsfw", "url"}
+}
+
+func (r PostRecord) Values() []string {
+	return []string{
+		r.ID, r.Subreddit, r.Title, r.Body, r.Author,
+		strconv.Itoa(r.Score), strconv.Itoa(r.NumComments),
+		formatTime(r.CreatedAt), formatEpoch(r.CreatedUTC), r.Flair, r.Category, strconv.FormatBool(r.NSFW), r.URL,
+	}
+}
+
+// CommentRecord is a single comment lifted out of its reply tree. ParentID is
+// empty for top-level comments and Depth is 0 for them.
+type CommentRecord struct {
+	PostID     string    `json:"post_id"`
+	ParentID   string    `json:"parent_id,omitempty"`
+	Depth      int       `json:"depth"`
+	ID         string    `json:"id"`
+	Author     string    `json:"author"`
+	Body       string    `json:"body"`
+	Score      int       `json:"score"`
+	CreatedAt  time.Time `json:"created_at"`
+	CreatedUTC float64   `json:"created_utc"`
+}
+
+func (r CommentRecord) Columns() []string {
+	return []string{"post_id", "parent_id", "depth", "id", "author", "body", "score", "created_at", "created_utc"}
+}
+
+func (r CommentRecord) Values() []string {
+	return []string{
+		r.PostID, r.ParentID, strconv.Itoa(r.Depth), r.ID, r.Author, r.Body,
+		strconv.Itoa(r.Score), formatTime(r.CreatedAt), formatEpoch(r.CreatedUTC),
+	}
+}
+
+// UserItemRecord is a user's post or comment in a single shape, told apart by Kind
+type UserItemRecord struct {
+	Kind       string    `json:"kind"`
+	Username   string    `json:"username"`
+	ID         string    `json:"id"`
+	Subreddit  string    `json:"subreddit"`
+	PostID     string    `json:"post_id,omitempty"`
+	Title      string    `json:"title"`
+	Body       string    `json:"body"`
+	Score      int       `json:"score"`
+	CreatedAt  time.Time `json:"created_at"`
+	CreatedUTC float64   `json:"created_utc"`
+	URL        string    `json:"url,omitempty"`
+}
+
+func (r UserItemRecord) Columns() []string {
+	return []string{"kind", "username", "id", "subreddit", "post_id", "title", "body", "score", "created_at", "created_utc", "url"}
+}
+
+func (r UserItemRecord) Values() []string {
+	return []string{
+		r.Kind, r.Username, r.ID, r.Subreddit, r.PostID, r.Title, r.Body,
+		strconv.Itoa(r.Score), formatTime(r.CreatedAt), formatEpoch(r.CreatedUTC), r.URL,
+	}
+}
+
+// PostRecords converts listing posts into records
+func PostRecords(posts []models.Post) []Record {
+	records := make([]Record, 0, len(posts))
+	for _, p := range posts {
+		records = append(records, PostRecord{Post: p})
+	}
+	return records
+}
+
+// CommentRecords flattens a post's comment tree depth-first, skipping "more" placeholders
+func CommentRecords(detail models.PostDetail) []Record {
+	var records []Record
+	var walk func(comments []models.Comment, parentID string, depth int)
+	walk = func(comments []models.Comment, parentID string, depth int) {
+		for _, c := range comments {
+			if c.IsMore {
+				continue
+			}
+			records = append(records, CommentRecord{
+				PostID:     detail.Post.ID,
+				ParentID:   parentID,
+				Depth:      depth,
+				ID:         c.ID,
+				Author:     c.Author,
+				Body:       c.Body,
+				Score:      c.Score,
+				CreatedAt:  c.CreatedAt,
+				CreatedUTC: c.CreatedUTC,
+			})
+			walk(c.Replies, c.ID, depth+1)
+		}
+	}
+	walk(detail.Comments, "", 0)
+	return records
+}
+
+// UserActivityRecords converts a user's posts followed by their comments into records
+func UserActivityRecords(activity models.UserActivity) []Record {
+	username := activity.UserInfo.Username
+	records := make([]Record, 0, len(activity.Posts)+len(activity.Comments))
+	for _, p := range activity.Posts {
+		records = append(records, UserItemRecord{
+			Kind:       "post",
+			Username:   username,
+			ID:         p.ID,
+			Subreddit:  p.Subreddit,
+			Title:      p.Title,
+			Body:       p.Body,
+			Score:      p.Score,
+			CreatedAt:  p.CreatedAt,
+			CreatedUTC: p.CreatedUTC,
+			URL:        p.URL,
+		})
+	}
+	for _, c := range activity.Comments {
+		records = append(records, UserItemRecord{
+			Kind:       "comment",
+			Username:   username,
+			ID:         c.ID,
+			Subreddit:  c.Subreddit,
+			PostID:     c.PostID,
+			Title:      c.PostTitle,
+			Body:       c.Body,
+			Score:      c.Score,
+			CreatedAt:  c.CreatedAt,
+			CreatedUTC: c.CreatedUTC,
+		})
+	}
+	return records
+}
+
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339Nano)
+}
+
+// formatEpoch writes Unix seconds without an exponent or trailing zeros
+func formatEpoch(utc float64) string {
+	if utc == 0 {
+		return ""
+	}
+	return strconv.FormatFloat(utc, 'f', -1, 64)
+}
@@ -0,0 +1,59 @@
+// internal/export/sink.go
+package export
+
+import (
+	"context"
+	"sync"
+
+	"reddit-ingestion/pkg/models"
+)
+
+// RecordSink is a sink.Sink that writes everything it receives as records
+// through a Writer. Posts, comments and user items have different columns, so
+// it is meant for NDJSON output.
+type RecordSink struct {
+	mu         sync.Mutex
+	w          *Writer
+	anonymizer *Anonymizer
+}
+
+func NewRecordSink(w *Writer) *RecordSink {
+	return &RecordSink{w: w}
+}
+
+// NewAnonymizingRecordSink is a RecordSink that pseudonymizes every record
+// before writing it
+func NewAnonymizingRecordSink(w *Writer, anonymizer *Anonymizer) *RecordSink {
+	return &RecordSink{w: w, anonymizer: anonymizer}
+}
+
+func (s *RecordSink) WritePosts(ctx context.Context, posts []models.Post) error {
+	return s.write(PostRecords(posts))
+}
+
+func (s *RecordSink) WriteComments(ctx context.Context, postID string, comments []models.Comment) error {
+	return s.write(CommentRecords(models.PostDetail{
+		Post:     models.Post{ID: postID},
+		Comments: comments,
+	}))
+}
+
+func (s *RecordSink) WriteUserActivity(ctx context.Context, activity models.UserActivity) error {
+	return s.write(UserActivityRecords(activity))
+}
+
+// Close flushes buffered output; the underlying io.Writer is owned by the caller
+func (s *RecordSink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.w.Flush()
+}
+
+func (s *RecordSink) write(records []Record) error {
+	if s.anonymizer != nil {
+		records = s.anonymizer.Records(records)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.w.Write(nil, records)
+}
@@ -0,0 +1,127 @@
+// internal/export/writer.go
+package export
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Format is an output encoding understood by Writer
+type Format string
+
+const (
+	FormatJSON   Format = "json"
+	FormatNDJSON Format = "ndjson"
+	FormatCSV    Format = "csv"
+)
+
+// ParseFormat validates a user supplied format name
+func ParseFormat(s string) (Format, error) {
+	switch f := Format(strings.ToLower(strings.TrimSpace(s))); f {
+	case FormatJSON, FormatNDJSON, FormatCSV:
+		return f, nil
+	case "":
+		return FormatJSON, nil
+	default:
+		return "", fmt.Errorf("unsupported format %q, must be json, ndjson or csv", s)
+	}
+}
+
+// Record is one flat row of a result, written as a single NDJSON line or CSV row.
+// Records are marshalled with encoding/json for NDJSON, so implementations carry json tags.
+type Record interface {
+	Columns() []string
+	Values() []string
+}
+
+// Writer encodes scrape results in one of the supported formats. JSON output is the
+// whole result document, NDJSON and CSV output is one row per record.
+type Writer struct {
+	w           io.Writer
+	format      Format
+	csv         *csv.Writer
+	wroteHeader bool
+}
+
+func NewWriter(w io.Writer, format Format) *Writer {
+	ew := &Writer{w: w, format: format}
+	if format == FormatCSV {
+		ew.csv = csv.NewWriter(w)
+	}
+	return ew
+}
+
+// Write emits a result. doc is used for JSON output, records for NDJSON and CSV.
+// CSV takes its header from the first record written.
+func (w *Writer) Write(doc interface{}, records []Record) error {
+	switch w.format {
+	case FormatNDJSON:
+		enc := json.NewEncoder(w.w)
+		for _, r := range records {
+			if err := w.rotateIfFull(); err != nil {
+				return err
+			}
+			if err := enc.Encode(r); err != nil {
+				return fmt.Errorf("failed to encode record: %w", err)
+			}
+		}
+		return nil
+	case FormatCSV:
+		for _, r := range records {
+			if err := w.rotateIfFull(); err != nil {
+				return err
+			}
+			if !w.wroteHeader {
+				if err := w.csv.Write(r.Columns()); err != nil {
+					return fmt.Errorf("failed to write csv header: %w", err)
+				}
+				w.wroteHeader = true
+			}
+			if err := w.csv.Write(r.Values()); err != nil {
+				return fmt.Errorf("failed to write csv row: %w", err)
+			}
+		}
+		return nil
+	default:
+		enc := json.NewEncoder(w.w)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(doc); err != nil {
+			return fmt.Errorf("failed to encode document: %w", err)
+		}
+		return nil
+	}
+}
+
+// Flush writes any buffered CSV data to the underlying writer
+func (w *Writer) Flush() error {
+	if w.csv == nil {
+		return nil
+	}
+	w.csv.Flush()
+	return w.csv.Error()
+}
+
+// rotateIfFull moves a size-limited File on to its next file once the current
+// one is full. It runs before each record, so no record is split across files
+// and no file is started without one; CSV output repeats its header in the new
+// file.
+func (w *Writer) rotateIfFull() error {
+	f, ok := w.w.(*File)
+	if !ok || f.opts.MaxBytes <= 0 {
+		return nil
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	if !f.full() {
+		return nil
+	}
+	if err := f.rotate(); err != nil {
+		return err
+	}
+	w.wroteHeader = false
+	return nil
+}
@@ -0,0 +1,440 @@
+// internal/fakereddit/content.go
+package fakereddit
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//go:embed fixtures/*.json
+var fixtures embed.FS
+
+// Templates of the data of a post, a comment and a user, taken from real
+// responses; generated items override their content fields
+var (
+	postTemplate    = mustFixture("post.json")
+	commentTemplate = mustFixture("comment.json")
+	userTemplate    = mustFixture("user.json")
+)
+
+func mustFixture(name string) map[string]any {
+	data, err := fixtures.ReadFile("fixtures/" + name)
+	if err != nil {
+		panic(fmt.Sprintf("fakereddit: missing fixture %s: %v", name, err))
+	}
+	var template map[string]any
+	if err := json.Unmarshal(data, &template); err != nil {
+		panic(fmt.Sprintf("fakereddit: invalid fixture %s: %v", name, err))
+	}
+	return template
+}
+
+// fromTemplate copies template with fields overridden
+func fromTemplate(template map[string]any, fields map[string]any) map[string]any {
+	data := make(map[string]any, len(template))
+	for key, value := range template {
+		data[key] = value
+	}
+	for key, value := range fields {
+		data[key] = value
+	}
+	return data
+}
+
+// postInterval is how far apart the posts of a listing were created
+const postInterval = 7 * time.Minute
+
+// keyLength is the length of the prefix identifying a listing in item ids
+const keyLength = 4
+
+// visibleReplies is how many replies of a comment a post page shows before
+// a "more" node
+const visibleReplies = 2
+
+var (
+	subreddits = []string{"golang", "programming", "datascience", "selfhosted", "webdev"}
+	authors    = []string{"gopher_42", "lambda_lover", "null_pointer", "kernel_panic", "tabs_not_spaces", "rubber_duck"}
+	flairs     = []any{"Discussion", nil, "Help", "Show and Tell", nil, "News"}
+	titles     = []string{
+		"Is it worth rewriting our ingestion pipeline?",
+		"What finally made goroutines click for you",
+		"Benchmarks of three JSON decoders on large payloads",
+		"Weekly self-promotion and project thread",
+		"How do you test code that talks to rate-limited APIs?",
+		"Migrating 40 services to structured logging: lessons learned",
+		"Ask: best way to page through a capped listing",
+	}
+	bodies = []string{
+		"We've been running this in production for a year and the retries are what bit us most.",
+		"Short answer: it depends on how much of the tree you actually need.",
+		"Has anyone measured this with real traffic rather than a synthetic benchmark?",
+		"I wrote up the details in the linked post, happy to answer questions here.",
+		"This is the way. Backoff with jitter and a budget per upstream.",
+		"Came here to say the same thing, plus make the cursor part of the state you persist.",
+	}
+)
+
+// key is the base36 prefix of the ids of a listing's items
+func key(name string) string {
+	k := strconv.FormatUint(uint64(fnvSum(name)%1679616), 36)
+	return strings.Repeat("0", keyLength-len(k)) + k
+}
+
+// pick chooses from options by a seed derived from parts
+func pick[T any](options []T, parts ...any) T {
+	return options[fnvSum(parts...)%uint32(len(options))]
+}
+
+// itemID returns the id of item i of a listing and remembers the listing, so
+// the page and cursor of the item can be found again
+func (s *Server) itemID(name string, i int) string {
+	k := key(name)
+	s.mutex.Lock()
+	s.listings[k] = name
+	s.mutex.Unlock()
+	return k + strconv.FormatInt(int64(i), 36)
+}
+
+// locate finds the listing and index of an item id; unknown ids are posts of
+// r/golang
+func (s *Server) locate(id string) (string, int) {
+	index := 0
+	if len(id) > keyLength {
+		if n, err := strconv.ParseInt(id[keyLength:], 36, 64); err == nil {
+			index = int(n)
+		}
+	}
+	s.mutex.Lock()
+	name, ok := s.listings[id[:min(keyLength, len(id))]]
+	s.mutex.Unlock()
+	if !ok {
+		return "r/golang", index
+	}
+	return name, index
+}
+
+// cursorIndex returns the index of the item an after cursor points at
+func (s *Server) cursorIndex(name, after string) (int, bool) {
+	_, id, ok := strings.Cut(after, "_")
+	if !ok || len(id) <= keyLength || id[:keyLength] != key(name) {
+		return 0, false
+	}
+	n, err := strconv.ParseInt(id[keyLength:], 36, 64)
+	if err != nil || n < 0 {
+		return 0, false
+	}
+	return int(n), true
+}
+
+// created is the creation time of item i of a listing, newest first
+func (s *Server) created(i int) time.Time {
+	return s.opts.Now.Add(-time.Duration(i+1) * postInterval)
+}
+
+// itemKind is the kind of item i of a listing: comments in a user's
+// comments, alternating posts and comments in an overview, posts elsewhere
+func (s *Server) itemKind(name string, i int) string {
+	switch {
+	case strings.HasPrefix(name, "comments/"):
+		return "t1"
+	case strings.HasPrefix(name, "overview/") && i%2 == 1:
+		return "t1"
+	default:
+		return "t3"
+	}
+}
+
+func (s *Server) item(name string, i int) map[string]any {
+	if s.itemKind(name, i) == "t1" {
+		return thing("t1", s.userComment(name, i))
+	}
+	return thing("t3", s.post(name, i))
+}
+
+// listingUser is the user whose page a listing is, if it is one
+func listingUser(name string) string {
+	for _, prefix := range []string{"submitted/", "comments/", "overview/"} {
+		if user, ok := strings.CutPrefix(name, prefix); ok {
+			return user
+		}
+	}
+	return ""
+}
+
+// post generates post i of a listing
+func (s *Server) post(name string, i int) map[string]any {
+	return s.postWithID(name, i, s.itemID(name, i))
+}
+
+// postByID generates the post with id, as the listing it was listed in shows
+// it, and returns its index there. A post the fake never listed is one of
+// r/golang that keeps id, so a page fetched by id always shows that post.
+func (s *Server) postByID(id string) (map[string]any, int) {
+	name, index := s.locate(id)
+	return s.postWithID(name, index, id), index
+}
+
+// postWithID generates post i of a listing with id
+func (s *Server) postWithID(name string, i int, id string) map[string]any {
+	sub, ok := strings.CutPrefix(name, "r/")
+	if !ok {
+		sub = pick(subreddits, name, i)
+	}
+	author := listingUser(name)
+	if author == "" {
+		author = pick(authors, name, i, "author")
+	}
+	title := titles[i%len(titles)]
+	if q, ok := strings.CutPrefix(name, "search/"); ok && q != "" {
+		title = q + ": " + title
+	}
+	created := s.created(i)
+	permalink := fmt.Sprintf("/r/%s/comments/%s/%s/", sub, id, slug(title))
+
+	fields := map[string]any{
+		"id":                      id,
+		"name":                    fullname("t3", id),
+		"title":                   title,
+		"author":                  author,
+		"author_fullname":         fullname("t2", key(author)),
+		"subreddit":               sub,
+		"subreddit_name_prefixed": "r/" + sub,
+		"subreddit_id":            fullname("t5", key(sub)),
+		"subreddit_subscribers":   1000 + int(fnvSum(sub)%250000),
+		"permalink":               permalink,
+		"created":                 float64(created.Unix()),
+		"created_utc":             float64(created.Unix()),
+		"score":                   1 + int(fnvSum(id)%500),
+		"ups":                     1 + int(fnvSum(id)%500),
+		"num_comments":            s.threadSize(id, created),
+		"link_flair_text":         flairs[i%len(flairs)],
+	}
+	if i%3 == 2 {
+		// A link post
+		fields["is_self"] = false
+		fields["domain"] = "example.com"
+		fields["url"] = "https://example.com/articles/" + id
+		fields["thumbnail"] = "default"
+	} else {
+		fields["selftext"] = bodies[i%len(bodies)]
+		fields["domain"] = "self." + sub
+		fields["url"] = "https://www.reddit.com" + permalink
+	}
+	return fromTemplate(postTemplate, fields)
+}
+
+// userComment generates comment i of a user's comments or overview, made on
+// a post of one of the fake subreddits
+func (s *Server) userComment(name string, i int) map[string]any {
+	id := s.itemID(name, i)
+	sub := pick(subreddits, name, i)
+	post := s.post("r/"+sub, i)
+	created := s.created(i)
+	return fromTemplate(commentTemplate, map[string]any{
+		"id":                      id,
+		"name":                    fullname("t1", id),
+		"author":                  listingUser(name),
+		"body":                    bodies[i%len(bodies)],
+		"score":                   1 + int(fnvSum(id)%200),
+		"ups":                     1 + int(fnvSum(id)%200),
+		"created":                 float64(created.Unix()),
+		"created_utc":             float64(created.Unix()),
+		"subreddit":               sub,
+		"subreddit_name_prefixed": "r/" + sub,
+		"subreddit_id":            post["subreddit_id"],
+		"link_id":                 post["name"],
+		"link_title":              post["title"],
+		"link_author":             post["author"],
+		"link_permalink":          "https://www.reddit.com" + post["permalink"].(string),
+		"parent_id":               post["name"],
+		"permalink":               post["permalink"].(string) + id + "/",
+	})
+}
+
+// user generates the about page of a user
+func (s *Server) user(username string) map[string]any {
+	profile := fromTemplate(userTemplate["subreddit"].(map[string]any), map[string]any{
+		"display_name":          "u_" + username,
+		"display_name_prefixed": "u/" + username,
+		"name":                  fullname("t5", key("u_"+username)),
+		"title":                 username,
+		"url":                   "/user/" + username + "/",
+		"subscribers":           int(fnvSum(username) % 500),
+	})
+	created := s.opts.Now.AddDate(-3, 0, -int(fnvSum(username)%1000))
+	linkKarma := int(fnvSum(username, "link") % 20000)
+	commentKarma := int(fnvSum(username, "comment") % 50000)
+	return fromTemplate(userTemplate, map[string]any{
+		"id":            key(username),
+		"name":          username,
+		"created":       float64(created.Unix()),
+		"created_utc":   float64(created.Unix()),
+		"link_karma":    linkKarma,
+		"comment_karma": commentKarma,
+		"total_karma":   linkKarma + commentKarma,
+		"subreddit":     profile,
+	})
+}
+
+func fnvSum(parts ...any) uint32 {
+	h := fnv.New32a()
+	fmt.Fprint(h, parts...)
+	return h.Sum32()
+}
+
+// slug is the title part of a permalink
+func slug(title string) string {
+	var b strings.Builder
+	for _, word := range strings.Fields(strings.ToLower(title)) {
+		word = strings.Map(func(r rune) rune {
+			if r >= 'a' && r <= 'z' || r >= '0' && r <= '9' {
+				return r
+			}
+			return -1
+		}, word)
+		if word == "" {
+			continue
+		}
+		if b.Len() > 0 {
+			b.WriteByte('_')
+		}
+		b.WriteString(word)
+	}
+	return b.String()
+}
+
+// comment is a comment of a generated thread
+type comment struct {
+	id      string
+	parent  string
+	author  string
+	body    string
+	score   int
+	depth   int
+	created time.Time
+	replies []*comment
+}
+
+// thread generates the comments of a post: Options.Comments top-level
+// comments, up to three replies to each and a few replies to those
+func (s *Server) thread(postID string, postCreated time.Time) []*comment {
+	newComment := func(path string, parent string, depth int, after time.Duration) *comment {
+		id := commentID(postID, path)
+		created := postCreated.Add(after)
+		if created.After(s.opts.Now) {
+			created = s.opts.Now
+		}
+		return &comment{
+			id:      id,
+			parent:  parent,
+			author:  pick(authors, postID, path),
+			body:    pick(bodies, postID, path),
+			score:   1 + int(fnvSum(id)%300) - depth*5,
+			depth:   depth,
+			created: created,
+		}
+	}
+
+	comments := make([]*comment, 0, s.opts.Comments)
+	for j := 0; j < s.opts.Comments; j++ {
+		top := newComment(strconv.Itoa(j), fullname("t3", postID), 0, time.Duration(j+1)*3*time.Minute)
+		for k := 0; k < j%4; k++ {
+			path := fmt.Sprintf("%d.%d", j, k)
+			reply := newComment(path, fullname("t1", top.id), 1, time.Duration(j+1)*3*time.Minute+time.Duration(k+1)*time.Minute)
+			if (j+k)%3 == 0 {
+				reply.replies = append(reply.replies, newComment(path+".0", fullname("t1", reply.id), 2, time.Duration(j+2)*3*time.Minute+time.Duration(k+1)*time.Minute))
+			}
+			top.replies = append(top.replies, reply)
+		}
+		comments = append(comments, top)
+	}
+	return comments
+}
+
+// threadSize counts the comments of a post
+func (s *Server) threadSize(postID string, postCreated time.Time) int {
+	byID := make(map[string]*comment)
+	indexThread(s.thread(postID, postCreated), byID)
+	return len(byID)
+}
+
+func indexThread(comments []*comment, byID map[string]*comment) {
+	for _, c := range comments {
+		byID[c.id] = c
+		indexThread(c.replies, byID)
+	}
+}
+
+// commentID derives the id of the comment at path in the thread of a post
+func commentID(postID, path string) string {
+	id := strconv.FormatUint(uint64(fnvSum(postID, "/", path)), 36)
+	return strings.Repeat("0", 7-len(id)) + id
+}
+
+// commentData renders a comment of a post's thread
+func (s *Server) commentData(c *comment, post map[string]any) map[string]any {
+	return fromTemplate(commentTemplate, map[string]any{
+		"id":                      c.id,
+		"name":                    fullname("t1", c.id),
+		"author":                  c.author,
+		"body":                    c.body,
+		"score":                   c.score,
+		"ups":                     c.score,
+		"depth":                   c.depth,
+		"created":                 float64(c.created.Unix()),
+		"created_utc":             float64(c.created.Unix()),
+		"parent_id":               c.parent,
+		"link_id":                 post["name"],
+		"subreddit":               post["subreddit"],
+		"subreddit_name_prefixed": post["subreddit_name_prefixed"],
+		"subreddit_id":            post["subreddit_id"],
+		"is_submitter":            c.author == post["author"],
+		"permalink":               post["permalink"].(string) + c.id + "/",
+	})
+}
+
+// renderComments renders comments as the children of a listing on a post
+// page. Replies past the first few, and a quarter of the top-level comments,
+// are left behind "more" nodes for morechildren to load.
+func (s *Server) renderComments(comments []*comment, parent string, post map[string]any) []any {
+	visible := len(comments)
+	if parent == post["name"] {
+		visible -= len(comments) / 4
+	} else {
+		visible = min(visible, visibleReplies)
+	}
+
+	children := make([]any, 0, visible+1)
+	for _, c := range comments[:visible] {
+		data := s.commentData(c, post)
+		data["replies"] = ""
+		if len(c.replies) > 0 {
+			data["replies"] = listing(s.renderComments(c.replies, fullname("t1", c.id), post), "")
+		}
+		children = append(children, thing("t1", data))
+	}
+	if hidden := comments[visible:]; len(hidden) > 0 {
+		ids := make([]string, 0, len(hidden))
+		count := 0
+		for _, c := range hidden {
+			ids = append(ids, c.id)
+			byID := make(map[string]*comment)
+			indexThread([]*comment{c}, byID)
+			count += len(byID)
+		}
+		children = append(children, thing("more", map[string]any{
+			"count":     count,
+			"name":      fullname("t1", ids[0]),
+			"id":        ids[0],
+			"parent_id": parent,
+			"depth":     hidden[0].depth,
+			"children":  ids,
+		}))
+	}
+	return children
+}
@@ -0,0 +1,381 @@
+// internal/fakereddit/server.go
+package fakereddit
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Options tune the content and failures of a fake Reddit
+type Options struct {
+	// Latency delays every response
+	Latency time.Duration
+	// ErrorRate is the share of requests answered with ErrorStatus, 0 to 1
+	ErrorRate float64
+	// ErrorStatus is the status of injected errors, 503 by default
+	ErrorStatus int
+	// Seed makes the injected errors reproducible; 0 seeds from the clock
+	Seed int64
+
+	// ListingSize is how many items every listing holds, 250 by default
+	ListingSize int
+	// Comments is how many top-level comments every post has, 8 by default
+	Comments int
+	// Now is the creation time listings count back from, the start-up time by
+	// default
+	Now time.Time
+}
+
+// Server is a fake Reddit on a local httptest server. It serves the JSON of
+// the endpoints the client uses: subreddit and feed listings, post pages,
+// morechildren, other discussions, user pages and search. Content is
+// generated from fixtures of real Reddit responses and is the same for the
+// same options, so a post found in a listing has the same page every time.
+// Query filters such as sort and t are accepted and ignored.
+type Server struct {
+	*httptest.Server
+
+	opts Options
+
+	mutex    sync.Mutex
+	rng      *rand.Rand
+	failures []int
+	requests map[string]int
+	// Listing of each item id prefix, to find the items of a cursor or page
+	listings map[string]string
+}
+
+// Routes counted by Requests
+const (
+	RouteListing      = "listing"
+	RoutePost         = "post"
+	RouteDuplicates   = "duplicates"
+	RouteMoreChildren = "morechildren"
+	RouteUser         = "user"
+	RouteSearch       = "search"
+)
+
+// feedSorts are the orderings of the front page and subreddit listings
+var feedSorts = map[string]bool{"hot": true, "new": true, "top": true, "rising": true, "controversial": true, "best": true}
+
+// NewServer starts a fake Reddit; Close stops it
+func NewServer(opts Options) *Server {
+	if opts.ErrorStatus == 0 {
+		opts.ErrorStatus = http.StatusServiceUnavailable
+	}
+	if opts.Seed == 0 {
+		opts.Seed = time.Now().UnixNano()
+	}
+	if opts.ListingSize <= 0 {
+		opts.ListingSize = 250
+	}
+	if opts.Comments <= 0 {
+		opts.Comments = 8
+	}
+	if opts.Now.IsZero() {
+		opts.Now = time.Now()
+	}
+	opts.Now = opts.Now.UTC().Truncate(time.Second)
+
+	s := &Server{
+		opts:     opts,
+		rng:      rand.New(rand.NewSource(opts.Seed)),
+		requests: make(map[string]int),
+		listings: make(map[string]string),
+	}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serve))
+	return s
+}
+
+// FailNext answers the next n requests with status, before any random error
+func (s *Server) FailNext(n int, status int) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	for i := 0; i < n; i++ {
+		s.failures = append(s.failures, status)
+	}
+}
+
+// Requests reports how many requests reached route, failed ones included, or
+// all routes when route is ""
+func (s *Server) Requests(route string) int {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if route != "" {
+		return s.requests[route]
+	}
+	total := 0
+	for _, n := range s.requests {
+		total += n
+	}
+	return total
+}
+
+func (s *Server) serve(w http.ResponseWriter, r *http.Request) {
+	route, handle := s.route(r)
+	if handle == nil {
+		writeError(w, http.StatusNotFound)
+		return
+	}
+
+	s.mutex.Lock()
+	s.requests[route]++
+	status := 0
+	if len(s.failures) > 0 {
+		status = s.failures[0]
+		s.failures = s.failures[1:]
+	} else if s.opts.ErrorRate > 0 && s.rng.Float64() < s.opts.ErrorRate {
+		status = s.opts.ErrorStatus
+	}
+	s.mutex.Unlock()
+
+	if s.opts.Latency > 0 {
+		timer := time.NewTimer(s.opts.Latency)
+		select {
+		case <-timer.C:
+		case <-r.Context().Done():
+			timer.Stop()
+			return
+		}
+	}
+	if status != 0 {
+		writeError(w, status)
+		return
+	}
+	handle(w, r)
+}
+
+// route finds the handler of a request by its path, the way Reddit routes
+// with or without the .json suffix
+func (s *Server) route(r *http.Request) (string, http.HandlerFunc) {
+	path := strings.TrimSuffix(strings.Trim(r.URL.Path, "/"), ".json")
+	segments := strings.Split(strings.TrimSuffix(path, "/"), "/")
+	query := r.URL.Query()
+
+	switch {
+	case len(segments) == 1 && segments[0] == "search":
+		return RouteSearch, func(w http.ResponseWriter, r *http.Request) {
+			s.writeListing(w, searchListing(query.Get("q")), query)
+		}
+	case len(segments) == 1 && (segments[0] == "" || feedSorts[segments[0]]):
+		return RouteListing, func(w http.ResponseWriter, r *http.Request) {
+			s.writeListing(w, "feed/frontpage", query)
+		}
+	case segments[0] == "r" && (len(segments) == 2 || len(segments) == 3 && feedSorts[segments[2]]):
+		name := "r/" + strings.ToLower(segments[1])
+		if name == "r/all" || name == "r/popular" {
+			name = "feed/" + segments[1]
+		}
+		return RouteListing, func(w http.ResponseWriter, r *http.Request) {
+			s.writeListing(w, name, query)
+		}
+	case len(segments) >= 2 && segments[0] == "comments":
+		return RoutePost, func(w http.ResponseWriter, r *http.Request) {
+			s.writePost(w, segments[1])
+		}
+	case len(segments) >= 4 && segments[0] == "r" && segments[2] == "comments":
+		return RoutePost, func(w http.ResponseWriter, r *http.Request) {
+			s.writePost(w, segments[3])
+		}
+	case len(segments) == 2 && segments[0] == "duplicates":
+		return RouteDuplicates, func(w http.ResponseWriter, r *http.Request) {
+			s.writeDuplicates(w, segments[1], query)
+		}
+	case len(segments) == 2 && segments[0] == "api" && segments[1] == "morechildren":
+		return RouteMoreChildren, s.writeMoreChildren
+	case len(segments) >= 3 && (segments[0] == "user" || segments[0] == "u"):
+		username := segments[1]
+		switch segments[2] {
+		case "about":
+			return RouteUser, func(w http.ResponseWriter, r *http.Request) {
+				writeJSON(w, thing("t2", s.user(username)))
+			}
+		case "submitted", "comments", "overview":
+			name := segments[2] + "/" + username
+			return RouteUser, func(w http.ResponseWriter, r *http.Request) {
+				s.writeListing(w, name, query)
+			}
+		}
+	}
+	return "", nil
+}
+
+// writeListing writes the page of a listing after the cursor of the query
+func (s *Server) writeListing(w http.ResponseWriter, name string, query url.Values) {
+	limit := 25
+	if values := query["limit"]; len(values) > 0 {
+		if n, err := strconv.Atoi(values[0]); err == nil && n > 0 {
+			limit = min(n, 100)
+		}
+	}
+	start := 0
+	if values := query["after"]; len(values) > 0 && values[0] != "" {
+		index, ok := s.cursorIndex(name, values[0])
+		if !ok {
+			// Reddit answers an unknown cursor with an empty page
+			writeJSON(w, listing(nil, ""))
+			return
+		}
+		start = index + 1
+	}
+
+	end := min(start+limit, s.opts.ListingSize)
+	children := make([]any, 0, max(end-start, 0))
+	for i := start; i < end; i++ {
+		children = append(children, s.item(name, i))
+	}
+	after := ""
+	if end < s.opts.ListingSize && len(children) > 0 {
+		after = fullname(s.itemKind(name, end-1), s.itemID(name, end-1))
+	}
+	writeJSON(w, listing(children, after))
+}
+
+// writePost writes a post page: the post and its comment tree, with the
+// comments past the first few behind "more" nodes
+func (s *Server) writePost(w http.ResponseWriter, postID string) {
+	post, index := s.postByID(postID)
+	comments := s.thread(postID, s.created(index))
+	writeJSON(w, []any{
+		listing([]any{thing("t3", post)}, ""),
+		listing(s.renderComments(comments, "t3_"+postID, post), ""),
+	})
+}
+
+// writeDuplicates writes the other discussions page of a post: the post and
+// its crossposts
+func (s *Server) writeDuplicates(w http.ResponseWriter, postID string, query url.Values) {
+	post, _ := s.postByID(postID)
+
+	crossposts := make([]any, 0, 2)
+	for i := 0; i < 2; i++ {
+		crosspost := s.post("duplicates/"+postID, i)
+		crosspost["crosspost_parent"] = "t3_" + postID
+		crosspost["title"] = post["title"]
+		crossposts = append(crossposts, thing("t3", crosspost))
+	}
+	writeJSON(w, []any{
+		listing([]any{thing("t3", post)}, ""),
+		listing(crossposts, ""),
+	})
+}
+
+// writeMoreChildren answers /api/morechildren with the requested comments and
+// their replies as a flat list, as Reddit does
+func (s *Server) writeMoreChildren(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		writeError(w, http.StatusBadRequest)
+		return
+	}
+	postID := strings.TrimPrefix(r.Form.Get("link_id"), "t3_")
+	if postID == "" {
+		writeError(w, http.StatusBadRequest)
+		return
+	}
+	post, index := s.postByID(postID)
+	byID := make(map[string]*comment)
+	indexThread(s.thread(postID, s.created(index)), byID)
+
+	things := []any{}
+	written := make(map[string]bool)
+	var add func(c *comment)
+	add = func(c *comment) {
+		if written[c.id] {
+			return
+		}
+		written[c.id] = true
+		data := s.commentData(c, post)
+		data["replies"] = ""
+		things = append(things, thing("t1", data))
+		for _, reply := range c.replies {
+			add(reply)
+		}
+	}
+	for _, id := range strings.Split(r.Form.Get("children"), ",") {
+		if c, ok := byID[strings.TrimSpace(id)]; ok {
+			add(c)
+		}
+	}
+
+	writeJSON(w, map[string]any{
+		"json": map[string]any{
+			"errors": []any{},
+			"data":   map[string]any{"things": things},
+		},
+	})
+}
+
+// searchListing names the listing a search query returns: the subreddit or
+// user it is restricted to, or posts matching the query
+func searchListing(q string) string {
+	for _, term := range strings.Fields(q) {
+		if sub, ok := strings.CutPrefix(term, "subreddit:"); ok && sub != "" {
+			return "r/" + strings.ToLower(sub)
+		}
+		if author, ok := strings.CutPrefix(term, "author:"); ok && author != "" {
+			return "submitted/" + author
+		}
+	}
+	return "search/" + q
+}
+
+func listing(children []any, after string) map[string]any {
+	if children == nil {
+		children = []any{}
+	}
+	var cursor any
+	if after != "" {
+		cursor = after
+	}
+	return map[string]any{
+		"kind": "Listing",
+		"data": map[string]any{
+			"after":      cursor,
+			"dist":       len(children),
+			"modhash":    "",
+			"geo_filter": "",
+			"children":   children,
+			"before":     nil,
+		},
+	}
+}
+
+func thing(kind string, data map[string]any) map[string]any {
+	return map[string]any{"kind": kind, "data": data}
+}
+
+func fullname(kind, id string) string {
+	return kind + "_" + id
+}
+
+func writeJSON(w http.ResponseWriter, v any) {
+	body, err := json.Marshal(v)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	w.Header().Set("X-Ratelimit-Used", "1")
+	w.Header().Set("X-Ratelimit-Remaining", "99.0")
+	w.Header().Set("X-Ratelimit-Reset", "600")
+	w.Write(body)
+}
+
+// writeError writes an error the way Reddit's JSON endpoints do
+func writeError(w http.ResponseWriter, status int) {
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	if status == http.StatusTooManyRequests {
+		w.Header().Set("Retry-After", "1")
+		w.Header().Set("X-Ratelimit-Remaining", "0.0")
+	}
+	w.WriteHeader(status)
+	fmt.Fprintf(w, `{"message": %q, "error": %d}`, http.StatusText(status), status)
+}
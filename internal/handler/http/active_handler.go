@@ -0,0 +1,56 @@
+// internal/handler/http/active_handler.go
+package http
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"reddit-ingestion/internal/active"
+)
+
+type ActiveHandler struct {
+	registry *active.Registry
+}
+
+// ActiveResponse lists the scrapes in progress
+type ActiveResponse struct {
+	Count      int                `json:"count"`
+	Operations []active.Operation `json:"operations"`
+}
+
+func NewActiveHandler(registry *active.Registry) *ActiveHandler {
+	return &ActiveHandler{registry: registry}
+}
+
+// GetActive godoc
+// @Summary List scrapes in progress
+// @Description Returns every scrape in progress, oldest first, with its target, the time elapsed and the pages fetched from Reddit so far.
+// @Tags admin
+// @Produce json
+// @Success 200 {object} ActiveResponse
+// @Router /admin/active [get]
+func (h *ActiveHandler) GetActive(c echo.Context) error {
+	ops := h.registry.List()
+	return c.JSON(http.StatusOK, ActiveResponse{Count: len(ops), Operations: ops})
+}
+
+// CancelActive godoc
+// @Summary Cancel a scrape in progress
+// @Description Cancels the scrape with the id listed by GET /admin/active, including its comment expansion workers. The request that started it fails with 409. Requires ADMIN_API_KEY, without which the endpoint is not registered.
+// @Tags admin
+// @Produce json
+// @Param id path string true "Operation id"
+// @Success 200 {object} map[string]interface{}
+// @Failure 401 {object} models.HTTPError
+// @Failure 404 {object} models.HTTPError
+// @Router /admin/active/{id} [delete]
+func (h *ActiveHandler) CancelActive(c echo.Context) error {
+	id := c.Param("id")
+	if !h.registry.Cancel(id) {
+		return echo.NewHTTPError(http.StatusNotFound, "no scrape in progress with id "+id)
+	}
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"id":        id,
+		"cancelled": true,
+	})
+}
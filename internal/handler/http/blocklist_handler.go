@@ -0,0 +1,94 @@
+// internal/handler/http/blocklist_handler.go
+package http
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"reddit-ingestion/internal/policy"
+)
+
+type BlocklistHandler struct {
+	blocklist *policy.Blocklist
+}
+
+func NewBlocklistHandler(blocklist *policy.Blocklist) *BlocklistHandler {
+	return &BlocklistHandler{blocklist: blocklist}
+}
+
+// GetBlocklist godoc
+// @Summary List the blocklist
+// @Description Returns every blocked subreddit and user with its source: config for BLOCKED_SUBREDDITS and BLOCKED_USERS, managed for entries added through this API.
+// @Tags admin
+// @Produce json
+// @Success 200 {object} policy.BlocklistEntries
+// @Router /admin/blocklist [get]
+func (h *BlocklistHandler) GetBlocklist(c echo.Context) error {
+	return c.JSON(http.StatusOK, h.blocklist.Entries())
+}
+
+// Block godoc
+// @Summary Block a subreddit or user
+// @Description Adds a managed blocklist entry, kept in BLOCKLIST_FILE when set. Requires ADMIN_API_KEY, without which the endpoint is not registered. Scrapes of the name are refused from then on.
+// @Tags admin
+// @Produce json
+// @Param kind path string true "subreddits or users"
+// @Param name path string true "Subreddit or username"
+// @Success 200 {object} policy.BlocklistEntries
+// @Failure 400 {object} models.ValidationError
+// @Failure 401 {object} models.HTTPError
+// @Failure 500 {object} models.HTTPError "The blocklist file could not be written"
+// @Router /admin/blocklist/{kind}/{name} [put]
+func (h *BlocklistHandler) Block(c echo.Context) error {
+	var err error
+	switch c.Param("kind") {
+	case "subreddits":
+		err = h.blocklist.BlockSubreddit(c.Param("name"))
+	case "users":
+		err = h.blocklist.BlockUser(c.Param("name"))
+	default:
+		return invalidParam("kind", "must be subreddits or users")
+	}
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+	fmt.Printf("Blocklist: blocked %s %s\n", c.Param("kind"), c.Param("name"))
+	return c.JSON(http.StatusOK, h.blocklist.Entries())
+}
+
+// Unblock godoc
+// @Summary Unblock a subreddit or user
+// @Description Removes a managed blocklist entry. Requires ADMIN_API_KEY, without which the endpoint is not registered. Entries from BLOCKED_SUBREDDITS and BLOCKED_USERS can only be lifted in the configuration.
+// @Tags admin
+// @Produce json
+// @Param kind path string true "subreddits or users"
+// @Param name path string true "Subreddit or username"
+// @Success 200 {object} policy.BlocklistEntries
+// @Failure 400 {object} models.ValidationError
+// @Failure 401 {object} models.HTTPError
+// @Failure 404 {object} models.HTTPError
+// @Failure 409 {object} models.HTTPError "Blocked by configuration"
+// @Router /admin/blocklist/{kind}/{name} [delete]
+func (h *BlocklistHandler) Unblock(c echo.Context) error {
+	var err error
+	switch c.Param("kind") {
+	case "subreddits":
+		err = h.blocklist.UnblockSubreddit(c.Param("name"))
+	case "users":
+		err = h.blocklist.UnblockUser(c.Param("name"))
+	default:
+		return invalidParam("kind", "must be subreddits or users")
+	}
+	switch {
+	case errors.Is(err, policy.ErrNotBlocked):
+		return echo.NewHTTPError(http.StatusNotFound, err.Error())
+	case errors.Is(err, policy.ErrConfigured):
+		return echo.NewHTTPError(http.StatusConflict, err.Error())
+	case err != nil:
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+	fmt.Printf("Blocklist: unblocked %s %s\n", c.Param("kind"), c.Param("name"))
+	return c.JSON(http.StatusOK, h.blocklist.Entries())
+}
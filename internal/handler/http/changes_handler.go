@@ -0,0 +1,59 @@
+// internal/handler/http/changes_handler.go
+package http
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"reddit-ingestion/internal/snapshot"
+)
+
+type ChangesHandler struct {
+	svc snapshot.DiffService
+}
+
+func NewChangesHandler(svc snapshot.DiffService) *ChangesHandler {
+	return &ChangesHandler{svc: svc}
+}
+
+// GetSubredditChanges godoc
+// @Summary Detect new, removed and changed posts in a subreddit
+// @Description Takes a fresh snapshot of the subreddit's newest posts and diffs it against the snapshot stored at or before `since`
+// @Tags subreddit
+// @Accept json
+// @Produce json
+// @Param subreddit query string true "Subreddit name without the r/ prefix"
+// @Param since query int false "Unix timestamp of the baseline snapshot (defaults to the latest snapshot)"
+// @Param purpose query string false "Purpose of the scrape, recorded in the audit log (required when REQUIRE_PURPOSE is set)"
+// @Param pool query string false "Only use proxies with this label, e.g. residential"
+// @Success 200 {object} models.SubredditChanges
+// @Failure 400 {object} models.ValidationError
+// @Failure 403 {object} models.HTTPError
+// @Failure 404 {object} models.HTTPError "Not found on Reddit"
+// @Failure 502 {object} models.HTTPError
+// @Failure 503 {object} models.HTTPError "Every proxy has used its daily or monthly bandwidth budget"
+// @Router /subreddit/changes [get]
+func (h *ChangesHandler) GetSubredditChanges(c echo.Context) error {
+	p := newParams(c)
+	sr := p.subreddit("subreddit")
+	var since time.Time
+	if c.QueryParam("since") != "" {
+		since = time.Unix(p.timestamp("since"), 0)
+	}
+	if err := p.err(); err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request().Context(), 60*time.Second)
+	defer cancel()
+
+	changes, err := h.svc.Changes(ctx, sr, since)
+	if err != nil {
+		return scrapeError(err, fmt.Sprintf("snapshot diff error: %v", err))
+	}
+
+	return c.JSON(http.StatusOK, changes)
+}
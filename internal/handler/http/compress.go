@@ -0,0 +1,250 @@
+// internal/handler/http/compress.go
+package http
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/andybalholm/brotli"
+	"github.com/labstack/echo/v4"
+)
+
+// Response compressions, as RESPONSE_COMPRESSION names them
+const (
+	CompressionNone   = "none"
+	CompressionGzip   = "gzip"
+	CompressionBrotli = "brotli"
+)
+
+// defaultBrotliLevel keeps brotli about as fast as gzip's default; its own
+// default of 6 is slow for responses compressed as they are served
+const defaultBrotliLevel = 4
+
+// CompressOptions configure CompressMiddleware
+type CompressOptions struct {
+	// Compression is "gzip", "brotli" (brotli for clients accepting it, gzip
+	// for the rest) or "none"
+	Compression string
+	// Levels of the codecs, 0 for their defaults: gzip takes 1 to 9, brotli
+	// 1 to 11
+	GzipLevel   int
+	BrotliLevel int
+	// Bodies smaller than MinSize bytes are sent uncompressed, unless the
+	// handler flushes first
+	MinSize int
+}
+
+// Validate reports the first invalid option
+func (o CompressOptions) Validate() error {
+	switch o.Compression {
+	case CompressionNone, CompressionGzip, CompressionBrotli:
+	default:
+		return fmt.Errorf("unsupported compression %q, must be gzip, brotli or none", o.Compression)
+	}
+	if o.GzipLevel < 0 || o.GzipLevel > gzip.BestCompression {
+		return fmt.Errorf("gzip level must be between 1 and %d, got %d", gzip.BestCompression, o.GzipLevel)
+	}
+	if o.BrotliLevel < 0 || o.BrotliLevel > brotli.BestCompression {
+		return fmt.Errorf("brotli level must be between 1 and %d, got %d", brotli.BestCompression, o.BrotliLevel)
+	}
+	if o.MinSize < 0 {
+		return fmt.Errorf("minimum size must not be negative, got %d", o.MinSize)
+	}
+	return nil
+}
+
+// encoder is a compressing writer that can be pooled
+type encoder interface {
+	io.Writer
+	Flush() error
+	Close() error
+	Reset(w io.Writer)
+}
+
+// CompressMiddleware compresses responses with the best encoding the client
+// accepts among those opts allow. Bodies are held back until MinSize bytes
+// are written or the handler flushes, so small responses go out as they are
+// and streamed ones are compressed from their first flush. Requests
+// upgrading to a WebSocket are left alone.
+func CompressMiddleware(opts CompressOptions) echo.MiddlewareFunc {
+	gzipLevel := opts.GzipLevel
+	if gzipLevel == 0 {
+		gzipLevel = gzip.DefaultCompression
+	}
+	brotliLevel := opts.BrotliLevel
+	if brotliLevel == 0 {
+		brotliLevel = defaultBrotliLevel
+	}
+	pools := map[string]*sync.Pool{
+		"gzip": {New: func() any {
+			w, _ := gzip.NewWriterLevel(io.Discard, gzipLevel)
+			return w
+		}},
+		"br": {New: func() any {
+			return brotli.NewWriterLevel(io.Discard, brotliLevel)
+		}},
+	}
+
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		if opts.Compression == CompressionNone {
+			return next
+		}
+		return func(c echo.Context) error {
+			res := c.Response()
+			res.Header().Add(echo.HeaderVary, echo.HeaderAcceptEncoding)
+			if c.Request().Header.Get("Upgrade") != "" {
+				return next(c)
+			}
+			encoding := negotiateEncoding(c.Request().Header.Get(echo.HeaderAcceptEncoding), opts.Compression == CompressionBrotli)
+			if encoding == "" {
+				return next(c)
+			}
+
+			pool := pools[encoding]
+			enc := pool.Get().(encoder)
+			cw := &compressWriter{
+				ResponseWriter: res.Writer,
+				encoder:        enc,
+				encoding:       encoding,
+				minSize:        opts.MinSize,
+			}
+			res.Writer = cw
+			defer func() {
+				cw.finish()
+				res.Writer = cw.ResponseWriter
+				enc.Reset(io.Discard)
+				pool.Put(enc)
+			}()
+			return next(c)
+		}
+	}
+}
+
+// negotiateEncoding picks br when allowed and accepted, gzip when accepted,
+// or "" for an uncompressed response
+func negotiateEncoding(acceptEncoding string, allowBrotli bool) string {
+	accepted := make(map[string]bool)
+	for _, part := range strings.Split(acceptEncoding, ",") {
+		name, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		q := 1.0
+		if value, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			if v, err := strconv.ParseFloat(value, 64); err == nil {
+				q = v
+			}
+		}
+		accepted[strings.ToLower(strings.TrimSpace(name))] = q > 0
+	}
+	switch {
+	case allowBrotli && accepted["br"]:
+		return "br"
+	case accepted["gzip"]:
+		return "gzip"
+	default:
+		return ""
+	}
+}
+
+// compressWriter buffers a response until it is known to be worth
+// compressing, then streams it through its encoder
+type compressWriter struct {
+	http.ResponseWriter
+	encoder  encoder
+	encoding string
+	minSize  int
+
+	buffer bytes.Buffer
+	code   int
+	// The header went out, with Content-Encoding when compressing
+	started     bool
+	compressing bool
+}
+
+func (w *compressWriter) WriteHeader(code int) {
+	w.Header().Del(echo.HeaderContentLength)
+	w.code = code
+}
+
+func (w *compressWriter) Write(b []byte) (int, error) {
+	if w.Header().Get(echo.HeaderContentType) == "" {
+		w.Header().Set(echo.HeaderContentType, http.DetectContentType(b))
+	}
+	if w.started {
+		if w.compressing {
+			return w.encoder.Write(b)
+		}
+		return w.ResponseWriter.Write(b)
+	}
+	w.buffer.Write(b)
+	if w.buffer.Len() >= w.minSize {
+		if err := w.start(true); err != nil {
+			return 0, err
+		}
+	}
+	return len(b), nil
+}
+
+// Flush compresses what was written so far, whatever its size, and sends it
+func (w *compressWriter) Flush() {
+	if !w.started {
+		w.start(true)
+	}
+	if w.compressing {
+		w.encoder.Flush()
+	}
+	_ = http.NewResponseController(w.ResponseWriter).Flush()
+}
+
+func (w *compressWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// start writes the header, compressing unless the handler set its own
+// Content-Encoding or the status has no body, and then the buffered body
+func (w *compressWriter) start(compress bool) error {
+	w.started = true
+	code := w.code
+	if code == 0 {
+		code = http.StatusOK
+	}
+	noBody := code == http.StatusNoContent || code == http.StatusNotModified || code < 200
+	w.compressing = compress && !noBody && w.Header().Get(echo.HeaderContentEncoding) == ""
+	if w.compressing {
+		w.Header().Set(echo.HeaderContentEncoding, w.encoding)
+		w.Header().Del(echo.HeaderContentLength)
+		w.encoder.Reset(w.ResponseWriter)
+	}
+	w.ResponseWriter.WriteHeader(code)
+	if w.buffer.Len() == 0 {
+		return nil
+	}
+	var err error
+	if w.compressing {
+		_, err = w.encoder.Write(w.buffer.Bytes())
+	} else {
+		_, err = w.ResponseWriter.Write(w.buffer.Bytes())
+	}
+	w.buffer.Reset()
+	return err
+}
+
+// finish sends a body that stayed below the minimum size as it is, or ends
+// the compressed stream
+func (w *compressWriter) finish() {
+	if !w.started {
+		if w.code == 0 && w.buffer.Len() == 0 {
+			// Nothing was written; echo's error handler writes to the
+			// original writer
+			return
+		}
+		w.start(false)
+	}
+	if w.compressing {
+		w.encoder.Close()
+	}
+}
@@ -0,0 +1,29 @@
+// internal/handler/http/config_handler.go
+package http
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"reddit-ingestion/internal/config"
+)
+
+type ConfigHandler struct {
+	live *config.Live
+}
+
+func NewConfigHandler(live *config.Live) *ConfigHandler {
+	return &ConfigHandler{live: live}
+}
+
+// GetConfig godoc
+// @Summary Show the live configuration
+// @Description Returns the configuration currently in effect, keyed by environment variable, with proxy passwords and storage credentials masked. Reflects SIGHUP reloads. Requires ADMIN_API_KEY, without which the endpoint is not registered.
+// @Tags admin
+// @Produce json
+// @Success 200 {object} map[string]interface{}
+// @Failure 401 {object} models.HTTPError
+// @Router /admin/config [get]
+func (h *ConfigHandler) GetConfig(c echo.Context) error {
+	return c.JSON(http.StatusOK, h.live.Get().Redacted())
+}
@@ -0,0 +1,85 @@
+// internal/handler/http/duplicates_handler.go
+package http
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"reddit-ingestion/internal/dedup"
+)
+
+const (
+	// defaultDuplicatesWindow is how far back duplicates are looked for
+	// unless the request says otherwise
+	defaultDuplicatesWindow = 24 * time.Hour
+	// maxDuplicatesWindow bounds the window, which is read in full
+	maxDuplicatesWindow = 7 * 24 * time.Hour
+)
+
+type DuplicatesHandler struct {
+	finder dedup.Finder
+	limits Limits
+}
+
+func NewDuplicatesHandler(finder dedup.Finder) *DuplicatesHandler {
+	return NewDuplicatesHandlerWithLimits(finder, Limits{})
+}
+
+// NewDuplicatesHandlerWithLimits creates a handler applying the ceiling of
+// limits to the limit parameter
+func NewDuplicatesHandlerWithLimits(finder dedup.Finder, limits Limits) *DuplicatesHandler {
+	return &DuplicatesHandler{finder: finder, limits: limits}
+}
+
+// GetDuplicates godoc
+// @Summary Find posts repeated across subreddits
+// @Description Reads the posts made in the given subreddits within the window and groups those sharing a content hash (normalized title and body) or a link hash (canonical link URL), keeping the groups that span at least two subreddits. Crossposts are left out.
+// @Tags subreddit
+// @Accept json
+// @Produce json
+// @Param subreddit query string true "Subreddit names joined by +, e.g. golang+rust"
+// @Param window query string false "How far back to look, e.g. 6h; defaults to 24h, at most 168h"
+// @Param limit query int false "Maximum number of posts to read, -1 (default) for every post in the window"
+// @Param purpose query string false "Purpose of the scrape, recorded in the audit log (required when REQUIRE_PURPOSE is set)"
+// @Param pool query string false "Only use proxies with this label, e.g. residential"
+// @Success 200 {object} models.Duplicates
+// @Failure 400 {object} models.ValidationError
+// @Failure 403 {object} models.HTTPError
+// @Failure 404 {object} models.HTTPError "Not found on Reddit"
+// @Failure 502 {object} models.HTTPError
+// @Failure 503 {object} models.HTTPError "Every proxy has used its daily or monthly bandwidth budget"
+// @Router /duplicates [get]
+func (h *DuplicatesHandler) GetDuplicates(c echo.Context) error {
+	p := newParams(c)
+	subreddits := p.subreddit("subreddit")
+	window := defaultDuplicatesWindow
+	if s := c.QueryParam("window"); s != "" {
+		d, err := time.ParseDuration(s)
+		switch {
+		case err != nil || d <= 0:
+			p.fail("window", "must be a positive duration such as 6h or 90m")
+		case d > maxDuplicatesWindow:
+			p.fail("window", fmt.Sprintf("must be at most %v", maxDuplicatesWindow))
+		default:
+			window = d
+		}
+	}
+	limit, _, err := h.limits.limitParam(c, "limit", -1)
+	p.check(err)
+	if err := p.err(); err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request().Context(), 60*time.Second)
+	defer cancel()
+
+	duplicates, err := h.finder.Duplicates(ctx, subreddits, window, limit)
+	if err != nil {
+		return scrapeError(err, fmt.Sprintf("duplicate search error: %v", err))
+	}
+
+	return c.JSON(http.StatusOK, duplicates)
+}
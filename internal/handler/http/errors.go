@@ -0,0 +1,36 @@
+// internal/handler/http/errors.go
+package http
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"reddit-ingestion/internal/active"
+	"reddit-ingestion/internal/policy"
+	"reddit-ingestion/pkg/utils"
+)
+
+// scrapeError maps a scrape failure to an HTTP error: 403 when the target is
+// blocked by policy, 400 when the requested proxy pool is gone, 503 when every
+// proxy is out of bandwidth, 409 when an admin cancelled the scrape, 404 when
+// Reddit has no such subreddit, user or post, otherwise 502 with the given
+// message
+func scrapeError(err error, message string) *echo.HTTPError {
+	if errors.Is(err, policy.ErrBlocked) {
+		return echo.NewHTTPError(http.StatusForbidden, err.Error())
+	}
+	if errors.Is(err, utils.ErrUnknownProxyPool) {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+	if errors.Is(err, utils.ErrBandwidthExhausted) {
+		return echo.NewHTTPError(http.StatusServiceUnavailable, err.Error())
+	}
+	if errors.Is(err, active.ErrCancelled) {
+		return echo.NewHTTPError(http.StatusConflict, active.ErrCancelled.Error())
+	}
+	if utils.UpstreamStatus(err) == http.StatusNotFound {
+		return echo.NewHTTPError(http.StatusNotFound, message+": not found on Reddit")
+	}
+	return echo.NewHTTPError(http.StatusBadGateway, message)
+}
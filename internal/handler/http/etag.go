@@ -0,0 +1,50 @@
+// internal/handler/http/etag.go
+package http
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+	"reddit-ingestion/internal/pagecache"
+	"reddit-ingestion/pkg/models"
+)
+
+// jsonWithETag writes body as JSON with an ETag computed from content, the
+// part of the response that only changes when Reddit's data does; hashing the
+// whole body would change the tag with every processing_time_ms. A request
+// whose If-None-Match carries the tag gets 304 Not Modified without a body.
+func jsonWithETag(c echo.Context, content, body interface{}) error {
+	hash, err := pagecache.ContentHash(content)
+	if err != nil {
+		fmt.Printf("Skipping ETag: %v\n", err)
+		return c.JSON(http.StatusOK, body)
+	}
+
+	etag := `"` + hash + `"`
+	c.Response().Header().Set("ETag", etag)
+	if etagMatches(c.Request().Header.Get("If-None-Match"), etag) {
+		return c.NoContent(http.StatusNotModified)
+	}
+	return c.JSON(http.StatusOK, body)
+}
+
+// postContent is the part of detail its ETag is computed from, the post, its
+// comments and related posts; the retries, parse report and scores of the
+// scrape, and whether it came from the result cache, do not change Reddit's
+// data
+func postContent(detail models.PostDetail) interface{} {
+	return models.PostDetail{Post: detail.Post, Comments: detail.Comments, Related: detail.Related}
+}
+
+// etagMatches applies the weak comparison If-None-Match calls for
+func etagMatches(ifNoneMatch, etag string) bool {
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,128 @@
+// internal/handler/http/frontpage_handler.go
+package http
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"reddit-ingestion/pkg/scraper"
+)
+
+// geoRegion matches Reddit's r/popular region codes, e.g. GLOBAL, GB or US_CA
+var geoRegion = regexp.MustCompile(`^[A-Z]{2,6}(_[A-Z]{2})?$`)
+
+var frontpageSorts = []string{"hot", "new", "top", "rising", "controversial"}
+
+// timeRanges are the time ranges of Reddit's top listings and searches
+var timeRanges = []string{"hour", "day", "week", "month", "year", "all"}
+
+type FrontpageHandler struct {
+	svc    scraper.ScraperService
+	limits Limits
+}
+
+func NewFrontpageHandler(svc scraper.ScraperService) *FrontpageHandler {
+	return NewFrontpageHandlerWithLimits(svc, Limits{})
+}
+
+// NewFrontpageHandlerWithLimits creates a handler applying limits to the
+// limit parameter
+func NewFrontpageHandlerWithLimits(svc scraper.ScraperService, limits Limits) *FrontpageHandler {
+	return &FrontpageHandler{svc: svc, limits: limits}
+}
+
+// GetFrontpagePosts godoc
+// @Summary Get posts from the front page, r/all or r/popular
+// @Description Retrieves the front page a logged-out visitor sees, r/all or r/popular. Posts come from many subreddits, so each carries its subreddit and subreddit_subscribers; posts from blocked subreddits or users are left out. Like /subreddit, the response carries an ETag of its posts.
+// @Tags frontpage
+// @Accept json
+// @Produce json
+// @Produce application/x-ndjson
+// @Param feed query string false "frontpage (default), all or popular"
+// @Param sort query string false "hot (default), new, top, rising or controversial"
+// @Param time query string false "Time range of top and controversial (hour, day, week, month, year, all)"
+// @Param geo query string false "Region r/popular is tailored to, e.g. GLOBAL, GB or US_CA"
+// @Param limit query int false "Maximum number of posts to retrieve, -1 for as many as Reddit lists; defaults to SCRAPER_DEFAULT_POST_LIMIT"
+// @Param after query string false "Continue after this post fullname, e.g. the cursor of an earlier response"
+// @Param include_awards query bool false "Include the awards of each post"
+// @Param anonymize query bool false "Replace usernames with pseudonyms that are consistent within the response"
+// @Param purpose query string false "Purpose of the scrape, recorded in the audit log (required when REQUIRE_PURPOSE is set)"
+// @Param pool query string false "Only use proxies with this label, e.g. residential"
+// @Param format query string false "json (default) or ndjson: one post per line, then a line with the meta"
+// @Param If-None-Match header string false "ETag of an earlier response"
+// @Success 200 {object} map[string]interface{}
+// @Success 304 "Not modified since the response with the given ETag"
+// @Failure 400 {object} models.ValidationError
+// @Failure 502 {object} models.HTTPError
+// @Failure 503 {object} models.HTTPError "Every proxy has used its daily or monthly bandwidth budget"
+// @Router /frontpage [get]
+func (h *FrontpageHandler) GetFrontpagePosts(c echo.Context) error {
+	p := newParams(c)
+	feed, params := frontpageParams(p)
+	limit, _, err := h.limits.limitParam(c, "limit", h.limits.DefaultPostLimit)
+	p.check(err)
+	opts, err := listingOptions(c)
+	p.check(err)
+	format, err := listingFormat(c)
+	p.check(err)
+	parent := p.withAnonymizedAuthors(p.withAwards(c.Request().Context()))
+	if err := p.err(); err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(parent, 60*time.Second)
+	defer cancel()
+
+	startTime := time.Now()
+
+	posts, listingMeta, err := h.svc.ScrapeFrontpage(ctx, feed, params, limit, opts)
+	if err != nil {
+		return scrapeError(err, fmt.Sprintf("scrape error: %v", err))
+	}
+
+	duration := time.Since(startTime)
+
+	return listingResponse(c, format, posts, addListingMeta(map[string]interface{}{
+		"requested_limit":    limit,
+		"actual_count":       len(posts),
+		"feed":               feed,
+		"params":             params,
+		"processing_time_ms": duration.Milliseconds(),
+	}, listingMeta))
+}
+
+// frontpageParams reads the feed, sort, time and geo parameters of
+// /frontpage in the form ScrapeFrontpage takes
+func frontpageParams(p *params) (string, map[string]string) {
+	feed := p.oneOf("feed", scraper.FeedFrontpage, scraper.FeedAll, scraper.FeedPopular)
+	if feed == "" {
+		feed = scraper.FeedFrontpage
+	}
+
+	params := map[string]string{"sort": "hot"}
+	if sort := p.oneOf("sort", frontpageSorts...); sort != "" {
+		params["sort"] = sort
+	}
+
+	if timeRange := p.oneOf("time", timeRanges...); timeRange != "" {
+		if params["sort"] != "top" && params["sort"] != "controversial" {
+			p.fail("time", "only applies to sort=top and sort=controversial")
+		}
+		params["t"] = timeRange
+	}
+
+	if geo := strings.ToUpper(p.c.QueryParam("geo")); geo != "" {
+		if feed != scraper.FeedPopular {
+			p.fail("geo", "only applies to feed=popular")
+		} else if !geoRegion.MatchString(geo) {
+			p.fail("geo", "must be a region code such as GLOBAL, GB or US_CA")
+		}
+		params["geo_filter"] = geo
+	}
+
+	return feed, params
+}
@@ -0,0 +1,51 @@
+// internal/handler/http/health_handler.go
+package http
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"reddit-ingestion/pkg/client"
+)
+
+// HostHealthReporter reports whether requests for Reddit's primary host go to
+// a fallback
+type HostHealthReporter interface {
+	HostHealth() client.HostHealth
+}
+
+type HealthHandler struct {
+	hosts HostHealthReporter
+}
+
+// HealthResponse is the health of the service
+type HealthResponse struct {
+	// client.HostStatusOK, or client.HostStatusDegraded while requests for
+	// the primary Reddit host go to a fallback
+	Status string `json:"status"`
+	// Failover state of the Reddit hosts and its degradation events
+	Hosts *client.HostHealth `json:"hosts,omitempty"`
+}
+
+// NewHealthHandler reports the service healthy and, when hosts is not nil,
+// degraded while hosts has failed over
+func NewHealthHandler(hosts HostHealthReporter) *HealthHandler {
+	return &HealthHandler{hosts: hosts}
+}
+
+// GetHealth godoc
+// @Summary Show service health
+// @Description Returns ok, or degraded while the primary Reddit host (REDDIT_BASE_URL) has failed REDDIT_FAILOVER_THRESHOLD requests in a row and its requests go to the first of REDDIT_FALLBACK_URLS, with the failover counters and the latest degradation events. A degraded service still answers 200: it keeps scraping through the fallback, and sends requests back once a probe of the primary succeeds.
+// @Tags health
+// @Produce json
+// @Success 200 {object} HealthResponse
+// @Router /healthz [get]
+func (h *HealthHandler) GetHealth(c echo.Context) error {
+	response := HealthResponse{Status: client.HostStatusOK}
+	if h.hosts != nil {
+		hosts := h.hosts.HostHealth()
+		response.Status = hosts.Status
+		response.Hosts = &hosts
+	}
+	return c.JSON(http.StatusOK, response)
+}
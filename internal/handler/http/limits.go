@@ -0,0 +1,61 @@
+// internal/handler/http/limits.go
+package http
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+	"reddit-ingestion/pkg/scraper"
+)
+
+// Limits are the defaults and the ceiling of the limit parameters of the
+// scraping endpoints. The zero value has no defaults, so a request without a
+// limit reads the first page, and no ceiling.
+type Limits struct {
+	// DefaultPostLimit replaces a missing or 0 limit or post_limit
+	DefaultPostLimit int
+	// DefaultCommentLimit replaces a missing or 0 comment_limit
+	DefaultCommentLimit int
+	// MaxLimit is the largest limit a request may ask for other than -1,
+	// which still asks for everything; 0 for no ceiling
+	MaxLimit int
+}
+
+// limitParam reads the limit parameter param: def when it is missing or 0.
+// defaulted reports that def was used. Limits below -1 or above the ceiling
+// are rejected with 400.
+func (l Limits) limitParam(c echo.Context, param string, def int) (limit int, defaulted bool, err error) {
+	if s := c.QueryParam(param); s != "" {
+		v, err := strconv.Atoi(s)
+		if err != nil || v < -1 {
+			return 0, false, invalidParam(param, "must be -1 or a positive integer")
+		}
+		limit = v
+	}
+	if limit == 0 && def != 0 {
+		limit, defaulted = def, true
+	}
+	if err := l.check(param, limit); err != nil {
+		return 0, false, err
+	}
+	return limit, defaulted, nil
+}
+
+// check rejects limit when it is above the ceiling
+func (l Limits) check(param string, limit int) error {
+	if l.MaxLimit > 0 && limit > l.MaxLimit {
+		return invalidParam(param, fmt.Sprintf("must be at most %d, or -1 for everything", l.MaxLimit))
+	}
+	return nil
+}
+
+// withDefaultLimit marks ctx when the limit of the request was a default, so
+// a crawl policy can replace it
+func withDefaultLimit(ctx context.Context, defaulted bool) context.Context {
+	if !defaulted {
+		return ctx
+	}
+	return scraper.WithDefaultLimit(ctx)
+}
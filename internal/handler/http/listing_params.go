@@ -0,0 +1,151 @@
+// internal/handler/http/listing_params.go
+package http
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+
+	"github.com/labstack/echo/v4"
+	"reddit-ingestion/internal/anonymize"
+	"reddit-ingestion/pkg/models"
+	"reddit-ingestion/pkg/parser"
+	"reddit-ingestion/pkg/scraper"
+)
+
+// postFullname matches the t3_ fullnames Reddit uses as listing cursors
+var postFullname = regexp.MustCompile(`^t3_[0-9a-z]+$`)
+
+// listingOptions reads the after parameter of the listing endpoints
+func listingOptions(c echo.Context) (scraper.ListingOptions, error) {
+	after := c.QueryParam("after")
+	if after != "" && !postFullname.MatchString(after) {
+		return scraper.ListingOptions{}, invalidParam("after", "must be a post fullname such as t3_abc123")
+	}
+	return scraper.ListingOptions{After: after}, nil
+}
+
+// postFilter reads the post filter parameters: flair, exclude_flair and
+// author, matched exactly (ignoring case), and title_contains and
+// body_contains, matched as substrings (ignoring case). Each is repeatable
+// and with regex=true all are regular expressions.
+func (p *params) postFilter() scraper.PostFilter {
+	regex := p.bool("regex")
+
+	var filter scraper.PostFilter
+	for _, f := range []struct {
+		param      string
+		matchers   *[]scraper.TextMatcher
+		newMatcher func(string, bool) (scraper.TextMatcher, error)
+	}{
+		{"flair", &filter.Flair, scraper.NewTextMatcher},
+		{"exclude_flair", &filter.ExcludeFlair, scraper.NewTextMatcher},
+		{"author", &filter.Author, scraper.NewTextMatcher},
+		{"title_contains", &filter.TitleContains, scraper.NewContainsMatcher},
+		{"body_contains", &filter.BodyContains, scraper.NewContainsMatcher},
+	} {
+		for _, value := range p.c.QueryParams()[f.param] {
+			if value == "" {
+				continue
+			}
+			m, err := f.newMatcher(value, regex)
+			if err != nil {
+				p.fail(f.param, fmt.Sprintf("is invalid: %v", err))
+				continue
+			}
+			*f.matchers = append(*f.matchers, m)
+		}
+	}
+	return filter
+}
+
+// nsfwMode reads the nsfw parameter: include (the default), exclude or only
+func nsfwMode(c echo.Context) (scraper.NSFWMode, error) {
+	mode, err := scraper.ParseNSFWMode(c.QueryParam("nsfw"))
+	if err != nil {
+		return "", invalidParam("nsfw", "must be include, exclude or only")
+	}
+	return mode, nil
+}
+
+// withAwards marks ctx for award parsing when the request sets
+// include_awards
+func (p *params) withAwards(ctx context.Context) context.Context {
+	if p.bool("include_awards") {
+		ctx = parser.WithAwards(ctx)
+	}
+	return ctx
+}
+
+// withRelated marks ctx for fetching related posts when the request sets
+// include_related
+func (p *params) withRelated(ctx context.Context) context.Context {
+	if p.bool("include_related") {
+		ctx = scraper.WithRelated(ctx)
+	}
+	return ctx
+}
+
+// withAnonymizedAuthors marks ctx for pseudonymizing authors when the request
+// sets anonymize
+func (p *params) withAnonymizedAuthors(ctx context.Context) context.Context {
+	if p.bool("anonymize") {
+		ctx = anonymize.WithAnonymizedAuthors(ctx)
+	}
+	return ctx
+}
+
+// withExpansion overrides the comment expansion pool sizes with the
+// expand_workers, expand_batch_size and expand_concurrency parameters
+func (p *params) withExpansion(ctx context.Context) context.Context {
+	opts := scraper.ExpansionOptions{
+		Workers:     p.positive("expand_workers"),
+		BatchSize:   p.positive("expand_batch_size"),
+		Concurrency: p.positive("expand_concurrency"),
+	}
+	if opts != (scraper.ExpansionOptions{}) {
+		ctx = scraper.WithExpansion(ctx, opts)
+	}
+	return ctx
+}
+
+// addListingMeta adds the paging fields of a listing scrape to a response meta
+func addListingMeta(meta map[string]interface{}, listing models.ListingMeta) map[string]interface{} {
+	meta["duplicates_dropped"] = listing.DuplicatesDropped
+	meta["filtered_out"] = listing.FilteredOut
+	if listing.FilterMatches != nil {
+		meta["filter_matches"] = listing.FilterMatches
+	}
+	meta["pages_fetched"] = listing.PagesFetched
+	meta["after"] = listing.After
+	meta["cursor"] = listing.Cursor
+	meta["reached_time_cutoff"] = listing.ReachedTimeCutoff
+	meta["timed_out"] = listing.TimedOut
+	meta["truncated"] = listing.Truncated
+	if listing.TruncationReason != "" {
+		meta["truncation_reason"] = listing.TruncationReason
+	}
+	if listing.OldestReached != nil {
+		meta["oldest_reached"] = listing.OldestReached
+	}
+	if listing.ReachedListingCap {
+		meta["reached_listing_cap"] = true
+		meta["backfilled"] = listing.Backfilled
+		if len(listing.CoverageGaps) > 0 {
+			meta["coverage_gaps"] = listing.CoverageGaps
+		}
+	}
+	if listing.Quality != nil {
+		meta["quality"] = listing.Quality
+	}
+	if listing.ParseReport != nil {
+		meta["parse_report"] = listing.ParseReport
+	}
+	if listing.Retries > 0 {
+		meta["retries"] = listing.Retries
+	}
+	if listing.Cached {
+		meta["cached"] = true
+	}
+	return meta
+}
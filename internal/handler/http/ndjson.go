@@ -0,0 +1,69 @@
+// internal/handler/http/ndjson.go
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"reddit-ingestion/pkg/models"
+)
+
+// Response formats of the listing endpoints
+const (
+	formatJSON   = "json"
+	formatNDJSON = "ndjson"
+)
+
+// MIMEApplicationNDJSON is the content type of NDJSON responses
+const MIMEApplicationNDJSON = "application/x-ndjson"
+
+// ndjsonFlushEvery is how many posts an NDJSON response writes between
+// flushes
+const ndjsonFlushEvery = 100
+
+// listingFormat reads the format parameter of the listing endpoints: json
+// (the default) or ndjson
+func listingFormat(c echo.Context) (string, error) {
+	switch format := c.QueryParam("format"); format {
+	case "", formatJSON:
+		return formatJSON, nil
+	case formatNDJSON:
+		return formatNDJSON, nil
+	default:
+		return "", invalidParam("format", "must be json or ndjson")
+	}
+}
+
+// listingResponse writes the posts and meta of a listing scrape in format:
+// JSON with an ETag of the posts, or NDJSON
+func listingResponse(c echo.Context, format string, posts []models.Post, meta map[string]interface{}) error {
+	if format == formatNDJSON {
+		return writeNDJSON(c, posts, meta)
+	}
+	return jsonWithETag(c, posts, map[string]interface{}{
+		"posts": posts,
+		"meta":  meta,
+	})
+}
+
+// writeNDJSON writes one post per line and then a last line holding the meta,
+// {"meta": {...}}. It flushes every ndjsonFlushEvery posts, so a client, and
+// the compression middleware, can work through the first posts before the
+// last are written.
+func writeNDJSON(c echo.Context, posts []models.Post, meta map[string]interface{}) error {
+	res := c.Response()
+	res.Header().Set(echo.HeaderContentType, MIMEApplicationNDJSON)
+	res.WriteHeader(http.StatusOK)
+
+	enc := json.NewEncoder(res)
+	for i, post := range posts {
+		if err := enc.Encode(post); err != nil {
+			return err
+		}
+		if (i+1)%ndjsonFlushEvery == 0 {
+			res.Flush()
+		}
+	}
+	return enc.Encode(map[string]interface{}{"meta": meta})
+}
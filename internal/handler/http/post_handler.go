@@ -3,11 +3,18 @@ package http
 
 import (
 	"context"
-	"net/http"
 	"time"
 
 	"github.com/labstack/echo/v4"
-	"reddit-ingestion/internal/scraper"
+	"reddit-ingestion/pkg/client"
+	"reddit-ingestion/pkg/scraper"
+)
+
+const (
+    // maxCommentDepth is the deepest comment tree Reddit returns in one page
+    maxCommentDepth = 10
+    // maxCommentLimit is the most comments Reddit returns in one page
+    maxCommentLimit = 500
 )
 
 type PostHandler struct {
@@ -20,27 +27,61 @@ func NewPostHandler(svc scraper.ScraperService) *PostHandler {
 
 // GetPostInfo godoc
 // @Summary Get a Reddit post with comments
-// @Description Retrieves a post and its comment tree from Reddit
+// @Description Retrieves a post and its comment tree from Reddit. The response carries an ETag of its content; send it back in If-None-Match to get 304 Not Modified while nothing changed.
 // @Tags post
 // @Accept json
 // @Produce json
 // @Param post_id query string true "Reddit post ID"
+// @Param include_awards query bool false "Include the awards of each post and comment"
+// @Param sort query string false "Order of the comments: best, top, new (default), controversial, old or qa"
+// @Param depth query int false "Levels of replies to fetch, 1 for top-level comments only; skips \"load more\" expansion"
+// @Param limit query int false "Most comments to fetch, up to 500; skips \"load more\" expansion"
+// @Param include_related query bool false "Also fetch the other submissions of the post's link, crossposts included"
+// @Param expand_workers query int false "\"Load more\" comment sets fetched at once, clamped to the proxy count"
+// @Param expand_batch_size query int false "\"Load more\" comment sets taken per expansion round"
+// @Param expand_concurrency query int false "Requests per \"load more\" set run at once, clamped to the proxy count"
+// @Param anonymize query bool false "Replace usernames with pseudonyms that are consistent within the response"
+// @Param purpose query string false "Purpose of the scrape, recorded in the audit log (required when REQUIRE_PURPOSE is set)"
+// @Param pool query string false "Only use proxies with this label, e.g. residential"
+// @Param If-None-Match header string false "ETag of an earlier response"
 // @Success 200 {object} models.PostDetail
-// @Failure 400 {object} models.HTTPError
+// @Success 304 "Not modified since the response with the given ETag"
+// @Failure 400 {object} models.ValidationError
+// @Failure 403 {object} models.HTTPError
+// @Failure 404 {object} models.HTTPError "Not found on Reddit"
 // @Failure 502 {object} models.HTTPError
+// @Failure 503 {object} models.HTTPError "Every proxy has used its daily or monthly bandwidth budget"
 // @Router /post [get]
 func (h *PostHandler) GetPostInfo(c echo.Context) error {
-    pid := c.QueryParam("post_id")
-    if pid == "" {
-        return echo.NewHTTPError(http.StatusBadRequest, "missing `post_id` parameter")
+    pid, parent, err := postParams(c)
+    if err != nil {
+        return err
     }
 
-    ctx, cancel := context.WithTimeout(c.Request().Context(), 300*time.Second)
+    ctx, cancel := context.WithTimeout(parent, 300*time.Second)
     defer cancel()
 
     detail, err := h.svc.ScrapePost(ctx, pid)
     if err != nil {
-        return echo.NewHTTPError(http.StatusBadGateway, err.Error())
+        return scrapeError(err, err.Error())
+    }
+    return jsonWithETag(c, postContent(detail), detail)
+}
+
+// postParams reads the parameters of /post and /ws/post: the post ID and the
+// options it returns in the request's context
+func postParams(c echo.Context) (string, context.Context, error) {
+    p := newParams(c)
+    pid := p.postID("post_id")
+    sort := p.oneOf("sort", client.CommentSorts...)
+    tree := client.CommentTree{
+        Depth: p.count("depth", maxCommentDepth),
+        Limit: p.count("limit", maxCommentLimit),
     }
-    return c.JSON(http.StatusOK, detail)
+    ctx := client.WithCommentTree(client.WithCommentSort(c.Request().Context(), sort), tree)
+    ctx = p.withAwards(ctx)
+    ctx = p.withRelated(ctx)
+    ctx = p.withExpansion(ctx)
+    ctx = p.withAnonymizedAuthors(ctx)
+    return pid, ctx, p.err()
 }
\ No newline at end of file
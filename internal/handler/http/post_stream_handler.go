@@ -0,0 +1,93 @@
+// internal/handler/http/post_stream_handler.go
+package http
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"golang.org/x/net/websocket"
+	"reddit-ingestion/pkg/models"
+	"reddit-ingestion/pkg/scraper"
+)
+
+// PostStreamEvent is one WebSocket message of /ws/post. Progress events are
+// followed by exactly one result or error event, after which the server
+// closes the connection.
+type PostStreamEvent struct {
+	// "progress", "result" or "error"
+	Type     string             `json:"type"`
+	Progress *scraper.Progress  `json:"progress,omitempty"`
+	Post     *models.PostDetail `json:"post,omitempty"`
+	Error    string             `json:"error,omitempty"`
+	// HTTP status the same error would have on /post
+	Status int `json:"status,omitempty"`
+}
+
+// StreamPostInfo godoc
+// @Summary Stream a post scrape over a WebSocket
+// @Description Upgrades to a WebSocket and scrapes the post like /post, sending a progress event whenever a page is fetched or "load more" comments are expanded, then the PostDetail as a result event. Closing the socket cancels the scrape.
+// @Tags post
+// @Produce json
+// @Param post_id query string true "Reddit post ID"
+// @Param include_awards query bool false "Include the awards of each post and comment"
+// @Param sort query string false "Order of the comments: best, top, new (default), controversial, old or qa"
+// @Param depth query int false "Levels of replies to fetch, 1 for top-level comments only; skips \"load more\" expansion"
+// @Param limit query int false "Most comments to fetch, up to 500; skips \"load more\" expansion"
+// @Param include_related query bool false "Also fetch the other submissions of the post's link, crossposts included"
+// @Param expand_workers query int false "\"Load more\" comment sets fetched at once, clamped to the proxy count"
+// @Param expand_batch_size query int false "\"Load more\" comment sets taken per expansion round"
+// @Param expand_concurrency query int false "Requests per \"load more\" set run at once, clamped to the proxy count"
+// @Param anonymize query bool false "Replace usernames with pseudonyms that are consistent within the response"
+// @Param purpose query string false "Purpose of the scrape, recorded in the audit log (required when REQUIRE_PURPOSE is set)"
+// @Param pool query string false "Only use proxies with this label, e.g. residential"
+// @Success 101 {object} PostStreamEvent "Switching protocols; the socket then carries PostStreamEvent messages"
+// @Failure 400 {object} models.ValidationError
+// @Router /ws/post [get]
+func (h *PostHandler) StreamPostInfo(c echo.Context) error {
+	pid, parent, err := postParams(c)
+	if err != nil {
+		return err
+	}
+
+	// websocket.Server skips the Origin check of websocket.Handler, which
+	// would turn away non-browser clients; CORS is open on the API anyway
+	server := websocket.Server{Handler: func(ws *websocket.Conn) {
+		defer ws.Close()
+		h.streamPost(parent, ws, pid)
+	}}
+	server.ServeHTTP(c.Response(), c.Request())
+	return nil
+}
+
+func (h *PostHandler) streamPost(parent context.Context, ws *websocket.Conn, pid string) {
+	ctx, cancel := context.WithTimeout(parent, 300*time.Second)
+	defer cancel()
+
+	// The client sends nothing; a read returning means it went away
+	go func() {
+		io.Copy(io.Discard, ws)
+		cancel()
+	}()
+
+	send := func(event PostStreamEvent) {
+		if err := websocket.JSON.Send(ws, event); err != nil && ctx.Err() == nil {
+			fmt.Printf("Failed to send %s event for post %s: %v\n", event.Type, pid, err)
+			cancel()
+		}
+	}
+
+	ctx = scraper.WithProgress(ctx, func(progress scraper.Progress) {
+		send(PostStreamEvent{Type: "progress", Progress: &progress})
+	})
+
+	detail, err := h.svc.ScrapePost(ctx, pid)
+	if err != nil {
+		httpErr := scrapeError(err, err.Error())
+		send(PostStreamEvent{Type: "error", Error: fmt.Sprint(httpErr.Message), Status: httpErr.Code})
+		return
+	}
+	send(PostStreamEvent{Type: "result", Post: &detail})
+}
@@ -0,0 +1,31 @@
+// internal/handler/http/proxy_pool.go
+package http
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+	"reddit-ingestion/pkg/utils"
+)
+
+// ProxyPoolMiddleware routes a request through the proxies labelled by its
+// pool query parameter. Requests naming a pool without proxies are rejected
+// with 400; requests without one may use every proxy.
+func ProxyPoolMiddleware(hasPool func(string) bool) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			pool := strings.TrimSpace(c.QueryParam("pool"))
+			if pool == "" {
+				return next(c)
+			}
+			if !hasPool(pool) {
+				return invalidParam("pool", fmt.Sprintf("names no proxy pool, there are no proxies labelled %s", pool))
+			}
+
+			req := c.Request()
+			c.SetRequest(req.WithContext(utils.WithProxyPool(req.Context(), pool)))
+			return next(c)
+		}
+	}
+}
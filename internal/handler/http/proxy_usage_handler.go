@@ -0,0 +1,44 @@
+// internal/handler/http/proxy_usage_handler.go
+package http
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"reddit-ingestion/pkg/utils"
+)
+
+// ProxyUsageReporter reports this month's traffic through each proxy
+type ProxyUsageReporter interface {
+	ProxyUsage() []utils.ProxyUsage
+}
+
+type ProxyUsageHandler struct {
+	usage ProxyUsageReporter
+}
+
+// ProxyUsageResponse is each proxy's traffic in the current UTC month
+type ProxyUsageResponse struct {
+	// UTC month the counters cover; they reset when it ends
+	Month   string             `json:"month"`
+	Proxies []utils.ProxyUsage `json:"proxies"`
+}
+
+func NewProxyUsageHandler(usage ProxyUsageReporter) *ProxyUsageHandler {
+	return &ProxyUsageHandler{usage: usage}
+}
+
+// GetProxyUsage godoc
+// @Summary Show monthly proxy usage
+// @Description Returns the bytes and requests each proxy carried this UTC month, its monthly cap (PROXY_MONTHLY_BANDWIDTH_MB, PROXY_MONTHLY_BANDWIDTH_CAPS) and the remaining quota. Proxies that are not available are skipped until the month ends. With PROXY_USAGE_FILE set the counters survive restarts.
+// @Tags admin
+// @Produce json
+// @Success 200 {object} ProxyUsageResponse
+// @Router /admin/proxies/usage [get]
+func (h *ProxyUsageHandler) GetProxyUsage(c echo.Context) error {
+	return c.JSON(http.StatusOK, ProxyUsageResponse{
+		Month:   time.Now().UTC().Format("2006-01"),
+		Proxies: h.usage.ProxyUsage(),
+	})
+}
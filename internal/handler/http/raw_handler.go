@@ -0,0 +1,104 @@
+// internal/handler/http/raw_handler.go
+package http
+
+import (
+	"context"
+	"crypto/subtle"
+	"fmt"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+)
+
+// AdminKeyHeader carries the admin API key; "Authorization: Bearer <key>"
+// works as well
+const AdminKeyHeader = "X-Admin-Key"
+
+// RawFetcher fetches a path of Reddit through the proxied, fingerprinting
+// client and returns the response with its body as Reddit sent it
+type RawFetcher interface {
+	FetchRaw(ctx context.Context, pathAndQuery string) (*http.Response, []byte, error)
+}
+
+type RawHandler struct {
+	fetcher   RawFetcher
+	allowlist []string
+}
+
+// NewRawHandler creates the passthrough handler for the paths matching one of
+// the allowlist patterns, in path.Match syntax
+func NewRawHandler(fetcher RawFetcher, allowlist []string) *RawHandler {
+	return &RawHandler{fetcher: fetcher, allowlist: allowlist}
+}
+
+// AdminKeyMiddleware rejects requests that do not carry key in the
+// X-Admin-Key header or as a bearer token with 401
+func AdminKeyMiddleware(key string) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			given := c.Request().Header.Get(AdminKeyHeader)
+			if given == "" {
+				given, _ = strings.CutPrefix(c.Request().Header.Get("Authorization"), "Bearer ")
+			}
+			if given == "" || subtle.ConstantTimeCompare([]byte(given), []byte(key)) != 1 {
+				return echo.NewHTTPError(http.StatusUnauthorized, "missing or invalid admin API key")
+			}
+			return next(c)
+		}
+	}
+}
+
+// GetRaw godoc
+// @Summary Fetch a raw Reddit response
+// @Description Fetches a Reddit JSON path through the proxied client and returns Reddit's status and body untouched (decompressed), for debugging the parser against live data. Requires ADMIN_API_KEY in the X-Admin-Key header or as a bearer token; only paths matching RAW_PATH_ALLOWLIST are fetched.
+// @Tags admin
+// @Produce json
+// @Param path query string true "Reddit path with its query, e.g. /r/golang/new.json?limit=5"
+// @Param pool query string false "Proxy pool to fetch through"
+// @Success 200 {object} map[string]interface{} "Reddit's response body"
+// @Failure 400 {object} models.ValidationError
+// @Failure 401 {object} models.HTTPError
+// @Failure 403 {object} models.HTTPError
+// @Failure 502 {object} models.HTTPError
+// @Failure 503 {object} models.HTTPError "Every proxy has used its daily or monthly bandwidth budget"
+// @Router /raw [get]
+func (h *RawHandler) GetRaw(c echo.Context) error {
+	rawPath := strings.TrimSpace(c.QueryParam("path"))
+	if rawPath == "" {
+		return invalidParam("path", "is required")
+	}
+	target, err := url.Parse(rawPath)
+	if err != nil || target.Scheme != "" || target.Host != "" || !strings.HasPrefix(target.Path, "/") {
+		return invalidParam("path", "must be a path on Reddit starting with /, e.g. /r/golang/new.json")
+	}
+	// A cleaned path differing from the given one hides a .. or //
+	if cleaned := path.Clean(target.Path); cleaned != target.Path && cleaned+"/" != target.Path {
+		return invalidParam("path", fmt.Sprintf("must be in canonical form, unlike %s", target.Path))
+	}
+	if !h.allowed(target.Path) {
+		return echo.NewHTTPError(http.StatusForbidden, fmt.Sprintf("path %s is not in RAW_PATH_ALLOWLIST", target.Path))
+	}
+
+	resp, body, err := h.fetcher.FetchRaw(c.Request().Context(), target.RequestURI())
+	if err != nil {
+		return scrapeError(err, fmt.Sprintf("failed to fetch %s from Reddit", target.Path))
+	}
+	contentType := resp.Header.Get("Content-Type")
+	if contentType == "" {
+		contentType = echo.MIMEApplicationJSON
+	}
+	return c.Blob(resp.StatusCode, contentType, body)
+}
+
+// allowed reports whether p matches a pattern of the allowlist
+func (h *RawHandler) allowed(p string) bool {
+	for _, pattern := range h.allowlist {
+		if ok, _ := path.Match(pattern, p); ok {
+			return true
+		}
+	}
+	return false
+}
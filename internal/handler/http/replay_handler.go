@@ -0,0 +1,48 @@
+// internal/handler/http/replay_handler.go
+package http
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"reddit-ingestion/internal/archive"
+)
+
+type ReplayHandler struct {
+	replayer *archive.Replayer
+}
+
+func NewReplayHandler(replayer *archive.Replayer) *ReplayHandler {
+	return &ReplayHandler{replayer: replayer}
+}
+
+// Replay godoc
+// @Summary Re-parse archived raw pages
+// @Description Runs the current parser over archived raw Reddit JSON below a key prefix and writes the parsed models to the configured sink. Requires ADMIN_API_KEY, without which the endpoint is not registered.
+// @Tags admin
+// @Produce json
+// @Param prefix query string true "Archive key prefix, e.g. raw/2025/04/15/r/golang"
+// @Success 200 {object} archive.ReplayResult
+// @Failure 400 {object} models.ValidationError
+// @Failure 401 {object} models.HTTPError
+// @Failure 500 {object} models.HTTPError
+// @Router /admin/replay [post]
+func (h *ReplayHandler) Replay(c echo.Context) error {
+	prefix := c.QueryParam("prefix")
+	if prefix == "" {
+		return invalidParam("prefix", "is required")
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request().Context(), 30*time.Minute)
+	defer cancel()
+
+	result, err := h.replayer.Replay(ctx, prefix)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, fmt.Sprintf("replay error: %v", err))
+	}
+
+	return c.JSON(http.StatusOK, result)
+}
@@ -5,21 +5,33 @@ import (
 	"context"
 	"fmt"
 	"net/http"
-	"strconv"
 	"strings"
 	"time"
 
-	"reddit-ingestion/internal/scraper"
+	"reddit-ingestion/pkg/scraper"
 
 	"github.com/labstack/echo/v4"
 )
 
+var searchSorts = []string{"relevance", "hot", "top", "new", "comments"}
+
+// defaultSearchLimit is the number of results of a search without a limit
+// when no default post limit is configured
+const defaultSearchLimit = 25
+
 type SearchHandler struct {
-	svc scraper.ScraperService
+	svc    scraper.ScraperService
+	limits Limits
 }
 
 func NewSearchHandler(svc scraper.ScraperService) *SearchHandler {
-	return &SearchHandler{svc: svc}
+	return NewSearchHandlerWithLimits(svc, Limits{})
+}
+
+// NewSearchHandlerWithLimits creates a handler applying limits to the limit
+// parameter
+func NewSearchHandlerWithLimits(svc scraper.ScraperService, limits Limits) *SearchHandler {
+	return &SearchHandler{svc: svc, limits: limits}
 }
 
 // Search godoc
@@ -28,39 +40,53 @@ func NewSearchHandler(svc scraper.ScraperService) *SearchHandler {
 // @Tags search
 // @Accept json
 // @Produce json
+// @Produce application/x-ndjson
 // @Param search_string query string false "Search query string"
 // @Param since_timestamp query int false "Unix timestamp to filter posts"
-// @Param limit query int false "Maximum number of results"
+// @Param limit query int false "Maximum number of results, -1 for all; defaults to SCRAPER_DEFAULT_POST_LIMIT"
 // @Param sort query string false "Sort order (relevance, hot, top, new, comments)"
 // @Param time query string false "Time range (hour, day, week, month, year, all)"
+// @Param after query string false "Continue after this post fullname, e.g. the cursor of an earlier response"
+// @Param nsfw query string false "NSFW posts: include (default), exclude or only"
+// @Param include_awards query bool false "Include the awards of each post"
+// @Param anonymize query bool false "Replace usernames with pseudonyms that are consistent within the response"
+// @Param purpose query string false "Purpose of the scrape, recorded in the audit log (required when REQUIRE_PURPOSE is set)"
+// @Param pool query string false "Only use proxies with this label, e.g. residential"
+// @Param format query string false "json (default) or ndjson: one post per line, then a line with the meta"
 // @Success 200 {object} map[string]interface{}
-// @Failure 400 {object} models.HTTPError
+// @Failure 400 {object} models.ValidationError
+// @Failure 403 {object} models.HTTPError
 // @Failure 502 {object} models.HTTPError
+// @Failure 503 {object} models.HTTPError "Every proxy has used its daily or monthly bandwidth budget"
 // @Router /search [get]
 func (h *SearchHandler) Search(c echo.Context) error {
 	query := c.QueryParam("search_string")
 
-	var limit int = 25 // Default
-	if l := c.QueryParam("limit"); l != "" {
-		v, err := strconv.Atoi(l)
-		if err != nil {
-			return echo.NewHTTPError(http.StatusBadRequest, "invalid 'limit' parameter")
-		}
-		limit = v
+	defaultLimit := h.limits.DefaultPostLimit
+	if defaultLimit == 0 {
+		defaultLimit = defaultSearchLimit
 	}
 
-	var sinceTimestamp int64
-	if s := c.QueryParam("since_timestamp"); s != "" {
-		v, err := strconv.ParseInt(s, 10, 64)
-		if err != nil {
-			return echo.NewHTTPError(http.StatusBadRequest, "invalid 'since_timestamp' parameter")
-		}
-		sinceTimestamp = v
+	p := newParams(c)
+	limit, _, err := h.limits.limitParam(c, "limit", defaultLimit)
+	p.check(err)
+	sinceTimestamp := p.timestamp("since_timestamp")
+	p.oneOf("sort", searchSorts...)
+	p.oneOf("time", timeRanges...)
+	p.matching("subreddit", subredditFormat, subredditExpected)
+	p.matching("author", usernameFormat, usernameExpected)
+	opts, err := listingOptions(c)
+	p.check(err)
+	if opts.After != "" && c.QueryParam("before") != "" {
+		p.fail("before", "cannot be combined with `after`, which pages the other way")
 	}
-
-	// Validate parameter combinations
-	if limit < -1 {
-		return echo.NewHTTPError(http.StatusBadRequest, "limit must be -1 or a positive integer")
+	opts.Filter.NSFW, err = nsfwMode(c)
+	p.check(err)
+	format, err := listingFormat(c)
+	p.check(err)
+	parent := p.withAnonymizedAuthors(p.withAwards(c.Request().Context()))
+	if err := p.err(); err != nil {
+		return err
 	}
 
 	// Increase timeout for unlimited fetching
@@ -69,16 +95,16 @@ func (h *SearchHandler) Search(c echo.Context) error {
 		timeout = 240 * time.Second
 	}
 
-	ctx, cancel := context.WithTimeout(c.Request().Context(), timeout)
+	ctx, cancel := context.WithTimeout(parent, timeout)
 	defer cancel()
 
 	startTime := time.Now()
 
 	searchParams := buildSearchParams(c)
 
-	posts, err := h.svc.Search(ctx, searchParams, sinceTimestamp, limit)
+	posts, listingMeta, err := h.svc.Search(ctx, searchParams, sinceTimestamp, limit, opts)
 	if err != nil {
-		return echo.NewHTTPError(http.StatusBadGateway, fmt.Sprintf("search_string error: %v", err))
+		return scrapeError(err, fmt.Sprintf("search_string error: %v", err))
 	}
 
 	duration := time.Since(startTime)
@@ -93,15 +119,19 @@ func (h *SearchHandler) Search(c echo.Context) error {
 		}
 	}
 
+	meta := addListingMeta(map[string]interface{}{
+		"query":              query,
+		"params":             searchParams,
+		"count":              len(posts),
+		"processing_time_ms": duration.Milliseconds(),
+		"requested_limit":    limitDescription,
+	}, listingMeta)
+	if format == formatNDJSON {
+		return writeNDJSON(c, posts, meta)
+	}
 	return c.JSON(http.StatusOK, map[string]interface{}{
 		"posts": posts,
-		"meta": map[string]interface{}{
-			"query":              query,
-			"params":             searchParams,
-			"count":              len(posts),
-			"processing_time_ms": duration.Milliseconds(),
-			"requested_limit":    limitDescription,
-		},
+		"meta":  meta,
 	})
 }
 
@@ -112,11 +142,7 @@ func buildSearchParams(c echo.Context) map[string]string {
 		params["search_string"] = searchString
 	}
 
-	// Pagination
-	if after := c.QueryParam("after"); after != "" {
-		params["after"] = after
-	}
-
+	// Pagination; after is passed as ListingOptions
 	if before := c.QueryParam("before"); before != "" {
 		params["before"] = before
 	}
@@ -143,7 +169,7 @@ func buildSearchParams(c echo.Context) map[string]string {
 
 	// Advanced parameters
 	advancedParams := []string{
-		"subreddit", "author", "site", "url", "selftext", "self", "nsfw", "restrict_sr",
+		"subreddit", "author", "site", "url", "selftext", "self", "restrict_sr",
 	}
 
 	for _, param := range advancedParams {
@@ -0,0 +1,69 @@
+// internal/handler/http/stats_handler.go
+package http
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"reddit-ingestion/internal/sink"
+	"reddit-ingestion/internal/stats"
+	"reddit-ingestion/pkg/client"
+	"reddit-ingestion/pkg/utils"
+)
+
+// SinkReporter reports the deliveries of the data sink
+type SinkReporter interface {
+	Stats() sink.Stats
+}
+
+type StatsHandler struct {
+	registry *stats.Registry
+	proxies  BandwidthReporter
+	sink     SinkReporter
+}
+
+// StatsResponse is what the service ingested since start-up
+type StatsResponse struct {
+	stats.Stats
+	// Today's traffic through each proxy; counters reset at midnight UTC
+	Proxies []utils.ProxyBandwidth `json:"proxies,omitempty"`
+	// Requests and recent success rate of each proxy
+	ProxyRequests []utils.ProxyRank `json:"proxy_requests,omitempty"`
+	// Failovers from the primary Reddit host, probes and requests sent to
+	// a fallback
+	Hosts *client.HostHealth `json:"hosts,omitempty"`
+	// Messages the Kafka sink delivered and failed to deliver
+	Sink *sink.Stats `json:"sink,omitempty"`
+}
+
+// NewStatsHandler reports the counters of registry and, when proxies and
+// dataSink are not nil, the usage of each proxy and the deliveries of the sink
+func NewStatsHandler(registry *stats.Registry, proxies BandwidthReporter, dataSink SinkReporter) *StatsHandler {
+	return &StatsHandler{registry: registry, proxies: proxies, sink: dataSink}
+}
+
+// GetStats godoc
+// @Summary Show ingestion statistics
+// @Description Returns counters since start-up: posts and comments ingested in total and per subreddit, scrapes, error rates and average durations per operation, today's traffic and the requests of each proxy, the failovers from the primary Reddit host, and the messages the Kafka sink delivered and failed to deliver. The counters are kept in memory and reset on restart.
+// @Tags stats
+// @Produce json
+// @Success 200 {object} StatsResponse
+// @Router /stats [get]
+func (h *StatsHandler) GetStats(c echo.Context) error {
+	response := StatsResponse{Stats: h.registry.Stats()}
+	if h.proxies != nil {
+		response.Proxies = h.proxies.BandwidthUsage()
+		if ranker, ok := h.proxies.(ProxyRankReporter); ok {
+			response.ProxyRequests = ranker.ProxyRanking()
+		}
+		if hosts, ok := h.proxies.(HostHealthReporter); ok {
+			health := hosts.HostHealth()
+			response.Hosts = &health
+		}
+	}
+	if h.sink != nil {
+		stats := h.sink.Stats()
+		response.Sink = &stats
+	}
+	return c.JSON(http.StatusOK, response)
+}
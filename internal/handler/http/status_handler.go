@@ -0,0 +1,126 @@
+// internal/handler/http/status_handler.go
+package http
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"reddit-ingestion/internal/quality"
+	"reddit-ingestion/internal/sink"
+	"reddit-ingestion/pkg/utils"
+)
+
+// BandwidthReporter reports today's traffic through each proxy
+type BandwidthReporter interface {
+	BandwidthUsage() []utils.ProxyBandwidth
+}
+
+// ProxyRankReporter reports how proxies rank by recent latency and success
+type ProxyRankReporter interface {
+	ProxyRanking() []utils.ProxyRank
+}
+
+// UpstreamReporter reports the rate limit and Cloudflare headers Reddit sent
+type UpstreamReporter interface {
+	UpstreamStats() utils.UpstreamStats
+}
+
+// ThrottleReporter reports the level of the adaptive throttle
+type ThrottleReporter interface {
+	ThrottleStats() utils.ThrottleStats
+}
+
+// RetryReporter reports the retries of requests to Reddit
+type RetryReporter interface {
+	RetryStats() utils.RetryStats
+}
+
+// SoftBlockReporter reports the block pages Reddit served in place of JSON
+type SoftBlockReporter interface {
+	SoftBlockStats() utils.SoftBlockStats
+}
+
+// QualityReporter reports the quality scores of the responses served
+type QualityReporter interface {
+	QualityStats() []quality.Stats
+}
+
+type StatusHandler struct {
+	bandwidth BandwidthReporter
+	quality   QualityReporter
+	sink      SinkReporter
+}
+
+// StatusResponse is the operational status of the service
+type StatusResponse struct {
+	// UTC day the bandwidth counters cover; they reset at midnight UTC
+	BandwidthDay string                 `json:"bandwidth_day"`
+	Proxies      []utils.ProxyBandwidth `json:"proxies"`
+	// Proxies best first by moving averages of latency and success rate;
+	// interactive requests use the top quarter, bulk scrapes the rest
+	Ranking []utils.ProxyRank `json:"ranking,omitempty"`
+	// x-ratelimit-*, content-type and cf-ray of Reddit's responses: counters
+	// since start-up and a trace of the latest responses
+	Upstream *utils.UpstreamStats `json:"upstream,omitempty"`
+	// Adaptive throttle level and the 429/403 rates behind it
+	Throttle *utils.ThrottleStats `json:"throttle,omitempty"`
+	// Retries of requests to Reddit since start-up and why requests were
+	// given up
+	Retries *utils.RetryStats `json:"retries,omitempty"`
+	// Block and login pages Reddit served with 200 in place of JSON since
+	// start-up, by reason and proxy
+	SoftBlocks *utils.SoftBlockStats `json:"soft_blocks,omitempty"`
+	// Quality scores of the responses served since start-up, by kind
+	Quality []quality.Stats `json:"quality,omitempty"`
+	// Messages the Kafka sink delivered and failed to deliver since
+	// start-up
+	Sink *sink.Stats `json:"sink,omitempty"`
+}
+
+// NewStatusHandler reports bandwidth and, when quality and dataSink are not nil,
+// the quality scores of responses and the deliveries of the sink
+func NewStatusHandler(bandwidth BandwidthReporter, quality QualityReporter, dataSink SinkReporter) *StatusHandler {
+	return &StatusHandler{bandwidth: bandwidth, quality: quality, sink: dataSink}
+}
+
+// GetStatus godoc
+// @Summary Show operational status
+// @Description Returns today's traffic through each proxy, its daily bandwidth cap (PROXY_DAILY_BANDWIDTH_MB) and the remaining budget. Proxies that are not available are skipped until the counters reset at midnight UTC. Also lists the proxies ranked by recent latency and success rate, and the rate limit, content type and Cloudflare headers of Reddit's latest responses, the adaptive throttle level, the retries of requests to Reddit, the block pages served in place of JSON, the quality scores of the responses served, and the messages the Kafka sink delivered and failed to deliver.
+// @Tags admin
+// @Produce json
+// @Success 200 {object} StatusResponse
+// @Router /admin/status [get]
+func (h *StatusHandler) GetStatus(c echo.Context) error {
+	status := StatusResponse{
+		BandwidthDay: time.Now().UTC().Format("2006-01-02"),
+		Proxies:      h.bandwidth.BandwidthUsage(),
+	}
+	if ranker, ok := h.bandwidth.(ProxyRankReporter); ok {
+		status.Ranking = ranker.ProxyRanking()
+	}
+	if upstream, ok := h.bandwidth.(UpstreamReporter); ok {
+		stats := upstream.UpstreamStats()
+		status.Upstream = &stats
+	}
+	if throttle, ok := h.bandwidth.(ThrottleReporter); ok {
+		stats := throttle.ThrottleStats()
+		status.Throttle = &stats
+	}
+	if retries, ok := h.bandwidth.(RetryReporter); ok {
+		stats := retries.RetryStats()
+		status.Retries = &stats
+	}
+	if softBlocks, ok := h.bandwidth.(SoftBlockReporter); ok {
+		stats := softBlocks.SoftBlockStats()
+		status.SoftBlocks = &stats
+	}
+	if h.quality != nil {
+		status.Quality = h.quality.QualityStats()
+	}
+	if h.sink != nil {
+		stats := h.sink.Stats()
+		status.Sink = &stats
+	}
+	return c.JSON(http.StatusOK, status)
+}
@@ -4,78 +4,93 @@ package http
 import (
 	"context"
 	"fmt"
-	"net/http"
-	"strconv"
 	"time"
 
 	"github.com/labstack/echo/v4"
-	"reddit-ingestion/internal/scraper"
+	"reddit-ingestion/pkg/scraper"
 )
 
 type SubredditHandler struct {
-	svc scraper.ScraperService
+	svc    scraper.ScraperService
+	limits Limits
 }
 
 func NewSubredditHandler(svc scraper.ScraperService) *SubredditHandler {
-	return &SubredditHandler{svc: svc}
+	return NewSubredditHandlerWithLimits(svc, Limits{})
+}
+
+// NewSubredditHandlerWithLimits creates a handler applying limits to the
+// limit parameter
+func NewSubredditHandlerWithLimits(svc scraper.ScraperService, limits Limits) *SubredditHandler {
+	return &SubredditHandler{svc: svc, limits: limits}
 }
 // GetSubredditPosts godoc
 // @Summary Get posts from a subreddit
-// @Description Retrieves posts from the specified subreddit with optional filters
+// @Description Retrieves posts from the specified subreddit with optional filters. The response carries an ETag of its posts; send it back in If-None-Match to get 304 Not Modified while they are unchanged.
 // @Tags subreddit
 // @Accept json
 // @Produce json
+// @Produce application/x-ndjson
 // @Param subreddit query string true "Subreddit name without the r/ prefix"
 // @Param since_timestamp query int false "Unix timestamp to filter posts"
-// @Param limit query int false "Maximum number of posts to retrieve"
+// @Param limit query int false "Maximum number of posts to retrieve, -1 for all; defaults to SCRAPER_DEFAULT_POST_LIMIT"
+// @Param after query string false "Continue after this post fullname, e.g. the cursor of an earlier response"
+// @Param flair query []string false "Only posts with this flair; repeat for any of several" collectionFormat(multi)
+// @Param exclude_flair query []string false "Leave out posts with this flair; repeatable" collectionFormat(multi)
+// @Param author query []string false "Only posts by this author; repeatable" collectionFormat(multi)
+// @Param title_contains query []string false "Only posts whose title contains this; repeatable" collectionFormat(multi)
+// @Param body_contains query []string false "Only posts whose body contains this; repeatable" collectionFormat(multi)
+// @Param regex query bool false "Match the filters as regular expressions instead of exactly or as substrings (ignoring case)"
+// @Param nsfw query string false "NSFW posts: include (default), exclude or only"
+// @Param include_awards query bool false "Include the awards of each post"
+// @Param anonymize query bool false "Replace usernames with pseudonyms that are consistent within the response"
+// @Param purpose query string false "Purpose of the scrape, recorded in the audit log (required when REQUIRE_PURPOSE is set)"
+// @Param pool query string false "Only use proxies with this label, e.g. residential"
+// @Param format query string false "json (default) or ndjson: one post per line, then a line with the meta"
+// @Param If-None-Match header string false "ETag of an earlier response"
 // @Success 200 {object} map[string]interface{}
-// @Failure 400 {object} models.HTTPError
+// @Success 304 "Not modified since the response with the given ETag"
+// @Failure 400 {object} models.ValidationError
+// @Failure 403 {object} models.HTTPError
+// @Failure 404 {object} models.HTTPError "Not found on Reddit"
 // @Failure 502 {object} models.HTTPError
+// @Failure 503 {object} models.HTTPError "Every proxy has used its daily or monthly bandwidth budget"
 // @Router /subreddit [get]
 func (h *SubredditHandler) GetSubredditPosts(c echo.Context) error {
-	sr := c.QueryParam("subreddit")
-	if sr == "" {
-		return echo.NewHTTPError(http.StatusBadRequest, "missing `subreddit` parameter")
+	p := newParams(c)
+	sr := p.subreddit("subreddit")
+	sinceTimestamp := p.timestamp("since_timestamp")
+	limit, defaulted, err := h.limits.limitParam(c, "limit", h.limits.DefaultPostLimit)
+	p.check(err)
+	opts, err := listingOptions(c)
+	p.check(err)
+	opts.Filter = p.postFilter()
+	opts.Filter.NSFW, err = nsfwMode(c)
+	p.check(err)
+	format, err := listingFormat(c)
+	p.check(err)
+	parent := p.withAnonymizedAuthors(p.withAwards(withDefaultLimit(c.Request().Context(), defaulted)))
+	if err := p.err(); err != nil {
+		return err
 	}
 
-	var sinceTimestamp int64
-	if s := c.QueryParam("since_timestamp"); s != "" {
-		v, err := strconv.ParseInt(s, 10, 64)
-		if err != nil {
-			return echo.NewHTTPError(http.StatusBadRequest, "invalid `since_timestamp`")
-		}
-		sinceTimestamp = v
-	}
-
-	var limit int
-	if l := c.QueryParam("limit"); l != "" {
-		v, err := strconv.Atoi(l)
-		if err != nil {
-			return echo.NewHTTPError(http.StatusBadRequest, "invalid `limit`")
-		}
-		limit = v
-	}
-	
-	ctx, cancel := context.WithTimeout(c.Request().Context(), 60*time.Second)
+	ctx, cancel := context.WithTimeout(parent, 60*time.Second)
 	defer cancel()
 
 	startTime := time.Now()
 
-	posts, err := h.svc.ScrapeSubreddit(ctx, sr, sinceTimestamp, limit)
+	posts, listingMeta, err := h.svc.ScrapeSubreddit(ctx, sr, sinceTimestamp, limit, opts)
 	if err != nil {
-		return echo.NewHTTPError(http.StatusBadGateway, fmt.Sprintf("scrape error: %v", err))
+		return scrapeError(err, fmt.Sprintf("scrape error: %v", err))
 	}
 
 	duration := time.Since(startTime)
 
-	return c.JSON(http.StatusOK, map[string]interface{}{
-		"posts": posts,
-		"meta": map[string]interface{}{
-			"requested_limit":    limit,
-			"actual_count":       len(posts),
-			"subreddit":          sr,
-			"since_timestamp":    sinceTimestamp,
-			"processing_time_ms": duration.Milliseconds(),
-		},
-	})
+	return listingResponse(c, format, posts, addListingMeta(map[string]interface{}{
+		"requested_limit":    limit,
+		"actual_count":       len(posts),
+		"subreddit":          sr,
+		"since_timestamp":    sinceTimestamp,
+		"processing_time_ms": duration.Milliseconds(),
+	}, listingMeta))
 }
\ No newline at end of file
@@ -5,19 +5,26 @@ import (
 	"context"
 	"fmt"
 	"net/http"
-	"strconv"
 	"time"
 
 	"github.com/labstack/echo/v4"
-	"reddit-ingestion/internal/scraper"
+	"reddit-ingestion/pkg/models"
+	"reddit-ingestion/pkg/scraper"
 )
 
 type UserHandler struct {
-	svc scraper.ScraperService
+	svc    scraper.ScraperService
+	limits Limits
 }
 
 func NewUserHandler(svc scraper.ScraperService) *UserHandler {
-	return &UserHandler{svc: svc}
+	return NewUserHandlerWithLimits(svc, Limits{})
+}
+
+// NewUserHandlerWithLimits creates a handler applying limits to the limit,
+// post_limit and comment_limit parameters
+func NewUserHandlerWithLimits(svc scraper.ScraperService, limits Limits) *UserHandler {
+	return &UserHandler{svc: svc, limits: limits}
 }
 // GetUserInfo godoc
 // @Summary Get information about a Reddit user
@@ -27,66 +34,195 @@ func NewUserHandler(svc scraper.ScraperService) *UserHandler {
 // @Produce json
 // @Param username query string true "Reddit username"
 // @Param since_timestamp query int false "Unix timestamp to filter posts and comments (newer than this timestamp)"
-// @Param post_limit query int false "Maximum number of posts to retrieve. Use -1 for all available posts"
-// @Param comment_limit query int false "Maximum number of comments to retrieve. Use -1 for all available comments"
+// @Param post_limit query int false "Maximum number of posts to retrieve. Use -1 for all available posts; defaults to SCRAPER_DEFAULT_POST_LIMIT"
+// @Param comment_limit query int false "Maximum number of comments to retrieve. Use -1 for all available comments; defaults to post_limit when that is set, else to SCRAPER_DEFAULT_COMMENT_LIMIT"
+// @Param anonymize query bool false "Replace usernames with pseudonyms that are consistent within the response"
+// @Param purpose query string false "Purpose of the scrape, recorded in the audit log (required when REQUIRE_PURPOSE is set)"
+// @Param pool query string false "Only use proxies with this label, e.g. residential"
+// @Param title_contains query []string false "Only posts whose title contains this, and comments on such posts; repeatable" collectionFormat(multi)
+// @Param body_contains query []string false "Only posts and comments whose body contains this; repeatable" collectionFormat(multi)
+// @Param regex query bool false "Match title_contains and body_contains as regular expressions instead of substrings (ignoring case)"
+// @Param strict query bool false "Fail the request when posts or comments cannot be fetched, instead of returning the rest with meta.partial and meta.warnings"
 // @Success 200 {object} models.UserActivity "Returns user information, posts, and comments"
-// @Failure 400 {object} models.HTTPError "Invalid request parameters"
+// @Failure 400 {object} models.ValidationError "Invalid request parameters"
+// @Failure 403 {object} models.HTTPError "User is blocked by policy"
+// @Failure 404 {object} models.HTTPError "Not found on Reddit"
 // @Failure 502 {object} models.HTTPError "Error occurred while scraping data"
+// @Failure 503 {object} models.HTTPError "Every proxy has used its daily or monthly bandwidth budget"
 // @Router /user [get]
 func (h *UserHandler) GetUserInfo(c echo.Context) error {
-	username := c.QueryParam("username")
-	if username == "" {
-		return echo.NewHTTPError(http.StatusBadRequest, "missing `username` parameter")
+	p := newParams(c)
+	username := p.username("username")
+	sinceTimestamp := p.timestamp("since_timestamp")
+	postLimit, postDefaulted, err := h.limits.limitParam(c, "post_limit", h.limits.DefaultPostLimit)
+	p.check(err)
+	// Without a comment_limit, comments follow an explicit post_limit
+	defaultCommentLimit := h.limits.DefaultCommentLimit
+	if !postDefaulted && postLimit != 0 {
+		defaultCommentLimit = postLimit
 	}
-
-	var sinceTimestamp int64
-	if s := c.QueryParam("since_timestamp"); s != "" {
-		v, err := strconv.ParseInt(s, 10, 64)
-		if err != nil {
-			return echo.NewHTTPError(http.StatusBadRequest, "invalid `since_timestamp`")
+	commentLimit, _, err := h.limits.limitParam(c, "comment_limit", defaultCommentLimit)
+	p.check(err)
+	strict := p.bool("strict")
+	filter := p.postFilter()
+	// Every item is by the user and comments carry no flair
+	for _, param := range []string{"author", "flair", "exclude_flair"} {
+		if c.QueryParam(param) != "" {
+			p.fail(param, "does not apply to /user, use title_contains or body_contains")
 		}
-		sinceTimestamp = v
+	}
+	parent := p.withAnonymizedAuthors(c.Request().Context())
+	if err := p.err(); err != nil {
+		return err
 	}
 
-	var postLimit int
-	if l := c.QueryParam("post_limit"); l != "" {
-		v, err := strconv.Atoi(l)
-		if err != nil {
-			return echo.NewHTTPError(http.StatusBadRequest, "invalid `post_limit`")
-		}
-		postLimit = v
+	// Increase timeout for unlimited fetching
+	timeout := 60 * time.Second
+	if (postLimit == -1 || commentLimit == -1) && sinceTimestamp > 0 {
+		timeout = 240 * time.Second
 	}
 
-	var commentLimit int
-	if l := c.QueryParam("comment_limit"); l != "" {
-		v, err := strconv.Atoi(l)
-		if err != nil {
-			return echo.NewHTTPError(http.StatusBadRequest, "invalid `comment_limit`")
-		}
-		commentLimit = v
-	} else {
-		commentLimit = postLimit
+	ctx, cancel := context.WithTimeout(parent, timeout)
+	defer cancel()
+	if strict {
+		ctx = scraper.WithStrict(ctx)
 	}
-	if postLimit < -1 || commentLimit < -1 {
-		return echo.NewHTTPError(http.StatusBadRequest, "limits must be -1 or a positive integer")
+	if !filter.IsZero() {
+		ctx = scraper.WithActivityFilter(ctx, filter)
+	}
+
+	startTime := time.Now()
+
+	activity, err := h.svc.ScrapeUserActivity(ctx, username, sinceTimestamp, postLimit, commentLimit)
+	if err != nil {
+		return scrapeError(err, fmt.Sprintf("scrape user data error: %v", err))
+	}
+
+	if activity.Meta == nil {
+		activity.Meta = &models.UserActivityMeta{}
+	}
+	activity.Meta.Ordering = models.OrderingNewestFirst
+	activity.Meta.RequestedPostLimit = postLimit
+	activity.Meta.RequestedCommentLimit = commentLimit
+	activity.Meta.SinceTimestamp = sinceTimestamp
+	activity.Meta.ProcessingTimeMS = time.Since(startTime).Milliseconds()
+
+	return c.JSON(http.StatusOK, activity)
+}
+// GetUserOverview godoc
+// @Summary Get a user's posts and comments as one stream
+// @Description Mirrors Reddit's user overview: posts and comments interleaved newest first, each in a common envelope with its kind, ID, subreddit, score and creation time
+// @Tags user
+// @Produce json
+// @Param username query string true "Reddit username"
+// @Param since_timestamp query int false "Unix timestamp to filter posts and comments (newer than this timestamp)"
+// @Param limit query int false "Maximum number of items to retrieve. Use -1 for all available items; defaults to SCRAPER_DEFAULT_POST_LIMIT, or the first page without one"
+// @Param anonymize query bool false "Replace usernames with pseudonyms that are consistent within the response"
+// @Param purpose query string false "Purpose of the scrape, recorded in the audit log (required when REQUIRE_PURPOSE is set)"
+// @Param pool query string false "Only use proxies with this label, e.g. residential"
+// @Success 200 {object} models.UserOverview
+// @Failure 400 {object} models.ValidationError "Invalid request parameters"
+// @Failure 403 {object} models.HTTPError "User is blocked by policy"
+// @Failure 404 {object} models.HTTPError "Not found on Reddit"
+// @Failure 502 {object} models.HTTPError "Error occurred while scraping data"
+// @Failure 503 {object} models.HTTPError "Every proxy has used its daily or monthly bandwidth budget"
+// @Router /user/overview [get]
+func (h *UserHandler) GetUserOverview(c echo.Context) error {
+	p := newParams(c)
+	username := p.username("username")
+	sinceTimestamp := p.timestamp("since_timestamp")
+	limit, _, err := h.limits.limitParam(c, "limit", h.limits.DefaultPostLimit)
+	p.check(err)
+	parent := p.withAnonymizedAuthors(c.Request().Context())
+	if err := p.err(); err != nil {
+		return err
 	}
 
-	// Increase timeout for unlimited fetching
 	timeout := 60 * time.Second
-	if (postLimit == -1 || commentLimit == -1) && sinceTimestamp > 0 {
+	if limit == -1 {
 		timeout = 240 * time.Second
 	}
-	
-	ctx, cancel := context.WithTimeout(c.Request().Context(), timeout)
+	ctx, cancel := context.WithTimeout(parent, timeout)
 	defer cancel()
 
+	startTime := time.Now()
+
+	overview, err := h.svc.ScrapeUserOverview(ctx, username, sinceTimestamp, limit)
+	if err != nil {
+		return scrapeError(err, fmt.Sprintf("scrape user overview error: %v", err))
+	}
+
+	if overview.Meta == nil {
+		overview.Meta = &models.UserOverviewMeta{}
+	}
+	overview.Meta.Ordering = models.OrderingNewestFirst
+	overview.Meta.RequestedLimit = limit
+	overview.Meta.SinceTimestamp = sinceTimestamp
+	overview.Meta.ProcessingTimeMS = time.Since(startTime).Milliseconds()
+
+	return c.JSON(http.StatusOK, overview)
+}
+
+// defaultSummaryLimit is how many posts and comments a user summary is made
+// from when the request sets no limit
+const defaultSummaryLimit = 100
+
+// GetUserSummary godoc
+// @Summary Summarize a user's activity
+// @Description Fetches a user's posts and comments like /user and aggregates them: counts and average scores per subreddit, activity per hour of the day (UTC) and when the user was first and last seen
+// @Tags user
+// @Produce json
+// @Param username query string true "Reddit username"
+// @Param since_timestamp query int false "Unix timestamp; only posts and comments newer than this are summarized"
+// @Param post_limit query int false "Maximum number of posts to summarize, default 100. Use -1 for all available posts"
+// @Param comment_limit query int false "Maximum number of comments to summarize, default 100. Use -1 for all available comments"
+// @Param anonymize query bool false "Replace usernames with pseudonyms that are consistent within the response"
+// @Param purpose query string false "Purpose of the scrape, recorded in the audit log (required when REQUIRE_PURPOSE is set)"
+// @Param pool query string false "Only use proxies with this label, e.g. residential"
+// @Success 200 {object} models.UserSummary
+// @Failure 400 {object} models.ValidationError "Invalid request parameters"
+// @Failure 403 {object} models.HTTPError "User is blocked by policy"
+// @Failure 404 {object} models.HTTPError "Not found on Reddit"
+// @Failure 502 {object} models.HTTPError "Error occurred while scraping data"
+// @Failure 503 {object} models.HTTPError "Every proxy has used its daily or monthly bandwidth budget"
+// @Router /user/summary [get]
+func (h *UserHandler) GetUserSummary(c echo.Context) error {
+	p := newParams(c)
+	username := p.username("username")
+	sinceTimestamp := p.timestamp("since_timestamp")
+	postLimit, _, err := h.limits.limitParam(c, "post_limit", defaultSummaryLimit)
+	p.check(err)
+	commentLimit, _, err := h.limits.limitParam(c, "comment_limit", defaultSummaryLimit)
+	p.check(err)
+	parent := p.withAnonymizedAuthors(c.Request().Context())
+	if err := p.err(); err != nil {
+		return err
+	}
+
+	timeout := 60 * time.Second
+	if postLimit == -1 || commentLimit == -1 {
+		timeout = 240 * time.Second
+	}
+
+	ctx, cancel := context.WithTimeout(parent, timeout)
+	defer cancel()
+
+	startTime := time.Now()
+
 	activity, err := h.svc.ScrapeUserActivity(ctx, username, sinceTimestamp, postLimit, commentLimit)
 	if err != nil {
-		return echo.NewHTTPError(
-			http.StatusBadGateway,
-			fmt.Sprintf("scrape user data error: %v", err),
-		)
+		return scrapeError(err, fmt.Sprintf("scrape user data error: %v", err))
 	}
 
-	return c.JSON(http.StatusOK, activity)
-}
\ No newline at end of file
+	summary := scraper.SummarizeUserActivity(activity)
+	if summary.Meta == nil {
+		summary.Meta = &models.UserActivityMeta{}
+	}
+	summary.Meta.Ordering = models.OrderingNewestFirst
+	summary.Meta.RequestedPostLimit = postLimit
+	summary.Meta.RequestedCommentLimit = commentLimit
+	summary.Meta.SinceTimestamp = sinceTimestamp
+	summary.Meta.ProcessingTimeMS = time.Since(startTime).Milliseconds()
+
+	return c.JSON(http.StatusOK, summary)
+}
@@ -0,0 +1,201 @@
+// internal/handler/http/validation.go
+package http
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+	"reddit-ingestion/pkg/models"
+)
+
+var (
+	// subredditFormat matches a subreddit name, or several joined by +
+	subredditFormat = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_]{1,20}(\+[A-Za-z0-9][A-Za-z0-9_]{1,20})*$`)
+	// usernameFormat matches the names Reddit lets accounts register
+	usernameFormat = regexp.MustCompile(`^[A-Za-z0-9_-]{3,20}$`)
+	// postIDFormat matches a base-36 post ID with or without its t3_ prefix
+	postIDFormat = regexp.MustCompile(`^(t3_)?[0-9a-zA-Z]{1,12}$`)
+)
+
+// invalidParam is the 400 response to a request whose field parameter is
+// wrong in the way message says
+func invalidParam(field, message string) *echo.HTTPError {
+	return invalidParams([]models.FieldError{{Field: field, Message: message}})
+}
+
+// invalidParams is the 400 response listing every invalid parameter, with a
+// message summing them up
+func invalidParams(fieldErrors []models.FieldError) *echo.HTTPError {
+	summary := make([]string, len(fieldErrors))
+	for i, e := range fieldErrors {
+		summary[i] = fmt.Sprintf("`%s` %s", e.Field, e.Message)
+	}
+	return echo.NewHTTPError(http.StatusBadRequest, models.ValidationError{
+		Message: strings.Join(summary, "; "),
+		Errors:  fieldErrors,
+	})
+}
+
+// params reads the query parameters of a request, collecting what is wrong
+// with each instead of stopping at the first, so a client learns about all of
+// them from one response. Readers return the zero value for an invalid or
+// missing parameter; err reports them once everything was read.
+type params struct {
+	c      echo.Context
+	errors []models.FieldError
+}
+
+func newParams(c echo.Context) *params {
+	return &params{c: c}
+}
+
+// fail records that field is wrong in the way message says
+func (p *params) fail(field, message string) {
+	p.errors = append(p.errors, models.FieldError{Field: field, Message: message})
+}
+
+// check records the field errors of err, as returned by invalidParam, and
+// reports whether there were none
+func (p *params) check(err error) bool {
+	if err == nil {
+		return true
+	}
+	var httpErr *echo.HTTPError
+	if errors.As(err, &httpErr) {
+		if validation, ok := httpErr.Message.(models.ValidationError); ok {
+			p.errors = append(p.errors, validation.Errors...)
+			return false
+		}
+	}
+	p.fail("", err.Error())
+	return false
+}
+
+// err is the 400 response listing the invalid parameters, or nil
+func (p *params) err() error {
+	if len(p.errors) == 0 {
+		return nil
+	}
+	return invalidParams(p.errors)
+}
+
+// Descriptions of the formats, completing "must be"
+const (
+	subredditExpected = "a subreddit name of 2 to 21 letters, digits and underscores, or several joined by +"
+	usernameExpected  = "a username of 3 to 20 letters, digits, underscores and dashes"
+)
+
+// matching reads name, recording an error when it is set but does not match
+// format, described by expected
+func (p *params) matching(name string, format *regexp.Regexp, expected string) string {
+	value := strings.TrimSpace(p.c.QueryParam(name))
+	if value != "" && !format.MatchString(value) {
+		p.fail(name, "must be "+expected)
+	}
+	return value
+}
+
+// required reads name like matching, recording an error when it is missing
+func (p *params) required(name string, format *regexp.Regexp, expected string) string {
+	if strings.TrimSpace(p.c.QueryParam(name)) == "" {
+		p.fail(name, "is required")
+		return ""
+	}
+	return p.matching(name, format, expected)
+}
+
+// subreddit reads the subreddit name name, which is required
+func (p *params) subreddit(name string) string {
+	if strings.HasPrefix(strings.ToLower(p.c.QueryParam(name)), "r/") {
+		p.fail(name, "must be given without the r/ prefix")
+		return ""
+	}
+	return p.required(name, subredditFormat, subredditExpected)
+}
+
+// username reads the username name, which is required
+func (p *params) username(name string) string {
+	if strings.HasPrefix(strings.ToLower(p.c.QueryParam(name)), "u/") {
+		p.fail(name, "must be given without the u/ prefix")
+		return ""
+	}
+	return p.required(name, usernameFormat, usernameExpected)
+}
+
+// postID reads the post ID name, which is required, without its t3_ prefix
+func (p *params) postID(name string) string {
+	id := p.required(name, postIDFormat, "a post ID such as abc123, optionally with its t3_ prefix")
+	return strings.TrimPrefix(id, "t3_")
+}
+
+// oneOf reads name, recording an error when it is set to anything but one of
+// values
+func (p *params) oneOf(name string, values ...string) string {
+	value := p.c.QueryParam(name)
+	if value != "" && !slices.Contains(values, value) {
+		p.fail(name, "must be "+strings.Join(values[:len(values)-1], ", ")+" or "+values[len(values)-1])
+	}
+	return value
+}
+
+// timestamp reads the Unix timestamp name, 0 when it is missing
+func (p *params) timestamp(name string) int64 {
+	s := p.c.QueryParam(name)
+	if s == "" {
+		return 0
+	}
+	v, err := strconv.ParseInt(s, 10, 64)
+	if err != nil || v < 0 {
+		p.fail(name, "must be a Unix timestamp in seconds")
+		return 0
+	}
+	return v
+}
+
+// count reads the positive integer name, at most max; 0 when it is missing
+func (p *params) count(name string, max int) int {
+	s := p.c.QueryParam(name)
+	if s == "" {
+		return 0
+	}
+	v, err := strconv.Atoi(s)
+	if err != nil || v < 1 || v > max {
+		p.fail(name, fmt.Sprintf("must be an integer from 1 to %d", max))
+		return 0
+	}
+	return v
+}
+
+// positive reads the positive integer name, 0 when it is missing
+func (p *params) positive(name string) int {
+	s := p.c.QueryParam(name)
+	if s == "" {
+		return 0
+	}
+	v, err := strconv.Atoi(s)
+	if err != nil || v < 1 {
+		p.fail(name, "must be a positive integer")
+		return 0
+	}
+	return v
+}
+
+// bool reads the boolean name, false when it is missing
+func (p *params) bool(name string) bool {
+	s := p.c.QueryParam(name)
+	if s == "" {
+		return false
+	}
+	v, err := strconv.ParseBool(s)
+	if err != nil {
+		p.fail(name, "must be true or false")
+		return false
+	}
+	return v
+}
@@ -1,10 +0,0 @@
-package models
-
-// HTTPError represents an HTTP error response
-// swagger:model HTTPError
-type HTTPError struct {
-	// HTTP status code
-	Code int `json:"code"`
-	// Error message
-	Message string `json:"message"`
-}
\ No newline at end of file
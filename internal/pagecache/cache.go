@@ -0,0 +1,127 @@
+// internal/pagecache/cache.go
+package pagecache
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"reddit-ingestion/pkg/client"
+)
+
+// cachingClient serves FetchJSON from a content-addressed disk cache keyed by
+// normalized URL and UTC day, so re-running a job on the same day reuses pages.
+// Refetches (see client.WithRefetch) skip the cache and replace its copy.
+type cachingClient struct {
+	client.RedditClientInterface
+	dir string
+	now func() time.Time
+}
+
+// WrapClient returns a client that caches raw pages below dir
+func WrapClient(c client.RedditClientInterface, dir string) (client.RedditClientInterface, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("create page cache directory: %w", err)
+	}
+	return &cachingClient{
+		RedditClientInterface: c,
+		dir:                   dir,
+		now:                   time.Now,
+	}, nil
+}
+
+func (c *cachingClient) FetchJSON(ctx context.Context, rawURL string) (json.RawMessage, error) {
+	path := c.path(rawURL)
+
+	if !client.IsRefetch(ctx) {
+		if data, err := os.ReadFile(path); err == nil {
+			fmt.Printf("Page cache hit for %s\n", rawURL)
+			return data, nil
+		}
+	}
+
+	data, err := c.RedditClientInterface.FetchJSON(ctx, rawURL)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := writePage(path, data); err != nil {
+		fmt.Printf("Page cache write failed for %s: %v\n", rawURL, err)
+	}
+
+	return data, nil
+}
+
+// writePage replaces the cached page at path with data. Each write goes
+// through a temp file of its own that is renamed into place, so neither
+// readers nor concurrent fetches of the same page see a partial one.
+func writePage(path string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".page-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Chmod(0644); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+func (c *cachingClient) path(rawURL string) string {
+	day := c.now().UTC().Format("2006-01-02")
+	return filepath.Join(c.dir, day, Key(rawURL)+".json")
+}
+
+// Key is the content address of a URL: the SHA-256 of its normalized form
+func Key(rawURL string) string {
+	sum := sha256.Sum256([]byte(NormalizeURL(rawURL)))
+	return hex.EncodeToString(sum[:])
+}
+
+// ContentHash is the content address of a value: the SHA-256 of its JSON encoding
+func ContentHash(v interface{}) (string, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return "", fmt.Errorf("encode content: %w", err)
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// NormalizeURL lowercases scheme and host, drops fragments and trailing
+// slashes, and sorts query parameters so equivalent URLs share a cache entry
+func NormalizeURL(rawURL string) string {
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		return rawURL
+	}
+
+	parsed.Scheme = strings.ToLower(parsed.Scheme)
+	parsed.Host = strings.ToLower(parsed.Host)
+	parsed.Fragment = ""
+	if len(parsed.Path) > 1 {
+		parsed.Path = strings.TrimSuffix(parsed.Path, "/")
+	}
+	// url.Values.Encode sorts by key
+	parsed.RawQuery = parsed.Query().Encode()
+
+	return parsed.String()
+}
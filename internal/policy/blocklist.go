@@ -0,0 +1,321 @@
+// internal/policy/blocklist.go
+package policy
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// ErrBlocked is returned for scrapes that target a blocklisted subreddit or user
+var ErrBlocked = errors.New("blocked by policy")
+
+// ErrNotBlocked is returned when unblocking a name that is not blocked
+var ErrNotBlocked = errors.New("not blocked")
+
+// ErrConfigured is returned when unblocking a name blocked by
+// BLOCKED_SUBREDDITS or BLOCKED_USERS, which only the configuration can lift
+var ErrConfigured = errors.New("blocked by configuration")
+
+// Blocklist is the set of subreddits and usernames the service refuses to
+// scrape. Names are compared case-insensitively, with or without r/ and u/.
+//
+// Names come from the configuration and from managed entries added at
+// runtime, which are kept in a JSON file when one is set with UseFile.
+type Blocklist struct {
+	mutex      sync.RWMutex
+	subreddits map[string]bool
+	users      map[string]bool
+
+	managed BlocklistFile
+	file    string
+}
+
+// BlocklistFile is the JSON form of the managed entries
+type BlocklistFile struct {
+	Subreddits []string `json:"subreddits"`
+	Users      []string `json:"users"`
+}
+
+// BlockedName is one entry of the blocklist and where it comes from:
+// "config" for BLOCKED_SUBREDDITS and BLOCKED_USERS, "managed" for entries
+// added through the admin API or the blocklist file
+type BlockedName struct {
+	Name   string `json:"name"`
+	Source string `json:"source"`
+}
+
+// BlocklistEntries lists every blocked subreddit and user
+type BlocklistEntries struct {
+	Subreddits []BlockedName `json:"subreddits"`
+	Users      []BlockedName `json:"users"`
+}
+
+func NewBlocklist(subreddits, users []string) *Blocklist {
+	b := &Blocklist{}
+	b.Set(subreddits, users)
+	return b
+}
+
+// Set replaces both configured lists, e.g. after a configuration reload;
+// managed entries stay
+func (b *Blocklist) Set(subreddits, users []string) {
+	subs := make(map[string]bool, len(subreddits))
+	for _, name := range subreddits {
+		if name = normalizeSubreddit(name); name != "" {
+			subs[name] = true
+		}
+	}
+	usrs := make(map[string]bool, len(users))
+	for _, name := range users {
+		if name = normalizeUser(name); name != "" {
+			usrs[name] = true
+		}
+	}
+
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	b.subreddits = subs
+	b.users = usrs
+}
+
+// Empty reports whether nothing is blocked
+func (b *Blocklist) Empty() bool {
+	b.mutex.RLock()
+	defer b.mutex.RUnlock()
+	return len(b.subreddits) == 0 && len(b.users) == 0 &&
+		len(b.managed.Subreddits) == 0 && len(b.managed.Users) == 0
+}
+
+func (b *Blocklist) SubredditBlocked(name string) bool {
+	name = normalizeSubreddit(name)
+	b.mutex.RLock()
+	defer b.mutex.RUnlock()
+	return b.subreddits[name] || contains(b.managed.Subreddits, name)
+}
+
+func (b *Blocklist) UserBlocked(name string) bool {
+	name = normalizeUser(name)
+	b.mutex.RLock()
+	defer b.mutex.RUnlock()
+	return b.users[name] || contains(b.managed.Users, name)
+}
+
+// UseFile keeps the managed entries in the JSON file at path, reading them
+// from it now and writing every change back. A missing file starts empty; an
+// empty path keeps managed entries in memory only.
+func (b *Blocklist) UseFile(path string) error {
+	var managed BlocklistFile
+	if path != "" {
+		data, err := os.ReadFile(path)
+		switch {
+		case errors.Is(err, os.ErrNotExist):
+		case err != nil:
+			return fmt.Errorf("read blocklist file: %w", err)
+		default:
+			if err := json.Unmarshal(data, &managed); err != nil {
+				return fmt.Errorf("parse blocklist file %s: %w", path, err)
+			}
+		}
+	}
+	managed.Subreddits = normalizeAll(managed.Subreddits, normalizeSubreddit)
+	managed.Users = normalizeAll(managed.Users, normalizeUser)
+
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	b.managed = managed
+	b.file = path
+	return nil
+}
+
+// BlockSubreddit adds a managed entry for a subreddit
+func (b *Blocklist) BlockSubreddit(name string) error {
+	return b.block(&b.managed.Subreddits, normalizeSubreddit(name))
+}
+
+// BlockUser adds a managed entry for a user
+func (b *Blocklist) BlockUser(name string) error {
+	return b.block(&b.managed.Users, normalizeUser(name))
+}
+
+// UnblockSubreddit removes the managed entry of a subreddit
+func (b *Blocklist) UnblockSubreddit(name string) error {
+	name = normalizeSubreddit(name)
+	return b.unblock(&b.managed.Subreddits, func() map[string]bool { return b.subreddits }, name, "subreddit r/"+name)
+}
+
+// UnblockUser removes the managed entry of a user
+func (b *Blocklist) UnblockUser(name string) error {
+	name = normalizeUser(name)
+	return b.unblock(&b.managed.Users, func() map[string]bool { return b.users }, name, "user u/"+name)
+}
+
+func (b *Blocklist) block(names *[]string, name string) error {
+	if name == "" {
+		return fmt.Errorf("empty name")
+	}
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	if contains(*names, name) {
+		return nil
+	}
+	previous := *names
+	*names = normalizeAll(append(append([]string(nil), previous...), name), strings.TrimSpace)
+	if err := b.save(); err != nil {
+		*names = previous
+		return err
+	}
+	return nil
+}
+
+// unblock removes name from the managed names; configured returns the
+// configured names, read under the mutex
+func (b *Blocklist) unblock(names *[]string, configured func() map[string]bool, name, what string) error {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	if !contains(*names, name) {
+		if configured()[name] {
+			return fmt.Errorf("%s: %w", what, ErrConfigured)
+		}
+		return fmt.Errorf("%s: %w", what, ErrNotBlocked)
+	}
+	previous := *names
+	remaining := make([]string, 0, len(previous)-1)
+	for _, n := range previous {
+		if n != name {
+			remaining = append(remaining, n)
+		}
+	}
+	*names = remaining
+	if err := b.save(); err != nil {
+		*names = previous
+		return err
+	}
+	return nil
+}
+
+// save writes the managed entries to the blocklist file; callers hold the
+// mutex. The file is replaced in one rename so a crash cannot truncate it.
+func (b *Blocklist) save() error {
+	if b.file == "" {
+		return nil
+	}
+	data, err := json.MarshalIndent(b.managed, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshal blocklist: %w", err)
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(b.file), ".blocklist-*")
+	if err != nil {
+		return fmt.Errorf("write blocklist file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(append(data, '\n')); err != nil {
+		tmp.Close()
+		return fmt.Errorf("write blocklist file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("write blocklist file: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), b.file); err != nil {
+		return fmt.Errorf("write blocklist file: %w", err)
+	}
+	return nil
+}
+
+// Entries lists every blocked subreddit and user by name
+func (b *Blocklist) Entries() BlocklistEntries {
+	b.mutex.RLock()
+	defer b.mutex.RUnlock()
+	return BlocklistEntries{
+		Subreddits: entries(b.subreddits, b.managed.Subreddits),
+		Users:      entries(b.users, b.managed.Users),
+	}
+}
+
+func entries(configured map[string]bool, managed []string) []BlockedName {
+	names := make([]BlockedName, 0, len(configured)+len(managed))
+	for name := range configured {
+		names = append(names, BlockedName{Name: name, Source: "config"})
+	}
+	for _, name := range managed {
+		if !configured[name] {
+			names = append(names, BlockedName{Name: name, Source: "managed"})
+		}
+	}
+	sort.Slice(names, func(i, j int) bool {
+		return names[i].Name < names[j].Name
+	})
+	return names
+}
+
+func contains(names []string, name string) bool {
+	for _, n := range names {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
+
+// normalizeAll normalizes names, dropping empty ones and duplicates, sorted
+func normalizeAll(names []string, normalize func(string) string) []string {
+	seen := make(map[string]bool, len(names))
+	normalized := make([]string, 0, len(names))
+	for _, name := range names {
+		if name = normalize(name); name != "" && !seen[name] {
+			seen[name] = true
+			normalized = append(normalized, name)
+		}
+	}
+	sort.Strings(normalized)
+	return normalized
+}
+
+// CheckSubreddit returns an error wrapping ErrBlocked if the subreddit is blocked
+func (b *Blocklist) CheckSubreddit(name string) error {
+	if b.SubredditBlocked(name) {
+		return fmt.Errorf("subreddit r/%s: %w", normalizeSubreddit(name), ErrBlocked)
+	}
+	return nil
+}
+
+// CheckUser returns an error wrapping ErrBlocked if the user is blocked
+func (b *Blocklist) CheckUser(name string) error {
+	if b.UserBlocked(name) {
+		return fmt.Errorf("user u/%s: %w", normalizeUser(name), ErrBlocked)
+	}
+	return nil
+}
+
+// CheckPostURL checks the subreddit encoded in a post permalink
+// (https://reddit.com/r/<subreddit>/comments/...)
+func (b *Blocklist) CheckPostURL(permalink string) error {
+	u, err := url.Parse(permalink)
+	if err != nil {
+		return nil
+	}
+	segments := strings.Split(strings.Trim(u.Path, "/"), "/")
+	if len(segments) >= 2 && segments[0] == "r" {
+		return b.CheckSubreddit(segments[1])
+	}
+	return nil
+}
+
+func normalizeSubreddit(name string) string {
+	name = strings.ToLower(strings.TrimSpace(name))
+	name = strings.TrimPrefix(name, "/")
+	return strings.TrimPrefix(name, "r/")
+}
+
+func normalizeUser(name string) string {
+	name = strings.ToLower(strings.TrimSpace(name))
+	name = strings.TrimPrefix(name, "/")
+	name = strings.TrimPrefix(name, "user/")
+	return strings.TrimPrefix(name, "u/")
+}
@@ -0,0 +1,113 @@
+// internal/policy/service.go
+package policy
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"reddit-ingestion/internal/audit"
+	"reddit-ingestion/pkg/models"
+	"reddit-ingestion/pkg/scraper"
+)
+
+// blockingService refuses scrapes that target blocklisted subreddits or users
+type blockingService struct {
+	scraper.ScraperService
+	blocklist *Blocklist
+	audit     *audit.Logger
+}
+
+// WrapService returns a ScraperService that checks every request against b
+// before scraping. Posts are checked after fetching, since their subreddit is
+// only known from the permalink, and front page listings, which mix
+// subreddits, have blocked posts dropped.
+func WrapService(svc scraper.ScraperService, b *Blocklist) scraper.ScraperService {
+	return WrapServiceWithAudit(svc, b, nil)
+}
+
+// WrapServiceWithAudit is WrapService that also records every refused scrape
+// in logger, which may be nil
+func WrapServiceWithAudit(svc scraper.ScraperService, b *Blocklist, logger *audit.Logger) scraper.ScraperService {
+	return &blockingService{
+		ScraperService: svc,
+		blocklist:      b,
+		audit:          logger,
+	}
+}
+
+// refuse records the refusal of a scrape and returns err
+func (w *blockingService) refuse(ctx context.Context, operation, target string, err error) error {
+	logErr := w.audit.LogRefusal(audit.Refusal{
+		Operation: operation,
+		Target:    target,
+		Reason:    err.Error(),
+		Purpose:   audit.PurposeFromContext(ctx),
+	})
+	if logErr != nil {
+		fmt.Printf("Failed to write audit entry: %v\n", logErr)
+	}
+	return err
+}
+
+func (w *blockingService) ScrapeSubreddit(ctx context.Context, subreddit string, sinceTimestamp int64, limit int, opts scraper.ListingOptions) ([]models.Post, models.ListingMeta, error) {
+	if err := w.blocklist.CheckSubreddit(subreddit); err != nil {
+		return nil, models.ListingMeta{}, w.refuse(ctx, "subreddit", subreddit, err)
+	}
+	return w.ScraperService.ScrapeSubreddit(ctx, subreddit, sinceTimestamp, limit, opts)
+}
+
+func (w *blockingService) ScrapeUserActivity(ctx context.Context, username string, sinceTimestamp int64, postLimit, commentLimit int) (models.UserActivity, error) {
+	if err := w.blocklist.CheckUser(username); err != nil {
+		return models.UserActivity{}, w.refuse(ctx, "user", username, err)
+	}
+	return w.ScraperService.ScrapeUserActivity(ctx, username, sinceTimestamp, postLimit, commentLimit)
+}
+
+func (w *blockingService) ScrapeUserOverview(ctx context.Context, username string, sinceTimestamp int64, limit int) (models.UserOverview, error) {
+	if err := w.blocklist.CheckUser(username); err != nil {
+		return models.UserOverview{}, w.refuse(ctx, "user_overview", username, err)
+	}
+	return w.ScraperService.ScrapeUserOverview(ctx, username, sinceTimestamp, limit)
+}
+
+func (w *blockingService) ScrapePost(ctx context.Context, postID string) (models.PostDetail, error) {
+	detail, err := w.ScraperService.ScrapePost(ctx, postID)
+	if err != nil {
+		return detail, err
+	}
+	if err := w.blocklist.CheckPostURL(detail.Post.URL); err != nil {
+		return models.PostDetail{}, w.refuse(ctx, "post", postID, err)
+	}
+	if err := w.blocklist.CheckUser(detail.Post.Author); err != nil {
+		return models.PostDetail{}, w.refuse(ctx, "post", postID, err)
+	}
+	return detail, nil
+}
+func (w *blockingService) Search(ctx context.Context, searchParams map[string]string, sinceTimestamp int64, limit int, opts scraper.ListingOptions) ([]models.Post, models.ListingMeta, error) {
+	// subreddit may be a "+"-joined multireddit
+	for _, sub := range strings.Split(searchParams["subreddit"], "+") {
+		if err := w.blocklist.CheckSubreddit(sub); err != nil {
+			return nil, models.ListingMeta{}, w.refuse(ctx, "search", searchParams["search_string"], err)
+		}
+	}
+	if err := w.blocklist.CheckUser(searchParams["author"]); err != nil {
+		return nil, models.ListingMeta{}, w.refuse(ctx, "search", searchParams["search_string"], err)
+	}
+	return w.ScraperService.Search(ctx, searchParams, sinceTimestamp, limit, opts)
+}
+
+func (w *blockingService) ScrapeFrontpage(ctx context.Context, feed string, params map[string]string, limit int, opts scraper.ListingOptions) ([]models.Post, models.ListingMeta, error) {
+	posts, meta, err := w.ScraperService.ScrapeFrontpage(ctx, feed, params, limit, opts)
+	if w.blocklist.Empty() {
+		return posts, meta, err
+	}
+	allowed := posts[:0]
+	for _, post := range posts {
+		if w.blocklist.SubredditBlocked(post.Subreddit) || w.blocklist.UserBlocked(post.Author) {
+			continue
+		}
+		allowed = append(allowed, post)
+	}
+	return allowed, meta, err
+}
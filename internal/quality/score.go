@@ -0,0 +1,110 @@
+// internal/quality/score.go
+package quality
+
+import (
+	"math"
+
+	"reddit-ingestion/pkg/models"
+)
+
+// Listing scores posts of a subreddit, search or front page listing
+func Listing(posts []models.Post, meta models.ListingMeta) models.Quality {
+	var fields fieldCounter
+	for _, post := range posts {
+		fields.post(post)
+	}
+	return newQuality(fields.share(), !meta.TimedOut && !meta.Truncated, nil)
+}
+
+// UserActivity scores a user's posts and comments
+func UserActivity(activity models.UserActivity) models.Quality {
+	var fields fieldCounter
+	for _, post := range activity.Posts {
+		fields.add(post.ID != "", post.Title != "", !post.CreatedAt.IsZero(), post.Subreddit != "", post.URL != "")
+	}
+	for _, comment := range activity.Comments {
+		fields.add(comment.ID != "", comment.Body != "", !comment.CreatedAt.IsZero(), comment.Subreddit != "", comment.PostID != "")
+	}
+	complete := activity.Meta == nil || !activity.Meta.Partial && !activity.Meta.Truncated
+	return newQuality(fields.share(), complete, nil)
+}
+
+// Post scores a post and its comment tree. Expansion compares the comments
+// fetched with Reddit's count, which also counts removed comments Reddit no
+// longer lists, so a fully expanded post can still score a little below 1.
+func Post(detail models.PostDetail) models.Quality {
+	var fields fieldCounter
+	fields.post(detail.Post)
+	fetched := fields.comments(detail.Comments)
+
+	expansion := 1.0
+	if detail.Post.NumComments > 0 {
+		expansion = math.Min(1, float64(fetched)/float64(detail.Post.NumComments))
+	}
+	return newQuality(fields.share(), true, &expansion)
+}
+
+func newQuality(fields float64, complete bool, expansion *float64) models.Quality {
+	q := models.Quality{Fields: round(fields)}
+	if complete {
+		q.Truncation = 1
+	}
+	parts := []float64{q.Fields, q.Truncation}
+	if expansion != nil {
+		e := round(*expansion)
+		q.Expansion = &e
+		parts = append(parts, e)
+	}
+
+	sum := 0.0
+	for _, part := range parts {
+		sum += part
+	}
+	q.Score = round(sum / float64(len(parts)))
+	return q
+}
+
+// round keeps three decimals, plenty for a score and easier to read
+func round(f float64) float64 {
+	return math.Round(f*1000) / 1000
+}
+
+// fieldCounter counts the core fields of items that are populated
+type fieldCounter struct {
+	populated, total int
+}
+
+func (c *fieldCounter) add(fields ...bool) {
+	for _, ok := range fields {
+		if ok {
+			c.populated++
+		}
+	}
+	c.total += len(fields)
+}
+
+func (c *fieldCounter) post(post models.Post) {
+	c.add(post.ID != "", post.Title != "", post.Author != "", !post.CreatedAt.IsZero(), post.URL != "", post.Subreddit != "")
+}
+
+// comments counts the fields of a comment tree, leaving out "load more"
+// placeholders, and returns how many comments it holds
+func (c *fieldCounter) comments(comments []models.Comment) int {
+	n := 0
+	for _, comment := range comments {
+		if !comment.IsMore {
+			c.add(comment.ID != "", comment.Author != "", comment.Body != "", !comment.CreatedAt.IsZero())
+			n++
+		}
+		n += c.comments(comment.Replies)
+	}
+	return n
+}
+
+// share is the populated share of the fields counted, 1 when there were none
+func (c *fieldCounter) share() float64 {
+	if c.total == 0 {
+		return 1
+	}
+	return float64(c.populated) / float64(c.total)
+}
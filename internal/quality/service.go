@@ -0,0 +1,79 @@
+// internal/quality/service.go
+package quality
+
+import (
+	"context"
+
+	"reddit-ingestion/pkg/models"
+	"reddit-ingestion/pkg/scraper"
+)
+
+// scoringService scores every successful scrape result
+type scoringService struct {
+	scraper.ScraperService
+	tracker *Tracker
+}
+
+// WrapService returns a ScraperService that adds a quality score to the meta
+// of every result, or to the PostDetail of posts, and records it in t, which
+// may be nil
+func WrapService(svc scraper.ScraperService, t *Tracker) scraper.ScraperService {
+	return &scoringService{
+		ScraperService: svc,
+		tracker:        t,
+	}
+}
+
+func (w *scoringService) ScrapeSubreddit(ctx context.Context, subreddit string, sinceTimestamp int64, limit int, opts scraper.ListingOptions) ([]models.Post, models.ListingMeta, error) {
+	posts, meta, err := w.ScraperService.ScrapeSubreddit(ctx, subreddit, sinceTimestamp, limit, opts)
+	if err == nil {
+		meta = w.scoreListing("subreddit", posts, meta)
+	}
+	return posts, meta, err
+}
+
+func (w *scoringService) ScrapeUserActivity(ctx context.Context, username string, sinceTimestamp int64, postLimit, commentLimit int) (models.UserActivity, error) {
+	activity, err := w.ScraperService.ScrapeUserActivity(ctx, username, sinceTimestamp, postLimit, commentLimit)
+	if err == nil {
+		q := UserActivity(activity)
+		if activity.Meta == nil {
+			activity.Meta = &models.UserActivityMeta{}
+		}
+		activity.Meta.Quality = &q
+		w.tracker.Record("user", q)
+	}
+	return activity, err
+}
+
+func (w *scoringService) ScrapePost(ctx context.Context, postID string) (models.PostDetail, error) {
+	detail, err := w.ScraperService.ScrapePost(ctx, postID)
+	if err == nil {
+		q := Post(detail)
+		detail.Quality = &q
+		w.tracker.Record("post", q)
+	}
+	return detail, err
+}
+
+func (w *scoringService) Search(ctx context.Context, searchParams map[string]string, sinceTimestamp int64, limit int, opts scraper.ListingOptions) ([]models.Post, models.ListingMeta, error) {
+	posts, meta, err := w.ScraperService.Search(ctx, searchParams, sinceTimestamp, limit, opts)
+	if err == nil {
+		meta = w.scoreListing("search", posts, meta)
+	}
+	return posts, meta, err
+}
+
+func (w *scoringService) ScrapeFrontpage(ctx context.Context, feed string, params map[string]string, limit int, opts scraper.ListingOptions) ([]models.Post, models.ListingMeta, error) {
+	posts, meta, err := w.ScraperService.ScrapeFrontpage(ctx, feed, params, limit, opts)
+	if err == nil {
+		meta = w.scoreListing("frontpage", posts, meta)
+	}
+	return posts, meta, err
+}
+
+func (w *scoringService) scoreListing(kind string, posts []models.Post, meta models.ListingMeta) models.ListingMeta {
+	q := Listing(posts, meta)
+	meta.Quality = &q
+	w.tracker.Record(kind, q)
+	return meta
+}
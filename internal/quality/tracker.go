@@ -0,0 +1,88 @@
+// internal/quality/tracker.go
+package quality
+
+import (
+	"sort"
+	"sync"
+
+	"reddit-ingestion/pkg/models"
+)
+
+// recentAlpha weighs the newest score in Stats.Recent; at 0.1 it mostly
+// reflects the last twenty or so responses
+const recentAlpha = 0.1
+
+// Stats summarizes the quality scores of one kind of response since start-up
+type Stats struct {
+	// Kind of response: subreddit, user, post, search or frontpage
+	Kind      string  `json:"kind"`
+	Responses int     `json:"responses"`
+	Mean      float64 `json:"mean"`
+	// Exponential moving average, to spot a recent drop the mean hides
+	Recent float64 `json:"recent"`
+	Min    float64 `json:"min"`
+	Last   float64 `json:"last"`
+	// Responses that were cut short by a timeout or a failed part
+	Truncated int `json:"truncated"`
+}
+
+// Tracker keeps Stats per kind of response. It is safe for concurrent use; a
+// nil Tracker records nothing.
+type Tracker struct {
+	mutex  sync.Mutex
+	byKind map[string]*tracked
+}
+
+type tracked struct {
+	stats Stats
+	sum   float64
+}
+
+// NewTracker creates an empty tracker
+func NewTracker() *Tracker {
+	return &Tracker{byKind: make(map[string]*tracked)}
+}
+
+// Record adds the score of a response of kind
+func (t *Tracker) Record(kind string, q models.Quality) {
+	if t == nil {
+		return
+	}
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	k, ok := t.byKind[kind]
+	if !ok {
+		k = &tracked{stats: Stats{Kind: kind, Recent: q.Score, Min: q.Score}}
+		t.byKind[kind] = k
+	}
+	k.sum += q.Score
+	k.stats.Responses++
+	k.stats.Mean = round(k.sum / float64(k.stats.Responses))
+	k.stats.Recent = round(recentAlpha*q.Score + (1-recentAlpha)*k.stats.Recent)
+	if q.Score < k.stats.Min {
+		k.stats.Min = q.Score
+	}
+	k.stats.Last = q.Score
+	if q.Truncation < 1 {
+		k.stats.Truncated++
+	}
+}
+
+// QualityStats returns the stats of every kind recorded so far, by kind
+func (t *Tracker) QualityStats() []Stats {
+	if t == nil {
+		return nil
+	}
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	stats := make([]Stats, 0, len(t.byKind))
+	for _, k := range t.byKind {
+		stats = append(stats, k.stats)
+	}
+	sort.Slice(stats, func(i, j int) bool {
+		return stats[i].Kind < stats[j].Kind
+	})
+	return stats
+}
@@ -0,0 +1,199 @@
+// internal/resultcache/cache.go
+package resultcache
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"reddit-ingestion/pkg/client"
+	"reddit-ingestion/pkg/parser"
+	"reddit-ingestion/pkg/scraper"
+)
+
+// Cache keeps recent scrape results in memory for a short time, keyed by the
+// operation and the parameters that decide its result, so a burst of
+// identical requests scrapes Reddit once. Results are stored encoded and
+// decoded for every hit, so no caller can change another's copy. It is safe
+// for concurrent use.
+type Cache struct {
+	mutex      sync.Mutex
+	ttl        time.Duration
+	maxEntries int
+	entries    map[string]entry
+	now        func() time.Time
+}
+
+type entry struct {
+	data     []byte
+	storedAt time.Time
+}
+
+// New creates a cache whose results last ttl, holding at most maxEntries of
+// them; when full the oldest result makes room
+func New(ttl time.Duration, maxEntries int) *Cache {
+	return &Cache{
+		ttl:        ttl,
+		maxEntries: max(maxEntries, 1),
+		entries:    make(map[string]entry),
+		now:        time.Now,
+	}
+}
+
+// get decodes the result stored under key into v; false when there is none
+// or it expired
+func (c *Cache) get(key string, v interface{}) bool {
+	c.mutex.Lock()
+	e, ok := c.entries[key]
+	if ok && c.now().Sub(e.storedAt) >= c.ttl {
+		delete(c.entries, key)
+		ok = false
+	}
+	c.mutex.Unlock()
+	if !ok {
+		return false
+	}
+
+	if err := json.Unmarshal(e.data, v); err != nil {
+		fmt.Printf("Result cache entry for %s is unreadable: %v\n", key, err)
+		return false
+	}
+	return true
+}
+
+// put stores v under key
+func (c *Cache) put(key string, v interface{}) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		fmt.Printf("Not caching result for %s: %v\n", key, err)
+		return
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	now := c.now()
+	if _, ok := c.entries[key]; !ok && len(c.entries) >= c.maxEntries {
+		c.evict(now)
+	}
+	c.entries[key] = entry{data: data, storedAt: now}
+}
+
+// evict drops the expired results, or the oldest one when none expired;
+// callers hold the mutex
+func (c *Cache) evict(now time.Time) {
+	oldest := ""
+	for key, e := range c.entries {
+		if now.Sub(e.storedAt) >= c.ttl {
+			delete(c.entries, key)
+			continue
+		}
+		if oldest == "" || e.storedAt.Before(c.entries[oldest].storedAt) {
+			oldest = key
+		}
+	}
+	if len(c.entries) >= c.maxEntries {
+		delete(c.entries, oldest)
+	}
+}
+
+// Len is the number of results held, expired ones included until they are
+// looked up or pushed out
+func (c *Cache) Len() int {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return len(c.entries)
+}
+
+// key builds the key of operation on target: params plus the options of ctx
+// that change what a scrape returns. Options that only change how it is
+// fetched, such as the proxy pool or the expansion sizes, are left out.
+func key(ctx context.Context, operation, target string, params url.Values) string {
+	if params == nil {
+		params = url.Values{}
+	}
+	if parser.IncludesAwards(ctx) {
+		params.Set("awards", "true")
+	}
+	if scraper.IncludesRelated(ctx) {
+		params.Set("related", "true")
+	}
+	if scraper.IsStrict(ctx) {
+		params.Set("strict", "true")
+	}
+	if sort := client.CommentSortFromContext(ctx); sort != "" {
+		params.Set("comment_sort", sort)
+	}
+	if tree := client.CommentTreeFromContext(ctx); !tree.IsZero() {
+		params.Set("comment_tree", fmt.Sprintf("%+v", tree))
+	}
+	// A crawl policy may replace a default limit
+	if scraper.IsDefaultLimit(ctx) {
+		params.Set("default_limit", "true")
+	}
+	if filter := scraper.ActivityFilterFromContext(ctx); !filter.IsZero() {
+		params.Set("activity_filter", fmt.Sprintf("%+v", filter))
+	}
+	// Encode sorts by name
+	return operation + " " + strings.ToLower(target) + "?" + params.Encode()
+}
+
+// listingParams are the parameters of a listing scrape
+func listingParams(sinceTimestamp int64, limit int, opts scraper.ListingOptions) url.Values {
+	params := url.Values{
+		"since": {strconv.FormatInt(sinceTimestamp, 10)},
+		"limit": {strconv.Itoa(limit)},
+	}
+	if opts.After != "" {
+		params.Set("after", opts.After)
+	}
+	if !opts.Filter.IsZero() {
+		params.Set("filter", fmt.Sprintf("%+v", opts.Filter))
+	}
+	return params
+}
+
+// userParams are the parameters of a user activity scrape
+func userParams(sinceTimestamp int64, postLimit, commentLimit int) url.Values {
+	return url.Values{
+		"since":         {strconv.FormatInt(sinceTimestamp, 10)},
+		"post_limit":    {strconv.Itoa(postLimit)},
+		"comment_limit": {strconv.Itoa(commentLimit)},
+	}
+}
+
+// overviewParams are the parameters of a user overview scrape
+func overviewParams(sinceTimestamp int64, limit int) url.Values {
+	return url.Values{
+		"since": {strconv.FormatInt(sinceTimestamp, 10)},
+		"limit": {strconv.Itoa(limit)},
+	}
+}
+
+// searchKeyParams are the parameters of a search
+func searchKeyParams(searchParams map[string]string, sinceTimestamp int64, limit int, opts scraper.ListingOptions) url.Values {
+	return mapParams(listingParams(sinceTimestamp, limit, opts), "search_", searchParams)
+}
+
+// frontpageKeyParams are the parameters of a frontpage scrape
+func frontpageKeyParams(params map[string]string, limit int, opts scraper.ListingOptions) url.Values {
+	return mapParams(listingParams(0, limit, opts), "feed_", params)
+}
+
+// postTarget is the target of a post scrape, the post ID with or without its
+// t3_ prefix
+func postTarget(postID string) string {
+	return strings.TrimPrefix(postID, "t3_")
+}
+
+// mapParams adds the entries of m to params under prefix
+func mapParams(params url.Values, prefix string, m map[string]string) url.Values {
+	for name, value := range m {
+		params.Set(prefix+name, value)
+	}
+	return params
+}
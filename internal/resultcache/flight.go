@@ -0,0 +1,138 @@
+// internal/resultcache/flight.go
+package resultcache
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+
+	"golang.org/x/sync/singleflight"
+
+	"reddit-ingestion/pkg/models"
+	"reddit-ingestion/pkg/scraper"
+)
+
+// sharingService lets concurrent identical scrapes share one upstream scrape:
+// the first runs it and the others wait for its result
+type sharingService struct {
+	scraper.ScraperService
+	group singleflight.Group
+}
+
+// ShareInFlight returns a ScraperService that runs concurrent scrapes with the
+// same key once, keyed like the result cache, so a burst of requests for a
+// hot thread costs one scrape. Every waiting caller gets its own copy of the
+// result and stops waiting when its own context ends.
+func ShareInFlight(svc scraper.ScraperService) scraper.ScraperService {
+	return &sharingService{ScraperService: svc}
+}
+
+func (w *sharingService) ScrapeSubreddit(ctx context.Context, subreddit string, sinceTimestamp int64, limit int, opts scraper.ListingOptions) ([]models.Post, models.ListingMeta, error) {
+	result, err := share(ctx, &w.group, key(ctx, "subreddit", subreddit, listingParams(sinceTimestamp, limit, opts)),
+		func() (listing, error) {
+			posts, meta, err := w.ScraperService.ScrapeSubreddit(ctx, subreddit, sinceTimestamp, limit, opts)
+			return listing{Posts: posts, Meta: meta}, err
+		})
+	return result.Posts, result.Meta, err
+}
+
+func (w *sharingService) ScrapeUserActivity(ctx context.Context, username string, sinceTimestamp int64, postLimit, commentLimit int) (models.UserActivity, error) {
+	return share(ctx, &w.group, key(ctx, "user", username, userParams(sinceTimestamp, postLimit, commentLimit)),
+		func() (models.UserActivity, error) {
+			return w.ScraperService.ScrapeUserActivity(ctx, username, sinceTimestamp, postLimit, commentLimit)
+		})
+}
+
+func (w *sharingService) ScrapeUserOverview(ctx context.Context, username string, sinceTimestamp int64, limit int) (models.UserOverview, error) {
+	return share(ctx, &w.group, key(ctx, "overview", username, overviewParams(sinceTimestamp, limit)),
+		func() (models.UserOverview, error) {
+			return w.ScraperService.ScrapeUserOverview(ctx, username, sinceTimestamp, limit)
+		})
+}
+
+func (w *sharingService) ScrapePost(ctx context.Context, postID string) (models.PostDetail, error) {
+	return share(ctx, &w.group, key(ctx, "post", postTarget(postID), nil),
+		func() (models.PostDetail, error) {
+			return w.ScraperService.ScrapePost(ctx, postID)
+		})
+}
+
+func (w *sharingService) Search(ctx context.Context, searchParams map[string]string, sinceTimestamp int64, limit int, opts scraper.ListingOptions) ([]models.Post, models.ListingMeta, error) {
+	result, err := share(ctx, &w.group, key(ctx, "search", "", searchKeyParams(searchParams, sinceTimestamp, limit, opts)),
+		func() (listing, error) {
+			posts, meta, err := w.ScraperService.Search(ctx, searchParams, sinceTimestamp, limit, opts)
+			return listing{Posts: posts, Meta: meta}, err
+		})
+	return result.Posts, result.Meta, err
+}
+
+func (w *sharingService) ScrapeFrontpage(ctx context.Context, feed string, params map[string]string, limit int, opts scraper.ListingOptions) ([]models.Post, models.ListingMeta, error) {
+	result, err := share(ctx, &w.group, key(ctx, "frontpage", feed, frontpageKeyParams(params, limit, opts)),
+		func() (listing, error) {
+			posts, meta, err := w.ScraperService.ScrapeFrontpage(ctx, feed, params, limit, opts)
+			return listing{Posts: posts, Meta: meta}, err
+		})
+	return result.Posts, result.Meta, err
+}
+
+// flight is the outcome of a shared scrape. Once it is shared nobody touches
+// value; each caller decodes its own copy of the encoded result instead.
+type flight[T any] struct {
+	value   T
+	once    sync.Once
+	encoded []byte
+	err     error
+}
+
+func (f *flight[T]) copy() (T, error) {
+	f.once.Do(func() {
+		f.encoded, f.err = json.Marshal(f.value)
+	})
+	var result T
+	if f.err != nil {
+		return result, f.err
+	}
+	err := json.Unmarshal(f.encoded, &result)
+	return result, err
+}
+
+// share runs scrape under key unless an identical scrape is running, in which
+// case it waits for that one's result. A caller whose own context is still
+// live scrapes again when the scrape it waited on was cancelled by the
+// context of the caller that started it.
+func share[T any](ctx context.Context, group *singleflight.Group, key string, scrape func() (T, error)) (T, error) {
+	var zero T
+	results := group.DoChan(key, func() (interface{}, error) {
+		value, err := scrape()
+		return &flight[T]{value: value}, err
+	})
+
+	var result singleflight.Result
+	select {
+	case result = <-results:
+	case <-ctx.Done():
+		return zero, ctx.Err()
+	}
+
+	f := result.Val.(*flight[T])
+	if result.Err != nil && isContextError(result.Err) && ctx.Err() == nil {
+		fmt.Printf("Shared scrape for %s was cancelled, scraping again\n", key)
+		return scrape()
+	}
+	if !result.Shared {
+		return f.value, result.Err
+	}
+
+	fmt.Printf("Shared in-flight scrape for %s\n", key)
+	value, err := f.copy()
+	if err != nil {
+		return zero, fmt.Errorf("failed to copy shared result: %w", err)
+	}
+	return value, result.Err
+}
+
+func isContextError(err error) bool {
+	return errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded)
+}
@@ -0,0 +1,142 @@
+// internal/resultcache/service.go
+package resultcache
+
+import (
+	"context"
+	"fmt"
+
+	"reddit-ingestion/pkg/models"
+	"reddit-ingestion/pkg/scraper"
+)
+
+// cachingService answers scrapes from a Cache when an identical one finished
+// within its TTL
+type cachingService struct {
+	scraper.ScraperService
+	cache *Cache
+}
+
+// WrapService returns a ScraperService that serves repeated scrapes from
+// cache, marking them as cached. Full-history scrapes (a limit of -1) are
+// not cached, nor are results cut short by the request's time budget or
+// missing parts, which another request may well get whole.
+func WrapService(svc scraper.ScraperService, cache *Cache) scraper.ScraperService {
+	return &cachingService{
+		ScraperService: svc,
+		cache:          cache,
+	}
+}
+
+// listing is a cached subreddit, search or frontpage result
+type listing struct {
+	Posts []models.Post      `json:"posts"`
+	Meta  models.ListingMeta `json:"meta"`
+}
+
+func (w *cachingService) ScrapeSubreddit(ctx context.Context, subreddit string, sinceTimestamp int64, limit int, opts scraper.ListingOptions) ([]models.Post, models.ListingMeta, error) {
+	if limit < 0 {
+		return w.ScraperService.ScrapeSubreddit(ctx, subreddit, sinceTimestamp, limit, opts)
+	}
+	result, err := through(w.cache, key(ctx, "subreddit", subreddit, listingParams(sinceTimestamp, limit, opts)),
+		func() (listing, error) {
+			posts, meta, err := w.ScraperService.ScrapeSubreddit(ctx, subreddit, sinceTimestamp, limit, opts)
+			return listing{Posts: posts, Meta: meta}, err
+		}, keepListing, markListing)
+	return result.Posts, result.Meta, err
+}
+
+func (w *cachingService) ScrapeUserActivity(ctx context.Context, username string, sinceTimestamp int64, postLimit, commentLimit int) (models.UserActivity, error) {
+	if postLimit < 0 || commentLimit < 0 {
+		return w.ScraperService.ScrapeUserActivity(ctx, username, sinceTimestamp, postLimit, commentLimit)
+	}
+	return through(w.cache, key(ctx, "user", username, userParams(sinceTimestamp, postLimit, commentLimit)),
+		func() (models.UserActivity, error) {
+			return w.ScraperService.ScrapeUserActivity(ctx, username, sinceTimestamp, postLimit, commentLimit)
+		},
+		func(activity models.UserActivity) bool {
+			return activity.Meta == nil || !activity.Meta.Partial && activity.Meta.TruncationReason != models.TruncatedTimeLimit
+		},
+		func(activity *models.UserActivity) {
+			if activity.Meta == nil {
+				activity.Meta = &models.UserActivityMeta{}
+			}
+			activity.Meta.Cached = true
+		})
+}
+
+func (w *cachingService) ScrapeUserOverview(ctx context.Context, username string, sinceTimestamp int64, limit int) (models.UserOverview, error) {
+	if limit < 0 {
+		return w.ScraperService.ScrapeUserOverview(ctx, username, sinceTimestamp, limit)
+	}
+	return through(w.cache, key(ctx, "overview", username, overviewParams(sinceTimestamp, limit)),
+		func() (models.UserOverview, error) {
+			return w.ScraperService.ScrapeUserOverview(ctx, username, sinceTimestamp, limit)
+		},
+		func(overview models.UserOverview) bool {
+			return overview.Meta == nil || overview.Meta.TruncationReason != models.TruncatedTimeLimit
+		},
+		func(overview *models.UserOverview) {
+			if overview.Meta == nil {
+				overview.Meta = &models.UserOverviewMeta{}
+			}
+			overview.Meta.Cached = true
+		})
+}
+
+func (w *cachingService) ScrapePost(ctx context.Context, postID string) (models.PostDetail, error) {
+	return through(w.cache, key(ctx, "post", postTarget(postID), nil),
+		func() (models.PostDetail, error) {
+			return w.ScraperService.ScrapePost(ctx, postID)
+		},
+		func(models.PostDetail) bool { return true },
+		func(detail *models.PostDetail) { detail.Cached = true })
+}
+
+func (w *cachingService) Search(ctx context.Context, searchParams map[string]string, sinceTimestamp int64, limit int, opts scraper.ListingOptions) ([]models.Post, models.ListingMeta, error) {
+	if limit < 0 {
+		return w.ScraperService.Search(ctx, searchParams, sinceTimestamp, limit, opts)
+	}
+	result, err := through(w.cache, key(ctx, "search", "", searchKeyParams(searchParams, sinceTimestamp, limit, opts)),
+		func() (listing, error) {
+			posts, meta, err := w.ScraperService.Search(ctx, searchParams, sinceTimestamp, limit, opts)
+			return listing{Posts: posts, Meta: meta}, err
+		}, keepListing, markListing)
+	return result.Posts, result.Meta, err
+}
+
+func (w *cachingService) ScrapeFrontpage(ctx context.Context, feed string, params map[string]string, limit int, opts scraper.ListingOptions) ([]models.Post, models.ListingMeta, error) {
+	if limit < 0 {
+		return w.ScraperService.ScrapeFrontpage(ctx, feed, params, limit, opts)
+	}
+	result, err := through(w.cache, key(ctx, "frontpage", feed, frontpageKeyParams(params, limit, opts)),
+		func() (listing, error) {
+			posts, meta, err := w.ScraperService.ScrapeFrontpage(ctx, feed, params, limit, opts)
+			return listing{Posts: posts, Meta: meta}, err
+		}, keepListing, markListing)
+	return result.Posts, result.Meta, err
+}
+
+func keepListing(result listing) bool {
+	return !result.Meta.TimedOut && result.Meta.TruncationReason != models.TruncatedTimeLimit
+}
+
+func markListing(result *listing) {
+	result.Meta.Cached = true
+}
+
+// through returns the result cached under key, marked by mark, or runs scrape
+// and caches its result when it succeeded and keep accepts it
+func through[T any](cache *Cache, key string, scrape func() (T, error), keep func(T) bool, mark func(*T)) (T, error) {
+	var result T
+	if cache.get(key, &result) {
+		fmt.Printf("Result cache hit for %s\n", key)
+		mark(&result)
+		return result, nil
+	}
+
+	result, err := scrape()
+	if err == nil && keep(result) {
+		cache.put(key, result)
+	}
+	return result, err
+}
@@ -2,20 +2,134 @@
 package router
 
 import (
+	"reddit-ingestion/internal/active"
+	"reddit-ingestion/internal/archive"
+	"reddit-ingestion/internal/config"
+	"reddit-ingestion/internal/dedup"
 	"reddit-ingestion/internal/handler/http"
-	"reddit-ingestion/internal/scraper"
+	"reddit-ingestion/internal/policy"
+	"reddit-ingestion/internal/snapshot"
+	"reddit-ingestion/internal/stats"
+	"reddit-ingestion/pkg/scraper"
 
 	"github.com/labstack/echo/v4"
 )
 
-func NewRouter(e *echo.Echo, svc scraper.ScraperService) {
-	sub := http.NewSubredditHandler(svc)
-	usr := http.NewUserHandler(svc)
+// snapshotHistorySize is how many snapshots are kept per subreddit for diffing
+const snapshotHistorySize = 48
+
+// NewRouter registers the scraping endpoints; mw is applied to each of them
+func NewRouter(e *echo.Echo, svc scraper.ScraperService, mw ...echo.MiddlewareFunc) {
+	NewRouterWithLimits(e, svc, http.Limits{}, mw...)
+}
+
+// NewRouterWithLimits registers the scraping endpoints, applying limits to
+// their limit parameters; mw is applied to each of them
+func NewRouterWithLimits(e *echo.Echo, svc scraper.ScraperService, limits http.Limits, mw ...echo.MiddlewareFunc) {
+	sub := http.NewSubredditHandlerWithLimits(svc, limits)
+	usr := http.NewUserHandlerWithLimits(svc, limits)
 	pst := http.NewPostHandler(svc)
-	sch := http.NewSearchHandler(svc)
+	sch := http.NewSearchHandlerWithLimits(svc, limits)
+	frt := http.NewFrontpageHandlerWithLimits(svc, limits)
+	chg := http.NewChangesHandler(snapshot.NewDiffService(svc, snapshot.NewStore(snapshotHistorySize)))
+	dup := http.NewDuplicatesHandlerWithLimits(dedup.NewFinder(svc), limits)
+
+	e.GET("/subreddit", sub.GetSubredditPosts, mw...)
+	e.GET("/subreddit/changes", chg.GetSubredditChanges, mw...)
+	e.GET("/duplicates", dup.GetDuplicates, mw...)
+	e.GET("/user", usr.GetUserInfo, mw...)
+	e.GET("/user/overview", usr.GetUserOverview, mw...)
+	e.GET("/user/summary", usr.GetUserSummary, mw...)
+	e.GET("/post", pst.GetPostInfo, mw...)
+	e.GET("/ws/post", pst.StreamPostInfo, mw...)
+	e.GET("/search", sch.Search, mw...)
+	e.GET("/frontpage", frt.GetFrontpagePosts, mw...)
+}
+
+// NewStatsRouter registers GET /stats, reporting the counters of registry
+// and, when proxies and sink are not nil, the usage of each proxy and the
+// deliveries of the sink
+func NewStatsRouter(e *echo.Echo, registry *stats.Registry, proxies http.BandwidthReporter, sink http.SinkReporter) {
+	sts := http.NewStatsHandler(registry, proxies, sink)
+	e.GET("/stats", sts.GetStats)
+}
+
+// NewHealthRouter registers GET /healthz, and GET /health for the checks
+// configured before it, reporting the service degraded while hosts, when not
+// nil, has failed over to a fallback Reddit host
+func NewHealthRouter(e *echo.Echo, hosts http.HostHealthReporter) {
+	hlt := http.NewHealthHandler(hosts)
+	e.GET("/healthz", hlt.GetHealth)
+	e.GET("/health", hlt.GetHealth)
+}
+
+// NewRawRouter registers GET /raw, passing allowlisted Reddit paths through
+// fetcher, behind mw (the admin key check first)
+func NewRawRouter(e *echo.Echo, fetcher http.RawFetcher, allowlist []string, mw ...echo.MiddlewareFunc) {
+	raw := http.NewRawHandler(fetcher, allowlist)
+	e.GET("/raw", raw.GetRaw, mw...)
+}
+
+// AdminOptions carries the optional components behind the /admin endpoints;
+// endpoints whose component is nil are not registered
+type AdminOptions struct {
+	// Key is the admin API key every /admin request must carry; without it
+	// the configuration and the endpoints that change anything are not
+	// registered
+	Key       string
+	Replayer  *archive.Replayer
+	Config    *config.Live
+	Bandwidth http.BandwidthReporter
+	Usage     http.ProxyUsageReporter
+	Quality   http.QualityReporter
+	Active    *active.Registry
+	Blocklist *policy.Blocklist
+	Sink      http.SinkReporter
+}
+
+// NewAdminRouter registers the /admin endpoints, behind the admin key check
+// when opts.Key is set
+func NewAdminRouter(e *echo.Echo, opts AdminOptions) {
+	admin := e.Group("/admin")
+	if opts.Key != "" {
+		admin.Use(http.AdminKeyMiddleware(opts.Key))
+	}
+
+	if opts.Replayer != nil && opts.Key != "" {
+		rpl := http.NewReplayHandler(opts.Replayer)
+		admin.POST("/replay", rpl.Replay)
+	}
+
+	// The configuration names proxy hosts and users even with secrets masked
+	if opts.Config != nil && opts.Key != "" {
+		cfg := http.NewConfigHandler(opts.Config)
+		admin.GET("/config", cfg.GetConfig)
+	}
+
+	if opts.Bandwidth != nil {
+		sts := http.NewStatusHandler(opts.Bandwidth, opts.Quality, opts.Sink)
+		admin.GET("/status", sts.GetStatus)
+	}
+
+	if opts.Usage != nil {
+		usg := http.NewProxyUsageHandler(opts.Usage)
+		admin.GET("/proxies/usage", usg.GetProxyUsage)
+	}
+
+	if opts.Active != nil {
+		act := http.NewActiveHandler(opts.Active)
+		admin.GET("/active", act.GetActive)
+		if opts.Key != "" {
+			admin.DELETE("/active/:id", act.CancelActive)
+		}
+	}
 
-	e.GET("/subreddit", sub.GetSubredditPosts)
-	e.GET("/user", usr.GetUserInfo)
-	e.GET("/post", pst.GetPostInfo)
-	e.GET("/search", sch.Search)
-}
\ No newline at end of file
+	if opts.Blocklist != nil {
+		blk := http.NewBlocklistHandler(opts.Blocklist)
+		admin.GET("/blocklist", blk.GetBlocklist)
+		if opts.Key != "" {
+			admin.PUT("/blocklist/:kind/:name", blk.Block)
+			admin.DELETE("/blocklist/:kind/:name", blk.Unblock)
+		}
+	}
+}
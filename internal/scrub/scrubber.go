@@ -0,0 +1,222 @@
+// internal/scrub/scrubber.go
+package scrub
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"sync"
+
+	"reddit-ingestion/pkg/models"
+)
+
+// DefaultPatterns are the kinds of personal data scrubbed unless a patterns
+// file overrides them: email addresses, phone numbers and u/name mentions
+var DefaultPatterns = map[string]string{
+	"email": `[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}`,
+	"phone": `(?:\+\d{1,3}[\s.-]?)?(?:\(\d{2,4}\)|\d{2,4})[\s.-]\d{3,4}[\s.-]\d{3,4}\b`,
+	"user":  `(?i)/?\b(?:u|user)/[a-z0-9_-]{3,20}`,
+}
+
+// defaultOrder applies emails before the other defaults so an address is
+// never half-redacted as a phone number or mention
+var defaultOrder = []string{"email", "phone", "user"}
+
+type pattern struct {
+	name        string
+	re          *regexp.Regexp
+	replacement string
+}
+
+// Scrubber redacts personal data from the titles and bodies of posts and
+// comments, replacing each match with the name of its pattern in brackets,
+// e.g. [email]. It is safe for concurrent use and its patterns can be replaced
+// by a reload.
+type Scrubber struct {
+	mutex    sync.RWMutex
+	patterns []pattern
+}
+
+// NewScrubber creates a scrubber with DefaultPatterns, overridden and extended
+// by patterns: a pattern named like a default replaces it, and an empty one
+// turns it off
+func NewScrubber(patterns map[string]string) (*Scrubber, error) {
+	s := &Scrubber{}
+	if err := s.Set(patterns); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Set replaces the patterns on top of DefaultPatterns. On error the previous
+// patterns stay in place.
+func (s *Scrubber) Set(patterns map[string]string) error {
+	merged := make(map[string]string, len(DefaultPatterns)+len(patterns))
+	for name, expr := range DefaultPatterns {
+		merged[name] = expr
+	}
+	var extra []string
+	for name, expr := range patterns {
+		if _, ok := DefaultPatterns[name]; !ok {
+			extra = append(extra, name)
+		}
+		merged[name] = expr
+	}
+	sort.Strings(extra)
+
+	var compiled []pattern
+	for _, name := range append(append([]string{}, defaultOrder...), extra...) {
+		if merged[name] == "" {
+			continue
+		}
+		re, err := regexp.Compile(merged[name])
+		if err != nil {
+			return fmt.Errorf("scrub pattern %s: %w", name, err)
+		}
+		compiled = append(compiled, pattern{name: name, re: re, replacement: "[" + name + "]"})
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.patterns = compiled
+	return nil
+}
+
+// Text returns text with every match of every pattern redacted
+func (s *Scrubber) Text(text string) string {
+	if text == "" {
+		return text
+	}
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	for _, p := range s.patterns {
+		text = p.re.ReplaceAllLiteralString(text, p.replacement)
+	}
+	return text
+}
+
+// Post scrubs a post's title and body and marks it scrubbed
+func (s *Scrubber) Post(post models.Post) models.Post {
+	post.Title = s.Text(post.Title)
+	post.Body = s.Text(post.Body)
+	post.Scrubbed = true
+	return post
+}
+
+// Posts returns scrubbed copies of posts
+func (s *Scrubber) Posts(posts []models.Post) []models.Post {
+	if posts == nil {
+		return nil
+	}
+	scrubbed := make([]models.Post, len(posts))
+	for i, post := range posts {
+		scrubbed[i] = s.Post(post)
+	}
+	return scrubbed
+}
+
+// Comments returns scrubbed copies of comments and all their replies. "load
+// more" placeholders carry no text and are left unmarked.
+func (s *Scrubber) Comments(comments []models.Comment) []models.Comment {
+	if comments == nil {
+		return nil
+	}
+	scrubbed := make([]models.Comment, len(comments))
+	for i, comment := range comments {
+		if !comment.IsMore {
+			comment.Body = s.Text(comment.Body)
+			comment.Scrubbed = true
+		}
+		comment.Replies = s.Comments(comment.Replies)
+		scrubbed[i] = comment
+	}
+	return scrubbed
+}
+
+// PostDetail scrubs a post, its comment tree and its related posts
+func (s *Scrubber) PostDetail(detail models.PostDetail) models.PostDetail {
+	detail.Post = s.Post(detail.Post)
+	detail.Comments = s.Comments(detail.Comments)
+	detail.Related = s.Posts(detail.Related)
+	return detail
+}
+
+// UserActivity scrubs a user's posts and comments. The titles of the posts
+// the comments were made on are scrubbed too.
+func (s *Scrubber) UserActivity(activity models.UserActivity) models.UserActivity {
+	if activity.Posts != nil {
+		posts := make([]models.UserPost, len(activity.Posts))
+		for i, post := range activity.Posts {
+			posts[i] = s.userPost(post)
+		}
+		activity.Posts = posts
+	}
+	if activity.Comments != nil {
+		comments := make([]models.UserComment, len(activity.Comments))
+		for i, comment := range activity.Comments {
+			comments[i] = s.userComment(comment)
+		}
+		activity.Comments = comments
+	}
+	return activity
+}
+
+// UserOverview scrubs the posts and comments of a user's overview
+func (s *Scrubber) UserOverview(overview models.UserOverview) models.UserOverview {
+	if overview.Items == nil {
+		return overview
+	}
+	items := make([]models.UserOverviewItem, len(overview.Items))
+	for i, item := range overview.Items {
+		if item.Post != nil {
+			post := s.userPost(*item.Post)
+			item.Post = &post
+		}
+		if item.Comment != nil {
+			comment := s.userComment(*item.Comment)
+			item.Comment = &comment
+		}
+		items[i] = item
+	}
+	overview.Items = items
+	return overview
+}
+
+func (s *Scrubber) userPost(post models.UserPost) models.UserPost {
+	post.Title = s.Text(post.Title)
+	post.Body = s.Text(post.Body)
+	post.Scrubbed = true
+	return post
+}
+
+func (s *Scrubber) userComment(comment models.UserComment) models.UserComment {
+	comment.Body = s.Text(comment.Body)
+	comment.PostTitle = s.Text(comment.PostTitle)
+	comment.Scrubbed = true
+	return comment
+}
+
+// ReadPatterns reads scrub patterns from a JSON file mapping pattern names to
+// regular expressions, e.g. {"iban": "\\b[A-Z]{2}\\d{2}[A-Z0-9]{11,30}\\b"}.
+// An empty path means no patterns beyond the defaults.
+func ReadPatterns(path string) (map[string]string, error) {
+	if path == "" {
+		return nil, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read scrub patterns: %w", err)
+	}
+	var patterns map[string]string
+	if err := json.Unmarshal(data, &patterns); err != nil {
+		return nil, fmt.Errorf("parse scrub patterns %s: %w", path, err)
+	}
+	for name := range patterns {
+		if name == "" {
+			return nil, fmt.Errorf("scrub patterns %s: pattern names must not be empty", path)
+		}
+	}
+	return patterns, nil
+}
@@ -0,0 +1,55 @@
+// internal/scrub/service.go
+package scrub
+
+import (
+	"context"
+
+	"reddit-ingestion/pkg/models"
+	"reddit-ingestion/pkg/scraper"
+)
+
+// scrubbingService redacts personal data from every scrape result before it
+// is returned or handed on to a sink
+type scrubbingService struct {
+	scraper.ScraperService
+	scrubber *Scrubber
+}
+
+// WrapService returns a ScraperService whose results are scrubbed by s. Wrap
+// it inside the sink so only scrubbed text is stored.
+func WrapService(svc scraper.ScraperService, s *Scrubber) scraper.ScraperService {
+	return &scrubbingService{
+		ScraperService: svc,
+		scrubber:       s,
+	}
+}
+
+func (w *scrubbingService) ScrapeSubreddit(ctx context.Context, subreddit string, sinceTimestamp int64, limit int, opts scraper.ListingOptions) ([]models.Post, models.ListingMeta, error) {
+	posts, meta, err := w.ScraperService.ScrapeSubreddit(ctx, subreddit, sinceTimestamp, limit, opts)
+	return w.scrubber.Posts(posts), meta, err
+}
+
+func (w *scrubbingService) ScrapeUserActivity(ctx context.Context, username string, sinceTimestamp int64, postLimit, commentLimit int) (models.UserActivity, error) {
+	activity, err := w.ScraperService.ScrapeUserActivity(ctx, username, sinceTimestamp, postLimit, commentLimit)
+	return w.scrubber.UserActivity(activity), err
+}
+
+func (w *scrubbingService) ScrapeUserOverview(ctx context.Context, username string, sinceTimestamp int64, limit int) (models.UserOverview, error) {
+	overview, err := w.ScraperService.ScrapeUserOverview(ctx, username, sinceTimestamp, limit)
+	return w.scrubber.UserOverview(overview), err
+}
+
+func (w *scrubbingService) ScrapePost(ctx context.Context, postID string) (models.PostDetail, error) {
+	detail, err := w.ScraperService.ScrapePost(ctx, postID)
+	return w.scrubber.PostDetail(detail), err
+}
+
+func (w *scrubbingService) Search(ctx context.Context, searchParams map[string]string, sinceTimestamp int64, limit int, opts scraper.ListingOptions) ([]models.Post, models.ListingMeta, error) {
+	posts, meta, err := w.ScraperService.Search(ctx, searchParams, sinceTimestamp, limit, opts)
+	return w.scrubber.Posts(posts), meta, err
+}
+
+func (w *scrubbingService) ScrapeFrontpage(ctx context.Context, feed string, params map[string]string, limit int, opts scraper.ListingOptions) ([]models.Post, models.ListingMeta, error) {
+	posts, meta, err := w.ScraperService.ScrapeFrontpage(ctx, feed, params, limit, opts)
+	return w.scrubber.Posts(posts), meta, err
+}
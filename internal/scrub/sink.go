@@ -0,0 +1,33 @@
+// internal/scrub/sink.go
+package scrub
+
+import (
+	"context"
+
+	"reddit-ingestion/internal/sink"
+	"reddit-ingestion/pkg/models"
+)
+
+// scrubbingSink redacts personal data from everything written to it, for
+// writers that bypass the scraper such as the archive replayer
+type scrubbingSink struct {
+	sink.Sink
+	scrubber *Scrubber
+}
+
+// WrapSink returns a Sink that scrubs content with s before writing it to next
+func WrapSink(next sink.Sink, s *Scrubber) sink.Sink {
+	return &scrubbingSink{Sink: next, scrubber: s}
+}
+
+func (w *scrubbingSink) WritePosts(ctx context.Context, posts []models.Post) error {
+	return w.Sink.WritePosts(ctx, w.scrubber.Posts(posts))
+}
+
+func (w *scrubbingSink) WriteComments(ctx context.Context, postID string, comments []models.Comment) error {
+	return w.Sink.WriteComments(ctx, postID, w.scrubber.Comments(comments))
+}
+
+func (w *scrubbingSink) WriteUserActivity(ctx context.Context, activity models.UserActivity) error {
+	return w.Sink.WriteUserActivity(ctx, w.scrubber.UserActivity(activity))
+}
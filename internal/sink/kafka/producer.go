@@ -0,0 +1,204 @@
+// internal/sink/kafka/producer.go
+package kafka
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	kafkago "github.com/segmentio/kafka-go"
+
+	"reddit-ingestion/internal/audit"
+	"reddit-ingestion/internal/sink"
+	"reddit-ingestion/pkg/models"
+)
+
+// Config holds the producer settings
+type Config struct {
+	Brokers           []string
+	PostsTopic        string
+	CommentsTopic     string
+	UserActivityTopic string
+	BatchSize         int
+	BatchTimeout      time.Duration
+
+	// NewWriter creates the writer of a topic, which calls completion with
+	// every batch it delivers or fails to deliver; nil writes to Brokers
+	NewWriter func(topic string, completion func(messages []kafkago.Message, err error)) MessageWriter
+}
+
+// MessageWriter writes messages to one topic, as *kafkago.Writer does
+type MessageWriter interface {
+	WriteMessages(ctx context.Context, messages ...kafkago.Message) error
+	Close() error
+}
+
+// topicWriter is the writer of topic
+type topicWriter struct {
+	MessageWriter
+	topic string
+}
+
+// commentMessage is the payload published for every comment in a tree
+type commentMessage struct {
+	PostID   string         `json:"post_id"`
+	ParentID string         `json:"parent_id"`
+	Comment  models.Comment `json:"comment"`
+}
+
+// Producer publishes scraped posts, comments and user activity to Kafka.
+// Writes are asynchronous and batched; delivery failures are counted.
+type Producer struct {
+	posts        topicWriter
+	comments     topicWriter
+	userActivity topicWriter
+	delivered    atomic.Uint64
+	failed       atomic.Uint64
+}
+
+func NewProducer(cfg Config) (*Producer, error) {
+	if len(cfg.Brokers) == 0 && cfg.NewWriter == nil {
+		return nil, fmt.Errorf("at least one Kafka broker must be provided")
+	}
+
+	p := &Producer{}
+	p.posts = p.newWriter(cfg, cfg.PostsTopic)
+	p.comments = p.newWriter(cfg, cfg.CommentsTopic)
+	p.userActivity = p.newWriter(cfg, cfg.UserActivityTopic)
+
+	fmt.Printf("Kafka sink enabled with %d brokers (topics: %s, %s, %s)\n",
+		len(cfg.Brokers), cfg.PostsTopic, cfg.CommentsTopic, cfg.UserActivityTopic)
+
+	return p, nil
+}
+
+func (p *Producer) newWriter(cfg Config, topic string) topicWriter {
+	completion := func(messages []kafkago.Message, err error) {
+		if err != nil {
+			p.failed.Add(uint64(len(messages)))
+			fmt.Printf("Kafka delivery of %d messages to %s failed: %v\n", len(messages), topic, err)
+			return
+		}
+		p.delivered.Add(uint64(len(messages)))
+	}
+	if cfg.NewWriter != nil {
+		return topicWriter{MessageWriter: cfg.NewWriter(topic, completion), topic: topic}
+	}
+	return topicWriter{
+		MessageWriter: &kafkago.Writer{
+			Addr:         kafkago.TCP(cfg.Brokers...),
+			Topic:        topic,
+			Balancer:     &kafkago.Hash{},
+			BatchSize:    cfg.BatchSize,
+			BatchTimeout: cfg.BatchTimeout,
+			Async:        true,
+			Completion:   completion,
+		},
+		topic: topic,
+	}
+}
+
+// postKey is the key of a post's message: its lowercased subreddit and its
+// ID, as golang/abc123, so consumers can pick a subreddit's posts by key
+// prefix. Posts without a subreddit are keyed by their ID alone.
+func postKey(post models.Post) string {
+	if post.Subreddit == "" {
+		return post.ID
+	}
+	return strings.ToLower(post.Subreddit) + "/" + post.ID
+}
+
+// WritePosts publishes one message per post, keyed by postKey
+func (p *Producer) WritePosts(ctx context.Context, posts []models.Post) error {
+	messages := make([]kafkago.Message, 0, len(posts))
+	for _, post := range posts {
+		value, err := json.Marshal(post)
+		if err != nil {
+			return fmt.Errorf("marshal post %s: %w", post.ID, err)
+		}
+		messages = append(messages, kafkago.Message{Key: []byte(postKey(post)), Value: value})
+	}
+	return p.write(ctx, p.posts, messages)
+}
+
+// WriteComments flattens the comment tree and publishes one message per
+// comment, keyed by post ID so a thread stays on a single partition. The
+// subreddit is left out of the key: comments reach the sink with their
+// post's ID only, replayed morechildren pages included, and the post ID
+// alone already keeps a thread together.
+func (p *Producer) WriteComments(ctx context.Context, postID string, comments []models.Comment) error {
+	var messages []kafkago.Message
+	var flatten func(comments []models.Comment, parentID string) error
+	flatten = func(comments []models.Comment, parentID string) error {
+		for _, comment := range comments {
+			if comment.IsMore {
+				continue
+			}
+			replies := comment.Replies
+			comment.Replies = nil
+
+			value, err := json.Marshal(commentMessage{PostID: postID, ParentID: parentID, Comment: comment})
+			if err != nil {
+				return fmt.Errorf("marshal comment %s: %w", comment.ID, err)
+			}
+			messages = append(messages, kafkago.Message{Key: []byte(postID), Value: value})
+
+			if err := flatten(replies, comment.ID); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	if err := flatten(comments, postID); err != nil {
+		return err
+	}
+	return p.write(ctx, p.comments, messages)
+}
+
+// WriteUserActivity publishes the activity as a single message keyed by username
+func (p *Producer) WriteUserActivity(ctx context.Context, activity models.UserActivity) error {
+	value, err := json.Marshal(activity)
+	if err != nil {
+		return fmt.Errorf("marshal user activity: %w", err)
+	}
+	return p.write(ctx, p.userActivity, []kafkago.Message{{Key: []byte(activity.UserInfo.Username), Value: value}})
+}
+
+func (p *Producer) write(ctx context.Context, w topicWriter, messages []kafkago.Message) error {
+	if len(messages) == 0 {
+		return nil
+	}
+	if purpose := audit.PurposeFromContext(ctx); purpose != "" {
+		for i := range messages {
+			messages[i].Headers = append(messages[i].Headers, kafkago.Header{Key: "purpose", Value: []byte(purpose)})
+		}
+	}
+	if err := w.WriteMessages(ctx, messages...); err != nil {
+		p.failed.Add(uint64(len(messages)))
+		return fmt.Errorf("write %d messages to %s: %w", len(messages), w.topic, err)
+	}
+	return nil
+}
+
+// Stats returns the delivery counters
+func (p *Producer) Stats() sink.Stats {
+	return sink.Stats{
+		Delivered: p.delivered.Load(),
+		Failed:    p.failed.Load(),
+	}
+}
+
+// Close flushes pending batches and closes all writers
+func (p *Producer) Close() error {
+	var firstErr error
+	for _, w := range []topicWriter{p.posts, p.comments, p.userActivity} {
+		if err := w.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
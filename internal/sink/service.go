@@ -0,0 +1,88 @@
+// internal/sink/service.go
+package sink
+
+import (
+	"context"
+	"fmt"
+
+	"reddit-ingestion/pkg/models"
+	"reddit-ingestion/pkg/scraper"
+)
+
+// sinkingService writes every successful scrape result through a Sink
+type sinkingService struct {
+	scraper.ScraperService
+	sink Sink
+}
+
+// WrapService returns a ScraperService that forwards results to s. Sink
+// failures are logged and never fail the scrape itself.
+func WrapService(svc scraper.ScraperService, s Sink) scraper.ScraperService {
+	return &sinkingService{
+		ScraperService: svc,
+		sink:           s,
+	}
+}
+
+func (w *sinkingService) ScrapeSubreddit(ctx context.Context, subreddit string, sinceTimestamp int64, limit int, opts scraper.ListingOptions) ([]models.Post, models.ListingMeta, error) {
+	posts, meta, err := w.ScraperService.ScrapeSubreddit(ctx, subreddit, sinceTimestamp, limit, opts)
+	if err == nil && len(posts) > 0 {
+		if sinkErr := w.sink.WritePosts(ctx, posts); sinkErr != nil {
+			fmt.Printf("Sink write failed for %d posts from r/%s: %v\n", len(posts), subreddit, sinkErr)
+		}
+	}
+	return posts, meta, err
+}
+
+func (w *sinkingService) ScrapeUserActivity(ctx context.Context, username string, sinceTimestamp int64, postLimit, commentLimit int) (models.UserActivity, error) {
+	activity, err := w.ScraperService.ScrapeUserActivity(ctx, username, sinceTimestamp, postLimit, commentLimit)
+	if err == nil {
+		if sinkErr := w.sink.WriteUserActivity(ctx, activity); sinkErr != nil {
+			fmt.Printf("Sink write failed for user %s: %v\n", username, sinkErr)
+		}
+	}
+	return activity, err
+}
+
+func (w *sinkingService) ScrapeUserOverview(ctx context.Context, username string, sinceTimestamp int64, limit int) (models.UserOverview, error) {
+	overview, err := w.ScraperService.ScrapeUserOverview(ctx, username, sinceTimestamp, limit)
+	if err == nil {
+		if sinkErr := w.sink.WriteUserActivity(ctx, overview.Activity()); sinkErr != nil {
+			fmt.Printf("Sink write failed for overview of user %s: %v\n", username, sinkErr)
+		}
+	}
+	return overview, err
+}
+
+func (w *sinkingService) ScrapePost(ctx context.Context, postID string) (models.PostDetail, error) {
+	detail, err := w.ScraperService.ScrapePost(ctx, postID)
+	if err == nil {
+		if sinkErr := w.sink.WritePosts(ctx, []models.Post{detail.Post}); sinkErr != nil {
+			fmt.Printf("Sink write failed for post %s: %v\n", postID, sinkErr)
+		}
+		if sinkErr := w.sink.WriteComments(ctx, postID, detail.Comments); sinkErr != nil {
+			fmt.Printf("Sink write failed for comments of post %s: %v\n", postID, sinkErr)
+		}
+	}
+	return detail, err
+}
+
+func (w *sinkingService) Search(ctx context.Context, searchParams map[string]string, sinceTimestamp int64, limit int, opts scraper.ListingOptions) ([]models.Post, models.ListingMeta, error) {
+	posts, meta, err := w.ScraperService.Search(ctx, searchParams, sinceTimestamp, limit, opts)
+	if err == nil && len(posts) > 0 {
+		if sinkErr := w.sink.WritePosts(ctx, posts); sinkErr != nil {
+			fmt.Printf("Sink write failed for %d search results: %v\n", len(posts), sinkErr)
+		}
+	}
+	return posts, meta, err
+}
+
+func (w *sinkingService) ScrapeFrontpage(ctx context.Context, feed string, params map[string]string, limit int, opts scraper.ListingOptions) ([]models.Post, models.ListingMeta, error) {
+	posts, meta, err := w.ScraperService.ScrapeFrontpage(ctx, feed, params, limit, opts)
+	if err == nil && len(posts) > 0 {
+		if sinkErr := w.sink.WritePosts(ctx, posts); sinkErr != nil {
+			fmt.Printf("Sink write failed for %d posts from %s: %v\n", len(posts), feed, sinkErr)
+		}
+	}
+	return posts, meta, err
+}
@@ -0,0 +1,44 @@
+// internal/sink/sink.go
+package sink
+
+import (
+	"context"
+
+	"reddit-ingestion/pkg/models"
+)
+
+// Sink receives scraped content so it can be forwarded to downstream systems
+type Sink interface {
+	WritePosts(ctx context.Context, posts []models.Post) error
+	WriteComments(ctx context.Context, postID string, comments []models.Comment) error
+	WriteUserActivity(ctx context.Context, activity models.UserActivity) error
+	Close() error
+}
+
+// Stats are the delivery counters of a sink
+type Stats struct {
+	// Messages delivered, and messages that failed to be written or
+	// delivered, since start-up
+	Delivered uint64 `json:"delivered"`
+	Failed    uint64 `json:"failed"`
+}
+
+// StatsReporter is a sink that counts its deliveries
+type StatsReporter interface {
+	Stats() Stats
+}
+
+// NopSink discards everything written to it
+type NopSink struct{}
+
+func (NopSink) WritePosts(ctx context.Context, posts []models.Post) error { return nil }
+
+func (NopSink) WriteComments(ctx context.Context, postID string, comments []models.Comment) error {
+	return nil
+}
+
+func (NopSink) WriteUserActivity(ctx context.Context, activity models.UserActivity) error {
+	return nil
+}
+
+func (NopSink) Close() error { return nil }
@@ -0,0 +1,128 @@
+// internal/snapshot/service.go
+package snapshot
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"reddit-ingestion/pkg/models"
+	"reddit-ingestion/pkg/scraper"
+)
+
+// snapshotPostLimit is how many of the newest posts each snapshot captures
+const snapshotPostLimit = 100
+
+// DiffService detects new, removed and changed posts between subreddit snapshots
+type DiffService interface {
+	Changes(ctx context.Context, subreddit string, since time.Time) (models.SubredditChanges, error)
+}
+
+type diffService struct {
+	svc   scraper.ScraperService
+	store *Store
+}
+
+func NewDiffService(svc scraper.ScraperService, store *Store) DiffService {
+	return &diffService{
+		svc:   svc,
+		store: store,
+	}
+}
+
+// Changes takes a fresh snapshot of the subreddit and diffs it against the
+// stored snapshot taken at or before since (or the latest one if since is zero).
+// Subreddit names are case-insensitive, so they are kept lowercased.
+func (d *diffService) Changes(ctx context.Context, subreddit string, since time.Time) (models.SubredditChanges, error) {
+	subreddit = strings.ToLower(strings.TrimSpace(subreddit))
+
+	var previous Snapshot
+	var found bool
+	if since.IsZero() {
+		previous, found = d.store.Latest(subreddit)
+	} else {
+		previous, found = d.store.AtOrBefore(subreddit, since)
+	}
+
+	posts, _, err := d.svc.ScrapeSubreddit(ctx, subreddit, 0, snapshotPostLimit, scraper.ListingOptions{})
+	if err != nil {
+		return models.SubredditChanges{}, fmt.Errorf("take snapshot: %w", err)
+	}
+
+	current := Snapshot{
+		Subreddit: subreddit,
+		TakenAt:   time.Now(),
+		Posts:     posts,
+	}
+	d.store.Add(current)
+
+	if !found {
+		fmt.Printf("No previous snapshot for subreddit %s, stored baseline with %d posts\n", subreddit, len(posts))
+		return models.SubredditChanges{
+			Subreddit: subreddit,
+			To:        current.TakenAt,
+			New:       []models.Post{},
+			Removed:   []models.Post{},
+			Changed:   []models.PostChange{},
+		}, nil
+	}
+
+	return Diff(previous, current), nil
+}
+
+// Diff compares two snapshots of the same subreddit. A post only counts as
+// removed if it is missing from the current snapshot while being newer than
+// the oldest post the current snapshot still covers; older posts simply
+// paged out of the listing.
+func Diff(previous, current Snapshot) models.SubredditChanges {
+	changes := models.SubredditChanges{
+		Subreddit: current.Subreddit,
+		From:      previous.TakenAt,
+		To:        current.TakenAt,
+		New:       []models.Post{},
+		Removed:   []models.Post{},
+		Changed:   []models.PostChange{},
+	}
+
+	currentByID := make(map[string]models.Post, len(current.Posts))
+	var oldest time.Time
+	for _, post := range current.Posts {
+		currentByID[post.ID] = post
+		if oldest.IsZero() || post.CreatedAt.Before(oldest) {
+			oldest = post.CreatedAt
+		}
+	}
+
+	previousIDs := make(map[string]bool, len(previous.Posts))
+	for _, before := range previous.Posts {
+		previousIDs[before.ID] = true
+
+		after, ok := currentByID[before.ID]
+		if !ok {
+			if !oldest.IsZero() && !before.CreatedAt.Before(oldest) {
+				changes.Removed = append(changes.Removed, before)
+			}
+			continue
+		}
+
+		if after.Score != before.Score || after.NumComments != before.NumComments {
+			changes.Changed = append(changes.Changed, models.PostChange{
+				ID:                after.ID,
+				Title:             after.Title,
+				ScoreBefore:       before.Score,
+				ScoreAfter:        after.Score,
+				NumCommentsBefore: before.NumComments,
+				NumCommentsAfter:  after.NumComments,
+			})
+		}
+	}
+
+	for _, post := range current.Posts {
+		if !previousIDs[post.ID] {
+			changes.New = append(changes.New, post)
+		}
+	}
+
+	return changes
+}
@@ -0,0 +1,125 @@
+// internal/snapshot/store.go
+package snapshot
+
+import (
+	"sync"
+	"time"
+
+	"reddit-ingestion/pkg/models"
+)
+
+// Snapshot is the state of a subreddit listing at a point in time
+type Snapshot struct {
+	Subreddit string
+	TakenAt   time.Time
+	Posts     []models.Post
+}
+
+// MaxSubreddits is how many subreddits a Store keeps histories for; adding
+// another drops the history used least recently
+const MaxSubreddits = 100
+
+// Store keeps a bounded, in-memory history of snapshots per subreddit
+type Store struct {
+	mu        sync.Mutex
+	maxPerSub int
+	snapshots map[string]*history
+	// Incremented on every use of a history, to find the least recent one
+	uses uint64
+}
+
+// history is the snapshots of one subreddit, oldest first
+type history struct {
+	snapshots []Snapshot
+	used      uint64
+}
+
+func NewStore(maxPerSubreddit int) *Store {
+	if maxPerSubreddit <= 0 {
+		maxPerSubreddit = 1
+	}
+	return &Store{
+		maxPerSub: maxPerSubreddit,
+		snapshots: make(map[string]*history),
+	}
+}
+
+// Add appends a snapshot, dropping the oldest one when the history is full
+// and the least recently used history when MaxSubreddits are kept
+func (s *Store) Add(snap Snapshot) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	h, ok := s.snapshots[snap.Subreddit]
+	if !ok {
+		if len(s.snapshots) >= MaxSubreddits {
+			s.evict()
+		}
+		h = &history{}
+		s.snapshots[snap.Subreddit] = h
+	}
+	h.snapshots = append(h.snapshots, snap)
+	if len(h.snapshots) > s.maxPerSub {
+		h.snapshots = h.snapshots[len(h.snapshots)-s.maxPerSub:]
+	}
+	s.use(h)
+}
+
+// use marks h as the most recently used history; callers hold the mutex
+func (s *Store) use(h *history) {
+	s.uses++
+	h.used = s.uses
+}
+
+// evict drops the least recently used history; callers hold the mutex
+func (s *Store) evict() {
+	var oldest string
+	for subreddit, h := range s.snapshots {
+		if oldest == "" || h.used < s.snapshots[oldest].used {
+			oldest = subreddit
+		}
+	}
+	delete(s.snapshots, oldest)
+}
+
+// get returns the snapshots of subreddit, marking them used; callers hold
+// the mutex
+func (s *Store) get(subreddit string) []Snapshot {
+	h, ok := s.snapshots[subreddit]
+	if !ok {
+		return nil
+	}
+	s.use(h)
+	return h.snapshots
+}
+
+// Latest returns the most recent snapshot for a subreddit
+func (s *Store) Latest(subreddit string) (Snapshot, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	history := s.get(subreddit)
+	if len(history) == 0 {
+		return Snapshot{}, false
+	}
+	return history[len(history)-1], true
+}
+
+// AtOrBefore returns the newest snapshot taken at or before t. If every
+// snapshot is newer than t, the oldest one is returned instead.
+func (s *Store) AtOrBefore(subreddit string, t time.Time) (Snapshot, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	history := s.get(subreddit)
+	if len(history) == 0 {
+		return Snapshot{}, false
+	}
+
+	for i := len(history) - 1; i >= 0; i-- {
+		if !history[i].TakenAt.After(t) {
+			return history[i], true
+		}
+	}
+	return history[0], true
+}
@@ -0,0 +1,193 @@
+// internal/stats/registry.go
+package stats
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"reddit-ingestion/pkg/models"
+)
+
+// Registry counts what the service ingested since start-up: scrapes and their
+// failures and durations per operation, and posts and comments per
+// subreddit. It lives in memory, so the counters reset on restart. It is safe
+// for concurrent use.
+type Registry struct {
+	mutex       sync.Mutex
+	started     time.Time
+	posts       int64
+	comments    int64
+	operations  map[string]*operationCounts
+	bySubreddit map[string]*SubredditCounts
+}
+
+type operationCounts struct {
+	scrapes  int64
+	errors   int64
+	duration time.Duration
+}
+
+// SubredditCounts are the posts and comments ingested from one subreddit
+type SubredditCounts struct {
+	Subreddit string `json:"subreddit"`
+	Posts     int64  `json:"posts"`
+	Comments  int64  `json:"comments"`
+}
+
+// OperationStats are the scrapes of one operation, e.g. subreddit or post
+type OperationStats struct {
+	Operation string `json:"operation"`
+	Scrapes   int64  `json:"scrapes"`
+	Errors    int64  `json:"errors"`
+	// Errors divided by scrapes
+	ErrorRate float64 `json:"error_rate"`
+	// Mean duration of the scrapes, failed ones included
+	AvgDurationMS int64 `json:"avg_duration_ms"`
+}
+
+// Stats are the counters of a Registry at one point in time
+type Stats struct {
+	Since         time.Time         `json:"since"`
+	UptimeSeconds int64             `json:"uptime_seconds"`
+	Posts         int64             `json:"posts"`
+	Comments      int64             `json:"comments"`
+	Operations    []OperationStats  `json:"operations"`
+	Subreddits    []SubredditCounts `json:"subreddits"`
+}
+
+// NewRegistry creates a registry with every counter at zero
+func NewRegistry() *Registry {
+	return &Registry{
+		started:     time.Now(),
+		operations:  make(map[string]*operationCounts),
+		bySubreddit: make(map[string]*SubredditCounts),
+	}
+}
+
+// RecordScrape counts one scrape of operation that took d and failed when
+// err is not nil
+func (r *Registry) RecordScrape(operation string, d time.Duration, err error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	op, ok := r.operations[operation]
+	if !ok {
+		op = &operationCounts{}
+		r.operations[operation] = op
+	}
+	op.scrapes++
+	op.duration += d
+	if err != nil {
+		op.errors++
+	}
+}
+
+// RecordPosts counts posts, each under its own subreddit or, when the post
+// does not name one, under subreddit
+func (r *Registry) RecordPosts(subreddit string, posts []models.Post) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	for _, post := range posts {
+		name := post.Subreddit
+		if name == "" {
+			name = subreddit
+		}
+		r.subreddit(name).Posts++
+		r.posts++
+	}
+}
+
+// RecordUserActivity counts a user's posts and comments by subreddit
+func (r *Registry) RecordUserActivity(activity models.UserActivity) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	for _, post := range activity.Posts {
+		r.subreddit(post.Subreddit).Posts++
+		r.posts++
+	}
+	for _, comment := range activity.Comments {
+		r.subreddit(comment.Subreddit).Comments++
+		r.comments++
+	}
+}
+
+// RecordPost counts a post and the comments of its tree
+func (r *Registry) RecordPost(detail models.PostDetail) {
+	comments := int64(countComments(detail.Comments))
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	counts := r.subreddit(detail.Post.Subreddit)
+	counts.Posts++
+	counts.Comments += comments
+	r.posts++
+	r.comments += comments
+}
+
+// subreddit returns the counts of a subreddit; callers hold the mutex
+func (r *Registry) subreddit(name string) *SubredditCounts {
+	if name == "" {
+		name = "unknown"
+	}
+	counts, ok := r.bySubreddit[name]
+	if !ok {
+		counts = &SubredditCounts{Subreddit: name}
+		r.bySubreddit[name] = counts
+	}
+	return counts
+}
+
+// Stats returns the current counters. Operations are sorted by name and
+// subreddits by posts and comments ingested, most first.
+func (r *Registry) Stats() Stats {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	stats := Stats{
+		Since:         r.started.UTC(),
+		UptimeSeconds: int64(time.Since(r.started).Seconds()),
+		Posts:         r.posts,
+		Comments:      r.comments,
+		Operations:    make([]OperationStats, 0, len(r.operations)),
+		Subreddits:    make([]SubredditCounts, 0, len(r.bySubreddit)),
+	}
+	for name, op := range r.operations {
+		entry := OperationStats{Operation: name, Scrapes: op.scrapes, Errors: op.errors}
+		if op.scrapes > 0 {
+			entry.ErrorRate = float64(op.errors) / float64(op.scrapes)
+			entry.AvgDurationMS = (op.duration / time.Duration(op.scrapes)).Milliseconds()
+		}
+		stats.Operations = append(stats.Operations, entry)
+	}
+	sort.Slice(stats.Operations, func(i, j int) bool {
+		return stats.Operations[i].Operation < stats.Operations[j].Operation
+	})
+	for _, counts := range r.bySubreddit {
+		stats.Subreddits = append(stats.Subreddits, *counts)
+	}
+	sort.Slice(stats.Subreddits, func(i, j int) bool {
+		a, b := stats.Subreddits[i], stats.Subreddits[j]
+		if a.Posts+a.Comments != b.Posts+b.Comments {
+			return a.Posts+a.Comments > b.Posts+b.Comments
+		}
+		return a.Subreddit < b.Subreddit
+	})
+	return stats
+}
+
+// countComments counts the comments of a tree, leaving out "load more"
+// placeholders
+func countComments(comments []models.Comment) int {
+	n := 0
+	for _, comment := range comments {
+		if !comment.IsMore {
+			n++
+		}
+		n += countComments(comment.Replies)
+	}
+	return n
+}
@@ -0,0 +1,84 @@
+// internal/stats/service.go
+package stats
+
+import (
+	"context"
+	"time"
+
+	"reddit-ingestion/pkg/models"
+	"reddit-ingestion/pkg/scraper"
+)
+
+// countingService records every scrape and what it ingested in a Registry
+type countingService struct {
+	scraper.ScraperService
+	registry *Registry
+}
+
+// WrapService returns a ScraperService that counts its scrapes in r
+func WrapService(svc scraper.ScraperService, r *Registry) scraper.ScraperService {
+	return &countingService{
+		ScraperService: svc,
+		registry:       r,
+	}
+}
+
+func (w *countingService) ScrapeSubreddit(ctx context.Context, subreddit string, sinceTimestamp int64, limit int, opts scraper.ListingOptions) ([]models.Post, models.ListingMeta, error) {
+	start := time.Now()
+	posts, meta, err := w.ScraperService.ScrapeSubreddit(ctx, subreddit, sinceTimestamp, limit, opts)
+	w.registry.RecordScrape("subreddit", time.Since(start), err)
+	if err == nil {
+		w.registry.RecordPosts(subreddit, posts)
+	}
+	return posts, meta, err
+}
+
+func (w *countingService) ScrapeUserActivity(ctx context.Context, username string, sinceTimestamp int64, postLimit, commentLimit int) (models.UserActivity, error) {
+	start := time.Now()
+	activity, err := w.ScraperService.ScrapeUserActivity(ctx, username, sinceTimestamp, postLimit, commentLimit)
+	w.registry.RecordScrape("user", time.Since(start), err)
+	if err == nil {
+		w.registry.RecordUserActivity(activity)
+	}
+	return activity, err
+}
+
+func (w *countingService) ScrapeUserOverview(ctx context.Context, username string, sinceTimestamp int64, limit int) (models.UserOverview, error) {
+	start := time.Now()
+	overview, err := w.ScraperService.ScrapeUserOverview(ctx, username, sinceTimestamp, limit)
+	w.registry.RecordScrape("user_overview", time.Since(start), err)
+	if err == nil {
+		w.registry.RecordUserActivity(overview.Activity())
+	}
+	return overview, err
+}
+
+func (w *countingService) ScrapePost(ctx context.Context, postID string) (models.PostDetail, error) {
+	start := time.Now()
+	detail, err := w.ScraperService.ScrapePost(ctx, postID)
+	w.registry.RecordScrape("post", time.Since(start), err)
+	if err == nil {
+		w.registry.RecordPost(detail)
+	}
+	return detail, err
+}
+
+func (w *countingService) Search(ctx context.Context, searchParams map[string]string, sinceTimestamp int64, limit int, opts scraper.ListingOptions) ([]models.Post, models.ListingMeta, error) {
+	start := time.Now()
+	posts, meta, err := w.ScraperService.Search(ctx, searchParams, sinceTimestamp, limit, opts)
+	w.registry.RecordScrape("search", time.Since(start), err)
+	if err == nil {
+		w.registry.RecordPosts("", posts)
+	}
+	return posts, meta, err
+}
+
+func (w *countingService) ScrapeFrontpage(ctx context.Context, feed string, params map[string]string, limit int, opts scraper.ListingOptions) ([]models.Post, models.ListingMeta, error) {
+	start := time.Now()
+	posts, meta, err := w.ScraperService.ScrapeFrontpage(ctx, feed, params, limit, opts)
+	w.registry.RecordScrape("frontpage", time.Since(start), err)
+	if err == nil {
+		w.registry.RecordPosts("", posts)
+	}
+	return posts, meta, err
+}
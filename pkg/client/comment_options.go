@@ -0,0 +1,103 @@
+// pkg/client/comment_options.go
+package client
+
+import (
+	"context"
+	"net/url"
+	"strconv"
+)
+
+// DefaultCommentSort is the order comment trees are fetched in unless a
+// request asks for another: newest first, as ingestion wants
+const DefaultCommentSort = "new"
+
+// CommentSorts are the orders Reddit can sort a comment tree in
+var CommentSorts = []string{"best", "top", "new", "controversial", "old", "qa"}
+
+type commentSortKey struct{}
+
+// WithCommentSort makes post and morechildren fetches with the returned
+// context ask Reddit for comments in sort, one of CommentSorts. An empty sort
+// or DefaultCommentSort returns ctx as it is, so caches key every request for
+// the default order alike.
+func WithCommentSort(ctx context.Context, sort string) context.Context {
+	if sort == "" || sort == DefaultCommentSort {
+		return ctx
+	}
+	return context.WithValue(ctx, commentSortKey{}, sort)
+}
+
+// CommentSortFromContext returns the sort carried by ctx, or "" for
+// DefaultCommentSort
+func CommentSortFromContext(ctx context.Context) string {
+	sort, _ := ctx.Value(commentSortKey{}).(string)
+	return sort
+}
+
+// commentSortParam is the sort parameter sent to Reddit for the sort of ctx.
+// Reddit's API calls "best" confidence.
+func commentSortParam(ctx context.Context) string {
+	switch sort := CommentSortFromContext(ctx); sort {
+	case "":
+		return DefaultCommentSort
+	case "best":
+		return "confidence"
+	default:
+		return sort
+	}
+}
+
+// CommentTree bounds the comment tree of a post page: how many levels of
+// replies and how many comments Reddit returns. Zero fields leave Reddit's
+// defaults.
+type CommentTree struct {
+	Depth int
+	Limit int
+}
+
+// IsZero reports whether t leaves the whole tree to be fetched
+func (t CommentTree) IsZero() bool {
+	return t.Depth == 0 && t.Limit == 0
+}
+
+type commentTreeKey struct{}
+
+// WithCommentTree makes post fetches with the returned context ask Reddit
+// for the comment tree bounded by tree
+func WithCommentTree(ctx context.Context, tree CommentTree) context.Context {
+	if tree.IsZero() {
+		return ctx
+	}
+	return context.WithValue(ctx, commentTreeKey{}, tree)
+}
+
+// CommentTreeFromContext returns the bounds carried by ctx, zero when it
+// carries none
+func CommentTreeFromContext(ctx context.Context) CommentTree {
+	tree, _ := ctx.Value(commentTreeKey{}).(CommentTree)
+	return tree
+}
+
+// WithPostURLOptions rewrites a post URL from GetPostURL to ask for the
+// comment sort and tree bounds of ctx; the URL is unchanged when ctx carries
+// neither
+func WithPostURLOptions(ctx context.Context, postURL string) string {
+	tree := CommentTreeFromContext(ctx)
+	if CommentSortFromContext(ctx) == "" && tree.IsZero() {
+		return postURL
+	}
+	u, err := url.Parse(postURL)
+	if err != nil {
+		return postURL
+	}
+	q := u.Query()
+	q.Set("sort", commentSortParam(ctx))
+	if tree.Depth > 0 {
+		q.Set("depth", strconv.Itoa(tree.Depth))
+	}
+	if tree.Limit > 0 {
+		q.Set("limit", strconv.Itoa(tree.Limit))
+	}
+	u.RawQuery = q.Encode()
+	return u.String()
+}
@@ -0,0 +1,225 @@
+// pkg/client/failover.go
+package client
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// DefaultFailoverThreshold and DefaultFailoverProbeInterval are used when
+// Options name no failover policy
+const (
+	DefaultFailoverThreshold     = 5
+	DefaultFailoverProbeInterval = time.Minute
+)
+
+// Statuses of HostHealth
+const (
+	HostStatusOK       = "ok"
+	HostStatusDegraded = "degraded"
+)
+
+// maxDegradationEvents is how many degradation events HostHealth keeps
+const maxDegradationEvents = 20
+
+// DegradationEvent is a spell during which the requests for the primary base
+// URL went to a fallback
+type DegradationEvent struct {
+	Primary  string `json:"primary"`
+	Fallback string `json:"fallback"`
+	// Error of the failure that tipped the primary over
+	Reason string    `json:"reason"`
+	Start  time.Time `json:"start"`
+	// When a probe of the primary succeeded; empty while the spell lasts
+	End *time.Time `json:"end,omitempty"`
+}
+
+// HostHealth is the state of the failover policy of a client
+type HostHealth struct {
+	// HostStatusOK, or HostStatusDegraded while requests for the primary
+	// go to a fallback
+	Status string `json:"status"`
+	// BaseURL, and the base URL its requests go to now
+	Primary string `json:"primary"`
+	Active  string `json:"active"`
+	// Failures in a row of the primary; Threshold of them fail it over
+	ConsecutiveFailures int `json:"consecutive_failures"`
+	Threshold           int `json:"threshold"`
+	// When the next request for the primary probes it, while degraded
+	NextProbe *time.Time `json:"next_probe,omitempty"`
+	// Counters since start-up: spells begun and ended, probes sent and
+	// failed, and requests for the primary sent straight to a fallback
+	Failovers        int64 `json:"failovers"`
+	Recoveries       int64 `json:"recoveries"`
+	Probes           int64 `json:"probes"`
+	FailedProbes     int64 `json:"failed_probes"`
+	FallbackRequests int64 `json:"fallback_requests"`
+	// Latest spells, oldest first
+	Events []DegradationEvent `json:"events,omitempty"`
+}
+
+// failover moves the requests for the primary base URL to the first fallback
+// once the primary has failed threshold requests in a row, in a way another
+// host may not, and moves them back once a probe succeeds. While degraded,
+// the first request for the primary after each probe interval is the probe.
+type failover struct {
+	mutex         sync.Mutex
+	primary       string
+	fallback      string
+	threshold     int
+	probeInterval time.Duration
+	now           func() time.Time
+
+	failures  int
+	degraded  bool
+	probing   bool
+	nextProbe time.Time
+
+	failovers        int64
+	recoveries       int64
+	probes           int64
+	failedProbes     int64
+	fallbackRequests int64
+	events           []DegradationEvent
+}
+
+// newFailover creates the policy of primary; a threshold of 0 or an interval
+// of 0 takes the default, a negative threshold or no fallbacks disable it
+func newFailover(primary string, fallbacks []string, threshold int, probeInterval time.Duration) *failover {
+	if threshold == 0 {
+		threshold = DefaultFailoverThreshold
+	}
+	if probeInterval <= 0 {
+		probeInterval = DefaultFailoverProbeInterval
+	}
+	f := &failover{primary: primary, threshold: threshold, probeInterval: probeInterval, now: time.Now}
+	for _, fallback := range fallbacks {
+		if fallback != primary {
+			f.fallback = fallback
+			break
+		}
+	}
+	return f
+}
+
+func (f *failover) enabled() bool {
+	return f.threshold > 0 && f.fallback != ""
+}
+
+// route is the base URL a request for the primary goes to, and whether it is
+// the probe of a degraded primary
+func (f *failover) route() (base string, probe bool) {
+	if !f.enabled() {
+		return f.primary, false
+	}
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	if !f.degraded {
+		return f.primary, false
+	}
+	if !f.probing && !f.now().Before(f.nextProbe) {
+		f.probing = true
+		f.probes++
+		f.nextProbe = f.now().Add(f.probeInterval)
+		return f.primary, true
+	}
+	f.fallbackRequests++
+	return f.fallback, false
+}
+
+// record counts the outcome of a request the primary answered; err is nil or
+// does not fail over when the primary served it
+func (f *failover) record(ctx context.Context, err error, probe bool) {
+	if !f.enabled() {
+		return
+	}
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	if probe {
+		f.probing = false
+	}
+	if ctx.Err() != nil {
+		return
+	}
+	if err == nil || !failsOver(ctx, err) {
+		f.failures = 0
+		if probe {
+			f.recover()
+		}
+		return
+	}
+
+	f.failures++
+	switch {
+	case probe:
+		f.failedProbes++
+		fmt.Printf("Probe of Reddit host %s failed (%v), next probe in %v\n", f.primary, err, f.probeInterval)
+	case !f.degraded && f.failures >= f.threshold:
+		f.degrade(err)
+	}
+}
+
+func (f *failover) degrade(err error) {
+	now := f.now()
+	f.degraded = true
+	f.failovers++
+	f.nextProbe = now.Add(f.probeInterval)
+	f.events = append(f.events, DegradationEvent{
+		Primary:  f.primary,
+		Fallback: f.fallback,
+		Reason:   err.Error(),
+		Start:    now,
+	})
+	if len(f.events) > maxDegradationEvents {
+		f.events = f.events[len(f.events)-maxDegradationEvents:]
+	}
+	fmt.Printf("Reddit host %s failed %d requests in a row (%v), sending its requests to %s\n",
+		f.primary, f.failures, err, f.fallback)
+}
+
+func (f *failover) recover() {
+	if !f.degraded {
+		return
+	}
+	now := f.now()
+	f.degraded = false
+	f.recoveries++
+	f.events[len(f.events)-1].End = &now
+	fmt.Printf("Probe of Reddit host %s succeeded, sending its requests back from %s\n", f.primary, f.fallback)
+}
+
+func (f *failover) health() HostHealth {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	health := HostHealth{
+		Status:              HostStatusOK,
+		Primary:             f.primary,
+		Active:              f.primary,
+		ConsecutiveFailures: f.failures,
+		Threshold:           f.threshold,
+		Failovers:           f.failovers,
+		Recoveries:          f.recoveries,
+		Probes:              f.probes,
+		FailedProbes:        f.failedProbes,
+		FallbackRequests:    f.fallbackRequests,
+	}
+	if f.degraded {
+		nextProbe := f.nextProbe
+		health.Status = HostStatusDegraded
+		health.Active = f.fallback
+		health.NextProbe = &nextProbe
+	}
+	for _, event := range f.events {
+		if event.End != nil {
+			end := *event.End
+			event.End = &end
+		}
+		health.Events = append(health.Events, event)
+	}
+	return health
+}
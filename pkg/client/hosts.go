@@ -0,0 +1,147 @@
+// pkg/client/hosts.go
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"reddit-ingestion/pkg/utils"
+)
+
+// Endpoints a client can send to a Reddit host of their own, see
+// Options.EndpointURLs
+const (
+	// Subreddit, front page and feed listings
+	EndpointListing = "listing"
+	// Posts with their comments, and their other discussions
+	EndpointPost = "post"
+	// User profiles and activity
+	EndpointUser   = "user"
+	EndpointSearch = "search"
+	// "load more" comments
+	EndpointMoreChildren = "morechildren"
+)
+
+// Endpoints are the names of Options.EndpointURLs
+var Endpoints = []string{EndpointListing, EndpointPost, EndpointUser, EndpointSearch, EndpointMoreChildren}
+
+// hosts picks the base URL of each endpoint and the base URLs requests fall
+// back to when theirs fails
+type hosts struct {
+	// BaseURL, which GET /raw reads too
+	base      string
+	endpoints map[string]string
+	fallbacks []string
+}
+
+func newHosts(baseURL, apiURL string, endpointURLs map[string]string, fallbackURLs []string) *hosts {
+	h := &hosts{base: baseURL, endpoints: make(map[string]string, len(Endpoints))}
+	for _, endpoint := range Endpoints {
+		h.endpoints[endpoint] = baseURL
+	}
+	h.endpoints[EndpointMoreChildren] = apiURL
+	for endpoint, u := range endpointURLs {
+		if u != "" {
+			h.endpoints[endpoint] = urlOrDefault(u, "")
+		}
+	}
+	for _, u := range fallbackURLs {
+		if u != "" {
+			h.fallbacks = append(h.fallbacks, urlOrDefault(u, ""))
+		}
+	}
+	return h
+}
+
+// url is the base URL of endpoint
+func (h *hosts) url(endpoint string) string {
+	return h.endpoints[endpoint]
+}
+
+// all are every base URL requests may go to
+func (h *hosts) all() []string {
+	urls := append([]string{h.base}, h.fallbacks...)
+	for _, u := range h.endpoints {
+		urls = append(urls, u)
+	}
+	return urls
+}
+
+// baseOf is the longest known base URL u is on; empty when none
+func (h *hosts) baseOf(u string) string {
+	base := ""
+	for _, candidate := range h.all() {
+		if strings.HasPrefix(u, candidate+"/") && len(candidate) > len(base) {
+			base = candidate
+		}
+	}
+	return base
+}
+
+// alternatives are u moved onto each fallback base URL in turn, leaving out
+// the base it is on; none when u is on no known base
+func (h *hosts) alternatives(u string) []string {
+	base := h.baseOf(u)
+	if base == "" {
+		return nil
+	}
+	var alternatives []string
+	for _, fallback := range h.fallbacks {
+		if fallback != base {
+			alternatives = append(alternatives, fallback+strings.TrimPrefix(u, base))
+		}
+	}
+	return alternatives
+}
+
+// failsOver reports whether a request that failed with err may succeed on
+// another Reddit host: the host failed, blocked or rate limited it. Answers
+// about the content, such as 404, and limits of the client itself do not
+// change with the host.
+func failsOver(ctx context.Context, err error) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+	switch status := utils.UpstreamStatus(err); {
+	case status == http.StatusForbidden || status == http.StatusTooManyRequests || status >= 500:
+		return true
+	case status != 0:
+		return false
+	}
+	return !errors.Is(err, utils.ErrBandwidthExhausted) &&
+		!errors.Is(err, utils.ErrUnknownProxyPool) &&
+		!errors.Is(err, utils.ErrHostNotAllowed) &&
+		!errors.Is(err, utils.ErrResponseTooLarge)
+}
+
+// withFailover calls fetch with u and, while it fails in a way another host
+// may not, with u on each fallback host in turn. Requests for the primary base
+// URL go to a fallback first while the failover policy has it degraded.
+func (r *RedditClient) withFailover(ctx context.Context, u string, fetch func(u string) error) error {
+	onPrimary := r.hosts.baseOf(u) == r.hosts.base
+	probe := false
+	if onPrimary {
+		var base string
+		base, probe = r.failover.route()
+		if base != r.hosts.base {
+			u = base + strings.TrimPrefix(u, r.hosts.base)
+			onPrimary = false
+		}
+	}
+	err := fetch(u)
+	if onPrimary {
+		r.failover.record(ctx, err, probe)
+	}
+	for _, alternative := range r.hosts.alternatives(u) {
+		if err == nil || !failsOver(ctx, err) {
+			break
+		}
+		fmt.Printf("Request to %s failed (%v), falling back to %s\n", u, err, alternative)
+		u = alternative
+		err = fetch(u)
+	}
+	return err
+}
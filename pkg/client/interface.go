@@ -1,9 +1,10 @@
-// internal/client/interface.go
+// pkg/client/interface.go
 package client
 
 import (
 	"context"
 	"encoding/json"
+	"io"
 )
 
 type RedditClientInterface interface {
@@ -13,6 +14,15 @@ type RedditClientInterface interface {
 	GetUserAboutURL(username string) string
 	GetUserPostsURL(username string, after string) string
 	GetUserCommentsURL(username string, after string) string
+	GetUserOverviewURL(username string, after string) string
 	GetPostURL(postID string) string
+	GetDuplicatesURL(postID string) string
 	GetSearchURL(searchParams map[string]string) string
-}
\ No newline at end of file
+	GetFrontpageURL(feed string, limit int, after string, params map[string]string) string
+}
+
+// StreamingClient is implemented by clients that can return a page's body
+// unbuffered, so listings can be decoded while they download
+type StreamingClient interface {
+	StreamJSON(ctx context.Context, url string) (io.ReadCloser, error)
+}
@@ -0,0 +1,187 @@
+// pkg/client/options.go
+package client
+
+import (
+	"net/http"
+	"time"
+
+	"reddit-ingestion/pkg/utils"
+)
+
+// DefaultBaseURL and DefaultAPIURL are the Reddit hosts a client reads when
+// its Options name none
+const (
+	DefaultBaseURL = "https://old.reddit.com"
+	DefaultAPIURL  = "https://api.reddit.com"
+)
+
+// RedditHosts are the hosts, subdomains included, every client may fetch
+var RedditHosts = []string{"reddit.com"}
+
+// DefaultUserAgent and DefaultMaxRetries are used when no user agent or retry
+// count is configured
+const (
+	DefaultUserAgent  = "Mozilla/5.0"
+	DefaultMaxRetries = 3
+)
+
+// Options configure a RedditClient. The zero value of a field keeps its
+// default: no cap, no throttling, no cookies, Reddit's own hosts.
+type Options struct {
+	// User agent sent with every request; required
+	UserAgent string
+	// Send a user agent of the browser profile each request is
+	// fingerprinted as instead of UserAgent
+	RandomUserAgents bool
+	// Proxies requests rotate through; at least one, or a session gateway,
+	// is required unless Direct or HTTPClient is set
+	ProxyURLs []string
+	// Residential gateways minting sticky sessions, with {session} where the
+	// session id goes, how many sessions each keeps in rotation and how
+	// often they are replaced by fresh ones
+	SessionGateways    []string
+	SessionsPerGateway int
+	SessionTTL         time.Duration
+	// Reach Reddit without proxies, e.g. a fake Reddit on localhost
+	Direct bool
+	// Send requests with this client instead of the proxied, fingerprinting
+	// transport, so the proxy settings, bandwidth caps, throttle and cookies
+	// do not apply
+	HTTPClient *http.Client
+	// Attempts per request, the first included
+	MaxRetries int
+
+	// Base URL of the JSON endpoints and of Reddit's API host, which serves
+	// morechildren
+	BaseURL string
+	APIURL  string
+	// Base URLs of the endpoints that do not use BaseURL, or APIURL for
+	// EndpointMoreChildren, by endpoint name, e.g. EndpointSearch on
+	// https://www.reddit.com
+	EndpointURLs map[string]string
+	// Base URLs tried in turn when a request fails in a way another host
+	// may not: the host fails, blocks or rate limits it
+	FallbackURLs []string
+	// Failures in a row, in a way another host may not, after which the
+	// requests for BaseURL go to the first of FallbackURLs until a probe of
+	// BaseURL, sent every FailoverProbeInterval, succeeds; 0 for
+	// DefaultFailoverThreshold and DefaultFailoverProbeInterval, a negative
+	// threshold to keep trying BaseURL first
+	FailoverThreshold     int
+	FailoverProbeInterval time.Duration
+	// Hosts besides RedditHosts and those of the base URLs above the client
+	// may fetch, subdomains included; requests and redirects anywhere else
+	// fail with utils.ErrHostNotAllowed
+	ExtraHosts []string
+
+	// Daily and monthly traffic caps per proxy in bytes, the monthly one
+	// overridden for the proxies carrying a label
+	DailyBandwidthCap    int64
+	MonthlyBandwidthCap  int64
+	MonthlyBandwidthCaps map[string]int64
+	// JSON file keeping each proxy's monthly usage across restarts, in
+	// memory only when empty
+	UsageFile string
+
+	// Largest decoded response accepted in bytes
+	MaxResponseBytes int64
+	// Window 429/403 rates are measured over and the rate that slows
+	// requests down
+	ThrottleWindow    time.Duration
+	ThrottleBlockRate float64
+	// Waits between the attempts of a request, utils.DefaultRetryPolicy
+	// when zero
+	RetryPolicy utils.RetryPolicy
+	// How long the cookie jar of each proxy and browser profile is kept
+	CookieTTL time.Duration
+	// Spaces out every request of the client, retries included
+	RateLimiter *utils.RequestLimiter
+}
+
+// Option sets one of the Options of NewRedditClientWithOptions
+type Option func(*Options)
+
+// NewRedditClientWithOptions creates a client from DefaultUserAgent and
+// DefaultMaxRetries changed by opts, so embedders and tests set only what they
+// need. Without WithProxies or WithHTTPClient it reaches Reddit directly.
+func NewRedditClientWithOptions(opts ...Option) (*RedditClient, error) {
+	options := Options{
+		UserAgent:  DefaultUserAgent,
+		MaxRetries: DefaultMaxRetries,
+	}
+	for _, opt := range opts {
+		opt(&options)
+	}
+	if len(options.ProxyURLs) == 0 && len(options.SessionGateways) == 0 {
+		options.Direct = true
+	}
+	return NewRedditClient(options)
+}
+
+// WithProxies rotates requests through proxyURLs
+func WithProxies(proxyURLs ...string) Option {
+	return func(o *Options) { o.ProxyURLs = append(o.ProxyURLs, proxyURLs...) }
+}
+
+// WithHTTPClient sends requests with httpClient, e.g. one whose transport
+// talks to a fake Reddit
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(o *Options) { o.HTTPClient = httpClient }
+}
+
+// WithBaseURL reads the JSON endpoints from baseURL instead of DefaultBaseURL
+func WithBaseURL(baseURL string) Option {
+	return func(o *Options) { o.BaseURL = baseURL }
+}
+
+// WithAPIURL sends morechildren to apiURL instead of DefaultAPIURL
+func WithAPIURL(apiURL string) Option {
+	return func(o *Options) { o.APIURL = apiURL }
+}
+
+// WithEndpointURL sends endpoint, one of Endpoints, to baseURL
+func WithEndpointURL(endpoint, baseURL string) Option {
+	return func(o *Options) {
+		if o.EndpointURLs == nil {
+			o.EndpointURLs = make(map[string]string)
+		}
+		o.EndpointURLs[endpoint] = baseURL
+	}
+}
+
+// WithFallbackURLs tries baseURLs in turn when a request's host fails
+func WithFallbackURLs(baseURLs ...string) Option {
+	return func(o *Options) { o.FallbackURLs = append(o.FallbackURLs, baseURLs...) }
+}
+
+// WithFailover sends the requests for the base URL to the first fallback
+// after threshold failures in a row, probing the base URL every probeInterval
+// to send them back
+func WithFailover(threshold int, probeInterval time.Duration) Option {
+	return func(o *Options) {
+		o.FailoverThreshold = threshold
+		o.FailoverProbeInterval = probeInterval
+	}
+}
+
+// WithExtraHosts lets the client fetch hosts besides Reddit's and those of
+// its base URLs
+func WithExtraHosts(hosts ...string) Option {
+	return func(o *Options) { o.ExtraHosts = append(o.ExtraHosts, hosts...) }
+}
+
+// WithRateLimiter makes every request wait for limiter
+func WithRateLimiter(limiter *utils.RequestLimiter) Option {
+	return func(o *Options) { o.RateLimiter = limiter }
+}
+
+// WithUserAgent sends userAgent instead of DefaultUserAgent
+func WithUserAgent(userAgent string) Option {
+	return func(o *Options) { o.UserAgent = userAgent }
+}
+
+// WithMaxRetries makes up to maxRetries attempts per request instead of
+// DefaultMaxRetries
+func WithMaxRetries(maxRetries int) Option {
+	return func(o *Options) { o.MaxRetries = maxRetries }
+}
@@ -0,0 +1,480 @@
+// pkg/client/reddit_client.go
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+
+	"reddit-ingestion/pkg/utils"
+)
+
+type RedditClient struct {
+	client     *utils.RetryableClient
+	mutex      sync.RWMutex
+	userAgent  string
+	baseURL    string
+	// Base URLs of the endpoints and fallbacks
+	hosts *hosts
+	// Moves the requests for BaseURL to a fallback while it keeps failing
+	failover *failover
+}
+
+// NewRedditClient creates a client with opts; it reads no environment, so
+// programs embedding the scraper configure it here
+func NewRedditClient(opts Options) (*RedditClient, error) {
+	if opts.UserAgent == "" {
+		return nil, fmt.Errorf("a user agent is required")
+	}
+	
+	if len(opts.ProxyURLs) == 0 && len(opts.SessionGateways) == 0 && !opts.Direct && opts.HTTPClient == nil {
+		return nil, fmt.Errorf("at least one proxy URL must be provided")
+	}
+	
+	fmt.Printf("Initializing Reddit client with %d proxies\n", len(opts.ProxyURLs))
+	
+	for i, proxy := range opts.ProxyURLs {
+		maskedProxy := maskProxyURL(proxy)
+		fmt.Printf("Proxy #%d: %s\n", i+1, maskedProxy)
+	}
+
+	client, err := newRetryableClient(opts)
+	
+	if err != nil {
+		return nil, fmt.Errorf("failed to create HTTP client: %w", err)
+	}
+	client.SetDailyBandwidthCap(opts.DailyBandwidthCap)
+	client.SetMonthlyBandwidthCaps(opts.MonthlyBandwidthCap, opts.MonthlyBandwidthCaps)
+	client.SetMaxResponseBytes(opts.MaxResponseBytes)
+	client.SetThrottle(opts.ThrottleWindow, opts.ThrottleBlockRate)
+	client.SetRetryPolicy(policyOrDefault(opts.RetryPolicy))
+	client.SetRandomUserAgents(opts.RandomUserAgents)
+	client.SetCookieTTL(opts.CookieTTL)
+	client.SetRequestLimiter(opts.RateLimiter)
+	if err := client.UseUsageFile(opts.UsageFile); err != nil {
+		return nil, err
+	}
+	
+	r := &RedditClient{
+		client:    client,
+		userAgent: opts.UserAgent,
+		baseURL:   urlOrDefault(opts.BaseURL, DefaultBaseURL),
+	}
+	r.hosts = newHosts(r.baseURL, urlOrDefault(opts.APIURL, DefaultAPIURL), opts.EndpointURLs, opts.FallbackURLs)
+	r.failover = newFailover(r.baseURL, r.hosts.fallbacks, opts.FailoverThreshold, opts.FailoverProbeInterval)
+	client.SetHostAllowlist(r.allowlist(opts.ExtraHosts))
+	return r, nil
+}
+
+// Reload applies the hot-reloadable parts of opts (proxies, retries, user
+// agents, caps, rate limiter, extra hosts) to the live client. The base URLs, sessions and
+// HTTP client need a restart.
+func (r *RedditClient) Reload(opts Options) error {
+	if opts.UserAgent == "" {
+		return fmt.Errorf("a user agent is required")
+	}
+
+	proxyURLs := opts.ProxyURLs
+	if opts.Direct || opts.HTTPClient != nil {
+		proxyURLs = nil
+	}
+	if err := r.client.Reconfigure(proxyURLs, opts.MaxRetries, opts.UserAgent); err != nil {
+		return fmt.Errorf("failed to reconfigure HTTP client: %w", err)
+	}
+	r.client.SetDailyBandwidthCap(opts.DailyBandwidthCap)
+	r.client.SetMonthlyBandwidthCaps(opts.MonthlyBandwidthCap, opts.MonthlyBandwidthCaps)
+	r.client.SetMaxResponseBytes(opts.MaxResponseBytes)
+	r.client.SetThrottle(opts.ThrottleWindow, opts.ThrottleBlockRate)
+	r.client.SetRetryPolicy(policyOrDefault(opts.RetryPolicy))
+	r.client.SetRandomUserAgents(opts.RandomUserAgents)
+	r.client.SetCookieTTL(opts.CookieTTL)
+	r.client.SetRequestLimiter(opts.RateLimiter)
+	r.client.SetHostAllowlist(r.allowlist(opts.ExtraHosts))
+
+	r.mutex.Lock()
+	r.userAgent = opts.UserAgent
+	r.mutex.Unlock()
+
+	return nil
+}
+
+// Close stops minting proxy sessions
+func (r *RedditClient) Close() {
+	r.client.Close()
+}
+
+// HostHealth reports whether the requests for the primary base URL go to a
+// fallback, and the degradation events of the failover policy
+func (r *RedditClient) HostHealth() HostHealth {
+	return r.failover.health()
+}
+
+// BandwidthUsage reports today's traffic through each proxy against its daily cap
+func (r *RedditClient) BandwidthUsage() []utils.ProxyBandwidth {
+	return r.client.BandwidthUsage()
+}
+
+// ProxyUsage reports this month's traffic and requests through each proxy
+// against its monthly cap
+func (r *RedditClient) ProxyUsage() []utils.ProxyUsage {
+	return r.client.ProxyUsage()
+}
+
+// SaveUsage writes the proxies' monthly usage to PROXY_USAGE_FILE, if set
+func (r *RedditClient) SaveUsage() error {
+	return r.client.SaveUsage()
+}
+
+// ProxyRanking reports each proxy's recent latency and success rate, best first
+func (r *RedditClient) ProxyRanking() []utils.ProxyRank {
+	return r.client.ProxyRanking()
+}
+
+// UpstreamStats reports the diagnostic headers of Reddit's responses
+func (r *RedditClient) UpstreamStats() utils.UpstreamStats {
+	return r.client.UpstreamStats()
+}
+
+// Throttle is the adaptive throttle fed with the status of Reddit's responses
+func (r *RedditClient) Throttle() *utils.Throttle {
+	return r.client.Throttle()
+}
+
+// RetryStats reports the retries of requests to Reddit since start-up
+func (r *RedditClient) RetryStats() utils.RetryStats {
+	return r.client.RetryStats()
+}
+
+// SoftBlockStats reports the block pages Reddit served in place of JSON since
+// start-up
+func (r *RedditClient) SoftBlockStats() utils.SoftBlockStats {
+	return r.client.SoftBlockStats()
+}
+
+// ThrottleStats reports the current throttle level and block rates
+func (r *RedditClient) ThrottleStats() utils.ThrottleStats {
+	return r.client.Throttle().Stats()
+}
+
+// HasProxyPool reports whether any configured proxy carries the pool label
+func (r *RedditClient) HasProxyPool(pool string) bool {
+	return r.client.HasProxyPool(pool)
+}
+
+// newRetryableClient creates the HTTP client, with the sessions of the
+// gateways in rotation next to the proxies when set. A direct client reaches
+// Reddit without proxies, and a client of the caller's is used as it is.
+func newRetryableClient(opts Options) (*utils.RetryableClient, error) {
+	if opts.HTTPClient != nil {
+		return utils.NewRetryableClientWithHTTPClient(opts.HTTPClient, opts.MaxRetries, opts.UserAgent)
+	}
+	if opts.Direct {
+		return utils.NewDirectClient(opts.MaxRetries, opts.UserAgent)
+	}
+	if len(opts.SessionGateways) == 0 {
+		return utils.NewRetryableClient(opts.ProxyURLs, opts.MaxRetries, opts.UserAgent)
+	}
+	provider, err := utils.NewSessionProvider(opts.SessionGateways, opts.SessionsPerGateway)
+	if err != nil {
+		return nil, err
+	}
+	return utils.NewRetryableClientWithProvider(opts.ProxyURLs, provider, opts.SessionTTL, opts.MaxRetries, opts.UserAgent)
+}
+
+// allowlist allows Reddit's hosts, those of the client's base URLs and extra
+func (r *RedditClient) allowlist(extra []string) *utils.HostAllowlist {
+	hosts := append(r.hosts.all(), RedditHosts...)
+	return utils.NewHostAllowlist(append(hosts, extra...)...)
+}
+
+// urlOrDefault is u without a trailing slash, fallback when empty
+func urlOrDefault(u, fallback string) string {
+	if u == "" {
+		return fallback
+	}
+	return strings.TrimSuffix(u, "/")
+}
+
+// policyOrDefault is policy, utils.DefaultRetryPolicy when it is the zero
+// value
+func policyOrDefault(policy utils.RetryPolicy) utils.RetryPolicy {
+	if policy == (utils.RetryPolicy{}) {
+		return utils.DefaultRetryPolicy()
+	}
+	return policy
+}
+
+func (r *RedditClient) currentUserAgent() string {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	return r.userAgent
+}
+
+func maskProxyURL(proxyURL string) string {
+	if !strings.Contains(proxyURL, "@") {
+		return proxyURL
+	}
+	
+	parsedURL, err := url.Parse(proxyURL)
+	if err != nil {
+		parts := strings.Split(proxyURL, "@")
+		if len(parts) > 1 {
+			auth := strings.Split(parts[0], "://")
+			protocol := ""
+			if len(auth) > 1 {
+				protocol = auth[0] + "://"
+				auth[0] = auth[1]
+			}
+			
+			userPass := strings.Split(auth[0], ":")
+			if len(userPass) > 1 {
+				return protocol + userPass[0] + ":****@" + parts[1]
+			}
+		}
+		return "[masked]"
+	}
+	
+	if parsedURL.User != nil {
+		username := parsedURL.User.Username()
+		return strings.Replace(proxyURL, parsedURL.User.String(), username+":****", 1)
+	}
+	
+	return proxyURL
+}
+
+func (r *RedditClient) FetchJSON(ctx context.Context, url string) (json.RawMessage, error) {
+	var bodyBytes []byte
+	err := r.withFailover(ctx, url, func(url string) error {
+		req, err := http.NewRequestWithContext(utils.WithJSONExpected(ctx), "GET", url, nil)
+		if err != nil {
+			return fmt.Errorf("creating request: %w", err)
+		}
+		_, bodyBytes, err = r.client.Do(req)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("fetchJSON request: %w", err)
+	}
+	
+	return bodyBytes, nil
+}
+
+// FetchRaw fetches pathAndQuery from REDDIT_BASE_URL and returns Reddit's
+// response and decompressed body as they are, error statuses included
+func (r *RedditClient) FetchRaw(ctx context.Context, pathAndQuery string) (*http.Response, []byte, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", r.baseURL+pathAndQuery, nil)
+	if err != nil {
+		return nil, nil, fmt.Errorf("creating request: %w", err)
+	}
+
+	resp, bodyBytes, err := r.client.Do(req)
+	// Reddit's error responses are passed on like the others
+	if err != nil && utils.UpstreamStatus(err) == 0 {
+		return nil, nil, fmt.Errorf("fetchRaw request: %w", err)
+	}
+
+	return resp, bodyBytes, nil
+}
+
+// StreamJSON fetches url and returns its body unread so it can be decoded while
+// it downloads. The caller must close it.
+func (r *RedditClient) StreamJSON(ctx context.Context, url string) (io.ReadCloser, error) {
+	var resp *http.Response
+	err := r.withFailover(ctx, url, func(url string) error {
+		req, err := http.NewRequestWithContext(utils.WithJSONExpected(ctx), "GET", url, nil)
+		if err != nil {
+			return fmt.Errorf("creating request: %w", err)
+		}
+		resp, err = r.client.DoStream(req)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("streamJSON request: %w", err)
+	}
+
+	return resp.Body, nil
+}
+
+func (r *RedditClient) GetSubredditURL(subreddit string, limit int, after string) string {
+	baseURL := fmt.Sprintf("%s/r/%s/new.json?raw_json=1", r.hosts.url(EndpointListing), subreddit)
+	
+	params := url.Values{}
+	if limit > 0 {
+		params.Set("limit", fmt.Sprintf("%d", limit))
+	}
+	if after != "" {
+		params.Set("after", after)
+	}
+	
+	paramsStr := params.Encode()
+	if paramsStr != "" {
+		baseURL += "&" + paramsStr
+	}
+	
+	return baseURL
+}
+
+// GetFrontpageURL returns a page of the anonymous front page ("frontpage"),
+// r/all or r/popular. params may set sort (default hot), t for top and
+// controversial, and geo_filter, the region r/popular is tailored to.
+func (r *RedditClient) GetFrontpageURL(feed string, limit int, after string, params map[string]string) string {
+	sort := params["sort"]
+	if sort == "" {
+		sort = "hot"
+	}
+	path := "/" + sort + ".json"
+	if feed != "frontpage" {
+		path = "/r/" + feed + path
+	}
+	baseURL := r.hosts.url(EndpointListing) + path + "?raw_json=1"
+
+	query := url.Values{}
+	for _, param := range []string{"t", "geo_filter"} {
+		if value := params[param]; value != "" {
+			query.Set(param, value)
+		}
+	}
+	if limit > 0 {
+		query.Set("limit", fmt.Sprintf("%d", limit))
+	}
+	if after != "" {
+		query.Set("after", after)
+	}
+
+	if paramsStr := query.Encode(); paramsStr != "" {
+		baseURL += "&" + paramsStr
+	}
+	return baseURL
+}
+
+func (r *RedditClient) GetUserAboutURL(username string) string {
+	return fmt.Sprintf("%s/user/%s/about.json", r.hosts.url(EndpointUser), username)
+}
+
+func (r *RedditClient) GetUserPostsURL(username string, after string) string {
+	baseURL := fmt.Sprintf("%s/user/%s/submitted/new.json?raw_json=1&sort=new", r.hosts.url(EndpointUser), username)
+	
+	if after != "" {
+		baseURL += "&after=" + after
+	}
+	
+	return baseURL
+}
+
+func (r *RedditClient) GetUserCommentsURL(username string, after string) string {
+	baseURL := fmt.Sprintf("%s/user/%s/comments/.json?raw_json=1&limit=100", r.hosts.url(EndpointUser), username)
+	
+	if after != "" {
+		baseURL += "&after=" + after
+	}
+	
+	return baseURL
+}
+
+// GetUserOverviewURL is the page listing a user's posts and comments
+// together, newest first
+func (r *RedditClient) GetUserOverviewURL(username string, after string) string {
+	baseURL := fmt.Sprintf("%s/user/%s/overview.json?raw_json=1&sort=new&limit=100", r.hosts.url(EndpointUser), username)
+	if after != "" {
+		baseURL += "&after=" + after
+	}
+	return baseURL
+}
+
+func (r *RedditClient) GetPostURL(postID string) string {
+	return fmt.Sprintf("%s/comments/%s.json?raw_json=1&sort=new", r.hosts.url(EndpointPost), postID)
+}
+
+// GetDuplicatesURL is the "other discussions" page of a post: the post and the
+// other submissions of the same link, crossposts included
+func (r *RedditClient) GetDuplicatesURL(postID string) string {
+	return fmt.Sprintf("%s/duplicates/%s.json?raw_json=1&limit=100", r.hosts.url(EndpointPost), postID)
+}
+
+func (r *RedditClient) FetchMoreComments(ctx context.Context, postID string, commentIDs []string) (json.RawMessage, error) {
+    if len(commentIDs) == 0 {
+        return nil, nil
+    }
+    
+    fullPostID := postID
+    if !strings.HasPrefix(fullPostID, "t3_") {
+        fullPostID = "t3_" + postID
+    }
+    
+    endpoint := r.hosts.url(EndpointMoreChildren) + "/api/morechildren"
+    
+    params := url.Values{
+        "api_type":       {"json"},
+        "link_id":        {fullPostID},
+        "children":       {strings.Join(commentIDs, ",")},
+        "limit_children": {"false"},
+        "sort":           {commentSortParam(ctx)},
+    }
+    
+    // Log the request
+    fmt.Printf("Fetching %d more comments for post %s\n", len(commentIDs), postID)
+    
+    var bodyBytes []byte
+    err := r.withFailover(ctx, endpoint+"?"+params.Encode(), func(url string) error {
+        req, err := http.NewRequestWithContext(utils.WithJSONExpected(ctx), "GET", url, nil)
+        if err != nil {
+            return fmt.Errorf("create request: %w", err)
+        }
+        req.Header.Set("User-Agent", r.currentUserAgent())
+
+        // The client retries under the retry policy, honouring Retry-After on 429
+        _, bodyBytes, err = r.client.Do(req)
+        return err
+    })
+    if err != nil {
+        return nil, fmt.Errorf("fetchMoreComments request: %w, For comments: %v", err, commentIDs)
+    }
+    
+    return bodyBytes, nil
+}
+
+func (r *RedditClient) GetSearchURL(searchParams map[string]string) string {
+	baseSearchURL := fmt.Sprintf("%s/search.json?raw_json=1", r.hosts.url(EndpointSearch))
+	
+	params := url.Values{}
+	
+	var queryParts []string
+	
+	if search, ok := searchParams["search_string"]; ok && search != "" {
+		queryParts = append(queryParts, search)
+	}
+	
+	advancedParams := []string{"subreddit", "author", "site", "url", "selftext", "self", "nsfw"}
+	for _, param := range advancedParams {
+		if value, ok := searchParams[param]; ok && value != "" {
+			queryParts = append(queryParts, fmt.Sprintf("%s:%s", param, value))
+		}
+	}
+	
+	if len(queryParts) > 0 {
+		params.Set("q", strings.Join(queryParts, " "))
+	}
+	
+	directParams := []string{"sort", "t", "limit", "after", "before", "restrict_sr"}
+	for _, param := range directParams {
+		if value, ok := searchParams[param]; ok && value != "" {
+			params.Set(param, value)
+		}
+	}
+	
+	if time, ok := searchParams["time"]; ok && time != "" {
+		params.Set("t", time)
+	}
+	
+	paramsStr := params.Encode()
+	if paramsStr != "" {
+		baseSearchURL += "&" + paramsStr
+	}
+	
+	return baseSearchURL
+}
\ No newline at end of file
@@ -0,0 +1,20 @@
+// pkg/client/refetch.go
+package client
+
+import "context"
+
+type refetchKey struct{}
+
+// WithRefetch marks fetches with the returned context as retries of a page
+// whose earlier copy looked wrong, e.g. an interstitial that parsed to no
+// posts. Caches in front of the client must fetch such pages from Reddit
+// instead of serving their copy.
+func WithRefetch(ctx context.Context) context.Context {
+	return context.WithValue(ctx, refetchKey{}, true)
+}
+
+// IsRefetch reports whether ctx was marked by WithRefetch
+func IsRefetch(ctx context.Context) bool {
+	refetch, _ := ctx.Value(refetchKey{}).(bool)
+	return refetch
+}
@@ -0,0 +1,575 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Post represents a Reddit post
+// swagger:model Post
+type Post struct {
+	// Reddit post ID
+	ID string `json:"id"`
+	// Post title
+	Title string `json:"title"`
+	// Post body/content
+	Body string `json:"body"`
+	// Author's username
+	Author string `json:"author"`
+	// Post score (upvotes minus downvotes)
+	Score int `json:"score"`
+	// Number of comments reported by Reddit
+	NumComments int `json:"num_comments"`
+	// Creation timestamp, RFC3339 in UTC
+	CreatedAt time.Time `json:"created_at"`
+	// Creation time in Unix seconds as Reddit sends it, fraction included
+	CreatedUTC float64 `json:"created_utc"`
+	// Post flair text
+	Flair string `json:"flair,omitempty"`
+	// Normalized category of the flair, from FLAIR_CATEGORIES_FILE
+	Category string `json:"category,omitempty"`
+	// Marked NSFW (over_18) by its author or the subreddit
+	NSFW bool `json:"nsfw"`
+	// Full URL to the post on the canonical host (REDDIT_CANONICAL_HOST)
+	URL string `json:"url"`
+	// Address a link post points to; empty for text posts
+	LinkURL string `json:"link_url,omitempty"`
+	// Host the post was fetched from, e.g. old.reddit.com
+	SourceHost string `json:"source_host,omitempty"`
+	// Subreddit the post was made in, without the r/ prefix
+	Subreddit string `json:"subreddit,omitempty"`
+	// Fullname of that subreddit, e.g. t5_2rc7j; stable across renames
+	SubredditID string `json:"subreddit_id,omitempty"`
+	// Subscriber count of that subreddit when the post was listed
+	SubredditSubscribers int `json:"subreddit_subscribers,omitempty"`
+	// Fullname of the post this one crossposts, e.g. t3_abc123
+	CrosspostParent string `json:"crosspost_parent,omitempty"`
+	// Awards given to the post; only parsed when awards are requested
+	Awards []Award `json:"awards,omitempty"`
+	// Personal data was redacted from the title and body (SCRUB_PII)
+	Scrubbed bool `json:"scrubbed,omitempty"`
+	// Hash of the normalized title and body, shared by posts of the same text
+	ContentHash string `json:"content_hash,omitempty"`
+	// Hash of the canonical link URL, shared by posts of the same link
+	LinkHash string `json:"link_hash,omitempty"`
+}
+
+// Comment represents a Reddit comment
+// swagger:model Comment
+type Comment struct {
+	// Comment ID
+	ID string `json:"id"`
+	// Fullname of what the comment replies to: t1_ and a comment ID, or t3_
+	// and the post ID
+	ParentID string `json:"parent_id,omitempty"`
+	// Comment author's username
+	Author string `json:"author"`
+	// Comment body text
+	Body string `json:"body"`
+	// Comment score
+	Score int `json:"score"`
+	// Comment creation timestamp, RFC3339 in UTC
+	CreatedAt time.Time `json:"created_at"`
+	// Creation time in Unix seconds as Reddit sends it, fraction included
+	CreatedUTC float64 `json:"created_utc"`
+	// Nested comment replies
+	Replies []Comment `json:"replies,omitempty"`
+	// Flag indicating if this is a "more comments" placeholder
+	IsMore bool `json:"is_more,omitempty"`
+	// List of IDs for additional comments that need to be loaded
+    MoreIDs []string `json:"more_ids,omitempty"`
+    // Flag indicating if there are more child comments available
+    HasMore bool `json:"has_more,omitempty"`
+	// Count of total remaining comments in a "more" object
+    MoreCount int `json:"more_count,omitempty"`
+	// Awards given to the comment; only parsed when awards are requested
+	Awards []Award `json:"awards,omitempty"`
+	// Personal data was redacted from the body (SCRUB_PII)
+	Scrubbed bool `json:"scrubbed,omitempty"`
+}
+
+// Award is one kind of award given to a post or comment
+// swagger:model Award
+type Award struct {
+	// Award name, e.g. Wholesome
+	Name string `json:"name"`
+	// Number of times it was given
+	Count int `json:"count"`
+	// Price of one award in Reddit coins
+	CoinPrice int `json:"coin_price"`
+}
+
+// UserInfo represents a Reddit user's profile information
+// swagger:model UserInfo
+type UserInfo struct {
+	// Username
+	Username string `json:"username"`
+	// Link karma score
+	LinkKarma int `json:"link_karma"`
+	// Comment karma score
+	CommentKarma int `json:"comment_karma"`
+	// Account creation timestamp, RFC3339 in UTC
+	CreatedAt time.Time `json:"created_at"`
+	// Creation time in Unix seconds as Reddit sends it, fraction included
+	CreatedUTC float64 `json:"created_utc"`
+}
+
+// PostDetail represents a Reddit post with its comments
+// swagger:model PostDetail
+type PostDetail struct {
+	// Post information
+	Post Post `json:"post"`
+	// Comments on the post
+	Comments []Comment `json:"comments"`
+	// Other submissions of the post's link, crossposts included; only
+	// fetched when related posts are requested
+	Related []Post `json:"related,omitempty"`
+	// How complete the post and its comment tree are
+	Quality *Quality `json:"quality,omitempty"`
+	// Comments left out because they did not match Reddit's usual schema
+	ParseReport *ParseReport `json:"parse_report,omitempty"`
+	// Requests to Reddit sent again after a failed attempt
+	Retries int64 `json:"retries,omitempty"`
+	// Served from the result cache, as scraped for an earlier identical
+	// request, rather than scraped for this one
+	Cached bool `json:"cached,omitempty"`
+	// Estimate of how much of the comment tree was fetched
+	Completeness *CommentCompleteness `json:"completeness,omitempty"`
+}
+
+// CommentCompleteness compares the comments fetched for a post with the count
+// Reddit reports for it
+// swagger:model CommentCompleteness
+type CommentCompleteness struct {
+	// Comments in the tree, "load more" placeholders left out
+	Fetched int `json:"fetched"`
+	// Comments Reddit reports for the post (num_comments), which also counts
+	// removed comments it no longer lists
+	Reported int `json:"reported"`
+	// Comments behind the "load more" placeholders left in the tree, as
+	// Reddit counts them
+	Unloaded int `json:"unloaded"`
+	// Fetched as a percentage of Reported, at most 100; 100 when Reddit
+	// reports no comments
+	Percent float64 `json:"percent"`
+}
+// UserComment represents a comment made by a user
+// swagger:model UserComment
+type UserComment struct {
+	// Comment ID
+	ID string `json:"id"`
+	// Comment body text
+	Body string `json:"body"`
+	// Comment score
+	Score int `json:"score"`
+	// Comment creation timestamp, RFC3339 in UTC
+	CreatedAt time.Time `json:"created_at"`
+	// Creation time in Unix seconds as Reddit sends it, fraction included
+	CreatedUTC float64 `json:"created_utc"`
+	// Subreddit where the comment was posted
+	Subreddit string `json:"subreddit"`
+	// ID of the post containing this comment
+	PostID string `json:"post_id"`
+	// Title of the post containing this comment
+	PostTitle string `json:"post_title"`
+	// Author of the parent comment (if this is a reply)
+	ParentAuthor string `json:"parent_author,omitempty"`
+	// Personal data was redacted from the body and post title (SCRUB_PII)
+	Scrubbed bool `json:"scrubbed,omitempty"`
+}
+
+// UserPost represents a post made by a user
+// swagger:model UserPost
+type UserPost struct {
+	// Post ID
+	ID string `json:"id"`
+	// Post title
+	Title string `json:"title"`
+	// Post body/content
+	Body string `json:"body"`
+	// Post score
+	Score int `json:"score"`
+	// Post creation timestamp, RFC3339 in UTC
+	CreatedAt time.Time `json:"created_at"`
+	// Creation time in Unix seconds as Reddit sends it, fraction included
+	CreatedUTC float64 `json:"created_utc"`
+	// Subreddit where the post was created
+	Subreddit string `json:"subreddit"`
+	// Full URL to the post on the canonical host (REDDIT_CANONICAL_HOST)
+	URL string `json:"url"`
+	// Host the post was fetched from, e.g. old.reddit.com
+	SourceHost string `json:"source_host,omitempty"`
+	// Post flair text
+	Flair string `json:"flair,omitempty"`
+	// Normalized category of the flair, from FLAIR_CATEGORIES_FILE
+	Category string `json:"category,omitempty"`
+	// Marked NSFW (over_18) by its author or the subreddit
+	NSFW bool `json:"nsfw"`
+	// Pinned to the user's profile, so listed ahead of newer posts by Reddit
+	Pinned bool `json:"pinned,omitempty"`
+	// Personal data was redacted from the title and body (SCRUB_PII)
+	Scrubbed bool `json:"scrubbed,omitempty"`
+}
+
+// UserActivity represents all activity for a specific user
+// swagger:model UserActivity
+type UserActivity struct {
+	// User profile information
+	UserInfo UserInfo `json:"user_info"`
+	// Posts created by the user
+	Posts []UserPost `json:"posts,omitempty"`
+	// Comments made by the user
+	Comments []UserComment `json:"comments,omitempty"`
+	// Request metadata; the scraper reports what it dropped, the HTTP API fills in the rest
+	Meta *UserActivityMeta `json:"meta,omitempty"`
+}
+
+// UserOverviewItem is one entry of a user's overview: a post or a comment in
+// a common envelope, with the fields both have lifted to the top
+// swagger:model UserOverviewItem
+type UserOverviewItem struct {
+	// "post" or "comment"
+	Kind string `json:"kind"`
+	// Post or comment ID
+	ID string `json:"id"`
+	// Subreddit the post or comment was made in
+	Subreddit string `json:"subreddit"`
+	// Post or comment score
+	Score int `json:"score"`
+	// Creation timestamp, RFC3339 in UTC
+	CreatedAt time.Time `json:"created_at"`
+	// Creation time in Unix seconds as Reddit sends it, fraction included
+	CreatedUTC float64 `json:"created_utc"`
+	// The post, when kind is "post"
+	Post *UserPost `json:"post,omitempty"`
+	// The comment, when kind is "comment"
+	Comment *UserComment `json:"comment,omitempty"`
+}
+
+// Kinds of UserOverviewItem
+const (
+	OverviewKindPost    = "post"
+	OverviewKindComment = "comment"
+)
+
+// UserOverview is a user's posts and comments as one stream, newest first,
+// as Reddit lists them on the user's overview page
+// swagger:model UserOverview
+type UserOverview struct {
+	// Username
+	Username string `json:"username"`
+	// Posts and comments, newest first
+	Items []UserOverviewItem `json:"items"`
+	// Request metadata
+	Meta *UserOverviewMeta `json:"meta,omitempty"`
+}
+
+// Activity splits the overview into the posts and comments of a UserActivity
+func (o UserOverview) Activity() UserActivity {
+	activity := UserActivity{UserInfo: UserInfo{Username: o.Username}}
+	for _, item := range o.Items {
+		switch {
+		case item.Post != nil:
+			activity.Posts = append(activity.Posts, *item.Post)
+		case item.Comment != nil:
+			activity.Comments = append(activity.Comments, *item.Comment)
+		}
+	}
+	return activity
+}
+
+// UserOverviewMeta describes how a user overview response was assembled
+// swagger:model UserOverviewMeta
+type UserOverviewMeta struct {
+	// Always "newest_first"; when a limit or timeout cuts the scrape short,
+	// the items kept are the newest ones
+	Ordering string `json:"ordering"`
+	// Item limit as requested
+	RequestedLimit int `json:"requested_limit"`
+	// Only items newer than this Unix timestamp were returned
+	SinceTimestamp int64 `json:"since_timestamp"`
+	// Processing time in milliseconds
+	ProcessingTimeMS int64 `json:"processing_time_ms"`
+	// Overview pages fetched from Reddit
+	PagesFetched int `json:"pages_fetched"`
+	// Items dropped because Reddit listed them again on a later page
+	DuplicatesDropped int `json:"duplicates_dropped"`
+	// Paging stopped at an item older than since_timestamp
+	ReachedTimeCutoff bool `json:"reached_time_cutoff"`
+	// Set when paging stopped before the listing ended, the limit was reached
+	// or since_timestamp was passed, so older items may be missing
+	Truncated bool `json:"truncated"`
+	// Why paging stopped early: "time_limit", "page_cap" or "listing_cap"
+	TruncationReason string `json:"truncation_reason,omitempty"`
+	// Creation time of the oldest item read. When the scrape was truncated it
+	// is that of the part cut short, so everything newer was covered.
+	OldestReached *time.Time `json:"oldest_reached,omitempty"`
+	// Items left out because they did not match Reddit's usual schema
+	ParseReport *ParseReport `json:"parse_report,omitempty"`
+	// Requests to Reddit sent again after a failed attempt
+	Retries int64 `json:"retries,omitempty"`
+	// Served from the result cache, as scraped for an earlier identical
+	// request, rather than scraped for this one
+	Cached bool `json:"cached,omitempty"`
+}
+
+// UserSummary aggregates a user's fetched posts and comments
+// swagger:model UserSummary
+type UserSummary struct {
+	// Username
+	Username string `json:"username"`
+	// Posts and comments the summary is made from
+	Posts    int `json:"posts"`
+	Comments int `json:"comments"`
+	// Oldest and newest post or comment; unset without any
+	FirstSeen *time.Time `json:"first_seen,omitempty"`
+	LastSeen  *time.Time `json:"last_seen,omitempty"`
+	// Activity per subreddit, most active first
+	Subreddits []SubredditActivity `json:"subreddits"`
+	// Posts and comments made in each hour of the day (UTC), from 0 to 23
+	HourlyActivity [24]int `json:"hourly_activity"`
+	// Hours of the day (UTC) with the most posts and comments, most active first
+	MostActiveHours []int `json:"most_active_hours"`
+	// How the posts and comments were fetched
+	Meta *UserActivityMeta `json:"meta,omitempty"`
+}
+
+// SubredditActivity is a user's activity in one subreddit
+// swagger:model SubredditActivity
+type SubredditActivity struct {
+	// Subreddit name
+	Subreddit string `json:"subreddit"`
+	// Posts and comments made there
+	Posts    int `json:"posts"`
+	Comments int `json:"comments"`
+	// Average score of those posts and comments, 0 without any
+	AveragePostScore    float64 `json:"average_post_score"`
+	AverageCommentScore float64 `json:"average_comment_score"`
+	// Oldest and newest post or comment there
+	FirstSeen time.Time `json:"first_seen"`
+	LastSeen  time.Time `json:"last_seen"`
+}
+
+// UserActivityMeta describes how a user activity response was assembled
+// swagger:model UserActivityMeta
+type UserActivityMeta struct {
+	// Order of posts and comments. Always "newest_first": when a limit or timeout
+	// cuts the scrape short, the items kept are the newest ones
+	Ordering string `json:"ordering"`
+	// Post limit as requested
+	RequestedPostLimit int `json:"requested_post_limit"`
+	// Comment limit as requested
+	RequestedCommentLimit int `json:"requested_comment_limit"`
+	// Only items newer than this Unix timestamp were returned
+	SinceTimestamp int64 `json:"since_timestamp"`
+	// Processing time in milliseconds
+	ProcessingTimeMS int64 `json:"processing_time_ms"`
+	// Posts dropped because Reddit listed them again on a later page
+	DuplicatePostsDropped int `json:"duplicate_posts_dropped"`
+	// Posts and comments read but left out because they did not match the
+	// request's filters
+	FilteredOut int `json:"filtered_out"`
+	// Posts and comments read that each filter matched, by parameter name;
+	// set when the request has filters
+	FilterMatches map[string]int `json:"filter_matches,omitempty"`
+	// Set when posts or comments could not be fetched and only the rest is returned
+	Partial bool `json:"partial"`
+	// What failed, e.g. "fetch user comments: server error: status 429"
+	Warnings []string `json:"warnings,omitempty"`
+	// Set when paging stopped before the listing ended, the limit was reached
+	// or since_timestamp was passed, so older items may be missing
+	Truncated bool `json:"truncated"`
+	// Why paging stopped early: "time_limit", "page_cap" or "listing_cap"
+	TruncationReason string `json:"truncation_reason,omitempty"`
+	// Creation time of the oldest item read. When the scrape was truncated it
+	// is that of the part cut short, so everything newer was covered.
+	OldestReached *time.Time `json:"oldest_reached,omitempty"`
+	// How complete the posts and comments are
+	Quality *Quality `json:"quality,omitempty"`
+	// Posts and comments left out because they did not match Reddit's usual
+	// schema
+	ParseReport *ParseReport `json:"parse_report,omitempty"`
+	// Requests to Reddit sent again after a failed attempt
+	Retries int64 `json:"retries,omitempty"`
+	// Served from the result cache, as scraped for an earlier identical
+	// request, rather than scraped for this one
+	Cached bool `json:"cached,omitempty"`
+}
+
+// ListingMeta describes how a subreddit or search listing was assembled
+// swagger:model ListingMeta
+type ListingMeta struct {
+	// Posts dropped because Reddit listed them again on a later page, which
+	// happens when the listing shifts while it is being paged
+	DuplicatesDropped int `json:"duplicates_dropped"`
+	// Posts read but left out because they did not match the request's filters
+	FilteredOut int `json:"filtered_out"`
+	// Posts read that each filter matched, by parameter name; set when the
+	// request has filters
+	FilterMatches map[string]int `json:"filter_matches,omitempty"`
+	// Listing pages fetched from Reddit
+	PagesFetched int `json:"pages_fetched"`
+	// Reddit's cursor after the last page fetched, empty at the end of the listing
+	After string `json:"after,omitempty"`
+	// Pass as after to continue where this response stopped; empty when
+	// nothing is left or the since_timestamp cutoff was reached
+	Cursor string `json:"cursor,omitempty"`
+	// Paging stopped at a post older than since_timestamp
+	ReachedTimeCutoff bool `json:"reached_time_cutoff"`
+	// Paging stopped at the request's time budget before the listing ended
+	TimedOut bool `json:"timed_out"`
+	// The listing ended at Reddit's cap of about 1000 posts before reaching
+	// since_timestamp
+	ReachedListingCap bool `json:"reached_listing_cap,omitempty"`
+	// Posts older than the listing cap found by backfilling
+	Backfilled int `json:"backfilled,omitempty"`
+	// Parts of the requested time window that neither the listing nor the
+	// backfill reached
+	CoverageGaps []TimeRange `json:"coverage_gaps,omitempty"`
+	// Set when paging stopped before the listing ended, the limit was reached
+	// or since_timestamp was passed, so older posts may be missing
+	Truncated bool `json:"truncated"`
+	// Why paging stopped early: "time_limit", "page_cap" or "listing_cap"
+	TruncationReason string `json:"truncation_reason,omitempty"`
+	// Creation time of the oldest post read
+	OldestReached *time.Time `json:"oldest_reached,omitempty"`
+	// How complete the posts are
+	Quality *Quality `json:"quality,omitempty"`
+	// Posts left out because they did not match Reddit's usual schema
+	ParseReport *ParseReport `json:"parse_report,omitempty"`
+	// Requests to Reddit sent again after a failed attempt
+	Retries int64 `json:"retries,omitempty"`
+	// Served from the result cache, as scraped for an earlier identical
+	// request, rather than scraped for this one
+	Cached bool `json:"cached,omitempty"`
+}
+
+// TimeRange is a span of post creation times, from inclusive to exclusive
+// swagger:model TimeRange
+type TimeRange struct {
+	From time.Time `json:"from"`
+	To   time.Time `json:"to"`
+}
+
+// Quality scores how complete a response is, each part from 0 to 1
+// swagger:model Quality
+type Quality struct {
+	// Mean of the parts below that apply to the response
+	Score float64 `json:"score"`
+	// Share of the core fields of each item (ID, title, author, creation
+	// time, URL and the like) that are populated; 1 when there are no items
+	Fields float64 `json:"fields"`
+	// 1 when the scrape ran to its limit, cutoff or the end of the listing, 0
+	// when it was truncated or part of it failed
+	Truncation float64 `json:"truncation"`
+	// Share of the comments Reddit counts for the post (num_comments) that
+	// were fetched, capped at 1; only set for posts
+	Expansion *float64 `json:"expansion,omitempty"`
+}
+
+// ParseReport counts the entries of Reddit's responses that were skipped
+// because they could not be decoded, e.g. a post whose score is a string. The
+// rest of the page is kept.
+// swagger:model ParseReport
+type ParseReport struct {
+	// Posts, comments and other entries skipped
+	Skipped int `json:"skipped"`
+	// Why the first few were skipped, e.g. "t3 abc123: json: cannot unmarshal
+	// string into Go struct field listingChild.data.score of type int"
+	Errors []string `json:"errors,omitempty"`
+}
+
+// OrderingNewestFirst is the ordering reported in response meta for listings
+// sorted by creation time, newest first
+const OrderingNewestFirst = "newest_first"
+
+// Reasons reported in response meta for a truncated scrape
+const (
+	// The scrape's time budget ran out
+	TruncatedTimeLimit = "time_limit"
+	// The scrape fetched as many pages as it allows itself
+	TruncatedPageCap = "page_cap"
+	// Reddit stopped listing at about 1000 items, and no backfill covered the rest
+	TruncatedListingCap = "listing_cap"
+)
+
+// RawChild is an internal structure used for parsing Reddit API responses
+type RawChild struct {
+	Kind string `json:"kind"`
+	Data struct {
+		ID string `json:"id"`
+		Author string `json:"author"`
+		Body string `json:"body"`
+		Score int `json:"score"`
+		CreatedUTC float64 `json:"created_utc"`
+		Replies json.RawMessage `json:"replies"`
+		Children []string `json:"children"`
+		ParentID string `json:"parent_id"`
+		Count int `json:"count"`
+		Permalink string `json:"permalink"`
+		AllAwardings json.RawMessage `json:"all_awardings"`
+	} `json:"data"`
+}
+// PostChange describes how a post's counters moved between two snapshots
+// swagger:model PostChange
+type PostChange struct {
+	// Reddit post ID
+	ID string `json:"id"`
+	// Post title
+	Title string `json:"title"`
+	// Score in the earlier snapshot
+	ScoreBefore int `json:"score_before"`
+	// Score in the later snapshot
+	ScoreAfter int `json:"score_after"`
+	// Comment count in the earlier snapshot
+	NumCommentsBefore int `json:"num_comments_before"`
+	// Comment count in the later snapshot
+	NumCommentsAfter int `json:"num_comments_after"`
+}
+
+// SubredditChanges is the result of diffing two successive subreddit snapshots
+// swagger:model SubredditChanges
+type SubredditChanges struct {
+	// Subreddit name
+	Subreddit string `json:"subreddit"`
+	// Time the earlier snapshot was taken (zero if there was none)
+	From time.Time `json:"from"`
+	// Time the later snapshot was taken
+	To time.Time `json:"to"`
+	// Posts that appeared since the earlier snapshot
+	New []Post `json:"new"`
+	// Posts that disappeared while still inside the compared window (likely removed or deleted)
+	Removed []Post `json:"removed"`
+	// Posts whose score or comment count changed
+	Changed []PostChange `json:"changed"`
+}
+
+// DuplicateGroup is a set of posts in different subreddits sharing a hash
+// swagger:model DuplicateGroup
+type DuplicateGroup struct {
+	// What the posts share: content (title and body) or link
+	Kind string `json:"kind"`
+	// The shared hash
+	Hash string `json:"hash"`
+	// Subreddits the posts were made in, sorted
+	Subreddits []string `json:"subreddits"`
+	// Distinct authors of the posts
+	Authors int `json:"authors"`
+	// The posts, oldest first
+	Posts []Post `json:"posts"`
+}
+
+// Duplicates are the posts repeated across subreddits within a time window
+// swagger:model Duplicates
+type Duplicates struct {
+	// Subreddits searched, joined by +
+	Subreddits string `json:"subreddits"`
+	// Start of the window
+	From time.Time `json:"from"`
+	// End of the window
+	To time.Time `json:"to"`
+	// Posts read from the subreddits within the window
+	PostsScanned int `json:"posts_scanned"`
+	// Groups of duplicates, largest first
+	Groups []DuplicateGroup `json:"groups"`
+}
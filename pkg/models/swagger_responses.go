@@ -0,0 +1,32 @@
+package models
+
+// HTTPError represents an HTTP error response
+// swagger:model HTTPError
+type HTTPError struct {
+	// HTTP status code
+	Code int `json:"code"`
+	// Error message
+	Message string `json:"message"`
+}
+
+// ValidationError is the response to a request with invalid parameters
+// swagger:model ValidationError
+type ValidationError struct {
+	// Every problem in one sentence
+	Message string `json:"message"`
+	// One entry per invalid parameter
+	Errors []FieldError `json:"errors"`
+}
+
+// FieldError is what is wrong with one parameter
+type FieldError struct {
+	// Name of the parameter
+	Field string `json:"field"`
+	// What is wrong with it, e.g. "must be true or false"
+	Message string `json:"message"`
+}
+
+// String is the message, so the error prints like a plain HTTPError
+func (e ValidationError) String() string {
+	return e.Message
+}
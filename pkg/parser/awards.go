@@ -0,0 +1,53 @@
+// pkg/parser/awards.go
+package parser
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"reddit-ingestion/pkg/models"
+)
+
+type awardsKey struct{}
+
+// WithAwards makes parsers fill in the awards of posts and comments parsed
+// with the returned context. Awards are left out otherwise: Reddit lists every
+// award with its icons and descriptions, which inflates large scrapes.
+func WithAwards(ctx context.Context) context.Context {
+	return context.WithValue(ctx, awardsKey{}, true)
+}
+
+// WithoutAwards leaves awards out of what is parsed with the returned context,
+// even if a parent context was marked by WithAwards
+func WithoutAwards(ctx context.Context) context.Context {
+	return context.WithValue(ctx, awardsKey{}, false)
+}
+
+// IncludesAwards reports whether ctx was marked by WithAwards
+func IncludesAwards(ctx context.Context) bool {
+	include, _ := ctx.Value(awardsKey{}).(bool)
+	return include
+}
+
+// parseAwards reads the all_awardings array of a post or comment. It is kept
+// raw while decoding so the array is only parsed when awards were requested.
+func parseAwards(ctx context.Context, raw json.RawMessage) []models.Award {
+	if len(raw) == 0 || !IncludesAwards(ctx) {
+		return nil
+	}
+	var awardings []struct {
+		Name      string `json:"name"`
+		Count     int    `json:"count"`
+		CoinPrice int    `json:"coin_price"`
+	}
+	if err := json.Unmarshal(raw, &awardings); err != nil {
+		fmt.Printf("Skipping unreadable all_awardings: %v\n", err)
+		return nil
+	}
+	var awards []models.Award
+	for _, a := range awardings {
+		awards = append(awards, models.Award{Name: a.Name, Count: a.Count, CoinPrice: a.CoinPrice})
+	}
+	return awards
+}
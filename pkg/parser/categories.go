@@ -0,0 +1,82 @@
+// pkg/parser/categories.go
+package parser
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+)
+
+// FlairCategories maps the free-text flairs of each subreddit onto normalized
+// categories, so "Bug", "Bug Report" and "Confirmed bug" can all be filtered
+// on as "bug". Subreddits and flairs are matched ignoring case and surrounding
+// spaces. It is safe for concurrent use and can be replaced by a reload.
+type FlairCategories struct {
+	mutex       sync.RWMutex
+	bySubreddit map[string]map[string]string
+}
+
+// NewFlairCategories creates the mapping from subreddit to flair to category
+func NewFlairCategories(mappings map[string]map[string]string) *FlairCategories {
+	c := &FlairCategories{}
+	c.Set(mappings)
+	return c
+}
+
+// Set replaces every mapping
+func (c *FlairCategories) Set(mappings map[string]map[string]string) {
+	bySubreddit := make(map[string]map[string]string, len(mappings))
+	for subreddit, flairs := range mappings {
+		normalized := make(map[string]string, len(flairs))
+		for flair, category := range flairs {
+			normalized[normalizeFlair(flair)] = category
+		}
+		bySubreddit[normalizeFlair(subreddit)] = normalized
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.bySubreddit = bySubreddit
+}
+
+// Category returns the category flair maps to in subreddit, empty when the
+// subreddit or flair has none
+func (c *FlairCategories) Category(subreddit, flair string) string {
+	if c == nil || flair == "" {
+		return ""
+	}
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	return c.bySubreddit[normalizeFlair(subreddit)][normalizeFlair(flair)]
+}
+
+func normalizeFlair(s string) string {
+	return strings.ToLower(strings.TrimSpace(s))
+}
+
+// ReadFlairCategories reads mappings from a JSON file of the form
+// {"golang": {"Bug": "bug", "Discussion": "discussion"}}. An empty path means
+// no mappings.
+func ReadFlairCategories(path string) (map[string]map[string]string, error) {
+	if path == "" {
+		return nil, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read flair categories: %w", err)
+	}
+	var mappings map[string]map[string]string
+	if err := json.Unmarshal(data, &mappings); err != nil {
+		return nil, fmt.Errorf("parse flair categories %s: %w", path, err)
+	}
+	for subreddit, flairs := range mappings {
+		for flair, category := range flairs {
+			if strings.TrimSpace(flair) == "" || category == "" {
+				return nil, fmt.Errorf("flair categories of %s: flairs and categories must not be empty", subreddit)
+			}
+		}
+	}
+	return mappings, nil
+}
@@ -1,11 +1,12 @@
-// internal/parser/interface.go
+// pkg/parser/interface.go
 package parser
 
 import (
 	"context"
 	"encoding/json"
+	"io"
 	
-	"reddit-ingestion/internal/models"
+	"reddit-ingestion/pkg/models"
 )
 
 type ParserInterface interface {
@@ -13,6 +14,13 @@ type ParserInterface interface {
 	ParseUserInfo(ctx context.Context, data json.RawMessage) (models.UserInfo, error)
 	ParseUserPosts(ctx context.Context, data json.RawMessage) ([]models.UserPost, string, error)
 	ParseUserComments(ctx context.Context, data json.RawMessage) ([]models.UserComment, string, error)
+	ParseUserOverview(ctx context.Context, data json.RawMessage) ([]models.UserOverviewItem, string, error)
 	ParsePost(ctx context.Context, postData, commentData json.RawMessage) (models.PostDetail, error)
 	ParseMoreComments(ctx context.Context, data json.RawMessage) ([]models.Comment, error)
-}
\ No newline at end of file
+}
+
+// SubredditStreamer is implemented by parsers that can decode a listing page
+// while it downloads; see RedditParser.StreamSubreddit
+type SubredditStreamer interface {
+	StreamSubreddit(ctx context.Context, r io.Reader, emit func(models.Post) bool) (string, error)
+}
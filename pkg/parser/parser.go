@@ -0,0 +1,477 @@
+// pkg/parser/parser.go
+package parser
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"reddit-ingestion/pkg/models"
+
+	"github.com/google/uuid"
+)
+
+// Parser defines the interface for parsing Reddit API responses
+type Parser interface {
+	ParseSubreddit(ctx context.Context, data json.RawMessage) ([]models.Post, string, error)
+	ParseUserInfo(ctx context.Context, data json.RawMessage) (models.UserInfo, error)
+	ParseUserPosts(ctx context.Context, data json.RawMessage) ([]models.UserPost, string, error)
+	ParseUserComments(ctx context.Context, data json.RawMessage) ([]models.UserComment, string, error)
+	ParseUserOverview(ctx context.Context, data json.RawMessage) ([]models.UserOverviewItem, string, error)
+	ParsePost(ctx context.Context, postData, commentData json.RawMessage) (models.PostDetail, error)
+	ParseMoreComments(ctx context.Context, data json.RawMessage) ([]models.Comment, error)
+}
+
+// ParserOptions decides how parsed items link back to Reddit
+type ParserOptions struct {
+	// CanonicalHost is the host of every post URL, whichever endpoint or
+	// Reddit front end the post came from
+	CanonicalHost string
+
+	// SourceHost is reported on each post as the host it was fetched from;
+	// empty leaves it out
+	SourceHost string
+
+	// Categories gives posts a normalized category from their subreddit and
+	// flair; nil leaves it out
+	Categories *FlairCategories
+}
+
+// DefaultParserOptions returns the options used by NewRedditParser
+func DefaultParserOptions() ParserOptions {
+	return ParserOptions{CanonicalHost: "reddit.com"}
+}
+
+type RedditParser struct {
+	opts ParserOptions
+}
+
+func NewRedditParser() *RedditParser {
+	return NewRedditParserWithOptions(DefaultParserOptions())
+}
+
+// NewRedditParserWithOptions creates a parser that links to opts.CanonicalHost
+func NewRedditParserWithOptions(opts ParserOptions) *RedditParser {
+	if opts.CanonicalHost == "" {
+		opts.CanonicalHost = DefaultParserOptions().CanonicalHost
+	}
+	return &RedditParser{opts: opts}
+}
+
+// postURL is the canonical URL of the post at permalink
+func (p *RedditParser) postURL(permalink string) string {
+	return "https://" + p.opts.CanonicalHost + permalink
+}
+
+// createdAt is the time of a created_utc value in UTC, keeping its fraction.
+// The fraction is rounded to microseconds, finer than a float64 holds at
+// today's epoch.
+func createdAt(utc float64) time.Time {
+	sec, frac := math.Modf(utc)
+	return time.Unix(int64(sec), int64(math.Round(frac*1e6))*1e3).UTC()
+}
+
+func (p *RedditParser) ParseSubreddit(ctx context.Context, data json.RawMessage) ([]models.Post, string, error) {
+	var posts []models.Post
+	after, err := p.StreamSubreddit(ctx, bytes.NewReader(data), func(post models.Post) bool {
+		posts = append(posts, post)
+		return true
+	})
+	if err != nil {
+		return nil, "", err
+	}
+	return posts, after, nil
+}
+
+func (p *RedditParser) ParseUserInfo(ctx context.Context, data json.RawMessage) (models.UserInfo, error) {
+	var about struct {
+		Data struct {
+			Name         string  `json:"name"`
+			CreatedUTC   float64 `json:"created_utc"`
+			LinkKarma    int     `json:"link_karma"`
+			CommentKarma int     `json:"comment_karma"`
+		} `json:"data"`
+	}
+
+	if err := json.Unmarshal(data, &about); err != nil {
+		return models.UserInfo{}, fmt.Errorf("parse user info JSON: %w", err)
+	}
+
+	return models.UserInfo{
+		Username:     about.Data.Name,
+		LinkKarma:    about.Data.LinkKarma,
+		CommentKarma: about.Data.CommentKarma,
+		CreatedAt:    createdAt(about.Data.CreatedUTC),
+		CreatedUTC:   about.Data.CreatedUTC,
+	}, nil
+}
+
+// userListing is a page of a user's posts, comments or overview
+type userListing struct {
+	Data struct {
+		Children []json.RawMessage `json:"children"`
+		After    string            `json:"after"`
+	} `json:"data"`
+}
+
+func (p *RedditParser) ParseUserPosts(ctx context.Context, data json.RawMessage) ([]models.UserPost, string, error) {
+	var listing userListing
+
+	if err := json.Unmarshal(data, &listing); err != nil {
+		return nil, "", fmt.Errorf("parse user posts JSON: %w", err)
+	}
+
+	return p.userPosts(ctx, listing.Data.Children), listing.Data.After, nil
+}
+
+// userPosts builds the posts (t3) of a user listing's children
+func (p *RedditParser) userPosts(ctx context.Context, children []json.RawMessage) []models.UserPost {
+	var posts []models.UserPost
+	for _, raw := range children {
+		var child struct {
+			Kind string `json:"kind"`
+			Data struct {
+				ID            string  `json:"id"`
+				Title         string  `json:"title"`
+				Selftext      string  `json:"selftext"`
+				Author        string  `json:"author"`
+				Score         int     `json:"score"`
+				CreatedUTC    float64 `json:"created_utc"`
+				Subreddit     string  `json:"subreddit"`
+				LinkFlairText string  `json:"link_flair_text"`
+				Over18        bool    `json:"over_18"`
+				Permalink     string  `json:"permalink"`
+				URL           string  `json:"url"`
+				Pinned        bool    `json:"pinned"`
+				Stickied      bool    `json:"stickied"`
+			} `json:"data"`
+		}
+		if !decodeChild(ctx, raw, &child, "t3") {
+			continue
+		}
+
+		posts = append(posts, models.UserPost{
+			ID:         child.Data.ID,
+			Title:      child.Data.Title,
+			Body:       child.Data.Selftext,
+			Score:      child.Data.Score,
+			CreatedAt:  createdAt(child.Data.CreatedUTC),
+			CreatedUTC: child.Data.CreatedUTC,
+			Subreddit:  child.Data.Subreddit,
+			Flair:      child.Data.LinkFlairText,
+			Category:   p.opts.Categories.Category(child.Data.Subreddit, child.Data.LinkFlairText),
+			NSFW:       child.Data.Over18,
+			URL:        p.postURL(child.Data.Permalink),
+			SourceHost: p.opts.SourceHost,
+			Pinned:     child.Data.Pinned || child.Data.Stickied,
+		})
+	}
+
+	return posts
+}
+
+func (p *RedditParser) ParseUserComments(ctx context.Context, data json.RawMessage) ([]models.UserComment, string, error) {
+	var listing userListing
+
+	if err := json.Unmarshal(data, &listing); err != nil {
+		return nil, "", fmt.Errorf("parse user comments JSON: %w", err)
+	}
+
+	return p.userComments(ctx, listing.Data.Children), listing.Data.After, nil
+}
+
+// userComments builds the comments (t1) of a user listing's children
+func (p *RedditParser) userComments(ctx context.Context, children []json.RawMessage) []models.UserComment {
+	var comments []models.UserComment
+	for _, raw := range children {
+		var child struct {
+			Kind string `json:"kind"`
+			Data struct {
+				ID         string  `json:"id"`
+				Body       string  `json:"body"`
+				Author     string  `json:"author"`
+				Score      int     `json:"score"`
+				CreatedUTC float64 `json:"created_utc"`
+				Subreddit  string  `json:"subreddit"`
+				LinkID     string  `json:"link_id"`
+				LinkTitle  string  `json:"link_title"`
+				ParentID   string  `json:"parent_id"`
+			} `json:"data"`
+		}
+		if !decodeChild(ctx, raw, &child, "t1") {
+			continue
+		}
+
+		postID := child.Data.LinkID
+		if len(postID) > 3 {
+			postID = postID[3:] // Remove "t3_" prefix
+		}
+
+		comments = append(comments, models.UserComment{
+			ID:         child.Data.ID,
+			Body:       child.Data.Body,
+			Score:      child.Data.Score,
+			CreatedAt:  createdAt(child.Data.CreatedUTC),
+			CreatedUTC: child.Data.CreatedUTC,
+			Subreddit:  child.Data.Subreddit,
+			PostID:     postID,
+			PostTitle:  child.Data.LinkTitle,
+		})
+	}
+
+	return comments
+}
+
+// ParseUserOverview parses a page of a user's overview, which lists posts (t3)
+// and comments (t1) together, into one stream of items, newest first
+func (p *RedditParser) ParseUserOverview(ctx context.Context, data json.RawMessage) ([]models.UserOverviewItem, string, error) {
+	var listing userListing
+	if err := json.Unmarshal(data, &listing); err != nil {
+		return nil, "", fmt.Errorf("parse user overview JSON: %w", err)
+	}
+	// Children that are not things at all are reported here, once, rather
+	// than by both passes below
+	children := things(ctx, listing.Data.Children)
+	posts := p.userPosts(ctx, children)
+	comments := p.userComments(ctx, children)
+
+	items := make([]models.UserOverviewItem, 0, len(posts)+len(comments))
+	for i := range posts {
+		post := posts[i]
+		items = append(items, models.UserOverviewItem{
+			Kind:       models.OverviewKindPost,
+			ID:         post.ID,
+			Subreddit:  post.Subreddit,
+			Score:      post.Score,
+			CreatedAt:  post.CreatedAt,
+			CreatedUTC: post.CreatedUTC,
+			Post:       &post,
+		})
+	}
+	for i := range comments {
+		comment := comments[i]
+		items = append(items, models.UserOverviewItem{
+			Kind:       models.OverviewKindComment,
+			ID:         comment.ID,
+			Subreddit:  comment.Subreddit,
+			Score:      comment.Score,
+			CreatedAt:  comment.CreatedAt,
+			CreatedUTC: comment.CreatedUTC,
+			Comment:    &comment,
+		})
+	}
+	sort.SliceStable(items, func(i, j int) bool {
+		return items[i].CreatedAt.After(items[j].CreatedAt)
+	})
+	return items, listing.Data.After, nil
+}
+
+func (p *RedditParser) ParsePost(ctx context.Context, postData, commentData json.RawMessage) (models.PostDetail, error) {
+	var postBlock struct {
+		Data struct {
+			Children []struct {
+				Data struct {
+					ID            string  `json:"id"`
+					Title         string  `json:"title"`
+					Author        string  `json:"author"`
+					CreatedUTC    float64 `json:"created_utc"`
+					Score         int     `json:"score"`
+					NumComments   int     `json:"num_comments"`
+					LinkFlairText string  `json:"link_flair_text"`
+					Over18        bool    `json:"over_18"`
+					Permalink     string  `json:"permalink"`
+					Selftext      string  `json:"selftext"`
+					URL           string  `json:"url"`
+					IsSelf        bool    `json:"is_self"`
+					Subreddit     string  `json:"subreddit"`
+					SubredditID   string  `json:"subreddit_id"`
+					SubredditSubs int     `json:"subreddit_subscribers"`
+
+					CrosspostParent string          `json:"crosspost_parent"`
+					AllAwardings    json.RawMessage `json:"all_awardings"`
+				} `json:"data"`
+			} `json:"children"`
+		} `json:"data"`
+	}
+
+	if err := json.Unmarshal(postData, &postBlock); err != nil {
+		return models.PostDetail{}, fmt.Errorf("parse post JSON: %w", err)
+	}
+
+	if len(postBlock.Data.Children) == 0 {
+		return models.PostDetail{}, fmt.Errorf("post not found")
+	}
+
+	pd := postBlock.Data.Children[0].Data
+	post := models.Post{
+		ID:          pd.ID,
+		Title:       pd.Title,
+		Body:        pd.Selftext,
+		Author:      pd.Author,
+		Score:       pd.Score,
+		NumComments: pd.NumComments,
+		CreatedAt:   createdAt(pd.CreatedUTC),
+		CreatedUTC:  pd.CreatedUTC,
+		Flair:       pd.LinkFlairText,
+		Category:    p.opts.Categories.Category(pd.Subreddit, pd.LinkFlairText),
+		NSFW:        pd.Over18,
+		URL:         p.postURL(pd.Permalink),
+		LinkURL:     linkURL(pd.IsSelf, pd.URL),
+		SourceHost:  p.opts.SourceHost,
+
+		Subreddit:            pd.Subreddit,
+		SubredditID:          pd.SubredditID,
+		SubredditSubscribers: pd.SubredditSubs,
+		CrosspostParent:      pd.CrosspostParent,
+		Awards:               parseAwards(ctx, pd.AllAwardings),
+	}
+
+	comments, err := p.parseCommentsTree(ctx, commentData)
+	if err != nil {
+		return models.PostDetail{Post: post}, fmt.Errorf("parse comments: %w", err)
+	}
+
+	return models.PostDetail{Post: post, Comments: comments}, nil
+}
+
+func (p *RedditParser) ParseMoreComments(ctx context.Context, data json.RawMessage) ([]models.Comment, error) {
+    var wrapper struct {
+        JSON struct {
+            Data struct {
+                Things []json.RawMessage `json:"things"`
+            } `json:"data"`
+        } `json:"json"`
+    }
+    
+    if err := json.Unmarshal(data, &wrapper); err != nil {
+        var directThings []json.RawMessage
+        if err2 := json.Unmarshal(data, &directThings); err2 == nil {
+            return p.processComments(ctx, directThings), nil
+        }
+        
+        return nil, fmt.Errorf("parse more comments JSON: %w", err)
+    }
+    
+    thingCount := len(wrapper.JSON.Data.Things)
+    fmt.Printf("Received %d things in morecomments response\n", thingCount)
+    
+    processed := p.processComments(ctx, wrapper.JSON.Data.Things)
+    fmt.Printf("Processed %d comments from morecomments response\n", len(processed))
+    return processed, nil
+}
+
+func (p *RedditParser) parseCommentsTree(ctx context.Context, data json.RawMessage) ([]models.Comment, error) {
+	var commentsBlock struct {
+		Data struct {
+			Children []json.RawMessage `json:"children"`
+		} `json:"data"`
+	}
+
+	if err := json.Unmarshal(data, &commentsBlock); err != nil {
+		return nil, fmt.Errorf("parse comments JSON: %w", err)
+	}
+
+	return p.processComments(ctx, commentsBlock.Data.Children), nil
+}
+
+// processComments builds the comments of a listing's children, skipping
+// those that do not decode
+func (p *RedditParser) processComments(ctx context.Context, children []json.RawMessage) []models.Comment {
+    var comments []models.Comment
+    
+    for _, raw := range children {
+        if ctx.Err() != nil {
+            return comments
+        }
+        var child models.RawChild
+        if !decodeChild(ctx, raw, &child) {
+            continue
+        }
+        
+        switch child.Kind {
+        case "t1": // Regular comment
+            comment := models.Comment{
+                ID:        child.Data.ID,
+                ParentID:  child.Data.ParentID,
+                Author:    child.Data.Author,
+                Body:      child.Data.Body,
+                Score:     child.Data.Score,
+                CreatedAt: createdAt(child.Data.CreatedUTC),
+                CreatedUTC: child.Data.CreatedUTC,
+                Awards:    parseAwards(ctx, child.Data.AllAwardings),
+            }
+            
+            // Process replies if they exist
+            if len(child.Data.Replies) > 0 {
+                var replies struct {
+                    Data struct {
+                        Children []json.RawMessage `json:"children"`
+                    } `json:"data"`
+                }
+                
+                if err := json.Unmarshal(child.Data.Replies, &replies); err == nil {
+                    comment.Replies = p.processComments(ctx, replies.Data.Children)
+                    
+                    // Check for "more" comments; children that do not decode
+                    // were reported above
+                    for _, raw := range replies.Data.Children {
+                        var replyChild models.RawChild
+                        if json.Unmarshal(raw, &replyChild) != nil {
+                            continue
+                        }
+                        if replyChild.Kind == "more" && len(replyChild.Data.Children) > 0 {
+                            comment.HasMore = true
+                            comment.MoreIDs = append(comment.MoreIDs, replyChild.Data.Children...)
+                        }
+                    }
+                }
+            }
+            
+            comments = append(comments, comment)
+            
+        case "more": // "Load more comments" link
+            if len(child.Data.Children) > 0 {
+                // Check for "continue thread" links
+                shouldSkip := false
+                for _, id := range child.Data.Children {
+                    if id == "continue" {
+                        shouldSkip = true
+                        fmt.Printf("Found 'continue' link in more comments, marked for special handling\n")
+                        break
+                    }
+                }
+                
+                if !shouldSkip {
+                    // Regular "more comments"
+                    moreComment := models.Comment{
+                        ID:        "more_" + uuid.New().String(),
+                        ParentID:  child.Data.ParentID,
+                        IsMore:    true,
+                        MoreIDs:   child.Data.Children,
+                        MoreCount: child.Data.Count,
+                    }
+                    
+                    fmt.Printf("Found 'more' comment with %d child IDs\n", len(child.Data.Children))
+                    comments = append(comments, moreComment)
+                } else {
+                    // Add the "continue" as a special type
+                    continueComment := models.Comment{
+                        ID:        "continue_" + uuid.New().String(),
+                        IsMore:    true,         // Still mark as "more" for compatibility
+                        MoreIDs:   []string{child.Data.ParentID}, // Store parent ID
+                        HasMore:   true,         // Use HasMore flag for "continue" links
+                        MoreCount: child.Data.Count,
+                    }
+                    comments = append(comments, continueComment)
+                    fmt.Printf("Added 'continue' link as special comment type\n")
+                }
+            }
+        }
+    }
+    
+    return comments
+}
\ No newline at end of file
@@ -0,0 +1,120 @@
+// pkg/parser/report.go
+package parser
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"sync"
+
+	"reddit-ingestion/pkg/models"
+)
+
+// maxReportErrors is how many skip reasons a Report keeps as samples
+const maxReportErrors = 5
+
+type reportKey struct{}
+
+// Report collects the entries that parsers skipped because they did not
+// decode, so one post or comment in an unexpected shape does not fail its
+// whole page. It is safe for concurrent use.
+type Report struct {
+	mutex   sync.Mutex
+	skipped int
+	errors  []string
+}
+
+// WithReport makes parsers record the entries they skip while parsing with
+// the returned context in the returned Report
+func WithReport(ctx context.Context) (context.Context, *Report) {
+	report := &Report{}
+	return context.WithValue(ctx, reportKey{}, report), report
+}
+
+// reportFrom returns the Report of ctx, nil if there is none
+func reportFrom(ctx context.Context) *Report {
+	report, _ := ctx.Value(reportKey{}).(*Report)
+	return report
+}
+
+// skip records that an entry of kind and id was left out for err. A nil
+// Report only logs it.
+func (r *Report) skip(kind, id string, err error) {
+	reason := fmt.Sprintf("%s %s: %v", kind, id, err)
+	fmt.Printf("Skipping malformed entry %s\n", reason)
+	if r == nil {
+		return
+	}
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.skipped++
+	if len(r.errors) < maxReportErrors {
+		r.errors = append(r.errors, reason)
+	}
+}
+
+// Result returns what was skipped so far, nil when nothing was
+func (r *Report) Result() *models.ParseReport {
+	if r == nil {
+		return nil
+	}
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if r.skipped == 0 {
+		return nil
+	}
+	return &models.ParseReport{
+		Skipped: r.skipped,
+		Errors:  append([]string(nil), r.errors...),
+	}
+}
+
+// childHeader is read from a listing child before its data, to tell kinds
+// apart and name the child when its data does not decode
+type childHeader struct {
+	Kind string `json:"kind"`
+	Data struct {
+		ID json.RawMessage `json:"id"`
+	} `json:"data"`
+}
+
+// decodeChild decodes the listing child raw into v when its kind is one of
+// kinds, or any kind when none are given. It reports false for other kinds
+// and for children that do not decode, which are recorded in the Report of
+// ctx.
+func decodeChild(ctx context.Context, raw json.RawMessage, v any, kinds ...string) bool {
+	var header childHeader
+	if err := json.Unmarshal(raw, &header); err != nil {
+		reportFrom(ctx).skip(cmp.Or(header.Kind, "entry"), "?", err)
+		return false
+	}
+	if len(kinds) > 0 && !slices.Contains(kinds, header.Kind) {
+		return false
+	}
+	if err := json.Unmarshal(raw, v); err != nil {
+		id := "?"
+		if json.Unmarshal(header.Data.ID, &id) != nil && len(header.Data.ID) > 0 {
+			id = string(header.Data.ID)
+		}
+		reportFrom(ctx).skip(header.Kind, id, err)
+		return false
+	}
+	return true
+}
+
+// things returns the children that have the shape of a thing, recording the
+// others in the Report of ctx
+func things(ctx context.Context, children []json.RawMessage) []json.RawMessage {
+	kept := children[:0:0]
+	for _, raw := range children {
+		var header childHeader
+		if err := json.Unmarshal(raw, &header); err != nil {
+			reportFrom(ctx).skip(cmp.Or(header.Kind, "entry"), "?", err)
+			continue
+		}
+		kept = append(kept, raw)
+	}
+	return kept
+}
@@ -0,0 +1,185 @@
+// pkg/parser/stream.go
+package parser
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+
+	"reddit-ingestion/pkg/models"
+)
+
+// errStopStream ends a listing early once the consumer has what it needs
+var errStopStream = errors.New("stop listing stream")
+
+type listingChild struct {
+	Kind string `json:"kind"`
+	Data struct {
+		ID            string  `json:"id"`
+		Title         string  `json:"title"`
+		Selftext      string  `json:"selftext"`
+		Author        string  `json:"author"`
+		Score         int     `json:"score"`
+		NumComments   int     `json:"num_comments"`
+		CreatedUTC    float64 `json:"created_utc"`
+		Subreddit     string  `json:"subreddit"`
+		SubredditID   string  `json:"subreddit_id"`
+		SubredditSubs int     `json:"subreddit_subscribers"`
+		LinkFlairText string  `json:"link_flair_text"`
+		Over18        bool    `json:"over_18"`
+		Permalink     string  `json:"permalink"`
+		URL           string  `json:"url"`
+		IsSelf        bool    `json:"is_self"`
+
+		CrosspostParent string          `json:"crosspost_parent"`
+		AllAwardings    json.RawMessage `json:"all_awardings"`
+	} `json:"data"`
+}
+
+func (p *RedditParser) listingPost(ctx context.Context, c listingChild) models.Post {
+	return models.Post{
+		ID:          c.Data.ID,
+		Title:       c.Data.Title,
+		Body:        c.Data.Selftext,
+		Author:      c.Data.Author,
+		Score:       c.Data.Score,
+		NumComments: c.Data.NumComments,
+		CreatedAt:   createdAt(c.Data.CreatedUTC),
+		CreatedUTC:  c.Data.CreatedUTC,
+		Flair:       c.Data.LinkFlairText,
+		Category:    p.opts.Categories.Category(c.Data.Subreddit, c.Data.LinkFlairText),
+		NSFW:        c.Data.Over18,
+		URL:         p.postURL(c.Data.Permalink),
+		LinkURL:     linkURL(c.Data.IsSelf, c.Data.URL),
+		SourceHost:  p.opts.SourceHost,
+
+		Subreddit:            c.Data.Subreddit,
+		SubredditID:          c.Data.SubredditID,
+		SubredditSubscribers: c.Data.SubredditSubs,
+		CrosspostParent:      c.Data.CrosspostParent,
+		Awards:               parseAwards(ctx, c.Data.AllAwardings),
+	}
+}
+
+// linkURL is the address a link post points to, empty for text posts, whose
+// url is their own permalink
+func linkURL(isSelf bool, url string) string {
+	if isSelf {
+		return ""
+	}
+	return url
+}
+
+// StreamSubreddit decodes a subreddit or search listing from r one post at a
+// time, handing each to emit as soon as it is decoded, so a page never has to
+// be held in memory whole. emit returns false to stop reading the rest of the
+// page. Posts that do not decode are skipped and recorded in the Report of
+// ctx, if any; see WithReport. The returned cursor is empty when the listing ended or was stopped
+// before its after field was read.
+func (p *RedditParser) StreamSubreddit(ctx context.Context, r io.Reader, emit func(models.Post) bool) (string, error) {
+	dec := json.NewDecoder(r)
+	after := ""
+
+	err := walkObject(dec, func(key string) error {
+		if key != "data" {
+			return skipValue(dec)
+		}
+		return walkObject(dec, func(key string) error {
+			switch key {
+			case "after":
+				var cursor *string
+				if err := dec.Decode(&cursor); err != nil {
+					return err
+				}
+				if cursor != nil {
+					after = *cursor
+				}
+				return nil
+
+			case "children":
+				if ok, err := openDelim(dec, '['); !ok {
+					return err
+				}
+				for dec.More() {
+					if err := ctx.Err(); err != nil {
+						return err
+					}
+					// A child that does not decode is skipped, not the page
+					var raw json.RawMessage
+					if err := dec.Decode(&raw); err != nil {
+						return err
+					}
+					var child listingChild
+					if decodeChild(ctx, raw, &child, "t3") && !emit(p.listingPost(ctx, child)) {
+						return errStopStream
+					}
+				}
+				return expectDelim(dec, ']')
+
+			default:
+				return skipValue(dec)
+			}
+		})
+	})
+
+	if errors.Is(err, errStopStream) {
+		return after, nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("parse subreddit JSON: %w", err)
+	}
+	return after, nil
+}
+
+// walkObject reads a JSON object (or null) from dec, calling field for each
+// key with the decoder positioned at that key's value; field must consume it
+func walkObject(dec *json.Decoder, field func(key string) error) error {
+	if ok, err := openDelim(dec, '{'); !ok {
+		return err
+	}
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return err
+		}
+		key, _ := tok.(string)
+		if err := field(key); err != nil {
+			return err
+		}
+	}
+	return expectDelim(dec, '}')
+}
+
+// openDelim reads the opening delimiter of an object or array. It reports
+// false with no error for null, which Reddit sends for empty values.
+func openDelim(dec *json.Decoder, want json.Delim) (bool, error) {
+	tok, err := dec.Token()
+	if err != nil {
+		return false, err
+	}
+	if tok == nil {
+		return false, nil
+	}
+	if delim, ok := tok.(json.Delim); !ok || delim != want {
+		return false, fmt.Errorf("expected %q, got %v", want, tok)
+	}
+	return true, nil
+}
+
+func expectDelim(dec *json.Decoder, want json.Delim) error {
+	tok, err := dec.Token()
+	if err != nil {
+		return err
+	}
+	if delim, ok := tok.(json.Delim); !ok || delim != want {
+		return fmt.Errorf("expected %q, got %v", want, tok)
+	}
+	return nil
+}
+
+func skipValue(dec *json.Decoder) error {
+	var raw json.RawMessage
+	return dec.Decode(&raw)
+}
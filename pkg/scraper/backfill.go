@@ -0,0 +1,161 @@
+// pkg/scraper/backfill.go
+package scraper
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"reddit-ingestion/pkg/models"
+)
+
+// listingCapMin is how many posts a listing must have yielded before its end
+// is taken for Reddit's cap of about 1000 rather than the real end
+const listingCapMin = 900
+
+// HistoryProvider finds the posts of a subreddit created in a time window. It
+// backfills subreddit scrapes whose since_timestamp reaches past the posts
+// Reddit lists; one backed by an archive can be plugged in through
+// ScraperOptions.History.
+type HistoryProvider interface {
+	// SubredditHistory returns the posts of subreddit created from from up to
+	// to, and the parts of that window it could not reach
+	SubredditHistory(ctx context.Context, subreddit string, from, to time.Time) ([]models.Post, []models.TimeRange, error)
+}
+
+// historyFunc backfills one listing from from up to to
+type historyFunc func(ctx context.Context, from, to time.Time) ([]models.Post, []models.TimeRange, error)
+
+// subredditHistory returns the backfill of a subreddit scrape, nil when
+// backfilling is off
+func (s *scraperService) subredditHistory(subreddit string) historyFunc {
+	if !s.opts.Backfill {
+		return nil
+	}
+	provider := s.opts.History
+	if provider == nil {
+		provider = searchHistory{s: s}
+	}
+	return func(ctx context.Context, from, to time.Time) ([]models.Post, []models.TimeRange, error) {
+		return provider.SubredditHistory(ctx, subreddit, from, to)
+	}
+}
+
+// backfillListing hands collector the posts from since_timestamp up to the
+// oldest post listed. A failed backfill reports that whole window as a gap.
+func (s *scraperService) backfillListing(ctx context.Context, name string, history historyFunc, collector *listingCollector) {
+	from := time.Unix(collector.sinceTimestamp, 0)
+	to := collector.oldest
+	fmt.Printf("Listing of %s ended at Reddit's cap at %s, backfilling back to %s\n",
+		name, to.Format(time.RFC3339), from.Format(time.RFC3339))
+
+	posts, gaps, err := history(ctx, from, to)
+	if err != nil {
+		fmt.Printf("Backfilling %s failed: %v\n", name, err)
+		gaps = []models.TimeRange{{From: from, To: to}}
+	}
+	collector.backfill(posts, gaps)
+	fmt.Printf("Backfill of %s added %d posts\n", name, collector.meta.Backfilled)
+}
+
+// backfillSearch is one subreddit search of the default HistoryProvider
+type backfillSearch struct {
+	sort string
+	t    string
+	// The search lists every post newest first, so everything from the
+	// oldest post it reached on is covered
+	chronological bool
+}
+
+// backfillSearches are the searches searchHistory runs. Reddit's search takes
+// no time range and caps each ordering much like a listing, so several
+// orderings are searched and the posts in the window kept.
+var backfillSearches = []backfillSearch{
+	{sort: "new", t: "all", chronological: true},
+	{sort: "top", t: "all"},
+	{sort: "comments", t: "all"},
+	{sort: "top", t: "year"},
+	{sort: "relevance", t: "all"},
+}
+
+// backfillSearchPages bounds each search; 10 pages of 100 is Reddit's cap
+const backfillSearchPages = 10
+
+// searchHistory is the default HistoryProvider, searching Reddit itself. Only
+// the newest-first search can tell how far back it covered the window, so the
+// gap it reports is the part older than that search reached; the other
+// orderings add posts from anywhere in the window.
+type searchHistory struct {
+	s *scraperService
+}
+
+func (h searchHistory) SubredditHistory(ctx context.Context, subreddit string, from, to time.Time) ([]models.Post, []models.TimeRange, error) {
+	seen := make(map[string]bool)
+	var posts []models.Post
+	reached := to
+	failed := 0
+
+	for _, search := range backfillSearches {
+		err := h.search(ctx, subreddit, search, func(post models.Post) {
+			if search.chronological && post.CreatedAt.Before(reached) {
+				reached = post.CreatedAt
+			}
+			if post.CreatedAt.Before(from) || !post.CreatedAt.Before(to) || seen[post.ID] {
+				return
+			}
+			seen[post.ID] = true
+			posts = append(posts, post)
+		})
+		if err == nil {
+			continue
+		}
+		if ctx.Err() != nil {
+			return posts, uncovered(from, reached), ctx.Err()
+		}
+		failed++
+		fmt.Printf("Backfill search %s/%s of r/%s failed, continuing with the others: %v\n", search.sort, search.t, subreddit, err)
+	}
+
+	if failed == len(backfillSearches) {
+		return nil, nil, fmt.Errorf("backfill r/%s: every search failed", subreddit)
+	}
+	return posts, uncovered(from, reached), nil
+}
+
+// search pages one subreddit search, passing each post to take
+func (h searchHistory) search(ctx context.Context, subreddit string, search backfillSearch, take func(models.Post)) error {
+	after := ""
+	for page := 1; page <= backfillSearchPages; page++ {
+		params := map[string]string{
+			"subreddit": subreddit,
+			"sort":      search.sort,
+			"t":         search.t,
+			"limit":     "100",
+			"after":     after,
+		}
+		read := 0
+		next, err := h.s.fetchListingPage(ctx, "backfill search "+search.sort+"/"+search.t, h.s.client.GetSearchURL(params), func(post models.Post) bool {
+			read++
+			take(post)
+			return true
+		})
+		if err != nil {
+			return err
+		}
+		if next == "" || read == 0 {
+			return nil
+		}
+		after = next
+		h.s.pause(ctx, 200*time.Millisecond)
+	}
+	return nil
+}
+
+// uncovered returns the part of the window from from that is older than
+// reached, if any
+func uncovered(from, reached time.Time) []models.TimeRange {
+	if !from.Before(reached) {
+		return nil
+	}
+	return []models.TimeRange{{From: from, To: reached}}
+}
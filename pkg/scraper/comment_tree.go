@@ -0,0 +1,312 @@
+// pkg/scraper/comment_tree.go
+package scraper
+
+import (
+	"fmt"
+	"sort"
+
+	"reddit-ingestion/pkg/models"
+)
+
+// commentTree holds a post's comments while their "load more" links are
+// expanded. Comments and placeholders are indexed by ID, so loaded comments
+// find their parent and placeholder in constant time instead of by walking
+// the tree. The walks left use a stack of their own, so a thread thousands of
+// replies deep does not recurse as deep.
+type commentTree struct {
+	postID string
+	top    []*commentNode
+	// The first node of each ID
+	byID map[string]*commentNode
+	// Nodes with "load more" IDs, in the order they were added; replaced
+	// placeholders are dropped on the next moreSets
+	more []*commentNode
+	// Nodes in the tree, placeholders included
+	size int
+}
+
+// commentNode is a comment in a commentTree. Its replies are held in replies;
+// comment.Replies is only filled in by comments.
+type commentNode struct {
+	comment models.Comment
+	// nil at the top level
+	parent   *commentNode
+	replies  []*commentNode
+	replaced bool
+}
+
+// newCommentTree indexes the comments of the post postID
+func newCommentTree(postID string, comments []models.Comment) *commentTree {
+	t := &commentTree{postID: postID, byID: make(map[string]*commentNode)}
+	for _, comment := range comments {
+		node := &commentNode{comment: comment}
+		t.top = append(t.top, node)
+		t.index(node)
+	}
+	return t
+}
+
+// index adds node and everything below it to the index, turning the replies
+// its comments came with into nodes
+func (t *commentTree) index(node *commentNode) {
+	stack := []*commentNode{node}
+	for len(stack) > 0 {
+		n := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+
+		if _, ok := t.byID[n.comment.ID]; !ok {
+			t.byID[n.comment.ID] = n
+		}
+		t.size++
+		if (n.comment.IsMore || n.comment.HasMore) && len(n.comment.MoreIDs) > 0 {
+			t.more = append(t.more, n)
+		}
+		if len(n.comment.Replies) > 0 {
+			replies := make([]*commentNode, 0, len(n.comment.Replies)+len(n.replies))
+			for _, reply := range n.comment.Replies {
+				replies = append(replies, &commentNode{comment: reply, parent: n})
+			}
+			n.replies = append(replies, n.replies...)
+			n.comment.Replies = nil
+		}
+		stack = append(stack, n.replies...)
+	}
+}
+
+// depth is how many comments node is below the top level
+func (n *commentNode) depth() int {
+	depth := 0
+	for p := n.parent; p != nil; p = p.parent {
+		depth++
+	}
+	return depth
+}
+
+// parentID is the ID of the comment node replies to, or postID at the top
+// level
+func (t *commentTree) parentID(n *commentNode) string {
+	if n.parent == nil {
+		return t.postID
+	}
+	return n.parent.comment.ID
+}
+
+// moreSets are the "load more" sets left in the tree, largest first: the IDs
+// of each placeholder, and of each comment that has more replies than it
+// came with. Placeholders of "continue this thread" links are left out.
+func (t *commentTree) moreSets() []MoreCommentSet {
+	var sets []MoreCommentSet
+	more := t.more[:0]
+	for _, n := range t.more {
+		if n.replaced {
+			continue
+		}
+		more = append(more, n)
+
+		if n.comment.IsMore && !hasContinue(n.comment.MoreIDs) {
+			sets = append(sets, MoreCommentSet{
+				Parent:        t.parentID(n),
+				CommentIDs:    n.comment.MoreIDs,
+				Depth:         n.depth(),
+				PlaceholderID: n.comment.ID,
+			})
+		}
+		if n.comment.HasMore {
+			sets = append(sets, MoreCommentSet{
+				Parent:        n.comment.ID,
+				CommentIDs:    n.comment.MoreIDs,
+				Depth:         n.depth() + 1,
+				PlaceholderID: n.comment.ID + "-more",
+			})
+		}
+	}
+	t.more = more
+
+	sort.SliceStable(sets, func(i, j int) bool {
+		return len(sets[i].CommentIDs) > len(sets[j].CommentIDs)
+	})
+	return sets
+}
+
+func hasContinue(ids []string) bool {
+	for _, id := range ids {
+		if id == "continue" {
+			fmt.Println("Found a 'continue' link, special handling might be needed")
+			return true
+		}
+	}
+	return false
+}
+
+// place puts the comments loaded for set into the tree, each under the
+// parent its parent_id names. Replies returned flat next to their parents are
+// nested under them, and comments already in the tree are left where they
+// are. The rest, and comments without a parent_id, take the place of the
+// set's placeholder, or else go at the end of the set's parent's replies.
+func (t *commentTree) place(set MoreCommentSet, comments []models.Comment) {
+	nodes := make(map[string]*commentNode, len(comments))
+	var fresh []*commentNode
+	for _, comment := range comments {
+		if _, ok := t.byID[comment.ID]; ok {
+			continue
+		}
+		if _, ok := nodes[comment.ID]; ok {
+			continue
+		}
+		node := &commentNode{comment: comment}
+		nodes[comment.ID] = node
+		fresh = append(fresh, node)
+	}
+
+	var roots []*commentNode
+	for _, node := range fresh {
+		parent, ok := nodes[parentID(node.comment)]
+		if ok && parent != node && !isAncestor(node, parent) {
+			node.parent = parent
+			parent.replies = append(parent.replies, node)
+		} else {
+			roots = append(roots, node)
+		}
+	}
+
+	var own []*commentNode
+	for _, root := range roots {
+		parent := parentID(root.comment)
+		switch existing := t.byID[parent]; {
+		case parent == "" || parent == set.Parent:
+			own = append(own, root)
+		case parent == t.postID:
+			t.insert(nil, len(t.top), root)
+		case existing != nil && !existing.comment.IsMore:
+			t.insert(existing, len(existing.replies), root)
+		default:
+			fmt.Printf("Parent %s of loaded comment %s is not in the tree, placing it with its set\n", parent, root.comment.ID)
+			own = append(own, root)
+		}
+	}
+	if len(own) == 0 {
+		return
+	}
+
+	var parent *commentNode
+	if set.Depth > 0 {
+		parent = t.byID[set.Parent]
+		if parent != nil && parent.comment.IsMore {
+			parent = nil
+		}
+	}
+	if placeholder := t.byID[set.PlaceholderID]; placeholder != nil && placeholder.comment.IsMore &&
+		placeholder.parent == parent && (set.Depth == 0 || parent != nil) {
+		t.replace(placeholder, own)
+		return
+	}
+	switch {
+	case set.Depth == 0:
+		fmt.Printf("No placeholder found, adding %d comments to top level\n", len(own))
+		t.insert(nil, len(t.top), own...)
+	case parent != nil:
+		t.insert(parent, len(parent.replies), own...)
+	default:
+		// The top level would misplace them
+		fmt.Printf("WARNING: Could not find parent %s, dropping %d comments\n", set.Parent, len(own))
+	}
+}
+
+// isAncestor reports whether node is above n, so making n its parent would
+// close a circle of parent_ids
+func isAncestor(node, n *commentNode) bool {
+	for p := n.parent; p != nil; p = p.parent {
+		if p == node {
+			return true
+		}
+	}
+	return false
+}
+
+// insert puts nodes among the replies of parent, or at the top level when
+// parent is nil, at position at, and indexes them
+func (t *commentTree) insert(parent *commentNode, at int, nodes ...*commentNode) {
+	siblings := &t.top
+	if parent != nil {
+		siblings = &parent.replies
+	}
+	updated := make([]*commentNode, 0, len(*siblings)+len(nodes))
+	updated = append(updated, (*siblings)[:at]...)
+	updated = append(updated, nodes...)
+	*siblings = append(updated, (*siblings)[at:]...)
+	for _, node := range nodes {
+		node.parent = parent
+		t.index(node)
+	}
+}
+
+// replace puts nodes in the place of placeholder
+func (t *commentTree) replace(placeholder *commentNode, nodes []*commentNode) {
+	siblings := t.top
+	if placeholder.parent != nil {
+		siblings = placeholder.parent.replies
+	}
+	for i, sibling := range siblings {
+		if sibling != placeholder {
+			continue
+		}
+		if placeholder.parent != nil {
+			placeholder.parent.replies = append(siblings[:i:i], siblings[i+1:]...)
+		} else {
+			t.top = append(siblings[:i:i], siblings[i+1:]...)
+		}
+		placeholder.replaced = true
+		t.size--
+		if t.byID[placeholder.comment.ID] == placeholder {
+			delete(t.byID, placeholder.comment.ID)
+		}
+		t.insert(placeholder.parent, i, nodes...)
+		return
+	}
+}
+
+// walk calls visit with each node of the tree, parents before their replies
+// and replies in order
+func (t *commentTree) walk(visit func(n *commentNode)) {
+	stack := make([]*commentNode, 0, len(t.top))
+	for i := len(t.top) - 1; i >= 0; i-- {
+		stack = append(stack, t.top[i])
+	}
+	for len(stack) > 0 {
+		n := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		visit(n)
+		for i := len(n.replies) - 1; i >= 0; i-- {
+			stack = append(stack, n.replies[i])
+		}
+	}
+}
+
+// comments is the tree as nested comments, leaving out the placeholders
+// still in it unless placeholders is set
+func (t *commentTree) comments(placeholders bool) []models.Comment {
+	var order []*commentNode
+	t.walk(func(n *commentNode) {
+		order = append(order, n)
+	})
+
+	// Replies are built before the comments they reply to
+	built := make(map[*commentNode]models.Comment, len(order))
+	collect := func(nodes []*commentNode) []models.Comment {
+		var comments []models.Comment
+		for _, n := range nodes {
+			if n.comment.IsMore && !placeholders {
+				continue
+			}
+			comments = append(comments, built[n])
+			delete(built, n)
+		}
+		return comments
+	}
+	for i := len(order) - 1; i >= 0; i-- {
+		comment := order[i].comment
+		comment.Replies = collect(order[i].replies)
+		built[order[i]] = comment
+	}
+	return collect(t.top)
+}
@@ -0,0 +1,39 @@
+// pkg/scraper/completeness.go
+package scraper
+
+import (
+	"math"
+
+	"reddit-ingestion/pkg/models"
+)
+
+// completeness compares the comments of detail with the count Reddit reports
+// for the post. Reddit's count includes removed comments it no longer lists,
+// so a fully expanded tree can still fall a little short of 100.
+func completeness(detail models.PostDetail) *models.CommentCompleteness {
+	c := &models.CommentCompleteness{Reported: detail.Post.NumComments}
+	c.Fetched, c.Unloaded = tallyComments(detail.Comments)
+
+	c.Percent = 100
+	if c.Reported > 0 {
+		percent := float64(c.Fetched) / float64(c.Reported) * 100
+		c.Percent = math.Min(100, math.Round(percent*10)/10)
+	}
+	return c
+}
+
+// tallyComments counts the comments of a tree and sums the counts of the
+// "load more" placeholders left in it
+func tallyComments(comments []models.Comment) (fetched, unloaded int) {
+	for _, comment := range comments {
+		if comment.IsMore {
+			unloaded += comment.MoreCount
+		} else {
+			fetched++
+		}
+		f, u := tallyComments(comment.Replies)
+		fetched += f
+		unloaded += u
+	}
+	return fetched, unloaded
+}
@@ -0,0 +1,158 @@
+// pkg/scraper/crawl_policy.go
+package scraper
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+
+	"reddit-ingestion/pkg/parser"
+	"reddit-ingestion/pkg/utils"
+)
+
+// CrawlPolicy tunes how one subreddit is scraped, e.g. to go easy on a
+// heavily moderated subreddit or skip the comment trees of a high-volume one.
+// Unset fields leave the request and service settings alone.
+type CrawlPolicy struct {
+	// MaxRequestsPerMinute caps the requests of all scrapes of the
+	// subreddit together
+	MaxRequestsPerMinute int `json:"max_requests_per_minute,omitempty"`
+
+	// DefaultLimit is the number of posts a listing scrape without a limit
+	// returns, instead of the first page
+	DefaultLimit int `json:"default_limit,omitempty"`
+
+	// ExpandComments set to false returns posts with only the comments of
+	// their first page, leaving "load more" placeholders in place
+	ExpandComments *bool `json:"expand_comments,omitempty"`
+
+	// IncludeAwards and IncludeRelated override include_awards and
+	// include_related of the requests
+	IncludeAwards  *bool `json:"include_awards,omitempty"`
+	IncludeRelated *bool `json:"include_related,omitempty"`
+}
+
+type defaultLimitKey struct{}
+
+// WithDefaultLimit marks the limit of scrapes with the returned context as
+// the configured default rather than one the caller asked for, so the
+// DefaultLimit of a crawl policy takes precedence over it
+func WithDefaultLimit(ctx context.Context) context.Context {
+	return context.WithValue(ctx, defaultLimitKey{}, true)
+}
+
+// IsDefaultLimit reports whether ctx was marked by WithDefaultLimit
+func IsDefaultLimit(ctx context.Context) bool {
+	defaulted, _ := ctx.Value(defaultLimitKey{}).(bool)
+	return defaulted
+}
+
+// CrawlPolicies holds the crawl policy of each subreddit. Subreddits are
+// matched ignoring case and an r/ prefix. It is safe for concurrent use and
+// can be replaced by a reload; a nil CrawlPolicies has no policies.
+type CrawlPolicies struct {
+	mutex       sync.RWMutex
+	bySubreddit map[string]CrawlPolicy
+	limiters    map[string]*utils.RequestLimiter
+}
+
+// NewCrawlPolicies creates the policies of the given subreddits
+func NewCrawlPolicies(policies map[string]CrawlPolicy) *CrawlPolicies {
+	p := &CrawlPolicies{}
+	p.Set(policies)
+	return p
+}
+
+// Set replaces every policy. Request rates start over.
+func (p *CrawlPolicies) Set(policies map[string]CrawlPolicy) {
+	bySubreddit := make(map[string]CrawlPolicy, len(policies))
+	limiters := make(map[string]*utils.RequestLimiter)
+	for subreddit, policy := range policies {
+		name := normalizePolicySubreddit(subreddit)
+		bySubreddit[name] = policy
+		if limiter := utils.NewRequestLimiter(policy.MaxRequestsPerMinute); limiter != nil {
+			limiters[name] = limiter
+		}
+	}
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	p.bySubreddit = bySubreddit
+	p.limiters = limiters
+}
+
+// For returns the policy of subreddit and whether it has one
+func (p *CrawlPolicies) For(subreddit string) (CrawlPolicy, bool) {
+	if p == nil {
+		return CrawlPolicy{}, false
+	}
+	p.mutex.RLock()
+	defer p.mutex.RUnlock()
+	policy, ok := p.bySubreddit[normalizePolicySubreddit(subreddit)]
+	return policy, ok
+}
+
+func (p *CrawlPolicies) limiter(subreddit string) *utils.RequestLimiter {
+	p.mutex.RLock()
+	defer p.mutex.RUnlock()
+	return p.limiters[normalizePolicySubreddit(subreddit)]
+}
+
+func normalizePolicySubreddit(name string) string {
+	name = strings.ToLower(strings.TrimSpace(name))
+	name = strings.TrimPrefix(name, "/")
+	return strings.TrimPrefix(name, "r/")
+}
+
+// ReadCrawlPolicies reads policies from a JSON file of the form
+// {"askreddit": {"max_requests_per_minute": 30, "expand_comments": false}}.
+// An empty path means no policies.
+func ReadCrawlPolicies(path string) (map[string]CrawlPolicy, error) {
+	if path == "" {
+		return nil, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read crawl policies: %w", err)
+	}
+	var policies map[string]CrawlPolicy
+	if err := json.Unmarshal(data, &policies); err != nil {
+		return nil, fmt.Errorf("parse crawl policies %s: %w", path, err)
+	}
+	for subreddit, policy := range policies {
+		if normalizePolicySubreddit(subreddit) == "" {
+			return nil, fmt.Errorf("crawl policies: subreddit names must not be empty")
+		}
+		if policy.MaxRequestsPerMinute < 0 || policy.DefaultLimit < -1 {
+			return nil, fmt.Errorf("crawl policy of %s: max_requests_per_minute must not be negative and default_limit not below -1", subreddit)
+		}
+	}
+	return policies, nil
+}
+
+// withCrawlPolicy applies the policy of subreddit to a scrape with ctx: its
+// request rate and enrichment toggles. It also returns the policy for the
+// settings the scrape applies itself.
+func (s *scraperService) withCrawlPolicy(ctx context.Context, subreddit string) (context.Context, CrawlPolicy) {
+	policy, ok := s.opts.Policies.For(subreddit)
+	if !ok {
+		return ctx, policy
+	}
+	if limiter := s.opts.Policies.limiter(subreddit); limiter != nil {
+		ctx = utils.WithRequestLimiter(ctx, limiter)
+	}
+	if policy.IncludeAwards != nil {
+		if *policy.IncludeAwards {
+			ctx = parser.WithAwards(ctx)
+		} else {
+			ctx = parser.WithoutAwards(ctx)
+		}
+	}
+	if policy.IncludeRelated != nil {
+		ctx = context.WithValue(ctx, relatedKey{}, *policy.IncludeRelated)
+	}
+	return ctx, policy
+}
@@ -0,0 +1,69 @@
+// pkg/scraper/empty_page.go
+package scraper
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+
+	"reddit-ingestion/pkg/client"
+	"reddit-ingestion/pkg/utils"
+)
+
+// emptyPageMinBytes is the smallest body that is suspicious when it parses to
+// no items. The last page of a listing is a bare envelope of a few hundred
+// bytes; anything larger that holds nothing is most likely an interstitial or
+// a throttled response served with a 200.
+const emptyPageMinBytes = 1024
+
+// retryEmptyPages runs fetch, which fetches and parses one page and returns
+// how many items it held and the size of its body. A page that parsed to no
+// items from a body of at least emptyPageMinBytes is fetched again through
+// another proxy, bypassing caches, up to EmptyPageRetries times, so it does
+// not end pagination as if the listing were exhausted. A page Reddit refused
+// with 403, which it answers proxies it blocks with, is fetched once more
+// right away; the client has already moved the session to another proxy.
+// fetch must not keep anything from an empty page.
+func (s *scraperService) retryEmptyPages(ctx context.Context, what string, fetch func(ctx context.Context) (items, size int, err error)) error {
+	refused := false
+	for attempt := 1; ; attempt++ {
+		items, size, err := fetch(ctx)
+		if utils.UpstreamStatus(err) == http.StatusForbidden && !refused && ctx.Err() == nil {
+			fmt.Printf("%s page was refused with 403, refetching through another proxy\n", what)
+			refused = true
+			attempt--
+			continue
+		}
+		if err != nil || items > 0 || size < emptyPageMinBytes || attempt > s.opts.EmptyPageRetries || ctx.Err() != nil {
+			return err
+		}
+
+		fmt.Printf("%s page parsed to nothing from %d bytes, refetching through another proxy (%d/%d)\n",
+			what, size, attempt, s.opts.EmptyPageRetries)
+		if session := utils.ProxySessionFromContext(ctx); session != nil {
+			session.Rotate()
+		}
+		ctx = client.WithRefetch(ctx)
+	}
+}
+
+// listingGone reports whether err means that page, counting from 1, is no
+// longer there. Reddit answers 404 for a cursor that fell out of the listing
+// while it was paged, which ends the walk rather than failing it; a 404 for
+// the first page is an error.
+func listingGone(err error, page int) bool {
+	return page > 1 && utils.UpstreamStatus(err) == http.StatusNotFound
+}
+
+// countingReader counts the bytes read through it
+type countingReader struct {
+	r io.Reader
+	n int
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += n
+	return n, err
+}
@@ -0,0 +1,88 @@
+// pkg/scraper/expansion.go
+package scraper
+
+import (
+	"context"
+	"fmt"
+)
+
+// expansionRequestsPerProxy is how many morechildren requests a post
+// scrape keeps in flight per proxy at most
+const expansionRequestsPerProxy = 2
+
+// ExpansionOptions sizes the worker pools that expand "load more" comments
+type ExpansionOptions struct {
+	// Workers is how many "load more" sets, or groups of small ones sharing
+	// a morechildren call, are fetched at once
+	Workers int
+
+	// BatchSize is how many "load more" sets are taken per round; the rest
+	// wait for the next round
+	BatchSize int
+
+	// Concurrency is how many morechildren requests of one set run at once
+	Concurrency int
+}
+
+// DefaultExpansionOptions returns the pool sizes used when none are configured
+func DefaultExpansionOptions() ExpansionOptions {
+	return ExpansionOptions{
+		Workers:     3,
+		BatchSize:   15,
+		Concurrency: 2,
+	}
+}
+
+type expansionKey struct{}
+
+// WithExpansion overrides the expansion pool sizes for scrapes with the
+// returned context; zero fields keep the service's. The sizes are still
+// clamped to the proxies available.
+func WithExpansion(ctx context.Context, opts ExpansionOptions) context.Context {
+	return context.WithValue(ctx, expansionKey{}, opts)
+}
+
+// expansion returns the pool sizes for a scrape with ctx
+func (s *scraperService) expansion(ctx context.Context) ExpansionOptions {
+	opts := s.opts.Expansion
+	if override, ok := ctx.Value(expansionKey{}).(ExpansionOptions); ok {
+		if override.Workers > 0 {
+			opts.Workers = override.Workers
+		}
+		if override.BatchSize > 0 {
+			opts.BatchSize = override.BatchSize
+		}
+		if override.Concurrency > 0 {
+			opts.Concurrency = override.Concurrency
+		}
+	}
+	return clampExpansion(opts, s.opts.Proxies)
+}
+
+// clampExpansion fills in unset sizes and keeps the requests in flight,
+// workers times concurrency, within expansionRequestsPerProxy per proxy. The
+// default pool sizes are always allowed, so a single proxy or a direct
+// connection keeps the throughput it had before the sizes were configurable.
+func clampExpansion(opts ExpansionOptions, proxies int) ExpansionOptions {
+	defaults := DefaultExpansionOptions()
+	if opts.Workers < 1 {
+		opts.Workers = defaults.Workers
+	}
+	if opts.BatchSize < 1 {
+		opts.BatchSize = defaults.BatchSize
+	}
+	if opts.Concurrency < 1 {
+		opts.Concurrency = defaults.Concurrency
+	}
+
+	limit := max(proxies*expansionRequestsPerProxy, defaults.Workers*defaults.Concurrency)
+	if opts.Workers*opts.Concurrency <= limit {
+		return opts
+	}
+	requested := opts
+	opts.Concurrency = min(opts.Concurrency, limit)
+	opts.Workers = max(1, min(opts.Workers, limit/opts.Concurrency))
+	fmt.Printf("Expansion pool of %d workers x %d requests exceeds %d for %d proxies, using %d x %d\n",
+		requested.Workers, requested.Concurrency, limit, proxies, opts.Workers, opts.Concurrency)
+	return opts
+}
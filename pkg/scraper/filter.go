@@ -0,0 +1,267 @@
+// pkg/scraper/filter.go
+package scraper
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"reddit-ingestion/pkg/models"
+)
+
+// TextMatcher matches a post field exactly or by substring, ignoring case, or
+// by regular expression
+type TextMatcher struct {
+	text     string
+	contains bool
+	pattern  *regexp.Regexp
+}
+
+// ExactMatcher matches text equal to s, ignoring case
+func ExactMatcher(s string) TextMatcher {
+	return TextMatcher{text: s}
+}
+
+// ContainsMatcher matches text containing s, ignoring case
+func ContainsMatcher(s string) TextMatcher {
+	return TextMatcher{text: strings.ToLower(s), contains: true}
+}
+
+// RegexMatcher matches text containing a match of expr; anchor it with ^ and
+// $ to match whole values, and prefix (?i) to ignore case
+func RegexMatcher(expr string) (TextMatcher, error) {
+	pattern, err := regexp.Compile(expr)
+	if err != nil {
+		return TextMatcher{}, fmt.Errorf("invalid pattern %q: %w", expr, err)
+	}
+	return TextMatcher{pattern: pattern}, nil
+}
+
+// NewTextMatcher returns RegexMatcher(value) when regex is set and
+// ExactMatcher(value) otherwise
+func NewTextMatcher(value string, regex bool) (TextMatcher, error) {
+	if regex {
+		return RegexMatcher(value)
+	}
+	return ExactMatcher(value), nil
+}
+
+// NewContainsMatcher returns RegexMatcher(value) when regex is set and
+// ContainsMatcher(value) otherwise
+func NewContainsMatcher(value string, regex bool) (TextMatcher, error) {
+	if regex {
+		return RegexMatcher(value)
+	}
+	return ContainsMatcher(value), nil
+}
+
+// Match reports whether s matches
+func (m TextMatcher) Match(s string) bool {
+	switch {
+	case m.pattern != nil:
+		return m.pattern.MatchString(s)
+	case m.contains:
+		return strings.Contains(strings.ToLower(s), m.text)
+	default:
+		return strings.EqualFold(s, m.text)
+	}
+}
+
+// String describes what m matches, e.g. contains:rust, so equal matchers
+// describe themselves the same
+func (m TextMatcher) String() string {
+	switch {
+	case m.pattern != nil:
+		return "regex:" + m.pattern.String()
+	case m.contains:
+		return "contains:" + m.text
+	default:
+		return "exact:" + strings.ToLower(m.text)
+	}
+}
+
+// NSFWMode selects posts by their NSFW (over_18) mark
+type NSFWMode string
+
+const (
+	// NSFWInclude keeps posts whether or not they are NSFW
+	NSFWInclude NSFWMode = "include"
+	// NSFWExclude drops NSFW posts
+	NSFWExclude NSFWMode = "exclude"
+	// NSFWOnly keeps only NSFW posts
+	NSFWOnly NSFWMode = "only"
+)
+
+// ParseNSFWMode validates a user supplied mode; empty means include. yes and
+// no, the values of Reddit's nsfw: search operator, mean only and exclude.
+func ParseNSFWMode(s string) (NSFWMode, error) {
+	switch m := NSFWMode(strings.ToLower(strings.TrimSpace(s))); m {
+	case NSFWInclude, NSFWExclude, NSFWOnly:
+		return m, nil
+	case "":
+		return NSFWInclude, nil
+	case "yes":
+		return NSFWOnly, nil
+	case "no":
+		return NSFWExclude, nil
+	default:
+		return "", fmt.Errorf("unsupported nsfw mode %q, must be include, exclude or only", s)
+	}
+}
+
+// PostFilter keeps the posts of a listing that match it. The scraper applies
+// it while paging, so a limit counts matching posts rather than posts read,
+// and a since_timestamp cutoff still ends paging at the first older post
+// whether or not that post matches. The zero value keeps every post.
+//
+// A post is kept when it matches one matcher of every non-empty include
+// filter and none of ExcludeFlair. On user activity, comments are matched by
+// their body and the title of the post they were made on.
+type PostFilter struct {
+	// Keep only posts whose flair matches one of these
+	Flair []TextMatcher
+	// Drop posts whose flair matches one of these
+	ExcludeFlair []TextMatcher
+	// Keep only posts by an author matching one of these
+	Author []TextMatcher
+	// Keep only posts whose title matches one of these
+	TitleContains []TextMatcher
+	// Keep only posts whose body matches one of these
+	BodyContains []TextMatcher
+	// Drop NSFW posts or keep only them; empty keeps both
+	NSFW NSFWMode
+}
+
+// Match reports whether the filter keeps post
+func (f PostFilter) Match(post models.Post) bool {
+	return f.check(postItem(post), nil)
+}
+
+// IsZero reports whether the filter keeps everything
+func (f PostFilter) IsZero() bool {
+	return len(f.Flair) == 0 && len(f.ExcludeFlair) == 0 && len(f.Author) == 0 &&
+		len(f.TitleContains) == 0 && len(f.BodyContains) == 0 &&
+		(f.NSFW == "" || f.NSFW == NSFWInclude)
+}
+
+// filterItem is the text of a post or comment that filters look at
+type filterItem struct {
+	author, title, body, flair string
+	nsfw                       bool
+}
+
+func postItem(post models.Post) filterItem {
+	return filterItem{author: post.Author, title: post.Title, body: post.Body, flair: post.Flair, nsfw: post.NSFW}
+}
+
+func userPostItem(post models.UserPost) filterItem {
+	return filterItem{title: post.Title, body: post.Body, flair: post.Flair, nsfw: post.NSFW}
+}
+
+func userCommentItem(comment models.UserComment) filterItem {
+	return filterItem{title: comment.PostTitle, body: comment.Body}
+}
+
+// check reports whether the filter keeps item. Every filter is tried so that
+// matches, when not nil, counts each filter that matched by parameter name;
+// the nsfw filter counts the NSFW items it saw.
+func (f PostFilter) check(item filterItem, matches map[string]int) bool {
+	keep := true
+	for _, c := range []struct {
+		name     string
+		matchers []TextMatcher
+		text     string
+		exclude  bool
+	}{
+		{"flair", f.Flair, item.flair, false},
+		{"exclude_flair", f.ExcludeFlair, item.flair, true},
+		{"author", f.Author, item.author, false},
+		{"title_contains", f.TitleContains, item.title, false},
+		{"body_contains", f.BodyContains, item.body, false},
+	} {
+		if len(c.matchers) == 0 {
+			continue
+		}
+		matched := matchAny(c.matchers, c.text)
+		if matched && matches != nil {
+			matches[c.name]++
+		}
+		if matched == c.exclude {
+			keep = false
+		}
+	}
+	if f.NSFW == NSFWExclude || f.NSFW == NSFWOnly {
+		if item.nsfw && matches != nil {
+			matches["nsfw"]++
+		}
+		if item.nsfw != (f.NSFW == NSFWOnly) {
+			keep = false
+		}
+	}
+	return keep
+}
+
+func matchAny(matchers []TextMatcher, s string) bool {
+	for _, m := range matchers {
+		if m.Match(s) {
+			return true
+		}
+	}
+	return false
+}
+
+// itemFilter applies a PostFilter over one scrape, counting what it left out
+// and what each filter matched for the response meta
+type itemFilter struct {
+	filter      PostFilter
+	filteredOut int
+	matches     map[string]int
+}
+
+func newItemFilter(filter PostFilter) *itemFilter {
+	f := &itemFilter{filter: filter}
+	if !filter.IsZero() {
+		f.matches = make(map[string]int)
+	}
+	return f
+}
+
+// active reports whether the filter can leave anything out
+func (f *itemFilter) active() bool {
+	return f.matches != nil
+}
+
+func (f *itemFilter) keep(item filterItem) bool {
+	if !f.active() {
+		return true
+	}
+	if f.filter.check(item, f.matches) {
+		return true
+	}
+	f.filteredOut++
+	return false
+}
+
+// merge adds the counts of other, e.g. those of the comments to the posts'
+func (f *itemFilter) merge(other *itemFilter) {
+	f.filteredOut += other.filteredOut
+	for name, n := range other.matches {
+		f.matches[name] += n
+	}
+}
+
+type activityFilterKey struct{}
+
+// WithActivityFilter makes user activity scrapes with the returned context
+// keep only the posts and comments filter matches
+func WithActivityFilter(ctx context.Context, filter PostFilter) context.Context {
+	return context.WithValue(ctx, activityFilterKey{}, filter)
+}
+
+// ActivityFilterFromContext returns the filter set by WithActivityFilter, if
+// any
+func ActivityFilterFromContext(ctx context.Context) PostFilter {
+	filter, _ := ctx.Value(activityFilterKey{}).(PostFilter)
+	return filter
+}
@@ -0,0 +1,40 @@
+// pkg/scraper/frontpage.go
+package scraper
+
+import (
+	"context"
+
+	"reddit-ingestion/pkg/models"
+)
+
+// Feeds ScrapeFrontpage can read
+const (
+	// The front page a logged-out visitor sees
+	FeedFrontpage = "frontpage"
+	FeedAll       = "all"
+	FeedPopular   = "popular"
+)
+
+// ScrapeFrontpage retrieves posts from the anonymous front page, r/all or
+// r/popular. params carries the listing's sort, t (time range of top and
+// controversial) and geo_filter (r/popular only) as passed to the client.
+// Posts come from many subreddits, so each carries its subreddit.
+func (s *scraperService) ScrapeFrontpage(
+	ctx context.Context,
+	feed string,
+	params map[string]string,
+	limit int,
+	opts ListingOptions,
+) ([]models.Post, models.ListingMeta, error) {
+	pageURL := func(limit int, after string) string {
+		return s.client.GetFrontpageURL(feed, limit, after, params)
+	}
+	name := "the front page"
+	if feed != FeedFrontpage {
+		name = "r/" + feed
+	}
+	ctx, progress := startProgress(ctx, "frontpage")
+	posts, meta, err := s.scrapeListing(ctx, name, pageURL, 0, limit, opts, nil)
+	progress.finish(err)
+	return posts, meta, err
+}
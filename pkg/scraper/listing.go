@@ -0,0 +1,168 @@
+// pkg/scraper/listing.go
+package scraper
+
+import (
+	"sort"
+	"time"
+
+	"reddit-ingestion/pkg/models"
+	"reddit-ingestion/pkg/parser"
+	"reddit-ingestion/pkg/utils"
+)
+
+// listingCollector gathers the posts of one subreddit or search scrape across
+// its pages, dropping repeats and tracking where the listing can resume
+type listingCollector struct {
+	sinceTimestamp int64
+	limit          int
+	filter         *itemFilter
+
+	posts []models.Post
+	seen  map[string]bool
+	meta  models.ListingMeta
+	// ID of the last post read, kept or filtered out
+	last string
+	// Creation time of the oldest post read
+	oldest time.Time
+	// Posts past the listing cap were added, so no cursor resumes the listing
+	backfilled bool
+	// Posts the parser skipped
+	report *parser.Report
+	// Requests sent for the listing, retries included
+	requests *utils.PageCounter
+
+	// State of the page being read: new posts read and posts kept
+	pageRead  int
+	pagePosts int
+	stopped   bool
+}
+
+func newListingCollector(sinceTimestamp int64, limit int, filter PostFilter) *listingCollector {
+	return &listingCollector{
+		sinceTimestamp: sinceTimestamp,
+		limit:          limit,
+		filter:         newItemFilter(filter),
+		seen:           make(map[string]bool),
+	}
+}
+
+func (c *listingCollector) startPage() {
+	c.meta.PagesFetched++
+	c.pageRead = 0
+	c.pagePosts = 0
+	c.stopped = false
+}
+
+// emit takes one post of the current page, filtering by timestamp and the
+// post filter as posts arrive. It returns false once the limit is reached, so
+// the rest of the page is not read.
+func (c *listingCollector) emit(post models.Post) bool {
+	if c.sinceTimestamp > 0 && post.CreatedAt.Unix() < c.sinceTimestamp {
+		c.meta.ReachedTimeCutoff = true
+		return true
+	}
+
+	// Listings shift while they are paged, so a post can show up again
+	if c.seen[post.ID] {
+		c.meta.DuplicatesDropped++
+		return true
+	}
+	c.seen[post.ID] = true
+	c.pageRead++
+	c.last = post.ID
+	if c.oldest.IsZero() || post.CreatedAt.Before(c.oldest) {
+		c.oldest = post.CreatedAt
+	}
+
+	if !c.filter.keep(postItem(post)) {
+		return true
+	}
+
+	c.pagePosts++
+	c.posts = append(c.posts, post)
+	if c.limit > 0 && len(c.posts) >= c.limit {
+		c.stopped = true
+		return false
+	}
+	return true
+}
+
+// finish trims the posts to the limit and fills in the paging meta. next is
+// Reddit's cursor after the last page fetched. The resumable cursor is set
+// whenever the listing has more posts than were returned; it points past the
+// posts the filter left out too, so they are not read again.
+func (c *listingCollector) finish(next string) ([]models.Post, models.ListingMeta) {
+	if c.limit > 0 && len(c.posts) > c.limit {
+		c.posts = c.posts[:c.limit]
+		c.last = c.posts[len(c.posts)-1].ID
+	}
+
+	c.meta.After = next
+	if c.meta.TimedOut {
+		c.truncate(models.TruncatedTimeLimit)
+	}
+	if !c.oldest.IsZero() {
+		oldest := c.oldest
+		c.meta.OldestReached = &oldest
+	}
+	c.meta.FilteredOut = c.filter.filteredOut
+	c.meta.FilterMatches = c.filter.matches
+	c.meta.ParseReport = c.report.Result()
+	c.meta.Retries = c.requests.Retries()
+	more := next != "" || c.stopped
+	if more && !c.meta.ReachedTimeCutoff && !c.backfilled && c.last != "" {
+		c.meta.Cursor = "t3_" + c.last
+	}
+	return c.posts, c.meta
+}
+
+// truncate marks the scrape as cut short for reason, unless it already was
+func (c *listingCollector) truncate(reason string) {
+	if !c.meta.Truncated {
+		c.meta.Truncated = true
+		c.meta.TruncationReason = reason
+	}
+}
+
+// hitListingCap reports whether paging ran to the end of the listing with
+// since_timestamp still further back or no limit, after about as many posts
+// as Reddit lists. next is Reddit's cursor after the last page fetched.
+func (c *listingCollector) hitListingCap(next string) bool {
+	if c.sinceTimestamp == 0 && c.limit != -1 {
+		return false
+	}
+	if next != "" || c.meta.ReachedTimeCutoff || c.meta.TimedOut {
+		return false
+	}
+	if c.limit > 0 && len(c.posts) >= c.limit {
+		return false
+	}
+	return len(c.seen) >= listingCapMin
+}
+
+// backfill takes the posts found past the listing cap, newest first, through
+// the same filters, repeat check and limit as the listed ones. Posts outside
+// since_timestamp up to the oldest post listed are ignored.
+func (c *listingCollector) backfill(posts []models.Post, gaps []models.TimeRange) {
+	sort.SliceStable(posts, func(i, j int) bool {
+		return posts[i].CreatedAt.After(posts[j].CreatedAt)
+	})
+
+	listed := len(c.posts)
+	c.stopped = false
+	for _, post := range posts {
+		if post.CreatedAt.Unix() < c.sinceTimestamp || !post.CreatedAt.Before(c.oldest) {
+			continue
+		}
+		if !c.emit(post) {
+			break
+		}
+	}
+	c.meta.ReachedListingCap = true
+	c.meta.Backfilled = len(c.posts) - listed
+	c.meta.CoverageGaps = gaps
+	c.backfilled = true
+	if len(gaps) > 0 {
+		c.truncate(models.TruncatedListingCap)
+	}
+}
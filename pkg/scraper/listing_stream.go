@@ -0,0 +1,105 @@
+// pkg/scraper/listing_stream.go
+package scraper
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"reddit-ingestion/pkg/client"
+	"reddit-ingestion/pkg/models"
+	"reddit-ingestion/pkg/parser"
+)
+
+// fetchListingPage fetches one subreddit or search listing page and hands its
+// posts to emit in order, returning the cursor of the next page. When both the
+// client and the parser can stream, posts are decoded while the page downloads
+// and emit returning false stops the download; otherwise the page is fetched
+// whole. A page that looks like an interstitial rather than the end of the
+// listing is refetched, see retryEmptyPages. what names the listing in errors,
+// e.g. "subreddit golang".
+func (s *scraperService) fetchListingPage(ctx context.Context, what, apiURL string, emit func(models.Post) bool) (string, error) {
+	var after string
+	err := s.retryEmptyPages(ctx, what, func(ctx context.Context) (int, int, error) {
+		var posts, size int
+		var err error
+		after, posts, size, err = s.fetchListingPageOnce(ctx, what, apiURL, emit)
+		return posts, size, err
+	})
+	return after, err
+}
+
+// fetchListingPageOnce fetches a listing page a single time, returning the
+// cursor of the next page, how many posts the page held and its size
+func (s *scraperService) fetchListingPageOnce(ctx context.Context, what, apiURL string, emit func(models.Post) bool) (string, int, int, error) {
+	skip := 0
+
+	fetcher, canFetch := s.client.(client.StreamingClient)
+	streamer, canParse := s.parser.(parser.SubredditStreamer)
+	if canFetch && canParse {
+		body, err := fetcher.StreamJSON(ctx, apiURL)
+		if err != nil {
+			return "", 0, 0, fmt.Errorf("fetch %s: %w", what, err)
+		}
+
+		emitted := 0
+		counted := &countingReader{r: body}
+		after, err := streamer.StreamSubreddit(ctx, counted, func(post models.Post) bool {
+			emitted++
+			return emit(post)
+		})
+		body.Close()
+		if err == nil || ctx.Err() != nil {
+			return after, emitted, counted.n, err
+		}
+
+		// The download broke off mid-page; fetch it again whole and skip the
+		// posts that were already handed out
+		fmt.Printf("Streaming %s page failed after %d posts, refetching: %v\n", what, emitted, err)
+		skip = emitted
+	}
+
+	data, err := s.client.FetchJSON(ctx, apiURL)
+	if err != nil {
+		return "", 0, 0, fmt.Errorf("fetch %s: %w", what, err)
+	}
+	return s.emitListingPage(ctx, what, data, skip, emit)
+}
+
+// fetchListingPageAhead fetches the listing page at cursor after through
+// pages, which requests the page after it while this one is handed to emit.
+// The page is fetched whole rather than streamed, as its cursor is needed
+// before its posts are read.
+func (s *scraperService) fetchListingPageAhead(ctx context.Context, what string, pages *pagePipeline, after string, emit func(models.Post) bool) (string, error) {
+	var next string
+	err := s.retryEmptyPages(ctx, what, func(ctx context.Context) (int, int, error) {
+		data, err := pages.fetch(ctx, after)
+		if err != nil {
+			return 0, 0, fmt.Errorf("fetch %s: %w", what, err)
+		}
+		var posts, size int
+		next, posts, size, err = s.emitListingPage(ctx, what, data, 0, emit)
+		return posts, size, err
+	})
+	return next, err
+}
+
+// emitListingPage parses a fetched listing page and hands its posts to emit
+// in order, skipping the first skip, returning the cursor of the next page,
+// how many posts the page held and its size
+func (s *scraperService) emitListingPage(ctx context.Context, what string, data json.RawMessage, skip int, emit func(models.Post) bool) (string, int, int, error) {
+	posts, after, err := s.parser.ParseSubreddit(ctx, data)
+	if err != nil {
+		return "", 0, 0, fmt.Errorf("parse %s: %w", what, err)
+	}
+
+	for i, post := range posts {
+		if i < skip {
+			continue
+		}
+		if !emit(post) {
+			break
+		}
+	}
+	return after, len(posts), len(data), nil
+}
@@ -0,0 +1,128 @@
+// pkg/scraper/morechildren.go
+package scraper
+
+import (
+	"strings"
+
+	"reddit-ingestion/pkg/models"
+)
+
+// moreChildrenMaxIDs is how many comment IDs Reddit answers in one
+// morechildren call
+const moreChildrenMaxIDs = 100
+
+// moreSetGroup are "load more" sets of a post fetched with one morechildren
+// call; a set too large for one call is a group of its own
+type moreSetGroup struct {
+	Sets []MoreCommentSet
+	// Positions of Sets among the sets of the round, so results are placed
+	// in the order the sets were found
+	Indexes []int
+}
+
+// IDs are the comment IDs of every set of g
+func (g moreSetGroup) IDs() []string {
+	var ids []string
+	for _, set := range g.Sets {
+		ids = append(ids, set.CommentIDs...)
+	}
+	return ids
+}
+
+// coalesceMoreSets packs the sets of a post into groups of at most
+// moreChildrenMaxIDs IDs, each set into the first group it fits in, so the
+// many small "load more" sets of a big thread share morechildren calls. Sets
+// without IDs are left out.
+func coalesceMoreSets(sets []MoreCommentSet) []moreSetGroup {
+	var groups []moreSetGroup
+	var sizes []int
+	for i, set := range sets {
+		size := len(set.CommentIDs)
+		if size == 0 {
+			continue
+		}
+		placed := false
+		for g := range groups {
+			if sizes[g]+size <= moreChildrenMaxIDs {
+				groups[g].Sets = append(groups[g].Sets, set)
+				groups[g].Indexes = append(groups[g].Indexes, i)
+				sizes[g] += size
+				placed = true
+				break
+			}
+		}
+		if !placed {
+			groups = append(groups, moreSetGroup{Sets: []MoreCommentSet{set}, Indexes: []int{i}})
+			sizes = append(sizes, size)
+		}
+	}
+	return groups
+}
+
+// routeMoreComments splits the comments a morechildren call returned among
+// the sets that asked for them: a requested comment goes to its set and a
+// reply to the set of its parent, following parent_id up the returned
+// comments, in whatever order they came. A comment leading to none of them
+// goes to the set whose parent is its own, or else to the first set.
+func routeMoreComments(sets []MoreCommentSet, comments []models.Comment) [][]models.Comment {
+	routed := make([][]models.Comment, len(sets))
+	if len(sets) == 1 {
+		routed[0] = comments
+		return routed
+	}
+
+	owner := make(map[string]int)
+	byParent := make(map[string]int)
+	for i, set := range sets {
+		for _, id := range set.CommentIDs {
+			owner[strings.TrimPrefix(id, "t1_")] = i
+		}
+		if _, ok := byParent[set.Parent]; !ok {
+			byParent[set.Parent] = i
+		}
+	}
+	parents := make(map[string]string, len(comments))
+	for _, comment := range comments {
+		parents[comment.ID] = parentID(comment)
+	}
+
+	// setOf follows parent_ids up to a requested comment, remembering the
+	// set of each comment on the way
+	setOf := func(id string) (int, bool) {
+		var chain []string
+		for len(chain) <= len(comments) {
+			if i, ok := owner[id]; ok {
+				for _, c := range chain {
+					owner[c] = i
+				}
+				return i, true
+			}
+			parent, ok := parents[id]
+			if !ok {
+				break
+			}
+			chain = append(chain, id)
+			id = parent
+		}
+		return 0, false
+	}
+
+	for _, comment := range comments {
+		i, ok := setOf(comment.ID)
+		if !ok {
+			// The first set when no set shares its parent either
+			i = byParent[parentID(comment)]
+		}
+		routed[i] = append(routed[i], comment)
+	}
+	return routed
+}
+
+// parentID is the ID of the comment or post comment replies to, without its
+// t1_ or t3_ prefix
+func parentID(comment models.Comment) string {
+	if _, id, ok := strings.Cut(comment.ParentID, "_"); ok {
+		return id
+	}
+	return comment.ParentID
+}
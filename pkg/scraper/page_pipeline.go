@@ -0,0 +1,113 @@
+// pkg/scraper/page_pipeline.go
+package scraper
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+)
+
+// pagePipeline fetches the pages of a listing one page ahead. Reddit only
+// hands out the cursor of the next page with the current one, so as soon as
+// a page arrives its cursor is read and the next page requested, and that
+// page downloads while the caller parses and filters the current one. The
+// caller asks for pages by cursor as before; a page requested ahead that the
+// walk never asks for, because it stopped or an empty page was refetched, is
+// cancelled.
+type pagePipeline struct {
+	s    *scraperService
+	ctx  context.Context
+	url  func(after string) string
+	pace time.Duration
+
+	// ahead is how many more pages may still be requested ahead, so the walk
+	// never fetches past its page budget
+	ahead int
+	next  *pageAhead
+}
+
+// pageAhead is a page requested before the walk asked for it
+type pageAhead struct {
+	after  string
+	cancel context.CancelFunc
+	done   chan struct{}
+	data   json.RawMessage
+	err    error
+}
+
+// newPagePipeline creates a pipeline over the listing at url, requesting at
+// most ahead pages ahead of the walk, each after a pause of pace. Pages
+// requested ahead use ctx, not the context of a refetch, so they do not bypass
+// caches or pin a rotated proxy. The caller must stop the pipeline when the
+// walk ends.
+func (s *scraperService) newPagePipeline(ctx context.Context, url func(after string) string, ahead int, pace time.Duration) *pagePipeline {
+	return &pagePipeline{s: s, ctx: ctx, url: url, ahead: ahead, pace: pace}
+}
+
+// fetch returns the page at cursor after, waiting for it if it was requested
+// ahead, and requests the page after it
+func (p *pagePipeline) fetch(ctx context.Context, after string) (json.RawMessage, error) {
+	var data json.RawMessage
+	var err error
+	if next := p.next; next != nil && next.after == after {
+		p.next = nil
+		select {
+		case <-next.done:
+			data, err = next.data, next.err
+		case <-ctx.Done():
+			next.cancel()
+			return nil, ctx.Err()
+		}
+		next.cancel()
+	} else {
+		p.stop()
+		data, err = p.s.client.FetchJSON(ctx, p.url(after))
+	}
+
+	if err == nil && p.ahead > 0 {
+		if cursor := listingCursor(data); cursor != "" {
+			p.request(cursor)
+		}
+	}
+	return data, err
+}
+
+// request starts fetching the page at cursor after in the background
+func (p *pagePipeline) request(after string) {
+	p.ahead--
+	ctx, cancel := context.WithCancel(p.ctx)
+	next := &pageAhead{after: after, cancel: cancel, done: make(chan struct{})}
+	go func() {
+		defer close(next.done)
+		if p.pace > 0 {
+			p.s.pause(ctx, p.pace)
+		}
+		if next.err = ctx.Err(); next.err != nil {
+			return
+		}
+		next.data, next.err = p.s.client.FetchJSON(ctx, p.url(after))
+	}()
+	p.next = next
+}
+
+// stop cancels the page requested ahead, if any
+func (p *pagePipeline) stop() {
+	if p.next != nil {
+		p.next.cancel()
+		p.next = nil
+	}
+}
+
+// listingCursor reads the after cursor of a listing page without parsing its
+// items. A page that is not a listing has no cursor.
+func listingCursor(data json.RawMessage) string {
+	var listing struct {
+		Data struct {
+			After string `json:"after"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(data, &listing); err != nil {
+		return ""
+	}
+	return listing.Data.After
+}
@@ -0,0 +1,123 @@
+// pkg/scraper/progress.go
+package scraper
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Stages of a scrape as reported in Progress
+const (
+	StageFetchPages     = "fetch_pages"
+	StageFetchPost      = "fetch_post"
+	StageExpandComments = "expand_comments"
+	StageDone           = "done"
+)
+
+// Progress reports how far a scrape has got. Listing and user scrapes report
+// each page they fetch; post scrapes also report their comments as they are
+// expanded.
+type Progress struct {
+	// Method reporting: subreddit, search, frontpage, user, overview or post
+	Operation string `json:"operation"`
+	Stage     string `json:"stage"`
+	// Requests made to Reddit: listing pages, or the post page and every
+	// morechildren batch
+	PagesFetched int `json:"pages_fetched"`
+	// Comments in the tree so far
+	Comments int `json:"comments"`
+	// Comments added by expanding "load more" placeholders
+	CommentsExpanded int `json:"comments_expanded"`
+	// "load more" comment IDs found so far and how many were requested;
+	// new placeholders can turn up as replies are expanded
+	MoreIDsTotal    int `json:"more_ids_total"`
+	MoreIDsResolved int `json:"more_ids_resolved"`
+	// Time since the scrape started
+	ElapsedMS int64   `json:"elapsed_ms"`
+	Percent   float64 `json:"percent"`
+}
+
+// ProgressFunc receives progress updates in order; it is called on the
+// scraping goroutines, one call at a time, and should return quickly
+type ProgressFunc func(Progress)
+
+type progressFuncKey struct{}
+
+// WithProgress makes every ScraperService call with the returned context
+// report its progress to fn, so callers can show a scrape moving without
+// reading its logs
+func WithProgress(ctx context.Context, fn ProgressFunc) context.Context {
+	return context.WithValue(ctx, progressFuncKey{}, fn)
+}
+
+type progressKey struct{}
+
+// progress accumulates a scrape's progress and reports each change. A nil
+// *progress ignores all updates, so scrapes nobody watches pay nothing.
+type progress struct {
+	mutex   sync.Mutex
+	report  ProgressFunc
+	started time.Time
+	state   Progress
+}
+
+// startProgress starts tracking operation for the ProgressFunc of ctx. The
+// returned context carries the tracker for the parts of the scrape; it is nil
+// when nobody watches.
+func startProgress(ctx context.Context, operation string) (context.Context, *progress) {
+	fn, _ := ctx.Value(progressFuncKey{}).(ProgressFunc)
+	if fn == nil {
+		return ctx, nil
+	}
+	p := &progress{
+		report:  fn,
+		started: time.Now(),
+		state:   Progress{Operation: operation},
+	}
+	return context.WithValue(ctx, progressKey{}, p), p
+}
+
+func progressFrom(ctx context.Context) *progress {
+	p, _ := ctx.Value(progressKey{}).(*progress)
+	return p
+}
+
+// update applies change and reports the new state
+func (p *progress) update(change func(*Progress)) {
+	if p == nil {
+		return
+	}
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	change(&p.state)
+	p.state.ElapsedMS = time.Since(p.started).Milliseconds()
+	switch {
+	case p.state.MoreIDsTotal > 0:
+		p.state.Percent = float64(p.state.MoreIDsResolved) * 100 / float64(p.state.MoreIDsTotal)
+	case p.state.Stage == StageDone:
+		p.state.Percent = 100
+	}
+	p.report(p.state)
+}
+
+func (p *progress) pageFetched() {
+	p.update(func(state *Progress) { state.PagesFetched++ })
+}
+
+// listingPageFetched reports a page of a listing or user scrape
+func (p *progress) listingPageFetched() {
+	p.update(func(state *Progress) {
+		state.Stage = StageFetchPages
+		state.PagesFetched++
+	})
+}
+
+// finish reports a listing or user scrape done, unless it failed with err
+func (p *progress) finish(err error) {
+	if err != nil {
+		return
+	}
+	p.update(func(state *Progress) { state.Stage = StageDone })
+}
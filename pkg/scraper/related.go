@@ -0,0 +1,50 @@
+// pkg/scraper/related.go
+package scraper
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"reddit-ingestion/pkg/models"
+)
+
+type relatedKey struct{}
+
+// WithRelated makes post scrapes with the returned context also fetch the
+// post's related posts: Reddit's "other discussions" of the same link, in
+// other subreddits or by other authors. Crossposts are among them and name the
+// post they crosspost in CrosspostParent.
+func WithRelated(ctx context.Context) context.Context {
+	return context.WithValue(ctx, relatedKey{}, true)
+}
+
+// IncludesRelated reports whether ctx was marked by WithRelated
+func IncludesRelated(ctx context.Context) bool {
+	include, _ := ctx.Value(relatedKey{}).(bool)
+	return include
+}
+
+// fetchRelated fetches the other discussions of postID. The page holds two
+// listings, the post itself and the other submissions of its link; self
+// posts have none.
+func (s *scraperService) fetchRelated(ctx context.Context, postID string) ([]models.Post, error) {
+	data, err := s.client.FetchJSON(ctx, s.client.GetDuplicatesURL(postID))
+	if err != nil {
+		return nil, fmt.Errorf("fetch related posts: %w", err)
+	}
+
+	var raw []json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("invalid related posts JSON format: %w", err)
+	}
+	if len(raw) < 2 {
+		return nil, fmt.Errorf("invalid related posts JSON format: expected 2 listings, got %d", len(raw))
+	}
+
+	related, _, err := s.parser.ParseSubreddit(ctx, raw[1])
+	if err != nil {
+		return nil, fmt.Errorf("parse related posts: %w", err)
+	}
+	return related, nil
+}
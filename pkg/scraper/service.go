@@ -0,0 +1,1520 @@
+// pkg/scraper/service.go
+package scraper
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"reddit-ingestion/pkg/client"
+	"reddit-ingestion/pkg/models"
+	"reddit-ingestion/pkg/parser"
+	"reddit-ingestion/pkg/utils"
+)
+
+// ScraperService defines the interface for scraping Reddit content
+type ScraperService interface {
+	ScrapeSubreddit(ctx context.Context, subreddit string, sinceTimestamp int64, limit int, opts ListingOptions) ([]models.Post, models.ListingMeta, error)
+	ScrapeUserActivity(ctx context.Context, username string, sinceTimestamp int64, postLimit, commentLimit int) (models.UserActivity, error)
+	ScrapeUserOverview(ctx context.Context, username string, sinceTimestamp int64, limit int) (models.UserOverview, error)
+	ScrapePost(ctx context.Context, postID string) (models.PostDetail, error)
+	Search(ctx context.Context, searchParams map[string]string, sinceTimestamp int64, limit int, opts ListingOptions) ([]models.Post, models.ListingMeta, error)
+	ScrapeFrontpage(ctx context.Context, feed string, params map[string]string, limit int, opts ListingOptions) ([]models.Post, models.ListingMeta, error)
+}
+
+// ListingOptions controls where a subreddit or search listing starts
+type ListingOptions struct {
+	// After resumes the listing after this post fullname (t3_...), usually
+	// the cursor of an earlier response that stopped short
+	After string
+
+	// Filter keeps only the posts it matches; see PostFilter
+	Filter PostFilter
+}
+
+// ScraperOptions tunes how the scraper spreads work across requests
+type ScraperOptions struct {
+	// UserWindowWorkers bounds how many listing windows of a user's posts or
+	// comments are paged at once for full-history scrapes (limit -1, no
+	// since_timestamp). 1 keeps the single newest-first walk.
+	UserWindowWorkers int
+
+	// StickyProxySessions pins all fetches of one scrape operation to a single
+	// proxy and TLS fingerprint, rotating only when a fetch fails. When false
+	// every request picks its own proxy.
+	StickyProxySessions bool
+
+	// EmptyPageRetries is how many times a listing page that parsed to nothing
+	// from a sizeable body is refetched through another proxy before it is
+	// taken as the end of the listing. 0 disables the retries.
+	EmptyPageRetries int
+
+	// Backfill searches for the older posts of a subreddit scrape whose
+	// since_timestamp reaches past the roughly 1000 posts Reddit lists
+	Backfill bool
+
+	// History finds those older posts; nil searches Reddit for them
+	History HistoryProvider
+
+	// Expansion sizes the worker pools that expand "load more" comments;
+	// requests can override it with WithExpansion
+	Expansion ExpansionOptions
+
+	// Proxies is how many proxies the client rotates through. The expansion
+	// pools are clamped to it; 0 counts as a single connection.
+	Proxies int
+
+	// Throttle stretches the pauses between requests and shrinks the
+	// expansion pools while Reddit answers with 429s; nil keeps them fixed
+	Throttle *utils.Throttle
+
+	// Policies tune the scrapes of single subreddits; nil has none
+	Policies *CrawlPolicies
+}
+
+// DefaultScraperOptions returns the options used by NewScraperService
+func DefaultScraperOptions() ScraperOptions {
+	return ScraperOptions{
+		UserWindowWorkers:   1,
+		StickyProxySessions: true,
+		EmptyPageRetries:    1,
+		Backfill:            true,
+		Expansion:           DefaultExpansionOptions(),
+	}
+}
+
+type scraperService struct {
+	client client.RedditClientInterface
+	parser parser.ParserInterface
+	opts   ScraperOptions
+}
+
+type MoreCommentSet struct {
+    Parent        string   
+    CommentIDs    []string 
+    Depth         int      
+    PlaceholderID string   
+}
+
+func NewScraperService(client client.RedditClientInterface, parser parser.ParserInterface) ScraperService {
+	return NewScraperServiceWithOptions(client, parser, DefaultScraperOptions())
+}
+
+// NewScraperServiceWithOptions creates a scraper with explicit tuning options
+func NewScraperServiceWithOptions(client client.RedditClientInterface, parser parser.ParserInterface, opts ScraperOptions) ScraperService {
+	if opts.UserWindowWorkers < 1 {
+		opts.UserWindowWorkers = 1
+	}
+	if opts.EmptyPageRetries < 0 {
+		opts.EmptyPageRetries = 0
+	}
+	opts.Expansion = clampExpansion(opts.Expansion, opts.Proxies)
+	return &scraperService{
+		client: client,
+		parser: parser,
+		opts:   opts,
+	}
+}
+
+type strictKey struct{}
+
+// WithStrict makes scrapes with the returned context fail as a whole when any
+// part fails, instead of returning the parts that succeeded with warnings
+func WithStrict(ctx context.Context) context.Context {
+	return context.WithValue(ctx, strictKey{}, true)
+}
+
+// IsStrict reports whether ctx was marked by WithStrict
+func IsStrict(ctx context.Context) bool {
+	strict, _ := ctx.Value(strictKey{}).(bool)
+	return strict
+}
+
+// withBulkPriority keeps full-history scrapes off the best ranked proxies,
+// which are left to interactive requests
+func withBulkPriority(ctx context.Context, limits ...int) context.Context {
+	for _, limit := range limits {
+		if limit == -1 {
+			return utils.WithBulkPriority(ctx)
+		}
+	}
+	return ctx
+}
+
+// withProxySession starts a sticky proxy session for one scrape operation
+func (s *scraperService) withProxySession(ctx context.Context) context.Context {
+	if !s.opts.StickyProxySessions {
+		return ctx
+	}
+	return utils.WithProxySession(ctx)
+}
+
+// ScrapeSubreddit retrieves posts from a subreddit
+func (s *scraperService) ScrapeSubreddit(
+	ctx context.Context,
+	subreddit string,
+	sinceTimestamp int64,
+	limit int,
+	opts ListingOptions,
+) ([]models.Post, models.ListingMeta, error) {
+	ctx, policy := s.withCrawlPolicy(ctx, subreddit)
+	if (limit == 0 || IsDefaultLimit(ctx)) && policy.DefaultLimit != 0 {
+		limit = policy.DefaultLimit
+	}
+	pageURL := func(limit int, after string) string {
+		return s.client.GetSubredditURL(subreddit, limit, after)
+	}
+	ctx, progress := startProgress(ctx, "subreddit")
+	posts, meta, err := s.scrapeListing(ctx, "subreddit "+subreddit, pageURL, sinceTimestamp, limit, opts, s.subredditHistory(subreddit))
+	progress.finish(err)
+	return posts, meta, err
+}
+
+// scrapeListing pages through a listing of posts. name describes the listing
+// in logs and errors; pageURL returns the URL of the page of limit posts (0
+// for Reddit's default) following after. A sinceTimestamp cutoff ends paging
+// at the first older post, so it needs a listing sorted newest first. When the
+// listing ends at Reddit's cap before the cutoff, history backfills the rest
+// of the window; nil leaves it out.
+func (s *scraperService) scrapeListing(
+	ctx context.Context,
+	name string,
+	pageURL func(limit int, after string) string,
+	sinceTimestamp int64,
+	limit int,
+	opts ListingOptions,
+	history historyFunc,
+) ([]models.Post, models.ListingMeta, error) {
+	ctx = s.withProxySession(ctx)
+	ctx = withBulkPriority(ctx, limit)
+	startTime := time.Now()
+	collector := newListingCollector(sinceTimestamp, limit, opts.Filter)
+	ctx, collector.report = parser.WithReport(ctx)
+	collector.requests = &utils.PageCounter{OnPage: progressFrom(ctx).listingPageFetched}
+	ctx = utils.WithPageCounter(ctx, collector.requests)
+
+	// Case 1: No timestamp and limit 0 - fetch only first page with default size
+	if sinceTimestamp == 0 && limit == 0 {
+		fmt.Printf("No timestamp or limit provided, fetching only the first page for %s\n", name)
+
+		apiURL := pageURL(0, opts.After)
+
+		collector.startPage()
+		nextAfter, err := s.fetchListingPage(ctx, name, apiURL, collector.emit)
+		if err != nil {
+			return nil, models.ListingMeta{}, err
+		}
+
+		posts, meta := collector.finish(nextAfter)
+		fmt.Printf("First page fetch yielded %d posts\n", len(posts))
+		fmt.Printf("Final result: %d posts fetched in %v\n", len(posts), time.Since(startTime))
+		return posts, meta, nil
+	}
+
+	apiLimit := 100 // Maximum allowed by Reddit API per page
+	
+	// Special case: if limit is -1, we retrieve all posts (no post limit)
+	if limit > 0 && limit < apiLimit {
+		apiLimit = limit
+	}
+
+	after := opts.After
+	pageCount := 0
+	maxPages := 20 
+
+	// Special case: if limit is -1, set a very high max pages value
+	if limit == -1 {
+		maxPages = 1000
+		fmt.Printf("Special case: limit = -1, attempting to scrape ALL posts from %s\n", name)
+	}
+	
+	// Increase max pages for timestamp filtering
+	if sinceTimestamp > 0 {
+		if limit != -1 {
+			maxPages = 20
+		}
+	}
+
+	// A walk that needs more than one page requests each page while the one
+	// before it is filtered. Smaller walks stream their page instead, as most
+	// end with it.
+	var pages *pagePipeline
+	if limit == -1 || limit > apiLimit {
+		pages = s.newPagePipeline(ctx, func(after string) string {
+			return pageURL(apiLimit, after)
+		}, maxPages-1, 0)
+		defer pages.stop()
+	}
+
+	for pageCount < maxPages {
+		if ctx.Err() != nil {
+			posts, meta := collector.finish(after)
+			return posts, meta, ctx.Err()
+		}
+
+		pageCount++
+
+		apiURL := pageURL(apiLimit, after)
+		fmt.Printf("Fetching page %d for %s (URL: %s)\n", pageCount, name, apiURL)
+
+		// Filter by timestamp as posts arrive; stop reading the page at the limit
+		collector.startPage()
+		var nextAfter string
+		var err error
+		if pages != nil {
+			nextAfter, err = s.fetchListingPageAhead(ctx, name, pages, after, collector.emit)
+		} else {
+			nextAfter, err = s.fetchListingPage(ctx, name, apiURL, collector.emit)
+		}
+		if listingGone(err, pageCount) {
+			fmt.Printf("Page %d for %s is gone, stopping pagination: %v\n", pageCount, name, err)
+			break
+		}
+		if err != nil {
+			return nil, models.ListingMeta{}, err
+		}
+
+		fmt.Printf("Page %d yielded %d posts (total now: %d/%d)\n",
+			pageCount, collector.pagePosts, len(collector.posts), limit)
+
+		after = nextAfter
+
+		// Stop conditions
+		if limit > 0 && len(collector.posts) >= limit {
+			fmt.Println("Reached requested limit, stopping pagination")
+			break
+		}
+
+		if collector.meta.ReachedTimeCutoff {
+			fmt.Println("Reached time limit cutoff, stopping pagination")
+			break
+		}
+
+		if nextAfter == "" || collector.pageRead == 0 {
+			fmt.Println("No more pages available or empty page")
+			break
+		}
+
+		// Timeout handling
+		timeoutDuration := 30 * time.Second
+		if limit == -1 {
+			timeoutDuration = 3 * time.Minute
+		}
+		
+		if time.Since(startTime) > timeoutDuration && len(collector.posts) > 0 {
+			if limit == -1 {
+				fmt.Printf("Extended time limit (%v) for full scraping reached, returning results so far\n", timeoutDuration)
+			} else {
+				fmt.Printf("Time limit (%v) for request reached, returning results so far\n", timeoutDuration)
+			}
+			collector.meta.TimedOut = true
+			break
+		}
+
+		if pageCount == maxPages {
+			fmt.Printf("Reached the cap of %d pages, returning results so far\n", maxPages)
+			collector.truncate(models.TruncatedPageCap)
+		}
+	}
+
+	if collector.hitListingCap(after) {
+		if history != nil && sinceTimestamp > 0 {
+			s.backfillListing(ctx, name, history, collector)
+		} else {
+			collector.meta.ReachedListingCap = true
+			collector.truncate(models.TruncatedListingCap)
+		}
+	}
+
+	posts, meta := collector.finish(after)
+
+	if meta.DuplicatesDropped > 0 {
+		fmt.Printf("Dropped %d duplicate posts from %s\n", meta.DuplicatesDropped, name)
+	}
+	fmt.Printf("Final result: %d posts fetched in %v\n", len(posts), time.Since(startTime))
+	return posts, meta, nil
+}
+
+// ScrapeUserActivity retrieves a user's activity on Reddit
+func (s *scraperService) ScrapeUserActivity(
+	ctx context.Context,
+	username string,
+	sinceTimestamp int64,
+	postLimit, commentLimit int,
+) (models.UserActivity, error) {
+	ctx, progress := startProgress(ctx, "user")
+	activity, err := s.scrapeUserActivity(ctx, username, sinceTimestamp, postLimit, commentLimit)
+	progress.finish(err)
+	return activity, err
+}
+
+func (s *scraperService) scrapeUserActivity(
+	ctx context.Context,
+	username string,
+	sinceTimestamp int64,
+	postLimit, commentLimit int,
+) (models.UserActivity, error) {
+	ctx = s.withProxySession(ctx)
+	ctx = withBulkPriority(ctx, postLimit, commentLimit)
+	ctx, report := parser.WithReport(ctx)
+	requests := &utils.PageCounter{OnPage: progressFrom(ctx).listingPageFetched}
+	ctx = utils.WithPageCounter(ctx, requests)
+	activity := models.UserActivity{}
+
+	aboutURL := s.client.GetUserAboutURL(username)
+
+	aboutData, err := s.client.FetchJSON(ctx, aboutURL)
+	if err != nil {
+		return activity, fmt.Errorf("fetch user info: %w", err)
+	}
+
+	userInfo, err := s.parser.ParseUserInfo(ctx, aboutData)
+	if err != nil {
+		return activity, fmt.Errorf("parse user info: %w", err)
+	}
+
+	activity.UserInfo = userInfo
+
+	var wg sync.WaitGroup
+	var postsErr, commentsErr error
+	var duplicatePosts int
+	var postsStop, commentsStop walkStop
+	// Each half counts its own filter matches; they are added up below
+	postFilter := newItemFilter(ActivityFilterFromContext(ctx))
+	commentFilter := newItemFilter(ActivityFilterFromContext(ctx))
+	postsChan := make(chan []models.UserPost, 1)
+	commentsChan := make(chan []models.UserComment, 1)
+
+	wg.Add(2)
+
+	// Fetch posts concurrently
+	go func() {
+		defer wg.Done()
+		var posts []models.UserPost
+		var err error
+		if s.useUserWindows(sinceTimestamp, postLimit) {
+			posts, postsStop, err = s.fetchUserPostWindows(ctx, username, postFilter)
+		} else {
+			posts, duplicatePosts, postsStop, err = s.fetchUserPosts(ctx, username, sinceTimestamp, postLimit, postFilter)
+		}
+		if err != nil {
+			postsErr = err
+			return
+		}
+		postsChan <- posts
+	}()
+
+	// Fetch comments concurrently
+	go func() {
+		defer wg.Done()
+		var comments []models.UserComment
+		var err error
+		if s.useUserWindows(sinceTimestamp, commentLimit) {
+			comments, commentsStop, err = s.fetchUserCommentWindows(ctx, username, commentFilter)
+		} else {
+			comments, commentsStop, err = s.fetchUserComments(ctx, username, sinceTimestamp, commentLimit, commentFilter)
+		}
+		if err != nil {
+			commentsErr = err
+			return
+		}
+		commentsChan <- comments
+	}()
+
+	wg.Wait()
+	close(postsChan)
+	close(commentsChan)
+
+	// One half failing still returns the other with a warning, unless the
+	// caller asked for all or nothing
+	if postsErr != nil && commentsErr != nil {
+		return activity, fmt.Errorf("%w; %w", postsErr, commentsErr)
+	}
+	if IsStrict(ctx) {
+		if postsErr != nil {
+			return activity, postsErr
+		}
+		if commentsErr != nil {
+			return activity, commentsErr
+		}
+	}
+
+	if posts, ok := <-postsChan; ok {
+		activity.Posts = posts
+	}
+
+	if comments, ok := <-commentsChan; ok {
+		activity.Comments = comments
+	}
+
+	postFilter.merge(commentFilter)
+	activity.Meta = &models.UserActivityMeta{
+		Ordering:              models.OrderingNewestFirst,
+		DuplicatePostsDropped: duplicatePosts,
+		FilteredOut:           postFilter.filteredOut,
+		FilterMatches:         postFilter.matches,
+		ParseReport:           report.Result(),
+		Retries:               requests.Retries(),
+	}
+	// A half that failed is reported as partial instead
+	if postsErr != nil {
+		postsStop = walkStop{}
+	}
+	if commentsErr != nil {
+		commentsStop = walkStop{}
+	}
+	meta := activity.Meta
+	meta.Truncated, meta.TruncationReason, meta.OldestReached = mergeWalkStops(postsStop, commentsStop)
+	for _, err := range []error{postsErr, commentsErr} {
+		if err != nil {
+			fmt.Printf("Returning partial activity for user %s: %v\n", username, err)
+			activity.Meta.Partial = true
+			activity.Meta.Warnings = append(activity.Meta.Warnings, err.Error())
+		}
+	}
+
+	return activity, nil
+}
+
+// fetchUserPosts walks a user's posts newest first, keeping those filter
+// matches. It also returns how many posts were dropped for appearing on more
+// than one page.
+func (s *scraperService) fetchUserPosts(
+	ctx context.Context,
+	username string,
+	sinceTimestamp int64,
+	limit int,
+	filter *itemFilter,
+) ([]models.UserPost, int, walkStop, error) {
+	var posts []models.UserPost
+	var stop walkStop
+	seen := make(map[string]bool)
+	duplicates := 0
+	after := ""
+	pageCount := 0
+	unpinnedCount := 0
+	startTime := time.Now()
+
+	var needMultiplePages bool
+	var maxPages int
+	var effectiveLimit int
+
+	switch {
+	case limit == 0:
+		needMultiplePages = false
+		maxPages = 1
+		effectiveLimit = 0 
+		fmt.Printf("No post limit provided, fetching only first page for user %s\n", username)
+		
+	case limit == -1:
+		needMultiplePages = true
+		effectiveLimit = -1 
+		if sinceTimestamp > 0 {
+			maxPages = 1000 
+			fmt.Printf("Fetching ALL posts for user %s since timestamp %d\n", username, sinceTimestamp)
+		} else {
+			maxPages = 500 
+			fmt.Printf("Fetching ALL posts for user %s (no timestamp filter)\n", username)
+		}
+		
+	default:
+		// A filter can leave a page short of the limit however large it is
+		needMultiplePages = limit > 25 || sinceTimestamp > 0 || filter.active()
+		maxPages = (limit/25 + 1) * 2 
+		if maxPages > 50 {
+			maxPages = 50 
+		}
+		effectiveLimit = limit
+		fmt.Printf("Fetching up to %d posts for user %s\n", limit, username)
+	}
+
+	if sinceTimestamp > 0 {
+		sinceTime := time.Unix(sinceTimestamp, 0)
+		fmt.Printf("Filtering posts since %s (timestamp: %d)\n", sinceTime.Format(time.RFC3339), sinceTimestamp)
+	}
+
+	// Each page is requested while the one before it is parsed
+	lookahead := 0
+	if needMultiplePages {
+		lookahead = maxPages - 1
+	}
+	pages := s.newPagePipeline(ctx, func(after string) string {
+		return s.client.GetUserPostsURL(username, after)
+	}, lookahead, 200*time.Millisecond)
+	defer pages.stop()
+
+	for pageCount < maxPages {
+		if ctx.Err() != nil {
+			return nil, duplicates, stop, ctx.Err()
+		}
+
+		pageCount++
+		fmt.Printf("Fetching posts page %d for user %s\n", pageCount, username)
+
+		var pagePosts []models.UserPost
+		var nextAfter string
+		err := s.retryEmptyPages(ctx, "user "+username+" posts", func(ctx context.Context) (int, int, error) {
+			data, err := pages.fetch(ctx, after)
+			if err != nil {
+				return 0, 0, fmt.Errorf("fetch user posts: %w", err)
+			}
+			pagePosts, nextAfter, err = s.parser.ParseUserPosts(ctx, data)
+			if err != nil {
+				return 0, 0, fmt.Errorf("parse user posts: %w", err)
+			}
+			return len(pagePosts), len(data), nil
+		})
+		if listingGone(err, pageCount) {
+			fmt.Printf("Posts page %d for user %s is gone, stopping pagination: %v\n", pageCount, username, err)
+			break
+		}
+		if err != nil {
+			return nil, duplicates, stop, err
+		}
+
+		reachedTimeLimit := false
+		reachedLimit := false
+		pageReadCount := 0
+		pagePostCount := 0
+		
+		for _, post := range pagePosts {
+			if seen[post.ID] {
+				duplicates++
+				continue
+			}
+			seen[post.ID] = true
+
+			// Pinned posts lead the listing whatever their age, so they must not
+			// trigger the timestamp cutoff or use up the limit
+			if post.Pinned {
+				if (sinceTimestamp == 0 || post.CreatedAt.Unix() >= sinceTimestamp) && filter.keep(userPostItem(post)) {
+					posts = append(posts, post)
+				}
+				continue
+			}
+		
+			if sinceTimestamp > 0 && post.CreatedAt.Unix() < sinceTimestamp {
+				reachedTimeLimit = true
+				continue 
+			}
+
+			pageReadCount++
+			stop.read(post.CreatedAt)
+			if !filter.keep(userPostItem(post)) {
+				continue
+			}
+			
+			pagePostCount++
+			posts = append(posts, post)
+			unpinnedCount++
+
+			if effectiveLimit > 0 && unpinnedCount >= effectiveLimit {
+				fmt.Printf("Reached requested limit of %d posts\n", effectiveLimit)
+				reachedLimit = true
+				break
+			}
+		}
+
+		if reachedLimit {
+			break
+		}
+
+		fmt.Printf("Posts page %d yielded %d posts (total now: %d)\n",
+			pageCount, pagePostCount, len(posts))
+
+		// Stop conditions
+		if reachedTimeLimit && sinceTimestamp > 0 {
+			fmt.Println("Reached timestamp cutoff, stopping pagination")
+			break
+		}
+
+		if !needMultiplePages {
+			fmt.Println("First page only mode, stopping pagination")
+			break
+		}
+
+		if nextAfter == "" || pageReadCount == 0 {
+			fmt.Println("No more posts available")
+			if nextAfter == "" && (effectiveLimit == -1 || sinceTimestamp > 0) && len(seen) >= listingCapMin {
+				stop.truncate(models.TruncatedListingCap)
+			}
+			break
+		}
+
+		after = nextAfter
+
+		var timeoutDuration time.Duration
+		if effectiveLimit == -1 {
+			timeoutDuration = 5 * time.Minute 
+		} else {
+			timeoutDuration = 2 * time.Minute 
+		}
+		
+		if time.Since(startTime) > timeoutDuration && len(posts) > 0 {
+			fmt.Printf("Time limit (%v) reached, returning results so far\n", timeoutDuration)
+			stop.truncate(models.TruncatedTimeLimit)
+			break
+		}
+
+		if pageCount == maxPages {
+			fmt.Printf("Reached the cap of %d pages, returning results so far\n", maxPages)
+			stop.truncate(models.TruncatedPageCap)
+		}
+	}
+
+	// Newest first, then trim, so whatever cut the walk short the newest posts survive
+	sort.SliceStable(posts, func(i, j int) bool {
+		return posts[i].CreatedAt.After(posts[j].CreatedAt)
+	})
+	if effectiveLimit > 0 && len(posts) > effectiveLimit {
+		posts = posts[:effectiveLimit]
+	}
+
+	if duplicates > 0 {
+		fmt.Printf("Dropped %d duplicate posts for user %s\n", duplicates, username)
+	}
+	fmt.Printf("Final result: %d posts fetched for user %s\n", len(posts), username)
+	return posts, duplicates, stop, nil
+}
+
+//  fetchUserComments function
+func (s *scraperService) fetchUserComments(
+	ctx context.Context,
+	username string,
+	sinceTimestamp int64,
+	limit int,
+	filter *itemFilter,
+) ([]models.UserComment, walkStop, error) {
+	var comments []models.UserComment
+	var stop walkStop
+	read := 0
+	after := ""
+	pageCount := 0
+	startTime := time.Now()
+
+	// Determine pagination behavior based on limit
+	var needMultiplePages bool
+	var maxPages int
+	var effectiveLimit int
+
+	switch {
+	case limit == 0:
+		needMultiplePages = false
+		maxPages = 1
+		effectiveLimit = 0 
+		fmt.Printf("No comment limit provided, fetching only first page for user %s\n", username)
+		
+	case limit == -1:
+		needMultiplePages = true
+		effectiveLimit = -1 
+		if sinceTimestamp > 0 {
+			maxPages = 1000 
+			fmt.Printf("Fetching ALL comments for user %s since timestamp %d\n", username, sinceTimestamp)
+		} else {
+			maxPages = 500 
+			fmt.Printf("Fetching ALL comments for user %s (no timestamp filter)\n", username)
+		}
+		
+	default:
+		// A filter can leave a page short of the limit however large it is
+		needMultiplePages = limit > 25 || sinceTimestamp > 0 || filter.active()
+		maxPages = (limit/25 + 1) * 2 // Estimate pages needed
+		if maxPages > 50 {
+			maxPages = 50 
+		}
+		effectiveLimit = limit
+		fmt.Printf("Fetching up to %d comments for user %s\n", limit, username)
+	}
+
+
+	if sinceTimestamp > 0 {
+		sinceTime := time.Unix(sinceTimestamp, 0)
+		fmt.Printf("Filtering comments since %s (timestamp: %d)\n", sinceTime.Format(time.RFC3339), sinceTimestamp)
+	}
+
+	// Each page is requested while the one before it is parsed
+	lookahead := 0
+	if needMultiplePages {
+		lookahead = maxPages - 1
+	}
+	pages := s.newPagePipeline(ctx, func(after string) string {
+		return s.client.GetUserCommentsURL(username, after)
+	}, lookahead, 200*time.Millisecond)
+	defer pages.stop()
+
+	for pageCount < maxPages {
+		if ctx.Err() != nil {
+			return nil, stop, ctx.Err()
+		}
+
+		pageCount++
+		fmt.Printf("Fetching comments page %d for user %s\n", pageCount, username)
+
+		var pageComments []models.UserComment
+		var nextAfter string
+		err := s.retryEmptyPages(ctx, "user "+username+" comments", func(ctx context.Context) (int, int, error) {
+			data, err := pages.fetch(ctx, after)
+			if err != nil {
+				return 0, 0, fmt.Errorf("fetch user comments: %w", err)
+			}
+			pageComments, nextAfter, err = s.parser.ParseUserComments(ctx, data)
+			if err != nil {
+				return 0, 0, fmt.Errorf("parse user comments: %w", err)
+			}
+			return len(pageComments), len(data), nil
+		})
+		if listingGone(err, pageCount) {
+			fmt.Printf("Comments page %d for user %s is gone, stopping pagination: %v\n", pageCount, username, err)
+			break
+		}
+		if err != nil {
+			return nil, stop, err
+		}
+
+		reachedTimeLimit := false
+		pageReadCount := 0
+		pageCommentCount := 0
+		
+		for _, comment := range pageComments {
+			if sinceTimestamp > 0 && comment.CreatedAt.Unix() < sinceTimestamp {
+				reachedTimeLimit = true
+				continue 
+			}
+
+			pageReadCount++
+			read++
+			stop.read(comment.CreatedAt)
+			if !filter.keep(userCommentItem(comment)) {
+				continue
+			}
+			
+			pageCommentCount++
+			comments = append(comments, comment)
+			
+			if effectiveLimit > 0 && len(comments) >= effectiveLimit {
+				fmt.Printf("Reached requested limit of %d comments\n", effectiveLimit)
+				return sortUserCommentsNewestFirst(comments), stop, nil
+			}
+		}
+
+		fmt.Printf("Comments page %d yielded %d comments (total now: %d)\n",
+			pageCount, pageCommentCount, len(comments))
+
+		// Stop conditions
+		if reachedTimeLimit && sinceTimestamp > 0 {
+			fmt.Println("Reached timestamp cutoff, stopping pagination")
+			break
+		}
+
+		if !needMultiplePages {
+			fmt.Println("First page only mode, stopping pagination")
+			break
+		}
+
+		if nextAfter == "" || pageReadCount == 0 {
+			fmt.Println("No more comments available")
+			if nextAfter == "" && (effectiveLimit == -1 || sinceTimestamp > 0) && read >= listingCapMin {
+				stop.truncate(models.TruncatedListingCap)
+			}
+			break
+		}
+
+		after = nextAfter
+		var timeoutDuration time.Duration
+		if effectiveLimit == -1 {
+			timeoutDuration = 5 * time.Minute 
+		} else {
+			timeoutDuration = 2 * time.Minute 
+		}
+		
+		if time.Since(startTime) > timeoutDuration && len(comments) > 0 {
+			fmt.Printf("Time limit (%v) reached, returning results so far\n", timeoutDuration)
+			stop.truncate(models.TruncatedTimeLimit)
+			break
+		}
+
+		if pageCount == maxPages {
+			fmt.Printf("Reached the cap of %d pages, returning results so far\n", maxPages)
+			stop.truncate(models.TruncatedPageCap)
+		}
+	}
+
+	fmt.Printf("Final result: %d comments fetched for user %s\n", len(comments), username)
+	return sortUserCommentsNewestFirst(comments), stop, nil
+}
+
+// sortUserCommentsNewestFirst orders comments by creation time, newest first.
+// The comments listing is already in that order; this keeps the guarantee
+// if Reddit ever interleaves anything else.
+func sortUserCommentsNewestFirst(comments []models.UserComment) []models.UserComment {
+	sort.SliceStable(comments, func(i, j int) bool {
+		return comments[i].CreatedAt.After(comments[j].CreatedAt)
+	})
+	return comments
+}
+
+// ScrapePost retrieves a post with all its comments, including all "load more" content
+func (s *scraperService) ScrapePost(ctx context.Context, postID string) (models.PostDetail, error) {
+    ctx = s.withProxySession(ctx)
+    ctx, report := parser.WithReport(ctx)
+    requests := &utils.PageCounter{}
+    ctx = utils.WithPageCounter(ctx, requests)
+    ctx, progress := startProgress(ctx, "post")
+    startTime := time.Now()
+    fmt.Printf("[%s] Starting to scrape post %s\n", startTime.Format(time.RFC3339), postID)
+    progress.update(func(state *Progress) { state.Stage = StageFetchPost })
+
+    // Fetch initial post with first level comments
+    raw, err := s.fetchInitialPost(ctx, postID)
+    if err != nil {
+        return models.PostDetail{}, err
+    }
+    detail, err := s.parser.ParsePost(ctx, raw[0], raw[1])
+    if err != nil {
+        return models.PostDetail{}, err
+    }
+
+    // The subreddit, and so its crawl policy, is only known now; reparse
+    // when the policy turns awards on or off
+    policyCtx, policy := s.withCrawlPolicy(ctx, detail.Post.Subreddit)
+    if parser.IncludesAwards(policyCtx) != parser.IncludesAwards(ctx) {
+        // The reparse reports what it skips afresh
+        policyCtx, report = parser.WithReport(policyCtx)
+        if detail, err = s.parser.ParsePost(policyCtx, raw[0], raw[1]); err != nil {
+            return models.PostDetail{}, err
+        }
+    }
+    ctx = policyCtx
+    
+    initialCommentCount := s.countComments(detail.Comments)
+    fmt.Printf("Initial post fetch retrieved %d comments\n", initialCommentCount)
+    progress.update(func(state *Progress) {
+        state.PagesFetched++
+        state.Comments = initialCommentCount
+    })
+
+
+    // Expand all "load more" comment sections
+    expandedCount := 0
+    // A bounded tree is what the caller asked for; expanding it would
+    // fetch the rest
+    switch {
+    case !client.CommentTreeFromContext(ctx).IsZero():
+        fmt.Printf("Comment tree of %s is bounded, skipping comment expansion\n", postID)
+    case policy.ExpandComments == nil || *policy.ExpandComments:
+        expandedCount = s.expandCommentsFast(ctx, postID, &detail)
+    default:
+        fmt.Printf("Crawl policy of r/%s skips comment expansion\n", detail.Post.Subreddit)
+    }
+    // A deadline keeps the comments expanded so far; a cancelled scrape
+    // has nobody left to return them to
+    if errors.Is(ctx.Err(), context.Canceled) {
+        return models.PostDetail{}, fmt.Errorf("expand comments of %s: %w", postID, ctx.Err())
+    }
+    
+    if IncludesRelated(ctx) {
+        related, err := s.fetchRelated(ctx, postID)
+        if err != nil {
+            if IsStrict(ctx) {
+                return models.PostDetail{}, err
+            }
+            // Related posts are an extra; the post and its comments stand without them
+            fmt.Printf("Skipping related posts of %s: %v\n", postID, err)
+        }
+        detail.Related = related
+    }
+
+
+    elapsed := time.Since(startTime)
+    totalComments := s.countComments(detail.Comments)
+    
+    fmt.Printf("[%s] Finished scraping post %s in %v - found %d total comments (expanded %d)\n", 
+        time.Now().Format(time.RFC3339), postID, elapsed, totalComments, expandedCount)
+    progress.update(func(state *Progress) {
+        state.Stage = StageDone
+        state.Comments = totalComments
+    })
+    
+    detail.ParseReport = report.Result()
+    detail.Retries = requests.Retries()
+    detail.Completeness = completeness(detail)
+    return detail, nil
+}
+
+// fetchInitialPost retrieves the post with its initial comments, returning
+// the listings of the post and of its comments
+func (s *scraperService) fetchInitialPost(ctx context.Context, postID string) ([]json.RawMessage, error) {
+    apiURL := client.WithPostURLOptions(ctx, s.client.GetPostURL(postID))
+    data, err := s.client.FetchJSON(ctx, apiURL)
+    if err != nil {
+        return nil, fmt.Errorf("fetch post JSON: %w", err)
+    }
+
+    var raw []json.RawMessage
+    if err := json.Unmarshal(data, &raw); err != nil || len(raw) < 2 {
+        return nil, fmt.Errorf("invalid post JSON format: %w", err)
+    }
+
+    return raw, nil
+}
+
+
+
+// expandCommentsFast uses concurrent processing to load comments faster
+func (s *scraperService) expandCommentsFast(ctx context.Context, postID string, detail *models.PostDetail) int {
+    expandedCount := 0
+    maxIterations := 60 
+    
+    // Resolved once so the fetches of every set use the same clamped sizes
+    pool := s.expansion(ctx)
+    ctx = WithExpansion(ctx, pool)
+    
+    remainingIDs := 0
+    stuckCount := 0
+    stuckLimit := 3      // Increased from 2
+    
+    // "load more" IDs seen and requested, for progress reports
+    progress := progressFrom(ctx)
+    knownIDs := make(map[string]bool)
+    requestedIDs := make(map[string]bool)
+    
+    // Indexed once; the comments go back into detail when expansion ends
+    tree := newCommentTree(detail.Post.ID, detail.Comments)
+    
+    for iteration := 0; iteration < maxIterations; iteration++ {
+        if ctx.Err() != nil {
+            fmt.Printf("Stopping comment expansion of %s: %v\n", postID, ctx.Err())
+            break
+        }
+        moreSets := tree.moreSets()
+        if len(moreSets) == 0 {
+            fmt.Println("No more 'load more' comments found, expansion complete")
+            break
+        }
+        
+        for _, set := range moreSets {
+            for _, id := range set.CommentIDs {
+                knownIDs[id] = true
+            }
+        }
+        progress.update(func(state *Progress) {
+            state.Stage = StageExpandComments
+            state.MoreIDsTotal = len(knownIDs)
+        })
+        
+        newRemainingIDs := 0
+        for _, set := range moreSets {
+            newRemainingIDs += len(set.CommentIDs)
+        }
+        
+        if newRemainingIDs == remainingIDs && newRemainingIDs > 0 {
+            stuckCount++
+            if stuckCount >= stuckLimit {
+                fmt.Printf("No progress after %d iterations, stopping\n", stuckCount)
+                break
+            }
+        } else {
+            stuckCount = 0
+        }
+        remainingIDs = newRemainingIDs
+        
+        fmt.Printf("Iteration %d: Processing %d more comment sets (%d IDs remaining)\n", 
+            iteration, len(moreSets), remainingIDs)
+        
+        // Add proper delay between iterations
+        pause := time.Duration(0)
+        if iteration > 0 {
+            pause = 2 * time.Second  // Increased delay
+        }
+        
+        // Take longer breaks periodically
+        if iteration > 10 && iteration % 5 == 0 {
+            fmt.Println("Taking longer break after multiple iterations")
+            pause += 5 * time.Second
+        }
+        if pause > 0 {
+            s.pause(ctx, pause)
+            if ctx.Err() != nil {
+                continue
+            }
+        }
+        
+        batchSize := pool.BatchSize
+        if len(moreSets) > batchSize {
+            fmt.Printf("Limiting to %d more comment sets per iteration\n", batchSize)
+            moreSets = moreSets[:batchSize]
+        }
+        for _, set := range moreSets {
+            for _, id := range set.CommentIDs {
+                requestedIDs[id] = true
+            }
+        }
+        
+        // Small sets share morechildren calls
+        groups := coalesceMoreSets(moreSets)
+        if len(groups) < len(moreSets) {
+            fmt.Printf("Coalesced %d more comment sets into %d morechildren calls\n", len(moreSets), len(groups))
+        }
+        commentSets := make(chan moreSetGroup, len(groups))
+        
+        results := make(chan struct {
+            Comments []models.Comment
+            Set struct {
+                Parent string
+                CommentIDs []string
+                Depth int
+                PlaceholderID string
+            }
+            Index int
+        }, len(moreSets))
+        
+        // Fewer workers while Reddit is answering with 429s
+        workerCount := s.opts.Throttle.Concurrency(pool.Workers)
+        var wg sync.WaitGroup
+        for w := 0; w < workerCount; w++ {
+            wg.Add(1)
+            go func(workerId int) {
+                defer wg.Done()
+                s.commentWorker(ctx, postID, commentSets, results)
+            }(w)
+        }
+        
+        for _, group := range groups {
+            commentSets <- group
+        }
+        close(commentSets)
+        
+        go func() {
+            wg.Wait()
+            close(results)
+        }()
+        
+        iterationCount := 0
+        processedResults := make([]struct {
+            Comments []models.Comment
+            Set struct {
+                Parent string
+                CommentIDs []string
+                Depth int
+                PlaceholderID string
+            }
+            Index int
+        }, 0, len(moreSets))
+        
+        for result := range results {
+            processedResults = append(processedResults, result)
+        }
+        
+        sort.Slice(processedResults, func(i, j int) bool {
+            return processedResults[i].Index < processedResults[j].Index
+        })
+        
+        for _, result := range processedResults {
+            if len(result.Comments) > 0 {
+                iterationCount += len(result.Comments)
+                s.placeComments(tree, result.Set, result.Comments, client.CommentSortFromContext(ctx))
+            }
+        }
+        
+        expandedCount += iterationCount
+        fmt.Printf("Added %d comments (total: %d)\n", iterationCount, expandedCount)
+        progress.update(func(state *Progress) {
+            state.Comments = tree.size
+            state.CommentsExpanded = expandedCount
+            state.MoreIDsResolved = len(requestedIDs)
+        })
+        
+        if iterationCount == 0 {
+            fmt.Println("No new comments added in this iteration, may be stuck")
+        }
+    }
+    
+    detail.Comments = tree.comments(false)
+    
+    return expandedCount
+}
+
+// commentWorker processes groups of comment sets in parallel, one
+// morechildren call per group, and splits each group's comments among its sets
+func (s *scraperService) commentWorker(
+    ctx context.Context, 
+    postID string,
+    commentSets <-chan moreSetGroup,
+    results chan<- struct {
+        Comments []models.Comment
+        Set struct {
+            Parent string
+            CommentIDs []string
+            Depth int
+            PlaceholderID string
+        }
+        Index int
+    },
+) {
+    for group := range commentSets {
+        comments, _ := s.fetchMoreCommentsFast(ctx, postID, group.IDs())
+        routed := routeMoreComments(group.Sets, comments)
+        
+        for i, set := range group.Sets {
+            results <- struct {
+                Comments []models.Comment
+                Set struct {
+                    Parent string
+                    CommentIDs []string
+                    Depth int
+                    PlaceholderID string
+                }
+                Index int
+            }{
+                Comments: routed[i],
+                Set: set,
+                Index: group.Indexes[i],
+            }
+        }
+    }
+}
+
+// fetchMoreCommentsFast is an optimized version with fewer retries and delays
+func (s *scraperService) fetchMoreCommentsFast(ctx context.Context, postID string, commentIDs []string) ([]models.Comment, error) {
+    // Smaller batch size - Reddit sometimes rejects large batches
+    const batchSize = moreChildrenMaxIDs
+    var allComments []models.Comment
+    
+    var validIDs []string
+    for _, id := range commentIDs {
+        if id != "continue" {
+            // Ensure proper format without t1_ prefix
+            validIDs = append(validIDs, strings.TrimPrefix(id, "t1_"))
+        }
+    }
+    
+    if len(validIDs) == 0 {
+        return allComments, nil
+    }
+    
+    // Add debugging
+    if len(validIDs) > 0 {
+        fmt.Printf("Fetching %d comment IDs (first few: %v)\n", 
+            len(validIDs), validIDs[:min(3, len(validIDs))])
+    }
+    
+    var wg sync.WaitGroup
+    var mu sync.Mutex
+    
+    maxConcurrent := s.opts.Throttle.Concurrency(s.expansion(ctx).Concurrency)
+    semaphore := make(chan struct{}, maxConcurrent)
+    
+    for i := 0; i < len(validIDs); i += batchSize {
+        // Add delay between batches to avoid rate limiting
+        if i > 0 {
+            s.pause(ctx, 1000*time.Millisecond)
+        }
+        if ctx.Err() != nil {
+            break
+        }
+        
+        end := min(i+batchSize, len(validIDs))
+        batch := validIDs[i:end]
+        
+        wg.Add(1)
+        
+        go func(batch []string, batchNum int) {
+            defer wg.Done()
+            
+            semaphore <- struct{}{}
+            defer func() { <-semaphore }()
+            
+            processedIDs := s.processCommentIDs(batch, len(batch))
+            if len(processedIDs) == 0 {
+                return
+            }
+            
+            data, err := s.client.FetchMoreComments(ctx, postID, processedIDs)
+            if err != nil {
+                fmt.Printf("Error fetching comments batch %d: %v\n", batchNum, err)
+                return
+            }
+            progressFrom(ctx).pageFetched()
+            
+            comments, err := s.parser.ParseMoreComments(ctx, data)
+            if err != nil {
+                fmt.Printf("Error parsing comments batch %d: %v\n", batchNum, err)
+                return
+            }
+            
+            if len(comments) > 0 {
+                mu.Lock()
+                allComments = append(allComments, comments...)
+                mu.Unlock()
+                
+                fmt.Printf("Batch %d: retrieved %d comments\n", batchNum, len(comments))
+            } else {
+                fmt.Printf("Batch %d: WARNING - retrieved 0 comments for %d IDs\n", 
+                    batchNum, len(processedIDs))
+            }
+        }(batch, i/batchSize)
+    }
+    
+    wg.Wait()
+    
+    // Log results
+    if len(allComments) == 0 && len(validIDs) > 0 {
+        fmt.Printf("WARNING: No comments returned for %d IDs\n", len(validIDs))
+    }
+    
+    return allComments, nil
+}
+
+func (s *scraperService) processCommentIDs(ids []string, limit int) []string {
+    if len(ids) == 0 {
+        return nil
+    }
+    
+    // Create a set to avoid duplicate IDs
+    uniqueIDs := make(map[string]bool)
+    
+    result := make([]string, 0, min(len(ids), limit))
+    for _, id := range ids {
+        if len(result) >= limit {
+            break
+        }
+        
+        // Skip continue links
+        if id == "continue" {
+            continue
+        }
+        
+        // Clean up ID format - ensure no t1_ prefix
+        cleanID := strings.TrimPrefix(id, "t1_")
+        
+        // Check for duplicates
+        if !uniqueIDs[cleanID] {
+            uniqueIDs[cleanID] = true
+            result = append(result, cleanID)
+        }
+    }
+    
+    return result
+}
+// placeComments puts the comments loaded for set into tree, each under the
+// parent its parent_id names, deduplicated and sorted.
+// commentSort is the sort of the request; comments keep the order Reddit
+// returned them in unless it sorts by time.
+func (s *scraperService) placeComments(tree *commentTree, set MoreCommentSet, newComments []models.Comment, commentSort string) {
+    if len(newComments) == 0 {
+        return
+    }
+    
+    idMap := make(map[string]bool)
+    var uniqueBatchComments []models.Comment
+    
+    for _, comment := range newComments {
+        if !idMap[comment.ID] {
+            idMap[comment.ID] = true
+            uniqueBatchComments = append(uniqueBatchComments, comment)
+        } else {
+            fmt.Printf("Skipping duplicate within batch, ID: %s\n", comment.ID)
+        }
+    }
+    
+    // Sort by creation time, newest first unless the request asked for oldest
+    switch commentSort {
+    case "", client.DefaultCommentSort:
+        sort.SliceStable(uniqueBatchComments, func(i, j int) bool {
+            return uniqueBatchComments[i].CreatedAt.After(uniqueBatchComments[j].CreatedAt)
+        })
+    case "old":
+        sort.SliceStable(uniqueBatchComments, func(i, j int) bool {
+            return uniqueBatchComments[i].CreatedAt.Before(uniqueBatchComments[j].CreatedAt)
+        })
+    }
+    
+    tree.place(set, uniqueBatchComments)
+}
+
+// countComments counts the total number of comments in a tree, one level of
+// replies at a time
+func (s *scraperService) countComments(comments []models.Comment) int {
+    count := 0
+    levels := [][]models.Comment{comments}
+    for len(levels) > 0 {
+        level := levels[len(levels)-1]
+        levels = levels[:len(levels)-1]
+        count += len(level)
+        for i := range level {
+            if len(level[i].Replies) > 0 {
+                levels = append(levels, level[i].Replies)
+            }
+        }
+    }
+    
+    return count
+}
+
+// Utility function for min
+func min(a, b int) int {
+    if a < b {
+        return a
+    }
+    return b
+}
+
+// Search function for Reddit content
+func (s *scraperService) Search(
+	ctx context.Context,
+	searchParams map[string]string,
+	sinceTimestamp int64,
+	limit int,
+	opts ListingOptions,
+) ([]models.Post, models.ListingMeta, error) {
+	ctx, progress := startProgress(ctx, "search")
+	posts, meta, err := s.search(ctx, searchParams, sinceTimestamp, limit, opts)
+	progress.finish(err)
+	return posts, meta, err
+}
+
+func (s *scraperService) search(
+	ctx context.Context,
+	searchParams map[string]string,
+	sinceTimestamp int64,
+	limit int,
+	opts ListingOptions,
+) ([]models.Post, models.ListingMeta, error) {
+	ctx = s.withProxySession(ctx)
+	ctx = withBulkPriority(ctx, limit)
+	startTime := time.Now()
+
+	// Without a cutoff, "all" results stop at a cap of our own
+	resultCap := 0
+	if limit == -1 && sinceTimestamp == 0 {
+		limit = 1000 
+		resultCap = limit
+		fmt.Printf("Limit was -1 with no timestamp filter for search, using default limit of %d\n", limit)
+	}
+	collector := newListingCollector(sinceTimestamp, limit, opts.Filter)
+	ctx, collector.report = parser.WithReport(ctx)
+	collector.requests = &utils.PageCounter{OnPage: progressFrom(ctx).listingPageFetched}
+	ctx = utils.WithPageCounter(ctx, collector.requests)
+
+	apiLimit := 100 
+	
+	
+	if limit > 0 && limit < apiLimit {
+		apiLimit = limit
+	}
+
+
+	searchParams["limit"] = strconv.Itoa(apiLimit)
+
+	// Let Reddit narrow the results too; the filter still checks every post
+	switch opts.Filter.NSFW {
+	case NSFWOnly:
+		searchParams["nsfw"] = "yes"
+	case NSFWExclude:
+		searchParams["nsfw"] = "no"
+	}
+
+	after := opts.After
+	pageCount := 0
+	maxPages := 10 
+
+	if limit == -1 && sinceTimestamp > 0 {
+		maxPages = 1000 
+		fmt.Printf("Fetching all search results until timestamp %d\n", sinceTimestamp)
+	} else if limit > 0 {
+		// Estimate pages needed based on limit
+		estimatedPages := (limit + apiLimit - 1) / apiLimit 
+		maxPages = estimatedPages * 2 
+		fmt.Printf("Fetching up to %d search results (estimated %d pages)\n", limit, estimatedPages)
+	}
+
+	for pageCount < maxPages {
+		if ctx.Err() != nil {
+			posts, meta := collector.finish(after)
+			return posts, meta, ctx.Err()
+		}
+
+		pageCount++
+
+
+		if after != "" {
+			searchParams["after"] = after
+		} else {
+			delete(searchParams, "after")
+		}
+
+		apiURL := s.client.GetSearchURL(searchParams)
+		fmt.Printf("Fetching search page %d\n", pageCount)
+
+		collector.startPage()
+		nextAfter, err := s.fetchListingPage(ctx, "search results", apiURL, collector.emit)
+		if listingGone(err, pageCount) {
+			fmt.Printf("Search page %d is gone, stopping pagination: %v\n", pageCount, err)
+			break
+		}
+		if err != nil {
+			return nil, models.ListingMeta{}, err
+		}
+
+		fmt.Printf("Search page %d yielded %d posts (total now: %d/%d)\n",
+			pageCount, collector.pagePosts, len(collector.posts), limit)
+
+		after = nextAfter
+
+		if limit > 0 && len(collector.posts) >= limit {
+			fmt.Println("Reached requested limit, stopping pagination")
+			if resultCap > 0 && nextAfter != "" {
+				collector.truncate(models.TruncatedPageCap)
+			}
+			break
+		}
+
+		if collector.meta.ReachedTimeCutoff {
+			fmt.Println("Reached time limit cutoff, stopping pagination")
+			break
+		}
+
+		if nextAfter == "" || collector.pageRead == 0 {
+			fmt.Println("No more pages available or empty page")
+			break
+		}
+
+		timeoutDuration := 60 * time.Second
+		if limit == -1 {
+			timeoutDuration = 3 * time.Minute
+		}
+		
+		if time.Since(startTime) > timeoutDuration && len(collector.posts) > 0 {
+			fmt.Printf("Time limit (%v) reached, returning results so far\n", timeoutDuration)
+			collector.meta.TimedOut = true
+			break
+		}
+
+		if pageCount == maxPages {
+			fmt.Printf("Reached the cap of %d pages, returning results so far\n", maxPages)
+			collector.truncate(models.TruncatedPageCap)
+			break
+		}
+
+		s.pause(ctx, 200*time.Millisecond)
+	}
+
+	if collector.hitListingCap(after) {
+		collector.meta.ReachedListingCap = true
+		collector.truncate(models.TruncatedListingCap)
+	}
+
+	posts, meta := collector.finish(after)
+
+	if meta.DuplicatesDropped > 0 {
+		fmt.Printf("Dropped %d duplicate search results\n", meta.DuplicatesDropped)
+	}
+	fmt.Printf("Final search result: %d posts fetched in %v\n", len(posts), time.Since(startTime))
+	return posts, meta, nil
+}
\ No newline at end of file
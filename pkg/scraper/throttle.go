@@ -0,0 +1,19 @@
+// pkg/scraper/throttle.go
+package scraper
+
+import (
+	"context"
+	"time"
+
+	"reddit-ingestion/pkg/utils"
+)
+
+// pauseJitter is the share by which pauses vary, so requests after a pause do
+// not go out at fixed intervals
+const pauseJitter = 0.3
+
+// pause waits base, stretched by the throttle level and varied by
+// pauseJitter, or until ctx is done
+func (s *scraperService) pause(ctx context.Context, base time.Duration) {
+	utils.SleepContext(ctx, s.opts.Throttle.Delay(base), pauseJitter)
+}
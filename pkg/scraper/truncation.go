@@ -0,0 +1,57 @@
+// pkg/scraper/truncation.go
+package scraper
+
+import (
+	"time"
+)
+
+// walkStop records where a walk through a user listing stopped: why it
+// stopped early, if it did, and the oldest item it read
+type walkStop struct {
+	reason string
+	oldest time.Time
+}
+
+// read notes the creation time of an item the walk read
+func (w *walkStop) read(createdAt time.Time) {
+	if w.oldest.IsZero() || createdAt.Before(w.oldest) {
+		w.oldest = createdAt
+	}
+}
+
+// truncate records why the walk stopped early, unless it already did
+func (w *walkStop) truncate(reason string) {
+	if w.reason == "" {
+		w.reason = reason
+	}
+}
+
+// mergeWalkStops combines the walks of a user's posts and comments into the
+// truncation meta of the response. When either was cut short, the oldest time
+// reached is the newest of those the truncated walks reached, as only items
+// after it were covered by both.
+func mergeWalkStops(stops ...walkStop) (truncated bool, reason string, oldest *time.Time) {
+	var reached time.Time
+	for _, stop := range stops {
+		if stop.reason == "" {
+			continue
+		}
+		if !truncated {
+			truncated, reason = true, stop.reason
+		}
+		if reached.IsZero() || stop.oldest.After(reached) {
+			reached = stop.oldest
+		}
+	}
+	if !truncated {
+		for _, stop := range stops {
+			if !stop.oldest.IsZero() && (reached.IsZero() || stop.oldest.Before(reached)) {
+				reached = stop.oldest
+			}
+		}
+	}
+	if reached.IsZero() {
+		return truncated, reason, nil
+	}
+	return truncated, reason, &reached
+}
@@ -0,0 +1,179 @@
+// pkg/scraper/user_overview.go
+package scraper
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"reddit-ingestion/pkg/models"
+	"reddit-ingestion/pkg/parser"
+	"reddit-ingestion/pkg/utils"
+)
+
+// overviewPageSize is how many items Reddit lists per overview page
+const overviewPageSize = 100
+
+// ScrapeUserOverview walks a user's overview, which interleaves posts and
+// comments, newest first. limit works as for ScrapeUserActivity: 0 reads the
+// first page, -1 everything (since sinceTimestamp, if set) and any other
+// value up to that many items. Pinned posts lead the overview whatever their
+// age, so they do not count towards the limit or end the walk at the
+// timestamp cutoff.
+func (s *scraperService) ScrapeUserOverview(
+	ctx context.Context,
+	username string,
+	sinceTimestamp int64,
+	limit int,
+) (models.UserOverview, error) {
+	ctx, progress := startProgress(ctx, "overview")
+	overview, err := s.scrapeUserOverview(ctx, username, sinceTimestamp, limit)
+	progress.finish(err)
+	return overview, err
+}
+
+func (s *scraperService) scrapeUserOverview(
+	ctx context.Context,
+	username string,
+	sinceTimestamp int64,
+	limit int,
+) (models.UserOverview, error) {
+	ctx = s.withProxySession(ctx)
+	ctx = withBulkPriority(ctx, limit)
+	ctx, report := parser.WithReport(ctx)
+	requests := &utils.PageCounter{OnPage: progressFrom(ctx).listingPageFetched}
+	ctx = utils.WithPageCounter(ctx, requests)
+
+	overview := models.UserOverview{
+		Username: username,
+		Items:    []models.UserOverviewItem{},
+		Meta:     &models.UserOverviewMeta{Ordering: models.OrderingNewestFirst},
+	}
+	meta := overview.Meta
+
+	var maxPages int
+	switch {
+	case limit == 0:
+		maxPages = 1
+	case limit == -1:
+		maxPages = 500
+	default:
+		maxPages = limit/overviewPageSize + 2
+		if maxPages > 50 {
+			maxPages = 50
+		}
+	}
+
+	seen := make(map[string]bool)
+	var stop walkStop
+	after := ""
+	unpinned := 0
+	startTime := time.Now()
+
+	// Each page is requested while the one before it is parsed
+	lookahead := 0
+	if limit != 0 {
+		lookahead = maxPages - 1
+	}
+	pages := s.newPagePipeline(ctx, func(after string) string {
+		return s.client.GetUserOverviewURL(username, after)
+	}, lookahead, 200*time.Millisecond)
+	defer pages.stop()
+
+	for meta.PagesFetched < maxPages {
+		if err := ctx.Err(); err != nil {
+			return overview, err
+		}
+
+		var page []models.UserOverviewItem
+		var nextAfter string
+		err := s.retryEmptyPages(ctx, "user "+username+" overview", func(ctx context.Context) (int, int, error) {
+			data, err := pages.fetch(ctx, after)
+			if err != nil {
+				return 0, 0, fmt.Errorf("fetch user overview: %w", err)
+			}
+			page, nextAfter, err = s.parser.ParseUserOverview(ctx, data)
+			if err != nil {
+				return 0, 0, fmt.Errorf("parse user overview: %w", err)
+			}
+			return len(page), len(data), nil
+		})
+		if listingGone(err, meta.PagesFetched+1) {
+			fmt.Printf("Overview page %d for user %s is gone, stopping pagination: %v\n", meta.PagesFetched+1, username, err)
+			break
+		}
+		if err != nil {
+			return overview, err
+		}
+		meta.PagesFetched++
+
+		read := 0
+		reachedLimit := false
+		for _, item := range page {
+			key := item.Kind + "/" + item.ID
+			if seen[key] {
+				meta.DuplicatesDropped++
+				continue
+			}
+			seen[key] = true
+
+			if item.Post != nil && item.Post.Pinned {
+				if sinceTimestamp == 0 || item.CreatedAt.Unix() >= sinceTimestamp {
+					overview.Items = append(overview.Items, item)
+				}
+				continue
+			}
+			if sinceTimestamp > 0 && item.CreatedAt.Unix() < sinceTimestamp {
+				meta.ReachedTimeCutoff = true
+				continue
+			}
+
+			read++
+			stop.read(item.CreatedAt)
+			overview.Items = append(overview.Items, item)
+			unpinned++
+			if limit > 0 && unpinned >= limit {
+				reachedLimit = true
+				break
+			}
+		}
+
+		if reachedLimit || meta.ReachedTimeCutoff || nextAfter == "" || read == 0 {
+			if nextAfter == "" && !reachedLimit && !meta.ReachedTimeCutoff && (limit == -1 || sinceTimestamp > 0) && len(seen) >= listingCapMin {
+				stop.truncate(models.TruncatedListingCap)
+			}
+			break
+		}
+		after = nextAfter
+
+		budget := 2 * time.Minute
+		if limit == -1 {
+			budget = 5 * time.Minute
+		}
+		if time.Since(startTime) > budget {
+			fmt.Printf("Time limit (%v) reached, returning overview of user %s so far\n", budget, username)
+			stop.truncate(models.TruncatedTimeLimit)
+			break
+		}
+
+		if limit != 0 && meta.PagesFetched == maxPages {
+			fmt.Printf("Reached the cap of %d pages, returning overview of user %s so far\n", maxPages, username)
+			stop.truncate(models.TruncatedPageCap)
+		}
+	}
+	meta.Truncated, meta.TruncationReason, meta.OldestReached = mergeWalkStops(stop)
+	meta.ParseReport = report.Result()
+	meta.Retries = requests.Retries()
+
+	// Newest first, then trim, so whatever cut the walk short the newest items survive
+	sort.SliceStable(overview.Items, func(i, j int) bool {
+		return overview.Items[i].CreatedAt.After(overview.Items[j].CreatedAt)
+	})
+	if limit > 0 && len(overview.Items) > limit {
+		overview.Items = overview.Items[:limit]
+	}
+
+	fmt.Printf("Fetched %d overview items in %d pages for user %s\n", len(overview.Items), meta.PagesFetched, username)
+	return overview, nil
+}
@@ -0,0 +1,102 @@
+// pkg/scraper/user_summary.go
+package scraper
+
+import (
+	"sort"
+	"strings"
+	"time"
+
+	"reddit-ingestion/pkg/models"
+)
+
+// mostActiveHours is how many hours of the day a summary names as most active
+const mostActiveHours = 3
+
+// SummarizeUserActivity aggregates a user's posts and comments per subreddit
+// and per hour of the day. Subreddits are matched ignoring case and named as
+// first seen; hours are in UTC.
+func SummarizeUserActivity(activity models.UserActivity) models.UserSummary {
+	summary := models.UserSummary{
+		Username:   activity.UserInfo.Username,
+		Posts:      len(activity.Posts),
+		Comments:   len(activity.Comments),
+		Subreddits: []models.SubredditActivity{},
+		Meta:       activity.Meta,
+	}
+
+	type totals struct {
+		activity      models.SubredditActivity
+		postScores    int
+		commentScores int
+	}
+	bySubreddit := make(map[string]*totals)
+	record := func(subreddit string, createdAt time.Time) *totals {
+		key := strings.ToLower(subreddit)
+		t, ok := bySubreddit[key]
+		if !ok {
+			t = &totals{activity: models.SubredditActivity{Subreddit: subreddit, FirstSeen: createdAt, LastSeen: createdAt}}
+			bySubreddit[key] = t
+		}
+		if createdAt.Before(t.activity.FirstSeen) {
+			t.activity.FirstSeen = createdAt
+		}
+		if createdAt.After(t.activity.LastSeen) {
+			t.activity.LastSeen = createdAt
+		}
+
+		if summary.FirstSeen == nil || createdAt.Before(*summary.FirstSeen) {
+			first := createdAt
+			summary.FirstSeen = &first
+		}
+		if summary.LastSeen == nil || createdAt.After(*summary.LastSeen) {
+			last := createdAt
+			summary.LastSeen = &last
+		}
+		summary.HourlyActivity[createdAt.UTC().Hour()]++
+		return t
+	}
+
+	for _, post := range activity.Posts {
+		t := record(post.Subreddit, post.CreatedAt)
+		t.activity.Posts++
+		t.postScores += post.Score
+	}
+	for _, comment := range activity.Comments {
+		t := record(comment.Subreddit, comment.CreatedAt)
+		t.activity.Comments++
+		t.commentScores += comment.Score
+	}
+
+	for _, t := range bySubreddit {
+		if t.activity.Posts > 0 {
+			t.activity.AveragePostScore = float64(t.postScores) / float64(t.activity.Posts)
+		}
+		if t.activity.Comments > 0 {
+			t.activity.AverageCommentScore = float64(t.commentScores) / float64(t.activity.Comments)
+		}
+		summary.Subreddits = append(summary.Subreddits, t.activity)
+	}
+	sort.Slice(summary.Subreddits, func(i, j int) bool {
+		a, b := summary.Subreddits[i], summary.Subreddits[j]
+		if a.Posts+a.Comments != b.Posts+b.Comments {
+			return a.Posts+a.Comments > b.Posts+b.Comments
+		}
+		return strings.ToLower(a.Subreddit) < strings.ToLower(b.Subreddit)
+	})
+
+	hours := make([]int, 0, 24)
+	for hour, count := range summary.HourlyActivity {
+		if count > 0 {
+			hours = append(hours, hour)
+		}
+	}
+	sort.SliceStable(hours, func(i, j int) bool {
+		return summary.HourlyActivity[hours[i]] > summary.HourlyActivity[hours[j]]
+	})
+	if len(hours) > mostActiveHours {
+		hours = hours[:mostActiveHours]
+	}
+	summary.MostActiveHours = hours
+
+	return summary
+}
@@ -0,0 +1,243 @@
+// pkg/scraper/user_windowing.go
+package scraper
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"reddit-ingestion/pkg/models"
+)
+
+// userWindow is one ordering of a user listing. Reddit pages each ordering
+// with its own cursor and caps it at roughly 1000 items, so walking several
+// orderings side by side reaches further into a long history and overlaps
+// the per-page latency instead of paying it serially.
+type userWindow struct {
+	sort string
+	t    string
+}
+
+var userWindows = []userWindow{
+	{sort: "new"},
+	{sort: "top", t: "all"},
+	{sort: "controversial", t: "all"},
+	{sort: "top", t: "year"},
+	{sort: "top", t: "month"},
+	{sort: "hot"},
+}
+
+// userWindowMaxPages bounds each window; 10 pages of 100 is Reddit's listing cap
+const userWindowMaxPages = 10
+
+// useUserWindows reports whether a user listing should be fetched as parallel
+// windows. Windows only pay off for full-history scrapes; a since_timestamp or
+// positive limit is served best by the newest-first walk that can stop early.
+func (s *scraperService) useUserWindows(sinceTimestamp int64, limit int) bool {
+	return s.opts.UserWindowWorkers > 1 && limit == -1 && sinceTimestamp == 0
+}
+
+// fetchUserPostWindows fetches a user's full post history across all windows,
+// merged by ID, filtered and ordered newest first
+func (s *scraperService) fetchUserPostWindows(ctx context.Context, username string, filter *itemFilter) ([]models.UserPost, walkStop, error) {
+	var mu sync.Mutex
+	seen := make(map[string]models.UserPost)
+	// Only the newest-first window covers the history without gaps
+	var stop, newest walkStop
+
+	capped, err := s.walkUserWindows(ctx, s.client.GetUserPostsURL(username, ""), func(window int, data json.RawMessage) (int, string, error) {
+		posts, after, err := s.parser.ParseUserPosts(ctx, data)
+		if err != nil {
+			return 0, "", fmt.Errorf("parse user posts: %w", err)
+		}
+		mu.Lock()
+		for _, post := range posts {
+			seen[post.ID] = post
+			if window == 0 {
+				newest.read(post.CreatedAt)
+			}
+		}
+		mu.Unlock()
+		return len(posts), after, nil
+	})
+	if err != nil {
+		return nil, stop, fmt.Errorf("fetch user posts: %w", err)
+	}
+
+	posts := make([]models.UserPost, 0, len(seen))
+	for _, post := range seen {
+		if !capped {
+			stop.read(post.CreatedAt)
+		}
+		if filter.keep(userPostItem(post)) {
+			posts = append(posts, post)
+		}
+	}
+	sort.Slice(posts, func(i, j int) bool {
+		return posts[i].CreatedAt.After(posts[j].CreatedAt)
+	})
+
+	if capped {
+		stop = newest
+		stop.truncate(models.TruncatedListingCap)
+	}
+	fmt.Printf("Final result: %d unique posts fetched for user %s across %d windows\n", len(posts), username, len(userWindows))
+	return posts, stop, nil
+}
+
+// fetchUserCommentWindows fetches a user's full comment history across all
+// windows, merged by ID, filtered and ordered newest first
+func (s *scraperService) fetchUserCommentWindows(ctx context.Context, username string, filter *itemFilter) ([]models.UserComment, walkStop, error) {
+	var mu sync.Mutex
+	seen := make(map[string]models.UserComment)
+	// Only the newest-first window covers the history without gaps
+	var stop, newest walkStop
+
+	capped, err := s.walkUserWindows(ctx, s.client.GetUserCommentsURL(username, ""), func(window int, data json.RawMessage) (int, string, error) {
+		comments, after, err := s.parser.ParseUserComments(ctx, data)
+		if err != nil {
+			return 0, "", fmt.Errorf("parse user comments: %w", err)
+		}
+		mu.Lock()
+		for _, comment := range comments {
+			seen[comment.ID] = comment
+			if window == 0 {
+				newest.read(comment.CreatedAt)
+			}
+		}
+		mu.Unlock()
+		return len(comments), after, nil
+	})
+	if err != nil {
+		return nil, stop, fmt.Errorf("fetch user comments: %w", err)
+	}
+
+	comments := make([]models.UserComment, 0, len(seen))
+	for _, comment := range seen {
+		if !capped {
+			stop.read(comment.CreatedAt)
+		}
+		if filter.keep(userCommentItem(comment)) {
+			comments = append(comments, comment)
+		}
+	}
+	sort.Slice(comments, func(i, j int) bool {
+		return comments[i].CreatedAt.After(comments[j].CreatedAt)
+	})
+
+	if capped {
+		stop = newest
+		stop.truncate(models.TruncatedListingCap)
+	}
+	fmt.Printf("Final result: %d unique comments fetched for user %s across %d windows\n", len(comments), username, len(userWindows))
+	return comments, stop, nil
+}
+
+// walkUserWindows pages every window of the listing at baseURL, at most
+// UserWindowWorkers at a time, passing each page to collect. collect returns
+// the number of items on the page and the next cursor; window is the index of
+// the window in userWindows, 0 being newest first. Only a failure of the
+// newest-first window is fatal; the others just add coverage. It reports
+// whether the newest-first window still had a cursor at its page cap, so the
+// history goes back further than the windows reached.
+func (s *scraperService) walkUserWindows(
+	ctx context.Context,
+	baseURL string,
+	collect func(window int, data json.RawMessage) (int, string, error),
+) (bool, error) {
+	sem := make(chan struct{}, s.opts.UserWindowWorkers)
+	errs := make([]error, len(userWindows))
+	var capped bool
+	var wg sync.WaitGroup
+
+	for i, window := range userWindows {
+		wg.Add(1)
+		go func(i int, window userWindow) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			after := ""
+			for page := 1; page <= userWindowMaxPages; page++ {
+				if ctx.Err() != nil {
+					errs[i] = ctx.Err()
+					return
+				}
+
+				var count int
+				var next string
+				err := s.retryEmptyPages(ctx, "window "+window.sort+"/"+window.t, func(ctx context.Context) (int, int, error) {
+					data, err := s.client.FetchJSON(ctx, userWindowURL(baseURL, window, after))
+					if err != nil {
+						return 0, 0, err
+					}
+					count, next, err = collect(i, data)
+					return count, len(data), err
+				})
+				if listingGone(err, page) {
+					return
+				}
+				if err != nil {
+					errs[i] = err
+					return
+				}
+
+				if next == "" || count == 0 {
+					return
+				}
+				if i == 0 && page == userWindowMaxPages {
+					capped = true
+				}
+				after = next
+				s.pause(ctx, 200*time.Millisecond)
+			}
+		}(i, window)
+	}
+	wg.Wait()
+
+	for i, err := range errs {
+		if err == nil {
+			continue
+		}
+		if i == 0 {
+			return false, err
+		}
+		fmt.Printf("Window %s/%s failed, continuing with the others: %v\n", userWindows[i].sort, userWindows[i].t, err)
+	}
+	return capped, nil
+}
+
+// userWindowURL rewrites a user listing URL for the given ordering and cursor
+func userWindowURL(baseURL string, window userWindow, after string) string {
+	u, err := url.Parse(baseURL)
+	if err != nil {
+		return baseURL
+	}
+
+	// /submitted/new.json pins the order in the path; use the plain listing
+	u.Path = strings.TrimSuffix(u.Path, "/new.json")
+	if !strings.HasSuffix(u.Path, ".json") {
+		u.Path += ".json"
+	}
+
+	q := u.Query()
+	q.Set("sort", window.sort)
+	q.Set("limit", "100")
+	if window.t != "" {
+		q.Set("t", window.t)
+	} else {
+		q.Del("t")
+	}
+	if after != "" {
+		q.Set("after", after)
+	} else {
+		q.Del("after")
+	}
+	u.RawQuery = q.Encode()
+	return u.String()
+}
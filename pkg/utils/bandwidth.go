@@ -0,0 +1,126 @@
+// pkg/utils/bandwidth.go
+package utils
+
+import (
+	"errors"
+	"net"
+	"net/url"
+	"sync"
+	"time"
+)
+
+// ErrBandwidthExhausted is returned when every proxy is at its daily or monthly bandwidth cap
+var ErrBandwidthExhausted = errors.New("every proxy has used its daily or monthly bandwidth budget")
+
+// bandwidthReserveFraction of the cap is held back: a proxy with less than
+// this left is skipped, so a large response does not run it over its plan
+const bandwidthReserveFraction = 20
+
+// BandwidthBudget counts the bytes each proxy moves during the current UTC day
+// and enforces an optional daily cap per proxy. Counters reset at UTC midnight.
+type BandwidthBudget struct {
+	mutex    sync.Mutex
+	capBytes int64
+	day      string
+	used     map[string]int64
+}
+
+// ProxyBandwidth is one proxy's usage for the current UTC day
+type ProxyBandwidth struct {
+	Proxy          string   `json:"proxy"`
+	Labels         []string `json:"labels,omitempty"`
+	UsedBytes      int64    `json:"used_bytes"`
+	CapBytes       int64    `json:"cap_bytes,omitempty"`
+	RemainingBytes int64    `json:"remaining_bytes,omitempty"`
+	Available      bool     `json:"available"`
+}
+
+// NewBandwidthBudget creates a budget with the given daily cap per proxy; 0 only counts
+func NewBandwidthBudget(capBytes int64) *BandwidthBudget {
+	return &BandwidthBudget{
+		capBytes: capBytes,
+		day:      today(),
+		used:     make(map[string]int64),
+	}
+}
+
+func today() string {
+	return time.Now().UTC().Format("2006-01-02")
+}
+
+// SetCap changes the daily cap per proxy; 0 removes it
+func (b *BandwidthBudget) SetCap(capBytes int64) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	b.capBytes = capBytes
+}
+
+// rollover resets the counters when the UTC day changes; callers hold the mutex
+func (b *BandwidthBudget) rollover() {
+	if day := today(); day != b.day {
+		b.day = day
+		b.used = make(map[string]int64)
+	}
+}
+
+// Add records n bytes moved through proxy
+func (b *BandwidthBudget) Add(proxy string, n int64) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	b.rollover()
+	b.used[proxy] += n
+}
+
+// Available reports whether proxy still has budget left beyond the reserve
+func (b *BandwidthBudget) Available(proxy string) bool {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	b.rollover()
+	return b.capBytes <= 0 || b.capBytes-b.used[proxy] > b.capBytes/bandwidthReserveFraction
+}
+
+// Usage reports today's usage of each given proxy, with credentials masked
+func (b *BandwidthBudget) Usage(proxies []*url.URL) []ProxyBandwidth {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	b.rollover()
+
+	usage := make([]ProxyBandwidth, 0, len(proxies))
+	for _, proxyURL := range proxies {
+		key := proxyURL.String()
+		display := *proxyURL
+		display.Fragment = ""
+		entry := ProxyBandwidth{
+			Proxy:     maskProxyURL(display.String()),
+			Labels:    proxyLabels(proxyURL),
+			UsedBytes: b.used[key],
+			Available: true,
+		}
+		if b.capBytes > 0 {
+			entry.CapBytes = b.capBytes
+			entry.RemainingBytes = max(b.capBytes-entry.UsedBytes, 0)
+			entry.Available = entry.RemainingBytes > b.capBytes/bandwidthReserveFraction
+		}
+		usage = append(usage, entry)
+	}
+	return usage
+}
+
+// countingConn reports every byte read or written, TLS overhead included,
+// since that is what a metered proxy bills
+type countingConn struct {
+	net.Conn
+	count func(int64)
+}
+
+func (c *countingConn) Read(p []byte) (int, error) {
+	n, err := c.Conn.Read(p)
+	c.count(int64(n))
+	return n, err
+}
+
+func (c *countingConn) Write(p []byte) (int, error) {
+	n, err := c.Conn.Write(p)
+	c.count(int64(n))
+	return n, err
+}
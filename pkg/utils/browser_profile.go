@@ -0,0 +1,165 @@
+// pkg/utils/browser_profile.go
+package utils
+
+import (
+	"strings"
+
+	utls "github.com/refraction-networking/utls"
+)
+
+// BrowserProfile bundles everything that identifies one browser on the wire,
+// so the TLS ClientHello, User-Agent and headers of a request always agree
+// with each other. A Safari User-Agent never travels with a Chrome JA3.
+type BrowserProfile struct {
+	Name        string
+	Browser     BrowserType
+	ClientHello utls.ClientHelloID
+
+	// User-Agent strings of versions whose ClientHello matches ClientHello
+	UserAgents []string
+
+	Accept         string
+	AcceptEncoding string
+
+	// Headers this browser sends on every top-level navigation
+	NavigationHeaders map[string]string
+
+	// Order in which this browser sends its request headers
+	HeaderOrder []string
+	// Order in which it sends the HTTP/2 pseudo-headers, before the others
+	PseudoHeaderOrder []string
+}
+
+var browserProfiles = []BrowserProfile{
+	{
+		Name:        "chrome",
+		Browser:     Chrome,
+		ClientHello: utls.HelloChrome_Auto,
+		UserAgents: []string{
+			"Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/121.0.0.0 Safari/537.36",
+			"Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/122.0.0.0 Safari/537.36",
+			"Mozilla/5.0 (X11; Linux x86_64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/121.0.0.0 Safari/537.36",
+			"Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/122.0.0.0 Safari/537.36",
+		},
+		Accept:         "text/html,application/xhtml+xml,application/xml;q=0.9,image/avif,image/webp,image/apng,*/*;q=0.8,application/signed-exchange;v=b3;q=0.7",
+		AcceptEncoding: "gzip, deflate, br",
+		NavigationHeaders: map[string]string{
+			"Upgrade-Insecure-Requests": "1",
+			"Sec-Fetch-Site":            "none",
+			"Sec-Fetch-Mode":            "navigate",
+			"Sec-Fetch-User":            "?1",
+			"Sec-Fetch-Dest":            "document",
+		},
+		HeaderOrder: []string{
+			"Host", "Connection", "Cache-Control", "Upgrade-Insecure-Requests", "User-Agent", "Accept",
+			"Sec-Fetch-Site", "Sec-Fetch-Mode", "Sec-Fetch-User", "Sec-Fetch-Dest",
+			"Accept-Encoding", "Accept-Language", "Cookie",
+		},
+		PseudoHeaderOrder: []string{":method", ":authority", ":scheme", ":path"},
+	},
+	{
+		Name:        "firefox",
+		Browser:     Firefox,
+		ClientHello: utls.HelloFirefox_Auto,
+		UserAgents: []string{
+			"Mozilla/5.0 (Windows NT 10.0; Win64; x64; rv:123.0) Gecko/20100101 Firefox/123.0",
+			"Mozilla/5.0 (Macintosh; Intel Mac OS X 10.15; rv:124.0) Gecko/20100101 Firefox/124.0",
+			"Mozilla/5.0 (X11; Linux x86_64; rv:122.0) Gecko/20100101 Firefox/122.0",
+			"Mozilla/5.0 (Windows NT 10.0; Win64; x64; rv:124.0) Gecko/20100101 Firefox/124.0",
+		},
+		Accept:         "text/html,application/xhtml+xml,application/xml;q=0.9,image/avif,image/webp,*/*;q=0.8",
+		AcceptEncoding: "gzip, deflate, br",
+		NavigationHeaders: map[string]string{
+			"Upgrade-Insecure-Requests": "1",
+			"Sec-Fetch-Dest":            "document",
+			"Sec-Fetch-Mode":            "navigate",
+			"Sec-Fetch-Site":            "none",
+			"Sec-Fetch-User":            "?1",
+			"TE":                        "trailers",
+		},
+		HeaderOrder: []string{
+			"Host", "User-Agent", "Accept", "Accept-Language", "Accept-Encoding", "DNT", "Connection",
+			"Cookie", "Upgrade-Insecure-Requests",
+			"Sec-Fetch-Dest", "Sec-Fetch-Mode", "Sec-Fetch-Site", "Sec-Fetch-User",
+			"Cache-Control", "TE",
+		},
+		PseudoHeaderOrder: []string{":method", ":path", ":authority", ":scheme"},
+	},
+	{
+		Name:        "safari",
+		Browser:     Safari,
+		ClientHello: utls.HelloSafari_Auto,
+		UserAgents: []string{
+			"Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/16.5 Safari/605.1.15",
+			"Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.0 Safari/605.1.15",
+		},
+		Accept:         "text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8",
+		AcceptEncoding: "gzip, deflate, br",
+		NavigationHeaders: map[string]string{
+			"Sec-Fetch-Site": "none",
+			"Sec-Fetch-Mode": "navigate",
+			"Sec-Fetch-Dest": "document",
+		},
+		HeaderOrder: []string{
+			"Host", "Sec-Fetch-Site", "Cookie", "Connection", "Sec-Fetch-Mode", "Accept",
+			"User-Agent", "Accept-Language", "Sec-Fetch-Dest", "Cache-Control", "Accept-Encoding",
+		},
+		PseudoHeaderOrder: []string{":method", ":scheme", ":path", ":authority"},
+	},
+	{
+		Name:        "edge",
+		Browser:     Edge,
+		ClientHello: utls.HelloEdge_Auto,
+		UserAgents: []string{
+			"Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/121.0.0.0 Safari/537.36 Edg/121.0.2277.128",
+			"Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/122.0.0.0 Safari/537.36 Edg/122.0.2365.66",
+			"Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/122.0.0.0 Safari/537.36 Edg/122.0.2365.80",
+		},
+		Accept:         "text/html,application/xhtml+xml,application/xml;q=0.9,image/avif,image/webp,image/apng,*/*;q=0.8,application/signed-exchange;v=b3;q=0.7",
+		AcceptEncoding: "gzip, deflate, br",
+		NavigationHeaders: map[string]string{
+			"Upgrade-Insecure-Requests": "1",
+			"Sec-Fetch-Site":            "none",
+			"Sec-Fetch-Mode":            "navigate",
+			"Sec-Fetch-User":            "?1",
+			"Sec-Fetch-Dest":            "document",
+		},
+		HeaderOrder: []string{
+			"Host", "Connection", "Cache-Control", "Upgrade-Insecure-Requests", "User-Agent", "Accept",
+			"Sec-Fetch-Site", "Sec-Fetch-Mode", "Sec-Fetch-User", "Sec-Fetch-Dest",
+			"Accept-Encoding", "Accept-Language", "Cookie",
+		},
+		PseudoHeaderOrder: []string{":method", ":authority", ":scheme", ":path"},
+	},
+}
+
+// BrowserProfiles returns the built-in browser profiles
+func BrowserProfiles() []BrowserProfile {
+	return append([]BrowserProfile(nil), browserProfiles...)
+}
+
+// profileIndexForUserAgent picks the profile whose browser sent userAgent, so
+// a fixed REDDIT_USER_AGENT still gets a matching ClientHello. ok is false for
+// user agents that match no profile.
+func profileIndexForUserAgent(userAgent string) (idx int, ok bool) {
+	var browser BrowserType
+	switch {
+	case strings.Contains(userAgent, "Edg/"):
+		browser = Edge
+	case strings.Contains(userAgent, "Firefox/"):
+		browser = Firefox
+	case strings.Contains(userAgent, "Chrome/"):
+		browser = Chrome
+	case strings.Contains(userAgent, "Safari/"):
+		browser = Safari
+	default:
+		return 0, false
+	}
+
+	for i, profile := range browserProfiles {
+		if profile.Browser == browser {
+			return i, true
+		}
+	}
+	return 0, false
+}
@@ -0,0 +1,74 @@
+// pkg/utils/cookie_jar.go
+package utils
+
+import (
+	"net/http/cookiejar"
+	"sync"
+	"time"
+
+	"golang.org/x/net/publicsuffix"
+)
+
+// CookieJars keeps a cookie jar per proxy and browser profile, so the
+// requests of a session send back the cookies Reddit set (loid, session
+// tracking) like the browser they present as, instead of arriving without
+// any. A jar is dropped once it is older than the TTL, as a browser session
+// would end. It is safe for concurrent use.
+type CookieJars struct {
+	mutex sync.Mutex
+	ttl   time.Duration
+	jars  map[transportKey]*expiringJar
+}
+
+type expiringJar struct {
+	jar     *cookiejar.Jar
+	expires time.Time
+}
+
+// NewCookieJars creates jars that last ttl; a ttl of 0 keeps no cookies
+func NewCookieJars(ttl time.Duration) *CookieJars {
+	return &CookieJars{ttl: ttl, jars: make(map[transportKey]*expiringJar)}
+}
+
+// SetTTL changes how long new jars last; 0 or less drops every jar and keeps
+// no cookies
+func (j *CookieJars) SetTTL(ttl time.Duration) {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+	j.ttl = ttl
+	if ttl <= 0 {
+		clear(j.jars)
+	}
+}
+
+// jarFor returns the jar of key, a fresh one when it has none or its jar
+// expired, or nil when cookies are off
+func (j *CookieJars) jarFor(key transportKey) *cookiejar.Jar {
+	if j == nil {
+		return nil
+	}
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+	if j.ttl <= 0 {
+		return nil
+	}
+
+	now := time.Now()
+	if jar, ok := j.jars[key]; ok && now.Before(jar.expires) {
+		return jar.jar
+	}
+	jar, _ := cookiejar.New(&cookiejar.Options{PublicSuffixList: publicsuffix.List})
+	j.jars[key] = &expiringJar{jar: jar, expires: now.Add(j.ttl)}
+	return jar
+}
+
+// prune drops the jars of proxies no longer in rotation
+func (j *CookieJars) prune(inRotation map[string]bool) {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+	for key := range j.jars {
+		if key.proxy != "" && !inRotation[key.proxy] {
+			delete(j.jars, key)
+		}
+	}
+}
@@ -0,0 +1,362 @@
+// pkg/utils/header_order.go
+package utils
+
+import (
+	"bytes"
+	"encoding/binary"
+	"net"
+	"net/textproto"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/hpack"
+)
+
+// Go writes HTTP/1.1 headers sorted by name and HTTP/2 headers in random map
+// order, while a browser sends them in the same order every time, and
+// anti-bot systems compare the two. headerOrderConn sits under the HTTP
+// transports and rewrites each request's header block into the order of the
+// connection's browser profile before it goes on the wire.
+
+// http2MaxFrameSize is the frame size every HTTP/2 peer accepts
+const http2MaxFrameSize = 16384
+
+// headerOrderConn reorders the request headers written to an HTTP/1.1 or
+// HTTP/2 connection
+type headerOrderConn struct {
+	net.Conn
+	profile *BrowserProfile
+	h2      bool
+
+	mutex   sync.Mutex
+	pending []byte
+
+	// HTTP/1.1: body bytes of the current request still to pass through,
+	// and whether the connection carries a body of unknown length, after
+	// which requests can no longer be told apart and pass as written
+	bodyLeft    int64
+	passThrough bool
+
+	// HTTP/2: whether the client preface went out, the header block being
+	// collected from HEADERS and CONTINUATION frames, and the HPACK contexts
+	// of Go's encoder (mirrored by decoder) and of the rewritten blocks
+	prefaceSent bool
+	block       *headerBlock
+	decoder     *hpack.Decoder
+	encoder     *hpack.Encoder
+	encoded     bytes.Buffer
+}
+
+type headerBlock struct {
+	streamID  uint32
+	endStream bool
+	priority  []byte
+	fragment  []byte
+}
+
+// newHeaderOrderConn wraps conn, which speaks HTTP/2 when h2 is set, to send
+// request headers in the order of profile
+func newHeaderOrderConn(conn net.Conn, profile *BrowserProfile, h2 bool) net.Conn {
+	c := &headerOrderConn{Conn: conn, profile: profile, h2: h2}
+	if h2 {
+		c.decoder = hpack.NewDecoder(4096, nil)
+		c.encoder = hpack.NewEncoder(&c.encoded)
+	}
+	return c
+}
+
+// Write passes p on once it holds whole header blocks, rewritten. It reports
+// p as written when it only buffered it.
+func (c *headerOrderConn) Write(p []byte) (int, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	var err error
+	if c.h2 {
+		err = c.writeHTTP2(p)
+	} else {
+		err = c.writeHTTP1(p)
+	}
+	if err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+func (c *headerOrderConn) writeHTTP1(p []byte) error {
+	for len(p) > 0 {
+		if c.passThrough {
+			_, err := c.Conn.Write(p)
+			return err
+		}
+		if c.bodyLeft > 0 {
+			n := int(min(c.bodyLeft, int64(len(p))))
+			if _, err := c.Conn.Write(p[:n]); err != nil {
+				return err
+			}
+			c.bodyLeft -= int64(n)
+			p = p[n:]
+			continue
+		}
+
+		c.pending = append(c.pending, p...)
+		p = nil
+		end := bytes.Index(c.pending, []byte("\r\n\r\n"))
+		if end < 0 {
+			return nil
+		}
+		head, rest := c.pending[:end+4], c.pending[end+4:]
+		c.pending = nil
+		if _, err := c.Conn.Write(c.orderHTTP1(head)); err != nil {
+			return err
+		}
+		p = rest
+	}
+	return nil
+}
+
+// orderHTTP1 reorders the header lines of a request head, which ends with an
+// empty line, and notes how much body follows it
+func (c *headerOrderConn) orderHTTP1(head []byte) []byte {
+	lines := strings.Split(strings.TrimSuffix(string(head), "\r\n\r\n"), "\r\n")
+	fields := lines[1:]
+	names := make([]string, len(fields))
+	for i, line := range fields {
+		name, value, _ := strings.Cut(line, ":")
+		// Spell names like the browser, e.g. DNT rather than Go's Dnt
+		for _, ordered := range c.profile.HeaderOrder {
+			if name != ordered && strings.EqualFold(name, ordered) {
+				name = ordered
+				fields[i] = name + ":" + value
+			}
+		}
+		names[i] = name
+		switch textproto.CanonicalMIMEHeaderKey(name) {
+		case "Content-Length":
+			c.bodyLeft, _ = strconv.ParseInt(strings.TrimSpace(value), 10, 64)
+		case "Transfer-Encoding":
+			c.passThrough = true
+		}
+	}
+	sortByOrder(names, fields, c.profile.HeaderOrder)
+	return []byte(lines[0] + "\r\n" + strings.Join(fields, "\r\n") + "\r\n\r\n")
+}
+
+func (c *headerOrderConn) writeHTTP2(p []byte) error {
+	c.pending = append(c.pending, p...)
+	if !c.prefaceSent {
+		if len(c.pending) < len(http2.ClientPreface) {
+			return nil
+		}
+		if _, err := c.Conn.Write(c.pending[:len(http2.ClientPreface)]); err != nil {
+			return err
+		}
+		c.pending = c.pending[len(http2.ClientPreface):]
+		c.prefaceSent = true
+	}
+
+	// Pass whole frames on, holding back HEADERS and CONTINUATION frames
+	// until their block is complete
+	var out []byte
+	for len(c.pending) >= 9 {
+		length := int(c.pending[0])<<16 | int(c.pending[1])<<8 | int(c.pending[2])
+		if len(c.pending) < 9+length {
+			break
+		}
+		frame := c.pending[:9+length]
+		c.pending = c.pending[9+length:]
+
+		var err error
+		switch http2.FrameType(frame[3]) {
+		case http2.FrameHeaders, http2.FrameContinuation:
+			out, err = c.collectHeaders(out, frame)
+		default:
+			out = append(out, frame...)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	// Keep the buffer from growing with the frames already written
+	c.pending = append([]byte(nil), c.pending...)
+	if len(out) == 0 {
+		return nil
+	}
+	_, err := c.Conn.Write(out)
+	return err
+}
+
+// collectHeaders adds a HEADERS or CONTINUATION frame to the current block
+// and appends the rewritten block to out once it is complete
+func (c *headerOrderConn) collectHeaders(out, frame []byte) ([]byte, error) {
+	flags := http2.Flags(frame[4])
+	payload := frame[9:]
+	if http2.FrameType(frame[3]) == http2.FrameHeaders {
+		c.block = &headerBlock{
+			streamID:  binary.BigEndian.Uint32(frame[5:9]) & (1<<31 - 1),
+			endStream: flags.Has(http2.FlagHeadersEndStream),
+		}
+		if flags.Has(http2.FlagHeadersPadded) && len(payload) > 0 {
+			padding := int(payload[0])
+			payload = payload[1:]
+			if padding > len(payload) {
+				return nil, http2.ConnectionError(http2.ErrCodeProtocol)
+			}
+			payload = payload[:len(payload)-padding]
+		}
+		if flags.Has(http2.FlagHeadersPriority) && len(payload) >= 5 {
+			c.block.priority = append([]byte(nil), payload[:5]...)
+			payload = payload[5:]
+		}
+	}
+	if c.block == nil {
+		return nil, http2.ConnectionError(http2.ErrCodeProtocol)
+	}
+	c.block.fragment = append(c.block.fragment, payload...)
+	if !flags.Has(http2.FlagHeadersEndHeaders) {
+		return out, nil
+	}
+
+	block := c.block
+	c.block = nil
+	fragment, err := c.reorderBlock(block.fragment)
+	if err != nil {
+		return nil, err
+	}
+	return appendHeaderFrames(out, block, fragment), nil
+}
+
+// reorderBlock decodes a header block as Go's encoder wrote it and encodes
+// it again in the profile's order
+func (c *headerOrderConn) reorderBlock(fragment []byte) ([]byte, error) {
+	// Go's encoder shrinks its table when the server asks for a smaller one;
+	// the rewritten blocks must stay within the same limit
+	for _, size := range tableSizeUpdates(fragment) {
+		c.encoder.SetMaxDynamicTableSizeLimit(size)
+	}
+	fields, err := c.decoder.DecodeFull(fragment)
+	if err != nil {
+		return nil, err
+	}
+
+	var pseudo, regular []hpack.HeaderField
+	for _, field := range fields {
+		if field.IsPseudo() {
+			pseudo = append(pseudo, field)
+		} else {
+			regular = append(regular, field)
+		}
+	}
+	orderFields(pseudo, c.profile.PseudoHeaderOrder)
+	orderFields(regular, c.profile.HeaderOrder)
+
+	c.encoded.Reset()
+	for _, field := range append(pseudo, regular...) {
+		if err := c.encoder.WriteField(field); err != nil {
+			return nil, err
+		}
+	}
+	return append([]byte(nil), c.encoded.Bytes()...), nil
+}
+
+// appendHeaderFrames appends block with fragment as its header block to out,
+// in a HEADERS frame followed by CONTINUATION frames as needed
+func appendHeaderFrames(out []byte, block *headerBlock, fragment []byte) []byte {
+	frameType := http2.FrameHeaders
+	payload := append(block.priority, fragment...)
+	for {
+		n := min(len(payload), http2MaxFrameSize)
+		var flags http2.Flags
+		if frameType == http2.FrameHeaders {
+			if block.endStream {
+				flags |= http2.FlagHeadersEndStream
+			}
+			if block.priority != nil {
+				flags |= http2.FlagHeadersPriority
+			}
+		}
+		if n == len(payload) {
+			flags |= http2.FlagHeadersEndHeaders
+		}
+		out = append(out, byte(n>>16), byte(n>>8), byte(n), byte(frameType), byte(flags))
+		out = binary.BigEndian.AppendUint32(out, block.streamID)
+		out = append(out, payload[:n]...)
+
+		payload = payload[n:]
+		if len(payload) == 0 {
+			return out
+		}
+		frameType = http2.FrameContinuation
+	}
+}
+
+// tableSizeUpdates returns the dynamic table size updates at the start of an
+// HPACK header block (RFC 7541 section 6.3)
+func tableSizeUpdates(fragment []byte) []uint32 {
+	var sizes []uint32
+	for len(fragment) > 0 && fragment[0]&0xe0 == 0x20 {
+		size := uint64(fragment[0] & 0x1f)
+		fragment = fragment[1:]
+		if size == 0x1f {
+			for shift := uint(0); ; shift += 7 {
+				if len(fragment) == 0 || shift > 28 {
+					return sizes
+				}
+				b := fragment[0]
+				fragment = fragment[1:]
+				size += uint64(b&0x7f) << shift
+				if b&0x80 == 0 {
+					break
+				}
+			}
+		}
+		sizes = append(sizes, uint32(size))
+	}
+	return sizes
+}
+
+// orderFields sorts HTTP/2 header fields into order
+func orderFields(fields []hpack.HeaderField, order []string) {
+	names := make([]string, len(fields))
+	for i, field := range fields {
+		names[i] = field.Name
+	}
+	sortByOrder(names, fields, order)
+}
+
+// sortByOrder sorts items, whose header names are names, by the position of
+// their name in order, compared case-insensitively. Items of the same name
+// keep their order, and those whose name is not in order go last as they
+// came.
+func sortByOrder[T any](names []string, items []T, order []string) {
+	rank := func(name string) int {
+		for i, ordered := range order {
+			if strings.EqualFold(name, ordered) {
+				return i
+			}
+		}
+		return len(order)
+	}
+	ranks := make([]int, len(names))
+	for i, name := range names {
+		ranks[i] = rank(name)
+	}
+	sort.Stable(byRank{ranks: ranks, swap: func(i, j int) {
+		items[i], items[j] = items[j], items[i]
+	}})
+}
+
+type byRank struct {
+	ranks []int
+	swap  func(i, j int)
+}
+
+func (b byRank) Len() int           { return len(b.ranks) }
+func (b byRank) Less(i, j int) bool { return b.ranks[i] < b.ranks[j] }
+func (b byRank) Swap(i, j int) {
+	b.ranks[i], b.ranks[j] = b.ranks[j], b.ranks[i]
+	b.swap(i, j)
+}
@@ -0,0 +1,68 @@
+// pkg/utils/host_allowlist.go
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// ErrHostNotAllowed is returned for a request, or a redirect, to a host
+// outside the client's allowlist
+var ErrHostNotAllowed = errors.New("host not allowed")
+
+// HostAllowlist holds the hosts a client may send requests to. A nil
+// HostAllowlist allows every host.
+type HostAllowlist struct {
+	hosts []string
+}
+
+// NewHostAllowlist allows each of hosts and its subdomains. An entry may be a
+// bare host, such as reddit.com, or a URL, whose host is taken; ports are
+// ignored.
+func NewHostAllowlist(hosts ...string) *HostAllowlist {
+	a := &HostAllowlist{}
+	for _, host := range hosts {
+		if host = allowlistHost(host); host != "" {
+			a.hosts = append(a.hosts, host)
+		}
+	}
+	return a
+}
+
+// allowlistHost is the lowercase host name of an allowlist entry
+func allowlistHost(entry string) string {
+	entry = strings.TrimSpace(entry)
+	if !strings.Contains(entry, "://") {
+		entry = "//" + entry
+	}
+	u, err := url.Parse(entry)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimPrefix(strings.ToLower(u.Hostname()), "*.")
+}
+
+// Allows reports whether host, with or without a port, is an allowed host or
+// a subdomain of one
+func (a *HostAllowlist) Allows(host string) bool {
+	if a == nil {
+		return true
+	}
+	host = allowlistHost(host)
+	for _, allowed := range a.hosts {
+		if host == allowed || strings.HasSuffix(host, "."+allowed) {
+			return true
+		}
+	}
+	return false
+}
+
+// check fails with ErrHostNotAllowed unless the host of u is allowed
+func (a *HostAllowlist) check(u *url.URL) error {
+	if !a.Allows(u.Host) {
+		return fmt.Errorf("%w: %s", ErrHostNotAllowed, u.Hostname())
+	}
+	return nil
+}
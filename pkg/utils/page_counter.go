@@ -0,0 +1,62 @@
+// pkg/utils/page_counter.go
+package utils
+
+import (
+	"context"
+	"net/http"
+	"sync/atomic"
+)
+
+type pageCounterKey struct{}
+
+// PageCounter counts the responses Reddit served to the requests of one
+// operation; retries of failed requests are not counted as pages but on
+// their own
+type PageCounter struct {
+	// OnPage, when set, is called after each page is counted, on the
+	// goroutine that fetched it
+	OnPage func()
+
+	pages   int64
+	retries int64
+	// Counter of the enclosing operation, which counts the same requests
+	parent *PageCounter
+}
+
+// WithPageCounter counts the pages fetched with the returned context in c, and
+// in the counter ctx already carries
+func WithPageCounter(ctx context.Context, c *PageCounter) context.Context {
+	if parent, ok := ctx.Value(pageCounterKey{}).(*PageCounter); ok && parent != c {
+		c.parent = parent
+	}
+	return context.WithValue(ctx, pageCounterKey{}, c)
+}
+
+// Pages is the number of pages fetched so far
+func (c *PageCounter) Pages() int64 {
+	return atomic.LoadInt64(&c.pages)
+}
+
+// Retries is the number of attempts made so far after a failed one
+func (c *PageCounter) Retries() int64 {
+	return atomic.LoadInt64(&c.retries)
+}
+
+// countPage counts a response to req in the counters its context carries
+func countPage(req *http.Request) {
+	c, _ := req.Context().Value(pageCounterKey{}).(*PageCounter)
+	for ; c != nil; c = c.parent {
+		atomic.AddInt64(&c.pages, 1)
+		if c.OnPage != nil {
+			c.OnPage()
+		}
+	}
+}
+
+// countRetry counts a retry of req in the counters its context carries
+func countRetry(req *http.Request) {
+	c, _ := req.Context().Value(pageCounterKey{}).(*PageCounter)
+	for ; c != nil; c = c.parent {
+		atomic.AddInt64(&c.retries, 1)
+	}
+}
@@ -2,24 +2,29 @@
 package utils
 
 import (
+	"bufio"
 	"bytes"
+	"compress/flate"
 	"compress/gzip"
+	"compress/zlib"
 	"context"
+	"crypto/tls"
+	"encoding/base64"
+	"errors"
 	"fmt"
 	"io"
 	"math/rand"
 	"net"
 	"net/http"
 	"net/url"
-	"os"
-	"strconv"
 	"strings"
 	"sync"
 	"sync/atomic"
 	"time"
 
+	"github.com/andybalholm/brotli"
+	"github.com/klauspost/compress/zstd"
 	utls "github.com/refraction-networking/utls"
-	proxy "golang.org/x/net/proxy"
 )
 
 type BrowserType int
@@ -29,16 +34,8 @@ const (
 	Firefox
 	Safari
 	Edge
-	EnvUseRandomUserAgents = "USE_RANDOM_USER_AGENTS"
 )
 
-var clientHelloIDs = []utls.ClientHelloID{
-	utls.HelloChrome_Auto,
-	utls.HelloFirefox_Auto,
-	utls.HelloSafari_Auto,
-	utls.HelloEdge_Auto,
-}
-
 var acceptLanguages = []string{
 	"en-US,en;q=0.9",
 	"en-US,en;q=0.8",
@@ -50,130 +47,57 @@ var acceptLanguages = []string{
 	"it-IT,it;q=0.9,en;q=0.8",
 }
 
-var userAgents = map[BrowserType][]string{
-	Chrome: {
-		"Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/121.0.0.0 Safari/537.36",
-		"Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/122.0.0.0 Safari/537.36",
-		"Mozilla/5.0 (X11; Linux x86_64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/121.0.0.0 Safari/537.36",
-		"Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/122.0.0.0 Safari/537.36",
-	},
-	Firefox: {
-		"Mozilla/5.0 (Windows NT 10.0; Win64; x64; rv:123.0) Gecko/20100101 Firefox/123.0",
-		"Mozilla/5.0 (Macintosh; Intel Mac OS X 10.15; rv:124.0) Gecko/20100101 Firefox/124.0",
-		"Mozilla/5.0 (X11; Linux x86_64; rv:122.0) Gecko/20100101 Firefox/122.0",
-		"Mozilla/5.0 (Windows NT 10.0; Win64; x64; rv:124.0) Gecko/20100101 Firefox/124.0",
-	},
-	Safari: {
-		"Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/16.5 Safari/605.1.15",
-		"Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.0 Safari/605.1.15",
-	},
-	Edge: {
-		"Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/121.0.0.0 Safari/537.36 Edg/121.0.2277.128",
-		"Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/122.0.0.0 Safari/537.36 Edg/122.0.2365.66",
-		"Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/122.0.0.0 Safari/537.36 Edg/122.0.2365.80",
-	},
-}
-
-func shouldUseRandomUserAgents() bool {
-	useRandomStr := os.Getenv(EnvUseRandomUserAgents)
-	if useRandomStr != "" {
-		useRandom, err := strconv.ParseBool(useRandomStr)
-		if err == nil {
-			return useRandom
-		}
-	}
-
-	return true
-}
-
-func getCorrespondingBrowserType(clientHelloID utls.ClientHelloID) BrowserType {
-	switch clientHelloID {
-	case utls.HelloFirefox_Auto:
-		return Firefox
-	case utls.HelloSafari_Auto:
-		return Safari
-	case utls.HelloEdge_Auto:
-		return Edge
-	default:
-		return Chrome
-	}
-}
-
 func randomItem[T any](items []T) T {
 	return items[rand.Intn(len(items))]
 }
 
-func addRandomizedBrowserHeaders(req *http.Request, browserType BrowserType, userAgent string) {
-	if !shouldUseRandomUserAgents() && userAgent != "" {
-		req.Header.Set("User-Agent", userAgent)
-	} else {
-		req.Header.Set("User-Agent", randomItem(userAgents[browserType]))
-	}
+// addBrowserHeaders sets the headers of profile's browser. The headers that
+// identify the browser come from the profile; only those that vary between
+// users of one browser (language, cache, DNT) are randomized.
+func addBrowserHeaders(req *http.Request, profile *BrowserProfile, userAgent string) {
+	req.Header.Set("User-Agent", userAgent)
 
-	req.Header.Set("Accept-Language", randomItem(acceptLanguages))
+	req.Header.Set("Accept", profile.Accept)
+	req.Header.Set("Accept-Encoding", profile.AcceptEncoding)
+	for name, value := range profile.NavigationHeaders {
+		req.Header.Set(name, value)
+	}
 
-	encodings := []string{
-		"gzip, deflate, br",
-		"gzip, deflate",
-		"br, gzip, deflate",
+	if profile.Browser == Safari {
+		req.Header.Set("Accept-Language", "en-US,en;q=0.9")
+	} else {
+		req.Header.Set("Accept-Language", randomItem(acceptLanguages))
 	}
-	req.Header.Set("Accept-Encoding", randomItem(encodings))
 
 	cacheControls := []string{
 		"max-age=0",
 		"no-cache",
-		"max-age=0, private, must-revalidate",
 	}
 	if rand.Intn(10) > 2 {
 		req.Header.Set("Cache-Control", randomItem(cacheControls))
 	}
 
-	if rand.Intn(10) > 3 {
-		req.Header.Set("DNT", fmt.Sprintf("%d", rand.Intn(2)+1))
-	}
-
-	switch browserType {
-	case Chrome, Edge:
-		req.Header.Set("Accept", "text/html,application/xhtml+xml,application/xml;q=0.9,image/avif,image/webp,image/apng,*/*;q=0.8,application/signed-exchange;v=b3;q=0.7")
-
-		if rand.Intn(10) > 2 {
-			req.Header.Set("Sec-Fetch-Dest", "document")
-			req.Header.Set("Sec-Fetch-Mode", "navigate")
-			req.Header.Set("Sec-Fetch-Site", "none")
-
-			if rand.Intn(10) > 3 {
-				req.Header.Set("Sec-Fetch-User", "?1")
-			}
-		}
-
-	case Firefox:
-		req.Header.Set("Accept", "text/html,application/xhtml+xml,application/xml;q=0.9,image/avif,image/webp,*/*;q=0.8")
-
-		if rand.Intn(10) > 2 {
-			req.Header.Set("TE", "trailers")
-		}
-
-	case Safari:
-		req.Header.Set("Accept", "text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8")
-
-		if rand.Intn(10) > 2 {
-			req.Header.Set("Accept-Language", "en-US,en;q=0.9")
-		}
-	}
-
-	if rand.Intn(10) > 0 {
-		req.Header.Set("Upgrade-Insecure-Requests", "1")
+	if profile.Browser == Firefox && rand.Intn(10) > 3 {
+		req.Header.Set("DNT", "1")
 	}
-
-	connections := []string{"keep-alive", "close"}
-	req.Header.Set("Connection", randomItem(connections))
 }
 
+// ProxyRotator hands out the configured proxies, followed by those of a
+// ProxyProvider when one is in use
 type ProxyRotator struct {
 	proxyURLs  []string
+	static     []*url.URL
 	parsedURLs []*url.URL
 	currentIdx uint32
 	mutex      sync.RWMutex
+
+	provider     ProxyProvider
+	provided     []*url.URL
+	stopProvider context.CancelFunc
+	// Consecutive failures of each provided proxy
+	failures map[string]int
+	// Called after the proxy list changed, without the lock held
+	onChange func()
 }
 
 func NewProxyRotator(proxyURLs []string) (*ProxyRotator, error) {
@@ -183,16 +107,48 @@ func NewProxyRotator(proxyURLs []string) (*ProxyRotator, error) {
 	}
 
 	for _, rawURL := range proxyURLs {
-		parsedURL, err := url.Parse(rawURL)
+		parsedURL, err := ParseProxyURL(rawURL)
 		if err != nil {
 			return nil, fmt.Errorf("failed to parse proxy URL %s: %w", rawURL, err)
 		}
-		rotator.parsedURLs = append(rotator.parsedURLs, parsedURL)
+		rotator.static = append(rotator.static, parsedURL)
 	}
+	rotator.combine()
 
 	return rotator, nil
 }
 
+// SetProxies replaces the proxy list; requests already in flight keep the proxy they picked
+func (r *ProxyRotator) SetProxies(proxyURLs []string) error {
+	var parsedURLs []*url.URL
+	for _, rawURL := range proxyURLs {
+		parsedURL, err := ParseProxyURL(rawURL)
+		if err != nil {
+			return fmt.Errorf("failed to parse proxy URL %s: %w", rawURL, err)
+		}
+		parsedURLs = append(parsedURLs, parsedURL)
+	}
+
+	r.mutex.Lock()
+	r.proxyURLs = proxyURLs
+	r.static = parsedURLs
+	r.combine()
+	onChange := r.onChange
+	r.mutex.Unlock()
+
+	if onChange != nil {
+		onChange()
+	}
+	return nil
+}
+
+// combine rebuilds the list handed out from the static and provided proxies;
+// callers hold the write lock
+func (r *ProxyRotator) combine() {
+	parsedURLs := make([]*url.URL, 0, len(r.static)+len(r.provided))
+	r.parsedURLs = append(append(parsedURLs, r.static...), r.provided...)
+}
+
 func (r *ProxyRotator) NextProxy() *url.URL {
 	r.mutex.RLock()
 	defer r.mutex.RUnlock()
@@ -205,6 +161,20 @@ func (r *ProxyRotator) NextProxy() *url.URL {
 	return r.parsedURLs[idx]
 }
 
+// Len returns the number of configured proxies
+func (r *ProxyRotator) Len() int {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	return len(r.parsedURLs)
+}
+
+// URLs returns a copy of the configured proxy URLs
+func (r *ProxyRotator) URLs() []*url.URL {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	return append([]*url.URL(nil), r.parsedURLs...)
+}
+
 func (r *ProxyRotator) GetProxyForID(id uint32) *url.URL {
 	r.mutex.RLock()
 	defer r.mutex.RUnlock()
@@ -218,19 +188,19 @@ func (r *ProxyRotator) GetProxyForID(id uint32) *url.URL {
 }
 
 type FingerprintingDialer struct {
-	proxyURL      *url.URL
-	clientHelloID utls.ClientHelloID
-	browserType   BrowserType
+	proxyURL   *url.URL
+	profile    *BrowserProfile
+	countBytes func(int64)
 }
 
 func NewFingerprintingDialer(proxyURL *url.URL) *FingerprintingDialer {
-	helloID := clientHelloIDs[rand.Intn(len(clientHelloIDs))]
-	browserType := getCorrespondingBrowserType(helloID)
+	return newFingerprintingDialer(proxyURL, rand.Intn(len(browserProfiles)))
+}
 
+func newFingerprintingDialer(proxyURL *url.URL, profileIdx int) *FingerprintingDialer {
 	return &FingerprintingDialer{
-		proxyURL:      proxyURL,
-		clientHelloID: helloID,
-		browserType:   browserType,
+		proxyURL: proxyURL,
+		profile:  &browserProfiles[profileIdx],
 	}
 }
 
@@ -251,6 +221,10 @@ func (d *FingerprintingDialer) DialTLSContext(ctx context.Context, network, addr
 		}
 	}
 
+	if d.countBytes != nil {
+		conn = &countingConn{Conn: conn, count: d.countBytes}
+	}
+
 	host := addr
 	if h, _, err := net.SplitHostPort(addr); err == nil {
 		host = h
@@ -260,7 +234,7 @@ func (d *FingerprintingDialer) DialTLSContext(ctx context.Context, network, addr
 		ServerName: host,
 	}
 
-	uconn := utls.UClient(conn, config, d.clientHelloID)
+	uconn := utls.UClient(conn, config, d.profile.ClientHello)
 	if err := uconn.Handshake(); err != nil {
 		conn.Close()
 		return nil, fmt.Errorf("uTLS handshake: %w", err)
@@ -272,91 +246,116 @@ func (d *FingerprintingDialer) DialTLSContext(ctx context.Context, network, addr
 func (d *FingerprintingDialer) dialThroughProxyWithContext(ctx context.Context, network, addr string) (net.Conn, error) {
 	switch d.proxyURL.Scheme {
 	case "http", "https":
-		transport := &http.Transport{
-			Proxy: http.ProxyURL(d.proxyURL),
-		}
-
-		req, err := http.NewRequestWithContext(ctx, "CONNECT", "https://"+addr, nil)
+		conn, err := d.dialConnectTunnel(ctx, addr)
 		if err != nil {
-			return nil, fmt.Errorf("create CONNECT request: %w", err)
-		}
-
-		if d.proxyURL.User != nil {
-			if password, ok := d.proxyURL.User.Password(); ok {
-				req.SetBasicAuth(d.proxyURL.User.Username(), password)
-			}
+			return nil, fmt.Errorf("dial via HTTP proxy: %w", err)
 		}
+		return conn, nil
 
-		conn, err := transport.DialContext(ctx, network, addr)
+	case "socks5", "socks5h":
+		conn, err := d.dialSOCKS5(ctx, network, addr)
 		if err != nil {
-			return nil, fmt.Errorf("dial via HTTP proxy: %w", err)
+			return nil, fmt.Errorf("dial via SOCKS5 proxy: %w", err)
 		}
-
 		return conn, nil
 
-	case "socks5":
-		auth := &proxy.Auth{}
-		if d.proxyURL.User != nil {
-			auth.User = d.proxyURL.User.Username()
-			if password, ok := d.proxyURL.User.Password(); ok {
-				auth.Password = password
-			}
-		}
+	default:
+		return nil, fmt.Errorf("unsupported proxy scheme: %s", d.proxyURL.Scheme)
+	}
+}
 
-		dialer, err := proxy.SOCKS5("tcp", d.proxyURL.Host, auth, &net.Dialer{
-			Timeout:   30 * time.Second,
-			KeepAlive: 30 * time.Second,
-		})
-		if err != nil {
-			return nil, fmt.Errorf("create SOCKS5 dialer: %w", err)
+// dialConnectTunnel opens a CONNECT tunnel to addr through an HTTP(S) proxy
+func (d *FingerprintingDialer) dialConnectTunnel(ctx context.Context, addr string) (net.Conn, error) {
+	dialer := net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second}
+	conn, err := dialer.DialContext(ctx, "tcp", proxyAddress(d.proxyURL))
+	if err != nil {
+		return nil, err
+	}
+
+	if d.proxyURL.Scheme == "https" {
+		tlsConn := tls.Client(conn, &tls.Config{ServerName: proxyServerName(d.proxyURL)})
+		if err := tlsConn.HandshakeContext(ctx); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("TLS handshake with proxy: %w", err)
 		}
+		conn = tlsConn
+	}
 
-		connCh := make(chan net.Conn, 1)
-		errCh := make(chan error, 1)
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
 
-		go func() {
-			conn, err := dialer.Dial(network, addr)
-			if err != nil {
-				errCh <- err
-				return
-			}
-			connCh <- conn
-		}()
-
-		select {
-		case <-ctx.Done():
-			return nil, ctx.Err()
-		case conn := <-connCh:
-			return conn, nil
-		case err := <-errCh:
-			return nil, fmt.Errorf("dial via SOCKS5 proxy: %w", err)
-		}
+	req := &http.Request{
+		Method: http.MethodConnect,
+		URL:    &url.URL{Opaque: addr},
+		Host:   addr,
+		Header: make(http.Header),
+	}
+	if d.proxyURL.User != nil {
+		password, _ := d.proxyURL.User.Password()
+		credentials := base64.StdEncoding.EncodeToString([]byte(d.proxyURL.User.Username() + ":" + password))
+		req.Header.Set("Proxy-Authorization", "Basic "+credentials)
+	}
 
-	default:
-		return nil, fmt.Errorf("unsupported proxy scheme: %s", d.proxyURL.Scheme)
+	if err := req.Write(conn); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("write CONNECT request: %w", err)
+	}
+
+	br := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(br, req)
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("read CONNECT response: %w", err)
 	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		conn.Close()
+		return nil, fmt.Errorf("proxy refused CONNECT: %s", resp.Status)
+	}
+	if br.Buffered() > 0 {
+		conn.Close()
+		return nil, fmt.Errorf("proxy sent unexpected data after CONNECT response")
+	}
+
+	conn.SetDeadline(time.Time{})
+	return conn, nil
 }
 
+// TLSFingerprintingTransport keeps one transport per proxy and browser
+// profile, so connections are reused across requests of the same session and
+// speak HTTP/2 whenever the profile's ClientHello advertises it
 type TLSFingerprintingTransport struct {
 	proxyRotator *ProxyRotator
-	transport    *http.Transport
+	budget       *BandwidthBudget
+	quota        *MonthlyQuota
+	ranker       *ProxyRanker
+	upstream     *UpstreamRecorder
+	throttle     *Throttle
+	cookies      *CookieJars
+	softBlocks   *softBlockCounters
+	mutex        sync.Mutex
+	transports   map[transportKey]*profileTransport
+	// Send a user agent of the browser profile even when the request
+	// carries one, see RetryableClient.SetRandomUserAgents
+	randomUserAgents atomic.Bool
 }
 
 func NewTLSFingerprintingTransport(rotator *ProxyRotator) http.RoundTripper {
-	transport := &http.Transport{
-		MaxIdleConns:          100,
-		MaxIdleConnsPerHost:   10,
-		IdleConnTimeout:       90 * time.Second,
-		TLSHandshakeTimeout:   10 * time.Second,
-		ExpectContinueTimeout: 1 * time.Second,
-		ResponseHeaderTimeout: 30 * time.Second,
-		ForceAttemptHTTP2:     false,
-		DisableCompression:    false,
-	}
+	return newTLSFingerprintingTransport(rotator, NewBandwidthBudget(0), NewMonthlyQuota(), NewProxyRanker(), NewUpstreamRecorder(), nil, NewCookieJars(0), newSoftBlockCounters())
+}
 
+func newTLSFingerprintingTransport(rotator *ProxyRotator, budget *BandwidthBudget, quota *MonthlyQuota, ranker *ProxyRanker, upstream *UpstreamRecorder, throttle *Throttle, cookies *CookieJars, softBlocks *softBlockCounters) *TLSFingerprintingTransport {
 	return &TLSFingerprintingTransport{
 		proxyRotator: rotator,
-		transport:    transport,
+		budget:       budget,
+		quota:        quota,
+		ranker:       ranker,
+		upstream:     upstream,
+		throttle:     throttle,
+		cookies:      cookies,
+		softBlocks:   softBlocks,
+		transports:   make(map[transportKey]*profileTransport),
 	}
 }
 
@@ -366,25 +365,174 @@ func (t *TLSFingerprintingTransport) RoundTrip(req *http.Request) (*http.Respons
 	existingUserAgent := req.Header.Get("User-Agent")
 
 	goroutineID := uint32(time.Now().UnixNano())
-	proxyURL := t.proxyRotator.GetProxyForID(goroutineID)
+	profileIdx := rand.Intn(len(browserProfiles))
+	session := ProxySessionFromContext(req.Context())
+	if session != nil {
+		goroutineID = session.ProxyID()
+		profileIdx = session.profileIdx
+	}
+	// A fixed user agent decides the profile, or the ClientHello would contradict it
+	userAgent := ""
+	if !t.randomUserAgents.Load() && existingUserAgent != "" {
+		userAgent = existingUserAgent
+		if idx, ok := profileIndexForUserAgent(userAgent); ok {
+			profileIdx = idx
+		}
+	} else if session != nil {
+		userAgent = session.userAgent
+	} else {
+		userAgent = randomItem(browserProfiles[profileIdx].UserAgents)
+	}
+	proxyURL, err := t.chooseProxy(req.Context(), session, goroutineID)
+	if err != nil {
+		return nil, err
+	}
 
-	var browserType BrowserType
+	key := newTransportKey(proxyURL, profileIdx)
+	pt := t.transportFor(key, proxyURL, profileIdx)
+	addBrowserHeaders(reqCopy, pt.dialer.profile, userAgent)
+	jar := t.cookies.jarFor(key)
+	if jar != nil {
+		for _, cookie := range jar.Cookies(req.URL) {
+			reqCopy.AddCookie(cookie)
+		}
+	}
 
+	start := time.Now()
+	resp, err := pt.RoundTrip(reqCopy)
+	softBlock := ""
+	if err == nil && jsonExpected(req.Context()) {
+		softBlock = detectSoftBlock(resp)
+	}
+	if err == nil && jar != nil {
+		if cookies := resp.Cookies(); len(cookies) > 0 {
+			jar.SetCookies(req.URL, cookies)
+		}
+	}
+	proxy := ""
 	if proxyURL != nil {
-		t.transport.Proxy = http.ProxyURL(proxyURL)
+		proxy = proxyURL.String()
+		proxyUsedFrom(req.Context()).url = maskProxyURL(proxy)
+		t.quota.AddRequest(proxyURL)
+		ok := err == nil && softBlock == "" && resp.StatusCode != http.StatusTooManyRequests && resp.StatusCode < 500
+		t.ranker.Record(proxy, time.Since(start), ok)
+		t.proxyRotator.Report(proxy, ok)
+	}
+	if err != nil {
+		return nil, err
 	}
+	t.upstream.Record(proxy, req, resp)
+	if softBlock != "" {
+		t.throttle.RecordSoftBlock()
+		resp.Body.Close()
+		blocked := &SoftBlockError{Reason: softBlock}
+		if proxy != "" {
+			blocked.Proxy = maskProxyURL(proxy)
+		}
+		t.softBlocks.add(softBlock, blocked.Proxy)
+		fmt.Printf("Soft block: %v\n", blocked)
+		return nil, blocked
+	}
+	t.throttle.Record(resp.StatusCode)
+	return resp, nil
+}
 
-	if req.URL.Scheme == "https" {
-		dialer := NewFingerprintingDialer(proxyURL)
-		t.transport.DialTLSContext = dialer.DialTLSContext
-		browserType = dialer.browserType
-	} else {
-		browserType = BrowserType(rand.Intn(4))
+// chooseProxy picks the proxy for one request among those of its pool with
+// daily and monthly bandwidth left. A session keeps its proxy while it works; otherwise
+// the proxy comes from the ranked share matching the request's priority.
+func (t *TLSFingerprintingTransport) chooseProxy(ctx context.Context, session *ProxySession, id uint32) (*url.URL, error) {
+	pool := ProxyPoolFromContext(ctx)
+	proxies := t.proxyRotator.PoolURLs(pool)
+	if len(proxies) == 0 {
+		if pool != "" {
+			return nil, fmt.Errorf("%w %q", ErrUnknownProxyPool, pool)
+		}
+		return nil, nil
 	}
 
-	addRandomizedBrowserHeaders(reqCopy, browserType, existingUserAgent)
+	// Skip proxies near their daily or monthly bandwidth cap
+	var available []*url.URL
+	for _, proxyURL := range proxies {
+		if t.budget.Available(proxyURL.String()) && t.quota.Available(proxyURL) {
+			available = append(available, proxyURL)
+		}
+	}
+	if len(available) == 0 {
+		return nil, ErrBandwidthExhausted
+	}
 
-	return t.transport.RoundTrip(reqCopy)
+	if session == nil {
+		return pickProxy(t.ranker.Rank(available), id, IsBulkPriority(ctx)), nil
+	}
+
+	pinned, avoid := session.pinned()
+	candidates := available[:0:0]
+	for _, proxyURL := range available {
+		if proxyURL.String() == pinned {
+			return proxyURL, nil
+		}
+		if proxyURL.String() != avoid {
+			candidates = append(candidates, proxyURL)
+		}
+	}
+	if len(candidates) == 0 {
+		candidates = available
+	}
+
+	proxyURL := pickProxy(t.ranker.Rank(candidates), id, IsBulkPriority(ctx))
+	session.pin(proxyURL.String())
+	return proxyURL, nil
+}
+
+// transportFor returns the pooled transport for a proxy and browser profile,
+// creating it on first use
+func (t *TLSFingerprintingTransport) transportFor(key transportKey, proxyURL *url.URL, profileIdx int) *profileTransport {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	pt, ok := t.transports[key]
+	if !ok {
+		var countBytes func(int64)
+		if proxyURL != nil {
+			usageKey := quotaKey(proxyURL)
+			countBytes = func(n int64) {
+				t.budget.Add(key.proxy, n)
+				t.quota.addBytes(usageKey, n)
+			}
+		}
+		pt = newProfileTransport(proxyURL, profileIdx, countBytes)
+		t.transports[key] = pt
+	}
+	return pt
+}
+
+// prune drops the transports of proxies no longer in rotation, such as
+// replaced provider sessions, closing their idle connections
+func (t *TLSFingerprintingTransport) prune(current []*url.URL) {
+	inRotation := make(map[string]bool, len(current))
+	for _, proxyURL := range current {
+		inRotation[proxyURL.String()] = true
+	}
+
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	for key, pt := range t.transports {
+		if key.proxy != "" && !inRotation[key.proxy] {
+			pt.closeIdleConnections()
+			delete(t.transports, key)
+		}
+	}
+	t.cookies.prune(inRotation)
+}
+
+// CloseIdleConnections closes idle connections of every pooled transport
+func (t *TLSFingerprintingTransport) CloseIdleConnections() {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	for _, pt := range t.transports {
+		pt.closeIdleConnections()
+	}
 }
 
 func maskProxyURL(proxyURL string) string {
@@ -421,8 +569,29 @@ func maskProxyURL(proxyURL string) string {
 
 type RetryableClient struct {
 	client     *http.Client
+	rotator    *ProxyRotator
+	budget     *BandwidthBudget
+	quota      *MonthlyQuota
+	ranker     *ProxyRanker
+	upstream   *UpstreamRecorder
+	throttle   *Throttle
+	cookies    *CookieJars
+	softBlocks *softBlockCounters
+	mutex      sync.RWMutex
 	maxRetries int
 	userAgent  string
+	maxBytes   int64
+	// Connects without proxies, see NewDirectClient
+	direct bool
+	// Spaces every request of the client, see SetRequestLimiter
+	limiter *RequestLimiter
+	// Hosts requests and redirects may go to, see SetHostAllowlist
+	allowlist *HostAllowlist
+
+	retryPolicy   RetryPolicy
+	retryCounters retryCounters
+	// Leave the user agent to the browser profile, see SetRandomUserAgents
+	randomUserAgents bool
 }
 
 func NewRetryableClient(proxyURLs []string, maxRetries int, userAgent string) (*RetryableClient, error) {
@@ -441,6 +610,56 @@ func NewRetryableClient(proxyURLs []string, maxRetries int, userAgent string) (*
 		return nil, fmt.Errorf("no valid proxy URLs provided")
 	}
 
+	return newRetryableClient(validProxies, maxRetries, userAgent)
+}
+
+// NewRetryableClientWithProvider creates a client rotating through proxyURLs
+// and the proxies of provider, refreshed every refresh. proxyURLs may be empty.
+func NewRetryableClientWithProvider(proxyURLs []string, provider ProxyProvider, refresh time.Duration, maxRetries int, userAgent string) (*RetryableClient, error) {
+	var validProxies []string
+	for _, proxy := range proxyURLs {
+		if proxy != "" {
+			validProxies = append(validProxies, proxy)
+		}
+	}
+
+	client, err := newRetryableClient(validProxies, maxRetries, userAgent)
+	if err != nil {
+		return nil, err
+	}
+	if err := client.rotator.UseProvider(provider, refresh); err != nil {
+		return nil, err
+	}
+	return client, nil
+}
+
+// NewDirectClient creates a client that connects to the target itself,
+// without proxies, for a local fake Reddit. It keeps the retries, browser
+// fingerprints and response limits of a proxied client.
+func NewDirectClient(maxRetries int, userAgent string) (*RetryableClient, error) {
+	client, err := newRetryableClient(nil, maxRetries, userAgent)
+	if err != nil {
+		return nil, err
+	}
+	client.direct = true
+	return client, nil
+}
+
+// NewRetryableClientWithHTTPClient creates a client sending its requests with
+// httpClient, e.g. one whose transport serves a fake Reddit in tests. It keeps
+// the retries and response limits of a proxied client; the proxy rotation,
+// browser fingerprints, bandwidth caps, cookies and throttle live in the
+// proxied transport and do not apply.
+func NewRetryableClientWithHTTPClient(httpClient *http.Client, maxRetries int, userAgent string) (*RetryableClient, error) {
+	client, err := NewDirectClient(maxRetries, userAgent)
+	if err != nil {
+		return nil, err
+	}
+	client.client = httpClient
+	return client, nil
+}
+
+func newRetryableClient(validProxies []string, maxRetries int, userAgent string) (*RetryableClient, error) {
 	for i, proxy := range validProxies {
 		maskedProxy := maskProxyURL(proxy)
 		fmt.Printf("Proxy #%d: %s\n", i+1, maskedProxy)
@@ -451,27 +670,234 @@ func NewRetryableClient(proxyURLs []string, maxRetries int, userAgent string) (*
 		return nil, fmt.Errorf("failed to create proxy rotator: %w", err)
 	}
 
+	budget := NewBandwidthBudget(0)
+	quota := NewMonthlyQuota()
+	ranker := NewProxyRanker()
+	upstream := NewUpstreamRecorder()
+	// Disabled until SetThrottle configures a window
+	throttle := NewThrottle(0, 0)
+	// No cookies until SetCookieTTL gives jars a lifetime
+	cookies := NewCookieJars(0)
+	softBlocks := newSoftBlockCounters()
+	transport := newTLSFingerprintingTransport(rotator, budget, quota, ranker, upstream, throttle, cookies, softBlocks)
+	rotator.onChange = func() { transport.prune(rotator.URLs()) }
 	httpClient := &http.Client{
-		Transport: NewTLSFingerprintingTransport(rotator),
+		Transport: transport,
 		Timeout:   30 * time.Second,
 	}
 
 	fmt.Printf("Created HTTP client with %d proxies and TLS fingerprinting\n", len(validProxies))
 
-	return &RetryableClient{
-		client:     httpClient,
-		maxRetries: maxRetries,
-		userAgent:  userAgent,
-	}, nil
+	client := &RetryableClient{
+		client:      httpClient,
+		rotator:     rotator,
+		budget:      budget,
+		quota:       quota,
+		ranker:      ranker,
+		upstream:    upstream,
+		throttle:    throttle,
+		cookies:     cookies,
+		softBlocks:  softBlocks,
+		maxRetries:  maxRetries,
+		userAgent:   userAgent,
+		retryPolicy: DefaultRetryPolicy(),
+	}
+	httpClient.CheckRedirect = client.checkRedirect
+	return client, nil
+}
+
+// Reconfigure swaps the proxy list, retry count and user agent at runtime
+func (c *RetryableClient) Reconfigure(proxyURLs []string, maxRetries int, userAgent string) error {
+	var validProxies []string
+	for _, proxy := range proxyURLs {
+		if proxy != "" {
+			validProxies = append(validProxies, proxy)
+		}
+	}
+
+	if len(validProxies) == 0 && !c.direct && !c.rotator.HasProvider() {
+		return fmt.Errorf("no valid proxy URLs provided")
+	}
+
+	if err := c.rotator.SetProxies(validProxies); err != nil {
+		return err
+	}
+
+	c.mutex.Lock()
+	c.maxRetries = maxRetries
+	c.userAgent = userAgent
+	c.mutex.Unlock()
+
+	// Drop idle connections to proxies that may no longer be configured
+	c.client.CloseIdleConnections()
+
+	fmt.Printf("Reconfigured HTTP client with %d proxies\n", len(validProxies))
+	return nil
+}
+
+// Close stops refreshing the proxies of a ProxyProvider
+func (c *RetryableClient) Close() {
+	c.rotator.Close()
+}
+
+// SetMaxResponseBytes caps the decoded size of a response body; 0 removes the cap
+func (c *RetryableClient) SetMaxResponseBytes(maxBytes int64) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.maxBytes = maxBytes
+}
+
+// SetRandomUserAgents makes each request send a user agent of the browser
+// profile it is fingerprinted as instead of the configured one. Either way
+// the user agent matches the TLS fingerprint: a fixed one picks its profile.
+func (c *RetryableClient) SetRandomUserAgents(random bool) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.randomUserAgents = random
+	if transport, ok := c.client.Transport.(*TLSFingerprintingTransport); ok {
+		transport.randomUserAgents.Store(random)
+	}
+}
+
+// SetRequestLimiter makes every request of the client, retries included, wait
+// for l on top of the limiter its context carries; nil removes the limit
+func (c *RetryableClient) SetRequestLimiter(l *RequestLimiter) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.limiter = l
+}
+
+// SetHostAllowlist restricts the hosts requests and the redirects of the
+// client's own transport may go to; nil allows every host
+func (c *RetryableClient) SetHostAllowlist(a *HostAllowlist) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.allowlist = a
+}
+
+// checkRedirect follows up to 10 redirects within the allowlist, so a
+// redirect cannot carry a request somewhere it could not be sent directly
+func (c *RetryableClient) checkRedirect(req *http.Request, via []*http.Request) error {
+	c.mutex.RLock()
+	allowlist := c.allowlist
+	c.mutex.RUnlock()
+	if err := allowlist.check(req.URL); err != nil {
+		return err
+	}
+	if len(via) >= 10 {
+		return errors.New("stopped after 10 redirects")
+	}
+	return nil
 }
 
+// SetRetryPolicy changes the backoff and retry budget of the requests sent
+// from now on
+func (c *RetryableClient) SetRetryPolicy(policy RetryPolicy) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.retryPolicy = policy
+}
+
+// RetryStats reports the retries made since start-up and why requests were
+// given up
+func (c *RetryableClient) RetryStats() RetryStats {
+	return c.retryCounters.stats()
+}
+
+// SoftBlockStats reports the soft blocks met since start-up
+func (c *RetryableClient) SoftBlockStats() SoftBlockStats {
+	return c.softBlocks.stats()
+}
+
+// SetDailyBandwidthCap caps the bytes each proxy may move per UTC day; 0 removes the cap
+func (c *RetryableClient) SetDailyBandwidthCap(capBytes int64) {
+	c.budget.SetCap(capBytes)
+}
+
+// SetMonthlyBandwidthCaps caps the bytes each proxy may move per UTC month:
+// defaultCap for every proxy, labelCaps for proxies carrying a label. 0 removes
+// a cap.
+func (c *RetryableClient) SetMonthlyBandwidthCaps(defaultCap int64, labelCaps map[string]int64) {
+	c.quota.SetCaps(defaultCap, labelCaps)
+}
+
+// UseUsageFile keeps the monthly usage of the proxies in path, loading what an
+// earlier run saved there
+func (c *RetryableClient) UseUsageFile(path string) error {
+	return c.quota.UseFile(path)
+}
+
+// SaveUsage writes the monthly usage of the proxies to the usage file, if any
+func (c *RetryableClient) SaveUsage() error {
+	return c.quota.Save()
+}
+
+// SetThrottle makes the client's Throttle evaluate windows of the given length
+// against blockRate; a window of 0 disables it
+func (c *RetryableClient) SetThrottle(window time.Duration, blockRate float64) {
+	c.throttle.Configure(window, blockRate)
+}
+
+// SetCookieTTL keeps a cookie jar per proxy and browser profile for ttl, so
+// the requests of a session send back Reddit's cookies; 0 sends none
+func (c *RetryableClient) SetCookieTTL(ttl time.Duration) {
+	c.cookies.SetTTL(ttl)
+}
+
+// Throttle is the throttle fed with the status of every response
+func (c *RetryableClient) Throttle() *Throttle {
+	return c.throttle
+}
+
+// HasProxyPool reports whether any configured proxy is labelled pool
+func (c *RetryableClient) HasProxyPool(pool string) bool {
+	return c.rotator.PoolLen(pool) > 0
+}
+
+// BandwidthUsage reports today's traffic through each configured proxy
+func (c *RetryableClient) BandwidthUsage() []ProxyBandwidth {
+	return c.budget.Usage(c.rotator.URLs())
+}
+
+// ProxyUsage reports this month's traffic and requests through each configured proxy
+func (c *RetryableClient) ProxyUsage() []ProxyUsage {
+	return c.quota.Usage(c.rotator.URLs())
+}
+
+// ProxyRanking reports every configured proxy's latency and success rate, best first
+func (c *RetryableClient) ProxyRanking() []ProxyRank {
+	return c.ranker.Ranking(c.rotator.URLs())
+}
+
+// UpstreamStats reports the rate limit, content type and Cloudflare headers of
+// Reddit's responses, with a trace of the latest ones
+func (c *RetryableClient) UpstreamStats() UpstreamStats {
+	return c.upstream.Stats()
+}
+
+// Do sends req, retrying failed connections and 429/5xx responses under the
+// retry policy, and returns the response with its decompressed body. A
+// response with an error status fails with an *UpstreamError but is returned
+// as well, so callers can pass Reddit's answer on.
 func (c *RetryableClient) Do(req *http.Request) (*http.Response, []byte, error) {
 	var resp *http.Response
 	var bodyBytes []byte
 	var err error
 
-	if req.Header.Get("User-Agent") == "" && !shouldUseRandomUserAgents() {
-		req.Header.Set("User-Agent", c.userAgent)
+	c.mutex.RLock()
+	maxRetries, userAgent, maxBytes, policy, limiter := c.maxRetries, c.userAgent, c.maxBytes, c.retryPolicy, c.limiter
+	allowlist, randomUserAgents := c.allowlist, c.randomUserAgents
+	c.mutex.RUnlock()
+
+	if maxRetries < 1 {
+		return nil, nil, fmt.Errorf("no attempts made: PROXY_MAX_RETRIES is %d", maxRetries)
+	}
+	if err := allowlist.check(req.URL); err != nil {
+		return nil, nil, err
+	}
+
+	if req.Header.Get("User-Agent") == "" && !randomUserAgents {
+		req.Header.Set("User-Agent", userAgent)
 	}
 
 	var reqBody []byte
@@ -483,52 +909,58 @@ func (c *RetryableClient) Do(req *http.Request) (*http.Response, []byte, error)
 		req.Body.Close()
 	}
 
-	for attempt := 0; attempt < c.maxRetries; attempt++ {
+	req = withProxyUsed(req)
+	retry := newRetryState(policy, maxRetries, &c.retryCounters)
+	for {
+		retry.attempts++
+		attempt := retry.attempts
 		if reqBody != nil {
 			req.Body = io.NopCloser(bytes.NewReader(reqBody))
 		}
 
-		if attempt > 0 {
-			backoffTime := time.Duration(1<<uint(attempt)) * time.Second
-			time.Sleep(backoffTime)
-
-			fmt.Printf("Retry attempt %d after waiting %v\n", attempt+1, backoffTime)
+		if err := limiter.Wait(req.Context()); err != nil {
+			return nil, nil, err
+		}
+		if err := waitForRequestSlot(req); err != nil {
+			return nil, nil, err
 		}
-
 		resp, err = c.client.Do(req)
 		if err != nil {
-			fmt.Printf("Request error (attempt %d): %v\n", attempt+1, err)
+			fmt.Printf("Request error (attempt %d): %v\n", attempt, err)
+			if errors.Is(err, ErrBandwidthExhausted) || errors.Is(err, ErrUnknownProxyPool) || errors.Is(err, ErrHostNotAllowed) {
+				return nil, nil, err
+			}
+			rotateSession(req)
 
-			if attempt == c.maxRetries-1 {
-				return nil, nil, fmt.Errorf("all %d attempts failed: %w", c.maxRetries, err)
+			if !retry.again(req, idempotent(req), 0) {
+				return nil, nil, fmt.Errorf("all %d attempts failed: %w", attempt, err)
 			}
 			continue
 		}
 
-		var reader io.ReadCloser
-		switch strings.ToLower(resp.Header.Get("Content-Encoding")) {
-		case "gzip":
-			reader, err = gzip.NewReader(resp.Body)
-			if err != nil {
-				resp.Body.Close()
-				fmt.Printf("Error creating gzip reader (attempt %d): %v\n", attempt+1, err)
-				if attempt == c.maxRetries-1 {
-					return nil, nil, fmt.Errorf("failed to decompress gzip response: %w", err)
-				}
-				continue
+		reader, err := decodedBody(resp, maxBytes)
+		if err != nil {
+			resp.Body.Close()
+			fmt.Printf("Error decoding response body (attempt %d): %v\n", attempt, err)
+			if errors.Is(err, ErrResponseTooLarge) {
+				return nil, nil, err
+			}
+			if !retry.again(req, idempotent(req), 0) {
+				return nil, nil, fmt.Errorf("failed to decompress response: %w", err)
 			}
-			defer reader.Close()
-		default:
-			reader = resp.Body
+			continue
 		}
 
 		bodyBytes, err = io.ReadAll(reader)
-		resp.Body.Close()
+		reader.Close()
 
 		if err != nil {
-			fmt.Printf("Error reading response body (attempt %d): %v\n", attempt+1, err)
+			fmt.Printf("Error reading response body (attempt %d): %v\n", attempt, err)
 
-			if attempt == c.maxRetries-1 {
+			if errors.Is(err, ErrResponseTooLarge) {
+				return nil, nil, err
+			}
+			if !retry.again(req, idempotent(req), 0) {
 				return nil, nil, fmt.Errorf("reading response body: %w", err)
 			}
 			continue
@@ -537,8 +969,15 @@ func (c *RetryableClient) Do(req *http.Request) (*http.Response, []byte, error)
 		if len(bodyBytes) > 0 && bodyBytes[0] == 0x1f && bodyBytes[1] == 0x8b {
 			gr, err := gzip.NewReader(bytes.NewReader(bodyBytes))
 			if err == nil {
-				uncompressed, err := io.ReadAll(gr)
+				var inner io.Reader = gr
+				if maxBytes > 0 {
+					inner = &limitedReader{r: gr, remaining: maxBytes, limit: maxBytes}
+				}
+				uncompressed, err := io.ReadAll(inner)
 				gr.Close()
+				if errors.Is(err, ErrResponseTooLarge) {
+					return nil, nil, err
+				}
 				if err == nil {
 					fmt.Printf("Detected and uncompressed double-gzipped content\n")
 					bodyBytes = uncompressed
@@ -547,10 +986,12 @@ func (c *RetryableClient) Do(req *http.Request) (*http.Response, []byte, error)
 		}
 
 		if resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500 {
-			fmt.Printf("Received status code %d (attempt %d)\n", resp.StatusCode, attempt+1)
+			fmt.Printf("Received status code %d (attempt %d) %s\n", resp.StatusCode, attempt, FormatHeaders(CaptureHeaders(resp.Header)))
+			rotateSession(req)
 
-			if attempt == c.maxRetries-1 {
-				return nil, nil, fmt.Errorf("server error: status %d", resp.StatusCode)
+			if !retry.again(req, retryableStatus(req, resp.StatusCode), retryAfter(resp)) {
+				resp.Body = io.NopCloser(bytes.NewReader(bodyBytes))
+				return resp, bodyBytes, newUpstreamError(req, resp, bodyBytes, attempt)
 			}
 			continue
 		}
@@ -558,6 +999,206 @@ func (c *RetryableClient) Do(req *http.Request) (*http.Response, []byte, error)
 		break
 	}
 
+	countPage(req)
 	resp.Body = io.NopCloser(bytes.NewReader(bodyBytes))
+	if resp.StatusCode >= 400 {
+		if resp.StatusCode == http.StatusForbidden {
+			// Reddit blocks proxies with 403s; the next request of the
+			// session should not go through this one
+			rotateSession(req)
+		}
+		return resp, bodyBytes, newUpstreamError(req, resp, bodyBytes, retry.attempts)
+	}
 	return resp, bodyBytes, nil
 }
+
+// DoStream sends req like Do but returns the response with its body unread,
+// decompressed and capped at the maximum response size, so the caller can
+// decode it while it downloads. Failed connections and 429/5xx responses are
+// retried as in Do; errors while reading the body are left to the caller.
+// Error statuses fail with an *UpstreamError. The caller must close the body.
+func (c *RetryableClient) DoStream(req *http.Request) (*http.Response, error) {
+	c.mutex.RLock()
+	maxRetries, userAgent, maxBytes, policy, limiter := c.maxRetries, c.userAgent, c.maxBytes, c.retryPolicy, c.limiter
+	allowlist, randomUserAgents := c.allowlist, c.randomUserAgents
+	c.mutex.RUnlock()
+
+	if maxRetries < 1 {
+		return nil, fmt.Errorf("no attempts made: PROXY_MAX_RETRIES is %d", maxRetries)
+	}
+	if err := allowlist.check(req.URL); err != nil {
+		return nil, err
+	}
+
+	if req.Header.Get("User-Agent") == "" && !randomUserAgents {
+		req.Header.Set("User-Agent", userAgent)
+	}
+
+	req = withProxyUsed(req)
+	retry := newRetryState(policy, maxRetries, &c.retryCounters)
+	for {
+		retry.attempts++
+		attempt := retry.attempts
+
+		if err := limiter.Wait(req.Context()); err != nil {
+			return nil, err
+		}
+		if err := waitForRequestSlot(req); err != nil {
+			return nil, err
+		}
+		resp, err := c.client.Do(req)
+		if err != nil {
+			fmt.Printf("Request error (attempt %d): %v\n", attempt, err)
+			if errors.Is(err, ErrBandwidthExhausted) || errors.Is(err, ErrUnknownProxyPool) || errors.Is(err, ErrHostNotAllowed) {
+				return nil, err
+			}
+			rotateSession(req)
+
+			if !retry.again(req, idempotent(req), 0) {
+				return nil, fmt.Errorf("all %d attempts failed: %w", attempt, err)
+			}
+			continue
+		}
+
+		if resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500 {
+			snippet := readSnippet(resp, maxBytes)
+			fmt.Printf("Received status code %d (attempt %d) %s\n", resp.StatusCode, attempt, FormatHeaders(CaptureHeaders(resp.Header)))
+			rotateSession(req)
+
+			if !retry.again(req, retryableStatus(req, resp.StatusCode), retryAfter(resp)) {
+				return nil, newUpstreamError(req, resp, snippet, attempt)
+			}
+			continue
+		}
+		if resp.StatusCode >= 400 {
+			countPage(req)
+			if resp.StatusCode == http.StatusForbidden {
+				rotateSession(req)
+			}
+			return nil, newUpstreamError(req, resp, readSnippet(resp, maxBytes), attempt)
+		}
+
+		body, err := decodedBody(resp, maxBytes)
+		if err != nil {
+			resp.Body.Close()
+			return nil, err
+		}
+		countPage(req)
+		resp.Body = body
+		return resp, nil
+	}
+}
+
+// decodedBody undoes the content encodings of resp, which may be any the
+// browser profiles advertise plus zstd, and caps the decoded size at maxBytes
+// (0 for no cap), so a compressed response cannot expand past it. Closing the
+// returned body closes the decoders and the response body.
+func decodedBody(resp *http.Response, maxBytes int64) (io.ReadCloser, error) {
+	if maxBytes > 0 && resp.ContentLength > maxBytes {
+		return nil, fmt.Errorf("%w: %d bytes declared, limit is %d", ErrResponseTooLarge, resp.ContentLength, maxBytes)
+	}
+
+	var reader io.Reader = resp.Body
+	closers := []io.Closer{resp.Body}
+	encodings := strings.Split(resp.Header.Get("Content-Encoding"), ",")
+	// Encodings are listed in the order they were applied
+	for i := len(encodings) - 1; i >= 0; i-- {
+		encoding := strings.ToLower(strings.TrimSpace(encodings[i]))
+		decoder, err := newDecoder(encoding, reader)
+		if err != nil {
+			for _, closer := range closers[1:] {
+				closer.Close()
+			}
+			return nil, fmt.Errorf("decoding %s response: %w", encoding, err)
+		}
+		reader = decoder
+		if closer, ok := decoder.(io.Closer); ok {
+			closers = append(closers, closer)
+		}
+	}
+	if maxBytes > 0 {
+		reader = &limitedReader{r: reader, remaining: maxBytes, limit: maxBytes}
+	}
+	return struct {
+		io.Reader
+		io.Closer
+	}{reader, decoderClosers(closers)}, nil
+}
+
+// newDecoder returns a reader decoding r from encoding, or r itself for the
+// identity encoding
+func newDecoder(encoding string, r io.Reader) (io.Reader, error) {
+	switch encoding {
+	case "", "identity":
+		return r, nil
+	case "gzip", "x-gzip":
+		return gzip.NewReader(r)
+	case "br":
+		return brotli.NewReader(r), nil
+	case "zstd":
+		decoder, err := zstd.NewReader(r, zstd.WithDecoderConcurrency(1))
+		if err != nil {
+			return nil, err
+		}
+		return decoder.IOReadCloser(), nil
+	case "deflate":
+		// deflate is meant to be zlib-wrapped, but some servers send the raw
+		// stream; a zlib header is told apart by its checksum
+		buffered := bufio.NewReader(r)
+		header, err := buffered.Peek(2)
+		if err == nil && header[0]&0x0f == 8 && (uint16(header[0])<<8|uint16(header[1]))%31 == 0 {
+			return zlib.NewReader(buffered)
+		}
+		return flate.NewReader(buffered), nil
+	default:
+		return nil, fmt.Errorf("unsupported content encoding %q", encoding)
+	}
+}
+
+// decoderClosers closes the decoders from the outermost in, then the
+// response body, which comes first
+type decoderClosers []io.Closer
+
+func (c decoderClosers) Close() error {
+	var err error
+	for i := len(c) - 1; i >= 0; i-- {
+		if closeErr := c[i].Close(); i == 0 {
+			err = closeErr
+		}
+	}
+	return err
+}
+
+// ErrResponseTooLarge is returned when a response body exceeds the maximum size
+var ErrResponseTooLarge = errors.New("response body exceeds the maximum size")
+
+// limitedReader fails with ErrResponseTooLarge instead of truncating silently
+type limitedReader struct {
+	r         io.Reader
+	remaining int64
+	limit     int64
+}
+
+func (l *limitedReader) Read(p []byte) (int, error) {
+	if l.remaining <= 0 {
+		var probe [1]byte
+		n, err := l.r.Read(probe[:])
+		if n > 0 {
+			return 0, fmt.Errorf("%w: limit is %d bytes", ErrResponseTooLarge, l.limit)
+		}
+		return 0, err
+	}
+	if int64(len(p)) > l.remaining {
+		p = p[:l.remaining]
+	}
+	n, err := l.r.Read(p)
+	l.remaining -= int64(n)
+	return n, err
+}
+
+// rotateSession moves a sticky proxy session off a proxy that just failed
+func rotateSession(req *http.Request) {
+	if session := ProxySessionFromContext(req.Context()); session != nil {
+		session.Rotate()
+	}
+}
@@ -0,0 +1,295 @@
+// pkg/utils/proxy_quota.go
+package utils
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// quotaSaveInterval is how often usage is written to the usage file while
+// traffic flows; Save writes it at once
+const quotaSaveInterval = time.Minute
+
+// MonthlyQuota counts the bytes and requests each proxy moves during the
+// current UTC month and enforces monthly caps: a default for every proxy and
+// overrides for proxies carrying a label. Counters reset when the month
+// changes. With a usage file the counters survive restarts.
+type MonthlyQuota struct {
+	mutex      sync.Mutex
+	defaultCap int64
+	labelCaps  map[string]int64
+	month      string
+	used       map[string]*proxyMonth
+
+	file    string
+	dirty   bool
+	savedAt time.Time
+	// Serializes saves, so an older snapshot never replaces a newer one
+	saving sync.Mutex
+}
+
+// proxyMonth is one proxy's traffic in a month
+type proxyMonth struct {
+	Bytes    int64 `json:"bytes"`
+	Requests int64 `json:"requests"`
+}
+
+// quotaFile is the usage file: the month counted and each proxy's traffic,
+// keyed by its URL with the password masked
+type quotaFile struct {
+	Month   string                 `json:"month"`
+	Proxies map[string]*proxyMonth `json:"proxies"`
+}
+
+// ProxyUsage is one proxy's traffic in the current UTC month
+type ProxyUsage struct {
+	Proxy          string   `json:"proxy"`
+	Labels         []string `json:"labels,omitempty"`
+	Month          string   `json:"month"`
+	UsedBytes      int64    `json:"used_bytes"`
+	Requests       int64    `json:"requests"`
+	CapBytes       int64    `json:"cap_bytes,omitempty"`
+	RemainingBytes int64    `json:"remaining_bytes,omitempty"`
+	Available      bool     `json:"available"`
+}
+
+// NewMonthlyQuota creates a quota without caps that only counts
+func NewMonthlyQuota() *MonthlyQuota {
+	return &MonthlyQuota{
+		month: thisMonth(),
+		used:  make(map[string]*proxyMonth),
+	}
+}
+
+func thisMonth() string {
+	return time.Now().UTC().Format("2006-01")
+}
+
+// quotaKey identifies a proxy in the counters and the usage file: its URL
+// without labels and with the password masked, so no secret is written out
+func quotaKey(proxyURL *url.URL) string {
+	display := *proxyURL
+	display.Fragment = ""
+	return maskProxyURL(display.String())
+}
+
+// SetCaps changes the monthly caps: defaultCap for every proxy and labelCaps
+// for proxies carrying one of its labels, the smallest winning when several
+// match. 0 removes a cap, so a label capped at 0 exempts its proxies from the
+// default.
+func (q *MonthlyQuota) SetCaps(defaultCap int64, labelCaps map[string]int64) {
+	caps := make(map[string]int64, len(labelCaps))
+	for label, capBytes := range labelCaps {
+		caps[strings.ToLower(label)] = capBytes
+	}
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	q.defaultCap = defaultCap
+	q.labelCaps = caps
+}
+
+// capFor returns the monthly cap of a proxy; callers hold the mutex
+func (q *MonthlyQuota) capFor(proxyURL *url.URL) int64 {
+	capBytes := int64(0)
+	matched := false
+	for _, label := range proxyLabels(proxyURL) {
+		labelCap, ok := q.labelCaps[strings.ToLower(label)]
+		if !ok {
+			continue
+		}
+		matched = true
+		if labelCap > 0 && (capBytes == 0 || labelCap < capBytes) {
+			capBytes = labelCap
+		}
+	}
+	if !matched {
+		capBytes = q.defaultCap
+	}
+	return capBytes
+}
+
+// rollover resets the counters when the UTC month changes; callers hold the mutex
+func (q *MonthlyQuota) rollover() {
+	if month := thisMonth(); month != q.month {
+		q.month = month
+		q.used = make(map[string]*proxyMonth)
+		q.dirty = true
+	}
+}
+
+// counter returns the counters of a proxy, creating them; callers hold the mutex
+func (q *MonthlyQuota) counter(key string) *proxyMonth {
+	q.rollover()
+	usage, ok := q.used[key]
+	if !ok {
+		usage = &proxyMonth{}
+		q.used[key] = usage
+	}
+	return usage
+}
+
+// Add records n bytes moved through proxyURL
+func (q *MonthlyQuota) Add(proxyURL *url.URL, n int64) {
+	q.addBytes(quotaKey(proxyURL), n)
+}
+
+// addBytes records n bytes moved through the proxy with the given quotaKey
+func (q *MonthlyQuota) addBytes(key string, n int64) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	q.counter(key).Bytes += n
+	q.dirty = true
+}
+
+// AddRequest records a request sent through proxyURL. Every minute or so it
+// also writes the counters to the usage file in the background.
+func (q *MonthlyQuota) AddRequest(proxyURL *url.URL) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	q.counter(quotaKey(proxyURL)).Requests++
+	q.dirty = true
+
+	if q.file != "" && time.Since(q.savedAt) >= quotaSaveInterval {
+		q.savedAt = time.Now()
+		go func() {
+			if err := q.Save(); err != nil {
+				fmt.Printf("Failed to save proxy usage: %v\n", err)
+			}
+		}()
+	}
+}
+
+// Available reports whether proxyURL is still under its monthly cap, keeping
+// the same reserve as the daily budget
+func (q *MonthlyQuota) Available(proxyURL *url.URL) bool {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	capBytes := q.capFor(proxyURL)
+	if capBytes <= 0 {
+		return true
+	}
+	return capBytes-q.counter(quotaKey(proxyURL)).Bytes > capBytes/bandwidthReserveFraction
+}
+
+// Usage reports this month's traffic of each given proxy, with credentials masked
+func (q *MonthlyQuota) Usage(proxies []*url.URL) []ProxyUsage {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	q.rollover()
+
+	usage := make([]ProxyUsage, 0, len(proxies))
+	for _, proxyURL := range proxies {
+		key := quotaKey(proxyURL)
+		entry := ProxyUsage{
+			Proxy:     key,
+			Labels:    proxyLabels(proxyURL),
+			Month:     q.month,
+			Available: true,
+		}
+		if counted, ok := q.used[key]; ok {
+			entry.UsedBytes = counted.Bytes
+			entry.Requests = counted.Requests
+		}
+		if capBytes := q.capFor(proxyURL); capBytes > 0 {
+			entry.CapBytes = capBytes
+			entry.RemainingBytes = max(capBytes-entry.UsedBytes, 0)
+			entry.Available = entry.RemainingBytes > capBytes/bandwidthReserveFraction
+		}
+		usage = append(usage, entry)
+	}
+	return usage
+}
+
+// UseFile loads the counters saved in path, if it exists and is of the
+// current month, and saves them there from now on. An empty path keeps them
+// in memory only.
+func (q *MonthlyQuota) UseFile(path string) error {
+	var saved quotaFile
+	if path != "" {
+		data, err := os.ReadFile(path)
+		switch {
+		case errors.Is(err, os.ErrNotExist):
+		case err != nil:
+			return fmt.Errorf("read proxy usage file: %w", err)
+		default:
+			if err := json.Unmarshal(data, &saved); err != nil {
+				return fmt.Errorf("parse proxy usage file %s: %w", path, err)
+			}
+		}
+	}
+
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	if q.file == "" && saved.Month == thisMonth() {
+		// Traffic counted since start-up adds to what was saved
+		for key, counted := range saved.Proxies {
+			if counted == nil {
+				continue
+			}
+			usage := q.counter(key)
+			usage.Bytes += counted.Bytes
+			usage.Requests += counted.Requests
+		}
+	}
+	q.file = path
+	q.savedAt = time.Now()
+	return nil
+}
+
+// Save writes the counters to the usage file if they changed since the last
+// save. The file is replaced in one rename so a crash cannot truncate it, and
+// written outside the lock so traffic is not held up by the disk.
+func (q *MonthlyQuota) Save() error {
+	q.saving.Lock()
+	defer q.saving.Unlock()
+
+	q.mutex.Lock()
+	if q.file == "" || !q.dirty {
+		q.mutex.Unlock()
+		return nil
+	}
+	q.rollover()
+	file := q.file
+	data, err := json.MarshalIndent(quotaFile{Month: q.month, Proxies: q.used}, "", "  ")
+	q.dirty = false
+	q.savedAt = time.Now()
+	q.mutex.Unlock()
+	if err != nil {
+		return fmt.Errorf("marshal proxy usage: %w", err)
+	}
+
+	if err := writeQuotaFile(file, data); err != nil {
+		q.mutex.Lock()
+		q.dirty = true
+		q.mutex.Unlock()
+		return err
+	}
+	return nil
+}
+
+// writeQuotaFile replaces the usage file with data
+func writeQuotaFile(file string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(file), ".proxy-usage-*")
+	if err != nil {
+		return fmt.Errorf("write proxy usage file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(append(data, '\n')); err != nil {
+		tmp.Close()
+		return fmt.Errorf("write proxy usage file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("write proxy usage file: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), file); err != nil {
+		return fmt.Errorf("write proxy usage file: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,185 @@
+// pkg/utils/proxy_ranking.go
+package utils
+
+import (
+	"context"
+	"net/url"
+	"sort"
+	"sync"
+	"time"
+)
+
+// proxyRankAlpha weighs the newest sample in the moving averages; at 0.2 a
+// proxy's rank mostly reflects its last ten or so requests
+const proxyRankAlpha = 0.2
+
+// proxyRankMinSuccess keeps a proxy that failed every recent request from
+// dividing by zero; its score is then 20 times its latency
+const proxyRankMinSuccess = 0.05
+
+// The best ranked 1/interactiveShare of a pool serves interactive requests;
+// bulk requests are spread across the rest
+const interactiveShare = 4
+
+type requestPriorityKey struct{}
+
+// WithBulkPriority marks the requests made with the returned context as bulk
+// work, such as full-history scrapes, which are kept off the best proxies so
+// those stay free for interactive requests
+func WithBulkPriority(ctx context.Context) context.Context {
+	return context.WithValue(ctx, requestPriorityKey{}, true)
+}
+
+// IsBulkPriority reports whether ctx was marked by WithBulkPriority
+func IsBulkPriority(ctx context.Context) bool {
+	bulk, _ := ctx.Value(requestPriorityKey{}).(bool)
+	return bulk
+}
+
+// ProxyRanker keeps an exponential moving average of each proxy's latency
+// and success rate and ranks proxies by them
+type ProxyRanker struct {
+	mutex sync.Mutex
+	stats map[string]*proxyStats
+}
+
+type proxyStats struct {
+	latency  float64 // EMA of time to response headers, in seconds
+	success  float64 // EMA of 1 for a usable response, 0 for a failure
+	samples  int
+	measured bool // latency has at least one sample
+}
+
+// ProxyRank is one proxy's standing in the ranking
+type ProxyRank struct {
+	Proxy       string  `json:"proxy"`
+	Rank        int     `json:"rank"`
+	LatencyMS   int64   `json:"latency_ms"`
+	SuccessRate float64 `json:"success_rate"`
+	Samples     int     `json:"samples"`
+}
+
+func NewProxyRanker() *ProxyRanker {
+	return &ProxyRanker{stats: make(map[string]*proxyStats)}
+}
+
+// Record adds one request through proxy. ok is false for connection errors
+// and 429/5xx responses; their latency is not counted, only the failure.
+func (r *ProxyRanker) Record(proxy string, latency time.Duration, ok bool) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	st, found := r.stats[proxy]
+	if !found {
+		st = &proxyStats{success: 1}
+		r.stats[proxy] = st
+	}
+	st.samples++
+
+	outcome := 0.0
+	if ok {
+		outcome = 1
+	}
+	st.success += proxyRankAlpha * (outcome - st.success)
+
+	if ok {
+		if st.measured {
+			st.latency += proxyRankAlpha * (latency.Seconds() - st.latency)
+		} else {
+			st.latency = latency.Seconds()
+			st.measured = true
+		}
+	}
+}
+
+// Rank returns proxies ordered best first. A proxy's score is its latency
+// divided by its success rate, so a fast proxy that keeps failing sinks below
+// a slower reliable one. Proxies without a latency sample are scored with the
+// average latency of the measured ones, so new proxies are neither shunned nor
+// favoured. Ties keep the configured order.
+func (r *ProxyRanker) Rank(proxies []*url.URL) []*url.URL {
+	r.mutex.Lock()
+	var total float64
+	var measured int
+	for _, proxyURL := range proxies {
+		if st, ok := r.stats[proxyURL.String()]; ok && st.measured {
+			total += st.latency
+			measured++
+		}
+	}
+	baseLatency := 1.0
+	if measured > 0 {
+		baseLatency = total / float64(measured)
+	}
+
+	scores := make([]float64, len(proxies))
+	for i, proxyURL := range proxies {
+		latency, success := baseLatency, 1.0
+		if st, ok := r.stats[proxyURL.String()]; ok {
+			success = st.success
+			if st.measured {
+				latency = st.latency
+			}
+		}
+		scores[i] = latency / max(success, proxyRankMinSuccess)
+	}
+	r.mutex.Unlock()
+
+	order := make([]int, len(proxies))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		return scores[order[a]] < scores[order[b]]
+	})
+
+	ranked := make([]*url.URL, len(proxies))
+	for i, idx := range order {
+		ranked[i] = proxies[idx]
+	}
+	return ranked
+}
+
+// Ranking reports the standing of each given proxy, best first, with
+// credentials masked
+func (r *ProxyRanker) Ranking(proxies []*url.URL) []ProxyRank {
+	ranked := r.Rank(proxies)
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	ranking := make([]ProxyRank, 0, len(ranked))
+	for i, proxyURL := range ranked {
+		display := *proxyURL
+		display.Fragment = ""
+		entry := ProxyRank{
+			Proxy:       maskProxyURL(display.String()),
+			Rank:        i + 1,
+			SuccessRate: 1,
+		}
+		if st, ok := r.stats[proxyURL.String()]; ok {
+			entry.LatencyMS = time.Duration(st.latency * float64(time.Second)).Milliseconds()
+			entry.SuccessRate = st.success
+			entry.Samples = st.samples
+		}
+		ranking = append(ranking, entry)
+	}
+	return ranking
+}
+
+// pickProxy chooses a proxy from ranked (best first) for one request. The best
+// quarter of the pool serves interactive requests and the rest serves bulk
+// requests, so long scrapes do not crowd out interactive ones. id spreads
+// requests within the share and moves a session on when it rotates.
+func pickProxy(ranked []*url.URL, id uint32, bulk bool) *url.URL {
+	if len(ranked) == 0 {
+		return nil
+	}
+
+	top := (len(ranked) + interactiveShare - 1) / interactiveShare
+	share := ranked[:top]
+	if bulk && top < len(ranked) {
+		share = ranked[top:]
+	}
+	return share[id%uint32(len(share))]
+}
@@ -0,0 +1,88 @@
+// pkg/utils/proxy_session.go
+package utils
+
+import (
+	"context"
+	"math/rand"
+	"sync"
+	"sync/atomic"
+)
+
+type proxySessionKey struct{}
+
+// ProxySession pins every request of one scrape operation to the same proxy
+// and browser profile (TLS fingerprint, User-Agent and headers), so a
+// multi-page scrape looks like one browser instead of hopping IPs between
+// pages. The session moves to the next proxy only when a request through the
+// current one fails.
+type ProxySession struct {
+	proxyID    uint32
+	profileIdx int
+	userAgent  string
+
+	mutex sync.Mutex
+	proxy string // proxy the session is pinned to, "" until the first request
+	avoid string // proxy the session last rotated away from
+}
+
+// WithProxySession returns a context carrying a new proxy session. If ctx
+// already carries one it is returned unchanged, so nested operations share
+// their caller's session.
+func WithProxySession(ctx context.Context) context.Context {
+	if ProxySessionFromContext(ctx) != nil {
+		return ctx
+	}
+	profileIdx := rand.Intn(len(browserProfiles))
+	return context.WithValue(ctx, proxySessionKey{}, &ProxySession{
+		proxyID:    rand.Uint32(),
+		profileIdx: profileIdx,
+		userAgent:  randomItem(browserProfiles[profileIdx].UserAgents),
+	})
+}
+
+// ProxySessionFromContext returns the session carried by ctx, or nil
+func ProxySessionFromContext(ctx context.Context) *ProxySession {
+	session, _ := ctx.Value(proxySessionKey{}).(*ProxySession)
+	return session
+}
+
+// ProxyID is the rotator index the session currently uses
+func (s *ProxySession) ProxyID() uint32 {
+	return atomic.LoadUint32(&s.proxyID)
+}
+
+// Profile is the browser the session presents on every request
+func (s *ProxySession) Profile() BrowserProfile {
+	return browserProfiles[s.profileIdx]
+}
+
+// UserAgent is the User-Agent the session sends unless a fixed one is configured
+func (s *ProxySession) UserAgent() string {
+	return s.userAgent
+}
+
+// Rotate moves the session to the next proxy after a failure
+func (s *ProxySession) Rotate() {
+	atomic.AddUint32(&s.proxyID, 1)
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.proxy != "" {
+		s.avoid = s.proxy
+	}
+	s.proxy = ""
+}
+
+// pinned returns the proxy the session is pinned to and the one it last
+// rotated away from
+func (s *ProxySession) pinned() (proxy, avoid string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.proxy, s.avoid
+}
+
+func (s *ProxySession) pin(proxy string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.proxy = proxy
+}
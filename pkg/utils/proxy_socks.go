@@ -0,0 +1,72 @@
+// pkg/utils/proxy_socks.go
+package utils
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"time"
+
+	"golang.org/x/net/proxy"
+)
+
+// dialSOCKS5 connects to addr through the dialer's SOCKS5 proxy. With
+// socks5:// the host is resolved here and the proxy is handed an IP address,
+// as curl does; with socks5h:// the proxy resolves the name, so no DNS lookup
+// leaves this host and the proxy's resolver picks the address.
+func (d *FingerprintingDialer) dialSOCKS5(ctx context.Context, network, addr string) (net.Conn, error) {
+	if d.proxyURL.Scheme == "socks5" {
+		resolved, err := resolveLocally(ctx, addr)
+		if err != nil {
+			return nil, err
+		}
+		addr = resolved
+	}
+
+	var auth *proxy.Auth
+	if d.proxyURL.User != nil {
+		auth = &proxy.Auth{User: d.proxyURL.User.Username()}
+		auth.Password, _ = d.proxyURL.User.Password()
+	}
+
+	dialer, err := proxy.SOCKS5("tcp", proxyAddress(d.proxyURL), auth, &net.Dialer{
+		Timeout:   30 * time.Second,
+		KeepAlive: 30 * time.Second,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("create SOCKS5 dialer: %w", err)
+	}
+	contextDialer, ok := dialer.(proxy.ContextDialer)
+	if !ok {
+		return nil, fmt.Errorf("SOCKS5 dialer does not support contexts")
+	}
+	return contextDialer.DialContext(ctx, network, addr)
+}
+
+// resolveLocally replaces the host of addr by one of its IP addresses,
+// preferring IPv4, which more SOCKS5 proxies can reach
+func resolveLocally(ctx context.Context, addr string) (string, error) {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return "", err
+	}
+	if net.ParseIP(host) != nil {
+		return addr, nil
+	}
+
+	ips, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+	if err != nil {
+		return "", fmt.Errorf("resolve %s for SOCKS5 proxy: %w", host, err)
+	}
+	if len(ips) == 0 {
+		return "", fmt.Errorf("resolve %s for SOCKS5 proxy: no addresses", host)
+	}
+	ip := ips[0].IP
+	for _, candidate := range ips {
+		if candidate.IP.To4() != nil {
+			ip = candidate.IP
+			break
+		}
+	}
+	return net.JoinHostPort(ip.String(), port), nil
+}
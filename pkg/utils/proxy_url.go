@@ -0,0 +1,122 @@
+// pkg/utils/proxy_url.go
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// defaultProxyPorts are used when a proxy URL leaves out the port
+var defaultProxyPorts = map[string]string{
+	"http":    "80",
+	"https":   "443",
+	"socks5":  "1080",
+	"socks5h": "1080",
+}
+
+// socks5MaxCredential is the longest username or password SOCKS5 can carry
+// (RFC 1929)
+const socks5MaxCredential = 255
+
+// ParseProxyURL parses and validates one proxy URL of scheme http, https,
+// socks5 or socks5h. The host may be a name, an IPv4 address or a bracketed
+// IPv6 address, optionally with a zone written as %25:
+// http://[2001:db8::1]:8080, socks5://[fe80::1%25eth0]:1080.
+func ParseProxyURL(rawURL string) (*url.URL, error) {
+	// url.Parse would read the last group of a bare IPv6 address as the port
+	if isBareIPv6Host(rawURL) {
+		return nil, errors.New("IPv6 addresses must be in brackets, e.g. http://[2001:db8::1]:8080")
+	}
+	proxyURL, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, ok := defaultProxyPorts[proxyURL.Scheme]; !ok {
+		return nil, fmt.Errorf("unsupported proxy scheme %q", proxyURL.Scheme)
+	}
+
+	host := proxyURL.Hostname()
+	if host == "" {
+		return nil, errors.New("missing proxy host")
+	}
+	if strings.HasPrefix(proxyURL.Host, "[") {
+		addr, _, _ := strings.Cut(host, "%")
+		if ip := net.ParseIP(addr); ip == nil || ip.To4() != nil {
+			return nil, fmt.Errorf("invalid IPv6 address %q", host)
+		}
+	}
+
+	if port := proxyURL.Port(); port != "" {
+		if n, err := strconv.Atoi(port); err != nil || n < 1 || n > 65535 {
+			return nil, fmt.Errorf("invalid proxy port %q", port)
+		}
+	}
+
+	if err := validateProxyCredentials(proxyURL); err != nil {
+		return nil, err
+	}
+
+	return proxyURL, nil
+}
+
+// validateProxyCredentials checks the user info of a proxy URL: a password
+// needs a username, and SOCKS5 limits both to 255 bytes
+func validateProxyCredentials(proxyURL *url.URL) error {
+	if proxyURL.User == nil {
+		return nil
+	}
+	username := proxyURL.User.Username()
+	password, _ := proxyURL.User.Password()
+	if username == "" {
+		return errors.New("proxy credentials need a username")
+	}
+	if isSOCKS5(proxyURL) && (len(username) > socks5MaxCredential || len(password) > socks5MaxCredential) {
+		return fmt.Errorf("SOCKS5 proxy username and password must be at most %d bytes", socks5MaxCredential)
+	}
+	return nil
+}
+
+// isSOCKS5 reports whether proxyURL is a SOCKS5 proxy, resolving names
+// locally (socks5) or on the proxy (socks5h)
+func isSOCKS5(proxyURL *url.URL) bool {
+	return proxyURL != nil && (proxyURL.Scheme == "socks5" || proxyURL.Scheme == "socks5h")
+}
+
+// isBareIPv6Host reports whether the host of rawURL looks like an IPv6
+// address written without brackets, as in http://2001:db8::1:8080
+func isBareIPv6Host(rawURL string) bool {
+	_, rest, found := strings.Cut(rawURL, "://")
+	if !found {
+		return false
+	}
+	authority := rest
+	if i := strings.IndexAny(rest, "/?#"); i >= 0 {
+		authority = rest[:i]
+	}
+	host := authority
+	if i := strings.LastIndex(authority, "@"); i >= 0 {
+		host = authority[i+1:]
+	}
+	return !strings.Contains(host, "[") && strings.Count(host, ":") >= 2
+}
+
+// proxyAddress is the host:port to dial for a proxy, filling in the scheme's
+// default port and keeping IPv6 addresses bracketed
+func proxyAddress(proxyURL *url.URL) string {
+	if proxyURL.Port() != "" {
+		return proxyURL.Host
+	}
+	return net.JoinHostPort(proxyURL.Hostname(), defaultProxyPorts[proxyURL.Scheme])
+}
+
+// proxyServerName is the name to verify an HTTPS proxy's certificate against;
+// an IPv6 zone only means something to the local host
+func proxyServerName(proxyURL *url.URL) string {
+	host, _, _ := strings.Cut(proxyURL.Hostname(), "%")
+	return host
+}
@@ -0,0 +1,63 @@
+// pkg/utils/request_limiter.go
+package utils
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+)
+
+type requestLimiterKey struct{}
+
+// RequestLimiter spaces requests evenly to stay under a rate. Requests of
+// every context carrying the same limiter share the rate. It is safe for
+// concurrent use; a nil RequestLimiter never waits.
+type RequestLimiter struct {
+	mutex    sync.Mutex
+	interval time.Duration
+	next     time.Time
+}
+
+// NewRequestLimiter allows perMinute requests a minute; it returns nil, which
+// never waits, when perMinute is 0 or less
+func NewRequestLimiter(perMinute int) *RequestLimiter {
+	if perMinute <= 0 {
+		return nil
+	}
+	return &RequestLimiter{interval: time.Minute / time.Duration(perMinute)}
+}
+
+// Wait blocks until the next request may go out or ctx is done
+func (l *RequestLimiter) Wait(ctx context.Context) error {
+	if l == nil {
+		return nil
+	}
+	l.mutex.Lock()
+	now := time.Now()
+	slot := l.next
+	if slot.Before(now) {
+		slot = now
+	}
+	l.next = slot.Add(l.interval)
+	l.mutex.Unlock()
+
+	wait := time.Until(slot)
+	if wait <= 0 {
+		return nil
+	}
+	// No jitter: the slots already keep the rate
+	return SleepContext(ctx, wait, 0)
+}
+
+// WithRequestLimiter makes every request sent with the returned context wait
+// for l, retries included
+func WithRequestLimiter(ctx context.Context, l *RequestLimiter) context.Context {
+	return context.WithValue(ctx, requestLimiterKey{}, l)
+}
+
+// waitForRequestSlot waits for the limiter the context of req carries
+func waitForRequestSlot(req *http.Request) error {
+	l, _ := req.Context().Value(requestLimiterKey{}).(*RequestLimiter)
+	return l.Wait(req.Context())
+}
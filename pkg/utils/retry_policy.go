@@ -0,0 +1,161 @@
+// pkg/utils/retry_policy.go
+package utils
+
+import (
+	"fmt"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"sync/atomic"
+	"time"
+)
+
+// RetryPolicy decides how long RetryableClient waits between the attempts of
+// a request and when it gives up on it
+type RetryPolicy struct {
+	// Each wait is drawn at random between 0 and BaseDelay doubled per
+	// retry, at most MaxDelay ("full jitter"), so clients failing together do
+	// not retry together
+	BaseDelay time.Duration
+	MaxDelay  time.Duration
+	// Budget caps the time one request spends on its attempts and the waits
+	// between them; 0 leaves only the attempt count
+	Budget time.Duration
+}
+
+// DefaultRetryPolicy waits up to 2s before the first retry and 4s before the
+// second, like the fixed backoff it replaced, and gives up after two minutes
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{BaseDelay: time.Second, MaxDelay: 30 * time.Second, Budget: 2 * time.Minute}
+}
+
+// backoff draws the wait before retry number retry, counting from 1
+func (p RetryPolicy) backoff(retry int) time.Duration {
+	ceiling := p.MaxDelay
+	if retry < 32 && p.BaseDelay > 0 {
+		if grown := p.BaseDelay << uint(retry); grown > 0 && (ceiling <= 0 || grown < ceiling) {
+			ceiling = grown
+		}
+	}
+	if ceiling <= 0 {
+		return 0
+	}
+	return time.Duration(rand.Int63n(int64(ceiling) + 1))
+}
+
+// RetryStats count the retries of a RetryableClient since start-up
+type RetryStats struct {
+	// Attempts made after a failed one
+	Retries int64 `json:"retries"`
+	// Requests that failed after their last allowed attempt
+	Exhausted int64 `json:"exhausted"`
+	// Requests given up early because the next wait would overrun the
+	// retry budget
+	BudgetExhausted int64 `json:"budget_exhausted"`
+	// Failures not retried because retrying them is not safe, e.g. a POST
+	// whose connection broke
+	NotRetried int64 `json:"not_retried"`
+}
+
+// retryCounters are the counters behind RetryStats
+type retryCounters struct {
+	retries         int64
+	exhausted       int64
+	budgetExhausted int64
+	notRetried      int64
+}
+
+func (c *retryCounters) stats() RetryStats {
+	return RetryStats{
+		Retries:         atomic.LoadInt64(&c.retries),
+		Exhausted:       atomic.LoadInt64(&c.exhausted),
+		BudgetExhausted: atomic.LoadInt64(&c.budgetExhausted),
+		NotRetried:      atomic.LoadInt64(&c.notRetried),
+	}
+}
+
+// idempotent reports whether sending req twice has the effect of sending it
+// once, so it may be retried after it possibly reached the server
+func idempotent(req *http.Request) bool {
+	switch req.Method {
+	case "", http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	return req.Header.Get("Idempotency-Key") != ""
+}
+
+// retryableStatus reports whether a response with status code is worth
+// another attempt of req. 429 and 503 mean the server turned the request
+// away without acting on it, so they are retried for any method; other
+// gateway failures only for idempotent requests.
+func retryableStatus(req *http.Request, code int) bool {
+	switch code {
+	case http.StatusTooManyRequests, http.StatusServiceUnavailable:
+		return true
+	case http.StatusRequestTimeout, http.StatusInternalServerError, http.StatusBadGateway, http.StatusGatewayTimeout:
+		return idempotent(req)
+	}
+	return false
+}
+
+// retryAfter is the wait a 429 or 503 response asks for in its Retry-After
+// header, in seconds or as a date; 0 when it has none
+func retryAfter(resp *http.Response) time.Duration {
+	value := resp.Header.Get("Retry-After")
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	if at, err := http.ParseTime(value); err == nil {
+		return max(time.Until(at), 0)
+	}
+	return 0
+}
+
+// retryState follows the attempts of one request
+type retryState struct {
+	policy      RetryPolicy
+	maxAttempts int
+	counters    *retryCounters
+	deadline    time.Time
+	// Attempts made so far
+	attempts int
+}
+
+func newRetryState(policy RetryPolicy, maxAttempts int, counters *retryCounters) *retryState {
+	s := &retryState{policy: policy, maxAttempts: maxAttempts, counters: counters}
+	if policy.Budget > 0 {
+		s.deadline = time.Now().Add(policy.Budget)
+	}
+	return s
+}
+
+// again decides after a failed attempt of req whether to make another,
+// waiting out the backoff, or at least minWait, before it returns true. It
+// returns false when retrying is not safe, the attempts or the budget are
+// used up, or the context of req ends during the wait.
+func (s *retryState) again(req *http.Request, safe bool, minWait time.Duration) bool {
+	if !safe {
+		atomic.AddInt64(&s.counters.notRetried, 1)
+		return false
+	}
+	if s.attempts >= s.maxAttempts {
+		atomic.AddInt64(&s.counters.exhausted, 1)
+		return false
+	}
+	wait := max(s.policy.backoff(s.attempts), minWait)
+	if !s.deadline.IsZero() && time.Now().Add(wait).After(s.deadline) {
+		fmt.Printf("Giving up after %d attempts: waiting %v would overrun the retry budget of %v\n", s.attempts, wait, s.policy.Budget)
+		atomic.AddInt64(&s.counters.budgetExhausted, 1)
+		return false
+	}
+	if err := SleepContext(req.Context(), wait, 0); err != nil {
+		return false
+	}
+	fmt.Printf("Retry attempt %d after waiting %v\n", s.attempts+1, wait)
+	atomic.AddInt64(&s.counters.retries, 1)
+	countRetry(req)
+	return true
+}
@@ -0,0 +1,37 @@
+// pkg/utils/sleep.go
+package utils
+
+import (
+	"context"
+	"math/rand"
+	"time"
+)
+
+// SleepContext waits for d or until ctx ends, whichever comes first, and
+// returns ctx's error when it ended. jitter stretches or shrinks d by a random
+// share of up to jitter (0.2 waits 80% to 120% of d), so clients pausing
+// together do not send their next requests in lockstep.
+func SleepContext(ctx context.Context, d time.Duration, jitter float64) error {
+	d = Jitter(d, jitter)
+	if d <= 0 {
+		return ctx.Err()
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Jitter is d changed by a random share of up to jitter either way; d itself
+// when jitter is 0 or less, and never more than twice d
+func Jitter(d time.Duration, jitter float64) time.Duration {
+	if jitter <= 0 || d <= 0 {
+		return d
+	}
+	jitter = min(jitter, 1)
+	return time.Duration(float64(d) * (1 + jitter*(2*rand.Float64()-1)))
+}
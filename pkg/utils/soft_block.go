@@ -0,0 +1,174 @@
+// pkg/utils/soft_block.go
+package utils
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// softBlockSniffBytes is how much of a response body, as sent, is read to
+// tell JSON from a block page
+const softBlockSniffBytes = 4096
+
+// Reasons of a soft block
+const (
+	// Reddit's "blocked by network security" or "whoa there, pardner" page
+	SoftBlockBlocked = "blocked"
+	// A page asking to log in before showing the content
+	SoftBlockLoginWall = "login_wall"
+	// Any other body that is not JSON, e.g. a CDN error page
+	SoftBlockNonJSON = "non_json"
+)
+
+// ErrSoftBlocked is wrapped by SoftBlockError
+var ErrSoftBlocked = errors.New("soft-blocked")
+
+// blockMarkers and loginMarkers identify Reddit's block and login pages,
+// matched against the lowercased start of the body
+var (
+	blockMarkers = []string{"blocked by network security", "whoa there, pardner", "your request has been blocked"}
+	loginMarkers = []string{"log in to continue", "please log in", "reddit.com/login"}
+)
+
+// SoftBlockError is returned for a response to a JSON request that Reddit
+// answered with 200 but a page in place of JSON, the way it turns away
+// clients it suspects without an error status
+type SoftBlockError struct {
+	// SoftBlockBlocked, SoftBlockLoginWall or SoftBlockNonJSON
+	Reason string
+	// Proxy with its password masked, empty without proxies
+	Proxy string
+}
+
+func (e *SoftBlockError) Error() string {
+	if e.Proxy == "" {
+		return fmt.Sprintf("%s: %s page instead of JSON", ErrSoftBlocked, e.Reason)
+	}
+	return fmt.Sprintf("%s: %s page instead of JSON through %s", ErrSoftBlocked, e.Reason, e.Proxy)
+}
+
+func (e *SoftBlockError) Unwrap() error {
+	return ErrSoftBlocked
+}
+
+type jsonExpectedKey struct{}
+
+// WithJSONExpected marks requests made with the returned context as asking
+// for JSON, so the client treats any other successful response as a soft
+// block: it fails the attempt, moves the session to another proxy and counts
+// the block against the proxy
+func WithJSONExpected(ctx context.Context) context.Context {
+	return context.WithValue(ctx, jsonExpectedKey{}, true)
+}
+
+func jsonExpected(ctx context.Context) bool {
+	expected, _ := ctx.Value(jsonExpectedKey{}).(bool)
+	return expected
+}
+
+// detectSoftBlock returns the reason a successful response is a block page,
+// or "" when its body starts like JSON. It reads the start of the body and
+// puts it back, so resp can be read as if untouched.
+func detectSoftBlock(resp *http.Response) string {
+	if resp.StatusCode != http.StatusOK {
+		return ""
+	}
+
+	raw := make([]byte, softBlockSniffBytes)
+	n, _ := io.ReadFull(resp.Body, raw)
+	raw = raw[:n]
+	resp.Body = struct {
+		io.Reader
+		io.Closer
+	}{io.MultiReader(bytes.NewReader(raw), resp.Body), resp.Body}
+
+	// Decode what was read; a cut-off stream still yields its start
+	sniffed := &http.Response{Header: resp.Header, Body: io.NopCloser(bytes.NewReader(raw))}
+	var start []byte
+	if reader, err := decodedBody(sniffed, 0); err == nil {
+		start, _ = io.ReadAll(io.LimitReader(reader, softBlockSniffBytes))
+		reader.Close()
+	}
+	return classifyBody(start)
+}
+
+// classifyBody tells the start of a body that is not JSON apart by its
+// markers; "" when it is JSON or empty
+func classifyBody(start []byte) string {
+	start = bytes.TrimSpace(start)
+	if len(start) == 0 || start[0] == '{' || start[0] == '[' {
+		return ""
+	}
+	lower := strings.ToLower(string(start))
+	for _, marker := range blockMarkers {
+		if strings.Contains(lower, marker) {
+			return SoftBlockBlocked
+		}
+	}
+	for _, marker := range loginMarkers {
+		if strings.Contains(lower, marker) {
+			return SoftBlockLoginWall
+		}
+	}
+	return SoftBlockNonJSON
+}
+
+// SoftBlockStats count the soft blocks since start-up
+type SoftBlockStats struct {
+	Total    int64            `json:"total"`
+	ByReason map[string]int64 `json:"by_reason"`
+	// Soft blocks per proxy, with passwords masked
+	ByProxy []ProxySoftBlocks `json:"by_proxy,omitempty"`
+}
+
+// ProxySoftBlocks is the number of soft blocks served through one proxy
+type ProxySoftBlocks struct {
+	Proxy  string `json:"proxy"`
+	Blocks int64  `json:"blocks"`
+}
+
+// softBlockCounters are the counters behind SoftBlockStats
+type softBlockCounters struct {
+	mutex    sync.Mutex
+	total    int64
+	byReason map[string]int64
+	byProxy  map[string]int64
+}
+
+func newSoftBlockCounters() *softBlockCounters {
+	return &softBlockCounters{byReason: make(map[string]int64), byProxy: make(map[string]int64)}
+}
+
+// add counts a soft block for reason through proxy, masked, "" without one
+func (c *softBlockCounters) add(reason, proxy string) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.total++
+	c.byReason[reason]++
+	if proxy != "" {
+		c.byProxy[proxy]++
+	}
+}
+
+func (c *softBlockCounters) stats() SoftBlockStats {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	stats := SoftBlockStats{Total: c.total, ByReason: make(map[string]int64, len(c.byReason))}
+	for reason, n := range c.byReason {
+		stats.ByReason[reason] = n
+	}
+	for proxy, n := range c.byProxy {
+		stats.ByProxy = append(stats.ByProxy, ProxySoftBlocks{Proxy: proxy, Blocks: n})
+	}
+	sort.Slice(stats.ByProxy, func(i, j int) bool {
+		return stats.ByProxy[i].Proxy < stats.ByProxy[j].Proxy
+	})
+	return stats
+}
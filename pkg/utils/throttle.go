@@ -0,0 +1,168 @@
+// pkg/utils/throttle.go
+package utils
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// maxThrottleLevel caps the slow-down at 2^4: delays 16 times as long and a
+// sixteenth of the concurrency
+const maxThrottleLevel = 4
+
+// throttleMinSamples is how many responses a window needs before its block
+// rate raises the throttle level
+const throttleMinSamples = 10
+
+// Throttle slows scrapes down while Reddit pushes back. It counts 429 and 403
+// responses, and soft blocks, per time window; a window whose share of them reaches the block
+// rate raises the throttle level by one, and a window with less than a
+// quarter of it lowers the level again. Each level doubles the delays between
+// requests and halves the concurrency of comment expansion. It is safe for
+// concurrent use; a nil Throttle never slows anything down.
+type Throttle struct {
+	mutex       sync.Mutex
+	window      time.Duration
+	blockRate   float64
+	level       int
+	windowStart time.Time
+	responses   int64
+	blocked     int64
+	lastRate    float64
+	changedAt   time.Time
+}
+
+// ThrottleStats is the state of a Throttle
+type ThrottleStats struct {
+	Level    int `json:"level"`
+	MaxLevel int `json:"max_level"`
+	// Factor the delays between requests are multiplied by
+	DelayFactor   int     `json:"delay_factor"`
+	WindowSeconds float64 `json:"window_seconds"`
+	// Responses and 429/403 responses in the current window
+	Responses int64 `json:"responses"`
+	Blocked   int64 `json:"blocked"`
+	// Share of 429/403 responses that raises the level
+	BlockRate float64 `json:"block_rate"`
+	// Share of 429/403 responses in the last completed window
+	LastBlockRate float64   `json:"last_block_rate"`
+	ChangedAt     time.Time `json:"changed_at"`
+}
+
+// NewThrottle creates a throttle at level 0 that evaluates windows of the
+// given length against blockRate
+func NewThrottle(window time.Duration, blockRate float64) *Throttle {
+	return &Throttle{window: window, blockRate: blockRate, windowStart: time.Now()}
+}
+
+// Configure changes the window length and block rate; a window of 0 or less
+// disables the throttle and drops it back to level 0
+func (t *Throttle) Configure(window time.Duration, blockRate float64) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	t.window = window
+	t.blockRate = blockRate
+	if window <= 0 {
+		t.level = 0
+	}
+}
+
+// Record counts a response with the given status
+func (t *Throttle) Record(status int) {
+	if t == nil {
+		return
+	}
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	if t.window <= 0 {
+		return
+	}
+
+	t.advance(time.Now())
+	t.responses++
+	if status == http.StatusTooManyRequests || status == http.StatusForbidden {
+		t.blocked++
+	}
+}
+
+// RecordSoftBlock counts a response that was a block page served with 200
+// like a 403
+func (t *Throttle) RecordSoftBlock() {
+	t.Record(http.StatusForbidden)
+}
+
+// advance closes the windows that ended before now; callers hold the mutex
+func (t *Throttle) advance(now time.Time) {
+	elapsed := now.Sub(t.windowStart)
+	if elapsed < t.window {
+		return
+	}
+
+	level := t.level
+	t.lastRate = 0
+	if t.responses > 0 {
+		t.lastRate = float64(t.blocked) / float64(t.responses)
+	}
+	switch {
+	case t.responses >= throttleMinSamples && t.lastRate >= t.blockRate:
+		level = min(level+1, maxThrottleLevel)
+	case t.lastRate < t.blockRate/4:
+		level--
+	}
+	// Windows without any traffic count as clean ones
+	level = max(0, level-int(elapsed/t.window-1))
+
+	if level != t.level {
+		fmt.Printf("Throttle level %d -> %d: %d of %d responses were 429/403 (%.1f%%), delays now x%d\n",
+			t.level, level, t.blocked, t.responses, t.lastRate*100, 1<<level)
+		t.level = level
+		t.changedAt = now
+	}
+	t.windowStart = now
+	t.responses = 0
+	t.blocked = 0
+}
+
+// Level is the current throttle level, 0 when not throttling
+func (t *Throttle) Level() int {
+	if t == nil {
+		return 0
+	}
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	if t.window > 0 {
+		t.advance(time.Now())
+	}
+	return t.level
+}
+
+// Delay stretches a delay between requests to the current level
+func (t *Throttle) Delay(base time.Duration) time.Duration {
+	return base << t.Level()
+}
+
+// Concurrency shrinks a number of concurrent workers to the current level,
+// keeping at least one
+func (t *Throttle) Concurrency(n int) int {
+	return max(1, n>>t.Level())
+}
+
+// Stats reports the current state
+func (t *Throttle) Stats() ThrottleStats {
+	level := t.Level()
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	return ThrottleStats{
+		Level:         level,
+		MaxLevel:      maxThrottleLevel,
+		DelayFactor:   1 << level,
+		WindowSeconds: t.window.Seconds(),
+		Responses:     t.responses,
+		Blocked:       t.blocked,
+		BlockRate:     t.blockRate,
+		LastBlockRate: t.lastRate,
+		ChangedAt:     t.changedAt,
+	}
+}
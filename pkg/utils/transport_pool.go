@@ -0,0 +1,180 @@
+// pkg/utils/transport_pool.go
+package utils
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"net"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	utls "github.com/refraction-networking/utls"
+	"golang.org/x/net/http2"
+)
+
+// errHTTP1Negotiated is returned by the HTTP/2 dialer when the server picked
+// HTTP/1.1 from the ClientHello's ALPN list
+var errHTTP1Negotiated = errors.New("server negotiated HTTP/1.1")
+
+type transportKey struct {
+	proxy      string
+	profileIdx int
+}
+
+// newTransportKey keys the transport of proxyURL, nil for direct
+// connections, and the browser profile profileIdx
+func newTransportKey(proxyURL *url.URL, profileIdx int) transportKey {
+	key := transportKey{profileIdx: profileIdx}
+	if proxyURL != nil {
+		key.proxy = proxyURL.String()
+	}
+	return key
+}
+
+// profileTransport carries every connection made through one proxy with one
+// browser profile. It is built once and never mutated afterwards, so it can be
+// shared by concurrent requests and keep their connections alive.
+type profileTransport struct {
+	dialer *FingerprintingDialer
+	h1     *http.Transport
+	h2     *http2.Transport
+
+	mutex  sync.RWMutex
+	h1Only map[string]bool
+}
+
+// newProfileTransport builds the transport for one proxy and profile;
+// countBytes, when set, receives the traffic of every connection it opens
+func newProfileTransport(proxyURL *url.URL, profileIdx int, countBytes func(int64)) *profileTransport {
+	dialer := newFingerprintingDialer(proxyURL, profileIdx)
+	dialer.countBytes = countBytes
+	pt := &profileTransport{
+		dialer: dialer,
+		h1Only: make(map[string]bool),
+	}
+
+	pt.h1 = &http.Transport{
+		// Plain HTTP goes through an HTTP proxy as a forward proxy and through
+		// a SOCKS5 proxy by the dialer below; HTTPS is tunnelled by the
+		// fingerprinting dialer itself
+		Proxy: func(req *http.Request) (*url.URL, error) {
+			if req.URL.Scheme == "https" || isSOCKS5(proxyURL) {
+				return nil, nil
+			}
+			return proxyURL, nil
+		},
+		DialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
+			var conn net.Conn
+			var err error
+			if isSOCKS5(proxyURL) {
+				conn, err = dialer.dialSOCKS5(ctx, network, addr)
+			} else {
+				netDialer := net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second}
+				conn, err = netDialer.DialContext(ctx, network, addr)
+			}
+			if err != nil {
+				return nil, err
+			}
+			if countBytes != nil {
+				conn = &countingConn{Conn: conn, count: countBytes}
+			}
+			return newHeaderOrderConn(conn, dialer.profile, false), nil
+		},
+		DialTLSContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
+			conn, err := dialer.DialTLSContext(ctx, network, addr)
+			if err != nil {
+				return nil, err
+			}
+			return newHeaderOrderConn(conn, dialer.profile, false), nil
+		},
+		MaxIdleConns:          100,
+		MaxIdleConnsPerHost:   10,
+		IdleConnTimeout:       90 * time.Second,
+		TLSHandshakeTimeout:   10 * time.Second,
+		ExpectContinueTimeout: 1 * time.Second,
+		ResponseHeaderTimeout: 30 * time.Second,
+		ForceAttemptHTTP2:     false,
+		DisableCompression:    false,
+	}
+
+	if advertisesHTTP2(dialer.profile.ClientHello) {
+		pt.h2 = &http2.Transport{
+			DialTLSContext: func(ctx context.Context, network, addr string, _ *tls.Config) (net.Conn, error) {
+				conn, err := dialer.DialTLSContext(ctx, network, addr)
+				if err != nil {
+					return nil, err
+				}
+				if conn.(*utls.UConn).ConnectionState().NegotiatedProtocol != http2.NextProtoTLS {
+					conn.Close()
+					return nil, errHTTP1Negotiated
+				}
+				return newHeaderOrderConn(conn, dialer.profile, true), nil
+			},
+			IdleConnTimeout: 90 * time.Second,
+			ReadIdleTimeout: 30 * time.Second,
+		}
+	}
+
+	return pt
+}
+
+func (pt *profileTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.URL.Scheme == "https" && pt.h2 != nil && !pt.isH1Only(req.URL.Host) {
+		h2req := req.Clone(req.Context())
+		h2req.Header.Del("Connection")
+
+		resp, err := pt.h2.RoundTrip(h2req)
+		if !errors.Is(err, errHTTP1Negotiated) {
+			return resp, err
+		}
+
+		pt.mutex.Lock()
+		pt.h1Only[req.URL.Host] = true
+		pt.mutex.Unlock()
+
+		// The dial failed before anything was written, but rewind the body anyway
+		if req.GetBody != nil {
+			if req.Body, err = req.GetBody(); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	req.Header.Set("Connection", "keep-alive")
+	return pt.h1.RoundTrip(req)
+}
+
+func (pt *profileTransport) isH1Only(host string) bool {
+	pt.mutex.RLock()
+	defer pt.mutex.RUnlock()
+	return pt.h1Only[host]
+}
+
+func (pt *profileTransport) closeIdleConnections() {
+	pt.h1.CloseIdleConnections()
+	if pt.h2 != nil {
+		pt.h2.CloseIdleConnections()
+	}
+}
+
+// advertisesHTTP2 reports whether the ClientHello offers h2 in its ALPN
+// extension; only then may the server pick HTTP/2
+func advertisesHTTP2(helloID utls.ClientHelloID) bool {
+	spec, err := utls.UTLSIdToSpec(helloID)
+	if err != nil {
+		return false
+	}
+	for _, ext := range spec.Extensions {
+		if alpn, ok := ext.(*utls.ALPNExtension); ok {
+			for _, proto := range alpn.AlpnProtocols {
+				if proto == http2.NextProtoTLS {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,97 @@
+// pkg/utils/upstream_error.go
+package utils
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"unicode/utf8"
+)
+
+// upstreamSnippetBytes is how much of an error response UpstreamError keeps
+const upstreamSnippetBytes = 512
+
+// UpstreamError is returned when Reddit answered a request with an error
+// status: a 4xx, which is not retried, or a 429 or 5xx that kept failing
+// until the retries ran out
+type UpstreamError struct {
+	StatusCode int
+	// Start of the decompressed response body
+	Snippet string
+	// Proxy of the last attempt with its password masked, empty without
+	// proxies
+	Proxy string
+	// Attempts made, the last one included
+	Attempts int
+}
+
+func (e *UpstreamError) Error() string {
+	if e.StatusCode == http.StatusTooManyRequests || e.StatusCode >= 500 {
+		return fmt.Sprintf("server error: status %d", e.StatusCode)
+	}
+	return fmt.Sprintf("upstream error: status %d", e.StatusCode)
+}
+
+// UpstreamStatus returns the status of the UpstreamError in err's chain, 0
+// when there is none
+func UpstreamStatus(err error) int {
+	var upstream *UpstreamError
+	if errors.As(err, &upstream) {
+		return upstream.StatusCode
+	}
+	return 0
+}
+
+// newUpstreamError describes the response to the last of attempts, whose
+// decompressed body starts with body
+func newUpstreamError(req *http.Request, resp *http.Response, body []byte, attempts int) *UpstreamError {
+	if len(body) > upstreamSnippetBytes {
+		body = body[:upstreamSnippetBytes]
+		// Do not cut a character in half
+		for len(body) > 0 && !utf8.Valid(body) {
+			body = body[:len(body)-1]
+		}
+	}
+	return &UpstreamError{
+		StatusCode: resp.StatusCode,
+		Snippet:    strings.TrimSpace(strings.ToValidUTF8(string(body), "")),
+		Proxy:      proxyUsedFrom(req.Context()).url,
+		Attempts:   attempts,
+	}
+}
+
+// readSnippet reads the start of the decompressed body of resp and closes it
+func readSnippet(resp *http.Response, maxBytes int64) []byte {
+	defer resp.Body.Close()
+	body, err := decodedBody(resp, maxBytes)
+	if err != nil {
+		return nil
+	}
+	snippet, _ := io.ReadAll(io.LimitReader(body, upstreamSnippetBytes))
+	// Drain a little more so the connection can be reused
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	return snippet
+}
+
+type proxyUsedKey struct{}
+
+// proxyUsed is where the transport notes the proxy a request went through
+type proxyUsed struct {
+	url string
+}
+
+// withProxyUsed makes the transport note the proxy of each attempt of req
+func withProxyUsed(req *http.Request) *http.Request {
+	return req.WithContext(context.WithValue(req.Context(), proxyUsedKey{}, &proxyUsed{}))
+}
+
+// proxyUsedFrom returns the note of ctx; an unused one when there is none
+func proxyUsedFrom(ctx context.Context) *proxyUsed {
+	if used, ok := ctx.Value(proxyUsedKey{}).(*proxyUsed); ok {
+		return used
+	}
+	return &proxyUsed{}
+}
@@ -0,0 +1,187 @@
+// pkg/utils/upstream_headers.go
+package utils
+
+import (
+	"mime"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// upstreamTraceSize is how many recent responses the trace keeps
+const upstreamTraceSize = 50
+
+// capturedHeaders are the response headers kept besides every x-ratelimit-*:
+// content-type shows HTML challenge pages served in place of JSON, cf-ray
+// identifies the Cloudflare edge and request when raising it with Reddit
+var capturedHeaders = []string{"Content-Type", "Cf-Ray"}
+
+// UpstreamRecorder keeps the diagnostic headers of Reddit's responses: a trace
+// of the latest responses and counters for throttling and Cloudflare behaviour
+type UpstreamRecorder struct {
+	mutex         sync.Mutex
+	responses     int64
+	cloudflare    int64
+	byStatus      map[int]int64
+	byContentType map[string]int64
+	rateLimits    map[string]*RateLimitState
+	trace         []UpstreamTrace
+	next          int
+}
+
+// UpstreamTrace is one response from Reddit as seen through a proxy
+type UpstreamTrace struct {
+	Time    time.Time         `json:"time"`
+	Proxy   string            `json:"proxy,omitempty"`
+	Path    string            `json:"path"`
+	Status  int               `json:"status"`
+	Headers map[string]string `json:"headers,omitempty"`
+}
+
+// RateLimitState is the last rate limit Reddit reported to one proxy
+type RateLimitState struct {
+	Proxy      string    `json:"proxy"`
+	Used       float64   `json:"used"`
+	Remaining  float64   `json:"remaining"`
+	ResetSecs  float64   `json:"reset_seconds"`
+	ObservedAt time.Time `json:"observed_at"`
+}
+
+// UpstreamStats summarises the responses recorded since start-up
+type UpstreamStats struct {
+	Responses int64 `json:"responses"`
+	// Responses that carried a cf-ray header, i.e. came through Cloudflare
+	CloudflareResponses int64            `json:"cloudflare_responses"`
+	ByStatus            map[string]int64 `json:"by_status"`
+	ByContentType       map[string]int64 `json:"by_content_type"`
+	RateLimits          []RateLimitState `json:"rate_limits,omitempty"`
+	// Latest responses, newest first
+	Recent []UpstreamTrace `json:"recent"`
+}
+
+func NewUpstreamRecorder() *UpstreamRecorder {
+	return &UpstreamRecorder{
+		byStatus:      make(map[int]int64),
+		byContentType: make(map[string]int64),
+		rateLimits:    make(map[string]*RateLimitState),
+	}
+}
+
+// CaptureHeaders returns the diagnostic headers of a response, keyed by their
+// lowercase names
+func CaptureHeaders(header http.Header) map[string]string {
+	captured := make(map[string]string)
+	for name, values := range header {
+		if len(values) == 0 {
+			continue
+		}
+		if strings.HasPrefix(strings.ToLower(name), "x-ratelimit-") {
+			captured[strings.ToLower(name)] = values[0]
+		}
+	}
+	for _, name := range capturedHeaders {
+		if value := header.Get(name); value != "" {
+			captured[strings.ToLower(name)] = value
+		}
+	}
+	return captured
+}
+
+// FormatHeaders renders captured headers as sorted name=value pairs for logs
+func FormatHeaders(captured map[string]string) string {
+	names := make([]string, 0, len(captured))
+	for name := range captured {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	pairs := make([]string, len(names))
+	for i, name := range names {
+		pairs[i] = name + "=" + captured[name]
+	}
+	return strings.Join(pairs, " ")
+}
+
+// Record adds one response received through proxy ("" when none was used)
+func (u *UpstreamRecorder) Record(proxy string, req *http.Request, resp *http.Response) {
+	captured := CaptureHeaders(resp.Header)
+	display := ""
+	if proxy != "" {
+		display = maskProxyURL(stripFragment(proxy))
+	}
+	now := time.Now().UTC()
+
+	u.mutex.Lock()
+	defer u.mutex.Unlock()
+
+	u.responses++
+	u.byStatus[resp.StatusCode]++
+	if _, ok := captured["cf-ray"]; ok {
+		u.cloudflare++
+	}
+	if contentType := captured["content-type"]; contentType != "" {
+		if mediaType, _, err := mime.ParseMediaType(contentType); err == nil {
+			contentType = mediaType
+		}
+		u.byContentType[contentType]++
+	}
+
+	if remaining, err := strconv.ParseFloat(captured["x-ratelimit-remaining"], 64); err == nil {
+		state := &RateLimitState{Proxy: display, Remaining: remaining, ObservedAt: now}
+		state.Used, _ = strconv.ParseFloat(captured["x-ratelimit-used"], 64)
+		state.ResetSecs, _ = strconv.ParseFloat(captured["x-ratelimit-reset"], 64)
+		u.rateLimits[proxy] = state
+	}
+
+	entry := UpstreamTrace{
+		Time:    now,
+		Proxy:   display,
+		Path:    req.URL.Path,
+		Status:  resp.StatusCode,
+		Headers: captured,
+	}
+	if len(u.trace) < upstreamTraceSize {
+		u.trace = append(u.trace, entry)
+	} else {
+		u.trace[u.next] = entry
+	}
+	u.next = (u.next + 1) % upstreamTraceSize
+}
+
+// Stats returns the counters and the trace of recent responses
+func (u *UpstreamRecorder) Stats() UpstreamStats {
+	u.mutex.Lock()
+	defer u.mutex.Unlock()
+
+	stats := UpstreamStats{
+		Responses:           u.responses,
+		CloudflareResponses: u.cloudflare,
+		ByStatus:            make(map[string]int64, len(u.byStatus)),
+		ByContentType:       make(map[string]int64, len(u.byContentType)),
+		Recent:              make([]UpstreamTrace, 0, len(u.trace)),
+	}
+	for status, n := range u.byStatus {
+		stats.ByStatus[strconv.Itoa(status)] = n
+	}
+	for contentType, n := range u.byContentType {
+		stats.ByContentType[contentType] = n
+	}
+	for _, state := range u.rateLimits {
+		stats.RateLimits = append(stats.RateLimits, *state)
+	}
+	sort.Slice(stats.RateLimits, func(i, j int) bool {
+		return stats.RateLimits[i].Proxy < stats.RateLimits[j].Proxy
+	})
+	for i := 1; i <= len(u.trace); i++ {
+		stats.Recent = append(stats.Recent, u.trace[(u.next-i+len(u.trace))%len(u.trace)])
+	}
+	return stats
+}
+
+func stripFragment(proxy string) string {
+	before, _, _ := strings.Cut(proxy, "#")
+	return before
+}
@@ -0,0 +1,61 @@
+package active_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"reddit-ingestion/internal/active"
+	"reddit-ingestion/pkg/models"
+	"reddit-ingestion/testing/mocks"
+)
+
+func TestCancelStopsActiveScrape(t *testing.T) {
+	started := make(chan struct{})
+	inner := &mocks.MockScraperService{
+		ScrapePostFunc: func(ctx context.Context, postID string) (models.PostDetail, error) {
+			close(started)
+			<-ctx.Done()
+			return models.PostDetail{}, ctx.Err()
+		},
+	}
+	registry := active.NewRegistry()
+	svc := active.WrapService(inner, registry)
+
+	errs := make(chan error, 1)
+	go func() {
+		_, err := svc.ScrapePost(context.Background(), "abc123")
+		errs <- err
+	}()
+	<-started
+
+	ops := registry.List()
+	if len(ops) != 1 || ops[0].Operation != "post" || ops[0].Target != "abc123" {
+		t.Fatalf("Unexpected active operations %+v", ops)
+	}
+	if registry.Cancel("unknown") {
+		t.Error("Expected cancelling an unknown id to fail")
+	}
+	if !registry.Cancel(ops[0].ID) {
+		t.Fatal("Expected the scrape to be cancelled")
+	}
+	if err := <-errs; !errors.Is(err, active.ErrCancelled) {
+		t.Errorf("Expected ErrCancelled, got %v", err)
+	}
+	if ops := registry.List(); len(ops) != 0 {
+		t.Errorf("Expected no active operations after the scrape, got %+v", ops)
+	}
+}
+
+func TestTimeoutIsNotReportedAsCancelled(t *testing.T) {
+	inner := &mocks.MockScraperService{
+		ScrapePostFunc: func(ctx context.Context, postID string) (models.PostDetail, error) {
+			return models.PostDetail{}, context.Canceled
+		},
+	}
+	svc := active.WrapService(inner, active.NewRegistry())
+
+	if _, err := svc.ScrapePost(context.Background(), "abc123"); errors.Is(err, active.ErrCancelled) {
+		t.Errorf("Expected the scrape's own error, got %v", err)
+	}
+}
@@ -0,0 +1,76 @@
+package anonymize_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"reddit-ingestion/internal/anonymize"
+	"reddit-ingestion/pkg/models"
+	"reddit-ingestion/testing/mocks"
+)
+
+func postDetail(postID string) models.PostDetail {
+	return models.PostDetail{
+		Post: models.Post{ID: postID, Author: "Alice", Body: "thoughts, u/bob?"},
+		Comments: []models.Comment{{
+			ID:     "c1",
+			Author: "bob",
+			Body:   "sure",
+			Replies: []models.Comment{
+				{ID: "c2", Author: "alice", Body: "thanks"},
+				{ID: "c3", Author: "[deleted]", Body: "[deleted]"},
+			},
+		}},
+	}
+}
+
+func TestAnonymizedAuthorsAreConsistentWithinAResponse(t *testing.T) {
+	inner := &mocks.MockScraperService{
+		ScrapePostFunc: func(ctx context.Context, postID string) (models.PostDetail, error) {
+			return postDetail(postID), nil
+		},
+	}
+	svc := anonymize.WrapService(inner, false)
+	ctx := anonymize.WithAnonymizedAuthors(context.Background())
+
+	detail, err := svc.ScrapePost(ctx, "abc")
+	if err != nil {
+		t.Fatalf("ScrapePost returned error: %v", err)
+	}
+	alice, bob := detail.Post.Author, detail.Comments[0].Author
+	if !strings.HasPrefix(alice, "anon_") || alice == bob {
+		t.Fatalf("Expected distinct pseudonyms, got %q and %q", alice, bob)
+	}
+	if detail.Comments[0].Replies[0].Author != alice {
+		t.Errorf("Expected the same user to get the same pseudonym, got %q and %q", alice, detail.Comments[0].Replies[0].Author)
+	}
+	if detail.Post.Body != "thoughts, u/"+bob+"?" {
+		t.Errorf("Expected mentions to use the pseudonym, got %q", detail.Post.Body)
+	}
+	if detail.Comments[0].Replies[1].Author != "[deleted]" {
+		t.Error("Expected deleted authors to be kept")
+	}
+
+	again, _ := svc.ScrapePost(ctx, "abc")
+	if again.Post.Author == alice {
+		t.Error("Expected pseudonyms not to match across responses")
+	}
+}
+
+func TestAuthorsAreKeptUnlessAnonymized(t *testing.T) {
+	inner := &mocks.MockScraperService{
+		ScrapePostFunc: func(ctx context.Context, postID string) (models.PostDetail, error) {
+			return postDetail(postID), nil
+		},
+	}
+
+	detail, _ := anonymize.WrapService(inner, false).ScrapePost(context.Background(), "abc")
+	if detail.Post.Author != "Alice" {
+		t.Errorf("Expected the author to be kept, got %q", detail.Post.Author)
+	}
+	detail, _ = anonymize.WrapService(inner, true).ScrapePost(context.Background(), "abc")
+	if !strings.HasPrefix(detail.Post.Author, "anon_") {
+		t.Errorf("Expected every scrape to be anonymized, got %q", detail.Post.Author)
+	}
+}
@@ -0,0 +1,181 @@
+package api_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"reddit-ingestion/internal/active"
+	"reddit-ingestion/internal/archive"
+	"reddit-ingestion/internal/config"
+	handler "reddit-ingestion/internal/handler/http"
+	"reddit-ingestion/internal/policy"
+	"reddit-ingestion/internal/router"
+	"reddit-ingestion/internal/sink"
+	"reddit-ingestion/pkg/parser"
+)
+
+const adminKey = "s3cret"
+
+// listCounter is an empty archive counting the replays listing it
+type listCounter struct {
+	lists int
+}
+
+func (s *listCounter) Put(ctx context.Context, key string, body []byte) error { return nil }
+
+func (s *listCounter) Get(ctx context.Context, key string) ([]byte, error) { return nil, nil }
+
+func (s *listCounter) List(ctx context.Context, prefix string) ([]string, error) {
+	s.lists++
+	return nil, nil
+}
+
+func newReplayer(store archive.ObjectStore) *archive.Replayer {
+	return archive.NewReplayer(store, parser.NewRedditParser(), sink.NopSink{})
+}
+
+// serveAdmin sends method path to e, with key in the admin key header when
+// not empty, and returns the status code
+func serveAdmin(e *echo.Echo, method, path, key string) int {
+	req := httptest.NewRequest(method, path, nil)
+	if key != "" {
+		req.Header.Set(handler.AdminKeyHeader, key)
+	}
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	return rec.Code
+}
+
+func TestAdminEndpointsRequireTheKey(t *testing.T) {
+	blocklist := policy.NewBlocklist(nil, nil)
+	e := echo.New()
+	router.NewAdminRouter(e, router.AdminOptions{Key: adminKey, Blocklist: blocklist})
+
+	tests := []struct {
+		name     string
+		method   string
+		path     string
+		key      string
+		wantCode int
+	}{
+		{"list without key", http.MethodGet, "/admin/blocklist", "", http.StatusUnauthorized},
+		{"block without key", http.MethodPut, "/admin/blocklist/subreddits/internal", "", http.StatusUnauthorized},
+		{"block with wrong key", http.MethodPut, "/admin/blocklist/subreddits/internal", "guess", http.StatusUnauthorized},
+		{"unblock without key", http.MethodDelete, "/admin/blocklist/subreddits/internal", "", http.StatusUnauthorized},
+		{"unknown path without key", http.MethodGet, "/admin/nothing", "", http.StatusUnauthorized},
+		{"list with key", http.MethodGet, "/admin/blocklist", adminKey, http.StatusOK},
+	}
+	for _, tt := range tests {
+		if code := serveAdmin(e, tt.method, tt.path, tt.key); code != tt.wantCode {
+			t.Errorf("%s: got status %d, want %d", tt.name, code, tt.wantCode)
+		}
+	}
+	if blocklist.SubredditBlocked("internal") {
+		t.Fatal("Expected requests without the key to leave the blocklist alone")
+	}
+
+	if code := serveAdmin(e, http.MethodPut, "/admin/blocklist/subreddits/internal", adminKey); code != http.StatusOK || !blocklist.SubredditBlocked("internal") {
+		t.Fatalf("block with key: got status %d, blocked %v", code, blocklist.SubredditBlocked("internal"))
+	}
+	if code := serveAdmin(e, http.MethodDelete, "/admin/blocklist/subreddits/internal", ""); code != http.StatusUnauthorized || !blocklist.SubredditBlocked("internal") {
+		t.Errorf("unblock without key: got status %d, blocked %v", code, blocklist.SubredditBlocked("internal"))
+	}
+	if code := serveAdmin(e, http.MethodDelete, "/admin/blocklist/subreddits/internal", adminKey); code != http.StatusOK || blocklist.SubredditBlocked("internal") {
+		t.Errorf("unblock with key: got status %d, blocked %v", code, blocklist.SubredditBlocked("internal"))
+	}
+}
+
+func TestAdminCancelRequiresTheKey(t *testing.T) {
+	registry := active.NewRegistry()
+	ctx, done := registry.Start(context.Background(), "post", "abc123")
+	defer done()
+	e := echo.New()
+	router.NewAdminRouter(e, router.AdminOptions{Key: adminKey, Active: registry})
+	path := "/admin/active/" + registry.List()[0].ID
+
+	for _, key := range []string{"", "guess"} {
+		if code := serveAdmin(e, http.MethodDelete, path, key); code != http.StatusUnauthorized {
+			t.Errorf("cancel with key %q: got status %d, want 401", key, code)
+		}
+	}
+	if ctx.Err() != nil {
+		t.Fatal("Expected requests without the key to leave the scrape running")
+	}
+	if code := serveAdmin(e, http.MethodDelete, path, adminKey); code != http.StatusOK || ctx.Err() == nil {
+		t.Errorf("cancel with key: got status %d, context error %v", code, ctx.Err())
+	}
+}
+
+func TestAdminReplayRequiresTheKey(t *testing.T) {
+	store := &listCounter{}
+	e := echo.New()
+	router.NewAdminRouter(e, router.AdminOptions{Key: adminKey, Replayer: newReplayer(store)})
+
+	for _, key := range []string{"", "guess"} {
+		if code := serveAdmin(e, http.MethodPost, "/admin/replay?prefix=raw/", key); code != http.StatusUnauthorized {
+			t.Errorf("replay with key %q: got status %d, want 401", key, code)
+		}
+	}
+	if store.lists != 0 {
+		t.Fatal("Expected requests without the key not to replay the archive")
+	}
+	if code := serveAdmin(e, http.MethodPost, "/admin/replay?prefix=raw/", adminKey); code != http.StatusOK || store.lists != 1 {
+		t.Errorf("replay with key: got status %d after %d replays", code, store.lists)
+	}
+}
+
+func TestAdminConfigRequiresTheKey(t *testing.T) {
+	e := echo.New()
+	router.NewAdminRouter(e, router.AdminOptions{Key: adminKey, Config: config.NewLive(&config.Config{})})
+
+	for _, key := range []string{"", "guess"} {
+		if code := serveAdmin(e, http.MethodGet, "/admin/config", key); code != http.StatusUnauthorized {
+			t.Errorf("config with key %q: got status %d, want 401", key, code)
+		}
+	}
+	if code := serveAdmin(e, http.MethodGet, "/admin/config", adminKey); code != http.StatusOK {
+		t.Errorf("config with key: got status %d, want 200", code)
+	}
+}
+
+func TestAdminEndpointsAreLimitedWithoutAKey(t *testing.T) {
+	blocklist := policy.NewBlocklist(nil, nil)
+	registry := active.NewRegistry()
+	ctx, done := registry.Start(context.Background(), "post", "abc123")
+	defer done()
+	e := echo.New()
+	store := &listCounter{}
+	router.NewAdminRouter(e, router.AdminOptions{Blocklist: blocklist, Active: registry, Replayer: newReplayer(store), Config: config.NewLive(&config.Config{})})
+
+	tests := []struct {
+		name     string
+		method   string
+		path     string
+		wantCode int
+	}{
+		{"list", http.MethodGet, "/admin/blocklist", http.StatusOK},
+		{"block", http.MethodPut, "/admin/blocklist/subreddits/internal", http.StatusNotFound},
+		{"unblock", http.MethodDelete, "/admin/blocklist/subreddits/internal", http.StatusNotFound},
+		{"list active", http.MethodGet, "/admin/active", http.StatusOK},
+		{"cancel", http.MethodDelete, "/admin/active/" + registry.List()[0].ID, http.StatusNotFound},
+		{"replay", http.MethodPost, "/admin/replay?prefix=raw/", http.StatusNotFound},
+		{"config", http.MethodGet, "/admin/config", http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		if code := serveAdmin(e, tt.method, tt.path, ""); code != tt.wantCode {
+			t.Errorf("%s: got status %d, want %d", tt.name, code, tt.wantCode)
+		}
+	}
+	if blocklist.SubredditBlocked("internal") {
+		t.Error("Expected the blocklist to be read-only without an admin key")
+	}
+	if ctx.Err() != nil {
+		t.Error("Expected scrapes not to be cancellable without an admin key")
+	}
+	if store.lists != 0 {
+		t.Error("Expected the archive not to be replayable without an admin key")
+	}
+}
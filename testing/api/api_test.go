@@ -3,53 +3,38 @@ package api_test
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"io"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
 	
 	"github.com/labstack/echo/v4"
+	"golang.org/x/net/websocket"
 	handler "reddit-ingestion/internal/handler/http"
-	"reddit-ingestion/internal/models"
+	"reddit-ingestion/internal/resultcache"
+	"reddit-ingestion/pkg/models"
+	"reddit-ingestion/pkg/scraper"
+	"reddit-ingestion/testing/mocks"
 )
 
-type MockScraperService struct {
-	ScrapeSubredditFunc   func(ctx context.Context, subreddit string, sinceTimestamp int64, limit int) ([]models.Post, error)
-	ScrapeUserActivityFunc func(ctx context.Context, username string, sinceTimestamp int64, postLimit, commentLimit int) (models.UserActivity, error)
-	ScrapePostFunc        func(ctx context.Context, postID string) (models.PostDetail, error)
-	SearchFunc            func(ctx context.Context, searchParams map[string]string, sinceTimestamp int64, limit int) ([]models.Post, error)
-}
-
-func (m *MockScraperService) ScrapeSubreddit(ctx context.Context, subreddit string, sinceTimestamp int64, limit int) ([]models.Post, error) {
-	return m.ScrapeSubredditFunc(ctx, subreddit, sinceTimestamp, limit)
-}
-
-func (m *MockScraperService) ScrapeUserActivity(ctx context.Context, username string, sinceTimestamp int64, postLimit, commentLimit int) (models.UserActivity, error) {
-	return m.ScrapeUserActivityFunc(ctx, username, sinceTimestamp, postLimit, commentLimit)
-}
-
-func (m *MockScraperService) ScrapePost(ctx context.Context, postID string) (models.PostDetail, error) {
-	return m.ScrapePostFunc(ctx, postID)
-}
-
-func (m *MockScraperService) Search(ctx context.Context, searchParams map[string]string, sinceTimestamp int64, limit int) ([]models.Post, error) {
-	return m.SearchFunc(ctx, searchParams, sinceTimestamp, limit)
-}
-
 func TestSubredditHandler(t *testing.T) {
 	e := echo.New()
 	req := httptest.NewRequest(http.MethodGet, "/subreddit?subreddit=test", nil)
 	rec := httptest.NewRecorder()
 	c := e.NewContext(req, rec)
 	
-	mockService := &MockScraperService{
-		ScrapeSubredditFunc: func(ctx context.Context, subreddit string, sinceTimestamp int64, limit int) ([]models.Post, error) {
+	mockService := &mocks.MockScraperService{
+		ScrapeSubredditFunc: func(ctx context.Context, subreddit string, sinceTimestamp int64, limit int, opts scraper.ListingOptions) ([]models.Post, models.ListingMeta, error) {
 			return []models.Post{
 				{
 					ID:     "123",
 					Title:  "Test Post",
 					Author: "testuser",
 				},
-			}, nil
+			}, models.ListingMeta{}, nil
 		},
 	}
 	
@@ -72,3 +57,405 @@ func TestSubredditHandler(t *testing.T) {
 		t.Errorf("Expected 1 post in response, got %v", posts)
 	}
 }
+
+func TestSubredditHandlerPassesCursor(t *testing.T) {
+	e := echo.New()
+
+	var gotAfter string
+	mockService := &mocks.MockScraperService{
+		ScrapeSubredditFunc: func(ctx context.Context, subreddit string, sinceTimestamp int64, limit int, opts scraper.ListingOptions) ([]models.Post, models.ListingMeta, error) {
+			gotAfter = opts.After
+			return []models.Post{{ID: "def456"}}, models.ListingMeta{PagesFetched: 1, After: "t3_xyz789", Cursor: "t3_def456", ParseReport: &models.ParseReport{Skipped: 1}}, nil
+		},
+	}
+	h := handler.NewSubredditHandler(mockService)
+
+	req := httptest.NewRequest(http.MethodGet, "/subreddit?subreddit=test&limit=1&after=t3_abc123", nil)
+	rec := httptest.NewRecorder()
+	if err := h.GetSubredditPosts(e.NewContext(req, rec)); err != nil {
+		t.Fatalf("Handler returned error: %v", err)
+	}
+	if gotAfter != "t3_abc123" {
+		t.Errorf("Expected after to reach the scraper, got %q", gotAfter)
+	}
+
+	var response struct {
+		Meta map[string]interface{} `json:"meta"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
+	}
+	if response.Meta["cursor"] != "t3_def456" || response.Meta["pages_fetched"] != float64(1) {
+		t.Errorf("Expected paging meta in response, got %v", response.Meta)
+	}
+	if report, _ := response.Meta["parse_report"].(map[string]interface{}); report["skipped"] != float64(1) {
+		t.Errorf("Expected the parse report in the meta, got %v", response.Meta["parse_report"])
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/subreddit?subreddit=test&after=abc123", nil)
+	err := h.GetSubredditPosts(e.NewContext(req, httptest.NewRecorder()))
+	if httpErr, ok := err.(*echo.HTTPError); !ok || httpErr.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for an after that is not a fullname, got %v", err)
+	}
+}
+
+func TestHandlersApplyLimits(t *testing.T) {
+	e := echo.New()
+	limits := handler.Limits{DefaultPostLimit: 25, DefaultCommentLimit: 50, MaxLimit: 1000}
+
+	var gotLimit, gotCommentLimit int
+	var gotDefault bool
+	mockService := &mocks.MockScraperService{
+		ScrapeSubredditFunc: func(ctx context.Context, subreddit string, sinceTimestamp int64, limit int, opts scraper.ListingOptions) ([]models.Post, models.ListingMeta, error) {
+			gotLimit, gotDefault = limit, scraper.IsDefaultLimit(ctx)
+			return nil, models.ListingMeta{}, nil
+		},
+		ScrapeUserActivityFunc: func(ctx context.Context, username string, sinceTimestamp int64, postLimit, commentLimit int) (models.UserActivity, error) {
+			gotLimit, gotCommentLimit = postLimit, commentLimit
+			return models.UserActivity{}, nil
+		},
+	}
+	subreddits := handler.NewSubredditHandlerWithLimits(mockService, limits)
+	users := handler.NewUserHandlerWithLimits(mockService, limits)
+
+	tests := []struct {
+		name        string
+		target      string
+		handle      echo.HandlerFunc
+		wantLimit   int
+		wantComment int
+		wantDefault bool
+		wantStatus  int
+	}{
+		{"default", "/subreddit?subreddit=golang", subreddits.GetSubredditPosts, 25, 0, true, http.StatusOK},
+		{"zero is the default", "/subreddit?subreddit=golang&limit=0", subreddits.GetSubredditPosts, 25, 0, true, http.StatusOK},
+		{"explicit", "/subreddit?subreddit=golang&limit=10", subreddits.GetSubredditPosts, 10, 0, false, http.StatusOK},
+		{"everything", "/subreddit?subreddit=golang&limit=-1", subreddits.GetSubredditPosts, -1, 0, false, http.StatusOK},
+		{"above the ceiling", "/subreddit?subreddit=golang&limit=5000", subreddits.GetSubredditPosts, 0, 0, false, http.StatusBadRequest},
+		{"below -1", "/subreddit?subreddit=golang&limit=-2", subreddits.GetSubredditPosts, 0, 0, false, http.StatusBadRequest},
+		{"user defaults", "/user?username=spez", users.GetUserInfo, 25, 50, false, http.StatusOK},
+		{"comments follow posts", "/user?username=spez&post_limit=5", users.GetUserInfo, 5, 5, false, http.StatusOK},
+		{"comments alone", "/user?username=spez&comment_limit=7", users.GetUserInfo, 25, 7, false, http.StatusOK},
+		{"user above the ceiling", "/user?username=spez&comment_limit=1001", users.GetUserInfo, 0, 0, false, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		gotLimit, gotCommentLimit, gotDefault = 0, 0, false
+		req := httptest.NewRequest(http.MethodGet, tt.target, nil)
+		rec := httptest.NewRecorder()
+		err := tt.handle(e.NewContext(req, rec))
+		if tt.wantStatus != http.StatusOK {
+			if httpErr, ok := err.(*echo.HTTPError); !ok || httpErr.Code != tt.wantStatus {
+				t.Errorf("%s: expected %d, got %v", tt.name, tt.wantStatus, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("%s: handler returned error: %v", tt.name, err)
+		}
+		if gotLimit != tt.wantLimit || gotCommentLimit != tt.wantComment || gotDefault != tt.wantDefault {
+			t.Errorf("%s: got limits %d/%d (default %v), want %d/%d (default %v)",
+				tt.name, gotLimit, gotCommentLimit, gotDefault, tt.wantLimit, tt.wantComment, tt.wantDefault)
+		}
+	}
+}
+
+func TestHandlersReportEveryInvalidParameter(t *testing.T) {
+	e := echo.New()
+	var gotPostID string
+	mockService := &mocks.MockScraperService{
+		ScrapePostFunc: func(ctx context.Context, postID string) (models.PostDetail, error) {
+			gotPostID = postID
+			return models.PostDetail{}, nil
+		},
+	}
+	subreddits := handler.NewSubredditHandler(mockService)
+	users := handler.NewUserHandler(mockService)
+	searches := handler.NewSearchHandler(mockService)
+	posts := handler.NewPostHandler(mockService)
+
+	tests := []struct {
+		name       string
+		target     string
+		handle     echo.HandlerFunc
+		wantFields []string
+	}{
+		{"several at once", "/subreddit?subreddit=r/golang&limit=abc&nsfw=maybe", subreddits.GetSubredditPosts, []string{"subreddit", "limit", "nsfw"}},
+		{"missing subreddit", "/subreddit", subreddits.GetSubredditPosts, []string{"subreddit"}},
+		{"malformed username", "/user?username=a!b", users.GetUserInfo, []string{"username"}},
+		{"author on a user", "/user?username=spez&author=kn0thing", users.GetUserInfo, []string{"author"}},
+		{"unknown sort", "/search?q=go&sort=best", searches.Search, []string{"sort"}},
+		{"before with after", "/search?q=go&after=t3_abc&before=t3_def", searches.Search, []string{"before"}},
+		{"malformed post ID", "/post?post_id=not-an-id", posts.GetPostInfo, []string{"post_id"}},
+		{"unknown comment sort", "/post?post_id=abc123&sort=hot", posts.GetPostInfo, []string{"sort"}},
+		{"comment tree bounds", "/post?post_id=abc123&depth=0&limit=501", posts.GetPostInfo, []string{"depth", "limit"}},
+		{"post options", "/post?post_id=abc123&include_awards=yes&include_related=2&expand_workers=0&anonymize=maybe", posts.GetPostInfo, []string{"include_awards", "include_related", "expand_workers", "anonymize"}},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodGet, tt.target, nil)
+		err := tt.handle(e.NewContext(req, httptest.NewRecorder()))
+		httpErr, ok := err.(*echo.HTTPError)
+		if !ok || httpErr.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %v", tt.name, err)
+			continue
+		}
+		validation, ok := httpErr.Message.(models.ValidationError)
+		if !ok {
+			t.Errorf("%s: expected a ValidationError, got %T", tt.name, httpErr.Message)
+			continue
+		}
+		var fields []string
+		for _, fieldErr := range validation.Errors {
+			fields = append(fields, fieldErr.Field)
+		}
+		if strings.Join(fields, ",") != strings.Join(tt.wantFields, ",") {
+			t.Errorf("%s: got errors for %v, want %v (%s)", tt.name, fields, tt.wantFields, validation.Message)
+		}
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/post?post_id=t3_abc123", nil)
+	if err := posts.GetPostInfo(e.NewContext(req, httptest.NewRecorder())); err != nil {
+		t.Fatalf("prefixed post ID rejected: %v", err)
+	}
+	if gotPostID != "abc123" {
+		t.Errorf("expected the t3_ prefix to be stripped, got %q", gotPostID)
+	}
+}
+
+func TestHandlersPassFilters(t *testing.T) {
+	e := echo.New()
+
+	var gotFilter scraper.PostFilter
+	mockService := &mocks.MockScraperService{
+		ScrapeSubredditFunc: func(ctx context.Context, subreddit string, sinceTimestamp int64, limit int, opts scraper.ListingOptions) ([]models.Post, models.ListingMeta, error) {
+			gotFilter = opts.Filter
+			return nil, models.ListingMeta{FilteredOut: 3, FilterMatches: map[string]int{"flair": 1}}, nil
+		},
+		ScrapeUserActivityFunc: func(ctx context.Context, username string, sinceTimestamp int64, postLimit, commentLimit int) (models.UserActivity, error) {
+			return models.UserActivity{}, nil
+		},
+	}
+	subreddits := handler.NewSubredditHandler(mockService)
+	users := handler.NewUserHandler(mockService)
+
+	req := httptest.NewRequest(http.MethodGet, "/subreddit?subreddit=golang&flair=Bug&flair=Question&author=gopher&title_contains=generics&nsfw=exclude", nil)
+	rec := httptest.NewRecorder()
+	if err := subreddits.GetSubredditPosts(e.NewContext(req, rec)); err != nil {
+		t.Fatalf("Handler returned error: %v", err)
+	}
+	if len(gotFilter.Flair) != 2 || !gotFilter.Flair[1].Match("question") || !gotFilter.Author[0].Match("Gopher") || !gotFilter.TitleContains[0].Match("On Generics in Go") || gotFilter.NSFW != scraper.NSFWExclude {
+		t.Errorf("Expected the filters to reach the scraper, got %+v", gotFilter)
+	}
+	if !strings.Contains(rec.Body.String(), `"filter_matches":{"flair":1}`) {
+		t.Errorf("Expected filter counts in the meta, got %s", rec.Body.String())
+	}
+
+	for _, tc := range []struct {
+		query  string
+		handle func(echo.Context) error
+	}{
+		{"/subreddit?subreddit=golang&flair=(bug&regex=true", subreddits.GetSubredditPosts},
+		{"/subreddit?subreddit=golang&regex=maybe", subreddits.GetSubredditPosts},
+		{"/subreddit?subreddit=golang&nsfw=maybe", subreddits.GetSubredditPosts},
+		{"/user?username=gopher&author=gopher", users.GetUserInfo},
+		{"/user?username=gopher&flair=Bug", users.GetUserInfo},
+	} {
+		req := httptest.NewRequest(http.MethodGet, strings.ReplaceAll(tc.query, "(", "%28"), nil)
+		err := tc.handle(e.NewContext(req, httptest.NewRecorder()))
+		if httpErr, ok := err.(*echo.HTTPError); !ok || httpErr.Code != http.StatusBadRequest {
+			t.Errorf("Expected 400 for %s, got %v", tc.query, err)
+		}
+	}
+}
+
+func TestFrontpageHandlerValidatesFeedParams(t *testing.T) {
+	e := echo.New()
+
+	var gotFeed string
+	var gotParams map[string]string
+	mockService := &mocks.MockScraperService{
+		ScrapeFrontpageFunc: func(ctx context.Context, feed string, params map[string]string, limit int, opts scraper.ListingOptions) ([]models.Post, models.ListingMeta, error) {
+			gotFeed, gotParams = feed, params
+			return []models.Post{{ID: "a1", Subreddit: "golang", SubredditSubscribers: 250000}}, models.ListingMeta{PagesFetched: 1}, nil
+		},
+	}
+	h := handler.NewFrontpageHandler(mockService)
+
+	req := httptest.NewRequest(http.MethodGet, "/frontpage?feed=popular&sort=top&time=week&geo=gb", nil)
+	rec := httptest.NewRecorder()
+	if err := h.GetFrontpagePosts(e.NewContext(req, rec)); err != nil {
+		t.Fatalf("Handler returned error: %v", err)
+	}
+	if gotFeed != "popular" || gotParams["sort"] != "top" || gotParams["t"] != "week" || gotParams["geo_filter"] != "GB" {
+		t.Errorf("Unexpected feed %q and params %v", gotFeed, gotParams)
+	}
+	if !strings.Contains(rec.Body.String(), `"subreddit_subscribers":250000`) {
+		t.Errorf("Expected subreddit fields in the response, got %s", rec.Body.String())
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/frontpage", nil)
+	if err := h.GetFrontpagePosts(e.NewContext(req, httptest.NewRecorder())); err != nil {
+		t.Fatalf("Handler returned error: %v", err)
+	}
+	if gotFeed != "frontpage" || gotParams["sort"] != "hot" {
+		t.Errorf("Expected the hot front page by default, got %q %v", gotFeed, gotParams)
+	}
+
+	for _, query := range []string{"feed=golang", "sort=best", "sort=hot&time=day", "feed=all&geo=GB", "feed=popular&geo=G1"} {
+		req := httptest.NewRequest(http.MethodGet, "/frontpage?"+query, nil)
+		err := h.GetFrontpagePosts(e.NewContext(req, httptest.NewRecorder()))
+		if httpErr, ok := err.(*echo.HTTPError); !ok || httpErr.Code != http.StatusBadRequest {
+			t.Errorf("Expected 400 for %s, got %v", query, err)
+		}
+	}
+}
+
+func TestSubredditHandlerAnswersIfNoneMatch(t *testing.T) {
+	e := echo.New()
+
+	calls := 0
+	mockService := &mocks.MockScraperService{
+		ScrapeSubredditFunc: func(ctx context.Context, subreddit string, sinceTimestamp int64, limit int, opts scraper.ListingOptions) ([]models.Post, models.ListingMeta, error) {
+			calls++
+			return []models.Post{{ID: "abc123", Title: "Test Post"}}, models.ListingMeta{PagesFetched: calls}, nil
+		},
+	}
+	h := handler.NewSubredditHandler(mockService)
+
+	req := httptest.NewRequest(http.MethodGet, "/subreddit?subreddit=test", nil)
+	rec := httptest.NewRecorder()
+	if err := h.GetSubredditPosts(e.NewContext(req, rec)); err != nil {
+		t.Fatalf("Handler returned error: %v", err)
+	}
+	etag := rec.Header().Get("ETag")
+	if rec.Code != http.StatusOK || etag == "" {
+		t.Fatalf("Expected 200 with an ETag, got %d and %q", rec.Code, etag)
+	}
+
+	// The meta differs between the two responses; only the posts decide the tag
+	req = httptest.NewRequest(http.MethodGet, "/subreddit?subreddit=test", nil)
+	req.Header.Set("If-None-Match", `"other", W/`+etag)
+	rec = httptest.NewRecorder()
+	if err := h.GetSubredditPosts(e.NewContext(req, rec)); err != nil {
+		t.Fatalf("Handler returned error: %v", err)
+	}
+	if rec.Code != http.StatusNotModified || rec.Body.Len() != 0 {
+		t.Errorf("Expected an empty 304, got %d with %q", rec.Code, rec.Body.String())
+	}
+	if rec.Header().Get("ETag") != etag {
+		t.Errorf("Expected the 304 to repeat the ETag, got %q", rec.Header().Get("ETag"))
+	}
+}
+
+// getPost sends GET /post?post_id=abc123 to h with ifNoneMatch, when not
+// empty, in If-None-Match
+func getPost(t *testing.T, h *handler.PostHandler, ifNoneMatch string) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodGet, "/post?post_id=abc123", nil)
+	if ifNoneMatch != "" {
+		req.Header.Set("If-None-Match", ifNoneMatch)
+	}
+	rec := httptest.NewRecorder()
+	if err := h.GetPostInfo(echo.New().NewContext(req, rec)); err != nil {
+		t.Fatalf("Handler returned error: %v", err)
+	}
+	return rec
+}
+
+func TestPostETagIgnoresResultCacheHits(t *testing.T) {
+	mockService := &mocks.MockScraperService{
+		ScrapePostFunc: func(ctx context.Context, postID string) (models.PostDetail, error) {
+			return models.PostDetail{
+				Post:     models.Post{ID: postID, Title: "Test Post"},
+				Comments: []models.Comment{{ID: "c1", Body: "First"}},
+			}, nil
+		},
+	}
+	h := handler.NewPostHandler(resultcache.WrapService(mockService, resultcache.New(time.Minute, 10)))
+
+	rec := getPost(t, h, "")
+	etag := rec.Header().Get("ETag")
+	if rec.Code != http.StatusOK || etag == "" || strings.Contains(rec.Body.String(), `"cached"`) {
+		t.Fatalf("Expected a fresh 200 with an ETag, got %d, %q and %s", rec.Code, etag, rec.Body.String())
+	}
+
+	rec = getPost(t, h, "")
+	if !strings.Contains(rec.Body.String(), `"cached":true`) || rec.Header().Get("ETag") != etag {
+		t.Errorf("Expected the cached response to keep the ETag %s, got %s with %s", etag, rec.Header().Get("ETag"), rec.Body.String())
+	}
+	if rec = getPost(t, h, etag); rec.Code != http.StatusNotModified {
+		t.Errorf("Expected 304 for a cached hit with the fresh ETag, got %d", rec.Code)
+	}
+}
+
+func TestPostETagIgnoresHowTheScrapeWent(t *testing.T) {
+	calls := 0
+	mockService := &mocks.MockScraperService{
+		ScrapePostFunc: func(ctx context.Context, postID string) (models.PostDetail, error) {
+			calls++
+			return models.PostDetail{
+				Post:         models.Post{ID: postID, Title: "Test Post"},
+				Comments:     []models.Comment{{ID: "c1", Body: "First"}},
+				Retries:      int64(calls),
+				Quality:      &models.Quality{Score: 1 / float64(calls)},
+				ParseReport:  &models.ParseReport{Skipped: calls},
+				Completeness: &models.CommentCompleteness{Fetched: 1, Reported: calls},
+			}, nil
+		},
+	}
+	h := handler.NewPostHandler(mockService)
+
+	etag := getPost(t, h, "").Header().Get("ETag")
+	if etag == "" {
+		t.Fatal("Expected the post to carry an ETag")
+	}
+	rec := getPost(t, h, etag)
+	if rec.Code != http.StatusNotModified || rec.Header().Get("ETag") != etag {
+		t.Errorf("Expected 304 with ETag %s after a retried fetch, got %d with %s", etag, rec.Code, rec.Header().Get("ETag"))
+	}
+}
+
+func TestStreamPostSendsResultOverWebSocket(t *testing.T) {
+	e := echo.New()
+	mockService := &mocks.MockScraperService{
+		ScrapePostFunc: func(ctx context.Context, postID string) (models.PostDetail, error) {
+			if postID == "missing" {
+				return models.PostDetail{}, errors.New("post not found")
+			}
+			return models.PostDetail{Post: models.Post{ID: postID, Title: "Test Post"}}, nil
+		},
+	}
+	e.GET("/ws/post", handler.NewPostHandler(mockService).StreamPostInfo)
+	server := httptest.NewServer(e)
+	defer server.Close()
+
+	receive := func(postID string) []handler.PostStreamEvent {
+		wsURL := "ws" + strings.TrimPrefix(server.URL, "http") + "/ws/post?post_id=" + postID
+		ws, err := websocket.Dial(wsURL, "", server.URL)
+		if err != nil {
+			t.Fatalf("Failed to connect: %v", err)
+		}
+		defer ws.Close()
+
+		var events []handler.PostStreamEvent
+		for {
+			var event handler.PostStreamEvent
+			if err := websocket.JSON.Receive(ws, &event); err != nil {
+				if err != io.EOF {
+					t.Fatalf("Failed to read event: %v", err)
+				}
+				return events
+			}
+			events = append(events, event)
+		}
+	}
+
+	events := receive("abc123")
+	if len(events) != 1 || events[0].Type != "result" || events[0].Post == nil || events[0].Post.Post.ID != "abc123" {
+		t.Errorf("Expected one result event with the post, got %+v", events)
+	}
+
+	events = receive("missing")
+	if len(events) != 1 || events[0].Type != "error" || events[0].Status != http.StatusBadGateway {
+		t.Errorf("Expected one error event with status 502, got %+v", events)
+	}
+}
@@ -0,0 +1,170 @@
+package api_test
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/andybalholm/brotli"
+	"github.com/labstack/echo/v4"
+	handler "reddit-ingestion/internal/handler/http"
+	"reddit-ingestion/pkg/models"
+	"reddit-ingestion/pkg/scraper"
+	"reddit-ingestion/testing/mocks"
+)
+
+// newCompressedServer serves /subreddit with n posts behind the compression
+// middleware
+func newCompressedServer(t *testing.T, opts handler.CompressOptions, n int) *echo.Echo {
+	t.Helper()
+	if err := opts.Validate(); err != nil {
+		t.Fatalf("Validate: %v", err)
+	}
+	posts := make([]models.Post, n)
+	for i := range posts {
+		posts[i] = models.Post{ID: fmt.Sprintf("p%d", i), Title: strings.Repeat("a long title ", 5)}
+	}
+	svc := &mocks.MockScraperService{
+		ScrapeSubredditFunc: func(ctx context.Context, subreddit string, sinceTimestamp int64, limit int, opts scraper.ListingOptions) ([]models.Post, models.ListingMeta, error) {
+			return posts, models.ListingMeta{PagesFetched: 1}, nil
+		},
+	}
+	e := echo.New()
+	e.Use(handler.CompressMiddleware(opts))
+	e.GET("/subreddit", handler.NewSubredditHandler(svc).GetSubredditPosts)
+	return e
+}
+
+func get(e *echo.Echo, target, acceptEncoding string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, target, nil)
+	if acceptEncoding != "" {
+		req.Header.Set("Accept-Encoding", acceptEncoding)
+	}
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestCompressMiddlewareNegotiatesEncoding(t *testing.T) {
+	gzipOnly := newCompressedServer(t, handler.CompressOptions{Compression: handler.CompressionGzip, MinSize: 1024}, 50)
+	withBrotli := newCompressedServer(t, handler.CompressOptions{Compression: handler.CompressionBrotli, MinSize: 1024}, 50)
+	off := newCompressedServer(t, handler.CompressOptions{Compression: handler.CompressionNone}, 50)
+
+	tests := []struct {
+		name           string
+		e              *echo.Echo
+		acceptEncoding string
+		want           string
+	}{
+		{"gzip", gzipOnly, "gzip, deflate", "gzip"},
+		{"brotli not enabled", gzipOnly, "br, gzip", "gzip"},
+		{"brotli preferred", withBrotli, "gzip, br", "br"},
+		{"brotli refused", withBrotli, "br;q=0, gzip", "gzip"},
+		{"nothing accepted", withBrotli, "", ""},
+		{"compression off", off, "gzip, br", ""},
+	}
+	for _, tt := range tests {
+		rec := get(tt.e, "/subreddit?subreddit=golang", tt.acceptEncoding)
+		if got := rec.Header().Get("Content-Encoding"); got != tt.want {
+			t.Errorf("%s: Content-Encoding = %q, want %q", tt.name, got, tt.want)
+			continue
+		}
+		var body io.Reader = rec.Body
+		switch tt.want {
+		case "gzip":
+			zr, err := gzip.NewReader(rec.Body)
+			if err != nil {
+				t.Fatalf("%s: %v", tt.name, err)
+			}
+			body = zr
+		case "br":
+			body = brotli.NewReader(rec.Body)
+		}
+		var response struct {
+			Posts []models.Post `json:"posts"`
+		}
+		if err := json.NewDecoder(body).Decode(&response); err != nil || len(response.Posts) != 50 {
+			t.Errorf("%s: decoded %d posts, %v", tt.name, len(response.Posts), err)
+		}
+	}
+}
+
+func TestCompressMiddlewareSkipsSmallBodies(t *testing.T) {
+	e := newCompressedServer(t, handler.CompressOptions{Compression: handler.CompressionGzip, MinSize: 1 << 20}, 2)
+	rec := get(e, "/subreddit?subreddit=golang", "gzip")
+	if got := rec.Header().Get("Content-Encoding"); got != "" {
+		t.Errorf("Content-Encoding = %q, want a small body sent as is", got)
+	}
+	if !strings.Contains(rec.Body.String(), `"p1"`) {
+		t.Errorf("body = %.200s, want the posts", rec.Body.String())
+	}
+
+	// Errors are written by echo after the middleware and are not compressed
+	rec = get(e, "/subreddit", "gzip")
+	if rec.Code != http.StatusBadRequest || rec.Header().Get("Content-Encoding") != "" {
+		t.Errorf("missing subreddit: %d %q, want an uncompressed 400", rec.Code, rec.Header().Get("Content-Encoding"))
+	}
+}
+
+func TestListingNDJSONIsCompressedAsItStreams(t *testing.T) {
+	e := newCompressedServer(t, handler.CompressOptions{Compression: handler.CompressionGzip, MinSize: 1 << 20}, 250)
+	rec := get(e, "/subreddit?subreddit=golang&format=ndjson", "gzip")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", rec.Code, rec.Body.String())
+	}
+	// The flushes start compressing long before the minimum size
+	if got := rec.Header().Get("Content-Encoding"); got != "gzip" {
+		t.Fatalf("Content-Encoding = %q, want gzip", got)
+	}
+	if got := rec.Header().Get("Content-Type"); got != handler.MIMEApplicationNDJSON {
+		t.Errorf("Content-Type = %q, want %s", got, handler.MIMEApplicationNDJSON)
+	}
+
+	zr, err := gzip.NewReader(rec.Body)
+	if err != nil {
+		t.Fatalf("gzip: %v", err)
+	}
+	scanner := bufio.NewScanner(zr)
+	var lines []string
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+	if len(lines) != 251 {
+		t.Fatalf("got %d lines, want 250 posts and the meta", len(lines))
+	}
+	var post models.Post
+	if err := json.Unmarshal([]byte(lines[0]), &post); err != nil || post.ID != "p0" {
+		t.Errorf("first line = %s, want post p0", lines[0])
+	}
+	var last struct {
+		Meta map[string]interface{} `json:"meta"`
+	}
+	if err := json.Unmarshal([]byte(lines[250]), &last); err != nil || last.Meta["actual_count"] != float64(250) {
+		t.Errorf("last line = %s, want the meta", lines[250])
+	}
+
+	rec = get(e, "/subreddit?subreddit=golang&format=xml", "")
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("format=xml: status %d, want 400", rec.Code)
+	}
+}
+
+func TestCompressOptionsValidate(t *testing.T) {
+	for _, opts := range []handler.CompressOptions{
+		{Compression: "zstd"},
+		{Compression: handler.CompressionGzip, GzipLevel: 10},
+		{Compression: handler.CompressionBrotli, BrotliLevel: 12},
+		{Compression: handler.CompressionGzip, MinSize: -1},
+	} {
+		if err := opts.Validate(); err == nil {
+			t.Errorf("Validate(%+v) = nil, want an error", opts)
+		}
+	}
+}
@@ -0,0 +1,44 @@
+package api_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	handler "reddit-ingestion/internal/handler/http"
+	"reddit-ingestion/internal/router"
+	"reddit-ingestion/pkg/client"
+)
+
+type stubHostHealth client.HostHealth
+
+func (s stubHostHealth) HostHealth() client.HostHealth {
+	return client.HostHealth(s)
+}
+
+func TestHealthReportsHostDegradation(t *testing.T) {
+	e := echo.New()
+	router.NewHealthRouter(e, stubHostHealth{
+		Status:    client.HostStatusDegraded,
+		Primary:   "https://old.reddit.com",
+		Active:    "https://www.reddit.com",
+		Failovers: 1,
+	})
+
+	for _, path := range []string{"/healthz", "/health"} {
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("GET %s: status %d, want 200 while degraded", path, rec.Code)
+		}
+		var got handler.HealthResponse
+		if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+			t.Fatalf("GET %s: %v", path, err)
+		}
+		if got.Status != client.HostStatusDegraded || got.Hosts == nil || got.Hosts.Active != "https://www.reddit.com" {
+			t.Errorf("GET %s = %+v, want degraded with the fallback active", path, got)
+		}
+	}
+}
@@ -0,0 +1,39 @@
+package api_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	handler "reddit-ingestion/internal/handler/http"
+	"reddit-ingestion/pkg/utils"
+)
+
+func TestProxyPoolMiddleware(t *testing.T) {
+	e := echo.New()
+	var seen string
+	e.GET("/subreddit", func(c echo.Context) error {
+		seen = utils.ProxyPoolFromContext(c.Request().Context())
+		return c.NoContent(http.StatusOK)
+	}, handler.ProxyPoolMiddleware(func(pool string) bool { return pool == "residential" }))
+
+	tests := []struct {
+		query    string
+		wantCode int
+		wantPool string
+	}{
+		{"", http.StatusOK, ""},
+		{"?pool=residential", http.StatusOK, "residential"},
+		{"?pool=mobile", http.StatusBadRequest, ""},
+	}
+
+	for _, tt := range tests {
+		seen = ""
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/subreddit"+tt.query, nil))
+		if rec.Code != tt.wantCode || seen != tt.wantPool {
+			t.Errorf("%q: got status %d and pool %q, want %d and %q", tt.query, rec.Code, seen, tt.wantCode, tt.wantPool)
+		}
+	}
+}
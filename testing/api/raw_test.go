@@ -0,0 +1,75 @@
+package api_test
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"reddit-ingestion/internal/config"
+	"reddit-ingestion/internal/fakereddit"
+	handler "reddit-ingestion/internal/handler/http"
+	"reddit-ingestion/internal/router"
+	"reddit-ingestion/pkg/client"
+)
+
+func TestRawPassthrough(t *testing.T) {
+	fake := fakereddit.NewServer(fakereddit.Options{})
+	defer fake.Close()
+	redditClient, err := client.NewRedditClient(client.Options{
+		UserAgent:  "raw-test/1.0",
+		MaxRetries: 1,
+		BaseURL:    fake.URL,
+		Direct:     true,
+	})
+	if err != nil {
+		t.Fatalf("NewRedditClient: %v", err)
+	}
+	defer redditClient.Close()
+
+	e := echo.New()
+	router.NewRawRouter(e, redditClient, config.DefaultRawPathAllowlist, handler.AdminKeyMiddleware("s3cret"))
+
+	resp, err := http.Get(fake.URL + "/r/golang/new.json?limit=2")
+	if err != nil {
+		t.Fatalf("fetching from the fake: %v", err)
+	}
+	want, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+
+	tests := []struct {
+		name     string
+		path     string
+		header   string
+		value    string
+		wantCode int
+	}{
+		{"no key", "/r/golang/new.json?limit=2", "", "", http.StatusUnauthorized},
+		{"wrong key", "/r/golang/new.json?limit=2", handler.AdminKeyHeader, "guess", http.StatusUnauthorized},
+		{"key header", "/r/golang/new.json?limit=2", handler.AdminKeyHeader, "s3cret", http.StatusOK},
+		{"bearer token", "/r/golang/new.json?limit=2", "Authorization", "Bearer s3cret", http.StatusOK},
+		{"upstream 404 passed through", "/user/spez/saved.json", handler.AdminKeyHeader, "s3cret", http.StatusNotFound},
+		{"not allowlisted", "/api/v1/me.json", handler.AdminKeyHeader, "s3cret", http.StatusForbidden},
+		{"dot segments", "/r/golang/../../api/v1/me.json", handler.AdminKeyHeader, "s3cret", http.StatusBadRequest},
+		{"other host", "//evil.example.com/r/golang/new.json", handler.AdminKeyHeader, "s3cret", http.StatusBadRequest},
+		{"missing path", "", handler.AdminKeyHeader, "s3cret", http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodGet, "/raw?path="+url.QueryEscape(tt.path), nil)
+		if tt.header != "" {
+			req.Header.Set(tt.header, tt.value)
+		}
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		if rec.Code != tt.wantCode {
+			t.Errorf("%s: got status %d, want %d: %s", tt.name, rec.Code, tt.wantCode, rec.Body.String())
+			continue
+		}
+		if rec.Code == http.StatusOK && rec.Body.String() != string(want) {
+			t.Errorf("%s: body differs from Reddit's:\n got %.200s\nwant %.200s", tt.name, rec.Body.String(), want)
+		}
+	}
+}
@@ -0,0 +1,54 @@
+package api_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	handler "reddit-ingestion/internal/handler/http"
+	"reddit-ingestion/internal/router"
+	"reddit-ingestion/internal/sink"
+	"reddit-ingestion/internal/stats"
+	"reddit-ingestion/pkg/utils"
+)
+
+type stubSinkStats sink.Stats
+
+func (s stubSinkStats) Stats() sink.Stats {
+	return sink.Stats(s)
+}
+
+type stubBandwidth struct{}
+
+func (stubBandwidth) BandwidthUsage() []utils.ProxyBandwidth {
+	return nil
+}
+
+func TestStatsReportSinkDeliveries(t *testing.T) {
+	deliveries := stubSinkStats{Delivered: 12, Failed: 3}
+	e := echo.New()
+	router.NewStatsRouter(e, stats.NewRegistry(), nil, deliveries)
+	router.NewAdminRouter(e, router.AdminOptions{Bandwidth: stubBandwidth{}, Sink: deliveries})
+
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/stats", nil))
+	var statsResponse handler.StatsResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &statsResponse); err != nil {
+		t.Fatalf("GET /stats: %v", err)
+	}
+	if statsResponse.Sink == nil || *statsResponse.Sink != sink.Stats(deliveries) {
+		t.Errorf("GET /stats sink = %+v, want %+v", statsResponse.Sink, deliveries)
+	}
+
+	rec = httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/admin/status", nil))
+	var status handler.StatusResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &status); err != nil {
+		t.Fatalf("GET /admin/status: %v", err)
+	}
+	if status.Sink == nil || *status.Sink != sink.Stats(deliveries) {
+		t.Errorf("GET /admin/status sink = %+v, want %+v", status.Sink, deliveries)
+	}
+}
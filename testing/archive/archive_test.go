@@ -0,0 +1,202 @@
+package archive_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"reddit-ingestion/internal/archive"
+	"reddit-ingestion/internal/compression"
+	"reddit-ingestion/internal/sink"
+	"reddit-ingestion/pkg/models"
+	"reddit-ingestion/pkg/parser"
+	"reddit-ingestion/testing/mocks"
+)
+
+func TestArchiveKeyPartitions(t *testing.T) {
+	fetchedAt := time.Date(2025, 4, 15, 12, 0, 0, 0, time.UTC)
+
+	cases := map[string]string{
+		"https://old.reddit.com/r/GoLang/new.json?raw_json=1":             "raw/2025/04/15/r/golang/listing/",
+		"https://old.reddit.com/user/spez/about.json":                     "raw/2025/04/15/user/spez/user_about/",
+		"https://old.reddit.com/user/spez/submitted/new.json?raw_json=1":  "raw/2025/04/15/user/spez/user_posts/",
+		"https://old.reddit.com/user/spez/comments/.json?raw_json=1":      "raw/2025/04/15/user/spez/user_comments/",
+		"https://old.reddit.com/comments/abc123.json?raw_json=1&sort=new": "raw/2025/04/15/post/abc123/post/",
+		"https://old.reddit.com/search.json?raw_json=1&q=golang":          "raw/2025/04/15/search/search/",
+	}
+
+	for rawURL, wantPrefix := range cases {
+		key := archive.Key("raw", rawURL, fetchedAt)
+		if len(key) < len(wantPrefix) || key[:len(wantPrefix)] != wantPrefix {
+			t.Errorf("Key(%s) = %s, expected prefix %s", rawURL, key, wantPrefix)
+		}
+	}
+}
+
+func TestWrapClientArchivesGzippedBody(t *testing.T) {
+	dir := t.TempDir()
+	store, err := archive.NewFileStore(dir)
+	if err != nil {
+		t.Fatalf("Failed to create file store: %v", err)
+	}
+
+	body := json.RawMessage(`{"data":{"children":[]}}`)
+	mockClient := &mocks.MockRedditClient{
+		FetchJSONFunc: func(ctx context.Context, url string) (json.RawMessage, error) {
+			return body, nil
+		},
+	}
+
+	c := archive.WrapClient(mockClient, store, "raw")
+	if _, err := c.FetchJSON(context.Background(), "https://old.reddit.com/r/test/new.json"); err != nil {
+		t.Fatalf("FetchJSON returned error: %v", err)
+	}
+
+	matches, _ := filepath.Glob(filepath.Join(dir, "raw", "*", "*", "*", "r", "test", "listing", "*.json.gz"))
+	if len(matches) != 1 {
+		t.Fatalf("Expected 1 archived object, found %d", len(matches))
+	}
+
+	compressed, err := os.ReadFile(matches[0])
+	if err != nil {
+		t.Fatalf("Failed to read archived object: %v", err)
+	}
+	gz, err := gzip.NewReader(bytes.NewReader(compressed))
+	if err != nil {
+		t.Fatalf("Archived object is not gzipped: %v", err)
+	}
+	archived, _ := io.ReadAll(gz)
+
+	if !bytes.Equal(archived, body) {
+		t.Errorf("Expected archived body %s, got %s", body, archived)
+	}
+}
+
+type postRecorder struct {
+	sink.NopSink
+	posts []models.Post
+}
+
+func (r *postRecorder) WritePosts(ctx context.Context, posts []models.Post) error {
+	r.posts = append(r.posts, posts...)
+	return nil
+}
+
+func TestReplayReparsesArchivedListing(t *testing.T) {
+	store, err := archive.NewFileStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("Failed to create file store: %v", err)
+	}
+
+	mockClient := &mocks.MockRedditClient{
+		FetchJSONFunc: func(ctx context.Context, url string) (json.RawMessage, error) {
+			return json.RawMessage(`{
+				"data": {
+					"children": [
+						{"kind": "t3", "data": {"id": "abc123", "title": "Archived post", "created_utc": 1620000000}},
+						{"kind": "t3", "data": {"id": "def456", "title": "Another post", "created_utc": 1620000100}}
+					],
+					"after": ""
+				}
+			}`), nil
+		},
+	}
+
+	c := archive.WrapClient(mockClient, store, "raw")
+	if _, err := c.FetchJSON(context.Background(), "https://old.reddit.com/r/test/new.json"); err != nil {
+		t.Fatalf("FetchJSON returned error: %v", err)
+	}
+
+	rec := &postRecorder{}
+	replayer := archive.NewReplayer(store, parser.NewRedditParser(), rec)
+
+	result, err := replayer.Replay(context.Background(), "raw/")
+	if err != nil {
+		t.Fatalf("Replay returned error: %v", err)
+	}
+
+	if result.Objects != 1 || result.Posts != 2 || result.Skipped != 0 {
+		t.Errorf("Unexpected replay result: %+v", result)
+	}
+
+	if len(rec.posts) != 2 || rec.posts[0].ID != "abc123" {
+		t.Errorf("Expected replayed posts to reach the sink, got %v", rec.posts)
+	}
+}
+
+func TestReplayReadsZstdAndGzipArchives(t *testing.T) {
+	dir := t.TempDir()
+	store, err := archive.NewFileStore(dir)
+	if err != nil {
+		t.Fatalf("Failed to create file store: %v", err)
+	}
+
+	mockClient := &mocks.MockRedditClient{
+		FetchJSONFunc: func(ctx context.Context, url string) (json.RawMessage, error) {
+			return json.RawMessage(`{"data":{"children":[{"kind":"t3","data":{"id":"abc123","title":"Archived post"}}],"after":""}}`), nil
+		},
+	}
+
+	// Objects written before and after switching ARCHIVE_COMPRESSION to zstd
+	gzipped := archive.WrapClient(mockClient, store, "raw")
+	zstded := archive.WrapClientWithOptions(mockClient, store, "raw", archive.Options{Compression: compression.Zstd, Level: 19})
+	if _, err := gzipped.FetchJSON(context.Background(), "https://old.reddit.com/r/test/new.json"); err != nil {
+		t.Fatalf("FetchJSON returned error: %v", err)
+	}
+	if _, err := zstded.FetchJSON(context.Background(), "https://old.reddit.com/r/test/new.json"); err != nil {
+		t.Fatalf("FetchJSON returned error: %v", err)
+	}
+
+	matches, _ := filepath.Glob(filepath.Join(dir, "raw", "*", "*", "*", "r", "test", "listing", "*.json.zst"))
+	if len(matches) != 1 {
+		t.Fatalf("Expected 1 zstd object, found %d", len(matches))
+	}
+
+	rec := &postRecorder{}
+	result, err := archive.NewReplayer(store, parser.NewRedditParser(), rec).Replay(context.Background(), "raw/")
+	if err != nil {
+		t.Fatalf("Replay returned error: %v", err)
+	}
+	if result.Objects != 2 || result.Posts != 2 || result.Skipped != 0 {
+		t.Errorf("Expected both objects to replay, got %+v", result)
+	}
+}
+
+func TestS3StoreLabelsUploadsWithTheirCodec(t *testing.T) {
+	var contentType string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		contentType = r.Header.Get("Content-Type")
+	}))
+	defer server.Close()
+
+	for codec, want := range map[compression.Codec]string{
+		compression.Gzip: "application/gzip",
+		compression.Zstd: "application/zstd",
+		compression.None: "application/json",
+	} {
+		store, err := archive.NewS3Store(archive.S3Config{
+			Endpoint:    server.URL,
+			Bucket:      "raw",
+			AccessKey:   "key",
+			SecretKey:   "secret",
+			Compression: codec,
+		})
+		if err != nil {
+			t.Fatalf("NewS3Store returned error: %v", err)
+		}
+		if err := store.Put(context.Background(), "raw/page.json"+codec.Extension(), []byte("{}")); err != nil {
+			t.Fatalf("Put returned error: %v", err)
+		}
+		if contentType != want {
+			t.Errorf("Expected Content-Type %s for %s, got %s", want, codec, contentType)
+		}
+	}
+}
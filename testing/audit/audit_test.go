@@ -0,0 +1,88 @@
+package audit_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"reddit-ingestion/internal/audit"
+	"reddit-ingestion/internal/router"
+	"reddit-ingestion/pkg/models"
+	"reddit-ingestion/pkg/scraper"
+	"reddit-ingestion/testing/mocks"
+)
+
+func newServer(buf *bytes.Buffer, requirePurpose bool, seen *string) *echo.Echo {
+	svc := &mocks.MockScraperService{
+		ScrapeSubredditFunc: func(ctx context.Context, subreddit string, sinceTimestamp int64, limit int, opts scraper.ListingOptions) ([]models.Post, models.ListingMeta, error) {
+			*seen = audit.PurposeFromContext(ctx)
+			return []models.Post{}, models.ListingMeta{}, nil
+		},
+	}
+	e := echo.New()
+	router.NewRouter(e, svc, audit.Middleware(audit.NewLogger(buf), requirePurpose))
+	return e
+}
+
+func TestPurposeIsAuditedAndPropagated(t *testing.T) {
+	var buf bytes.Buffer
+	var seen string
+	e := newServer(&buf, true, &seen)
+
+	req := httptest.NewRequest(http.MethodGet, "/subreddit?subreddit=golang&purpose=study-42", nil)
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", rec.Code)
+	}
+	if seen != "study-42" {
+		t.Errorf("Expected purpose in scraper context, got %q", seen)
+	}
+
+	var entry audit.Entry
+	if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+		t.Fatalf("Audit log is not a JSON line: %v", err)
+	}
+	if entry.Purpose != "study-42" || entry.Path != "/subreddit" || entry.Params["subreddit"] != "golang" || entry.Status != http.StatusOK {
+		t.Errorf("Unexpected audit entry: %+v", entry)
+	}
+}
+
+func TestMissingPurposeRejectedWhenRequired(t *testing.T) {
+	var buf bytes.Buffer
+	var seen string
+	e := newServer(&buf, true, &seen)
+
+	req := httptest.NewRequest(http.MethodGet, "/subreddit?subreddit=golang", nil)
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400, got %d", rec.Code)
+	}
+
+	var entry audit.Entry
+	if err := json.Unmarshal(buf.Bytes(), &entry); err != nil || entry.Status != http.StatusBadRequest {
+		t.Errorf("Expected rejected request to be audited with 400, got %+v (%v)", entry, err)
+	}
+}
+
+func TestPurposeHeaderAccepted(t *testing.T) {
+	var buf bytes.Buffer
+	var seen string
+	e := newServer(&buf, true, &seen)
+
+	req := httptest.NewRequest(http.MethodGet, "/subreddit?subreddit=golang", nil)
+	req.Header.Set(audit.PurposeHeader, "legal-review")
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK || seen != "legal-review" {
+		t.Errorf("Expected header purpose to be accepted, got status %d purpose %q", rec.Code, seen)
+	}
+}
@@ -0,0 +1,99 @@
+package dedup_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"reddit-ingestion/internal/dedup"
+	"reddit-ingestion/pkg/models"
+	"reddit-ingestion/pkg/scraper"
+	"reddit-ingestion/testing/mocks"
+)
+
+func TestContentHashIgnoresCaseAndPunctuation(t *testing.T) {
+	a := dedup.ContentHash("Free crypto, click NOW!!", "Limited  offer.")
+	b := dedup.ContentHash("free crypto click now", "limited offer")
+	if a == "" || a != b {
+		t.Errorf("expected equal hashes, got %q and %q", a, b)
+	}
+	if c := dedup.ContentHash("free crypto click now", "another offer"); c == a {
+		t.Error("expected a different body to change the hash")
+	}
+	if d := dedup.ContentHash("free crypto click now", "[removed]"); d != dedup.ContentHash("free crypto click now", "") {
+		t.Error("expected a removed body to hash like an empty one")
+	}
+	if e := dedup.ContentHash("!!!", ""); e != "" {
+		t.Errorf("expected no hash for text without words, got %q", e)
+	}
+}
+
+func TestCanonicalURL(t *testing.T) {
+	tests := []struct {
+		link string
+		want string
+	}{
+		{"http://www.Example.com/article/?utm_source=reddit&id=7#comments", "https://example.com/article?id=7"},
+		{"https://m.example.com/article?b=2&a=1&fbclid=x", "https://example.com/article?a=1&b=2"},
+		{"https://youtu.be/dQw4w9WgXcQ?si=abc", "https://youtube.com/watch?v=dQw4w9WgXcQ"},
+		{"https://www.youtube.com/shorts/dQw4w9WgXcQ", "https://youtube.com/watch?v=dQw4w9WgXcQ"},
+		{"/r/golang/comments/abc", ""},
+	}
+	for _, tt := range tests {
+		if got := dedup.CanonicalURL(tt.link); got != tt.want {
+			t.Errorf("CanonicalURL(%q) = %q, want %q", tt.link, got, tt.want)
+		}
+	}
+}
+
+func TestGroupKeepsDuplicatesAcrossSubreddits(t *testing.T) {
+	posts := dedup.Enrich([]models.Post{
+		{ID: "a", Subreddit: "golang", Author: "spammer", Title: "Buy now!", CreatedUTC: 300},
+		{ID: "b", Subreddit: "rust", Author: "spammer", Title: "buy now", CreatedUTC: 100},
+		{ID: "c", Subreddit: "golang", Author: "x", Title: "Same sub", LinkURL: "https://example.com/p?utm_medium=a"},
+		{ID: "d", Subreddit: "golang", Author: "y", Title: "Same sub again", LinkURL: "https://example.com/p"},
+		{ID: "e", Subreddit: "python", Author: "z", Title: "Buy now", CrosspostParent: "t3_a"},
+		{ID: "f", Subreddit: "python", Author: "w", Title: "Unrelated"},
+	})
+
+	groups := dedup.Group(posts)
+	if len(groups) != 1 {
+		t.Fatalf("expected 1 group, got %+v", groups)
+	}
+	group := groups[0]
+	if group.Kind != dedup.KindContent || group.Authors != 1 {
+		t.Errorf("unexpected group %+v", group)
+	}
+	if len(group.Subreddits) != 2 || group.Subreddits[0] != "golang" || group.Subreddits[1] != "rust" {
+		t.Errorf("unexpected subreddits %v", group.Subreddits)
+	}
+	if len(group.Posts) != 2 || group.Posts[0].ID != "b" {
+		t.Errorf("expected the crosspost left out and the oldest post first, got %+v", group.Posts)
+	}
+}
+
+func TestFinderReadsTheWindow(t *testing.T) {
+	var gotSince int64
+	var gotSubreddits string
+	svc := &mocks.MockScraperService{
+		ScrapeSubredditFunc: func(ctx context.Context, subreddit string, sinceTimestamp int64, limit int, opts scraper.ListingOptions) ([]models.Post, models.ListingMeta, error) {
+			gotSubreddits, gotSince = subreddit, sinceTimestamp
+			return []models.Post{
+				{ID: "a", Subreddit: "golang", Title: "t", LinkURL: "https://example.com/x"},
+				{ID: "b", Subreddit: "rust", Title: "u", LinkURL: "http://www.example.com/x/"},
+			}, models.ListingMeta{}, nil
+		},
+	}
+
+	before := time.Now()
+	duplicates, err := dedup.NewFinder(dedup.WrapService(svc)).Duplicates(context.Background(), "golang+rust", 6*time.Hour, -1)
+	if err != nil {
+		t.Fatalf("Duplicates failed: %v", err)
+	}
+	if gotSubreddits != "golang+rust" || gotSince < before.Add(-6*time.Hour).Unix() || gotSince > time.Now().Add(-6*time.Hour).Unix() {
+		t.Errorf("unexpected scrape of %s since %d", gotSubreddits, gotSince)
+	}
+	if duplicates.PostsScanned != 2 || len(duplicates.Groups) != 1 || duplicates.Groups[0].Kind != dedup.KindLink {
+		t.Errorf("unexpected duplicates %+v", duplicates)
+	}
+}
@@ -0,0 +1,377 @@
+package export_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"reddit-ingestion/internal/compression"
+	"reddit-ingestion/internal/export"
+	"reddit-ingestion/pkg/models"
+)
+
+func sampleDetail() models.PostDetail {
+	created := time.Unix(1700000000, 0)
+	return models.PostDetail{
+		Post: models.Post{ID: "p1", Title: "Title"},
+		Comments: []models.Comment{
+			{
+				ID: "c1", Author: "a", Body: "top, with comma", CreatedAt: created,
+				Replies: []models.Comment{
+					{ID: "c2", Author: "b", Body: "reply", CreatedAt: created},
+					{ID: "more_x", IsMore: true},
+				},
+			},
+			{ID: "c3", Author: "c", Body: "second"},
+		},
+	}
+}
+
+func TestCommentRecordsFlattenTree(t *testing.T) {
+	records := export.CommentRecords(sampleDetail())
+	if len(records) != 3 {
+		t.Fatalf("Expected 3 records (placeholder skipped), got %d", len(records))
+	}
+
+	reply := records[1].(export.CommentRecord)
+	if reply.ID != "c2" || reply.ParentID != "c1" || reply.Depth != 1 || reply.PostID != "p1" {
+		t.Errorf("Unexpected reply record: %+v", reply)
+	}
+	if top := records[2].(export.CommentRecord); top.ParentID != "" || top.Depth != 0 {
+		t.Errorf("Expected top-level record, got %+v", top)
+	}
+}
+
+func TestWriterCSV(t *testing.T) {
+	var buf bytes.Buffer
+	w := export.NewWriter(&buf, export.FormatCSV)
+	if err := w.Write(nil, export.CommentRecords(sampleDetail())); err != nil {
+		t.Fatalf("Write returned error: %v", err)
+	}
+	if err := w.Flush(); err != nil {
+		t.Fatalf("Flush returned error: %v", err)
+	}
+
+	rows, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatalf("Output is not valid CSV: %v", err)
+	}
+	if len(rows) != 4 {
+		t.Fatalf("Expected header and 3 rows, got %d rows", len(rows))
+	}
+	if rows[0][0] != "post_id" || rows[1][5] != "top, with comma" {
+		t.Errorf("Unexpected CSV content: %v", rows)
+	}
+	if rows[1][7] != "2023-11-14T22:13:20Z" {
+		t.Errorf("Expected RFC3339 UTC timestamp, got %q", rows[1][7])
+	}
+}
+
+func TestWriterNDJSON(t *testing.T) {
+	activity := models.UserActivity{
+		UserInfo: models.UserInfo{Username: "gopher"},
+		Posts:    []models.UserPost{{ID: "p1", Subreddit: "golang"}},
+		Comments: []models.UserComment{{ID: "c1", PostID: "p2", PostTitle: "Other"}},
+	}
+
+	var buf bytes.Buffer
+	w := export.NewWriter(&buf, export.FormatNDJSON)
+	if err := w.Write(activity, export.UserActivityRecords(activity)); err != nil {
+		t.Fatalf("Write returned error: %v", err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("Expected 2 lines, got %d", len(lines))
+	}
+
+	var item export.UserItemRecord
+	if err := json.Unmarshal([]byte(lines[1]), &item); err != nil {
+		t.Fatalf("Line is not valid JSON: %v", err)
+	}
+	if item.Kind != "comment" || item.Username != "gopher" || item.PostID != "p2" || item.Title != "Other" {
+		t.Errorf("Unexpected comment record: %+v", item)
+	}
+}
+
+func TestParseFormat(t *testing.T) {
+	if f, err := export.ParseFormat("NDJSON"); err != nil || f != export.FormatNDJSON {
+		t.Errorf("Expected ndjson, got %q (%v)", f, err)
+	}
+	if f, _ := export.ParseFormat(""); f != export.FormatJSON {
+		t.Errorf("Expected empty format to default to json, got %q", f)
+	}
+	if _, err := export.ParseFormat("xml"); err == nil {
+		t.Error("Expected error for unsupported format")
+	}
+}
+
+func TestRecordSinkWritesNDJSON(t *testing.T) {
+	var buf bytes.Buffer
+	s := export.NewRecordSink(export.NewWriter(&buf, export.FormatNDJSON))
+
+	ctx := context.Background()
+	if err := s.WritePosts(ctx, []models.Post{{ID: "p1"}}); err != nil {
+		t.Fatalf("WritePosts returned error: %v", err)
+	}
+	if err := s.WriteComments(ctx, "p1", sampleDetail().Comments); err != nil {
+		t.Fatalf("WriteComments returned error: %v", err)
+	}
+	if err := s.Close(); err != nil {
+		t.Fatalf("Close returned error: %v", err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 4 {
+		t.Fatalf("Expected 1 post and 3 comment lines, got %d", len(lines))
+	}
+
+	var comment export.CommentRecord
+	if err := json.Unmarshal([]byte(lines[2]), &comment); err != nil {
+		t.Fatalf("Line is not valid JSON: %v", err)
+	}
+	if comment.PostID != "p1" || comment.ParentID != "c1" {
+		t.Errorf("Unexpected comment record: %+v", comment)
+	}
+}
+
+func TestAnonymizerKeepsRecordsJoinable(t *testing.T) {
+	anonymizer, err := export.NewAnonymizer("research-export-key-2025", "")
+	if err != nil {
+		t.Fatalf("NewAnonymizer returned error: %v", err)
+	}
+	if _, err := export.NewAnonymizer("short", ""); err == nil {
+		t.Error("Expected a short key to be rejected")
+	}
+
+	var buf bytes.Buffer
+	s := export.NewAnonymizingRecordSink(export.NewWriter(&buf, export.FormatNDJSON), anonymizer)
+	ctx := context.Background()
+	post := models.Post{ID: "p1", Author: "Gopher", Body: "thanks u/Alice, see /user/bob_2"}
+	if err := s.WritePosts(ctx, []models.Post{post}); err != nil {
+		t.Fatalf("WritePosts returned error: %v", err)
+	}
+	comments := []models.Comment{{ID: "c1", Author: "gopher", Body: "menu/items"}, {ID: "c2", Author: "[deleted]"}}
+	if err := s.WriteComments(ctx, "p1", comments); err != nil {
+		t.Fatalf("WriteComments returned error: %v", err)
+	}
+	if err := s.WriteUserActivity(ctx, models.UserActivity{UserInfo: models.UserInfo{Username: "alice"}, Posts: []models.UserPost{{ID: "p2"}}}); err != nil {
+		t.Fatalf("WriteUserActivity returned error: %v", err)
+	}
+	s.Close()
+
+	output := buf.String()
+	for _, name := range []string{"Gopher", "gopher", "Alice", "alice", "bob_2"} {
+		if strings.Contains(output, name) {
+			t.Errorf("Expected %q to be pseudonymized, got %s", name, output)
+		}
+	}
+
+	lines := strings.Split(strings.TrimSpace(output), "\n")
+	var postRecord, commentRecord, deletedRecord export.CommentRecord
+	var userRecord export.UserItemRecord
+	json.Unmarshal([]byte(lines[0]), &postRecord)
+	json.Unmarshal([]byte(lines[1]), &commentRecord)
+	json.Unmarshal([]byte(lines[2]), &deletedRecord)
+	json.Unmarshal([]byte(lines[3]), &userRecord)
+
+	gopher := anonymizer.Pseudonym("gopher")
+	if postRecord.Author != gopher || commentRecord.Author != gopher {
+		t.Errorf("Expected both of gopher's records to carry %s, got %q and %q", gopher, postRecord.Author, commentRecord.Author)
+	}
+	if userRecord.Username != anonymizer.Pseudonym("Alice") {
+		t.Errorf("Expected the user record to match alice's mention, got %q", userRecord.Username)
+	}
+	if want := "thanks u/" + anonymizer.Pseudonym("alice") + ", see /user/" + anonymizer.Pseudonym("bob_2"); postRecord.Body != want {
+		t.Errorf("Expected mentions to be rewritten to %q, got %q", want, postRecord.Body)
+	}
+	if commentRecord.Body != "menu/items" || deletedRecord.Author != "[deleted]" {
+		t.Errorf("Expected text without mentions and deleted authors to be kept, got %+v and %+v", commentRecord, deletedRecord)
+	}
+	if commentRecord.ID != "c1" || commentRecord.PostID != "p1" {
+		t.Errorf("Expected IDs to be kept for joins, got %+v", commentRecord)
+	}
+
+	other, _ := export.NewAnonymizer("another-export-key-2025", "")
+	if other.Pseudonym("gopher") == gopher {
+		t.Error("Expected a different key to give different pseudonyms")
+	}
+}
+
+func TestAnonymizerRotatesKeysAndSealsMapping(t *testing.T) {
+	keys := "k2:second-export-key-2025,k1:research-export-key-2025"
+	current, err := export.NewAnonymizer(keys, "")
+	if err != nil {
+		t.Fatalf("NewAnonymizer returned error: %v", err)
+	}
+	previous, err := export.NewAnonymizer(keys, "k1")
+	if err != nil {
+		t.Fatalf("NewAnonymizer with key k1 returned error: %v", err)
+	}
+	if _, err := export.NewAnonymizer(keys, "k3"); err == nil {
+		t.Error("Expected an unknown key ID to be rejected")
+	}
+	if _, err := export.NewAnonymizer("k1:research-export-key-2025,another-export-key-2025", ""); err == nil {
+		t.Error("Expected a key without an ID to be rejected next to others")
+	}
+
+	if current.KeyID() != "k2" || !strings.HasPrefix(current.Pseudonym("gopher"), "anon_k2_") {
+		t.Errorf("Expected the newest key to be used, got %s", current.Pseudonym("gopher"))
+	}
+	if !strings.HasPrefix(previous.Pseudonym("gopher"), "anon_k1_") {
+		t.Errorf("Expected the older key to be selectable, got %s", previous.Pseudonym("gopher"))
+	}
+
+	current.TrackMapping()
+	current.Records([]export.Record{export.PostRecord{Post: models.Post{ID: "p1", Author: "Gopher", Body: "cc u/alice"}}})
+	mapping := current.Mapping()
+	if len(mapping) != 2 || mapping[current.Pseudonym("gopher")] != "gopher" || mapping[current.Pseudonym("alice")] != "alice" {
+		t.Fatalf("Expected the mapping to cover gopher and alice, got %v", mapping)
+	}
+
+	publicKey, privateKey, err := export.GenerateMappingKeys()
+	if err != nil {
+		t.Fatalf("GenerateMappingKeys returned error: %v", err)
+	}
+	sealed, err := export.SealMapping(export.Mapping{KeyID: current.KeyID(), Pseudonyms: mapping}, publicKey)
+	if err != nil {
+		t.Fatalf("SealMapping returned error: %v", err)
+	}
+	if bytes.Contains(sealed, []byte("gopher")) {
+		t.Error("Expected the sealed mapping not to contain usernames")
+	}
+
+	opened, err := export.OpenMapping(sealed, privateKey)
+	if err != nil {
+		t.Fatalf("OpenMapping returned error: %v", err)
+	}
+	if opened.KeyID != "k2" || opened.Pseudonyms[current.Pseudonym("gopher")] != "gopher" {
+		t.Errorf("Unexpected opened mapping: %+v", opened)
+	}
+
+	_, otherPrivateKey, _ := export.GenerateMappingKeys()
+	if _, err := export.OpenMapping(sealed, otherPrivateKey); err == nil {
+		t.Error("Expected another private key not to open the mapping")
+	}
+}
+
+func TestFileRotatesBetweenRecords(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "posts.csv")
+	f, err := export.CreateFile(path, export.FileOptions{MaxBytes: 200})
+	if err != nil {
+		t.Fatalf("CreateFile returned error: %v", err)
+	}
+
+	var posts []models.Post
+	for i := 0; i < 10; i++ {
+		posts = append(posts, models.Post{ID: strings.Repeat("p", i+1), Title: "A title long enough to fill a file quickly"})
+	}
+	w := export.NewWriter(f, export.FormatCSV)
+	if err := w.Write(nil, export.PostRecords(posts)); err != nil {
+		t.Fatalf("Write returned error: %v", err)
+	}
+	if err := w.Flush(); err != nil {
+		t.Fatalf("Flush returned error: %v", err)
+	}
+	if err := f.Close(); err != nil {
+		t.Fatalf("Close returned error: %v", err)
+	}
+
+	names := f.Names()
+	if len(names) < 2 || filepath.Base(names[0]) != "posts-00001.csv" {
+		t.Fatalf("Expected numbered files, got %v", names)
+	}
+	rows := 0
+	for _, name := range names {
+		data, _ := os.ReadFile(name)
+		records, err := csv.NewReader(bytes.NewReader(data)).ReadAll()
+		if err != nil {
+			t.Fatalf("%s is not valid CSV: %v", name, err)
+		}
+		if len(records) < 2 || records[0][0] != "id" {
+			t.Errorf("Expected %s to start with a header and hold whole rows, got %v", name, records)
+		}
+		rows += len(records) - 1
+	}
+	if rows != len(posts) {
+		t.Errorf("Expected %d rows across files, got %d", len(posts), rows)
+	}
+}
+
+func TestFileDoesNotStartAPartAfterTheLastRecord(t *testing.T) {
+	posts := []models.Post{{ID: "p1", Title: "First"}, {ID: "p2", Title: "Second"}}
+
+	// A limit the two rows and their header reach exactly
+	var buf bytes.Buffer
+	w := export.NewWriter(&buf, export.FormatCSV)
+	if err := w.Write(nil, export.PostRecords(posts)); err != nil {
+		t.Fatalf("Write returned error: %v", err)
+	}
+	if err := w.Flush(); err != nil {
+		t.Fatalf("Flush returned error: %v", err)
+	}
+
+	path := filepath.Join(t.TempDir(), "posts.csv")
+	f, err := export.CreateFile(path, export.FileOptions{MaxBytes: int64(buf.Len())})
+	if err != nil {
+		t.Fatalf("CreateFile returned error: %v", err)
+	}
+	w = export.NewWriter(f, export.FormatCSV)
+	if err := w.Write(nil, export.PostRecords(posts)); err != nil {
+		t.Fatalf("Write returned error: %v", err)
+	}
+	if err := w.Flush(); err != nil {
+		t.Fatalf("Flush returned error: %v", err)
+	}
+	if err := f.Close(); err != nil {
+		t.Fatalf("Close returned error: %v", err)
+	}
+
+	names := f.Names()
+	if len(names) != 1 {
+		t.Fatalf("Expected the full part to be the only file, got %v", names)
+	}
+	data, _ := os.ReadFile(names[0])
+	if !bytes.Equal(data, buf.Bytes()) {
+		t.Errorf("Expected %s to hold the header and both rows, got %q", names[0], data)
+	}
+}
+
+func TestFileCompressesOutput(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "posts.ndjson")
+	f, err := export.CreateFile(path, export.FileOptions{Compression: compression.Gzip, Level: 9})
+	if err != nil {
+		t.Fatalf("CreateFile returned error: %v", err)
+	}
+	w := export.NewWriter(f, export.FormatNDJSON)
+	if err := w.Write(nil, export.PostRecords([]models.Post{{ID: "p1"}})); err != nil {
+		t.Fatalf("Write returned error: %v", err)
+	}
+	if err := f.Close(); err != nil {
+		t.Fatalf("Close returned error: %v", err)
+	}
+
+	data, err := os.ReadFile(path + ".gz")
+	if err != nil {
+		t.Fatalf("Expected %s.gz to be written: %v", path, err)
+	}
+	gz, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("Output is not gzipped: %v", err)
+	}
+	var record export.PostRecord
+	if err := json.NewDecoder(gz).Decode(&record); err != nil || record.ID != "p1" {
+		t.Errorf("Expected the post record, got %+v (%v)", record, err)
+	}
+
+	if _, err := export.CreateFile(path, export.FileOptions{Compression: compression.Gzip, Level: 12}); err == nil {
+		t.Error("Expected an out of range gzip level to be rejected")
+	}
+}
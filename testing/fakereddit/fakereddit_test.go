@@ -0,0 +1,392 @@
+// testing/fakereddit/fakereddit_test.go
+package fakereddit_test
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"reddit-ingestion/internal/fakereddit"
+	"reddit-ingestion/pkg/client"
+	"reddit-ingestion/pkg/models"
+	"reddit-ingestion/pkg/parser"
+	"reddit-ingestion/pkg/scraper"
+	"reddit-ingestion/pkg/utils"
+)
+
+// newFakeScraper returns a scraper whose real RedditClient talks to fake
+func newFakeScraper(t *testing.T, fake *fakereddit.Server, maxRetries int) (*client.RedditClient, scraper.ScraperService) {
+	t.Helper()
+	redditClient, err := client.NewRedditClient(client.Options{
+		UserAgent:  "fakereddit-test/1.0",
+		MaxRetries: maxRetries,
+		BaseURL:    fake.URL,
+		APIURL:     fake.URL,
+		Direct:     true,
+	})
+	if err != nil {
+		t.Fatalf("NewRedditClient: %v", err)
+	}
+	t.Cleanup(redditClient.Close)
+	return redditClient, scraper.NewScraperService(redditClient, parser.NewRedditParser())
+}
+
+// commentIDs collects the ids of the loaded comments of a tree, "more" nodes
+// excluded
+func commentIDs(comments []models.Comment, ids map[string]bool) {
+	for _, c := range comments {
+		if !c.IsMore {
+			ids[c.ID] = true
+		}
+		commentIDs(c.Replies, ids)
+	}
+}
+
+// misplaced lists the comments of a tree that are not under the parent their
+// parent_id names
+func misplaced(comments []models.Comment, parent string, out *[]string) {
+	for _, c := range comments {
+		if !c.IsMore && c.ParentID != parent {
+			*out = append(*out, c.ID+" under "+parent+", parent_id "+c.ParentID)
+		}
+		misplaced(c.Replies, "t1_"+c.ID, out)
+	}
+}
+
+// countingTransport counts the requests sent through it
+type countingTransport struct {
+	requests atomic.Int64
+}
+
+func (t *countingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	t.requests.Add(1)
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+func TestClientWithOptionsUsesCustomHTTPClient(t *testing.T) {
+	fake := fakereddit.NewServer(fakereddit.Options{ListingSize: 150})
+	defer fake.Close()
+	transport := &countingTransport{}
+	redditClient, err := client.NewRedditClientWithOptions(
+		client.WithHTTPClient(&http.Client{Transport: transport}),
+		client.WithBaseURL(fake.URL),
+		client.WithAPIURL(fake.URL),
+		// 1200 a minute is one request every 50ms
+		client.WithRateLimiter(utils.NewRequestLimiter(1200)),
+	)
+	if err != nil {
+		t.Fatalf("NewRedditClientWithOptions: %v", err)
+	}
+	defer redditClient.Close()
+	svc := scraper.NewScraperService(redditClient, parser.NewRedditParser())
+
+	start := time.Now()
+	posts, _, err := svc.ScrapeSubreddit(context.Background(), "golang", 0, 150, scraper.ListingOptions{})
+	if err != nil {
+		t.Fatalf("ScrapeSubreddit: %v", err)
+	}
+	if len(posts) != 150 {
+		t.Errorf("got %d posts, want 150", len(posts))
+	}
+	pages := fake.Requests(fakereddit.RouteListing)
+	if int(transport.requests.Load()) != pages || pages < 2 {
+		t.Errorf("custom transport sent %d requests, fake served %d pages", transport.requests.Load(), pages)
+	}
+	if elapsed := time.Since(start); elapsed < time.Duration(pages-1)*50*time.Millisecond {
+		t.Errorf("%d pages took %v, want them spaced by the rate limiter", pages, elapsed)
+	}
+}
+
+func TestScrapeSubredditPagesThroughFake(t *testing.T) {
+	fake := fakereddit.NewServer(fakereddit.Options{ListingSize: 150})
+	defer fake.Close()
+	_, svc := newFakeScraper(t, fake, 3)
+
+	posts, _, err := svc.ScrapeSubreddit(context.Background(), "golang", 0, 200, scraper.ListingOptions{})
+	if err != nil {
+		t.Fatalf("ScrapeSubreddit: %v", err)
+	}
+	if len(posts) != 150 {
+		t.Fatalf("got %d posts, want the 150 the listing holds", len(posts))
+	}
+	seen := make(map[string]bool)
+	for i, post := range posts {
+		if seen[post.ID] {
+			t.Fatalf("post %s listed twice", post.ID)
+		}
+		seen[post.ID] = true
+		if post.Subreddit != "golang" || post.Title == "" || post.Author == "" {
+			t.Fatalf("post %d incomplete: %+v", i, post)
+		}
+		if i > 0 && post.CreatedAt.After(posts[i-1].CreatedAt) {
+			t.Fatalf("post %d is newer than the one before it", i)
+		}
+	}
+	if got := fake.Requests(fakereddit.RouteListing); got < 2 {
+		t.Errorf("listing requests = %d, want the listing paged", got)
+	}
+}
+
+func TestScrapeSubredditReportsPageProgress(t *testing.T) {
+	fake := fakereddit.NewServer(fakereddit.Options{ListingSize: 150})
+	defer fake.Close()
+	_, svc := newFakeScraper(t, fake, 3)
+
+	var events []scraper.Progress
+	ctx := scraper.WithProgress(context.Background(), func(progress scraper.Progress) {
+		events = append(events, progress)
+	})
+	if _, _, err := svc.ScrapeSubreddit(ctx, "golang", 0, 200, scraper.ListingOptions{}); err != nil {
+		t.Fatalf("ScrapeSubreddit: %v", err)
+	}
+
+	pages := fake.Requests(fakereddit.RouteListing)
+	if len(events) != pages+1 {
+		t.Fatalf("got %d progress events for %d pages, want one per page and done: %+v", len(events), pages, events)
+	}
+	for i, event := range events[:len(events)-1] {
+		if event.Operation != "subreddit" || event.Stage != scraper.StageFetchPages || event.PagesFetched != i+1 {
+			t.Errorf("event %d = %+v, want page %d fetched", i, event, i+1)
+		}
+	}
+	if last := events[len(events)-1]; last.Stage != scraper.StageDone || last.Percent != 100 || last.PagesFetched != pages {
+		t.Errorf("final progress = %+v", last)
+	}
+}
+
+func TestClientFallsBackToAnotherHost(t *testing.T) {
+	primary := fakereddit.NewServer(fakereddit.Options{ErrorRate: 1})
+	defer primary.Close()
+	fallback := fakereddit.NewServer(fakereddit.Options{})
+	defer fallback.Close()
+	search := fakereddit.NewServer(fakereddit.Options{})
+	defer search.Close()
+
+	redditClient, err := client.NewRedditClientWithOptions(
+		client.WithBaseURL(primary.URL),
+		client.WithAPIURL(primary.URL),
+		client.WithEndpointURL(client.EndpointSearch, search.URL),
+		client.WithFallbackURLs(fallback.URL),
+		client.WithMaxRetries(1),
+	)
+	if err != nil {
+		t.Fatalf("NewRedditClientWithOptions: %v", err)
+	}
+	defer redditClient.Close()
+	svc := scraper.NewScraperService(redditClient, parser.NewRedditParser())
+
+	posts, _, err := svc.ScrapeSubreddit(context.Background(), "golang", 0, 5, scraper.ListingOptions{})
+	if err != nil || len(posts) != 5 {
+		t.Fatalf("ScrapeSubreddit: %d posts, %v", len(posts), err)
+	}
+	if primary.Requests(fakereddit.RouteListing) == 0 || fallback.Requests(fakereddit.RouteListing) == 0 {
+		t.Errorf("listing requests: primary %d, fallback %d, want the failing primary tried first",
+			primary.Requests(fakereddit.RouteListing), fallback.Requests(fakereddit.RouteListing))
+	}
+
+	if _, _, err := svc.Search(context.Background(), map[string]string{"search_string": "go"}, 0, 5, scraper.ListingOptions{}); err != nil {
+		t.Fatalf("Search: %v", err)
+	}
+	if search.Requests(fakereddit.RouteSearch) == 0 || primary.Requests(fakereddit.RouteSearch) != 0 {
+		t.Errorf("search requests: search host %d, primary %d, want them on the search host",
+			search.Requests(fakereddit.RouteSearch), primary.Requests(fakereddit.RouteSearch))
+	}
+}
+
+func TestClientFailsOverAndProbesThePrimary(t *testing.T) {
+	primary := fakereddit.NewServer(fakereddit.Options{})
+	defer primary.Close()
+	fallback := fakereddit.NewServer(fakereddit.Options{})
+	defer fallback.Close()
+
+	redditClient, err := client.NewRedditClientWithOptions(
+		client.WithBaseURL(primary.URL),
+		client.WithAPIURL(primary.URL),
+		client.WithFallbackURLs(fallback.URL),
+		client.WithFailover(3, 100*time.Millisecond),
+		client.WithMaxRetries(1),
+	)
+	if err != nil {
+		t.Fatalf("NewRedditClientWithOptions: %v", err)
+	}
+	defer redditClient.Close()
+	fetch := func() {
+		t.Helper()
+		if _, err := redditClient.FetchJSON(context.Background(), redditClient.GetSubredditURL("golang", 10, "")); err != nil {
+			t.Fatalf("FetchJSON: %v", err)
+		}
+	}
+
+	primary.FailNext(3, http.StatusServiceUnavailable)
+	for i := 0; i < 3; i++ {
+		fetch()
+	}
+	health := redditClient.HostHealth()
+	if health.Status != client.HostStatusDegraded || health.Active != fallback.URL || health.Failovers != 1 ||
+		len(health.Events) != 1 || health.Events[0].End != nil {
+		t.Fatalf("after 3 failures: %+v, want degraded to the fallback with an open event", health)
+	}
+
+	fetch()
+	if got := primary.Requests(fakereddit.RouteListing); got != 3 {
+		t.Errorf("primary listing requests = %d, want none while degraded", got)
+	}
+	if got := redditClient.HostHealth().FallbackRequests; got != 1 {
+		t.Errorf("fallback requests = %d, want 1", got)
+	}
+
+	time.Sleep(150 * time.Millisecond)
+	fetch()
+	health = redditClient.HostHealth()
+	if health.Status != client.HostStatusOK || health.Active != primary.URL || health.Recoveries != 1 ||
+		health.Probes != 1 || len(health.Events) != 1 || health.Events[0].End == nil {
+		t.Errorf("after a successful probe: %+v, want the primary back and the event closed", health)
+	}
+	if got := primary.Requests(fakereddit.RouteListing); got != 4 {
+		t.Errorf("primary listing requests = %d, want the probe sent to it", got)
+	}
+}
+
+func TestScrapePostLoadsMoreChildren(t *testing.T) {
+	fake := fakereddit.NewServer(fakereddit.Options{})
+	defer fake.Close()
+	redditClient, svc := newFakeScraper(t, fake, 3)
+
+	posts, _, err := svc.ScrapeSubreddit(context.Background(), "programming", 0, 5, scraper.ListingOptions{})
+	if err != nil || len(posts) == 0 {
+		t.Fatalf("ScrapeSubreddit: %d posts, %v", len(posts), err)
+	}
+
+	detail, err := svc.ScrapePost(context.Background(), posts[0].ID)
+	if err != nil {
+		t.Fatalf("ScrapePost: %v", err)
+	}
+	if detail.Post.ID != posts[0].ID || detail.Post.Title != posts[0].Title {
+		t.Fatalf("post page shows %s %q, want the listed %s %q", detail.Post.ID, detail.Post.Title, posts[0].ID, posts[0].Title)
+	}
+	ids := make(map[string]bool)
+	commentIDs(detail.Comments, ids)
+	if len(ids) != detail.Post.NumComments {
+		t.Errorf("loaded %d comments, want all %d", len(ids), detail.Post.NumComments)
+	}
+	if fake.Requests(fakereddit.RouteMoreChildren) == 0 {
+		t.Error("no morechildren request, want the hidden comments loaded")
+	}
+	var wrong []string
+	misplaced(detail.Comments, "t3_"+detail.Post.ID, &wrong)
+	if len(wrong) > 0 {
+		t.Errorf("%d comments misplaced, e.g. %s", len(wrong), wrong[0])
+	}
+
+	// Reddit's morechildren ignores ids it does not know
+	data, err := redditClient.FetchMoreComments(context.Background(), posts[0].ID, []string{"unknown"})
+	if err != nil || !strings.Contains(string(data), `"things":[]`) {
+		t.Errorf("morechildren of unknown ids = %s, %v", data, err)
+	}
+}
+
+func TestScrapePostKeepsAnUnlistedID(t *testing.T) {
+	fake := fakereddit.NewServer(fakereddit.Options{})
+	defer fake.Close()
+	_, svc := newFakeScraper(t, fake, 3)
+
+	detail, err := svc.ScrapePost(context.Background(), "abc123")
+	if err != nil {
+		t.Fatalf("ScrapePost: %v", err)
+	}
+	if detail.Post.ID != "abc123" || detail.Post.Subreddit != "golang" {
+		t.Fatalf("post page shows %s in r/%s, want abc123 in r/golang", detail.Post.ID, detail.Post.Subreddit)
+	}
+	var wrong []string
+	misplaced(detail.Comments, "t3_abc123", &wrong)
+	if len(detail.Comments) == 0 || len(wrong) > 0 {
+		t.Errorf("got %d comments, %d not under abc123 or their parent: %v", len(detail.Comments), len(wrong), wrong)
+	}
+}
+
+func TestScrapeUserActivityFromFake(t *testing.T) {
+	fake := fakereddit.NewServer(fakereddit.Options{})
+	defer fake.Close()
+	_, svc := newFakeScraper(t, fake, 3)
+
+	activity, err := svc.ScrapeUserActivity(context.Background(), "spez", 0, 30, 30)
+	if err != nil {
+		t.Fatalf("ScrapeUserActivity: %v", err)
+	}
+	if activity.UserInfo.Username != "spez" {
+		t.Errorf("user name = %q, want spez", activity.UserInfo.Username)
+	}
+	if len(activity.Posts) != 30 || len(activity.Comments) != 30 {
+		t.Fatalf("got %d posts and %d comments, want 30 of each", len(activity.Posts), len(activity.Comments))
+	}
+	for _, comment := range activity.Comments {
+		if comment.PostID == "" || comment.Body == "" {
+			t.Fatalf("comment incomplete: %+v", comment)
+		}
+	}
+}
+
+func TestRetryableClientRetriesInjectedErrors(t *testing.T) {
+	fake := fakereddit.NewServer(fakereddit.Options{})
+	defer fake.Close()
+	redditClient, _ := newFakeScraper(t, fake, 2)
+
+	fake.FailNext(1, http.StatusServiceUnavailable)
+	data, err := redditClient.FetchJSON(context.Background(), redditClient.GetSubredditURL("golang", 10, ""))
+	if err != nil {
+		t.Fatalf("FetchJSON after one 503: %v", err)
+	}
+	if !strings.Contains(string(data), `"kind":"Listing"`) {
+		t.Errorf("body is not a listing: %.100s", data)
+	}
+	if got := fake.Requests(fakereddit.RouteListing); got != 2 {
+		t.Errorf("listing requests = %d, want the failed one retried", got)
+	}
+}
+
+func TestDefaultClientBacksOffBetweenRetries(t *testing.T) {
+	fake := fakereddit.NewServer(fakereddit.Options{})
+	defer fake.Close()
+	redditClient, err := client.NewRedditClientWithOptions(
+		client.WithBaseURL(fake.URL),
+		client.WithMaxRetries(5),
+	)
+	if err != nil {
+		t.Fatalf("NewRedditClientWithOptions: %v", err)
+	}
+	defer redditClient.Close()
+
+	// Without a backoff the five attempts take a few milliseconds; with the
+	// default one the waits before them add up to well over 100ms
+	fake.FailNext(5, http.StatusServiceUnavailable)
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	if _, err := redditClient.FetchJSON(ctx, redditClient.GetSubredditURL("golang", 10, "")); err == nil {
+		t.Fatal("FetchJSON succeeded, want it to fail while backing off")
+	}
+	if got := fake.Requests(fakereddit.RouteListing); got >= 5 {
+		t.Errorf("listing requests within 100ms = %d, want the retries spaced out", got)
+	}
+}
+
+func TestErrorRateAndLatency(t *testing.T) {
+	fake := fakereddit.NewServer(fakereddit.Options{
+		Latency:     50 * time.Millisecond,
+		ErrorRate:   1,
+		ErrorStatus: http.StatusTooManyRequests,
+	})
+	defer fake.Close()
+	redditClient, _ := newFakeScraper(t, fake, 1)
+
+	start := time.Now()
+	_, err := redditClient.FetchJSON(context.Background(), redditClient.GetPostURL("abc123"))
+	if err == nil || !strings.Contains(err.Error(), "429") {
+		t.Fatalf("FetchJSON = %v, want the injected 429", err)
+	}
+	if elapsed := time.Since(start); elapsed < 50*time.Millisecond {
+		t.Errorf("answered after %v, want the 50ms latency", elapsed)
+	}
+}
@@ -12,8 +12,8 @@ import (
 	"testing"
 	"time"
 
-	"reddit-ingestion/internal/client"
 	"reddit-ingestion/internal/config"
+	"reddit-ingestion/pkg/client"
 )
 
 // Setup a test config without requiring environment variables
@@ -23,6 +23,8 @@ func setupTestConfig() (*config.Config, bool) {
 		// Try loading from environment
 		cfg, err := config.LoadConfig()
 		if err == nil {
+			// The fingerprint checks fetch httpbin.org, outside Reddit's hosts
+			cfg.ExtraAllowedHosts = append(cfg.ExtraAllowedHosts, "httpbin.org")
 			return cfg, false // Using real config
 		}
 	}
@@ -144,7 +146,7 @@ func TestFingerprintingMeasures(t *testing.T) {
     }
 
     // Create the client with our config
-    redditClient, err := client.NewRedditClient(cfg)
+    redditClient, err := client.NewRedditClient(cfg.ClientOptions())
     if err != nil {
         t.Fatalf("Failed to create Reddit client: %v", err)
     }
@@ -395,7 +397,7 @@ func TestTLSFingerprintJA3Hash(t *testing.T) {
         t.Log("Using mock configuration - JA3 hash test will use sample data")
     }
 
-    redditClient, err := client.NewRedditClient(cfg)
+    redditClient, err := client.NewRedditClient(cfg.ClientOptions())
     if err != nil {
         t.Fatalf("Failed to create Reddit client: %v", err)
     }
@@ -470,7 +472,7 @@ func TestBrowserHeadersComprehensive(t *testing.T) {
 		t.Log("Using mock configuration - browser headers test will use sample data")
 	}
 
-	redditClient, err := client.NewRedditClient(cfg)
+	redditClient, err := client.NewRedditClient(cfg.ClientOptions())
 	if err != nil {
 		if !usingMockConfig {
 			t.Fatalf("Failed to create Reddit client: %v", err)
@@ -607,7 +609,7 @@ func TestProxyRotationExtended(t *testing.T) {
         return
     }
 
-    redditClient, err := client.NewRedditClient(cfg)
+    redditClient, err := client.NewRedditClient(cfg.ClientOptions())
     if err != nil {
         t.Fatalf("Failed to create Reddit client: %v", err)
     }
@@ -690,7 +692,7 @@ func TestRedditBotDetection(t *testing.T) {
         return
     }
 
-    redditClient, err := client.NewRedditClient(cfg)
+    redditClient, err := client.NewRedditClient(cfg.ClientOptions())
     if err != nil {
         t.Fatalf("Failed to create Reddit client: %v", err)
     }
@@ -14,10 +14,10 @@ import (
 
 	"github.com/labstack/echo/v4"
 	"reddit-ingestion/internal/config"
-	"reddit-ingestion/internal/models"
-	"reddit-ingestion/internal/parser"
 	"reddit-ingestion/internal/router"
-	"reddit-ingestion/internal/scraper"
+	"reddit-ingestion/pkg/models"
+	"reddit-ingestion/pkg/parser"
+	"reddit-ingestion/pkg/scraper"
 )
 
 // MockableRedditClient extends the RedditClient for testing
@@ -90,12 +90,27 @@ func (m *MockableRedditClient) GetUserCommentsURL(username string, after string)
 	return url
 }
 
+func (m *MockableRedditClient) GetUserOverviewURL(username string, after string) string {
+	url := fmt.Sprintf("https://reddit.com/user/%s/overview.json?raw_json=1&sort=new&limit=100", username)
+	if after != "" {
+		url += fmt.Sprintf("&after=%s", after)
+	}
+	log.Printf("MockClient: GetUserOverviewURL generated: %s", url)
+	return url
+}
+
 func (m *MockableRedditClient) GetPostURL(postID string) string {
 	url := fmt.Sprintf("https://reddit.com/comments/%s.json?raw_json=1&sort=new", postID)
 	log.Printf("MockClient: GetPostURL generated: %s", url)
 	return url
 }
 
+func (m *MockableRedditClient) GetDuplicatesURL(postID string) string {
+	url := fmt.Sprintf("https://reddit.com/duplicates/%s.json?raw_json=1&limit=100", postID)
+	log.Printf("MockClient: GetDuplicatesURL generated: %s", url)
+	return url
+}
+
 func (m *MockableRedditClient) GetSearchURL(searchParams map[string]string) string {
 	url := "https://reddit.com/search.json?raw_json=1"
 	for key, value := range searchParams {
@@ -105,6 +120,18 @@ func (m *MockableRedditClient) GetSearchURL(searchParams map[string]string) stri
 	return url
 }
 
+func (m *MockableRedditClient) GetFrontpageURL(feed string, limit int, after string, params map[string]string) string {
+	url := fmt.Sprintf("https://reddit.com/r/%s/%s.json?raw_json=1", feed, params["sort"])
+	if limit > 0 {
+		url += fmt.Sprintf("&limit=%d", limit)
+	}
+	if after != "" {
+		url += fmt.Sprintf("&after=%s", after)
+	}
+	log.Printf("MockClient: GetFrontpageURL generated: %s", url)
+	return url
+}
+
 // Mock the config loading for integration tests
 func mockConfig() *config.Config {
 	return &config.Config{
@@ -12,8 +12,11 @@ type MockRedditClient struct {
 	GetUserAboutURLFunc    func(username string) string
 	GetUserPostsURLFunc    func(username string, after string) string
 	GetUserCommentsURLFunc func(username string, after string) string
+	GetUserOverviewURLFunc func(username string, after string) string
 	GetPostURLFunc         func(postID string) string
+	GetDuplicatesURLFunc   func(postID string) string
 	GetSearchURLFunc       func(searchParams map[string]string) string
+	GetFrontpageURLFunc    func(feed string, limit int, after string, params map[string]string) string
 }
 
 func (m *MockRedditClient) FetchJSON(ctx context.Context, url string) (json.RawMessage, error) {
@@ -40,10 +43,22 @@ func (m *MockRedditClient) GetUserCommentsURL(username string, after string) str
 	return m.GetUserCommentsURLFunc(username, after)
 }
 
+func (m *MockRedditClient) GetUserOverviewURL(username string, after string) string {
+	return m.GetUserOverviewURLFunc(username, after)
+}
+
 func (m *MockRedditClient) GetPostURL(postID string) string {
 	return m.GetPostURLFunc(postID)
 }
 
+func (m *MockRedditClient) GetDuplicatesURL(postID string) string {
+	return m.GetDuplicatesURLFunc(postID)
+}
+
 func (m *MockRedditClient) GetSearchURL(searchParams map[string]string) string {
 	return m.GetSearchURLFunc(searchParams)
 }
+
+func (m *MockRedditClient) GetFrontpageURL(feed string, limit int, after string, params map[string]string) string {
+	return m.GetFrontpageURLFunc(feed, limit, after, params)
+}
@@ -4,7 +4,7 @@ import (
 	"context"
 	"encoding/json"
 	
-	"reddit-ingestion/internal/models"
+	"reddit-ingestion/pkg/models"
 )
 
 type MockParser struct {
@@ -12,6 +12,7 @@ type MockParser struct {
 	ParseUserInfoFunc      func(ctx context.Context, data json.RawMessage) (models.UserInfo, error)
 	ParseUserPostsFunc     func(ctx context.Context, data json.RawMessage) ([]models.UserPost, string, error)
 	ParseUserCommentsFunc  func(ctx context.Context, data json.RawMessage) ([]models.UserComment, string, error)
+	ParseUserOverviewFunc  func(ctx context.Context, data json.RawMessage) ([]models.UserOverviewItem, string, error)
 	ParsePostFunc          func(ctx context.Context, postData, commentData json.RawMessage) (models.PostDetail, error)
 	ParseMoreCommentsFunc  func(ctx context.Context, data json.RawMessage) ([]models.Comment, error)
 }
@@ -32,6 +33,10 @@ func (m *MockParser) ParseUserComments(ctx context.Context, data json.RawMessage
 	return m.ParseUserCommentsFunc(ctx, data)
 }
 
+func (m *MockParser) ParseUserOverview(ctx context.Context, data json.RawMessage) ([]models.UserOverviewItem, string, error) {
+	return m.ParseUserOverviewFunc(ctx, data)
+}
+
 func (m *MockParser) ParsePost(ctx context.Context, postData, commentData json.RawMessage) (models.PostDetail, error) {
 	return m.ParsePostFunc(ctx, postData, commentData)
 }
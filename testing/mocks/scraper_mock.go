@@ -0,0 +1,41 @@
+package mocks
+
+import (
+	"context"
+
+	"reddit-ingestion/pkg/models"
+	"reddit-ingestion/pkg/scraper"
+)
+
+type MockScraperService struct {
+	ScrapeSubredditFunc    func(ctx context.Context, subreddit string, sinceTimestamp int64, limit int, opts scraper.ListingOptions) ([]models.Post, models.ListingMeta, error)
+	ScrapeUserActivityFunc func(ctx context.Context, username string, sinceTimestamp int64, postLimit, commentLimit int) (models.UserActivity, error)
+	ScrapeUserOverviewFunc func(ctx context.Context, username string, sinceTimestamp int64, limit int) (models.UserOverview, error)
+	ScrapePostFunc         func(ctx context.Context, postID string) (models.PostDetail, error)
+	SearchFunc             func(ctx context.Context, searchParams map[string]string, sinceTimestamp int64, limit int, opts scraper.ListingOptions) ([]models.Post, models.ListingMeta, error)
+	ScrapeFrontpageFunc    func(ctx context.Context, feed string, params map[string]string, limit int, opts scraper.ListingOptions) ([]models.Post, models.ListingMeta, error)
+}
+
+func (m *MockScraperService) ScrapeSubreddit(ctx context.Context, subreddit string, sinceTimestamp int64, limit int, opts scraper.ListingOptions) ([]models.Post, models.ListingMeta, error) {
+	return m.ScrapeSubredditFunc(ctx, subreddit, sinceTimestamp, limit, opts)
+}
+
+func (m *MockScraperService) ScrapeUserActivity(ctx context.Context, username string, sinceTimestamp int64, postLimit, commentLimit int) (models.UserActivity, error) {
+	return m.ScrapeUserActivityFunc(ctx, username, sinceTimestamp, postLimit, commentLimit)
+}
+
+func (m *MockScraperService) ScrapeUserOverview(ctx context.Context, username string, sinceTimestamp int64, limit int) (models.UserOverview, error) {
+	return m.ScrapeUserOverviewFunc(ctx, username, sinceTimestamp, limit)
+}
+
+func (m *MockScraperService) ScrapePost(ctx context.Context, postID string) (models.PostDetail, error) {
+	return m.ScrapePostFunc(ctx, postID)
+}
+
+func (m *MockScraperService) Search(ctx context.Context, searchParams map[string]string, sinceTimestamp int64, limit int, opts scraper.ListingOptions) ([]models.Post, models.ListingMeta, error) {
+	return m.SearchFunc(ctx, searchParams, sinceTimestamp, limit, opts)
+}
+
+func (m *MockScraperService) ScrapeFrontpage(ctx context.Context, feed string, params map[string]string, limit int, opts scraper.ListingOptions) ([]models.Post, models.ListingMeta, error) {
+	return m.ScrapeFrontpageFunc(ctx, feed, params, limit, opts)
+}
@@ -0,0 +1,143 @@
+package pagecache_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"reddit-ingestion/internal/pagecache"
+	"reddit-ingestion/pkg/client"
+	"reddit-ingestion/testing/mocks"
+)
+
+func TestNormalizeURL(t *testing.T) {
+	a := pagecache.NormalizeURL("https://OLD.reddit.com/r/test/new.json?raw_json=1&limit=100&after=t3_x")
+	b := pagecache.NormalizeURL("https://old.reddit.com/r/test/new.json?after=t3_x&limit=100&raw_json=1#top")
+
+	if a != b {
+		t.Errorf("Expected equivalent URLs to normalize identically, got %s and %s", a, b)
+	}
+}
+
+func TestCachedPageIsReused(t *testing.T) {
+	fetchCount := 0
+	mockClient := &mocks.MockRedditClient{
+		FetchJSONFunc: func(ctx context.Context, url string) (json.RawMessage, error) {
+			fetchCount++
+			return json.RawMessage(`{"data":{"children":[]}}`), nil
+		},
+	}
+
+	c, err := pagecache.WrapClient(mockClient, t.TempDir())
+	if err != nil {
+		t.Fatalf("Failed to create page cache: %v", err)
+	}
+
+	for i := 0; i < 2; i++ {
+		data, err := c.FetchJSON(context.Background(), "https://old.reddit.com/r/test/new.json?limit=100&raw_json=1")
+		if err != nil {
+			t.Fatalf("FetchJSON returned error: %v", err)
+		}
+		if string(data) != `{"data":{"children":[]}}` {
+			t.Errorf("Unexpected body: %s", data)
+		}
+	}
+
+	if fetchCount != 1 {
+		t.Errorf("Expected 1 upstream fetch, got %d", fetchCount)
+	}
+}
+
+func TestRefetchBypassesCache(t *testing.T) {
+	fetchCount := 0
+	mockClient := &mocks.MockRedditClient{
+		FetchJSONFunc: func(ctx context.Context, url string) (json.RawMessage, error) {
+			fetchCount++
+			return json.RawMessage(fmt.Sprintf(`{"fetch":%d}`, fetchCount)), nil
+		},
+	}
+
+	c, err := pagecache.WrapClient(mockClient, t.TempDir())
+	if err != nil {
+		t.Fatalf("Failed to create page cache: %v", err)
+	}
+
+	pageURL := "https://old.reddit.com/r/test/new.json?limit=100&raw_json=1"
+	if _, err := c.FetchJSON(context.Background(), pageURL); err != nil {
+		t.Fatalf("FetchJSON returned error: %v", err)
+	}
+	if _, err := c.FetchJSON(client.WithRefetch(context.Background()), pageURL); err != nil {
+		t.Fatalf("FetchJSON returned error: %v", err)
+	}
+
+	// The refetched copy replaces the cached one
+	data, err := c.FetchJSON(context.Background(), pageURL)
+	if err != nil {
+		t.Fatalf("FetchJSON returned error: %v", err)
+	}
+	if fetchCount != 2 || string(data) != `{"fetch":2}` {
+		t.Errorf("Expected 2 upstream fetches and the refetched page, got %d and %s", fetchCount, data)
+	}
+}
+
+func TestConcurrentFetchesOfAPageLeaveAWholeCopy(t *testing.T) {
+	const fetches = 8
+	var mu sync.Mutex
+	fetchCount := 0
+	mockClient := &mocks.MockRedditClient{
+		FetchJSONFunc: func(ctx context.Context, url string) (json.RawMessage, error) {
+			mu.Lock()
+			fetchCount++
+			n := fetchCount
+			mu.Unlock()
+			// Large and of a different size for each fetch, so overlapping
+			// writes of one file would mix them up
+			return json.RawMessage(fmt.Sprintf(`{"fetch":%d,"pad":"%s"}`, n, strings.Repeat("x", 1<<20+n*1000))), nil
+		},
+	}
+
+	dir := t.TempDir()
+	c, err := pagecache.WrapClient(mockClient, dir)
+	if err != nil {
+		t.Fatalf("Failed to create page cache: %v", err)
+	}
+
+	pageURL := "https://old.reddit.com/r/test/new.json?limit=100&raw_json=1"
+	var wg sync.WaitGroup
+	for i := 0; i < fetches; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := c.FetchJSON(client.WithRefetch(context.Background()), pageURL); err != nil {
+				t.Errorf("FetchJSON returned error: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	data, err := c.FetchJSON(context.Background(), pageURL)
+	if err != nil {
+		t.Fatalf("FetchJSON returned error: %v", err)
+	}
+	var page struct {
+		Fetch int    `json:"fetch"`
+		Pad   string `json:"pad"`
+	}
+	if err := json.Unmarshal(data, &page); err != nil || page.Fetch < 1 || len(page.Pad) != 1<<20+page.Fetch*1000 {
+		t.Errorf("Expected one whole fetched page in the cache, got %d bytes (%v)", len(data), err)
+	}
+
+	// Only the page itself is left behind
+	files, _ := filepath.Glob(filepath.Join(dir, "*", "*"))
+	if len(files) != 1 || filepath.Base(files[0]) != pagecache.Key(pageURL)+".json" {
+		t.Fatalf("Expected only the cached page on disk, got %v", files)
+	}
+	if info, err := os.Stat(files[0]); err == nil && info.Mode().Perm() != 0644 {
+		t.Errorf("Expected the cached page to be readable by all, got %v", info.Mode())
+	}
+}
@@ -3,10 +3,14 @@ package parser_test
 import (
 	"context"
 	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 	
-	"reddit-ingestion/internal/parser"
+	"reddit-ingestion/pkg/models"
+	"reddit-ingestion/pkg/parser"
 )
 
 func TestParseSubreddit(t *testing.T) {
@@ -93,4 +97,314 @@ func TestParseUserInfo(t *testing.T) {
 	if !userInfo.CreatedAt.Equal(expectedTime) {
 		t.Errorf("Expected creation time %v, got %v", expectedTime, userInfo.CreatedAt)
 	}
-}
\ No newline at end of file
+}
+func TestStreamSubredditStopsEarly(t *testing.T) {
+	p := parser.NewRedditParser()
+
+	data := `{"kind":"Listing","data":{"after":"t3_c","dist":3,"children":[
+		{"kind":"t3","data":{"id":"a","title":"A","created_utc":1620000003}},
+		{"kind":"t3","data":{"id":"b","title":"B","created_utc":1620000002}},
+		{"kind":"t3","data":{"id":"c","title":"C","created_utc":1620000001}}
+	],"before":null}}`
+
+	var ids []string
+	after, err := p.StreamSubreddit(context.Background(), strings.NewReader(data), func(post models.Post) bool {
+		ids = append(ids, post.ID)
+		return len(ids) < 2
+	})
+	if err != nil {
+		t.Fatalf("Failed to stream subreddit: %v", err)
+	}
+	if strings.Join(ids, ",") != "a,b" {
+		t.Errorf("Expected streaming to stop after a,b, got %v", ids)
+	}
+	if after != "t3_c" {
+		t.Errorf("Expected cursor t3_c read before the children, got %q", after)
+	}
+
+	posts, after, err := p.ParseSubreddit(context.Background(), json.RawMessage(`{"kind":"Listing","data":null}`))
+	if err != nil || len(posts) != 0 || after != "" {
+		t.Errorf("Expected an empty listing for null data, got %v, %q, %v", posts, after, err)
+	}
+
+	if _, _, err := p.ParseSubreddit(context.Background(), json.RawMessage(`{"data":{"children":[{"kind":"t3"`)); err == nil {
+		t.Error("Expected an error for a truncated listing")
+	}
+}
+
+func TestParserUsesCanonicalHost(t *testing.T) {
+	p := parser.NewRedditParserWithOptions(parser.ParserOptions{
+		CanonicalHost: "www.reddit.com",
+		SourceHost:    "old.reddit.com",
+	})
+	ctx := context.Background()
+	child := `{"kind":"t3","data":{"id":"abc123","permalink":"/r/test/comments/abc123/test_post/"}}`
+	want := "https://www.reddit.com/r/test/comments/abc123/test_post/"
+
+	posts, _, err := p.ParseSubreddit(ctx, json.RawMessage(`{"data":{"children":[`+child+`]}}`))
+	if err != nil || len(posts) != 1 {
+		t.Fatalf("Failed to parse subreddit: %v", err)
+	}
+	detail, err := p.ParsePost(ctx, json.RawMessage(`{"data":{"children":[`+child+`]}}`), json.RawMessage(`{"data":{"children":[]}}`))
+	if err != nil {
+		t.Fatalf("Failed to parse post: %v", err)
+	}
+	userPosts, _, err := p.ParseUserPosts(ctx, json.RawMessage(`{"data":{"children":[`+child+`]}}`))
+	if err != nil || len(userPosts) != 1 {
+		t.Fatalf("Failed to parse user posts: %v", err)
+	}
+
+	for name, got := range map[string]models.Post{
+		"listing": posts[0],
+		"post":    detail.Post,
+		"user":    {URL: userPosts[0].URL, SourceHost: userPosts[0].SourceHost},
+	} {
+		if got.URL != want || got.SourceHost != "old.reddit.com" {
+			t.Errorf("%s: expected %s from old.reddit.com, got %s from %q", name, want, got.URL, got.SourceHost)
+		}
+	}
+}
+
+func TestParserKeepsPostSubreddit(t *testing.T) {
+	p := parser.NewRedditParser()
+	ctx := context.Background()
+	listing := json.RawMessage(`{"data":{"children":[{"kind":"t3","data":{"id":"abc123","subreddit":"golang","subreddit_id":"t5_2rc7j","subreddit_subscribers":250000}}]}}`)
+
+	posts, _, err := p.ParseSubreddit(ctx, listing)
+	if err != nil || len(posts) != 1 {
+		t.Fatalf("Failed to parse subreddit: %v", err)
+	}
+	detail, err := p.ParsePost(ctx, listing, json.RawMessage(`{"data":{"children":[]}}`))
+	if err != nil {
+		t.Fatalf("Failed to parse post: %v", err)
+	}
+
+	for name, got := range map[string]models.Post{"listing": posts[0], "post": detail.Post} {
+		if got.Subreddit != "golang" || got.SubredditID != "t5_2rc7j" || got.SubredditSubscribers != 250000 {
+			t.Errorf("%s: expected the post's subreddit, got %q %q %d", name, got.Subreddit, got.SubredditID, got.SubredditSubscribers)
+		}
+	}
+}
+
+func TestParserIncludesAwardsOnlyWhenRequested(t *testing.T) {
+	p := parser.NewRedditParser()
+	awardings := `"all_awardings":[{"name":"Wholesome","count":3,"coin_price":125,"icon_url":"https://example.com/w.png"},{"name":"Gold","count":1,"coin_price":500}]`
+	listing := json.RawMessage(`{"data":{"children":[{"kind":"t3","data":{"id":"abc123",` + awardings + `}}]}}`)
+	comments := json.RawMessage(`{"data":{"children":[{"kind":"t1","data":{"id":"c1",` + awardings + `}}]}}`)
+
+	detail, err := p.ParsePost(context.Background(), listing, comments)
+	if err != nil {
+		t.Fatalf("Failed to parse post: %v", err)
+	}
+	if detail.Post.Awards != nil || detail.Comments[0].Awards != nil {
+		t.Errorf("Expected no awards without WithAwards, got %v and %v", detail.Post.Awards, detail.Comments[0].Awards)
+	}
+
+	ctx := parser.WithAwards(context.Background())
+	posts, _, err := p.ParseSubreddit(ctx, listing)
+	if err != nil || len(posts) != 1 {
+		t.Fatalf("Failed to parse subreddit: %v", err)
+	}
+	detail, err = p.ParsePost(ctx, listing, comments)
+	if err != nil {
+		t.Fatalf("Failed to parse post: %v", err)
+	}
+
+	expected := []models.Award{{Name: "Wholesome", Count: 3, CoinPrice: 125}, {Name: "Gold", Count: 1, CoinPrice: 500}}
+	for name, got := range map[string][]models.Award{"listing": posts[0].Awards, "post": detail.Post.Awards, "comment": detail.Comments[0].Awards} {
+		if len(got) != len(expected) || got[0] != expected[0] || got[1] != expected[1] {
+			t.Errorf("%s: expected awards %v, got %v", name, expected, got)
+		}
+	}
+}
+
+func TestParserCategorizesFlairs(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "categories.json")
+	if err := os.WriteFile(path, []byte(`{"GoLang": {"Bug": "bug", " bug report ": "bug", "Discussion": "discussion"}}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	mappings, err := parser.ReadFlairCategories(path)
+	if err != nil {
+		t.Fatalf("Failed to read flair categories: %v", err)
+	}
+	categories := parser.NewFlairCategories(mappings)
+	p := parser.NewRedditParserWithOptions(parser.ParserOptions{Categories: categories})
+
+	listing := json.RawMessage(`{"data":{"children":[
+		{"kind":"t3","data":{"id":"a","subreddit":"golang","link_flair_text":"Bug Report"}},
+		{"kind":"t3","data":{"id":"b","subreddit":"golang","link_flair_text":"Meta"}},
+		{"kind":"t3","data":{"id":"c","subreddit":"rust","link_flair_text":"Bug"}}
+	]}}`)
+	posts, _, err := p.ParseSubreddit(context.Background(), listing)
+	if err != nil || len(posts) != 3 {
+		t.Fatalf("Failed to parse subreddit: %v", err)
+	}
+	if posts[0].Category != "bug" || posts[1].Category != "" || posts[2].Category != "" {
+		t.Errorf("Expected only the mapped golang flair to get a category, got %q %q %q", posts[0].Category, posts[1].Category, posts[2].Category)
+	}
+
+	// A reload swaps the mappings under a running parser
+	categories.Set(map[string]map[string]string{"golang": {"Meta": "meta"}})
+	posts, _, _ = p.ParseSubreddit(context.Background(), listing)
+	if posts[0].Category != "" || posts[1].Category != "meta" {
+		t.Errorf("Expected the new mappings after Set, got %q %q", posts[0].Category, posts[1].Category)
+	}
+
+	if err := os.WriteFile(path, []byte(`{"golang": {"Bug": ""}}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := parser.ReadFlairCategories(path); err == nil {
+		t.Error("Expected an empty category to be rejected")
+	}
+}
+
+func TestParseUserOverviewInterleavesPostsAndComments(t *testing.T) {
+	p := parser.NewRedditParser()
+
+	data := []byte(`{
+		"data": {
+			"children": [
+				{"kind": "t1", "data": {"id": "c2", "body": "newest", "score": 3, "created_utc": 1620000300, "subreddit": "golang", "link_id": "t3_p1", "link_title": "Generics"}},
+				{"kind": "t3", "data": {"id": "p1", "title": "Generics", "selftext": "thoughts?", "score": 42, "created_utc": 1620000200, "subreddit": "golang", "permalink": "/r/golang/comments/p1/generics/"}},
+				{"kind": "t1", "data": {"id": "c1", "body": "oldest", "score": 1, "created_utc": 1620000100, "subreddit": "rust", "link_id": "t3_p0", "link_title": "Traits"}}
+			],
+			"after": "t1_c1"
+		}
+	}`)
+
+	items, after, err := p.ParseUserOverview(context.Background(), json.RawMessage(data))
+	if err != nil {
+		t.Fatalf("Failed to parse user overview: %v", err)
+	}
+	if after != "t1_c1" {
+		t.Errorf("Expected cursor t1_c1, got %q", after)
+	}
+	if len(items) != 3 {
+		t.Fatalf("Expected 3 items, got %d", len(items))
+	}
+	if items[0].ID != "c2" || items[1].ID != "p1" || items[2].ID != "c1" {
+		t.Errorf("Expected items newest first, got %s, %s, %s", items[0].ID, items[1].ID, items[2].ID)
+	}
+	if items[1].Kind != models.OverviewKindPost || items[1].Post == nil || items[1].Post.Title != "Generics" {
+		t.Errorf("Expected the post in its envelope, got %+v", items[1])
+	}
+	if items[0].Kind != models.OverviewKindComment || items[0].Comment == nil || items[0].Comment.PostID != "p1" || items[0].Subreddit != "golang" {
+		t.Errorf("Expected the comment in its envelope, got %+v", items[0])
+	}
+}
+
+func TestParsersSkipMalformedChildren(t *testing.T) {
+	p := parser.NewRedditParser()
+	ctx, report := parser.WithReport(context.Background())
+
+	listing := []byte(`{"data": {"after": "t3_c", "children": [
+		{"kind": "t3", "data": {"id": "a", "title": "fine", "score": 1}},
+		{"kind": "t3", "data": {"id": "b", "title": "odd", "score": "lots"}},
+		{"kind": "t3", "data": {"id": "c", "title": "fine too", "score": 3}}
+	]}}`)
+	posts, after, err := p.ParseSubreddit(ctx, listing)
+	if err != nil {
+		t.Fatalf("ParseSubreddit: %v", err)
+	}
+	if len(posts) != 2 || posts[0].ID != "a" || posts[1].ID != "c" || after != "t3_c" {
+		t.Fatalf("got %d posts and cursor %q, want a and c and t3_c", len(posts), after)
+	}
+
+	comments := []byte(`{"data": {"children": [
+		{"kind": "t1", "data": {"id": "c1", "body": "fine", "created_utc": "yesterday"}},
+		{"kind": "t1", "data": {"id": "c2", "body": "fine", "replies": {"data": {"children": [
+			{"kind": "t1", "data": {"id": "c3", "body": "reply", "score": []}},
+			{"kind": "t1", "data": {"id": "c4", "body": "reply"}}
+		]}}}}
+	]}}`)
+	post := []byte(`{"data": {"children": [{"kind": "t3", "data": {"id": "p", "title": "post"}}]}}`)
+	detail, err := p.ParsePost(ctx, post, comments)
+	if err != nil {
+		t.Fatalf("ParsePost: %v", err)
+	}
+	if len(detail.Comments) != 1 || detail.Comments[0].ID != "c2" ||
+		len(detail.Comments[0].Replies) != 1 || detail.Comments[0].Replies[0].ID != "c4" {
+		t.Fatalf("got comments %+v, want c2 with reply c4", detail.Comments)
+	}
+
+	overview := []byte(`{"data": {"children": [
+		{"kind": "t3", "data": {"id": "d", "title": "post", "created_utc": 20}},
+		{"kind": "t1", "data": {"id": "e", "body": "comment", "link_id": 7}},
+		"not a thing"
+	]}}`)
+	items, _, err := p.ParseUserOverview(ctx, overview)
+	if err != nil {
+		t.Fatalf("ParseUserOverview: %v", err)
+	}
+	if len(items) != 1 || items[0].ID != "d" {
+		t.Fatalf("got %d overview items, want post d", len(items))
+	}
+
+	result := report.Result()
+	// b, c1, c3, e and the string child
+	if result == nil || result.Skipped != 5 {
+		t.Fatalf("report = %+v, want 5 skipped", result)
+	}
+	if len(result.Errors) != 5 {
+		t.Errorf("report kept %d errors, want a sample of 5", len(result.Errors))
+	}
+	if !strings.Contains(result.Errors[0], "t3 b:") || !strings.Contains(result.Errors[0], "score") {
+		t.Errorf("first error = %q, want it to name post b and its score field", result.Errors[0])
+	}
+}
+
+func TestParseReportEmptyWhenNothingSkipped(t *testing.T) {
+	ctx, report := parser.WithReport(context.Background())
+	data := []byte(`{"data": {"children": [{"kind": "t3", "data": {"id": "a"}}]}}`)
+	if _, _, err := parser.NewRedditParser().ParseSubreddit(ctx, data); err != nil {
+		t.Fatalf("ParseSubreddit: %v", err)
+	}
+	if result := report.Result(); result != nil {
+		t.Errorf("report = %+v, want nil", result)
+	}
+}
+
+func TestParserKeepsCreatedUTCInUTC(t *testing.T) {
+	data := []byte(`{"data": {"children": [{"kind": "t3", "data": {"id": "a", "created_utc": 1700000000.25}}]}}`)
+	posts, _, err := parser.NewRedditParser().ParseSubreddit(context.Background(), data)
+	if err != nil || len(posts) != 1 {
+		t.Fatalf("ParseSubreddit: %d posts, %v", len(posts), err)
+	}
+	post := posts[0]
+	if post.CreatedAt.Location() != time.UTC {
+		t.Errorf("created_at is in %v, want UTC", post.CreatedAt.Location())
+	}
+	if want := time.Date(2023, 11, 14, 22, 13, 20, 250_000_000, time.UTC); !post.CreatedAt.Equal(want) {
+		t.Errorf("created_at = %v, want %v", post.CreatedAt, want)
+	}
+	if post.CreatedUTC != 1700000000.25 {
+		t.Errorf("created_utc = %v, want 1700000000.25", post.CreatedUTC)
+	}
+
+	encoded, _ := json.Marshal(post)
+	if !strings.Contains(string(encoded), `"created_at":"2023-11-14T22:13:20.25Z","created_utc":1700000000.25`) {
+		t.Errorf("encoded post = %s, want both timestamps", encoded)
+	}
+}
+
+func TestParsePostKeepsMoreCount(t *testing.T) {
+	p := parser.NewRedditParser()
+	comments := []byte(`{"data": {"children": [
+		{"kind": "t1", "data": {"id": "c1", "body": "top", "replies": {"data": {"children": [
+			{"kind": "more", "data": {"id": "m2", "count": 7, "children": ["c5", "c6"]}}
+		]}}}},
+		{"kind": "more", "data": {"id": "m1", "count": 42, "children": ["c2", "c3", "c4"]}}
+	]}}`)
+	post := []byte(`{"data": {"children": [{"kind": "t3", "data": {"id": "p", "title": "post"}}]}}`)
+	detail, err := p.ParsePost(context.Background(), post, comments)
+	if err != nil {
+		t.Fatalf("ParsePost: %v", err)
+	}
+	if len(detail.Comments) != 2 || !detail.Comments[1].IsMore || detail.Comments[1].MoreCount != 42 {
+		t.Fatalf("got comments %+v, want c1 and a placeholder counting 42", detail.Comments)
+	}
+	replies := detail.Comments[0].Replies
+	if len(replies) != 1 || !replies[0].IsMore || replies[0].MoreCount != 7 {
+		t.Errorf("got replies %+v, want a placeholder counting 7", replies)
+	}
+}
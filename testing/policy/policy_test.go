@@ -0,0 +1,168 @@
+package policy_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"reddit-ingestion/internal/audit"
+	handler "reddit-ingestion/internal/handler/http"
+	"reddit-ingestion/internal/policy"
+	"reddit-ingestion/pkg/models"
+	"reddit-ingestion/pkg/scraper"
+	"reddit-ingestion/testing/mocks"
+)
+
+func TestBlocklistNormalizesNames(t *testing.T) {
+	b := policy.NewBlocklist([]string{"r/Private"}, []string{"/u/SomeUser"})
+
+	if !b.SubredditBlocked("private") || !b.SubredditBlocked("PRIVATE") {
+		t.Error("Expected subreddit match to ignore case and r/ prefix")
+	}
+	if !b.UserBlocked("someuser") {
+		t.Error("Expected user match to ignore case and u/ prefix")
+	}
+	if b.SubredditBlocked("golang") || b.UserBlocked("") {
+		t.Error("Expected unlisted names not to be blocked")
+	}
+}
+
+func TestBlockedScrapesNeverReachScraper(t *testing.T) {
+	called := false
+	inner := &mocks.MockScraperService{
+		ScrapeSubredditFunc: func(ctx context.Context, subreddit string, sinceTimestamp int64, limit int, opts scraper.ListingOptions) ([]models.Post, models.ListingMeta, error) {
+			called = true
+			return nil, models.ListingMeta{}, nil
+		},
+		SearchFunc: func(ctx context.Context, searchParams map[string]string, sinceTimestamp int64, limit int, opts scraper.ListingOptions) ([]models.Post, models.ListingMeta, error) {
+			called = true
+			return nil, models.ListingMeta{}, nil
+		},
+	}
+	svc := policy.WrapService(inner, policy.NewBlocklist([]string{"private"}, []string{"someuser"}))
+
+	if _, _, err := svc.ScrapeSubreddit(context.Background(), "Private", 0, 10, scraper.ListingOptions{}); !errors.Is(err, policy.ErrBlocked) {
+		t.Errorf("Expected ErrBlocked for subreddit, got %v", err)
+	}
+	if _, _, err := svc.Search(context.Background(), map[string]string{"subreddit": "golang+private"}, 0, 10, scraper.ListingOptions{}); !errors.Is(err, policy.ErrBlocked) {
+		t.Errorf("Expected ErrBlocked for multireddit search, got %v", err)
+	}
+	if called {
+		t.Error("Expected blocked requests not to reach the scraper")
+	}
+}
+
+func TestBlockedPostIsDiscarded(t *testing.T) {
+	inner := &mocks.MockScraperService{
+		ScrapePostFunc: func(ctx context.Context, postID string) (models.PostDetail, error) {
+			return models.PostDetail{Post: models.Post{
+				ID:  postID,
+				URL: "https://reddit.com/r/Private/comments/" + postID + "/title/",
+			}}, nil
+		},
+	}
+	svc := policy.WrapService(inner, policy.NewBlocklist([]string{"private"}, nil))
+
+	detail, err := svc.ScrapePost(context.Background(), "abc")
+	if !errors.Is(err, policy.ErrBlocked) {
+		t.Errorf("Expected ErrBlocked for post in blocked subreddit, got %v", err)
+	}
+	if detail.Post.ID != "" {
+		t.Error("Expected blocked post content to be discarded")
+	}
+}
+
+func TestBlockedPostsAreDroppedFromFrontpage(t *testing.T) {
+	inner := &mocks.MockScraperService{
+		ScrapeFrontpageFunc: func(ctx context.Context, feed string, params map[string]string, limit int, opts scraper.ListingOptions) ([]models.Post, models.ListingMeta, error) {
+			return []models.Post{
+				{ID: "a", Subreddit: "golang", Author: "gopher"},
+				{ID: "b", Subreddit: "Private", Author: "gopher"},
+				{ID: "c", Subreddit: "rust", Author: "SomeUser"},
+			}, models.ListingMeta{}, nil
+		},
+	}
+	svc := policy.WrapService(inner, policy.NewBlocklist([]string{"private"}, []string{"someuser"}))
+
+	posts, _, err := svc.ScrapeFrontpage(context.Background(), scraper.FeedAll, map[string]string{"sort": "hot"}, 25, scraper.ListingOptions{})
+	if err != nil {
+		t.Fatalf("ScrapeFrontpage returned error: %v", err)
+	}
+	if len(posts) != 1 || posts[0].ID != "a" {
+		t.Errorf("Expected only the unblocked post, got %+v", posts)
+	}
+}
+
+func TestBlockedRequestReturns403(t *testing.T) {
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodGet, "/user?username=someuser", nil)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+
+	svc := policy.WrapService(&mocks.MockScraperService{}, policy.NewBlocklist(nil, []string{"someuser"}))
+
+	err := handler.NewUserHandler(svc).GetUserInfo(c)
+
+	var httpErr *echo.HTTPError
+	if !errors.As(err, &httpErr) || httpErr.Code != http.StatusForbidden {
+		t.Errorf("Expected 403 error, got %v", err)
+	}
+}
+
+func TestManagedBlocklistPersists(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "blocklist.json")
+	b := policy.NewBlocklist([]string{"private"}, nil)
+	if err := b.UseFile(path); err != nil {
+		t.Fatalf("UseFile returned error for a missing file: %v", err)
+	}
+
+	if err := b.BlockSubreddit("r/Internal"); err != nil {
+		t.Fatalf("BlockSubreddit returned error: %v", err)
+	}
+	if err := b.BlockUser("u/SomeUser"); err != nil {
+		t.Fatalf("BlockUser returned error: %v", err)
+	}
+	if !b.SubredditBlocked("internal") || !b.UserBlocked("someuser") {
+		t.Error("Expected managed entries to be blocked")
+	}
+
+	reloaded := policy.NewBlocklist(nil, nil)
+	if err := reloaded.UseFile(path); err != nil {
+		t.Fatalf("UseFile returned error: %v", err)
+	}
+	if !reloaded.SubredditBlocked("internal") || !reloaded.UserBlocked("someuser") {
+		t.Error("Expected managed entries to be read back from the file")
+	}
+
+	if err := b.UnblockSubreddit("private"); !errors.Is(err, policy.ErrConfigured) {
+		t.Errorf("Expected ErrConfigured for a configured subreddit, got %v", err)
+	}
+	if err := b.UnblockUser("other"); !errors.Is(err, policy.ErrNotBlocked) {
+		t.Errorf("Expected ErrNotBlocked for an unlisted user, got %v", err)
+	}
+	if err := b.UnblockSubreddit("internal"); err != nil {
+		t.Fatalf("UnblockSubreddit returned error: %v", err)
+	}
+	if b.SubredditBlocked("internal") {
+		t.Error("Expected unblocked subreddit to be allowed")
+	}
+}
+
+func TestRefusedScrapesAreAudited(t *testing.T) {
+	var buf bytes.Buffer
+	svc := policy.WrapServiceWithAudit(&mocks.MockScraperService{}, policy.NewBlocklist([]string{"private"}, nil), audit.NewLogger(&buf))
+
+	if _, _, err := svc.ScrapeSubreddit(context.Background(), "private", 0, 10, scraper.ListingOptions{}); !errors.Is(err, policy.ErrBlocked) {
+		t.Fatalf("Expected ErrBlocked, got %v", err)
+	}
+	line := buf.String()
+	if !strings.Contains(line, `"event":"refused"`) || !strings.Contains(line, `"target":"private"`) {
+		t.Errorf("Expected a refusal in the audit log, got %q", line)
+	}
+}
@@ -0,0 +1,65 @@
+package quality_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"reddit-ingestion/internal/quality"
+	"reddit-ingestion/pkg/models"
+	"reddit-ingestion/pkg/scraper"
+	"reddit-ingestion/testing/mocks"
+)
+
+func TestPostQualityCountsFieldsAndExpansion(t *testing.T) {
+	now := time.Now()
+	detail := models.PostDetail{
+		Post: models.Post{
+			ID: "abc123", Title: "Go 1.22", Author: "gopher", CreatedAt: now,
+			URL: "https://reddit.com/r/golang/comments/abc123/", Subreddit: "golang", NumComments: 4,
+		},
+		Comments: []models.Comment{
+			{ID: "c1", Author: "a", Body: "first", CreatedAt: now, Replies: []models.Comment{
+				{ID: "c2", Author: "b", Body: "", CreatedAt: now},
+			}},
+			{ID: "more1", IsMore: true, MoreIDs: []string{"c3", "c4"}},
+		},
+	}
+
+	q := quality.Post(detail)
+	// 13 of 14 fields (the reply has no body), 2 of 4 comments, no truncation
+	if q.Fields != 0.929 || q.Expansion == nil || *q.Expansion != 0.5 || q.Truncation != 1 || q.Score != 0.81 {
+		t.Errorf("Unexpected post quality %+v", q)
+	}
+}
+
+func TestServiceScoresResultsAndTracksThem(t *testing.T) {
+	inner := &mocks.MockScraperService{
+		ScrapeSubredditFunc: func(ctx context.Context, subreddit string, sinceTimestamp int64, limit int, opts scraper.ListingOptions) ([]models.Post, models.ListingMeta, error) {
+			posts := []models.Post{{ID: "a", Title: "t", Author: "u", CreatedAt: time.Now(), URL: "u", Subreddit: subreddit}}
+			return posts, models.ListingMeta{TimedOut: subreddit == "slow"}, nil
+		},
+	}
+	tracker := quality.NewTracker()
+	svc := quality.WrapService(inner, tracker)
+
+	_, meta, err := svc.ScrapeSubreddit(context.Background(), "golang", 0, 25, scraper.ListingOptions{})
+	if err != nil {
+		t.Fatalf("ScrapeSubreddit returned error: %v", err)
+	}
+	if meta.Quality == nil || meta.Quality.Score != 1 {
+		t.Errorf("Expected a complete listing to score 1, got %+v", meta.Quality)
+	}
+
+	// A timed out listing loses its truncation part
+	_, meta, _ = svc.ScrapeSubreddit(context.Background(), "slow", 0, 25, scraper.ListingOptions{})
+	if meta.Quality.Score != 0.5 {
+		t.Errorf("Expected a timed out listing to score 0.5, got %+v", meta.Quality)
+	}
+
+	stats := tracker.QualityStats()
+	if len(stats) != 1 || stats[0].Kind != "subreddit" || stats[0].Responses != 2 ||
+		stats[0].Mean != 0.75 || stats[0].Min != 0.5 || stats[0].Last != 0.5 || stats[0].Truncated != 1 {
+		t.Errorf("Unexpected stats %+v", stats)
+	}
+}
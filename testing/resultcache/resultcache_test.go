@@ -0,0 +1,207 @@
+package resultcache_test
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"reddit-ingestion/internal/resultcache"
+	"reddit-ingestion/pkg/models"
+	"reddit-ingestion/pkg/parser"
+	"reddit-ingestion/pkg/scraper"
+	"reddit-ingestion/testing/mocks"
+)
+
+func TestServiceReusesIdenticalPostScrapes(t *testing.T) {
+	scrapes := 0
+	inner := &mocks.MockScraperService{
+		ScrapePostFunc: func(ctx context.Context, postID string) (models.PostDetail, error) {
+			scrapes++
+			return models.PostDetail{
+				Post:     models.Post{ID: postID, Title: "Go 1.22"},
+				Comments: []models.Comment{{ID: "c1", Body: "first"}},
+			}, nil
+		},
+	}
+	svc := resultcache.WrapService(inner, resultcache.New(time.Minute, 10))
+	ctx := context.Background()
+
+	first, _ := svc.ScrapePost(ctx, "abc123")
+	// A caller changing its result must not change the cached one
+	first.Comments[0].Body = "[removed]"
+
+	second, err := svc.ScrapePost(ctx, "t3_ABC123")
+	if err != nil || scrapes != 1 {
+		t.Fatalf("Expected the second request to reuse the first scrape, got %d scrapes, %v", scrapes, err)
+	}
+	if !second.Cached || first.Cached || second.Comments[0].Body != "first" {
+		t.Errorf("Expected an untouched copy marked as cached, got %+v", second)
+	}
+
+	// Awards change the result, so they get a scrape of their own
+	if _, err := svc.ScrapePost(parser.WithAwards(ctx), "abc123"); err != nil || scrapes != 2 {
+		t.Errorf("Expected a scrape with awards, got %d scrapes, %v", scrapes, err)
+	}
+}
+
+func TestServiceCachesOnlyWholeResults(t *testing.T) {
+	scrapes := 0
+	inner := &mocks.MockScraperService{
+		ScrapeSubredditFunc: func(ctx context.Context, subreddit string, sinceTimestamp int64, limit int, opts scraper.ListingOptions) ([]models.Post, models.ListingMeta, error) {
+			scrapes++
+			return []models.Post{{ID: "p1"}}, models.ListingMeta{TimedOut: subreddit == "slow", PagesFetched: 1}, nil
+		},
+	}
+	svc := resultcache.WrapService(inner, resultcache.New(time.Minute, 10))
+	ctx := context.Background()
+
+	tests := []struct {
+		name      string
+		subreddit string
+		limit     int
+		opts      scraper.ListingOptions
+		wantNew   bool
+	}{
+		{"first", "golang", 25, scraper.ListingOptions{}, true},
+		{"same", "GoLang", 25, scraper.ListingOptions{}, false},
+		{"other limit", "golang", 50, scraper.ListingOptions{}, true},
+		{"other cursor", "golang", 25, scraper.ListingOptions{After: "t3_x"}, true},
+		{"other filter", "golang", 25, scraper.ListingOptions{Filter: scraper.PostFilter{Flair: []scraper.TextMatcher{scraper.ExactMatcher("News")}}}, true},
+		{"same filter", "golang", 25, scraper.ListingOptions{Filter: scraper.PostFilter{Flair: []scraper.TextMatcher{scraper.ExactMatcher("news")}}}, false},
+		{"full history", "golang", -1, scraper.ListingOptions{}, true},
+		{"full history again", "golang", -1, scraper.ListingOptions{}, true},
+		{"timed out", "slow", 25, scraper.ListingOptions{}, true},
+		{"timed out again", "slow", 25, scraper.ListingOptions{}, true},
+	}
+	for _, tt := range tests {
+		before := scrapes
+		_, meta, err := svc.ScrapeSubreddit(ctx, tt.subreddit, 0, tt.limit, tt.opts)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if scraped := scrapes > before; scraped != tt.wantNew || meta.Cached == tt.wantNew {
+			t.Errorf("%s: scraped %v with cached %v, want scraped %v", tt.name, scraped, meta.Cached, tt.wantNew)
+		}
+	}
+}
+
+func TestCacheExpiresAndEvicts(t *testing.T) {
+	scrapes := 0
+	inner := &mocks.MockScraperService{
+		ScrapePostFunc: func(ctx context.Context, postID string) (models.PostDetail, error) {
+			scrapes++
+			return models.PostDetail{Post: models.Post{ID: postID}}, nil
+		},
+	}
+	cache := resultcache.New(50*time.Millisecond, 2)
+	svc := resultcache.WrapService(inner, cache)
+	ctx := context.Background()
+
+	for _, id := range []string{"a", "b", "c", "c"} {
+		svc.ScrapePost(ctx, id)
+	}
+	if scrapes != 3 || cache.Len() != 2 {
+		t.Errorf("Expected 3 scrapes and 2 results held, got %d and %d", scrapes, cache.Len())
+	}
+
+	time.Sleep(60 * time.Millisecond)
+	svc.ScrapePost(ctx, "c")
+	if scrapes != 4 {
+		t.Errorf("Expected an expired result to be scraped again, got %d scrapes", scrapes)
+	}
+}
+
+func TestShareInFlightRunsConcurrentScrapesOnce(t *testing.T) {
+	var scrapes atomic.Int32
+	release := make(chan struct{})
+	inner := &mocks.MockScraperService{
+		ScrapePostFunc: func(ctx context.Context, postID string) (models.PostDetail, error) {
+			scrapes.Add(1)
+			<-release
+			return models.PostDetail{
+				Post:     models.Post{ID: postID},
+				Comments: []models.Comment{{ID: "c1", Body: "first"}},
+			}, nil
+		},
+	}
+	svc := resultcache.ShareInFlight(inner)
+
+	const callers = 5
+	results := make(chan models.PostDetail, callers)
+	var wg sync.WaitGroup
+	for i := 0; i < callers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			detail, err := svc.ScrapePost(context.Background(), "abc123")
+			if err != nil {
+				t.Errorf("Unexpected error: %v", err)
+			}
+			results <- detail
+		}()
+	}
+	// Let every caller join the scrape before it finishes
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+	close(results)
+
+	if n := scrapes.Load(); n != 1 {
+		t.Errorf("Expected one shared scrape, got %d", n)
+	}
+	for detail := range results {
+		if len(detail.Comments) != 1 || detail.Comments[0].Body != "first" {
+			t.Fatalf("Expected every caller to get the result, got %+v", detail)
+		}
+		// Each caller owns its copy
+		detail.Comments[0].Body = "[removed]"
+	}
+
+	// Once finished, the next request scrapes again
+	go func() { svc.ScrapePost(context.Background(), "abc123") }()
+	time.Sleep(20 * time.Millisecond)
+	if n := scrapes.Load(); n != 2 {
+		t.Errorf("Expected a new scrape after the shared one finished, got %d", n)
+	}
+}
+
+func TestShareInFlightRescrapesWhenTheFirstCallerCancels(t *testing.T) {
+	var scrapes atomic.Int32
+	started := make(chan struct{}, 2)
+	inner := &mocks.MockScraperService{
+		ScrapePostFunc: func(ctx context.Context, postID string) (models.PostDetail, error) {
+			if scrapes.Add(1) == 1 {
+				started <- struct{}{}
+				<-ctx.Done()
+				return models.PostDetail{}, ctx.Err()
+			}
+			return models.PostDetail{Post: models.Post{ID: postID}}, nil
+		},
+	}
+	svc := resultcache.ShareInFlight(inner)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go svc.ScrapePost(ctx, "abc123")
+	<-started
+
+	done := make(chan error, 1)
+	go func() {
+		detail, err := svc.ScrapePost(context.Background(), "abc123")
+		if err == nil && detail.Post.ID != "abc123" {
+			err = fmt.Errorf("unexpected result %+v", detail)
+		}
+		done <- err
+	}()
+	time.Sleep(20 * time.Millisecond)
+	cancel()
+
+	if err := <-done; err != nil {
+		t.Errorf("Expected the waiting caller to scrape on its own, got %v", err)
+	}
+	if n := scrapes.Load(); n != 2 {
+		t.Errorf("Expected 2 scrapes, got %d", n)
+	}
+}
@@ -0,0 +1,202 @@
+package scraper_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"testing"
+
+	"reddit-ingestion/pkg/models"
+	"reddit-ingestion/pkg/scraper"
+	"reddit-ingestion/testing/mocks"
+)
+
+// benchmarkPostID is the post the synthetic threads belong to
+const benchmarkPostID = "bench"
+
+// benchmarkSizes and benchmarkFanouts span the synthetic threads: comments
+// in the thread, and replies per comment
+var (
+	benchmarkSizes   = []int{10000, 50000, 100000}
+	benchmarkFanouts = []int{2, 10, 100}
+)
+
+// syntheticThread is a comment thread of size comments in which every
+// comment has fanout replies, filled level by level. Comment i is "c<i>",
+// the first fanout comments are top-level, and the replies of comment i are
+// the comments (i+1)*fanout up to (i+2)*fanout.
+type syntheticThread struct {
+	size   int
+	fanout int
+}
+
+func (t syntheticThread) id(i int) string {
+	return "c" + strconv.Itoa(i)
+}
+
+func (t syntheticThread) index(id string) int {
+	i, err := strconv.Atoi(strings.TrimPrefix(id, "c"))
+	if err != nil {
+		return -1
+	}
+	return i
+}
+
+// replies are the indexes of the replies of comment i, or of the top-level
+// comments when i is -1
+func (t syntheticThread) replies(i int) (from, to int) {
+	from = (i + 1) * t.fanout
+	return min(from, t.size), min(from+t.fanout, t.size)
+}
+
+func (t syntheticThread) comment(i int) models.Comment {
+	parent := "t3_" + benchmarkPostID
+	if i >= t.fanout {
+		parent = "t1_" + t.id(i/t.fanout-1)
+	}
+	return models.Comment{ID: t.id(i), ParentID: parent, Body: "comment " + t.id(i)}
+}
+
+// tree is the thread as the post fetch returns it with only the first loaded
+// comments in it. The replies left out of a loaded comment are behind a
+// "load more" placeholder at the end of its replies; loaded counts the
+// top-level comments in.
+func (t syntheticThread) tree(loaded int) []models.Comment {
+	loaded = max(loaded, min(t.fanout, t.size))
+	built := make([]models.Comment, loaded)
+	for i := loaded - 1; i >= -1; i-- {
+		from, to := t.replies(i)
+		var replies []models.Comment
+		var more []string
+		for j := from; j < to; j++ {
+			if j < loaded {
+				replies = append(replies, built[j])
+				built[j] = models.Comment{}
+			} else {
+				more = append(more, t.id(j))
+			}
+		}
+		if len(more) > 0 {
+			replies = append(replies, models.Comment{ID: "more_" + t.id(i), IsMore: true, MoreIDs: more})
+		}
+		if i < 0 {
+			return replies
+		}
+		built[i] = t.comment(i)
+		built[i].Replies = replies
+	}
+	return nil
+}
+
+// load is what a morechildren call for ids returns: those comments and every
+// comment below them, flat, parents first
+func (t syntheticThread) load(ids []string) []models.Comment {
+	var comments []models.Comment
+	queue := make([]int, 0, len(ids))
+	for _, id := range ids {
+		if i := t.index(id); i >= 0 && i < t.size {
+			queue = append(queue, i)
+		}
+	}
+	for len(queue) > 0 {
+		i := queue[0]
+		queue = queue[1:]
+		comments = append(comments, t.comment(i))
+		from, to := t.replies(i)
+		for j := from; j < to; j++ {
+			queue = append(queue, j)
+		}
+	}
+	return comments
+}
+
+// service scrapes the thread with the first loaded comments in the post
+func (t syntheticThread) service(loaded int) scraper.ScraperService {
+	comments := t.tree(loaded)
+	mockClient := &mocks.MockRedditClient{
+		GetPostURLFunc: func(postID string) string { return "post" },
+		FetchJSONFunc: func(ctx context.Context, url string) (json.RawMessage, error) {
+			return json.RawMessage(`[{},{}]`), nil
+		},
+		FetchMoreCommentsFunc: func(ctx context.Context, postID string, commentIDs []string) (json.RawMessage, error) {
+			return json.Marshal(commentIDs)
+		},
+	}
+	mockParser := &mocks.MockParser{
+		ParsePostFunc: func(ctx context.Context, postData, commentData json.RawMessage) (models.PostDetail, error) {
+			return models.PostDetail{Post: models.Post{ID: benchmarkPostID}, Comments: comments}, nil
+		},
+		ParseMoreCommentsFunc: func(ctx context.Context, data json.RawMessage) ([]models.Comment, error) {
+			var ids []string
+			if err := json.Unmarshal(data, &ids); err != nil {
+				return nil, err
+			}
+			return t.load(ids), nil
+		},
+	}
+	return scraper.NewScraperService(mockClient, mockParser)
+}
+
+// benchmarkScrapePost runs ScrapePost over every synthetic thread, with the
+// first loaded(size, fanout) comments in the post fetch. All "load more"
+// sets are taken in one round, so the pauses between rounds are not timed.
+func benchmarkScrapePost(b *testing.B, loaded func(size, fanout int) int) {
+	for _, size := range benchmarkSizes {
+		for _, fanout := range benchmarkFanouts {
+			b.Run(fmt.Sprintf("comments=%d/fanout=%d", size, fanout), func(b *testing.B) {
+				thread := syntheticThread{size: size, fanout: fanout}
+				svc := thread.service(loaded(size, fanout))
+				ctx := scraper.WithExpansion(context.Background(), scraper.ExpansionOptions{BatchSize: size})
+
+				b.ReportAllocs()
+				b.ResetTimer()
+				for i := 0; i < b.N; i++ {
+					detail, err := svc.ScrapePost(ctx, benchmarkPostID)
+					if err != nil {
+						b.Fatalf("ScrapePost returned error: %v", err)
+					}
+					if count := countTree(detail.Comments); count != size {
+						b.Fatalf("Expected %d comments, got %d", size, count)
+					}
+				}
+			})
+		}
+	}
+}
+
+// countTree counts the comments of a tree, placeholders included
+func countTree(comments []models.Comment) int {
+	count := 0
+	levels := [][]models.Comment{comments}
+	for len(levels) > 0 {
+		level := levels[len(levels)-1]
+		levels = levels[:len(levels)-1]
+		count += len(level)
+		for i := range level {
+			levels = append(levels, level[i].Replies)
+		}
+	}
+	return count
+}
+
+// BenchmarkScrapePostExpandsComments loads the deeper half of each thread
+// through the "load more" placeholders along its middle: many small sets,
+// coalesced into shared calls and each replacing its placeholder
+func BenchmarkScrapePostExpandsComments(b *testing.B) {
+	benchmarkScrapePost(b, func(size, fanout int) int { return size / 2 })
+}
+
+// BenchmarkScrapePostPlacesComments loads everything below the top level
+// through one set per top-level comment, so each call returns a whole
+// subtree flat for placement to nest by parent_id
+func BenchmarkScrapePostPlacesComments(b *testing.B) {
+	benchmarkScrapePost(b, func(size, fanout int) int { return fanout })
+}
+
+// BenchmarkScrapePostCountsComments scrapes threads that come complete, so
+// only indexing the tree and counting its comments are timed
+func BenchmarkScrapePostCountsComments(b *testing.B) {
+	benchmarkScrapePost(b, func(size, fanout int) int { return size })
+}
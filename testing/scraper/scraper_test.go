@@ -3,11 +3,22 @@ package scraper_test
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 	
-	"reddit-ingestion/internal/models"
-	"reddit-ingestion/internal/scraper"
+	"reddit-ingestion/pkg/client"
+	"reddit-ingestion/pkg/models"
+	"reddit-ingestion/pkg/parser"
+	"reddit-ingestion/pkg/scraper"
+	"reddit-ingestion/pkg/utils"
 	"reddit-ingestion/testing/mocks"
 )
 
@@ -52,7 +63,7 @@ func TestScrapeSubreddit(t *testing.T) {
 	svc := scraper.NewScraperService(mockClient, mockParser)
 	
 	// Test the service - explicitly set limit to 1 to control behavior
-	posts, err := svc.ScrapeSubreddit(context.Background(), "test", 0, 1)
+	posts, _, err := svc.ScrapeSubreddit(context.Background(), "test", 0, 1, scraper.ListingOptions{})
 	if err != nil {
 		t.Fatalf("Failed to scrape subreddit: %v", err)
 	}
@@ -64,4 +75,1468 @@ func TestScrapeSubreddit(t *testing.T) {
 	if len(posts) > 0 && posts[0].ID != "abcd123" {
 		t.Errorf("Expected post ID 'abcd123', got '%s'", posts[0].ID)
 	}
-}
\ No newline at end of file
+}
+func TestScrapeUserActivityWindowsMergeDuplicates(t *testing.T) {
+	mockClient := &mocks.MockRedditClient{
+		GetUserAboutURLFunc: func(username string) string {
+			return "https://old.reddit.com/user/" + username + "/about.json"
+		},
+		GetUserPostsURLFunc: func(username string, after string) string {
+			return "https://old.reddit.com/user/" + username + "/submitted/new.json?raw_json=1&sort=new"
+		},
+		GetUserCommentsURLFunc: func(username string, after string) string {
+			return "https://old.reddit.com/user/" + username + "/comments/.json?raw_json=1&limit=100"
+		},
+	}
+
+	var mu sync.Mutex
+	fetched := map[string]bool{}
+	mockClient.FetchJSONFunc = func(ctx context.Context, rawURL string) (json.RawMessage, error) {
+		mu.Lock()
+		fetched[rawURL] = true
+		mu.Unlock()
+		u, _ := url.Parse(rawURL)
+		page, _ := json.Marshal(map[string]string{"path": u.Path, "sort": u.Query().Get("sort")})
+		return page, nil
+	}
+
+	now := time.Now()
+	mockParser := &mocks.MockParser{
+		ParseUserInfoFunc: func(ctx context.Context, data json.RawMessage) (models.UserInfo, error) {
+			return models.UserInfo{Username: "gopher"}, nil
+		},
+		ParseUserPostsFunc: func(ctx context.Context, data json.RawMessage) ([]models.UserPost, string, error) {
+			var page map[string]string
+			json.Unmarshal(data, &page)
+			// Every window returns the shared post, and each also has one of its own
+			return []models.UserPost{
+				{ID: "shared", CreatedAt: now},
+				{ID: "only_" + page["sort"], CreatedAt: now.Add(-time.Hour)},
+			}, "", nil
+		},
+		ParseUserCommentsFunc: func(ctx context.Context, data json.RawMessage) ([]models.UserComment, string, error) {
+			return []models.UserComment{{ID: "c1"}}, "", nil
+		},
+	}
+
+	svc := scraper.NewScraperServiceWithOptions(mockClient, mockParser, scraper.ScraperOptions{UserWindowWorkers: 3})
+
+	activity, err := svc.ScrapeUserActivity(context.Background(), "gopher", 0, -1, -1)
+	if err != nil {
+		t.Fatalf("Failed to scrape user activity: %v", err)
+	}
+
+	// shared + new, top, controversial, hot (top appears in three windows)
+	if len(activity.Posts) != 5 {
+		t.Errorf("Expected 5 unique posts, got %d", len(activity.Posts))
+	}
+	if activity.Posts[0].ID != "shared" {
+		t.Errorf("Expected newest post first, got %s", activity.Posts[0].ID)
+	}
+	if len(activity.Comments) != 1 {
+		t.Errorf("Expected duplicate comments to merge, got %d", len(activity.Comments))
+	}
+
+	if !fetched["https://old.reddit.com/user/gopher/submitted.json?limit=100&raw_json=1&sort=top&t=all"] {
+		t.Errorf("Expected a top/all window on the plain submitted listing, fetched %v", fetched)
+	}
+}
+
+func TestScrapeUserActivityPinnedPostDoesNotCutOffNewest(t *testing.T) {
+	now := time.Now()
+	since := now.Add(-48 * time.Hour).Unix()
+
+	mockClient := &mocks.MockRedditClient{
+		GetUserAboutURLFunc:    func(username string) string { return "about" },
+		GetUserPostsURLFunc:    func(username string, after string) string { return "posts" + after },
+		GetUserCommentsURLFunc: func(username string, after string) string { return "comments" },
+		FetchJSONFunc: func(ctx context.Context, url string) (json.RawMessage, error) {
+			return json.RawMessage(`"` + url + `"`), nil
+		},
+	}
+	mockParser := &mocks.MockParser{
+		ParseUserInfoFunc: func(ctx context.Context, data json.RawMessage) (models.UserInfo, error) {
+			return models.UserInfo{Username: "gopher"}, nil
+		},
+		ParseUserPostsFunc: func(ctx context.Context, data json.RawMessage) ([]models.UserPost, string, error) {
+			switch string(data) {
+			case `"posts"`:
+				// A year-old pinned post leads the listing, ahead of recent posts
+				return []models.UserPost{
+					{ID: "pinned", Pinned: true, CreatedAt: now.AddDate(-1, 0, 0)},
+					{ID: "new1", CreatedAt: now.Add(-time.Hour)},
+				}, "t3_new1", nil
+			case `"postst3_new1"`:
+				return []models.UserPost{
+					{ID: "new2", CreatedAt: now.Add(-2 * time.Hour)},
+					{ID: "new3", CreatedAt: now.Add(-3 * time.Hour)},
+				}, "", nil
+			}
+			return nil, "", nil
+		},
+		ParseUserCommentsFunc: func(ctx context.Context, data json.RawMessage) ([]models.UserComment, string, error) {
+			return nil, "", nil
+		},
+	}
+
+	svc := scraper.NewScraperService(mockClient, mockParser)
+
+	activity, err := svc.ScrapeUserActivity(context.Background(), "gopher", since, 3, 0)
+	if err != nil {
+		t.Fatalf("Failed to scrape user activity: %v", err)
+	}
+
+	if len(activity.Posts) != 3 || activity.Posts[0].ID != "new1" || activity.Posts[2].ID != "new3" {
+		t.Errorf("Expected the three newest posts, got %+v", activity.Posts)
+	}
+}
+
+// streamingClient serves listing pages through StreamJSON and records how
+// much of each page the scraper actually read
+type streamingClient struct {
+	*mocks.MockRedditClient
+	page      string
+	bytesRead int
+}
+
+type countingReader struct {
+	r *strings.Reader
+	n *int
+}
+
+func (c countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	*c.n += n
+	return n, err
+}
+
+func (c *streamingClient) StreamJSON(ctx context.Context, url string) (io.ReadCloser, error) {
+	return io.NopCloser(countingReader{strings.NewReader(c.page), &c.bytesRead}), nil
+}
+
+func TestScrapeSubredditStreamsListingPages(t *testing.T) {
+	var children []string
+	for i := 0; i < 100; i++ {
+		children = append(children, fmt.Sprintf(`{"kind":"t3","data":{"id":"p%d","title":"%s","created_utc":%d}}`,
+			i, strings.Repeat("x", 200), 1700000000-i))
+	}
+	page := `{"kind":"Listing","data":{"after":"t3_p99","children":[` + strings.Join(children, ",") + `]}}`
+
+	c := &streamingClient{
+		MockRedditClient: &mocks.MockRedditClient{
+			GetSubredditURLFunc: func(subreddit string, limit int, after string) string {
+				return "https://old.reddit.com/r/" + subreddit + "/new.json"
+			},
+			FetchJSONFunc: func(ctx context.Context, url string) (json.RawMessage, error) {
+				t.Fatal("Expected the listing to be streamed, not fetched whole")
+				return nil, nil
+			},
+		},
+		page: page,
+	}
+
+	svc := scraper.NewScraperService(c, parser.NewRedditParser())
+	posts, _, err := svc.ScrapeSubreddit(context.Background(), "golang", 0, 5, scraper.ListingOptions{})
+	if err != nil {
+		t.Fatalf("Failed to scrape subreddit: %v", err)
+	}
+
+	if len(posts) != 5 || posts[0].ID != "p0" || posts[4].ID != "p4" {
+		t.Fatalf("Expected the newest 5 posts, got %d", len(posts))
+	}
+	if c.bytesRead >= len(page)/2 {
+		t.Errorf("Expected decoding to stop at the limit, read %d of %d bytes", c.bytesRead, len(page))
+	}
+}
+
+func TestScrapeSubredditDropsDuplicatesAcrossPages(t *testing.T) {
+	now := time.Now()
+	pages := map[string][]models.Post{
+		"": {
+			{ID: "a", CreatedAt: now},
+			{ID: "b", CreatedAt: now.Add(-time.Minute)},
+		},
+		"t3_b": {
+			// The listing shifted by one between the two fetches
+			{ID: "b", CreatedAt: now.Add(-time.Minute)},
+			{ID: "c", CreatedAt: now.Add(-2 * time.Minute)},
+		},
+	}
+	cursors := map[string]string{"": "t3_b", "t3_b": ""}
+
+	mockClient := &mocks.MockRedditClient{
+		GetSubredditURLFunc: func(subreddit string, limit int, after string) string {
+			return after
+		},
+		FetchJSONFunc: func(ctx context.Context, url string) (json.RawMessage, error) {
+			return json.RawMessage(fmt.Sprintf("%q", url)), nil
+		},
+	}
+	mockParser := &mocks.MockParser{
+		ParseSubredditFunc: func(ctx context.Context, data json.RawMessage) ([]models.Post, string, error) {
+			var after string
+			json.Unmarshal(data, &after)
+			return pages[after], cursors[after], nil
+		},
+	}
+
+	svc := scraper.NewScraperService(mockClient, mockParser)
+	posts, meta, err := svc.ScrapeSubreddit(context.Background(), "golang", 0, -1, scraper.ListingOptions{})
+	if err != nil {
+		t.Fatalf("Failed to scrape subreddit: %v", err)
+	}
+
+	if len(posts) != 3 || posts[0].ID != "a" || posts[1].ID != "b" || posts[2].ID != "c" {
+		t.Errorf("Expected posts a, b, c once each, got %+v", posts)
+	}
+	if meta.DuplicatesDropped != 1 {
+		t.Errorf("Expected 1 duplicate dropped, got %d", meta.DuplicatesDropped)
+	}
+}
+
+func TestScrapeSubredditFetchesNextPageWhileFiltering(t *testing.T) {
+	now := time.Now()
+	secondRequested := make(chan struct{})
+	var once sync.Once
+	var fetches int32
+	mockClient := &mocks.MockRedditClient{
+		GetSubredditURLFunc: func(subreddit string, limit int, after string) string {
+			return "r/" + subreddit + "?after=" + after
+		},
+		FetchJSONFunc: func(ctx context.Context, url string) (json.RawMessage, error) {
+			atomic.AddInt32(&fetches, 1)
+			switch url {
+			case "r/golang?after=":
+				return json.RawMessage(`{"data":{"after":"t3_a","children":[]}}`), nil
+			case "r/golang?after=t3_a":
+				once.Do(func() { close(secondRequested) })
+				return json.RawMessage(`{"data":{"after":"t3_b","children":[]}}`), nil
+			}
+			return json.RawMessage(`{"data":{"after":null,"children":[]}}`), nil
+		},
+	}
+	mockParser := &mocks.MockParser{
+		ParseSubredditFunc: func(ctx context.Context, data json.RawMessage) ([]models.Post, string, error) {
+			switch {
+			case strings.Contains(string(data), `"t3_a"`):
+				// The second page must already be on its way while the first is filtered
+				select {
+				case <-secondRequested:
+				case <-time.After(2 * time.Second):
+					t.Error("Expected the second page to be requested while filtering the first")
+				}
+				return []models.Post{{ID: "a", CreatedAt: now.Add(-time.Hour)}}, "t3_a", nil
+			case strings.Contains(string(data), `"t3_b"`):
+				return []models.Post{{ID: "b", CreatedAt: now.Add(-2 * time.Hour)}}, "", nil
+			}
+			t.Errorf("Unexpected page %s", data)
+			return nil, "", nil
+		},
+	}
+
+	svc := scraper.NewScraperService(mockClient, mockParser)
+
+	posts, _, err := svc.ScrapeSubreddit(context.Background(), "golang", 0, -1, scraper.ListingOptions{})
+	if err != nil {
+		t.Fatalf("Failed to scrape subreddit: %v", err)
+	}
+	if len(posts) != 2 || posts[0].ID != "a" || posts[1].ID != "b" {
+		t.Errorf("Expected posts a and b, got %+v", posts)
+	}
+	// The listing ended with the second page, so nothing was requested past it
+	if n := atomic.LoadInt32(&fetches); n != 2 {
+		t.Errorf("Expected 2 requests, got %d", n)
+	}
+}
+
+// historyFunc adapts a function to scraper.HistoryProvider
+type historyFunc func(ctx context.Context, subreddit string, from, to time.Time) ([]models.Post, []models.TimeRange, error)
+
+func (f historyFunc) SubredditHistory(ctx context.Context, subreddit string, from, to time.Time) ([]models.Post, []models.TimeRange, error) {
+	return f(ctx, subreddit, from, to)
+}
+
+func TestScrapeSubredditBackfillsPastListingCap(t *testing.T) {
+	now := time.Now().Truncate(time.Second)
+	since := now.Add(-48 * time.Hour)
+
+	// The listing ends after 900 posts, the oldest 15 hours old
+	var listed []models.Post
+	for i := 1; i <= 900; i++ {
+		listed = append(listed, models.Post{ID: fmt.Sprintf("l%d", i), CreatedAt: now.Add(-time.Duration(i) * time.Minute)})
+	}
+	oldest := listed[len(listed)-1].CreatedAt
+
+	mockClient := &mocks.MockRedditClient{
+		GetSubredditURLFunc: func(subreddit string, limit int, after string) string { return "r/" + subreddit },
+		FetchJSONFunc: func(ctx context.Context, url string) (json.RawMessage, error) {
+			return json.RawMessage(`"` + url + `"`), nil
+		},
+	}
+	mockParser := &mocks.MockParser{
+		ParseSubredditFunc: func(ctx context.Context, data json.RawMessage) ([]models.Post, string, error) {
+			return listed, "", nil
+		},
+	}
+
+	gap := models.TimeRange{From: since, To: now.Add(-40 * time.Hour)}
+	opts := scraper.DefaultScraperOptions()
+	opts.History = historyFunc(func(ctx context.Context, subreddit string, from, to time.Time) ([]models.Post, []models.TimeRange, error) {
+		if subreddit != "golang" || !from.Equal(since) || !to.Equal(oldest) {
+			t.Errorf("Expected a backfill of r/golang from %v to %v, got r/%s from %v to %v", since, oldest, subreddit, from, to)
+		}
+		return []models.Post{
+			{ID: "old", CreatedAt: now.Add(-20 * time.Hour)},
+			{ID: "l5", CreatedAt: now.Add(-5 * time.Minute)},
+			{ID: "older", CreatedAt: now.Add(-30 * time.Hour)},
+			{ID: "too_old", CreatedAt: now.Add(-72 * time.Hour)},
+		}, []models.TimeRange{gap}, nil
+	})
+	svc := scraper.NewScraperServiceWithOptions(mockClient, mockParser, opts)
+
+	posts, meta, err := svc.ScrapeSubreddit(context.Background(), "golang", since.Unix(), -1, scraper.ListingOptions{})
+	if err != nil {
+		t.Fatalf("Failed to scrape subreddit: %v", err)
+	}
+
+	if len(posts) != 902 || posts[900].ID != "old" || posts[901].ID != "older" {
+		t.Fatalf("Expected the 900 listed posts followed by old and older, got %d posts", len(posts))
+	}
+	if !meta.ReachedListingCap || meta.Backfilled != 2 || meta.ReachedTimeCutoff {
+		t.Errorf("Expected the listing cap to be reached and 2 posts backfilled, got %+v", meta)
+	}
+	if len(meta.CoverageGaps) != 1 || meta.CoverageGaps[0] != gap {
+		t.Errorf("Expected the provider's gap, got %v", meta.CoverageGaps)
+	}
+	if meta.Cursor != "" {
+		t.Errorf("Expected no cursor after a backfill, got %q", meta.Cursor)
+	}
+}
+
+func TestScrapeSubredditReportsPageCapTruncation(t *testing.T) {
+	now := time.Now().Truncate(time.Second)
+	page := 0
+	mockClient := &mocks.MockRedditClient{
+		GetSubredditURLFunc: func(subreddit string, limit int, after string) string { return "r/" + subreddit + "?after=" + after },
+		FetchJSONFunc: func(ctx context.Context, url string) (json.RawMessage, error) {
+			return json.RawMessage(`"` + url + `"`), nil
+		},
+	}
+	mockParser := &mocks.MockParser{
+		// A listing that never reaches since_timestamp and never ends
+		ParseSubredditFunc: func(ctx context.Context, data json.RawMessage) ([]models.Post, string, error) {
+			page++
+			id := fmt.Sprintf("p%d", page)
+			return []models.Post{{ID: id, CreatedAt: now.Add(-time.Duration(page) * time.Minute)}}, "t3_" + id, nil
+		},
+	}
+
+	svc := scraper.NewScraperService(mockClient, mockParser)
+
+	since := now.Add(-24 * time.Hour)
+	posts, meta, err := svc.ScrapeSubreddit(context.Background(), "golang", since.Unix(), 0, scraper.ListingOptions{})
+	if err != nil {
+		t.Fatalf("Failed to scrape subreddit: %v", err)
+	}
+
+	if !meta.Truncated || meta.TruncationReason != models.TruncatedPageCap {
+		t.Errorf("Expected truncation at the page cap, got truncated=%v reason=%q", meta.Truncated, meta.TruncationReason)
+	}
+	oldest := posts[len(posts)-1].CreatedAt
+	if meta.OldestReached == nil || !meta.OldestReached.Equal(oldest) {
+		t.Errorf("Expected the oldest post read at %v, got %v", oldest, meta.OldestReached)
+	}
+}
+
+func TestScrapeSubredditReturnsResumableCursor(t *testing.T) {
+	now := time.Now()
+	page := []models.Post{
+		{ID: "a", CreatedAt: now},
+		{ID: "b", CreatedAt: now.Add(-time.Minute)},
+		{ID: "c", CreatedAt: now.Add(-2 * time.Minute)},
+	}
+
+	var requested []string
+	mockClient := &mocks.MockRedditClient{
+		GetSubredditURLFunc: func(subreddit string, limit int, after string) string {
+			requested = append(requested, after)
+			return "https://old.reddit.com/r/" + subreddit + "/new.json"
+		},
+		FetchJSONFunc: func(ctx context.Context, url string) (json.RawMessage, error) {
+			return json.RawMessage(`{}`), nil
+		},
+	}
+	mockParser := &mocks.MockParser{
+		ParseSubredditFunc: func(ctx context.Context, data json.RawMessage) ([]models.Post, string, error) {
+			return page, "t3_c", nil
+		},
+	}
+
+	svc := scraper.NewScraperService(mockClient, mockParser)
+	posts, meta, err := svc.ScrapeSubreddit(context.Background(), "golang", 0, 2, scraper.ListingOptions{After: "t3_start"})
+	if err != nil {
+		t.Fatalf("Failed to scrape subreddit: %v", err)
+	}
+
+	if len(requested) != 1 || requested[0] != "t3_start" {
+		t.Errorf("Expected the listing to start after t3_start, got %v", requested)
+	}
+	if len(posts) != 2 {
+		t.Fatalf("Expected 2 posts, got %d", len(posts))
+	}
+	// The limit cut the page short, so the cursor points at the last post
+	// returned rather than at Reddit's cursor after the page
+	if meta.Cursor != "t3_b" || meta.After != "t3_c" || meta.PagesFetched != 1 || meta.TimedOut || meta.ReachedTimeCutoff {
+		t.Errorf("Unexpected listing meta %+v", meta)
+	}
+
+	_, meta, err = svc.ScrapeSubreddit(context.Background(), "golang", now.Add(-90*time.Second).Unix(), 10, scraper.ListingOptions{})
+	if err != nil {
+		t.Fatalf("Failed to scrape subreddit: %v", err)
+	}
+	if !meta.ReachedTimeCutoff || meta.Cursor != "" {
+		t.Errorf("Expected no cursor once the time cutoff is reached, got %+v", meta)
+	}
+}
+
+func TestScrapeFrontpageKeepsEachPostsSubreddit(t *testing.T) {
+	var gotFeed string
+	var gotParams map[string]string
+	mockClient := &mocks.MockRedditClient{
+		GetFrontpageURLFunc: func(feed string, limit int, after string, params map[string]string) string {
+			gotFeed, gotParams = feed, params
+			return "https://old.reddit.com/r/popular/top.json"
+		},
+		FetchJSONFunc: func(ctx context.Context, url string) (json.RawMessage, error) {
+			return json.RawMessage(`{"data":{"after":null,"children":[
+				{"kind":"t3","data":{"id":"a1","title":"One","subreddit":"golang","subreddit_subscribers":250000,"permalink":"/r/golang/comments/a1/one/"}},
+				{"kind":"t3","data":{"id":"b2","title":"Two","subreddit":"rust","subreddit_subscribers":300000,"permalink":"/r/rust/comments/b2/two/"}}
+			]}}`), nil
+		},
+	}
+
+	svc := scraper.NewScraperService(mockClient, parser.NewRedditParser())
+	params := map[string]string{"sort": "top", "t": "day", "geo_filter": "GB"}
+	posts, _, err := svc.ScrapeFrontpage(context.Background(), scraper.FeedPopular, params, 0, scraper.ListingOptions{})
+	if err != nil {
+		t.Fatalf("Failed to scrape front page: %v", err)
+	}
+
+	if gotFeed != "popular" || gotParams["geo_filter"] != "GB" || gotParams["t"] != "day" {
+		t.Errorf("Expected feed and params to reach the client, got %q %v", gotFeed, gotParams)
+	}
+	if len(posts) != 2 {
+		t.Fatalf("Expected 2 posts, got %d", len(posts))
+	}
+	if posts[0].Subreddit != "golang" || posts[0].SubredditSubscribers != 250000 || posts[1].Subreddit != "rust" {
+		t.Errorf("Expected each post to carry its subreddit, got %+v", posts)
+	}
+}
+
+func TestScrapePostReportsProgress(t *testing.T) {
+	mockClient := &mocks.MockRedditClient{
+		GetPostURLFunc: func(postID string) string {
+			return "https://old.reddit.com/comments/" + postID + ".json"
+		},
+		FetchJSONFunc: func(ctx context.Context, url string) (json.RawMessage, error) {
+			return json.RawMessage(`[{},{}]`), nil
+		},
+		FetchMoreCommentsFunc: func(ctx context.Context, postID string, commentIDs []string) (json.RawMessage, error) {
+			return json.RawMessage(`{}`), nil
+		},
+	}
+	mockParser := &mocks.MockParser{
+		ParsePostFunc: func(ctx context.Context, postData, commentData json.RawMessage) (models.PostDetail, error) {
+			return models.PostDetail{
+				Post: models.Post{ID: "abc123"},
+				Comments: []models.Comment{
+					{ID: "c1", Body: "first"},
+					{ID: "more1", IsMore: true, MoreIDs: []string{"c2", "c3"}},
+				},
+			}, nil
+		},
+		ParseMoreCommentsFunc: func(ctx context.Context, data json.RawMessage) ([]models.Comment, error) {
+			return []models.Comment{{ID: "c2", Body: "second"}, {ID: "c3", Body: "third"}}, nil
+		},
+	}
+
+	var events []scraper.Progress
+	ctx := scraper.WithProgress(context.Background(), func(progress scraper.Progress) {
+		events = append(events, progress)
+	})
+
+	svc := scraper.NewScraperService(mockClient, mockParser)
+	if _, err := svc.ScrapePost(ctx, "abc123"); err != nil {
+		t.Fatalf("ScrapePost returned error: %v", err)
+	}
+
+	if len(events) < 3 || events[0].Stage != scraper.StageFetchPost {
+		t.Fatalf("Expected progress from the first fetch on, got %+v", events)
+	}
+	sawHalfway := false
+	for i, event := range events {
+		if i > 0 && event.Percent < events[i-1].Percent {
+			t.Errorf("Expected progress to only move forward, got %+v after %+v", event, events[i-1])
+		}
+		if event.Stage == scraper.StageExpandComments && event.Percent < 100 {
+			sawHalfway = true
+		}
+	}
+	if !sawHalfway {
+		t.Errorf("Expected a progress event while expanding comments, got %+v", events)
+	}
+
+	last := events[len(events)-1]
+	if last.Stage != scraper.StageDone || last.Percent != 100 || last.PagesFetched != 2 ||
+		last.MoreIDsTotal != 2 || last.MoreIDsResolved != 2 || last.CommentsExpanded != 2 {
+		t.Errorf("Unexpected final progress %+v", last)
+	}
+}
+
+func TestScrapePostCoalescesSmallMoreSets(t *testing.T) {
+	var calls [][]string
+	mockClient := &mocks.MockRedditClient{
+		GetPostURLFunc: func(postID string) string { return "post" },
+		FetchJSONFunc: func(ctx context.Context, url string) (json.RawMessage, error) {
+			return json.RawMessage(`[{},{}]`), nil
+		},
+		FetchMoreCommentsFunc: func(ctx context.Context, postID string, commentIDs []string) (json.RawMessage, error) {
+			calls = append(calls, commentIDs)
+			return json.RawMessage(`{}`), nil
+		},
+	}
+	mockParser := &mocks.MockParser{
+		ParsePostFunc: func(ctx context.Context, postData, commentData json.RawMessage) (models.PostDetail, error) {
+			return models.PostDetail{
+				Post: models.Post{ID: "abc123"},
+				Comments: []models.Comment{
+					{ID: "c1", Body: "first", Replies: []models.Comment{
+						{ID: "c1a", Body: "reply"},
+						{ID: "more2", IsMore: true, MoreIDs: []string{"r1", "r2"}},
+					}},
+					{ID: "more1", IsMore: true, MoreIDs: []string{"c2", "c3"}},
+				},
+			}, nil
+		},
+		ParseMoreCommentsFunc: func(ctx context.Context, data json.RawMessage) ([]models.Comment, error) {
+			// Flat, a reply before its parent
+			return []models.Comment{
+				{ID: "r1x", ParentID: "t1_r1"},
+				{ID: "c2", ParentID: "t3_abc123"},
+				{ID: "r1", ParentID: "t1_c1"},
+				{ID: "c3", ParentID: "t3_abc123"},
+				{ID: "r2", ParentID: "t1_c1"},
+			}, nil
+		},
+	}
+
+	svc := scraper.NewScraperService(mockClient, mockParser)
+	detail, err := svc.ScrapePost(context.Background(), "abc123")
+	if err != nil {
+		t.Fatalf("ScrapePost returned error: %v", err)
+	}
+
+	if len(calls) != 1 || len(calls[0]) != 4 {
+		t.Fatalf("Expected both sets in one morechildren call, got %v", calls)
+	}
+	var top, replies []string
+	var nested []models.Comment
+	for _, comment := range detail.Comments {
+		top = append(top, comment.ID)
+		if comment.ID == "c1" {
+			for _, reply := range comment.Replies {
+				replies = append(replies, reply.ID)
+				if reply.ID == "r1" {
+					nested = reply.Replies
+				}
+			}
+		}
+	}
+	sort.Strings(top)
+	sort.Strings(replies)
+	if strings.Join(top, ",") != "c1,c2,c3" {
+		t.Errorf("Expected the top-level set's comments at the top level, got %v", top)
+	}
+	if strings.Join(replies, ",") != "c1a,r1,r2" {
+		t.Errorf("Expected the nested set's comments under c1, got %v", replies)
+	}
+	if len(nested) != 1 || nested[0].ID != "r1x" {
+		t.Errorf("Expected r1x under its parent r1, got %+v", nested)
+	}
+}
+
+func TestScrapePostPlacesLoadedCommentsUnderTheirParents(t *testing.T) {
+	mockClient := &mocks.MockRedditClient{
+		GetPostURLFunc: func(postID string) string { return "post" },
+		FetchJSONFunc: func(ctx context.Context, url string) (json.RawMessage, error) {
+			return json.RawMessage(`[{},{}]`), nil
+		},
+		FetchMoreCommentsFunc: func(ctx context.Context, postID string, commentIDs []string) (json.RawMessage, error) {
+			return json.RawMessage(`{}`), nil
+		},
+	}
+	mockParser := &mocks.MockParser{
+		ParsePostFunc: func(ctx context.Context, postData, commentData json.RawMessage) (models.PostDetail, error) {
+			return models.PostDetail{
+				Post: models.Post{ID: "abc123"},
+				Comments: []models.Comment{
+					{ID: "c1", Replies: []models.Comment{{ID: "c1a", ParentID: "t1_c1"}}},
+					{ID: "more1", IsMore: true, MoreIDs: []string{"c2", "d1", "e1"}},
+				},
+			}, nil
+		},
+		ParseMoreCommentsFunc: func(ctx context.Context, data json.RawMessage) ([]models.Comment, error) {
+			return []models.Comment{
+				{ID: "c2", ParentID: "t3_abc123"},
+				{ID: "c2a", ParentID: "t1_c2"},
+				{ID: "c2b", ParentID: "t1_c2a"},
+				// Replies to a comment already in the tree, and to one
+				// that is nowhere
+				{ID: "d1", ParentID: "t1_c1a"},
+				{ID: "e1", ParentID: "t1_gone"},
+			}, nil
+		},
+	}
+
+	svc := scraper.NewScraperService(mockClient, mockParser)
+	detail, err := svc.ScrapePost(context.Background(), "abc123")
+	if err != nil {
+		t.Fatalf("ScrapePost returned error: %v", err)
+	}
+
+	var top []string
+	for _, comment := range detail.Comments {
+		top = append(top, comment.ID)
+	}
+	if strings.Join(top, ",") != "c1,c2,e1" {
+		t.Fatalf("Expected c2 and the orphan in place of the placeholder, got %v", top)
+	}
+	c2 := detail.Comments[1]
+	if len(c2.Replies) != 1 || c2.Replies[0].ID != "c2a" ||
+		len(c2.Replies[0].Replies) != 1 || c2.Replies[0].Replies[0].ID != "c2b" {
+		t.Errorf("Expected c2 > c2a > c2b, got %+v", c2)
+	}
+	c1a := detail.Comments[0].Replies[0]
+	if len(c1a.Replies) != 1 || c1a.Replies[0].ID != "d1" {
+		t.Errorf("Expected d1 under c1a, got %+v", c1a)
+	}
+}
+
+func TestScrapePostExpandsDeepThreads(t *testing.T) {
+	const depth = 2000
+	mockClient := &mocks.MockRedditClient{
+		GetPostURLFunc: func(postID string) string { return "post" },
+		FetchJSONFunc: func(ctx context.Context, url string) (json.RawMessage, error) {
+			return json.RawMessage(`[{},{}]`), nil
+		},
+		FetchMoreCommentsFunc: func(ctx context.Context, postID string, commentIDs []string) (json.RawMessage, error) {
+			return json.RawMessage(`{}`), nil
+		},
+	}
+	mockParser := &mocks.MockParser{
+		ParsePostFunc: func(ctx context.Context, postData, commentData json.RawMessage) (models.PostDetail, error) {
+			// A reply chain with a "load more" link at its bottom
+			chain := []models.Comment{{ID: "more1", IsMore: true, MoreIDs: []string{"leaf"}}}
+			for i := depth - 1; i >= 0; i-- {
+				chain = []models.Comment{{ID: fmt.Sprintf("c%d", i), Replies: chain}}
+			}
+			return models.PostDetail{Post: models.Post{ID: "abc123"}, Comments: chain}, nil
+		},
+		ParseMoreCommentsFunc: func(ctx context.Context, data json.RawMessage) ([]models.Comment, error) {
+			return []models.Comment{{ID: "leaf", ParentID: fmt.Sprintf("t1_c%d", depth-1)}}, nil
+		},
+	}
+
+	svc := scraper.NewScraperService(mockClient, mockParser)
+	detail, err := svc.ScrapePost(context.Background(), "abc123")
+	if err != nil {
+		t.Fatalf("ScrapePost returned error: %v", err)
+	}
+
+	comment := detail.Comments[0]
+	for i := 1; i < depth && len(comment.Replies) == 1; i++ {
+		comment = comment.Replies[0]
+	}
+	if comment.ID != fmt.Sprintf("c%d", depth-1) || len(comment.Replies) != 1 || comment.Replies[0].ID != "leaf" {
+		t.Errorf("Expected the loaded comment at the bottom of the chain, got %s with %+v", comment.ID, comment.Replies)
+	}
+}
+
+func TestScrapePostUsesCommentSort(t *testing.T) {
+	base := time.Unix(1700000000, 0)
+	var postURL string
+	mockClient := &mocks.MockRedditClient{
+		GetPostURLFunc: func(postID string) string {
+			return "https://old.reddit.com/comments/" + postID + ".json?raw_json=1&sort=new"
+		},
+		FetchJSONFunc: func(ctx context.Context, url string) (json.RawMessage, error) {
+			postURL = url
+			return json.RawMessage(`[{},{}]`), nil
+		},
+		FetchMoreCommentsFunc: func(ctx context.Context, postID string, commentIDs []string) (json.RawMessage, error) {
+			if sort := client.CommentSortFromContext(ctx); sort != "top" {
+				t.Errorf("Expected morechildren to be asked for top comments, got %q", sort)
+			}
+			return json.RawMessage(`{}`), nil
+		},
+	}
+	mockParser := &mocks.MockParser{
+		ParsePostFunc: func(ctx context.Context, postData, commentData json.RawMessage) (models.PostDetail, error) {
+			return models.PostDetail{
+				Post: models.Post{ID: "abc123"},
+				Comments: []models.Comment{
+					{ID: "c1", Body: "first"},
+					{ID: "more1", IsMore: true, MoreIDs: []string{"c2", "c3"}},
+				},
+			}, nil
+		},
+		ParseMoreCommentsFunc: func(ctx context.Context, data json.RawMessage) ([]models.Comment, error) {
+			// Top first, although it is older
+			return []models.Comment{
+				{ID: "c2", Body: "top", CreatedAt: base},
+				{ID: "c3", Body: "newer", CreatedAt: base.Add(time.Hour)},
+			}, nil
+		},
+	}
+
+	svc := scraper.NewScraperService(mockClient, mockParser)
+	detail, err := svc.ScrapePost(client.WithCommentSort(context.Background(), "top"), "abc123")
+	if err != nil {
+		t.Fatalf("ScrapePost returned error: %v", err)
+	}
+
+	if !strings.Contains(postURL, "sort=top") || strings.Contains(postURL, "sort=new") {
+		t.Errorf("Expected the post page to be asked for top comments, got %s", postURL)
+	}
+	var ids []string
+	for _, comment := range detail.Comments {
+		ids = append(ids, comment.ID)
+	}
+	if strings.Join(ids, ",") != "c1,c2,c3" {
+		t.Errorf("Expected loaded comments in Reddit's order, got %v", ids)
+	}
+}
+
+func TestScrapePostBoundedTreeIsNotExpanded(t *testing.T) {
+	var postURL string
+	mockClient := &mocks.MockRedditClient{
+		GetPostURLFunc: func(postID string) string {
+			return "https://old.reddit.com/comments/" + postID + ".json?raw_json=1&sort=new"
+		},
+		FetchJSONFunc: func(ctx context.Context, url string) (json.RawMessage, error) {
+			postURL = url
+			return json.RawMessage(`[{},{}]`), nil
+		},
+		FetchMoreCommentsFunc: func(ctx context.Context, postID string, commentIDs []string) (json.RawMessage, error) {
+			t.Errorf("Expected no morechildren requests for a bounded tree, got one for %v", commentIDs)
+			return json.RawMessage(`{}`), nil
+		},
+	}
+	mockParser := &mocks.MockParser{
+		ParsePostFunc: func(ctx context.Context, postData, commentData json.RawMessage) (models.PostDetail, error) {
+			return models.PostDetail{
+				Post: models.Post{ID: "abc123"},
+				Comments: []models.Comment{
+					{ID: "c1", Body: "first"},
+					{ID: "more1", IsMore: true, MoreIDs: []string{"c2", "c3"}, MoreCount: 2},
+				},
+			}, nil
+		},
+	}
+
+	svc := scraper.NewScraperService(mockClient, mockParser)
+	ctx := client.WithCommentTree(context.Background(), client.CommentTree{Depth: 1, Limit: 50})
+	detail, err := svc.ScrapePost(ctx, "abc123")
+	if err != nil {
+		t.Fatalf("ScrapePost returned error: %v", err)
+	}
+
+	if !strings.Contains(postURL, "depth=1") || !strings.Contains(postURL, "limit=50") || !strings.Contains(postURL, "sort=new") {
+		t.Errorf("Expected depth, limit and the default sort on the post page, got %s", postURL)
+	}
+	if len(detail.Comments) != 2 || !detail.Comments[1].IsMore {
+		t.Errorf("Expected the tree as Reddit sent it, placeholder included, got %+v", detail.Comments)
+	}
+}
+
+func TestScrapePostReportsCompleteness(t *testing.T) {
+	mockClient := &mocks.MockRedditClient{
+		GetPostURLFunc: func(postID string) string { return "post" },
+		FetchJSONFunc: func(ctx context.Context, url string) (json.RawMessage, error) {
+			return json.RawMessage(`[{},{}]`), nil
+		},
+	}
+	mockParser := &mocks.MockParser{
+		ParsePostFunc: func(ctx context.Context, postData, commentData json.RawMessage) (models.PostDetail, error) {
+			return models.PostDetail{
+				Post: models.Post{ID: "abc123", NumComments: 8},
+				Comments: []models.Comment{
+					{ID: "c1", Replies: []models.Comment{
+						{ID: "c2"},
+						{ID: "more2", IsMore: true, MoreIDs: []string{"c4"}, MoreCount: 1},
+					}},
+					{ID: "c3"},
+					{ID: "more1", IsMore: true, MoreIDs: []string{"c5", "c6"}, MoreCount: 4},
+				},
+			}, nil
+		},
+	}
+
+	svc := scraper.NewScraperService(mockClient, mockParser)
+	ctx := client.WithCommentTree(context.Background(), client.CommentTree{Depth: 2})
+	detail, err := svc.ScrapePost(ctx, "abc123")
+	if err != nil {
+		t.Fatalf("ScrapePost returned error: %v", err)
+	}
+
+	want := models.CommentCompleteness{Fetched: 3, Reported: 8, Unloaded: 5, Percent: 37.5}
+	if detail.Completeness == nil || *detail.Completeness != want {
+		t.Errorf("Expected completeness %+v, got %+v", want, detail.Completeness)
+	}
+}
+
+func TestScrapeUserActivityReturnsPartialResults(t *testing.T) {
+	mockClient := &mocks.MockRedditClient{
+		GetUserAboutURLFunc:    func(username string) string { return "about" },
+		GetUserPostsURLFunc:    func(username string, after string) string { return "posts" },
+		GetUserCommentsURLFunc: func(username string, after string) string { return "comments" },
+		FetchJSONFunc: func(ctx context.Context, url string) (json.RawMessage, error) {
+			if url == "comments" {
+				return nil, errors.New("server error: status 429")
+			}
+			return json.RawMessage(`"` + url + `"`), nil
+		},
+	}
+	mockParser := &mocks.MockParser{
+		ParseUserInfoFunc: func(ctx context.Context, data json.RawMessage) (models.UserInfo, error) {
+			return models.UserInfo{Username: "gopher"}, nil
+		},
+		ParseUserPostsFunc: func(ctx context.Context, data json.RawMessage) ([]models.UserPost, string, error) {
+			return []models.UserPost{{ID: "p1", CreatedAt: time.Now()}}, "", nil
+		},
+	}
+	svc := scraper.NewScraperService(mockClient, mockParser)
+
+	activity, err := svc.ScrapeUserActivity(context.Background(), "gopher", 0, 5, 5)
+	if err != nil {
+		t.Fatalf("Expected the posts despite the comments failing, got error %v", err)
+	}
+	if len(activity.Posts) != 1 || activity.Meta == nil || !activity.Meta.Partial {
+		t.Fatalf("Expected one post flagged partial, got %+v", activity)
+	}
+	if len(activity.Meta.Warnings) != 1 || activity.Meta.Warnings[0] != "fetch user comments: server error: status 429" {
+		t.Errorf("Unexpected warnings %q", activity.Meta.Warnings)
+	}
+
+	if _, err := svc.ScrapeUserActivity(scraper.WithStrict(context.Background()), "gopher", 0, 5, 5); err == nil {
+		t.Error("Expected a strict scrape to fail when the comments fail")
+	}
+}
+
+func TestScrapeSubredditFiltersByFlairWhilePaging(t *testing.T) {
+	pages := map[string]string{
+		"": `{"data":{"after":"t3_b","children":[
+			{"kind":"t3","data":{"id":"a","link_flair_text":"Discussion","created_utc":1000}},
+			{"kind":"t3","data":{"id":"b","link_flair_text":"Discussion","created_utc":990}}
+		]}}`,
+		"t3_b": `{"data":{"after":"t3_e","children":[
+			{"kind":"t3","data":{"id":"c","link_flair_text":"Bug","created_utc":980}},
+			{"kind":"t3","data":{"id":"d","link_flair_text":"Question","created_utc":975}},
+			{"kind":"t3","data":{"id":"e","link_flair_text":"bug","created_utc":970}}
+		]}}`,
+		"t3_e": `{"data":{"after":null,"children":[
+			{"kind":"t3","data":{"id":"f","link_flair_text":"Bug","created_utc":960}}
+		]}}`,
+	}
+	var fetched []string
+	mockClient := &mocks.MockRedditClient{
+		GetSubredditURLFunc: func(subreddit string, limit int, after string) string {
+			return after
+		},
+		FetchJSONFunc: func(ctx context.Context, url string) (json.RawMessage, error) {
+			fetched = append(fetched, url)
+			return json.RawMessage(pages[url]), nil
+		},
+	}
+	svc := scraper.NewScraperService(mockClient, parser.NewRedditParser())
+	opts := scraper.ListingOptions{Filter: scraper.PostFilter{Flair: []scraper.TextMatcher{scraper.ExactMatcher("BUG")}}}
+
+	// A first page without matches must not end paging, and the limit counts
+	// matching posts only
+	posts, meta, err := svc.ScrapeSubreddit(context.Background(), "golang", 0, 2, opts)
+	if err != nil {
+		t.Fatalf("Failed to scrape subreddit: %v", err)
+	}
+	if len(posts) != 2 || posts[0].ID != "c" || posts[1].ID != "e" {
+		t.Fatalf("Expected posts c and e, got %+v", posts)
+	}
+	if meta.FilteredOut != 3 || meta.Cursor != "t3_e" || len(fetched) != 2 {
+		t.Errorf("Expected 3 filtered out, cursor t3_e after 2 pages, got %+v after %v", meta, fetched)
+	}
+
+	// The time cutoff applies to every post read, matching or not
+	exclude, err := scraper.RegexMatcher("^(?i)bug$")
+	if err != nil {
+		t.Fatalf("Failed to compile pattern: %v", err)
+	}
+	opts.Filter = scraper.PostFilter{ExcludeFlair: []scraper.TextMatcher{exclude}}
+	posts, meta, err = svc.ScrapeSubreddit(context.Background(), "golang", 972, -1, opts)
+	if err != nil {
+		t.Fatalf("Failed to scrape subreddit: %v", err)
+	}
+	if len(posts) != 3 || posts[2].ID != "d" || !meta.ReachedTimeCutoff || meta.Cursor != "" {
+		t.Errorf("Expected a, b and d up to the cutoff, got %+v %+v", posts, meta)
+	}
+}
+
+func TestScrapeUserActivityAppliesFilters(t *testing.T) {
+	now := time.Now()
+	mockClient := &mocks.MockRedditClient{
+		GetUserAboutURLFunc:    func(username string) string { return "about" },
+		GetUserPostsURLFunc:    func(username string, after string) string { return "posts" + after },
+		GetUserCommentsURLFunc: func(username string, after string) string { return "comments" },
+		FetchJSONFunc: func(ctx context.Context, url string) (json.RawMessage, error) {
+			return json.RawMessage(`"` + url + `"`), nil
+		},
+	}
+	mockParser := &mocks.MockParser{
+		ParseUserInfoFunc: func(ctx context.Context, data json.RawMessage) (models.UserInfo, error) {
+			return models.UserInfo{Username: "gopher"}, nil
+		},
+		ParseUserPostsFunc: func(ctx context.Context, data json.RawMessage) ([]models.UserPost, string, error) {
+			switch string(data) {
+			case `"posts"`:
+				// No match on the first page must not end paging
+				return []models.UserPost{
+					{ID: "p1", Title: "Weekly thread", CreatedAt: now.Add(-time.Hour)},
+				}, "t3_p1", nil
+			case `"postst3_p1"`:
+				return []models.UserPost{
+					{ID: "p2", Title: "Generics in Go", CreatedAt: now.Add(-2 * time.Hour)},
+					{ID: "p3", Title: "Rust or GO?", CreatedAt: now.Add(-3 * time.Hour)},
+				}, "", nil
+			}
+			return nil, "", nil
+		},
+		ParseUserCommentsFunc: func(ctx context.Context, data json.RawMessage) ([]models.UserComment, string, error) {
+			return []models.UserComment{
+				{ID: "c1", PostTitle: "Why Go", Body: "Because", CreatedAt: now.Add(-time.Hour)},
+				{ID: "c2", PostTitle: "Weekly thread", Body: "Go!", CreatedAt: now.Add(-2 * time.Hour)},
+			}, "", nil
+		},
+	}
+
+	svc := scraper.NewScraperService(mockClient, mockParser)
+	filter := scraper.PostFilter{TitleContains: []scraper.TextMatcher{scraper.ContainsMatcher("go")}}
+	ctx := scraper.WithActivityFilter(context.Background(), filter)
+
+	activity, err := svc.ScrapeUserActivity(ctx, "gopher", 0, 2, 25)
+	if err != nil {
+		t.Fatalf("Failed to scrape user activity: %v", err)
+	}
+
+	if len(activity.Posts) != 2 || activity.Posts[0].ID != "p2" || activity.Posts[1].ID != "p3" {
+		t.Errorf("Expected posts p2 and p3, got %+v", activity.Posts)
+	}
+	// Comments are matched by the title of the post they were made on
+	if len(activity.Comments) != 1 || activity.Comments[0].ID != "c1" {
+		t.Errorf("Expected comment c1, got %+v", activity.Comments)
+	}
+	if activity.Meta.FilteredOut != 2 || activity.Meta.FilterMatches["title_contains"] != 3 {
+		t.Errorf("Expected 2 filtered out and 3 title matches, got %+v", activity.Meta)
+	}
+}
+
+func TestScrapeSubredditRefetchesSuspiciousEmptyPages(t *testing.T) {
+	interstitial := `{"data":{"after":null,"children":[]},"html":"` + strings.Repeat("x", 2048) + `"}`
+	pages := map[string][]string{
+		"": {`{"data":{"after":"t3_a","children":[
+			{"kind":"t3","data":{"id":"a","created_utc":1000}}
+		]}}`},
+		// The second page first comes back as an interstitial without posts
+		"t3_a": {interstitial, `{"data":{"after":"t3_b","children":[
+			{"kind":"t3","data":{"id":"b","created_utc":990}}
+		]}}`},
+		// A small empty page is the end of the listing and is not refetched
+		"t3_b": {`{"data":{"after":null,"children":[]}}`},
+	}
+	var fetched []string
+	mockClient := &mocks.MockRedditClient{
+		GetSubredditURLFunc: func(subreddit string, limit int, after string) string {
+			return after
+		},
+		FetchJSONFunc: func(ctx context.Context, url string) (json.RawMessage, error) {
+			fetched = append(fetched, url)
+			page := pages[url][0]
+			if len(pages[url]) > 1 {
+				pages[url] = pages[url][1:]
+			}
+			return json.RawMessage(page), nil
+		},
+	}
+	svc := scraper.NewScraperService(mockClient, parser.NewRedditParser())
+
+	posts, _, err := svc.ScrapeSubreddit(context.Background(), "golang", 0, -1, scraper.ListingOptions{})
+	if err != nil {
+		t.Fatalf("Failed to scrape subreddit: %v", err)
+	}
+	if len(posts) != 2 || posts[1].ID != "b" {
+		t.Errorf("Expected posts a and b, got %+v", posts)
+	}
+	if strings.Join(fetched, ",") != ",t3_a,t3_a,t3_b" {
+		t.Errorf("Expected only the interstitial to be refetched, got %q", fetched)
+	}
+
+	// With retries disabled the interstitial ends paging
+	pages["t3_a"] = []string{interstitial}
+	svc = scraper.NewScraperServiceWithOptions(mockClient, parser.NewRedditParser(), scraper.ScraperOptions{})
+	posts, _, err = svc.ScrapeSubreddit(context.Background(), "golang", 0, -1, scraper.ListingOptions{})
+	if err != nil {
+		t.Fatalf("Failed to scrape subreddit: %v", err)
+	}
+	if len(posts) != 1 {
+		t.Errorf("Expected paging to stop at the interstitial, got %+v", posts)
+	}
+}
+
+func TestScrapeSubredditActsOnUpstreamStatus(t *testing.T) {
+	refused := &utils.UpstreamError{StatusCode: 403, Attempts: 1}
+	gone := &utils.UpstreamError{StatusCode: 404, Attempts: 1}
+	pages := map[string][]any{
+		"": {`{"data":{"after":"t3_a","children":[
+			{"kind":"t3","data":{"id":"a","created_utc":1000}}
+		]}}`},
+		// The proxy is refused once, the next one gets the page
+		"t3_a": {refused, `{"data":{"after":"t3_b","children":[
+			{"kind":"t3","data":{"id":"b","created_utc":990}}
+		]}}`},
+		// The cursor fell out of the listing
+		"t3_b": {gone},
+	}
+	var fetched []string
+	mockClient := &mocks.MockRedditClient{
+		GetSubredditURLFunc: func(subreddit string, limit int, after string) string {
+			return after
+		},
+		FetchJSONFunc: func(ctx context.Context, url string) (json.RawMessage, error) {
+			fetched = append(fetched, url)
+			page := pages[url][0]
+			if len(pages[url]) > 1 {
+				pages[url] = pages[url][1:]
+			}
+			if err, ok := page.(error); ok {
+				return nil, err
+			}
+			return json.RawMessage(page.(string)), nil
+		},
+	}
+	svc := scraper.NewScraperService(mockClient, parser.NewRedditParser())
+
+	posts, _, err := svc.ScrapeSubreddit(context.Background(), "golang", 0, -1, scraper.ListingOptions{})
+	if err != nil {
+		t.Fatalf("Expected a 404 past the first page to end paging, got %v", err)
+	}
+	if len(posts) != 2 || posts[1].ID != "b" {
+		t.Errorf("Expected posts a and b, got %+v", posts)
+	}
+	if strings.Join(fetched, ",") != ",t3_a,t3_a,t3_b" {
+		t.Errorf("Expected the refused page to be refetched once, got %q", fetched)
+	}
+
+	// A first page that is not there fails the scrape
+	pages[""] = []any{gone}
+	if _, _, err := svc.ScrapeSubreddit(context.Background(), "golang", 0, -1, scraper.ListingOptions{}); utils.UpstreamStatus(err) != 404 {
+		t.Errorf("Expected the 404 of the first page, got %v", err)
+	}
+}
+
+func TestSearchFiltersNSFWPosts(t *testing.T) {
+	page := `{"data":{"after":null,"children":[
+		{"kind":"t3","data":{"id":"a","over_18":true,"created_utc":1000}},
+		{"kind":"t3","data":{"id":"b","over_18":false,"created_utc":990}},
+		{"kind":"t3","data":{"id":"c","created_utc":980}}
+	]}}`
+	var gotNSFW string
+	mockClient := &mocks.MockRedditClient{
+		GetSearchURLFunc: func(searchParams map[string]string) string {
+			gotNSFW = searchParams["nsfw"]
+			return "search"
+		},
+		FetchJSONFunc: func(ctx context.Context, url string) (json.RawMessage, error) {
+			return json.RawMessage(page), nil
+		},
+	}
+	svc := scraper.NewScraperService(mockClient, parser.NewRedditParser())
+
+	for _, tc := range []struct {
+		mode     scraper.NSFWMode
+		upstream string
+		want     string
+	}{
+		{scraper.NSFWInclude, "", "a,b,c"},
+		{scraper.NSFWExclude, "no", "b,c"},
+		{scraper.NSFWOnly, "yes", "a"},
+	} {
+		gotNSFW = ""
+		opts := scraper.ListingOptions{Filter: scraper.PostFilter{NSFW: tc.mode}}
+		posts, meta, err := svc.Search(context.Background(), map[string]string{"search_string": "go"}, 0, 25, opts)
+		if err != nil {
+			t.Fatalf("Search failed: %v", err)
+		}
+		var ids []string
+		for _, post := range posts {
+			ids = append(ids, post.ID)
+		}
+		if strings.Join(ids, ",") != tc.want || gotNSFW != tc.upstream {
+			t.Errorf("nsfw=%s: expected %s searching nsfw:%q, got %v searching nsfw:%q", tc.mode, tc.want, tc.upstream, ids, gotNSFW)
+		}
+		if tc.mode != scraper.NSFWInclude && meta.FilterMatches["nsfw"] != 1 {
+			t.Errorf("nsfw=%s: expected 1 NSFW post counted, got %v", tc.mode, meta.FilterMatches)
+		}
+	}
+}
+
+func TestScrapePostFetchesRelatedPostsOnRequest(t *testing.T) {
+	post := `{"data":{"children":[{"kind":"t3","data":{"id":"abc123","title":"Go 1.22 released"}}]}}`
+	pages := map[string]string{
+		"post": `[` + post + `,{"data":{"children":[]}}]`,
+		"duplicates": `[` + post + `,{"data":{"after":null,"children":[
+			{"kind":"t3","data":{"id":"def456","subreddit":"programming","crosspost_parent":"t3_abc123"}},
+			{"kind":"t3","data":{"id":"ghi789","subreddit":"golang"}}
+		]}}]`,
+	}
+	var fetched []string
+	mockClient := &mocks.MockRedditClient{
+		GetPostURLFunc:       func(postID string) string { return "post" },
+		GetDuplicatesURLFunc: func(postID string) string { return "duplicates" },
+		FetchJSONFunc: func(ctx context.Context, url string) (json.RawMessage, error) {
+			fetched = append(fetched, url)
+			if page, ok := pages[url]; ok {
+				return json.RawMessage(page), nil
+			}
+			return nil, errors.New("not found")
+		},
+	}
+	svc := scraper.NewScraperService(mockClient, parser.NewRedditParser())
+
+	detail, err := svc.ScrapePost(context.Background(), "abc123")
+	if err != nil {
+		t.Fatalf("ScrapePost returned error: %v", err)
+	}
+	if detail.Related != nil || len(fetched) != 1 {
+		t.Errorf("Expected no related posts unless requested, got %+v after %v", detail.Related, fetched)
+	}
+
+	detail, err = svc.ScrapePost(scraper.WithRelated(context.Background()), "abc123")
+	if err != nil {
+		t.Fatalf("ScrapePost returned error: %v", err)
+	}
+	if len(detail.Related) != 2 || detail.Related[0].CrosspostParent != "t3_abc123" || detail.Related[1].CrosspostParent != "" {
+		t.Errorf("Expected the crosspost and the other submission, got %+v", detail.Related)
+	}
+
+	// A failed fetch leaves related posts out unless the scrape is strict
+	delete(pages, "duplicates")
+	detail, err = svc.ScrapePost(scraper.WithRelated(context.Background()), "abc123")
+	if err != nil || detail.Post.ID != "abc123" || detail.Related != nil {
+		t.Errorf("Expected the post without related posts, got %+v, %v", detail, err)
+	}
+	if _, err := svc.ScrapePost(scraper.WithStrict(scraper.WithRelated(context.Background())), "abc123"); err == nil {
+		t.Error("Expected a strict scrape to fail without related posts")
+	}
+}
+
+func TestScrapePostClampsExpansionPoolToProxies(t *testing.T) {
+	var inFlight, maxInFlight int32
+	mockClient := &mocks.MockRedditClient{
+		GetPostURLFunc: func(postID string) string { return "post" },
+		FetchJSONFunc: func(ctx context.Context, url string) (json.RawMessage, error) {
+			return json.RawMessage(`[{},{}]`), nil
+		},
+		FetchMoreCommentsFunc: func(ctx context.Context, postID string, commentIDs []string) (json.RawMessage, error) {
+			n := atomic.AddInt32(&inFlight, 1)
+			defer atomic.AddInt32(&inFlight, -1)
+			for {
+				seen := atomic.LoadInt32(&maxInFlight)
+				if n <= seen || atomic.CompareAndSwapInt32(&maxInFlight, seen, n) {
+					break
+				}
+			}
+			time.Sleep(50 * time.Millisecond)
+			return json.Marshal(commentIDs[0])
+		},
+	}
+	mockParser := &mocks.MockParser{
+		ParsePostFunc: func(ctx context.Context, postData, commentData json.RawMessage) (models.PostDetail, error) {
+			detail := models.PostDetail{Post: models.Post{ID: "abc123"}}
+			// Sets too large to share a morechildren call, one request each
+			for i := 0; i < 12; i++ {
+				id := fmt.Sprintf("c%d", i)
+				ids := []string{id}
+				for j := 1; j < 60; j++ {
+					ids = append(ids, fmt.Sprintf("%s_%d", id, j))
+				}
+				detail.Comments = append(detail.Comments, models.Comment{ID: "more" + id, IsMore: true, MoreIDs: ids})
+			}
+			return detail, nil
+		},
+		ParseMoreCommentsFunc: func(ctx context.Context, data json.RawMessage) ([]models.Comment, error) {
+			var id string
+			json.Unmarshal(data, &id)
+			return []models.Comment{{ID: id, Body: "expanded"}}, nil
+		},
+	}
+	ctx := scraper.WithExpansion(context.Background(), scraper.ExpansionOptions{Workers: 10})
+
+	// Without proxies the default 3 workers x 2 requests is the limit
+	svc := scraper.NewScraperService(mockClient, mockParser)
+	if _, err := svc.ScrapePost(ctx, "abc123"); err != nil {
+		t.Fatalf("ScrapePost returned error: %v", err)
+	}
+	if maxInFlight > 3 {
+		t.Errorf("Expected at most 3 sets in flight without proxies, got %d", maxInFlight)
+	}
+
+	maxInFlight = 0
+	opts := scraper.DefaultScraperOptions()
+	opts.Proxies = 10
+	svc = scraper.NewScraperServiceWithOptions(mockClient, mockParser, opts)
+	detail, err := svc.ScrapePost(ctx, "abc123")
+	if err != nil {
+		t.Fatalf("ScrapePost returned error: %v", err)
+	}
+	if maxInFlight <= 3 {
+		t.Errorf("Expected more than 3 sets in flight with 10 proxies, got %d", maxInFlight)
+	}
+	if len(detail.Comments) != 12 {
+		t.Errorf("Expected every set expanded, got %d comments", len(detail.Comments))
+	}
+}
+
+func TestCrawlPolicyAppliesToItsSubreddit(t *testing.T) {
+	post := `{"data":{"children":[{"kind":"t3","data":{"id":"abc123","subreddit":"GoLang","title":"Go 1.22 released"}}]}}`
+	pages := map[string]string{
+		"post": `[` + post + `,{"data":{"children":[
+			{"kind":"t1","data":{"id":"c1","body":"first"}},
+			{"kind":"more","data":{"id":"m1","children":["c2"]}}
+		]}}]`,
+		"duplicates": `[` + post + `,{"data":{"after":null,"children":[]}}]`,
+		"listing":    `{"data":{"after":null,"children":[]}}`,
+	}
+	var fetched []string
+	var listingLimits []int
+	mockClient := &mocks.MockRedditClient{
+		GetPostURLFunc:       func(postID string) string { return "post" },
+		GetDuplicatesURLFunc: func(postID string) string { return "duplicates" },
+		GetSubredditURLFunc: func(subreddit string, limit int, after string) string {
+			listingLimits = append(listingLimits, limit)
+			return "listing"
+		},
+		FetchJSONFunc: func(ctx context.Context, url string) (json.RawMessage, error) {
+			fetched = append(fetched, url)
+			return json.RawMessage(pages[url]), nil
+		},
+		FetchMoreCommentsFunc: func(ctx context.Context, postID string, commentIDs []string) (json.RawMessage, error) {
+			fetched = append(fetched, "more")
+			return json.RawMessage(`{}`), nil
+		},
+	}
+	no, yes := false, true
+	opts := scraper.DefaultScraperOptions()
+	opts.Policies = scraper.NewCrawlPolicies(map[string]scraper.CrawlPolicy{
+		"r/golang": {DefaultLimit: 50, ExpandComments: &no, IncludeRelated: &yes},
+	})
+	svc := scraper.NewScraperServiceWithOptions(mockClient, parser.NewRedditParser(), opts)
+
+	if _, err := svc.ScrapePost(context.Background(), "abc123"); err != nil {
+		t.Fatalf("ScrapePost returned error: %v", err)
+	}
+	if strings.Join(fetched, ",") != "post,duplicates" {
+		t.Errorf("Expected related posts without comment expansion, got fetches %v", fetched)
+	}
+
+	if _, _, err := svc.ScrapeSubreddit(context.Background(), "golang", 0, 0, scraper.ListingOptions{}); err != nil {
+		t.Fatalf("ScrapeSubreddit returned error: %v", err)
+	}
+	if _, _, err := svc.ScrapeSubreddit(context.Background(), "rust", 0, 0, scraper.ListingOptions{}); err != nil {
+		t.Fatalf("ScrapeSubreddit returned error: %v", err)
+	}
+	if len(listingLimits) != 2 || listingLimits[0] != 50 || listingLimits[1] != 0 {
+		t.Errorf("Expected the policy's default limit for golang only, got %v", listingLimits)
+	}
+
+	// The policy also replaces a configured default, but not a requested limit
+	listingLimits = nil
+	defaulted := scraper.WithDefaultLimit(context.Background())
+	if _, _, err := svc.ScrapeSubreddit(defaulted, "golang", 0, 25, scraper.ListingOptions{}); err != nil {
+		t.Fatalf("ScrapeSubreddit returned error: %v", err)
+	}
+	if _, _, err := svc.ScrapeSubreddit(context.Background(), "golang", 0, 25, scraper.ListingOptions{}); err != nil {
+		t.Fatalf("ScrapeSubreddit returned error: %v", err)
+	}
+	if len(listingLimits) != 2 || listingLimits[0] != 50 || listingLimits[1] != 25 {
+		t.Errorf("Expected the policy's limit over the default only, got %v", listingLimits)
+	}
+}
+
+func TestScrapeUserOverviewPagesUpToLimit(t *testing.T) {
+	now := time.Now()
+	item := func(kind, id string, age time.Duration) models.UserOverviewItem {
+		it := models.UserOverviewItem{Kind: kind, ID: id, CreatedAt: now.Add(-age)}
+		if kind == models.OverviewKindPost {
+			it.Post = &models.UserPost{ID: id, CreatedAt: it.CreatedAt}
+		} else {
+			it.Comment = &models.UserComment{ID: id, CreatedAt: it.CreatedAt}
+		}
+		return it
+	}
+
+	var urls []string
+	mockClient := &mocks.MockRedditClient{
+		GetUserOverviewURLFunc: func(username string, after string) string { return "overview" + after },
+		FetchJSONFunc: func(ctx context.Context, url string) (json.RawMessage, error) {
+			urls = append(urls, url)
+			return json.RawMessage(`"` + url + `"`), nil
+		},
+	}
+	mockParser := &mocks.MockParser{
+		ParseUserOverviewFunc: func(ctx context.Context, data json.RawMessage) ([]models.UserOverviewItem, string, error) {
+			switch string(data) {
+			case `"overview"`:
+				return []models.UserOverviewItem{
+					item(models.OverviewKindComment, "c1", time.Hour),
+					item(models.OverviewKindPost, "p1", 2*time.Hour),
+				}, "t3_p1", nil
+			case `"overviewt3_p1"`:
+				// The overview shifted while paging and lists p1 again
+				return []models.UserOverviewItem{
+					item(models.OverviewKindPost, "p1", 2*time.Hour),
+					item(models.OverviewKindComment, "c2", 3*time.Hour),
+					item(models.OverviewKindComment, "c3", 4*time.Hour),
+				}, "t1_c3", nil
+			}
+			t.Errorf("Unexpected page %s", data)
+			return nil, "", nil
+		},
+	}
+
+	svc := scraper.NewScraperService(mockClient, mockParser)
+
+	overview, err := svc.ScrapeUserOverview(context.Background(), "gopher", 0, 3)
+	if err != nil {
+		t.Fatalf("Failed to scrape user overview: %v", err)
+	}
+
+	var ids []string
+	for _, it := range overview.Items {
+		ids = append(ids, it.ID)
+	}
+	if strings.Join(ids, ",") != "c1,p1,c2" {
+		t.Errorf("Expected c1,p1,c2, got %v", ids)
+	}
+	if overview.Meta.PagesFetched != 2 || overview.Meta.DuplicatesDropped != 1 {
+		t.Errorf("Expected 2 pages and 1 duplicate, got %+v", overview.Meta)
+	}
+	if len(urls) != 2 {
+		t.Errorf("Expected paging to stop at the limit, fetched %v", urls)
+	}
+
+	activity := overview.Activity()
+	if len(activity.Posts) != 1 || len(activity.Comments) != 2 {
+		t.Errorf("Expected the overview to split into 1 post and 2 comments, got %+v", activity)
+	}
+}
+
+func TestScrapeUserActivityFetchesNextPageWhileParsing(t *testing.T) {
+	now := time.Now()
+	secondRequested := make(chan struct{})
+	var once sync.Once
+	mockClient := &mocks.MockRedditClient{
+		GetUserAboutURLFunc:    func(username string) string { return "about" },
+		GetUserPostsURLFunc:    func(username string, after string) string { return "posts" + after },
+		GetUserCommentsURLFunc: func(username string, after string) string { return "comments" },
+		FetchJSONFunc: func(ctx context.Context, url string) (json.RawMessage, error) {
+			switch url {
+			case "posts":
+				return json.RawMessage(`{"data":{"after":"t3_p1","children":[]}}`), nil
+			case "postst3_p1":
+				once.Do(func() { close(secondRequested) })
+				return json.RawMessage(`{"data":{"after":null,"children":[]}}`), nil
+			}
+			return json.RawMessage(`{}`), nil
+		},
+	}
+	mockParser := &mocks.MockParser{
+		ParseUserInfoFunc: func(ctx context.Context, data json.RawMessage) (models.UserInfo, error) {
+			return models.UserInfo{Username: "gopher"}, nil
+		},
+		ParseUserPostsFunc: func(ctx context.Context, data json.RawMessage) ([]models.UserPost, string, error) {
+			if strings.Contains(string(data), "t3_p1") {
+				// The second page must already be on its way while the first is parsed
+				select {
+				case <-secondRequested:
+				case <-time.After(2 * time.Second):
+					t.Error("Expected the second page to be requested while parsing the first")
+				}
+				return []models.UserPost{{ID: "p1", CreatedAt: now.Add(-time.Hour)}}, "t3_p1", nil
+			}
+			return []models.UserPost{{ID: "p2", CreatedAt: now.Add(-2 * time.Hour)}}, "", nil
+		},
+		ParseUserCommentsFunc: func(ctx context.Context, data json.RawMessage) ([]models.UserComment, string, error) {
+			return nil, "", nil
+		},
+	}
+
+	svc := scraper.NewScraperService(mockClient, mockParser)
+
+	activity, err := svc.ScrapeUserActivity(context.Background(), "gopher", 0, 50, 0)
+	if err != nil {
+		t.Fatalf("Failed to scrape user activity: %v", err)
+	}
+	if len(activity.Posts) != 2 || activity.Posts[0].ID != "p1" || activity.Posts[1].ID != "p2" {
+		t.Errorf("Expected posts p1 and p2, got %+v", activity.Posts)
+	}
+}
+
+func TestSummarizeUserActivity(t *testing.T) {
+	at := func(day, hour int) time.Time {
+		return time.Date(2025, 4, day, hour, 30, 0, 0, time.UTC)
+	}
+	activity := models.UserActivity{
+		UserInfo: models.UserInfo{Username: "gopher"},
+		Posts: []models.UserPost{
+			{ID: "p1", Subreddit: "golang", Score: 10, CreatedAt: at(3, 14)},
+			{ID: "p2", Subreddit: "Golang", Score: 30, CreatedAt: at(9, 14)},
+		},
+		Comments: []models.UserComment{
+			{ID: "c1", Subreddit: "golang", Score: 4, CreatedAt: at(5, 9)},
+			{ID: "c2", Subreddit: "rust", Score: 2, CreatedAt: at(1, 22)},
+			{ID: "c3", Subreddit: "rust", Score: 6, CreatedAt: at(12, 14)},
+		},
+	}
+
+	summary := scraper.SummarizeUserActivity(activity)
+
+	if summary.Posts != 2 || summary.Comments != 3 {
+		t.Errorf("Expected 2 posts and 3 comments, got %d and %d", summary.Posts, summary.Comments)
+	}
+	if !summary.FirstSeen.Equal(at(1, 22)) || !summary.LastSeen.Equal(at(12, 14)) {
+		t.Errorf("Expected first and last seen from the oldest and newest items, got %v and %v", summary.FirstSeen, summary.LastSeen)
+	}
+	if len(summary.Subreddits) != 2 {
+		t.Fatalf("Expected subreddits to match ignoring case, got %+v", summary.Subreddits)
+	}
+	golang := summary.Subreddits[0]
+	if golang.Subreddit != "golang" || golang.Posts != 2 || golang.Comments != 1 || golang.AveragePostScore != 20 || golang.AverageCommentScore != 4 {
+		t.Errorf("Unexpected golang activity %+v", golang)
+	}
+	if !golang.FirstSeen.Equal(at(3, 14)) || !golang.LastSeen.Equal(at(9, 14)) {
+		t.Errorf("Unexpected golang first and last seen %v, %v", golang.FirstSeen, golang.LastSeen)
+	}
+	if summary.Subreddits[1].AverageCommentScore != 4 || summary.Subreddits[1].AveragePostScore != 0 {
+		t.Errorf("Unexpected rust activity %+v", summary.Subreddits[1])
+	}
+	if summary.HourlyActivity[14] != 3 || len(summary.MostActiveHours) != 3 || summary.MostActiveHours[0] != 14 {
+		t.Errorf("Expected 14:00 UTC to be the most active hour, got %v and %v", summary.HourlyActivity, summary.MostActiveHours)
+	}
+}
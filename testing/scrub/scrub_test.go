@@ -0,0 +1,100 @@
+package scrub_test
+
+import (
+	"context"
+	"testing"
+
+	"reddit-ingestion/internal/scrub"
+	"reddit-ingestion/internal/sink"
+	"reddit-ingestion/pkg/models"
+	"reddit-ingestion/testing/mocks"
+)
+
+func TestScrubberRedactsDefaults(t *testing.T) {
+	s, err := scrub.NewScrubber(nil)
+	if err != nil {
+		t.Fatalf("NewScrubber returned error: %v", err)
+	}
+
+	got := s.Text("Mail jane.doe@example.com or call +1 555-123-4567, thanks /u/some_user")
+	want := "Mail [email] or call [phone], thanks [user]"
+	if got != want {
+		t.Errorf("Text() = %q, want %q", got, want)
+	}
+	if got := s.Text("Released on 2025-04-15 for r/golang"); got != "Released on 2025-04-15 for r/golang" {
+		t.Errorf("Expected text without personal data to be kept, got %q", got)
+	}
+}
+
+func TestScrubberPatternsOverrideDefaults(t *testing.T) {
+	s, err := scrub.NewScrubber(map[string]string{
+		"phone":  "",
+		"ticket": `TCK-\d+`,
+	})
+	if err != nil {
+		t.Fatalf("NewScrubber returned error: %v", err)
+	}
+
+	got := s.Text("See TCK-4821, call 555-123-4567")
+	if got != "See [ticket], call 555-123-4567" {
+		t.Errorf("Text() = %q", got)
+	}
+	if _, err := scrub.NewScrubber(map[string]string{"broken": "("}); err == nil {
+		t.Error("Expected an invalid pattern to be rejected")
+	}
+}
+
+type recordingSink struct {
+	sink.NopSink
+	posts    []models.Post
+	comments []models.Comment
+}
+
+func (r *recordingSink) WritePosts(ctx context.Context, posts []models.Post) error {
+	r.posts = append(r.posts, posts...)
+	return nil
+}
+
+func (r *recordingSink) WriteComments(ctx context.Context, postID string, comments []models.Comment) error {
+	r.comments = append(r.comments, comments...)
+	return nil
+}
+
+func TestWrapServiceScrubsBeforeTheSink(t *testing.T) {
+	inner := &mocks.MockScraperService{
+		ScrapePostFunc: func(ctx context.Context, postID string) (models.PostDetail, error) {
+			return models.PostDetail{
+				Post: models.Post{ID: postID, Title: "Contact me", Body: "me@example.com"},
+				Comments: []models.Comment{{
+					ID:      "c1",
+					Body:    "ask u/helper",
+					Replies: []models.Comment{{ID: "c2", Body: "or jane@example.org"}, {IsMore: true, MoreIDs: []string{"c3"}}},
+				}},
+			}, nil
+		},
+	}
+	s, _ := scrub.NewScrubber(nil)
+	recorded := &recordingSink{}
+	svc := sink.WrapService(scrub.WrapService(inner, s), recorded)
+
+	detail, err := svc.ScrapePost(context.Background(), "abc")
+	if err != nil {
+		t.Fatalf("ScrapePost returned error: %v", err)
+	}
+	if detail.Post.Body != "[email]" || !detail.Post.Scrubbed {
+		t.Errorf("Expected a scrubbed post, got %+v", detail.Post)
+	}
+	reply := detail.Comments[0].Replies[0]
+	if detail.Comments[0].Body != "ask [user]" || reply.Body != "or [email]" || !reply.Scrubbed {
+		t.Errorf("Expected scrubbed comments, got %+v", detail.Comments)
+	}
+	if detail.Comments[0].Replies[1].Scrubbed {
+		t.Error("Expected \"load more\" placeholders to be left unmarked")
+	}
+	if len(recorded.posts) != 1 || recorded.posts[0].Body != "[email]" {
+		t.Errorf("Expected the sink to receive the scrubbed post, got %+v", recorded.posts)
+	}
+	if len(recorded.comments) != 1 || recorded.comments[0].Replies[0].Body != "or [email]" {
+		t.Errorf("Expected the sink to receive scrubbed comments, got %+v", recorded.comments)
+	}
+}
@@ -0,0 +1,173 @@
+package sink_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	kafkago "github.com/segmentio/kafka-go"
+
+	"reddit-ingestion/internal/audit"
+	"reddit-ingestion/internal/sink/kafka"
+	"reddit-ingestion/pkg/models"
+)
+
+// stubWriter records the batches written to a topic. With deliver set it
+// reports them delivered or failed with deliverErr, as an async kafka-go
+// writer does once a batch is acknowledged; with writeErr it rejects them.
+type stubWriter struct {
+	topic      string
+	completion func(messages []kafkago.Message, err error)
+	deliver    bool
+	deliverErr error
+	writeErr   error
+	batches    [][]kafkago.Message
+}
+
+func (w *stubWriter) WriteMessages(ctx context.Context, messages ...kafkago.Message) error {
+	if w.writeErr != nil {
+		return w.writeErr
+	}
+	w.batches = append(w.batches, messages)
+	if w.deliver {
+		w.completion(messages, w.deliverErr)
+	}
+	return nil
+}
+
+func (w *stubWriter) Close() error { return nil }
+
+// newStubProducer returns a producer writing to stub writers, by topic
+func newStubProducer(t *testing.T, configure func(w *stubWriter)) (*kafka.Producer, map[string]*stubWriter) {
+	writers := make(map[string]*stubWriter)
+	producer, err := kafka.NewProducer(kafka.Config{
+		PostsTopic:        "posts",
+		CommentsTopic:     "comments",
+		UserActivityTopic: "users",
+		NewWriter: func(topic string, completion func(messages []kafkago.Message, err error)) kafka.MessageWriter {
+			w := &stubWriter{topic: topic, completion: completion}
+			if configure != nil {
+				configure(w)
+			}
+			writers[topic] = w
+			return w
+		},
+	})
+	if err != nil {
+		t.Fatalf("NewProducer returned error: %v", err)
+	}
+	return producer, writers
+}
+
+func TestKafkaProducerBatchesAndKeysMessages(t *testing.T) {
+	producer, writers := newStubProducer(t, nil)
+	ctx := context.Background()
+
+	posts := []models.Post{{ID: "p1", Subreddit: "GoLang"}, {ID: "p2", Subreddit: "golang"}, {ID: "p3"}}
+	if err := producer.WritePosts(ctx, posts); err != nil {
+		t.Fatalf("WritePosts returned error: %v", err)
+	}
+	comments := []models.Comment{
+		{ID: "c1", Replies: []models.Comment{
+			{ID: "c2"},
+			{ID: "more_x", IsMore: true, MoreIDs: []string{"c9"}},
+		}},
+		{ID: "c3"},
+	}
+	if err := producer.WriteComments(ctx, "p1", comments); err != nil {
+		t.Fatalf("WriteComments returned error: %v", err)
+	}
+	if err := producer.WriteUserActivity(ctx, models.UserActivity{UserInfo: models.UserInfo{Username: "spez"}}); err != nil {
+		t.Fatalf("WriteUserActivity returned error: %v", err)
+	}
+
+	postBatches := writers["posts"].batches
+	if len(postBatches) != 1 || len(postBatches[0]) != len(posts) {
+		t.Fatalf("Expected the posts in one batch, got %v", postBatches)
+	}
+	for i, want := range []string{"golang/p1", "golang/p2", "p3"} {
+		if key := string(postBatches[0][i].Key); key != want {
+			t.Errorf("Expected post %d keyed %s, got %s", i, want, key)
+		}
+	}
+
+	commentBatches := writers["comments"].batches
+	if len(commentBatches) != 1 || len(commentBatches[0]) != 3 {
+		t.Fatalf("Expected the comments without placeholders in one batch, got %v", commentBatches)
+	}
+	parents := map[string]string{"c1": "p1", "c2": "c1", "c3": "p1"}
+	for _, message := range commentBatches[0] {
+		if string(message.Key) != "p1" {
+			t.Errorf("Expected comments keyed by post ID, got %s", message.Key)
+		}
+		var payload struct {
+			PostID   string         `json:"post_id"`
+			ParentID string         `json:"parent_id"`
+			Comment  models.Comment `json:"comment"`
+		}
+		if err := json.Unmarshal(message.Value, &payload); err != nil {
+			t.Fatalf("Comment message is not JSON: %v", err)
+		}
+		if payload.PostID != "p1" || payload.ParentID != parents[payload.Comment.ID] || len(payload.Comment.Replies) > 0 {
+			t.Errorf("Unexpected comment message %+v", payload)
+		}
+	}
+
+	userBatches := writers["users"].batches
+	if len(userBatches) != 1 || len(userBatches[0]) != 1 || string(userBatches[0][0].Key) != "spez" {
+		t.Errorf("Expected one user activity message keyed by username, got %v", userBatches)
+	}
+}
+
+func TestKafkaProducerAddsPurposeHeader(t *testing.T) {
+	producer, writers := newStubProducer(t, nil)
+
+	ctx := audit.WithPurpose(context.Background(), "research")
+	if err := producer.WritePosts(ctx, []models.Post{{ID: "p1"}}); err != nil {
+		t.Fatalf("WritePosts returned error: %v", err)
+	}
+	if err := producer.WritePosts(context.Background(), []models.Post{{ID: "p2"}}); err != nil {
+		t.Fatalf("WritePosts returned error: %v", err)
+	}
+
+	batches := writers["posts"].batches
+	headers := batches[0][0].Headers
+	if len(headers) != 1 || headers[0].Key != "purpose" || string(headers[0].Value) != "research" {
+		t.Errorf("Expected a purpose header, got %v", headers)
+	}
+	if headers := batches[1][0].Headers; len(headers) != 0 {
+		t.Errorf("Expected no headers without a purpose, got %v", headers)
+	}
+}
+
+func TestKafkaProducerCountsDeliveryFailures(t *testing.T) {
+	producer, _ := newStubProducer(t, func(w *stubWriter) {
+		switch w.topic {
+		case "posts":
+			w.deliver = true
+		case "comments":
+			w.deliver = true
+			w.deliverErr = errors.New("leader not available")
+		case "users":
+			w.writeErr = errors.New("writer closed")
+		}
+	})
+	ctx := context.Background()
+
+	if err := producer.WritePosts(ctx, []models.Post{{ID: "p1"}, {ID: "p2"}}); err != nil {
+		t.Fatalf("WritePosts returned error: %v", err)
+	}
+	// A failed delivery is only reported to the completion
+	if err := producer.WriteComments(ctx, "p1", []models.Comment{{ID: "c1"}, {ID: "c2"}, {ID: "c3"}}); err != nil {
+		t.Fatalf("WriteComments returned error: %v", err)
+	}
+	if err := producer.WriteUserActivity(ctx, models.UserActivity{}); err == nil {
+		t.Error("Expected the rejected write to return an error")
+	}
+
+	stats := producer.Stats()
+	if stats.Delivered != 2 || stats.Failed != 4 {
+		t.Errorf("Expected 2 delivered and 4 failed, got %+v", stats)
+	}
+}
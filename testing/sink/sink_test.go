@@ -0,0 +1,55 @@
+package sink_test
+
+import (
+	"context"
+	"testing"
+
+	"reddit-ingestion/internal/sink"
+	"reddit-ingestion/pkg/models"
+	"reddit-ingestion/testing/mocks"
+)
+
+type recordingSink struct {
+	sink.NopSink
+	posts    []models.Post
+	comments map[string][]models.Comment
+}
+
+func (r *recordingSink) WritePosts(ctx context.Context, posts []models.Post) error {
+	r.posts = append(r.posts, posts...)
+	return nil
+}
+
+func (r *recordingSink) WriteComments(ctx context.Context, postID string, comments []models.Comment) error {
+	if r.comments == nil {
+		r.comments = make(map[string][]models.Comment)
+	}
+	r.comments[postID] = append(r.comments[postID], comments...)
+	return nil
+}
+
+func TestWrapServiceWritesPostDetail(t *testing.T) {
+	svc := &mocks.MockScraperService{
+		ScrapePostFunc: func(ctx context.Context, postID string) (models.PostDetail, error) {
+			return models.PostDetail{
+				Post:     models.Post{ID: postID},
+				Comments: []models.Comment{{ID: "c1"}, {ID: "c2"}},
+			}, nil
+		},
+	}
+
+	rec := &recordingSink{}
+	wrapped := sink.WrapService(svc, rec)
+
+	if _, err := wrapped.ScrapePost(context.Background(), "abc123"); err != nil {
+		t.Fatalf("ScrapePost returned error: %v", err)
+	}
+
+	if len(rec.posts) != 1 || rec.posts[0].ID != "abc123" {
+		t.Errorf("Expected post abc123 to be written, got %v", rec.posts)
+	}
+
+	if len(rec.comments["abc123"]) != 2 {
+		t.Errorf("Expected 2 comments written for abc123, got %d", len(rec.comments["abc123"]))
+	}
+}
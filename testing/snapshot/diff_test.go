@@ -0,0 +1,131 @@
+package snapshot_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"reddit-ingestion/internal/snapshot"
+	"reddit-ingestion/pkg/models"
+	"reddit-ingestion/pkg/scraper"
+	"reddit-ingestion/testing/mocks"
+)
+
+func TestDiffSnapshots(t *testing.T) {
+	base := time.Unix(1620000000, 0)
+
+	previous := snapshot.Snapshot{
+		Subreddit: "test",
+		TakenAt:   base,
+		Posts: []models.Post{
+			{ID: "keep", Score: 10, NumComments: 2, CreatedAt: base.Add(-1 * time.Hour)},
+			{ID: "removed", Score: 5, CreatedAt: base.Add(-2 * time.Hour)},
+			{ID: "paged_out", Score: 1, CreatedAt: base.Add(-10 * time.Hour)},
+		},
+	}
+
+	current := snapshot.Snapshot{
+		Subreddit: "test",
+		TakenAt:   base.Add(time.Hour),
+		Posts: []models.Post{
+			{ID: "fresh", Score: 1, CreatedAt: base.Add(30 * time.Minute)},
+			{ID: "keep", Score: 25, NumComments: 7, CreatedAt: base.Add(-1 * time.Hour)},
+			{ID: "older", Score: 3, CreatedAt: base.Add(-3 * time.Hour)},
+		},
+	}
+
+	changes := snapshot.Diff(previous, current)
+
+	if len(changes.New) != 2 {
+		t.Errorf("Expected 2 new posts, got %d", len(changes.New))
+	}
+
+	if len(changes.Removed) != 1 || changes.Removed[0].ID != "removed" {
+		t.Errorf("Expected only 'removed' to be reported as removed, got %v", changes.Removed)
+	}
+
+	if len(changes.Changed) != 1 {
+		t.Fatalf("Expected 1 changed post, got %d", len(changes.Changed))
+	}
+
+	change := changes.Changed[0]
+	if change.ScoreBefore != 10 || change.ScoreAfter != 25 {
+		t.Errorf("Expected score 10 -> 25, got %d -> %d", change.ScoreBefore, change.ScoreAfter)
+	}
+	if change.NumCommentsBefore != 2 || change.NumCommentsAfter != 7 {
+		t.Errorf("Expected comments 2 -> 7, got %d -> %d", change.NumCommentsBefore, change.NumCommentsAfter)
+	}
+}
+
+func TestStoreAtOrBefore(t *testing.T) {
+	store := snapshot.NewStore(2)
+	base := time.Unix(1620000000, 0)
+
+	store.Add(snapshot.Snapshot{Subreddit: "test", TakenAt: base})
+	store.Add(snapshot.Snapshot{Subreddit: "test", TakenAt: base.Add(time.Hour)})
+	store.Add(snapshot.Snapshot{Subreddit: "test", TakenAt: base.Add(2 * time.Hour)})
+
+	snap, ok := store.AtOrBefore("test", base.Add(90*time.Minute))
+	if !ok || !snap.TakenAt.Equal(base.Add(time.Hour)) {
+		t.Errorf("Expected snapshot at +1h, got %v", snap.TakenAt)
+	}
+
+	// The first snapshot was evicted, so the oldest retained one is returned
+	snap, ok = store.AtOrBefore("test", base)
+	if !ok || !snap.TakenAt.Equal(base.Add(time.Hour)) {
+		t.Errorf("Expected oldest retained snapshot at +1h, got %v", snap.TakenAt)
+	}
+}
+
+func TestStoreDropsLeastRecentlyUsedSubreddit(t *testing.T) {
+	store := snapshot.NewStore(2)
+	base := time.Unix(1620000000, 0)
+
+	for i := 0; i < snapshot.MaxSubreddits; i++ {
+		store.Add(snapshot.Snapshot{Subreddit: fmt.Sprintf("sub%d", i), TakenAt: base})
+	}
+	// sub0 is asked for again, so sub1 is now the least recently used
+	if _, ok := store.Latest("sub0"); !ok {
+		t.Fatal("Expected a snapshot of sub0")
+	}
+	store.Add(snapshot.Snapshot{Subreddit: "new", TakenAt: base})
+
+	if _, ok := store.Latest("sub1"); ok {
+		t.Error("Expected the least recently used subreddit to be dropped")
+	}
+	for _, subreddit := range []string{"sub0", "sub2", "new"} {
+		if _, ok := store.Latest(subreddit); !ok {
+			t.Errorf("Expected %s to be kept", subreddit)
+		}
+	}
+}
+
+func TestChangesIgnoreSubredditCase(t *testing.T) {
+	var scraped []string
+	svc := &mocks.MockScraperService{
+		ScrapeSubredditFunc: func(ctx context.Context, subreddit string, sinceTimestamp int64, limit int, opts scraper.ListingOptions) ([]models.Post, models.ListingMeta, error) {
+			scraped = append(scraped, subreddit)
+			return []models.Post{{ID: fmt.Sprintf("p%d", len(scraped))}}, models.ListingMeta{}, nil
+		},
+	}
+	diff := snapshot.NewDiffService(svc, snapshot.NewStore(2))
+
+	if _, err := diff.Changes(context.Background(), "GoLang", time.Time{}); err != nil {
+		t.Fatalf("Changes returned error: %v", err)
+	}
+	changes, err := diff.Changes(context.Background(), "golang", time.Time{})
+	if err != nil {
+		t.Fatalf("Changes returned error: %v", err)
+	}
+
+	if changes.Subreddit != "golang" || changes.From.IsZero() {
+		t.Errorf("Expected the second call to diff against the first, got %+v", changes)
+	}
+	if len(changes.New) != 1 || changes.New[0].ID != "p2" {
+		t.Errorf("Expected only p2 to be new, got %+v", changes.New)
+	}
+	if scraped[0] != "golang" {
+		t.Errorf("Expected the lowercased name to be scraped, got %v", scraped)
+	}
+}
@@ -0,0 +1,52 @@
+package stats_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"reddit-ingestion/internal/stats"
+	"reddit-ingestion/pkg/models"
+	"reddit-ingestion/pkg/scraper"
+	"reddit-ingestion/testing/mocks"
+)
+
+func TestServiceCountsIngestion(t *testing.T) {
+	inner := &mocks.MockScraperService{
+		ScrapeSubredditFunc: func(ctx context.Context, subreddit string, sinceTimestamp int64, limit int, opts scraper.ListingOptions) ([]models.Post, models.ListingMeta, error) {
+			if subreddit == "private" {
+				return nil, models.ListingMeta{}, errors.New("forbidden")
+			}
+			return []models.Post{{ID: "a"}, {ID: "b", Subreddit: "golang"}}, models.ListingMeta{}, nil
+		},
+		ScrapePostFunc: func(ctx context.Context, postID string) (models.PostDetail, error) {
+			return models.PostDetail{
+				Post: models.Post{ID: postID, Subreddit: "rust"},
+				Comments: []models.Comment{
+					{ID: "c1", Replies: []models.Comment{{ID: "c2"}}},
+					{ID: "more1", IsMore: true},
+				},
+			}, nil
+		},
+	}
+	registry := stats.NewRegistry()
+	svc := stats.WrapService(inner, registry)
+
+	svc.ScrapeSubreddit(context.Background(), "golang", 0, 25, scraper.ListingOptions{})
+	svc.ScrapeSubreddit(context.Background(), "private", 0, 25, scraper.ListingOptions{})
+	svc.ScrapePost(context.Background(), "abc123")
+
+	got := registry.Stats()
+	if got.Posts != 3 || got.Comments != 2 {
+		t.Errorf("Expected 3 posts and 2 comments, got %d and %d", got.Posts, got.Comments)
+	}
+	// Posts without a subreddit count under the one scraped; most ingested first
+	if len(got.Subreddits) != 2 || got.Subreddits[0] != (stats.SubredditCounts{Subreddit: "rust", Posts: 1, Comments: 2}) ||
+		got.Subreddits[1] != (stats.SubredditCounts{Subreddit: "golang", Posts: 2}) {
+		t.Errorf("Unexpected subreddit counts %+v", got.Subreddits)
+	}
+	if len(got.Operations) != 2 || got.Operations[1].Operation != "subreddit" ||
+		got.Operations[1].Scrapes != 2 || got.Operations[1].Errors != 1 || got.Operations[1].ErrorRate != 0.5 {
+		t.Errorf("Unexpected operation counts %+v", got.Operations)
+	}
+}
@@ -0,0 +1,143 @@
+package utils_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"reddit-ingestion/pkg/utils"
+)
+
+// headerProxy records the headers of every request it forwards
+func headerProxy(headers chan<- http.Header) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		headers <- r.Header.Clone()
+		w.Write([]byte(`{}`))
+	}))
+}
+
+func profileFor(t *testing.T, userAgent string) utils.BrowserProfile {
+	for _, profile := range utils.BrowserProfiles() {
+		for _, ua := range profile.UserAgents {
+			if ua == userAgent {
+				return profile
+			}
+		}
+	}
+	t.Fatalf("User-Agent %q belongs to no profile", userAgent)
+	return utils.BrowserProfile{}
+}
+
+func TestRequestHeadersMatchOneProfile(t *testing.T) {
+	headers := make(chan http.Header, 20)
+	proxy := headerProxy(headers)
+	defer proxy.Close()
+
+	rotator, _ := utils.NewProxyRotator([]string{proxy.URL})
+	client := &http.Client{Transport: utils.NewTLSFingerprintingTransport(rotator)}
+
+	for i := 0; i < 20; i++ {
+		req, _ := http.NewRequest(http.MethodGet, "http://reddit.invalid/r/test.json", nil)
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatalf("Request failed: %v", err)
+		}
+		resp.Body.Close()
+
+		h := <-headers
+		profile := profileFor(t, h.Get("User-Agent"))
+		if h.Get("Accept") != profile.Accept || h.Get("Accept-Encoding") != profile.AcceptEncoding {
+			t.Errorf("%s User-Agent sent with Accept %q and Accept-Encoding %q", profile.Name, h.Get("Accept"), h.Get("Accept-Encoding"))
+		}
+		for name, value := range profile.NavigationHeaders {
+			if h.Get(name) != value {
+				t.Errorf("%s request has %s %q, want %q", profile.Name, name, h.Get(name), value)
+			}
+		}
+	}
+}
+
+func TestProxySessionKeepsOneUserAgent(t *testing.T) {
+	headers := make(chan http.Header, 5)
+	proxy := headerProxy(headers)
+	defer proxy.Close()
+
+	rotator, _ := utils.NewProxyRotator([]string{proxy.URL})
+	client := &http.Client{Transport: utils.NewTLSFingerprintingTransport(rotator)}
+
+	ctx := utils.WithProxySession(context.Background())
+	session := utils.ProxySessionFromContext(ctx)
+	for i := 0; i < 5; i++ {
+		req, _ := http.NewRequestWithContext(ctx, http.MethodGet, "http://reddit.invalid/r/test.json", nil)
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatalf("Request failed: %v", err)
+		}
+		resp.Body.Close()
+
+		if ua := (<-headers).Get("User-Agent"); ua != session.UserAgent() {
+			t.Errorf("Expected every page of the session to send %q, got %q", session.UserAgent(), ua)
+		}
+	}
+	if profileFor(t, session.UserAgent()).Name != session.Profile().Name {
+		t.Errorf("Session User-Agent does not belong to its %s profile", session.Profile().Name)
+	}
+}
+
+func TestFixedUserAgentSelectsMatchingProfile(t *testing.T) {
+	headers := make(chan http.Header, 10)
+	proxy := headerProxy(headers)
+	defer proxy.Close()
+
+	rotator, _ := utils.NewProxyRotator([]string{proxy.URL})
+	client := &http.Client{Transport: utils.NewTLSFingerprintingTransport(rotator)}
+
+	firefox := "Mozilla/5.0 (X11; Linux x86_64; rv:122.0) Gecko/20100101 Firefox/122.0"
+	for i := 0; i < 10; i++ {
+		req, _ := http.NewRequestWithContext(utils.WithProxySession(context.Background()), http.MethodGet, "http://reddit.invalid/r/test.json", nil)
+		req.Header.Set("User-Agent", firefox)
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatalf("Request failed: %v", err)
+		}
+		resp.Body.Close()
+
+		h := <-headers
+		if h.Get("User-Agent") != firefox || h.Get("TE") != "trailers" {
+			t.Errorf("Expected Firefox headers for a Firefox user agent, got %v", h)
+		}
+	}
+}
+
+func TestRandomUserAgentsFollowTheClientSetting(t *testing.T) {
+	// The client reads no environment
+	t.Setenv("USE_RANDOM_USER_AGENTS", "true")
+
+	headers := make(chan http.Header, 1)
+	proxy := headerProxy(headers)
+	defer proxy.Close()
+
+	client, err := utils.NewRetryableClient([]string{proxy.URL}, 1, "custom-agent/1.0")
+	if err != nil {
+		t.Fatalf("NewRetryableClient returned error: %v", err)
+	}
+	defer client.Close()
+	send := func() string {
+		req, _ := http.NewRequest(http.MethodGet, "http://reddit.invalid/r/test.json", nil)
+		if _, _, err := client.Do(req); err != nil {
+			t.Fatalf("Request failed: %v", err)
+		}
+		return (<-headers).Get("User-Agent")
+	}
+
+	if ua := send(); ua != "custom-agent/1.0" {
+		t.Errorf("Expected the configured user agent by default, got %q", ua)
+	}
+	client.SetRandomUserAgents(true)
+	profileFor(t, send())
+	client.SetRandomUserAgents(false)
+	if ua := send(); ua != "custom-agent/1.0" {
+		t.Errorf("Expected the configured user agent once random ones are off, got %q", ua)
+	}
+}
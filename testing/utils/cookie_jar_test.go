@@ -0,0 +1,82 @@
+package utils_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"reddit-ingestion/pkg/utils"
+)
+
+// cookieProxy sets loid on every response and records the Cookie header of
+// each request
+func cookieProxy(mutex *sync.Mutex, received *[]string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mutex.Lock()
+		*received = append(*received, r.Header.Get("Cookie"))
+		n := len(*received)
+		mutex.Unlock()
+		http.SetCookie(w, &http.Cookie{Name: "loid", Value: "visitor", Path: "/", Domain: "reddit.invalid"})
+		if n == 1 {
+			http.SetCookie(w, &http.Cookie{Name: "session_tracker", Value: "first", Path: "/"})
+		}
+		w.Write([]byte(`{}`))
+	}))
+}
+
+func TestRetryableClientKeepsCookiesPerSession(t *testing.T) {
+	var mutex sync.Mutex
+	var received []string
+	proxy := cookieProxy(&mutex, &received)
+	defer proxy.Close()
+
+	client, err := utils.NewRetryableClient([]string{proxy.URL}, 1, "test-agent")
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	fetch := func(ctx context.Context) {
+		req, _ := http.NewRequestWithContext(ctx, http.MethodGet, "http://www.reddit.invalid/r/golang/new.json", nil)
+		if _, _, err := client.Do(req); err != nil {
+			t.Fatalf("Request failed: %v", err)
+		}
+	}
+
+	// Off by default
+	session := utils.WithProxySession(context.Background())
+	fetch(session)
+	fetch(session)
+	if received[1] != "" {
+		t.Errorf("Expected no cookies without a TTL, got %q", received[1])
+	}
+
+	client.SetCookieTTL(time.Minute)
+	received = nil
+	fetch(session)
+	fetch(session)
+	fetch(session)
+	if received[0] != "" || received[2] != "loid=visitor; session_tracker=first" && received[2] != "session_tracker=first; loid=visitor" {
+		t.Errorf("Expected the session to send back its cookies, got %q", received)
+	}
+
+	// Another session presents another browser, or goes through another
+	// proxy, and starts without cookies unless it shares the first's jar
+	other := utils.WithProxySession(context.Background())
+	if utils.ProxySessionFromContext(other).Profile().Name != utils.ProxySessionFromContext(session).Profile().Name {
+		received = nil
+		fetch(other)
+		if received[0] != "" {
+			t.Errorf("Expected a new browser profile to start without cookies, got %q", received[0])
+		}
+	}
+
+	// Turning cookies off drops the jars
+	client.SetCookieTTL(0)
+	received = nil
+	fetch(session)
+	if received[0] != "" {
+		t.Errorf("Expected no cookies once turned off, got %q", received[0])
+	}
+}
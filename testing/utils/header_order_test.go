@@ -0,0 +1,114 @@
+package utils_test
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"net/http"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"testing"
+
+	"reddit-ingestion/pkg/utils"
+)
+
+// rawProxy answers every request on a connection with an empty listing and
+// sends the header lines of each, in the order they arrived on the wire
+func rawProxy(t *testing.T, heads chan<- []string) net.Listener {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				reader := textproto.NewReader(bufio.NewReader(conn))
+				for {
+					if _, err := reader.ReadLine(); err != nil {
+						return
+					}
+					var lines []string
+					length := 0
+					for {
+						line, err := reader.ReadLine()
+						if err != nil {
+							return
+						}
+						if line == "" {
+							break
+						}
+						name, value, _ := strings.Cut(line, ":")
+						lines = append(lines, line)
+						if name == "Content-Length" {
+							length, _ = strconv.Atoi(strings.TrimSpace(value))
+						}
+					}
+					io.CopyN(io.Discard, reader.R, int64(length))
+					heads <- lines
+					io.WriteString(conn, "HTTP/1.1 200 OK\r\nContent-Length: 2\r\n\r\n{}")
+				}
+			}()
+		}
+	}()
+	return listener
+}
+
+func TestRequestHeadersFollowProfileOrder(t *testing.T) {
+	heads := make(chan []string, 1)
+	proxy := rawProxy(t, heads)
+	defer proxy.Close()
+
+	rotator, _ := utils.NewProxyRotator([]string{"http://" + proxy.Addr().String()})
+	client := &http.Client{Transport: utils.NewTLSFingerprintingTransport(rotator)}
+
+	for i := 0; i < 20; i++ {
+		req, _ := http.NewRequest(http.MethodGet, "http://reddit.invalid/r/test.json", nil)
+		if i%3 == 0 {
+			// A body must not be taken for the next request's headers
+			req, _ = http.NewRequest(http.MethodPost, "http://reddit.invalid/api/morechildren", strings.NewReader("link_id=t3_abc"))
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatalf("Request %d failed: %v", i, err)
+		}
+		io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+
+		var names []string
+		var userAgent string
+		for _, line := range <-heads {
+			name, value, _ := strings.Cut(line, ":")
+			names = append(names, name)
+			if name == "User-Agent" {
+				userAgent = strings.TrimSpace(value)
+			}
+		}
+		profile := profileFor(t, userAgent)
+
+		// Headers of the profile come first and in its order, the others
+		// after them
+		rank := make(map[string]int)
+		for j, name := range profile.HeaderOrder {
+			rank[name] = j
+		}
+		last := -1
+		for _, name := range names {
+			j, ok := rank[name]
+			if !ok {
+				j = len(profile.HeaderOrder)
+			}
+			if j < last {
+				t.Errorf("%s request sent its headers out of order: %v", profile.Name, names)
+				break
+			}
+			last = j
+		}
+	}
+}
@@ -0,0 +1,66 @@
+package utils_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"reddit-ingestion/pkg/utils"
+)
+
+func TestHostAllowlistAllowsSubdomains(t *testing.T) {
+	allowlist := utils.NewHostAllowlist("reddit.com", "http://127.0.0.1:8080", "*.Example.org")
+	tests := []struct {
+		host string
+		want bool
+	}{
+		{"reddit.com", true},
+		{"old.reddit.com", true},
+		{"OLD.REDDIT.COM:443", true},
+		{"notreddit.com", false},
+		{"reddit.com.evil.net", false},
+		{"127.0.0.1:9999", true},
+		{"api.example.org", true},
+		{"169.254.169.254", false},
+	}
+	for _, tt := range tests {
+		if got := allowlist.Allows(tt.host); got != tt.want {
+			t.Errorf("Allows(%q) = %v, want %v", tt.host, got, tt.want)
+		}
+	}
+
+	var none *utils.HostAllowlist
+	if !none.Allows("anything.example") {
+		t.Error("Expected a nil allowlist to allow every host")
+	}
+}
+
+func TestRetryableClientKeepsToAllowedHosts(t *testing.T) {
+	var hits int32
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&hits, 1)
+		http.Redirect(w, r, "http://169.254.169.254/latest/meta-data/", http.StatusFound)
+	}))
+	defer proxy.Close()
+
+	client := newRetryTestClient(t, proxy.URL, 3, fastRetries)
+	client.SetHostAllowlist(utils.NewHostAllowlist("reddit.com"))
+
+	req, _ := http.NewRequest(http.MethodGet, "http://internal.example/admin", nil)
+	if _, _, err := client.Do(req); !errors.Is(err, utils.ErrHostNotAllowed) {
+		t.Errorf("Expected a host outside the allowlist to be refused, got %v", err)
+	}
+	if atomic.LoadInt32(&hits) != 0 {
+		t.Errorf("Expected the refused request never to reach the proxy, got %d hits", hits)
+	}
+
+	req, _ = http.NewRequest(http.MethodGet, "http://www.reddit.com/r/golang/new.json", nil)
+	if _, _, err := client.Do(req); !errors.Is(err, utils.ErrHostNotAllowed) {
+		t.Errorf("Expected a redirect off the allowlist to fail, got %v", err)
+	}
+	if atomic.LoadInt32(&hits) != 1 {
+		t.Errorf("Expected one attempt without retries or a followed redirect, got %d hits", hits)
+	}
+}
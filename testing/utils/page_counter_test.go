@@ -0,0 +1,45 @@
+package utils_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"reddit-ingestion/pkg/utils"
+)
+
+func TestPageCounterCountsResponses(t *testing.T) {
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{}`))
+	}))
+	defer proxy.Close()
+
+	client, err := utils.NewRetryableClient([]string{proxy.URL}, 3, "test-agent")
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	var onPage int
+	pages := &utils.PageCounter{OnPage: func() { onPage++ }}
+	ctx := utils.WithPageCounter(context.Background(), pages)
+
+	for i := 0; i < 2; i++ {
+		req, _ := http.NewRequestWithContext(ctx, http.MethodGet, "http://reddit.invalid/r/golang/new.json", nil)
+		if _, _, err := client.Do(req); err != nil {
+			t.Fatalf("Request failed: %v", err)
+		}
+	}
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, "http://reddit.invalid/r/golang/new.json", nil)
+	resp, err := client.DoStream(req)
+	if err != nil {
+		t.Fatalf("Stream failed: %v", err)
+	}
+	resp.Body.Close()
+
+	if pages.Pages() != 3 {
+		t.Errorf("Expected 3 pages, got %d", pages.Pages())
+	}
+	if onPage != 3 {
+		t.Errorf("Expected OnPage called for each of 3 pages, got %d", onPage)
+	}
+}
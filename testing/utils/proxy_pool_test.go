@@ -0,0 +1,48 @@
+package utils_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"reddit-ingestion/pkg/utils"
+)
+
+func TestProxyPoolRestrictsProxies(t *testing.T) {
+	var hitsDC, hitsRes int32
+	datacenter := countingProxy(http.StatusOK, &hitsDC)
+	defer datacenter.Close()
+	residential := countingProxy(http.StatusOK, &hitsRes)
+	defer residential.Close()
+
+	client, err := utils.NewRetryableClient([]string{datacenter.URL + "#datacenter", residential.URL + "#residential+us"}, 1, "test-agent")
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	if !client.HasProxyPool("residential") || !client.HasProxyPool("us") || client.HasProxyPool("mobile") {
+		t.Error("Expected pools residential and us, and no mobile pool")
+	}
+
+	ctx := utils.WithProxyPool(context.Background(), "residential")
+	for i := 0; i < 5; i++ {
+		req, _ := http.NewRequestWithContext(ctx, http.MethodGet, "http://reddit.invalid/r/test.json", nil)
+		if _, _, err := client.Do(req); err != nil {
+			t.Fatalf("Request %d failed: %v", i, err)
+		}
+	}
+	if hitsDC != 0 || hitsRes != 5 {
+		t.Errorf("Expected all requests on the residential proxy, got datacenter %d, residential %d", hitsDC, hitsRes)
+	}
+
+	req, _ := http.NewRequestWithContext(utils.WithProxyPool(context.Background(), "mobile"), http.MethodGet, "http://reddit.invalid/r/test.json", nil)
+	if _, _, err := client.Do(req); !errors.Is(err, utils.ErrUnknownProxyPool) {
+		t.Errorf("Expected ErrUnknownProxyPool for an empty pool, got %v", err)
+	}
+
+	for _, usage := range client.BandwidthUsage() {
+		if len(usage.Labels) == 0 {
+			t.Errorf("Expected labels to be reported for %s", usage.Proxy)
+		}
+	}
+}
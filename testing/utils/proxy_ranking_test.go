@@ -0,0 +1,91 @@
+package utils_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"reddit-ingestion/pkg/utils"
+)
+
+func TestProxyRankerPrefersFastReliableProxies(t *testing.T) {
+	fast, _ := url.Parse("http://fast.invalid:8080")
+	slow, _ := url.Parse("http://slow.invalid:8080")
+	flaky, _ := url.Parse("http://flaky.invalid:8080")
+	fresh, _ := url.Parse("http://fresh.invalid:8080")
+
+	ranker := utils.NewProxyRanker()
+	for i := 0; i < 5; i++ {
+		ranker.Record(fast.String(), 100*time.Millisecond, true)
+		ranker.Record(slow.String(), 900*time.Millisecond, true)
+		ranker.Record(flaky.String(), 50*time.Millisecond, false)
+	}
+
+	ranked := ranker.Rank([]*url.URL{slow, flaky, fresh, fast})
+	want := []*url.URL{fast, fresh, slow, flaky}
+	for i := range want {
+		if ranked[i] != want[i] {
+			t.Fatalf("Expected rank %d to be %s, got %s", i+1, want[i], ranked[i])
+		}
+	}
+
+	ranking := ranker.Ranking([]*url.URL{slow, fast})
+	if ranking[0].Rank != 1 || ranking[0].LatencyMS != 100 || ranking[0].Samples != 5 {
+		t.Errorf("Unexpected ranking entry %+v", ranking[0])
+	}
+}
+
+func TestInteractiveRequestsUseBestProxyAndBulkTheRest(t *testing.T) {
+	var hitsSlow, hitsFast int32
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&hitsSlow, 1)
+		time.Sleep(30 * time.Millisecond)
+		w.Write([]byte(`{}`))
+	}))
+	defer slow.Close()
+	fast := countingProxy(http.StatusOK, &hitsFast)
+	defer fast.Close()
+
+	client, err := utils.NewRetryableClient([]string{slow.URL, fast.URL}, 1, "test-agent")
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+
+	do := func(ctx context.Context) {
+		req, _ := http.NewRequestWithContext(ctx, http.MethodGet, "http://reddit.invalid/r/test.json", nil)
+		if _, _, err := client.Do(req); err != nil {
+			t.Fatalf("Request failed: %v", err)
+		}
+	}
+	bulk := utils.WithBulkPriority(context.Background())
+
+	// Measure both proxies: untried proxies rank in configured order
+	do(bulk)
+	do(context.Background())
+
+	atomic.StoreInt32(&hitsSlow, 0)
+	atomic.StoreInt32(&hitsFast, 0)
+	for i := 0; i < 5; i++ {
+		do(context.Background())
+	}
+	if hitsFast != 5 || hitsSlow != 0 {
+		t.Errorf("Expected interactive requests on the fast proxy, got fast %d, slow %d", hitsFast, hitsSlow)
+	}
+
+	atomic.StoreInt32(&hitsSlow, 0)
+	atomic.StoreInt32(&hitsFast, 0)
+	for i := 0; i < 5; i++ {
+		do(bulk)
+	}
+	if hitsSlow != 5 || hitsFast != 0 {
+		t.Errorf("Expected bulk requests on the remaining proxy, got fast %d, slow %d", hitsFast, hitsSlow)
+	}
+
+	if ranking := client.ProxyRanking(); len(ranking) != 2 || ranking[0].Proxy != fast.URL {
+		t.Errorf("Expected the fast proxy to rank first, got %+v", ranking)
+	}
+}
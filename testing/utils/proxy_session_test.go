@@ -0,0 +1,84 @@
+package utils_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"reddit-ingestion/pkg/utils"
+)
+
+// countingProxy is a plain HTTP proxy stand-in that answers every request itself
+func countingProxy(status int, hits *int32) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(hits, 1)
+		w.WriteHeader(status)
+		w.Write([]byte(`{}`))
+	}))
+}
+
+func TestProxySessionSticksToOneProxy(t *testing.T) {
+	var hitsA, hitsB int32
+	proxyA := countingProxy(http.StatusOK, &hitsA)
+	defer proxyA.Close()
+	proxyB := countingProxy(http.StatusOK, &hitsB)
+	defer proxyB.Close()
+
+	client, err := utils.NewRetryableClient([]string{proxyA.URL, proxyB.URL}, 1, "test-agent")
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+
+	ctx := utils.WithProxySession(context.Background())
+	for i := 0; i < 10; i++ {
+		req, _ := http.NewRequestWithContext(ctx, http.MethodGet, "http://reddit.invalid/r/test.json", nil)
+		if _, _, err := client.Do(req); err != nil {
+			t.Fatalf("Request %d failed: %v", i, err)
+		}
+	}
+
+	if !(hitsA == 10 && hitsB == 0) && !(hitsA == 0 && hitsB == 10) {
+		t.Errorf("Expected all requests on one proxy, got %d and %d", hitsA, hitsB)
+	}
+}
+
+func TestProxySessionRotatesOnFailure(t *testing.T) {
+	var hitsBad, hitsGood int32
+	bad := countingProxy(http.StatusBadGateway, &hitsBad)
+	defer bad.Close()
+	good := countingProxy(http.StatusOK, &hitsGood)
+	defer good.Close()
+
+	client, err := utils.NewRetryableClient([]string{bad.URL, good.URL}, 2, "test-agent")
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+
+	ctx := utils.WithProxySession(context.Background())
+	session := utils.ProxySessionFromContext(ctx)
+	// Point the session at the failing proxy (index 0 of 2)
+	for session.ProxyID()%2 != 0 {
+		session.Rotate()
+	}
+
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, "http://reddit.invalid/r/test.json", nil)
+	resp, _, err := client.Do(req)
+	if err != nil {
+		t.Fatalf("Expected retry on the next proxy to succeed, got %v", err)
+	}
+	if resp.StatusCode != http.StatusOK || hitsBad != 1 || hitsGood != 1 {
+		t.Errorf("Expected one failure then success on the other proxy, got status %d, hits %d/%d", resp.StatusCode, hitsBad, hitsGood)
+	}
+}
+
+func TestWithProxySessionKeepsExistingSession(t *testing.T) {
+	ctx := utils.WithProxySession(context.Background())
+	if utils.ProxySessionFromContext(utils.WithProxySession(ctx)) != utils.ProxySessionFromContext(ctx) {
+		t.Error("Expected nested operations to share the caller's session")
+	}
+	if utils.ProxySessionFromContext(context.Background()) != nil {
+		t.Error("Expected no session on a bare context")
+	}
+}
@@ -0,0 +1,166 @@
+package utils_test
+
+import (
+	"context"
+	"encoding/binary"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"reddit-ingestion/pkg/utils"
+)
+
+// socksServer is a minimal SOCKS5 proxy recording the address type and
+// credentials of each CONNECT it tunnels
+type socksServer struct {
+	listener net.Listener
+	mutex    sync.Mutex
+	atyps    []byte
+	users    []string
+}
+
+func newSOCKSServer(t *testing.T) *socksServer {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	s := &socksServer{listener: listener}
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go s.serve(conn)
+		}
+	}()
+	t.Cleanup(func() { listener.Close() })
+	return s
+}
+
+func (s *socksServer) serve(conn net.Conn) {
+	defer conn.Close()
+
+	header := make([]byte, 2)
+	if _, err := io.ReadFull(conn, header); err != nil {
+		return
+	}
+	methods := make([]byte, header[1])
+	if _, err := io.ReadFull(conn, methods); err != nil {
+		return
+	}
+	user := ""
+	if strings.IndexByte(string(methods), 0x02) >= 0 {
+		conn.Write([]byte{0x05, 0x02})
+		// Username/password subnegotiation: version, ulen, user, plen, password
+		if _, err := io.ReadFull(conn, header); err != nil {
+			return
+		}
+		name := make([]byte, header[1])
+		io.ReadFull(conn, name)
+		plen := make([]byte, 1)
+		io.ReadFull(conn, plen)
+		io.ReadFull(conn, make([]byte, plen[0]))
+		user = string(name)
+		conn.Write([]byte{0x01, 0x00})
+	} else {
+		conn.Write([]byte{0x05, 0x00})
+	}
+
+	request := make([]byte, 4)
+	if _, err := io.ReadFull(conn, request); err != nil {
+		return
+	}
+	var host string
+	switch request[3] {
+	case 0x01:
+		ip := make([]byte, 4)
+		io.ReadFull(conn, ip)
+		host = net.IP(ip).String()
+	case 0x04:
+		ip := make([]byte, 16)
+		io.ReadFull(conn, ip)
+		host = net.IP(ip).String()
+	case 0x03:
+		length := make([]byte, 1)
+		io.ReadFull(conn, length)
+		name := make([]byte, length[0])
+		io.ReadFull(conn, name)
+		host = string(name)
+	}
+	portBytes := make([]byte, 2)
+	io.ReadFull(conn, portBytes)
+	port := strconv.Itoa(int(binary.BigEndian.Uint16(portBytes)))
+
+	s.mutex.Lock()
+	s.atyps = append(s.atyps, request[3])
+	s.users = append(s.users, user)
+	s.mutex.Unlock()
+
+	target, err := net.Dial("tcp", net.JoinHostPort(host, port))
+	if err != nil {
+		conn.Write([]byte{0x05, 0x04, 0x00, 0x01, 0, 0, 0, 0, 0, 0})
+		return
+	}
+	defer target.Close()
+	conn.Write([]byte{0x05, 0x00, 0x00, 0x01, 0, 0, 0, 0, 0, 0})
+
+	go io.Copy(target, conn)
+	io.Copy(conn, target)
+}
+
+func (s *socksServer) connects() ([]byte, []string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return append([]byte(nil), s.atyps...), append([]string(nil), s.users...)
+}
+
+func TestRetryableClientRotatesAcrossProxySchemes(t *testing.T) {
+	var targetHits int32
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&targetHits, 1)
+		w.Write([]byte(`{}`))
+	}))
+	defer target.Close()
+	_, port, _ := net.SplitHostPort(target.Listener.Addr().String())
+	targetURL := "http://localhost:" + port + "/r/test.json"
+
+	var httpHits int32
+	httpProxy := countingProxy(http.StatusOK, &httpHits)
+	defer httpProxy.Close()
+	socks := newSOCKSServer(t)
+	socksh := newSOCKSServer(t)
+
+	client, err := utils.NewRetryableClient([]string{
+		httpProxy.URL + "#http",
+		"socks5://" + socks.listener.Addr().String() + "#socks5",
+		"socks5h://scraper:secret@" + socksh.listener.Addr().String() + "#socks5h",
+	}, 1, "test-agent")
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+
+	for _, pool := range []string{"http", "socks5", "socks5h"} {
+		ctx := utils.WithProxyPool(context.Background(), pool)
+		req, _ := http.NewRequestWithContext(ctx, http.MethodGet, targetURL, nil)
+		if _, _, err := client.Do(req); err != nil {
+			t.Fatalf("Request through the %s proxy failed: %v", pool, err)
+		}
+	}
+
+	if httpHits != 1 || targetHits != 2 {
+		t.Errorf("Expected one request forwarded by the HTTP proxy and two tunnelled by SOCKS5, got %d/%d", httpHits, targetHits)
+	}
+	if atyps, users := socks.connects(); len(atyps) != 1 || atyps[0] != 0x01 || users[0] != "" {
+		t.Errorf("Expected socks5 to connect to a locally resolved IPv4 address without credentials, got %v %q", atyps, users)
+	}
+	if atyps, users := socksh.connects(); len(atyps) != 1 || atyps[0] != 0x03 || users[0] != "scraper" {
+		t.Errorf("Expected socks5h to hand the proxy the host name with credentials, got %v %q", atyps, users)
+	}
+}
@@ -0,0 +1,35 @@
+package utils_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"reddit-ingestion/pkg/utils"
+)
+
+func TestRequestLimiterSpacesRequests(t *testing.T) {
+	// 1200 a minute is one request every 50ms
+	limiter := utils.NewRequestLimiter(1200)
+
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		if err := limiter.Wait(context.Background()); err != nil {
+			t.Fatalf("Wait failed: %v", err)
+		}
+	}
+	if elapsed := time.Since(start); elapsed < 100*time.Millisecond {
+		t.Errorf("Expected three requests to take at least 100ms, took %v", elapsed)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	limiter.Wait(ctx)
+	if err := limiter.Wait(ctx); err == nil {
+		t.Error("Expected waiting with a cancelled context to fail")
+	}
+
+	if utils.NewRequestLimiter(0).Wait(context.Background()) != nil {
+		t.Error("Expected no limit to never fail")
+	}
+}
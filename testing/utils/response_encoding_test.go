@@ -0,0 +1,121 @@
+package utils_test
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/andybalholm/brotli"
+	"github.com/klauspost/compress/zstd"
+	"reddit-ingestion/pkg/utils"
+)
+
+const encodedBody = `{"kind":"Listing","data":{"children":[]}}`
+
+// encode compresses body with each of encodings in turn
+func encode(t *testing.T, body []byte, encodings ...string) []byte {
+	t.Helper()
+	for _, encoding := range encodings {
+		var buf bytes.Buffer
+		var w io.WriteCloser
+		switch encoding {
+		case "gzip":
+			w = gzip.NewWriter(&buf)
+		case "br":
+			w = brotli.NewWriter(&buf)
+		case "zstd":
+			zw, err := zstd.NewWriter(&buf)
+			if err != nil {
+				t.Fatalf("zstd: %v", err)
+			}
+			w = zw
+		case "deflate":
+			w = zlib.NewWriter(&buf)
+		case "raw-deflate":
+			w, _ = flate.NewWriter(&buf, flate.DefaultCompression)
+		}
+		w.Write(body)
+		w.Close()
+		body = buf.Bytes()
+	}
+	return body
+}
+
+func TestRetryableClientDecodesContentEncodings(t *testing.T) {
+	tests := []struct {
+		name       string
+		header     string
+		encodings  []string
+		advertised bool
+	}{
+		{"identity", "", nil, true},
+		{"gzip", "gzip", []string{"gzip"}, true},
+		{"brotli", "br", []string{"br"}, true},
+		{"zstd", "zstd", []string{"zstd"}, false},
+		{"zlib deflate", "deflate", []string{"deflate"}, true},
+		{"raw deflate", "deflate", []string{"raw-deflate"}, true},
+		{"stacked", "gzip, br", []string{"gzip", "br"}, true},
+	}
+
+	for _, tt := range tests {
+		var acceptEncoding string
+		proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			acceptEncoding = r.Header.Get("Accept-Encoding")
+			if tt.header != "" {
+				w.Header().Set("Content-Encoding", tt.header)
+			}
+			w.Write(encode(t, []byte(encodedBody), tt.encodings...))
+		}))
+
+		client, err := utils.NewRetryableClient([]string{proxy.URL}, 1, "test-agent")
+		if err != nil {
+			t.Fatalf("Failed to create client: %v", err)
+		}
+		req, _ := http.NewRequest(http.MethodGet, "http://reddit.invalid/r/golang/new.json", nil)
+		if _, body, err := client.Do(req); err != nil || string(body) != encodedBody {
+			t.Errorf("%s: Do returned %q, %v", tt.name, body, err)
+		}
+		for _, encoding := range strings.Split(tt.header, ",") {
+			encoding = strings.TrimSpace(encoding)
+			if tt.advertised && !strings.Contains(acceptEncoding, encoding) {
+				t.Errorf("%s: Accept-Encoding %q does not advertise %s", tt.name, acceptEncoding, encoding)
+			}
+		}
+
+		req, _ = http.NewRequest(http.MethodGet, "http://reddit.invalid/r/golang/new.json", nil)
+		resp, err := client.DoStream(req)
+		if err != nil {
+			t.Errorf("%s: DoStream: %v", tt.name, err)
+		} else {
+			body, err := io.ReadAll(resp.Body)
+			resp.Body.Close()
+			if err != nil || string(body) != encodedBody {
+				t.Errorf("%s: DoStream returned %q, %v", tt.name, body, err)
+			}
+		}
+		proxy.Close()
+	}
+}
+
+func TestRetryableClientRejectsUnknownContentEncoding(t *testing.T) {
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Encoding", "compress")
+		w.Write([]byte("garbage"))
+	}))
+	defer proxy.Close()
+
+	client, err := utils.NewRetryableClient([]string{proxy.URL}, 1, "test-agent")
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	req, _ := http.NewRequest(http.MethodGet, "http://reddit.invalid/r/golang/new.json", nil)
+	if _, body, err := client.Do(req); err == nil || !strings.Contains(err.Error(), "compress") {
+		t.Errorf("Expected an unsupported encoding error, got %q, %v", body, err)
+	}
+}
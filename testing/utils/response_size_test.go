@@ -0,0 +1,59 @@
+package utils_test
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"reddit-ingestion/pkg/utils"
+)
+
+func largeBodyProxy(hits *int32) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(hits, 1)
+		// Flush first so the body is chunked and its size is unknown up front
+		w.Write([]byte(`{"data":`))
+		w.(http.Flusher).Flush()
+		w.Write([]byte(strings.Repeat(" ", 4096) + `{}}`))
+	}))
+}
+
+func TestRetryableClientRejectsOversizedResponse(t *testing.T) {
+	var hits int32
+	proxy := largeBodyProxy(&hits)
+	defer proxy.Close()
+
+	client, err := utils.NewRetryableClient([]string{proxy.URL}, 3, "test-agent")
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	client.SetMaxResponseBytes(1024)
+
+	req, _ := http.NewRequest(http.MethodGet, "http://reddit.invalid/comments/abc.json", nil)
+	if _, _, err := client.Do(req); !errors.Is(err, utils.ErrResponseTooLarge) {
+		t.Fatalf("Expected ErrResponseTooLarge, got %v", err)
+	}
+	if hits != 1 {
+		t.Errorf("Expected an oversized response not to be retried, got %d requests", hits)
+	}
+
+	req, _ = http.NewRequest(http.MethodGet, "http://reddit.invalid/comments/abc.json", nil)
+	resp, err := client.DoStream(req)
+	if err != nil {
+		t.Fatalf("Expected the stream to open, got %v", err)
+	}
+	defer resp.Body.Close()
+	if _, err := io.ReadAll(resp.Body); !errors.Is(err, utils.ErrResponseTooLarge) {
+		t.Errorf("Expected reading past the limit to fail with ErrResponseTooLarge, got %v", err)
+	}
+
+	client.SetMaxResponseBytes(0)
+	req, _ = http.NewRequest(http.MethodGet, "http://reddit.invalid/comments/abc.json", nil)
+	if _, body, err := client.Do(req); err != nil || len(body) < 4096 {
+		t.Errorf("Expected the full body without a limit, got %d bytes, %v", len(body), err)
+	}
+}
@@ -0,0 +1,129 @@
+package utils_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"reddit-ingestion/pkg/utils"
+)
+
+// fastRetries keeps the waits of a test short
+var fastRetries = utils.RetryPolicy{BaseDelay: time.Millisecond, MaxDelay: 5 * time.Millisecond, Budget: 10 * time.Second}
+
+// failingProxy answers the first failures requests with status and the rest
+// with an empty listing
+func failingProxy(hits *int32, failures int32, status int, header http.Header) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(hits, 1) <= failures {
+			for name, values := range header {
+				w.Header()[name] = values
+			}
+			w.WriteHeader(status)
+			return
+		}
+		w.Write([]byte(`{}`))
+	}))
+}
+
+func newRetryTestClient(t *testing.T, proxyURL string, maxRetries int, policy utils.RetryPolicy) *utils.RetryableClient {
+	t.Helper()
+	client, err := utils.NewRetryableClient([]string{proxyURL}, maxRetries, "test-agent")
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	client.SetRetryPolicy(policy)
+	return client
+}
+
+func TestRetryableClientRetriesOnlySafeConditions(t *testing.T) {
+	tests := []struct {
+		name     string
+		method   string
+		status   int
+		wantHits int32
+		wantErr  bool
+	}{
+		{"GET after 502", http.MethodGet, http.StatusBadGateway, 3, false},
+		{"POST after 502", http.MethodPost, http.StatusBadGateway, 1, true},
+		{"POST after 429", http.MethodPost, http.StatusTooManyRequests, 3, false},
+		{"GET after 501", http.MethodGet, http.StatusNotImplemented, 1, true},
+	}
+	for _, tt := range tests {
+		var hits int32
+		proxy := failingProxy(&hits, 2, tt.status, nil)
+		client := newRetryTestClient(t, proxy.URL, 3, fastRetries)
+
+		req, _ := http.NewRequest(tt.method, "http://reddit.invalid/api/morechildren", strings.NewReader("a=b"))
+		_, _, err := client.Do(req)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s: got error %v, want error %v", tt.name, err, tt.wantErr)
+		}
+		if hits != tt.wantHits {
+			t.Errorf("%s: got %d requests, want %d", tt.name, hits, tt.wantHits)
+		}
+		proxy.Close()
+	}
+}
+
+func TestRetryableClientStopsAtRetryBudget(t *testing.T) {
+	var hits int32
+	// Reddit asks for a wait longer than the whole budget
+	proxy := failingProxy(&hits, 10, http.StatusServiceUnavailable, http.Header{"Retry-After": {"5"}})
+	defer proxy.Close()
+	client := newRetryTestClient(t, proxy.URL, 5, utils.RetryPolicy{BaseDelay: time.Millisecond, MaxDelay: time.Millisecond, Budget: time.Second})
+
+	started := time.Now()
+	req, _ := http.NewRequest(http.MethodGet, "http://reddit.invalid/r/golang/new.json", nil)
+	if _, _, err := client.Do(req); err == nil || !strings.Contains(err.Error(), "status 503") {
+		t.Errorf("Expected the 503 once the budget is used up, got %v", err)
+	}
+	if hits != 1 || time.Since(started) > time.Second {
+		t.Errorf("Expected to give up at once, got %d requests in %v", hits, time.Since(started))
+	}
+	if stats := client.RetryStats(); stats.BudgetExhausted != 1 || stats.Retries != 0 {
+		t.Errorf("Expected one request given up for the budget, got %+v", stats)
+	}
+}
+
+func TestRetryableClientCountsRetries(t *testing.T) {
+	var hits int32
+	proxy := failingProxy(&hits, 2, http.StatusTooManyRequests, nil)
+	defer proxy.Close()
+	client := newRetryTestClient(t, proxy.URL, 3, fastRetries)
+
+	// The counter of the scrape and that of the operation around it
+	operation := &utils.PageCounter{}
+	scrape := &utils.PageCounter{}
+	ctx := utils.WithPageCounter(utils.WithPageCounter(context.Background(), operation), scrape)
+
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, "http://reddit.invalid/r/golang/new.json", nil)
+	resp, err := client.DoStream(req)
+	if err != nil {
+		t.Fatalf("Stream failed: %v", err)
+	}
+	resp.Body.Close()
+
+	for name, counter := range map[string]*utils.PageCounter{"scrape": scrape, "operation": operation} {
+		if counter.Pages() != 1 || counter.Retries() != 2 {
+			t.Errorf("%s: expected 1 page after 2 retries, got %d pages and %d retries", name, counter.Pages(), counter.Retries())
+		}
+	}
+	if stats := client.RetryStats(); stats.Retries != 2 || stats.Exhausted != 0 {
+		t.Errorf("Expected 2 retries and nothing given up, got %+v", stats)
+	}
+
+	// A cancelled request stops waiting and is not retried
+	atomic.StoreInt32(&hits, 0)
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+	client.SetRetryPolicy(utils.RetryPolicy{BaseDelay: time.Hour, MaxDelay: time.Hour})
+	req, _ = http.NewRequestWithContext(cancelled, http.MethodGet, "http://reddit.invalid/r/golang/new.json", nil)
+	if _, _, err := client.Do(req); err == nil {
+		t.Error("Expected a cancelled request to fail")
+	}
+}
@@ -0,0 +1,44 @@
+package utils_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"reddit-ingestion/pkg/utils"
+)
+
+func TestJitterStaysWithinItsShare(t *testing.T) {
+	varied := false
+	for i := 0; i < 100; i++ {
+		d := utils.Jitter(time.Second, 0.2)
+		if d < 800*time.Millisecond || d > 1200*time.Millisecond {
+			t.Fatalf("Expected 800ms to 1.2s, got %v", d)
+		}
+		varied = varied || d != time.Second
+	}
+	if !varied {
+		t.Error("Expected jitter to vary the duration")
+	}
+	if d := utils.Jitter(time.Second, 0); d != time.Second {
+		t.Errorf("Expected no jitter to keep the duration, got %v", d)
+	}
+}
+
+func TestSleepContextEndsWithContext(t *testing.T) {
+	if err := utils.SleepContext(context.Background(), 10*time.Millisecond, 0.5); err != nil {
+		t.Errorf("Expected a full sleep to succeed, got %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	err := utils.SleepContext(ctx, time.Minute, 0.1)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected the deadline error, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Expected the sleep to end with its context, took %v", elapsed)
+	}
+}
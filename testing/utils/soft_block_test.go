@@ -0,0 +1,93 @@
+package utils_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"reddit-ingestion/pkg/utils"
+)
+
+// blockingServer serves page, gzipped when zipped is set, to the first
+// blocks requests and an empty listing to the rest
+func blockingServer(hits *int32, blocks int32, page string, zipped bool) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(hits, 1) > blocks {
+			w.Write([]byte(`{"kind":"Listing","data":{"children":[]}}`))
+			return
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		if !zipped {
+			w.Write([]byte(page))
+			return
+		}
+		var buf bytes.Buffer
+		zw := gzip.NewWriter(&buf)
+		zw.Write([]byte(page))
+		zw.Close()
+		w.Header().Set("Content-Encoding", "gzip")
+		w.Write(buf.Bytes())
+	}))
+}
+
+func TestRetryableClientDetectsSoftBlocks(t *testing.T) {
+	tests := []struct {
+		name   string
+		page   string
+		zipped bool
+		reason string
+	}{
+		{"block page", "<html><body>You've been blocked by network security.</body></html>", false, utils.SoftBlockBlocked},
+		{"gzipped block page", "<!doctype html><title>Too Many Requests</title><h1>whoa there, pardner!</h1>", true, utils.SoftBlockBlocked},
+		{"login wall", "<html><a href=\"https://www.reddit.com/login\">Log In</a></html>", false, utils.SoftBlockLoginWall},
+		{"error page", "<html>502 Bad Gateway</html>", false, utils.SoftBlockNonJSON},
+	}
+	for _, tt := range tests {
+		var hits int32
+		server := blockingServer(&hits, 1, tt.page, tt.zipped)
+		client, _ := utils.NewDirectClient(3, "test-agent")
+		client.SetRetryPolicy(fastRetries)
+
+		req, _ := http.NewRequestWithContext(utils.WithJSONExpected(context.Background()), http.MethodGet, server.URL+"/r/golang/new.json", nil)
+		_, body, err := client.Do(req)
+		if err != nil || body[0] != '{' {
+			t.Errorf("%s: expected the listing after a retry, got %q, %v", tt.name, body, err)
+		}
+		if hits != 2 {
+			t.Errorf("%s: got %d requests, want 2", tt.name, hits)
+		}
+		if stats := client.SoftBlockStats(); stats.Total != 1 || stats.ByReason[tt.reason] != 1 {
+			t.Errorf("%s: expected one %s soft block, got %+v", tt.name, tt.reason, stats)
+		}
+		server.Close()
+	}
+}
+
+func TestRetryableClientFailsOnPersistentSoftBlock(t *testing.T) {
+	var hits int32
+	server := blockingServer(&hits, 10, "<html>blocked by network security</html>", false)
+	defer server.Close()
+	client, _ := utils.NewDirectClient(2, "test-agent")
+	client.SetRetryPolicy(fastRetries)
+
+	req, _ := http.NewRequestWithContext(utils.WithJSONExpected(context.Background()), http.MethodGet, server.URL+"/r/golang/new.json", nil)
+	_, err := client.DoStream(req)
+	var blocked *utils.SoftBlockError
+	if !errors.As(err, &blocked) || !errors.Is(err, utils.ErrSoftBlocked) || blocked.Reason != utils.SoftBlockBlocked {
+		t.Errorf("Expected a soft block error, got %v", err)
+	}
+	if hits != 2 {
+		t.Errorf("Expected both attempts to be made, got %d", hits)
+	}
+
+	// Requests that do not ask for JSON take what they get
+	req, _ = http.NewRequest(http.MethodGet, server.URL+"/r/golang/", nil)
+	if _, body, err := client.Do(req); err != nil || len(body) == 0 {
+		t.Errorf("Expected the page of a request not asking for JSON, got %q, %v", body, err)
+	}
+}
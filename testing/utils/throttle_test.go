@@ -0,0 +1,50 @@
+package utils_test
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"reddit-ingestion/pkg/utils"
+)
+
+func TestThrottleFollowsBlockRate(t *testing.T) {
+	window := 20 * time.Millisecond
+	throttle := utils.NewThrottle(window, 0.5)
+
+	// Too few responses to judge the window
+	throttle.Record(http.StatusTooManyRequests)
+	time.Sleep(window + 5*time.Millisecond)
+	if level := throttle.Level(); level != 0 {
+		t.Fatalf("Expected level 0 after a single 429, got %d", level)
+	}
+
+	for i := 0; i < 10; i++ {
+		throttle.Record(http.StatusOK)
+		throttle.Record(http.StatusTooManyRequests)
+	}
+	time.Sleep(window + 5*time.Millisecond)
+	if level := throttle.Level(); level != 1 {
+		t.Fatalf("Expected level 1 after a window of 50%% 429s, got %d", level)
+	}
+	if d := throttle.Delay(100 * time.Millisecond); d != 200*time.Millisecond {
+		t.Errorf("Expected the delay doubled, got %v", d)
+	}
+	if n := throttle.Concurrency(3); n != 1 {
+		t.Errorf("Expected the concurrency halved, got %d", n)
+	}
+	if stats := throttle.Stats(); stats.LastBlockRate != 0.5 || stats.DelayFactor != 2 {
+		t.Errorf("Unexpected stats %+v", stats)
+	}
+
+	// A quiet window lowers the level again
+	time.Sleep(window + 5*time.Millisecond)
+	if level := throttle.Level(); level != 0 {
+		t.Errorf("Expected level 0 after a quiet window, got %d", level)
+	}
+
+	var disabled *utils.Throttle
+	if d := disabled.Delay(time.Second); d != time.Second || disabled.Concurrency(3) != 3 {
+		t.Error("Expected a nil throttle to leave delays and concurrency alone")
+	}
+}
@@ -0,0 +1,84 @@
+package utils_test
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"reddit-ingestion/pkg/utils"
+)
+
+func TestFingerprintingTransportReusesConnections(t *testing.T) {
+	var conns int32
+	proxy := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{}`))
+	}))
+	proxy.Config.ConnState = func(c net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			atomic.AddInt32(&conns, 1)
+		}
+	}
+	proxy.Start()
+	defer proxy.Close()
+
+	rotator, err := utils.NewProxyRotator([]string{proxy.URL})
+	if err != nil {
+		t.Fatalf("Failed to create rotator: %v", err)
+	}
+	client := &http.Client{Transport: utils.NewTLSFingerprintingTransport(rotator)}
+
+	ctx := utils.WithProxySession(context.Background())
+	for i := 0; i < 10; i++ {
+		req, _ := http.NewRequestWithContext(ctx, http.MethodGet, "http://reddit.invalid/r/test.json", nil)
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatalf("Request %d failed: %v", i, err)
+		}
+		io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+	}
+
+	if conns != 1 {
+		t.Errorf("Expected one session to reuse one connection, got %d", conns)
+	}
+}
+
+func TestFingerprintingTransportConcurrentSessions(t *testing.T) {
+	var hits int32
+	proxy := countingProxy(http.StatusOK, &hits)
+	defer proxy.Close()
+
+	rotator, err := utils.NewProxyRotator([]string{proxy.URL})
+	if err != nil {
+		t.Fatalf("Failed to create rotator: %v", err)
+	}
+	client := &http.Client{Transport: utils.NewTLSFingerprintingTransport(rotator)}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ctx := utils.WithProxySession(context.Background())
+			for j := 0; j < 5; j++ {
+				req, _ := http.NewRequestWithContext(ctx, http.MethodGet, "http://reddit.invalid/r/test.json", nil)
+				resp, err := client.Do(req)
+				if err != nil {
+					t.Errorf("Request failed: %v", err)
+					return
+				}
+				resp.Body.Close()
+			}
+		}()
+	}
+	wg.Wait()
+
+	if hits != 40 {
+		t.Errorf("Expected 40 requests to reach the proxy, got %d", hits)
+	}
+}
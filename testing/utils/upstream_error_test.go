@@ -0,0 +1,68 @@
+package utils_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"reddit-ingestion/pkg/utils"
+)
+
+func TestRetryableClientReturnsUpstreamError(t *testing.T) {
+	var status int
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(status)
+		w.Write([]byte(`{"message": "` + http.StatusText(status) + `", "error": ` + strings.Repeat(" ", 1024) + `}`))
+	}))
+	defer proxy.Close()
+	proxyURL, _ := url.Parse(proxy.URL)
+	proxyURL.User = url.UserPassword("user", "secret")
+
+	client := newRetryTestClient(t, proxyURL.String(), 3, fastRetries)
+
+	tests := []struct {
+		name         string
+		status       int
+		wantAttempts int
+		wantMessage  string
+	}{
+		{"not found", http.StatusNotFound, 1, "upstream error: status 404"},
+		{"forbidden", http.StatusForbidden, 1, "upstream error: status 403"},
+		{"unavailable", http.StatusServiceUnavailable, 3, "server error: status 503"},
+	}
+	for _, tt := range tests {
+		status = tt.status
+		req, _ := http.NewRequest(http.MethodGet, "http://reddit.invalid/r/golang/new.json", nil)
+		resp, _, err := client.Do(req)
+		var upstream *utils.UpstreamError
+		if !errors.As(err, &upstream) {
+			t.Errorf("%s: expected an UpstreamError, got %v", tt.name, err)
+			continue
+		}
+		if upstream.StatusCode != tt.status || upstream.Attempts != tt.wantAttempts || err.Error() != tt.wantMessage {
+			t.Errorf("%s: got %q after %d attempts, want %q after %d", tt.name, err, upstream.Attempts, tt.wantMessage, tt.wantAttempts)
+		}
+		if !strings.HasPrefix(upstream.Snippet, `{"message": "`+http.StatusText(tt.status)) || len(upstream.Snippet) > 512 {
+			t.Errorf("%s: snippet %q, want the start of the body", tt.name, upstream.Snippet)
+		}
+		if strings.Contains(upstream.Proxy, "secret") || !strings.Contains(upstream.Proxy, proxyURL.Host) {
+			t.Errorf("%s: proxy %q, want %s with its password masked", tt.name, upstream.Proxy, proxyURL.Host)
+		}
+		// The response comes along so it can be passed on
+		if resp == nil || resp.StatusCode != tt.status {
+			t.Errorf("%s: expected the response with the error, got %v", tt.name, resp)
+		}
+
+		req, _ = http.NewRequest(http.MethodGet, "http://reddit.invalid/r/golang/new.json", nil)
+		if _, err := client.DoStream(req); utils.UpstreamStatus(err) != tt.status {
+			t.Errorf("%s: DoStream returned %v, want status %d", tt.name, err, tt.status)
+		}
+	}
+
+	if utils.UpstreamStatus(errors.New("connection refused")) != 0 {
+		t.Error("Expected no status for other errors")
+	}
+}
@@ -0,0 +1,65 @@
+package utils_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"reddit-ingestion/pkg/utils"
+)
+
+func TestRetryableClientRecordsUpstreamHeaders(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+		w.Header().Set("X-Ratelimit-Used", "12")
+		w.Header().Set("X-Ratelimit-Remaining", "588.0")
+		w.Header().Set("X-Ratelimit-Reset", "240")
+		w.Header().Set("Cf-Ray", "8a1b2c3d4e5f-AMS")
+		w.Header().Set("Set-Cookie", "session=secret")
+		w.Write([]byte(`{}`))
+	}))
+	defer upstream.Close()
+
+	proxyURL := strings.Replace(upstream.URL, "http://", "http://user:pass@", 1)
+	client, err := utils.NewRetryableClient([]string{proxyURL}, 1, "test-agent")
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	for i := 0; i < 2; i++ {
+		req, _ := http.NewRequest(http.MethodGet, "http://reddit.invalid/r/test.json", nil)
+		if _, _, err := client.Do(req); err != nil {
+			t.Fatalf("Request %d failed: %v", i, err)
+		}
+	}
+
+	stats := client.UpstreamStats()
+	if stats.Responses != 2 || stats.CloudflareResponses != 2 || stats.ByStatus["200"] != 2 {
+		t.Errorf("Unexpected counters %+v", stats)
+	}
+	if stats.ByContentType["application/json"] != 2 {
+		t.Errorf("Expected content types without parameters, got %v", stats.ByContentType)
+	}
+
+	if len(stats.RateLimits) != 1 {
+		t.Fatalf("Expected the rate limit of one proxy, got %+v", stats.RateLimits)
+	}
+	limit := stats.RateLimits[0]
+	if limit.Used != 12 || limit.Remaining != 588 || limit.ResetSecs != 240 {
+		t.Errorf("Unexpected rate limit %+v", limit)
+	}
+	if strings.Contains(limit.Proxy, "pass") {
+		t.Errorf("Expected the proxy password to be masked, got %s", limit.Proxy)
+	}
+
+	if len(stats.Recent) != 2 {
+		t.Fatalf("Expected two traced responses, got %d", len(stats.Recent))
+	}
+	trace := stats.Recent[0]
+	if trace.Path != "/r/test.json" || trace.Headers["cf-ray"] != "8a1b2c3d4e5f-AMS" || trace.Headers["x-ratelimit-used"] != "12" {
+		t.Errorf("Unexpected trace entry %+v", trace)
+	}
+	if _, ok := trace.Headers["set-cookie"]; ok {
+		t.Error("Expected headers outside the diagnostic set to be left out")
+	}
+}